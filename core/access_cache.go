@@ -0,0 +1,139 @@
+package teleflow
+
+import (
+	"sync"
+	"time"
+)
+
+// accessCacheKey identifies a cached permission decision. Decisions depend on
+// what is being done and where, so the command, flow, step, required permission
+// and chat are part of the key.
+type accessCacheKey struct {
+	UserID     int64
+	ChatID     int64
+	Command    string
+	FlowName   string
+	StepName   string
+	Permission string
+}
+
+// accessCacheEntry holds a cached result and its expiry time.
+type accessCacheEntry struct {
+	err      error
+	keyboard *ReplyKeyboard
+	expires  time.Time
+}
+
+// CachedAccessManager wraps an AccessManager and caches its decisions per user
+// for a fixed TTL, so database-backed implementations are not queried on every
+// update. Permission results are cached per user, chat, command, flow, step
+// and required permission; reply keyboards per user and chat. Call Invalidate
+// after changing a user's roles.
+//
+// If the wrapped AccessManager also implements CaptchaExempter, the wrapper
+// forwards exemption checks without caching them.
+type CachedAccessManager struct {
+	next AccessManager
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu          sync.Mutex
+	permissions map[accessCacheKey]accessCacheEntry
+	keyboards   map[accessCacheKey]accessCacheEntry
+}
+
+// NewCachedAccessManager wraps next with a cache that keeps decisions for ttl.
+//
+// Example:
+//
+//	cached := teleflow.NewCachedAccessManager(dbAccessManager, time.Minute)
+//	bot, err := teleflow.NewBot(token, teleflow.WithAccessManager(cached))
+//
+//	// After promoting a user:
+//	cached.Invalidate(userID)
+func NewCachedAccessManager(next AccessManager, ttl time.Duration) *CachedAccessManager {
+	return &CachedAccessManager{
+		next:        next,
+		ttl:         ttl,
+		now:         time.Now,
+		permissions: make(map[accessCacheKey]accessCacheEntry),
+		keyboards:   make(map[accessCacheKey]accessCacheEntry),
+	}
+}
+
+// CheckPermission returns the cached decision for the context or asks the wrapped AccessManager.
+func (m *CachedAccessManager) CheckPermission(ctx *PermissionContext) error {
+	key := accessCacheKey{
+		UserID:     ctx.UserID,
+		ChatID:     ctx.ChatID,
+		Command:    ctx.Command,
+		FlowName:   ctx.FlowName,
+		StepName:   ctx.StepName,
+		Permission: ctx.Permission,
+	}
+
+	m.mu.Lock()
+	entry, ok := m.permissions[key]
+	m.mu.Unlock()
+	if ok && m.now().Before(entry.expires) {
+		return entry.err
+	}
+
+	err := m.next.CheckPermission(ctx)
+
+	m.mu.Lock()
+	m.permissions[key] = accessCacheEntry{err: err, expires: m.now().Add(m.ttl)}
+	m.mu.Unlock()
+	return err
+}
+
+// GetReplyKeyboard returns the cached keyboard for the user and chat or asks the wrapped AccessManager.
+func (m *CachedAccessManager) GetReplyKeyboard(ctx *PermissionContext) *ReplyKeyboard {
+	key := accessCacheKey{UserID: ctx.UserID, ChatID: ctx.ChatID}
+
+	m.mu.Lock()
+	entry, ok := m.keyboards[key]
+	m.mu.Unlock()
+	if ok && m.now().Before(entry.expires) {
+		return entry.keyboard
+	}
+
+	keyboard := m.next.GetReplyKeyboard(ctx)
+
+	m.mu.Lock()
+	m.keyboards[key] = accessCacheEntry{keyboard: keyboard, expires: m.now().Add(m.ttl)}
+	m.mu.Unlock()
+	return keyboard
+}
+
+// IsCaptchaExempt forwards to the wrapped AccessManager if it implements CaptchaExempter.
+func (m *CachedAccessManager) IsCaptchaExempt(ctx *PermissionContext) bool {
+	if exempter, ok := m.next.(CaptchaExempter); ok {
+		return exempter.IsCaptchaExempt(ctx)
+	}
+	return false
+}
+
+// Invalidate drops all cached decisions for a user.
+func (m *CachedAccessManager) Invalidate(userID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.permissions {
+		if key.UserID == userID {
+			delete(m.permissions, key)
+		}
+	}
+	for key := range m.keyboards {
+		if key.UserID == userID {
+			delete(m.keyboards, key)
+		}
+	}
+}
+
+// InvalidateAll drops every cached decision.
+func (m *CachedAccessManager) InvalidateAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.permissions = make(map[accessCacheKey]accessCacheEntry)
+	m.keyboards = make(map[accessCacheKey]accessCacheEntry)
+}
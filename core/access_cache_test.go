@@ -0,0 +1,88 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCachedAccessManager_CachesUntilTTL(t *testing.T) {
+	inner := NewMockAccessManager()
+	denied := errors.New("denied")
+	inner.CheckPermissionFunc = func(ctx *PermissionContext) error { return denied }
+
+	cached := NewCachedAccessManager(inner, time.Minute)
+	now := time.Now()
+	cached.now = func() time.Time { return now }
+
+	permCtx := &PermissionContext{UserID: 1, ChatID: 1, Command: "admin"}
+	for i := 0; i < 3; i++ {
+		if err := cached.CheckPermission(permCtx); err != denied {
+			t.Fatalf("Expected cached error, got %v", err)
+		}
+	}
+	if len(inner.CheckPermissionCalls) != 1 {
+		t.Errorf("Expected 1 call to wrapped manager, got %d", len(inner.CheckPermissionCalls))
+	}
+
+	// A different command is a separate decision
+	_ = cached.CheckPermission(&PermissionContext{UserID: 1, ChatID: 1, Command: "start"})
+	if len(inner.CheckPermissionCalls) != 2 {
+		t.Errorf("Expected separate cache entry per command, got %d calls", len(inner.CheckPermissionCalls))
+	}
+
+	now = now.Add(2 * time.Minute)
+	_ = cached.CheckPermission(permCtx)
+	if len(inner.CheckPermissionCalls) != 3 {
+		t.Errorf("Expected expired entry to be refreshed, got %d calls", len(inner.CheckPermissionCalls))
+	}
+}
+
+func TestCachedAccessManager_Invalidate(t *testing.T) {
+	inner := NewMockAccessManager()
+	cached := NewCachedAccessManager(inner, time.Hour)
+
+	user1 := &PermissionContext{UserID: 1, ChatID: 1}
+	user2 := &PermissionContext{UserID: 2, ChatID: 2}
+	_ = cached.CheckPermission(user1)
+	_ = cached.CheckPermission(user2)
+	_ = cached.GetReplyKeyboard(user1)
+
+	cached.Invalidate(1)
+
+	_ = cached.CheckPermission(user1)
+	_ = cached.CheckPermission(user2)
+	_ = cached.GetReplyKeyboard(user1)
+
+	if len(inner.CheckPermissionCalls) != 3 {
+		t.Errorf("Expected only user 1 to be re-checked, got %d calls", len(inner.CheckPermissionCalls))
+	}
+	if len(inner.GetReplyKeyboardCalls) != 2 {
+		t.Errorf("Expected user 1 keyboard to be re-fetched, got %d calls", len(inner.GetReplyKeyboardCalls))
+	}
+
+	cached.InvalidateAll()
+	_ = cached.CheckPermission(user2)
+	if len(inner.CheckPermissionCalls) != 4 {
+		t.Errorf("Expected InvalidateAll to clear every entry, got %d calls", len(inner.CheckPermissionCalls))
+	}
+}
+
+func TestCachedAccessManager_SeparatesRequiredPermissions(t *testing.T) {
+	inner := NewMockAccessManager()
+	inner.CheckPermissionFunc = func(ctx *PermissionContext) error {
+		if ctx.Permission == "transfer:execute" {
+			return errors.New("denied")
+		}
+		return nil
+	}
+	cached := NewCachedAccessManager(inner, time.Hour)
+
+	if err := cached.CheckPermission(&PermissionContext{UserID: 1, ChatID: 1}); err != nil {
+		t.Fatalf("Expected plain message to be allowed, got %v", err)
+	}
+	flowCheck := &PermissionContext{UserID: 1, ChatID: 1, FlowName: "transfer", StepName: "confirm", Permission: "transfer:execute"}
+	if err := cached.CheckPermission(flowCheck); err == nil {
+		t.Error("Expected the cached allow of a plain message not to grant a required permission")
+	}
+}
@@ -0,0 +1,123 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Errors that failed Bot API calls can be matched against with errors.Is. Every
+// call a bot makes returns an *APIError when Telegram rejects it, or an
+// *ErrRateLimited when it was rejected by flood control.
+//
+// Example:
+//
+//	if err := ctx.SendPromptText("Reminder"); errors.Is(err, teleflow.ErrBotBlocked) {
+//		subscriptions.Remove(ctx.UserID())
+//	}
+var (
+	// ErrBotBlocked means the bot may not message the chat: the user blocked the
+	// bot or deleted their account, or the bot was removed from the group.
+	ErrBotBlocked = errors.New("bot cannot message this chat")
+
+	// ErrMessageNotModified means an edit left the message exactly as it was.
+	// It is also an ErrBadRequest.
+	ErrMessageNotModified = errors.New("message is not modified")
+
+	// ErrChatNotFound means the chat does not exist or the bot never talked to
+	// it. It is also an ErrBadRequest.
+	ErrChatNotFound = errors.New("chat not found")
+
+	// ErrBadRequest means Telegram rejected the call's parameters.
+	ErrBadRequest = errors.New("bad request")
+)
+
+// APIError is a Bot API call that Telegram rejected.
+type APIError struct {
+	Method      string // Bot API method, e.g. "sendMessage"
+	Code        int    // HTTP-like error code, e.g. 400 or 403
+	Description string // Telegram's description of the error
+
+	MigrateToChatID int64 // New ID of a group that was upgraded to a supergroup, if any
+
+	err *tgbotapi.Error
+}
+
+// Error returns the method and Telegram's description.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram %s: %s", e.Method, e.Description)
+}
+
+// Unwrap returns the original tgbotapi error.
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// Is matches the error against ErrBotBlocked, ErrMessageNotModified,
+// ErrChatNotFound and ErrBadRequest.
+func (e *APIError) Is(target error) bool {
+	description := strings.ToLower(e.Description)
+	switch target {
+	case ErrBotBlocked:
+		return e.Code == 403
+	case ErrMessageNotModified:
+		return e.Code == 400 && strings.Contains(description, "message is not modified")
+	case ErrChatNotFound:
+		return e.Code == 400 && strings.Contains(description, "chat not found")
+	case ErrBadRequest:
+		return e.Code == 400
+	}
+	return false
+}
+
+// ErrRateLimited is a Bot API call rejected by Telegram's flood control. The
+// call may be repeated after RetryAfter.
+//
+// Example:
+//
+//	var limited *teleflow.ErrRateLimited
+//	if errors.As(err, &limited) {
+//		time.Sleep(limited.RetryAfter)
+//	}
+type ErrRateLimited struct {
+	RetryAfter time.Duration
+	*APIError
+}
+
+// Unwrap returns the underlying APIError.
+func (e *ErrRateLimited) Unwrap() error {
+	return e.APIError
+}
+
+// classifyAPIError wraps an error returned by the Bot API for method into an
+// *APIError or *ErrRateLimited. Other errors, such as network failures, are
+// returned unchanged.
+func classifyAPIError(method string, err error) error {
+	var classified *APIError
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		var value tgbotapi.Error
+		if !errors.As(err, &value) {
+			return err
+		}
+		tgErr = &value
+	}
+
+	apiErr := &APIError{
+		Method:          method,
+		Code:            tgErr.Code,
+		Description:     tgErr.Message,
+		MigrateToChatID: tgErr.MigrateToChatID,
+		err:             tgErr,
+	}
+	if tgErr.Code == 429 {
+		return &ErrRateLimited{RetryAfter: time.Duration(tgErr.RetryAfter) * time.Second, APIError: apiErr}
+	}
+	return apiErr
+}
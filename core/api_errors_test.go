@@ -0,0 +1,97 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		matches []error
+		misses  []error
+	}{
+		{"blocked", &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, []error{ErrBotBlocked}, []error{ErrBadRequest}},
+		{"not modified", &tgbotapi.Error{Code: 400, Message: "Bad Request: message is not modified: specified new message content and reply markup are exactly the same"}, []error{ErrMessageNotModified, ErrBadRequest}, []error{ErrChatNotFound}},
+		{"chat not found", tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}, []error{ErrChatNotFound, ErrBadRequest}, []error{ErrBotBlocked}},
+		{"other bad request", &tgbotapi.Error{Code: 400, Message: "Bad Request: message text is empty"}, []error{ErrBadRequest}, []error{ErrMessageNotModified}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyAPIError("sendMessage", tt.err)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Method != "sendMessage" {
+				t.Fatalf("Expected *APIError for sendMessage, got %T %v", err, err)
+			}
+			for _, target := range tt.matches {
+				if !errors.Is(err, target) {
+					t.Errorf("Expected error to match %v", target)
+				}
+			}
+			for _, target := range tt.misses {
+				if errors.Is(err, target) {
+					t.Errorf("Expected error not to match %v", target)
+				}
+			}
+			var tgErr *tgbotapi.Error
+			var tgValue tgbotapi.Error
+			if !errors.As(err, &tgErr) && !errors.As(err, &tgValue) {
+				t.Error("Expected the original tgbotapi error to stay reachable")
+			}
+		})
+	}
+
+	network := errors.New("connection reset")
+	if err := classifyAPIError("sendMessage", network); err != network {
+		t.Errorf("Expected non-API errors unchanged, got %v", err)
+	}
+}
+
+func TestAPIErrors_ReturnedBySends(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	mockClient.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		return tgbotapi.Message{}, &tgbotapi.Error{
+			Code:               429,
+			Message:            "Too Many Requests: retry after 7",
+			ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 7},
+		}
+	}
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	ctx.telegramClient = bot.sender.forContext(ctx)
+
+	err := ctx.SendPromptText("Hello")
+	var limited *ErrRateLimited
+	if !errors.As(err, &limited) {
+		t.Fatalf("Expected *ErrRateLimited, got %T %v", err, err)
+	}
+	if limited.RetryAfter != 7*time.Second || limited.Code != 429 {
+		t.Errorf("Unexpected rate limit error: %+v", limited)
+	}
+}
+
+func TestPromptComposer_EditInPlaceNotModified(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	mockClient.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		if _, ok := c.(tgbotapi.EditMessageTextConfig); ok {
+			return tgbotapi.Message{}, &tgbotapi.Error{Code: 400, Message: "Bad Request: message is not modified"}
+		}
+		return tgbotapi.Message{MessageID: 10}, nil
+	}
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	ctx.editTarget = sentPrompt{ChatID: 42, MessageID: 9}
+
+	if err := bot.promptComposer.ComposeAndSend(ctx, &PromptConfig{Message: "Same text"}); err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+	if len(mockClient.SendCalls) != 1 {
+		t.Errorf("Expected the unchanged prompt not to be sent again, got %d calls", len(mockClient.SendCalls))
+	}
+	if len(ctx.sentPrompts) != 1 || ctx.sentPrompts[0].MessageID != 9 {
+		t.Errorf("Expected the edited prompt to be tracked, got %+v", ctx.sentPrompts)
+	}
+}
@@ -1,8 +1,13 @@
 package teleflow
 
 import (
+	"crypto/rsa"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -34,14 +39,17 @@ type BotOption func(*Bot)
 // It provides context about the user, chat, and operation being performed,
 // allowing AccessManager implementations to make informed authorization decisions.
 type PermissionContext struct {
-	UserID    int64            // Telegram user ID making the request
-	ChatID    int64            // Chat ID where the request originated
-	Command   string           // Command being executed (if applicable)
-	Arguments []string         // Command arguments (if applicable)
-	IsGroup   bool             // True if the request is from a group chat
-	IsChannel bool             // True if the request is from a channel
-	MessageID int              // Message ID of the request
-	Update    *tgbotapi.Update // Full Telegram update object for advanced processing
+	UserID     int64            // Telegram user ID making the request
+	ChatID     int64            // Chat ID where the request originated
+	Command    string           // Command being executed (if applicable)
+	Arguments  []string         // Command arguments (if applicable)
+	IsGroup    bool             // True if the request is from a group chat
+	IsChannel  bool             // True if the request is from a channel
+	MessageID  int              // Message ID of the request
+	FlowName   string           // Flow being started or continued (if applicable)
+	StepName   string           // Flow step being entered (if applicable)
+	Permission string           // Permission required by the flow or step (if applicable)
+	Update     *tgbotapi.Update // Full Telegram update object for advanced processing
 }
 
 // AccessManager defines the interface for controlling user access to bot features.
@@ -62,12 +70,25 @@ type AccessManager interface {
 // It provides methods for registering handlers, managing flows, and configuring bot behavior.
 // The Bot is the central component that coordinates all other framework features.
 type Bot struct {
-	api  TelegramClient // Interface for communicating with Telegram API
-	self tgbotapi.User  // Bot's own user information from Telegram
+	api    TelegramClient // Interface for communicating with Telegram API
+	sender *hookedClient  // api wrapped with the send hooks, used for all outgoing calls
+	self   tgbotapi.User  // Bot's own user information from Telegram
 
 	handlers           map[string]HandlerFunc // Registered command handlers
 	textHandlers       map[string]HandlerFunc // Registered text message handlers
 	defaultTextHandler HandlerFunc            // Fallback handler for unmatched messages
+	deepLinks          []deepLinkRoute        // Registered /start payload routes
+	preCheckoutHandler HandlerFunc            // Confirms orders before payment (nil accepts all)
+	paymentHandler     HandlerFunc            // Handles successful payments
+	shippingHandler    ShippingHandlerFunc    // Offers shipping options for flexible invoices
+	chatBoostHandler   ChatBoostHandlerFunc   // Handles boosts added to or removed from chats
+
+	businessConnectionHandler BusinessConnectionHandlerFunc // Handles business accounts connecting or disconnecting
+	giveawayHandler           GiveawayHandlerFunc           // Handles giveaways announced in chats
+	giveawayWinnersHandler    GiveawayWinnersHandlerFunc    // Handles giveaway winner announcements
+	passportHandler           HandlerFunc                   // Handles Telegram Passport data users share
+	passportKey               *rsa.PrivateKey               // Decrypts Telegram Passport data
+	chosenInlineResultHandler HandlerFunc                   // Handles inline results users pick
 
 	flowManager           *flowManager          // Manages multi-step conversation flows
 	promptKeyboardHandler PromptKeyboardActions // Handles inline keyboard interactions
@@ -78,6 +99,47 @@ type Bot struct {
 
 	accessManager AccessManager // Controls user access to bot features
 	flowConfig    FlowConfig    // Configuration for flow behavior
+
+	captcha    *CaptchaConfig    // Captcha started for new group members (nil if disabled)
+	flood      *floodGuard       // Counts group messages before they are dispatched (nil if disabled)
+	menuButton *MenuButtonConfig // Default menu button applied on Start (nil to keep current)
+
+	chatLocks    *chatLocks               // Serializes updates of the same chat (nil if disabled)
+	poolContexts bool                     // Reuse Context values between updates
+	dryRun       DryRunSink               // Receives outgoing calls instead of Telegram (nil to send normally)
+	recorder     atomic.Pointer[Recorder] // Records updates and responses (nil if disabled)
+	clock        Clock                    // Source of time for timeouts and rate limits
+	deadLetters  *deadLetterConfig        // Stores updates that failed (nil if disabled)
+
+	sendHooks    []SendHook    // Hooks run before every outgoing API call
+	rawSendHooks []RawSendHook // Hooks run before every outgoing raw API call
+	sendHooksMu  sync.RWMutex  // Guards sendHooks and rawSendHooks
+
+	stats     *statsCollector // Per-handler latency and error counts
+	apiConfig apiClientConfig // Connection options used by NewBot
+
+	updatesConfig updatesConfig // How updates are received, deduplicated and queued
+
+	dataSubjects  dataSubjectRegistry // Stores walked by ExportUserData and PurgeUserData
+	sweeperOnce   sync.Once           // Starts the sweep for flows idle beyond FlowConfig.StateTTL
+	featureGate   FeatureGate         // Enables commands, texts and flows per user (nil for all)
+	exposureHook  ExposureFunc        // Reports experiment variants shown to users (nil if disabled)
+	outboundLog   *OutboundLogConfig  // Logs outgoing calls (nil if disabled)
+	errorReporter Reporter            // Receives errors of flows (nil if disabled)
+	secrets       secretScrubber      // Removes tokens and secrets from errors and logs
+
+	// Runtime state reported by Health
+	polling          atomic.Bool
+	webhook          atomic.Bool  // A WebhookHandler was created
+	ping             apiPing      // Cached getMe ping
+	lastUpdate       atomic.Int64 // Unix nanoseconds of the last received update
+	startedAt        atomic.Int64 // Unix nanoseconds of Start or the first WebhookHandler
+	activeHandlers   atomic.Int64
+	duplicateUpdates atomic.Int64 // Updates dropped by WithDeduplication
+	droppedUpdates   atomic.Int64 // Updates dropped by a full update queue
+	updatesMu        sync.Mutex
+	updates          tgbotapi.UpdatesChannel
+	queue            *updateQueue // Queue configured with WithUpdateQueue, once started
 }
 
 // newBotInternal creates a new Bot instance with the provided client and configuration.
@@ -90,6 +152,9 @@ func newBotInternal(client TelegramClient, botUser tgbotapi.User, options ...Bot
 		handlers:              make(map[string]HandlerFunc),
 		textHandlers:          make(map[string]HandlerFunc),
 		promptKeyboardHandler: newPromptKeyboardHandler(),
+		stats:                 newStatsCollector(),
+		chatLocks:             &chatLocks{},
+		clock:                 systemClock{},
 		templateManager:       GetDefaultTemplateManager(),
 		middleware:            make([]MiddlewareFunc, 0),
 		flowConfig: FlowConfig{
@@ -101,16 +166,27 @@ func newBotInternal(client TelegramClient, botUser tgbotapi.User, options ...Bot
 		},
 	}
 
+	b.sender = &hookedClient{next: client, bot: b}
+
 	msgHandler := newMessageHandler(b.templateManager)
 	imageHandler := newImageHandler()
-	b.promptComposer = newPromptComposer(b.api, msgHandler, imageHandler, b.promptKeyboardHandler.(*PromptKeyboardHandler))
+	b.promptComposer = newPromptComposer(b.sender, msgHandler, imageHandler, b.promptKeyboardHandler.(*PromptKeyboardHandler))
 
+	if api, ok := client.(*tgbotapi.BotAPI); ok {
+		b.secrets.literals = []string{api.Token}
+	}
 	for _, opt := range options {
 		opt(b)
 	}
+	msgHandler.onExposure = b.exposureHook
+	if b.dryRun != nil {
+		b.sender.next = newDryRunClient(client, b.dryRun)
+	}
 
 	// Initialize flowManager with its new dependencies
 	b.flowManager = newFlowManager(&b.flowConfig, b.promptComposer, b.promptKeyboardHandler, b)
+	b.flowManager.clock = b.clock
+	b.flowManager.featureGate = b.featureGate
 	return b, nil
 }
 
@@ -128,19 +204,35 @@ func newBotInternal(client TelegramClient, botUser tgbotapi.User, options ...Bot
 //		log.Fatal(err)
 //	}
 func NewBot(token string, options ...BotOption) (*Bot, error) {
-	realAPI, err := tgbotapi.NewBotAPI(token)
+	scrubber := secretScrubber{literals: []string{token}}
+	realAPI, err := newBotAPI(token, options...)
 	if err != nil {
-		return nil, err
+		return nil, scrubber.scrubError(err)
 	}
 
 	botUser, err := realAPI.GetMe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get bot info: %w", err)
+		return nil, scrubber.scrubError(fmt.Errorf("failed to get bot info: %w", err))
 	}
 
 	return newBotInternal(realAPI, botUser, options...)
 }
 
+// NewBotWithClient creates a Bot that talks to Telegram through client instead of
+// connecting with a token. Use it with a custom TelegramClient, or with the fake
+// client of the teleflowtest package to test a bot without network access.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBotWithClient(client, teleflow.WithFlowConfig(config))
+func NewBotWithClient(client TelegramClient, options ...BotOption) (*Bot, error) {
+	botUser, err := client.GetMe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot info: %w", err)
+	}
+	return newBotInternal(client, botUser, options...)
+}
+
 // WithFlowConfig returns a BotOption that configures flow management behavior.
 // This option allows customization of exit commands, help commands, and flow processing options.
 //
@@ -189,13 +281,20 @@ func (b *Bot) UseMiddleware(m MiddlewareFunc) {
 // HandleCommand registers a handler for a specific Telegram command.
 // Commands are messages that start with "/" (e.g., "/start", "/help").
 // The handler receives the command name and any arguments that follow it.
+// The returned CommandRouter can be used to register subcommands; the handler
+// then only runs when no subcommand matches and may be nil.
 //
 // Example:
 //
 //	bot.HandleCommand("start", func(ctx *teleflow.Context, command, args string) error {
 //		return ctx.SendPromptText("Welcome! Arguments: " + args)
 //	})
-func (b *Bot) HandleCommand(commandName string, handler CommandHandlerFunc) {
+//
+//	bot.HandleCommand("admin", nil).
+//		Sub("users", listUsers).
+//		Sub("ban", banUser)
+func (b *Bot) HandleCommand(commandName string, handler CommandHandlerFunc) *CommandRouter {
+	router := newCommandRouter(commandName, "/"+commandName, handler)
 
 	wrappedHandler := func(ctx *Context) error {
 
@@ -204,9 +303,10 @@ func (b *Bot) HandleCommand(commandName string, handler CommandHandlerFunc) {
 		if ctx.update.Message != nil && len(ctx.update.Message.Text) > len(command)+1 {
 			args = ctx.update.Message.Text[len(command)+1:]
 		}
-		return handler(ctx, command, args)
+		return router.dispatch(ctx, command, args)
 	}
 	b.handlers[commandName] = b.applyMiddleware(wrappedHandler)
+	return router
 }
 
 // HandleText registers a handler for exact text message matches.
@@ -262,6 +362,52 @@ func (b *Bot) RegisterFlow(flow *Flow) {
 	b.flowManager.registerFlow(flow)
 }
 
+// StartFlowFor starts a flow for another user, e.g. from a support agent's command,
+// and sends the flow's first prompt to the given chat. Use the user's ID as chatID
+// for their private chat with the bot. data seeds the flow data and may be nil.
+// A flow the user is already in is replaced.
+//
+// StartFlowFor waits until no update of the target chat is being processed. It
+// must never be called for the chat of the update being handled: the
+// conversation lock held there is not reentrant and the call would deadlock.
+// Chats also share lock stripes, so from a handler, flow step or middleware
+// call it in its own goroutine.
+//
+// Example:
+//
+//	bot.HandleCommand("verify", func(ctx *teleflow.Context, command, args string) error {
+//		userID, _ := strconv.ParseInt(args, 10, 64)
+//		agent := ctx.UserID()
+//		go func() {
+//			if err := bot.StartFlowFor(userID, userID, "verification", map[string]interface{}{"agent": agent}); err != nil {
+//				log.Printf("verification for user %d not started: %v", userID, err)
+//			}
+//		}()
+//		return ctx.SendPromptText("Verification requested")
+//	})
+func (b *Bot) StartFlowFor(userID, chatID int64, flowName string, data map[string]interface{}) error {
+	ctx := b.contextFor(userID, chatID)
+	defer b.lockConversation(ctx)()
+	return b.flowManager.startFlowWith(userID, chatID, flowName, flowStartOptions{Data: data}, ctx)
+}
+
+// contextFor returns a context for acting on behalf of a user in a chat outside
+// of an update, as StartFlowFor and ResumeFlow do. The type of chats other than
+// the user's private chat is unknown and left empty; they count as groups when
+// their ID is negative, as the IDs of Telegram groups are.
+func (b *Bot) contextFor(userID, chatID int64) *Context {
+	chat := &tgbotapi.Chat{ID: chatID}
+	if chatID == userID {
+		chat.Type = "private"
+	}
+	update := tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: userID}, Chat: chat}}
+	ctx := newContext(update, b.sender, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+	ctx.telegramClient = b.sender.forContext(ctx)
+	ctx.clock = b.clock
+	ctx.isGroup = chatID < 0
+	return ctx
+}
+
 // GetPromptKeyboardHandler returns the bot's keyboard handler for advanced keyboard management.
 // This is typically used internally or for advanced use cases where direct keyboard manipulation is needed.
 func (b *Bot) GetPromptKeyboardHandler() PromptKeyboardActions {
@@ -282,85 +428,183 @@ func (b *Bot) applyMiddleware(handler HandlerFunc) HandlerFunc {
 // It manages flow state, applies global exit commands, and provides fallback error handling.
 // This method is called concurrently for each update, ensuring responsive bot behavior.
 func (b *Bot) processUpdate(update tgbotapi.Update) {
-	ctx := newContext(update, b.api, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+	b.processUpdateVia(update, "")
+}
+
+// processUpdateVia handles an update received through a business connection,
+// or through the bot's own chats if businessConnectionID is empty.
+func (b *Bot) processUpdateVia(update tgbotapi.Update, businessConnectionID string) {
+	var ctx *Context
+	if b.poolContexts {
+		ctx = acquireContext(update, b.sender, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+		defer releaseContext(ctx)
+	} else {
+		ctx = newContext(update, b.sender, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+	}
+	ctx.telegramClient = b.sender.forContext(ctx)
+	ctx.clock = b.clock
+	ctx.businessConnectionID = businessConnectionID
+	if r := b.recorder.Load(); r != nil {
+		r.recordUpdate(update)
+	}
+	defer b.lockConversation(ctx)()
 	var err error
 
+	// New group members must pass the captcha before anything else
+	if b.captcha != nil && update.Message != nil && len(update.Message.NewChatMembers) > 0 {
+		b.handleNewChatMembers(ctx)
+		return
+	}
+
+	// Messages over a flood limit are dropped before flows and handlers see them
+	if b.flood != nil && !b.flood.allow(ctx) {
+		return
+	}
+
+	// Chosen inline results belong to no chat and never reach flows
+	if update.ChosenInlineResult != nil {
+		b.handleChosenInlineResult(ctx)
+		return
+	}
+
+	// Payments are confirmed and recorded before flows see them
+	if update.PreCheckoutQuery != nil {
+		b.handlePreCheckout(ctx)
+		return
+	}
+	if update.ShippingQuery != nil {
+		b.handleShippingQuery(ctx)
+		return
+	}
+	if update.Message != nil && update.Message.SuccessfulPayment != nil {
+		b.handleSuccessfulPayment(ctx)
+	}
+	if update.Message != nil && update.Message.PassportData != nil {
+		b.handlePassportData(ctx)
+	}
+
 	// 1. Handle flow-related logic: exit commands, global commands within flows
 	if b.handleFlowPreProcessing(ctx) {
 		return // Pre-processing handled the update (e.g., exit command)
 	}
 
 	// 2. Attempt to handle the update via the flow manager
+	flowStart := time.Now()
+	flowStatsKey, flowName, stepName := "", "", ""
+	if state := b.flowManager.currentState(ctx.UserID(), ctx.ChatID()); state != nil {
+		flowName, stepName = state.FlowName, state.CurrentStep
+		flowStatsKey = "flow:" + flowName + "/" + stepName
+	}
 	if handledByFlow, flowErr := b.flowManager.HandleUpdate(ctx); handledByFlow {
+		if flowStatsKey != "" {
+			b.stats.record(flowStatsKey, time.Since(flowStart), flowErr)
+		}
 		if flowErr != nil {
 			log.Printf("Flow handler error for UserID %d: %v", ctx.UserID(), flowErr)
+			b.reportFlowError(ctx, flowErr, flowName, stepName)
+			b.deadLetter(ctx, flowErr, 1)
 		}
 		return // Flow manager handled the update
 	}
 
-	// 3. Handle regular messages (commands or text) if not handled by flow
-	if update.Message != nil {
-		err = b.handleMessage(ctx, update.Message)
-	} else if update.CallbackQuery != nil {
-		// 4. Handle callback queries
-		err = b.handleCallbackQuery(ctx)
+	handle := func() error {
+		// 3. Handle regular messages (commands or text) if not handled by flow
+		if update.Message != nil {
+			return b.handleMessage(ctx, update.Message)
+		} else if update.CallbackQuery != nil {
+			// 4. Handle callback queries
+			return b.handleCallbackQuery(ctx)
+		}
+		return nil
+	}
+	err = handle()
+	attempts := 1
+	for ; err != nil && attempts <= b.handlerRetries() && !isFlowRefusal(err); attempts++ {
+		err = handle()
 	}
 
 	// 5. Common error handling for non-flow related errors
 	if err != nil {
+		err = b.secrets.scrubError(err)
 		b.handleProcessingError(ctx, err)
+		b.deadLetter(ctx, err, attempts)
 	}
 }
 
 // handleFlowPreProcessing checks for global exit commands or global commands within a flow.
 // It returns true if the update was handled (e.g., an exit command was processed), otherwise false.
 func (b *Bot) handleFlowPreProcessing(ctx *Context) bool {
-	if !b.flowManager.isUserInFlow(ctx.UserID()) {
+	if !b.flowManager.isUserInFlow(ctx.UserID(), ctx.ChatID()) {
 		return false // Not in a flow, nothing to pre-process here
 	}
 
 	if ctx.update.Message != nil {
-		// Check for global exit command
-		if b.isGlobalExitCommand(ctx.update.Message.Text) {
-			b.flowManager.cancelFlow(ctx.UserID())
-			if err := ctx.sendSimpleText(b.flowConfig.ExitMessage); err != nil {
+		// Check for the flow's exit commands
+		exitCommands, exitMessage := b.flowExit(ctx)
+		if isExitCommand(ctx.update.Message.Text, exitCommands) {
+			b.flowManager.cancelFlowWithReason(ctx.UserID(), ctx.ChatID(), ctx, CancelReasonExitCommand)
+			if err := ctx.sendSimpleText(exitMessage); err != nil {
 				log.Printf("Error sending flow exit message: %v", err)
 			}
 			return true // Update handled
 		}
 
 		// Check for allowed global commands during a flow
-		if b.flowConfig.AllowGlobalCommands && ctx.update.Message.IsCommand() {
+		if ctx.update.Message.IsCommand() {
 			commandName := ctx.update.Message.Command()
-			if cmdHandler := b.resolveGlobalCommandHandler(commandName); cmdHandler != nil {
+			if cmdHandler := b.resolveGlobalCommandHandler(ctx, commandName); cmdHandler != nil {
+				if paused := b.pauseForInterruption(ctx); paused != nil {
+					defer func() {
+						if err := b.flowManager.resumeFlow(ctx, paused); err != nil {
+							log.Printf("Error resuming flow for UserID %d after command '%s': %v", ctx.UserID(), commandName, err)
+						}
+					}()
+				}
 				if err := cmdHandler(ctx); err != nil {
 					log.Printf("Global command handler error for UserID %d, command '%s': %v", ctx.UserID(), commandName, err)
 				}
 				return true // Update handled
 			}
 		}
+
+		// Other help commands are answered with help for the current step
+		if b.isHelpCommand(ctx.update.Message) {
+			b.flowManager.sendFlowHelp(ctx, exitCommands)
+			return true // Update handled
+		}
 	}
 	return false // Update not handled by pre-processing
 }
 
 // handleMessage processes regular command and text messages.
 func (b *Bot) handleMessage(ctx *Context, message *tgbotapi.Message) error {
+	if message.SuccessfulPayment != nil {
+		return nil // Handled by the payment handler
+	}
+	if message.PassportData != nil {
+		return nil // Handled by the passport handler
+	}
 	if message.IsCommand() {
 		commandName := message.Command()
-		if cmdHandler, ok := b.handlers[commandName]; ok {
-			return cmdHandler(ctx)
+		if commandName == "start" {
+			if handled, err := b.handleDeepLink(ctx, message.CommandArguments()); handled {
+				return err
+			}
+		}
+		if cmdHandler, ok := b.handlers[commandName]; ok && featureEnabled(b.featureGate, "command:/"+commandName, ctx.UserID()) {
+			return b.stats.track("command:/"+commandName, func() error { return cmdHandler(ctx) })
 		}
 		// If command not found, fall through to default text handler if available
 	}
 
 	// Handle text messages or fallback for unhandled commands
 	text := message.Text
-	if textHandler, ok := b.textHandlers[text]; ok {
-		return textHandler(ctx)
+	if textHandler, ok := b.textHandlers[text]; ok && featureEnabled(b.featureGate, "text:"+text, ctx.UserID()) {
+		return b.stats.track("text:"+text, func() error { return textHandler(ctx) })
 	}
 
 	if b.defaultTextHandler != nil {
-		return b.defaultTextHandler(ctx)
+		return b.stats.track("default", func() error { return b.defaultTextHandler(ctx) })
 	}
 	return nil // No handler found
 }
@@ -389,15 +633,35 @@ func (b *Bot) handleCallbackQuery(ctx *Context) error {
 // handleProcessingError logs errors from handlers and sends a generic error message to the user.
 func (b *Bot) handleProcessingError(ctx *Context, err error) {
 	log.Printf("Handler error for UserID %d: %v", ctx.UserID(), err)
+	if isFlowRefusal(err) {
+		return // The user has already been shown the denial or limit prompt
+	}
 	if replyErr := ctx.sendSimpleText("An error occurred. Please try again."); replyErr != nil {
 		log.Printf("Failed to send error reply to UserID %d: %v", ctx.UserID(), replyErr)
 	}
 }
 
-// isGlobalExitCommand checks if the given text matches any configured exit command.
+// flowExit returns the exit commands and exit message that apply to the flow the
+// user is in: the flow's own settings, else those of the FlowConfig.
+func (b *Bot) flowExit(ctx *Context) ([]string, string) {
+	commands, message := b.flowConfig.ExitCommands, b.flowConfig.ExitMessage
+	if flowName, _, ok := b.flowManager.currentStep(ctx.UserID(), ctx.ChatID()); ok {
+		if flow := b.flowManager.flows[flowName]; flow != nil {
+			if flow.ExitCommands != nil {
+				commands = flow.ExitCommands
+			}
+			if flow.ExitMessage != "" {
+				message = flow.ExitMessage
+			}
+		}
+	}
+	return commands, message
+}
+
+// isExitCommand checks if the given text matches one of the exit commands.
 // Exit commands allow users to cancel flows regardless of the current flow state.
-func (b *Bot) isGlobalExitCommand(text string) bool {
-	for _, cmd := range b.flowConfig.ExitCommands {
+func isExitCommand(text string, commands []string) bool {
+	for _, cmd := range commands {
 		if text == cmd {
 			return true
 		}
@@ -405,17 +669,32 @@ func (b *Bot) isGlobalExitCommand(text string) bool {
 	return false
 }
 
-// resolveGlobalCommandHandler finds a handler for commands that should be available globally,
-// even when a user is in a flow. Currently supports help commands as defined in FlowConfig.
-func (b *Bot) resolveGlobalCommandHandler(commandName string) HandlerFunc {
+// resolveGlobalCommandHandler finds the handler of a command that may run while the
+// user is in a flow. The flow's GlobalCommands, else FlowConfig.GlobalCommandWhitelist,
+// names the allowed commands; without either, AllowGlobalCommands lets only the
+// handlers of the help commands run.
+func (b *Bot) resolveGlobalCommandHandler(ctx *Context, commandName string) HandlerFunc {
+	handler, ok := b.handlers[commandName]
+	if !ok || !featureEnabled(b.featureGate, "command:/"+commandName, ctx.UserID()) {
+		return nil
+	}
 
-	for _, helpCmd := range b.flowConfig.HelpCommands {
+	whitelist := b.flowConfig.GlobalCommandWhitelist
+	if flowName, _, inFlow := b.flowManager.currentStep(ctx.UserID(), ctx.ChatID()); inFlow {
+		if flow := b.flowManager.flows[flowName]; flow != nil && flow.GlobalCommands != nil {
+			whitelist = flow.GlobalCommands
+		}
+	}
+	if whitelist == nil {
+		if b.flowConfig.AllowGlobalCommands && b.isHelpCommand(ctx.update.Message) {
+			return handler
+		}
+		return nil
+	}
 
-		normalizedCmd := "/" + commandName
-		if normalizedCmd == helpCmd || commandName == helpCmd {
-			if handler, ok := b.handlers[commandName]; ok {
-				return handler
-			}
+	for _, allowed := range whitelist {
+		if "/"+commandName == allowed || commandName == allowed {
+			return handler
 		}
 	}
 	return nil
@@ -423,7 +702,9 @@ func (b *Bot) resolveGlobalCommandHandler(commandName string) HandlerFunc {
 
 // SetBotCommands configures the bot's command menu that appears in Telegram clients.
 // This creates the command list that users see when typing "/" in a chat with the bot.
-// Pass an empty map to clear all commands.
+// Pass an empty map to clear all commands. Options restrict the list to a scope
+// (e.g. group administrators) or a user language; without options the default
+// list is set.
 //
 // Example:
 //
@@ -433,14 +714,29 @@ func (b *Bot) resolveGlobalCommandHandler(commandName string) HandlerFunc {
 //		"settings": "Configure bot settings",
 //	}
 //	err := bot.SetBotCommands(commands)
-func (b *Bot) SetBotCommands(commands map[string]string) error {
+//
+//	err = bot.SetBotCommands(map[string]string{"ban": "Ban a user"},
+//		teleflow.WithCommandScope(teleflow.ScopeAllChatAdministrators()),
+//		teleflow.WithCommandLanguage("en"),
+//	)
+func (b *Bot) SetBotCommands(commands map[string]string, options ...CommandsOption) error {
 	if b.api == nil {
 		return fmt.Errorf("bot API not initialized")
 	}
+
+	var target commandsTarget
+	for _, opt := range options {
+		opt(&target)
+	}
+	var scope *tgbotapi.BotCommandScope
+	if target.scope != nil {
+		scope = target.scope.toTgbotapi()
+	}
+
 	if len(commands) == 0 {
 
-		clearCmdCfg := tgbotapi.NewSetMyCommands()
-		_, err := b.api.Request(clearCmdCfg)
+		clearCmdCfg := tgbotapi.DeleteMyCommandsConfig{Scope: scope, LanguageCode: target.languageCode}
+		_, err := b.sender.Request(clearCmdCfg)
 		if err != nil {
 			log.Printf("Warning: Failed to clear bot commands: %v", err)
 			return fmt.Errorf("failed to clear bot commands: %w", err)
@@ -449,12 +745,12 @@ func (b *Bot) SetBotCommands(commands map[string]string) error {
 		return nil
 	}
 
-	var tgCommands []tgbotapi.BotCommand
-	for cmd, desc := range commands {
-		tgCommands = append(tgCommands, tgbotapi.BotCommand{Command: cmd, Description: desc})
+	cmdCfg := tgbotapi.SetMyCommandsConfig{
+		Commands:     sortedBotCommands(commands),
+		Scope:        scope,
+		LanguageCode: target.languageCode,
 	}
-	cmdCfg := tgbotapi.NewSetMyCommands(tgCommands...)
-	_, err := b.api.Request(cmdCfg)
+	_, err := b.sender.Request(cmdCfg)
 	if err != nil {
 		log.Printf("Warning: Failed to set bot commands: %v", err)
 		return fmt.Errorf("failed to set bot commands: %w", err)
@@ -471,10 +767,54 @@ func (b *Bot) SetBotCommands(commands map[string]string) error {
 //	err := bot.DeleteMessage(ctx, messageID)
 func (b *Bot) DeleteMessage(ctx *Context, messageID int) error {
 	deleteMsg := tgbotapi.NewDeleteMessage(ctx.ChatID(), messageID)
-	_, err := b.api.Request(deleteMsg)
+	_, err := clientFor(b.sender, ctx).Request(deleteMsg)
 	return err
 }
 
+// DeleteMessages deletes several messages of the context's chat, using Telegram's
+// deleteMessages batch method where possible.
+//
+// Example:
+//
+//	err := bot.DeleteMessages(ctx, []int{firstID, secondID})
+func (b *Bot) DeleteMessages(ctx *Context, messageIDs []int) error {
+	return deleteMessages(clientFor(b.sender, ctx), ctx.ChatID(), messageIDs)
+}
+
+// maxDeleteMessages is the number of messages a single deleteMessages call accepts.
+const maxDeleteMessages = 100
+
+// deleteMessages deletes messageIDs from chatID in batches. A lone message is
+// deleted with deleteMessage. If a batch call fails, for example because the
+// client cannot make raw requests, its messages are deleted one at a time.
+func deleteMessages(client TelegramClient, chatID int64, messageIDs []int) error {
+	if len(messageIDs) == 1 {
+		_, err := client.Request(tgbotapi.NewDeleteMessage(chatID, messageIDs[0]))
+		return err
+	}
+
+	var errs []error
+	for start := 0; start < len(messageIDs); start += maxDeleteMessages {
+		batch := messageIDs[start:min(start+maxDeleteMessages, len(messageIDs))]
+
+		params := tgbotapi.Params{}
+		params.AddNonZero64("chat_id", chatID)
+		if err := params.AddInterface("message_ids", batch); err != nil {
+			return fmt.Errorf("failed to encode message IDs: %w", err)
+		}
+		if _, err := makeRawRequest(client, "deleteMessages", params); err == nil {
+			continue
+		}
+
+		for _, messageID := range batch {
+			if _, err := client.Request(tgbotapi.NewDeleteMessage(chatID, messageID)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // EditMessageReplyMarkup edits the reply markup (inline keyboard) of a specific message.
 // This allows dynamic updating of message keyboards without resending the entire message.
 // To remove a keyboard completely, pass nil for replyMarkup.
@@ -508,7 +848,7 @@ func (b *Bot) EditMessageReplyMarkup(ctx *Context, messageID int, replyMarkup in
 		editMsg = tgbotapi.NewEditMessageReplyMarkup(ctx.ChatID(), messageID, keyboard)
 	}
 
-	_, err := b.api.Request(editMsg)
+	_, err := clientFor(b.sender, ctx).Request(editMsg)
 	return err
 }
 
@@ -532,13 +872,74 @@ func (b *Bot) EditMessageReplyMarkup(ctx *Context, messageID int, replyMarkup in
 func (b *Bot) Start() error {
 	log.Printf("Authorized on account %s", b.self.UserName)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := b.api.GetUpdatesChan(u)
+	b.applyStartupMenuButton()
 
-	for update := range updates {
-		go b.processUpdate(update)
+	offset, err := b.startOffset()
+	if err != nil {
+		return err
+	}
+	var offsets *offsetTracker
+	if store := b.updatesConfig.offsets; store != nil {
+		offsets = newOffsetTracker(store, offset)
 	}
 
+	var updates tgbotapi.UpdatesChannel
+	if b.updatesConfig.poller != nil {
+		updates = b.pollUpdates(offset)
+	} else {
+		u := tgbotapi.NewUpdate(offset)
+		u.Timeout = b.apiConfig.pollTimeout()
+		u.AllowedUpdates = b.updatesConfig.allowedUpdates
+		updates = b.api.GetUpdatesChan(u)
+	}
+
+	b.runUpdateLoop(updates, offsets)
 	return nil
 }
+
+// runUpdateLoop dispatches updates from the channel concurrently until it is
+// closed, reporting their progress to offsets if it is not nil. With an update
+// queue it returns once the queued updates are processed.
+func (b *Bot) runUpdateLoop(updates tgbotapi.UpdatesChannel, offsets *offsetTracker) {
+	b.updatesMu.Lock()
+	b.updates = updates
+	b.updatesMu.Unlock()
+	b.startedAt.Store(b.clock.Now().UnixNano())
+	b.startFlowSweeper()
+	b.polling.Store(true)
+	defer b.polling.Store(false)
+
+	done := func(update tgbotapi.Update) {
+		if offsets != nil {
+			offsets.done(update.UpdateID)
+		}
+	}
+	handle := func(update tgbotapi.Update) {
+		b.ProcessExternalUpdate(update)
+		done(update)
+	}
+
+	var queue *updateQueue
+	if config := b.updatesConfig.queue; config != nil {
+		queue = b.startUpdateQueue(*config, handle, done)
+		b.updatesMu.Lock()
+		b.queue = queue
+		b.updatesMu.Unlock()
+		defer queue.close()
+	}
+
+	for update := range updates {
+		if offsets != nil {
+			offsets.received(update.UpdateID)
+		}
+		if queue != nil {
+			queue.push(update)
+			continue
+		}
+		b.activeHandlers.Add(1)
+		go func(update tgbotapi.Update) {
+			defer b.activeHandlers.Add(-1)
+			handle(update)
+		}(update)
+	}
+}
@@ -0,0 +1,163 @@
+package teleflow
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// apiClientConfig collects the options that affect how NewBot connects to the Bot API.
+type apiClientConfig struct {
+	httpClient *http.Client  // Client supplied with WithHTTPClient
+	proxyURL   string        // Proxy supplied with WithProxy
+	timeout    time.Duration // Per-request timeout supplied with WithAPITimeout
+	serverURL  string        // Bot API server supplied with WithAPIEndpoint
+	testEnv    bool          // Use Telegram's test environment (WithTestEnvironment)
+}
+
+// WithHTTPClient returns a BotOption that makes the bot use client for all Bot API
+// requests, e.g. to add custom TLS settings or instrumentation. WithProxy and
+// WithAPITimeout are applied on top of a copy of the client.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithHTTPClient(&http.Client{Transport: myTransport}))
+func WithHTTPClient(client *http.Client) BotOption {
+	return func(b *Bot) {
+		b.apiConfig.httpClient = client
+	}
+}
+
+// WithProxy returns a BotOption that routes Bot API requests through an HTTP,
+// HTTPS or SOCKS5 proxy. An invalid URL makes NewBot fail.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithProxy("http://proxy.corp.example:3128"))
+func WithProxy(proxyURL string) BotOption {
+	return func(b *Bot) {
+		b.apiConfig.proxyURL = proxyURL
+	}
+}
+
+// WithAPITimeout returns a BotOption that limits how long a single Bot API request
+// may take. Long polling in Start adapts its server-side wait to stay below it.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithAPITimeout(15*time.Second))
+func WithAPITimeout(timeout time.Duration) BotOption {
+	return func(b *Bot) {
+		b.apiConfig.timeout = timeout
+	}
+}
+
+// WithAPIEndpoint returns a BotOption that sends Bot API requests to a different
+// server, typically a self-hosted telegram-bot-api instance which allows uploads
+// of up to 2 GB. Pass the server's base URL; a full endpoint format containing
+// two %s verbs (token and method) is accepted as well.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithAPIEndpoint("http://localhost:8081"))
+func WithAPIEndpoint(serverURL string) BotOption {
+	return func(b *Bot) {
+		b.apiConfig.serverURL = serverURL
+	}
+}
+
+// WithTestEnvironment returns a BotOption that talks to Telegram's test
+// environment, whose methods live under the /test path. Test environment bots
+// and accounts are separate from production ones and need their own token.
+// It can be combined with WithAPIEndpoint when given a base URL.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(os.Getenv("TEST_BOT_TOKEN"), teleflow.WithTestEnvironment())
+func WithTestEnvironment() BotOption {
+	return func(b *Bot) {
+		b.apiConfig.testEnv = true
+	}
+}
+
+// apiEndpoint returns the endpoint format passed to telegram-bot-api.
+func (cfg apiClientConfig) apiEndpoint() string {
+	if strings.Count(cfg.serverURL, "%s") == 2 {
+		return cfg.serverURL
+	}
+
+	base := "https://api.telegram.org"
+	if cfg.serverURL != "" {
+		base = strings.TrimRight(cfg.serverURL, "/")
+	}
+	if cfg.testEnv {
+		return base + "/bot%s/test/%s"
+	}
+	return base + "/bot%s/%s"
+}
+
+// buildHTTPClient creates the HTTP client described by the configuration.
+func (cfg apiClientConfig) buildHTTPClient() (*http.Client, error) {
+	client := &http.Client{}
+	if cfg.httpClient != nil {
+		clone := *cfg.httpClient
+		client = &clone
+	}
+
+	if cfg.timeout > 0 {
+		client.Timeout = cfg.timeout
+	}
+
+	if cfg.proxyURL != "" {
+		proxy, err := url.Parse(cfg.proxyURL)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.proxyURL)
+		}
+
+		var transport *http.Transport
+		switch t := client.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		default:
+			return nil, fmt.Errorf("cannot set a proxy on a custom %T transport", t)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+		client.Transport = transport
+	}
+
+	return client, nil
+}
+
+// pollTimeout returns the long-polling wait in seconds that fits within the API timeout.
+func (cfg apiClientConfig) pollTimeout() int {
+	const defaultPollTimeout = 60
+	if cfg.timeout <= 0 || cfg.timeout > defaultPollTimeout*time.Second+5*time.Second {
+		return defaultPollTimeout
+	}
+	seconds := int((cfg.timeout - 5*time.Second) / time.Second)
+	if seconds < 0 {
+		seconds = 0
+	}
+	return seconds
+}
+
+// newBotAPI connects to the Bot API using the connection options in options.
+func newBotAPI(token string, options ...BotOption) (*tgbotapi.BotAPI, error) {
+	// Options only assign fields, so applying them to a scratch Bot is side-effect free
+	probe := &Bot{}
+	for _, opt := range options {
+		opt(probe)
+	}
+
+	client, err := probe.apiConfig.buildHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return tgbotapi.NewBotAPIWithClient(token, probe.apiConfig.apiEndpoint(), client)
+}
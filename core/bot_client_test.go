@@ -0,0 +1,114 @@
+package teleflow
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestAPIClientConfig_BuildHTTPClient(t *testing.T) {
+	bot := &Bot{}
+	WithProxy("http://proxy.example:3128")(bot)
+	WithAPITimeout(15 * time.Second)(bot)
+
+	client, err := bot.apiConfig.buildHTTPClient()
+	if err != nil {
+		t.Fatalf("buildHTTPClient failed: %v", err)
+	}
+	if client.Timeout != 15*time.Second {
+		t.Errorf("Expected 15s timeout, got %v", client.Timeout)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", client.Transport)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.telegram.org", nil)
+	proxy, _ := transport.Proxy(req)
+	if proxy == nil || proxy.Host != "proxy.example:3128" {
+		t.Errorf("Expected proxy to be configured, got %v", proxy)
+	}
+}
+
+func TestAPIClientConfig_CustomClient(t *testing.T) {
+	custom := &http.Client{Timeout: time.Minute}
+	cfg := apiClientConfig{httpClient: custom, timeout: 5 * time.Second}
+
+	client, err := cfg.buildHTTPClient()
+	if err != nil {
+		t.Fatalf("buildHTTPClient failed: %v", err)
+	}
+	if client == custom || custom.Timeout != time.Minute {
+		t.Error("Expected the supplied client to be copied, not modified")
+	}
+	if client.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout override, got %v", client.Timeout)
+	}
+
+	cfg = apiClientConfig{
+		httpClient: &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) { return nil, nil })},
+		proxyURL:   "http://proxy.example:3128",
+	}
+	if _, err := cfg.buildHTTPClient(); err == nil {
+		t.Error("Expected error when combining a proxy with a custom transport")
+	}
+
+	if _, err := (apiClientConfig{proxyURL: "not a url"}).buildHTTPClient(); err == nil {
+		t.Error("Expected error for an invalid proxy URL")
+	}
+}
+
+func TestAPIClientConfig_PollTimeout(t *testing.T) {
+	tests := []struct {
+		timeout  time.Duration
+		expected int
+	}{
+		{0, 60},
+		{2 * time.Minute, 60},
+		{30 * time.Second, 25},
+		{3 * time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		if got := (apiClientConfig{timeout: tt.timeout}).pollTimeout(); got != tt.expected {
+			t.Errorf("pollTimeout(%v) = %d, expected %d", tt.timeout, got, tt.expected)
+		}
+	}
+}
+
+func TestAPIClientConfig_APIEndpoint(t *testing.T) {
+	tests := []struct {
+		serverURL string
+		expected  string
+	}{
+		{"", "https://api.telegram.org/bot%s/%s"},
+		{"http://localhost:8081", "http://localhost:8081/bot%s/%s"},
+		{"http://localhost:8081/", "http://localhost:8081/bot%s/%s"},
+		{"http://gateway/tg/bot%s/%s", "http://gateway/tg/bot%s/%s"},
+	}
+
+	for _, tt := range tests {
+		bot := &Bot{}
+		WithAPIEndpoint(tt.serverURL)(bot)
+		if got := bot.apiConfig.apiEndpoint(); got != tt.expected {
+			t.Errorf("apiEndpoint(%q) = %q, expected %q", tt.serverURL, got, tt.expected)
+		}
+	}
+}
+
+func TestAPIClientConfig_TestEnvironment(t *testing.T) {
+	bot := &Bot{}
+	WithTestEnvironment()(bot)
+	if got := bot.apiConfig.apiEndpoint(); got != "https://api.telegram.org/bot%s/test/%s" {
+		t.Errorf("Unexpected test endpoint: %q", got)
+	}
+
+	WithAPIEndpoint("http://localhost:8081")(bot)
+	if got := bot.apiConfig.apiEndpoint(); got != "http://localhost:8081/bot%s/test/%s" {
+		t.Errorf("Unexpected local test endpoint: %q", got)
+	}
+}
@@ -0,0 +1,125 @@
+package teleflow
+
+import (
+	"sort"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BotCommandScope selects which users see a command list set with SetBotCommands.
+// Use the Scope* constructors to create one.
+type BotCommandScope struct {
+	Type   string // Telegram scope type, e.g. "all_group_chats"
+	ChatID int64  // Target chat for chat-specific scopes
+	UserID int64  // Target user for the chat_member scope
+}
+
+// ScopeDefault applies commands to all users without a more specific scope.
+func ScopeDefault() BotCommandScope {
+	return BotCommandScope{Type: "default"}
+}
+
+// ScopeAllPrivateChats applies commands to all private chats.
+func ScopeAllPrivateChats() BotCommandScope {
+	return BotCommandScope{Type: "all_private_chats"}
+}
+
+// ScopeAllGroupChats applies commands to all group and supergroup chats.
+func ScopeAllGroupChats() BotCommandScope {
+	return BotCommandScope{Type: "all_group_chats"}
+}
+
+// ScopeAllChatAdministrators applies commands to administrators of all groups.
+func ScopeAllChatAdministrators() BotCommandScope {
+	return BotCommandScope{Type: "all_chat_administrators"}
+}
+
+// ScopeChat applies commands to a specific chat.
+func ScopeChat(chatID int64) BotCommandScope {
+	return BotCommandScope{Type: "chat", ChatID: chatID}
+}
+
+// ScopeChatAdministrators applies commands to the administrators of a specific chat.
+func ScopeChatAdministrators(chatID int64) BotCommandScope {
+	return BotCommandScope{Type: "chat_administrators", ChatID: chatID}
+}
+
+// ScopeChatMember applies commands to a single member of a specific chat.
+func ScopeChatMember(chatID, userID int64) BotCommandScope {
+	return BotCommandScope{Type: "chat_member", ChatID: chatID, UserID: userID}
+}
+
+// toTgbotapi converts the scope into the telegram-bot-api representation.
+func (s BotCommandScope) toTgbotapi() *tgbotapi.BotCommandScope {
+	return &tgbotapi.BotCommandScope{Type: s.Type, ChatID: s.ChatID, UserID: s.UserID}
+}
+
+// CommandsOption configures the audience of a SetBotCommands call.
+type CommandsOption func(*commandsTarget)
+
+// commandsTarget holds the scope and language a command list applies to.
+type commandsTarget struct {
+	scope        *BotCommandScope
+	languageCode string
+}
+
+// WithCommandScope limits a command list to the given scope.
+//
+// Example:
+//
+//	bot.SetBotCommands(adminCommands, teleflow.WithCommandScope(teleflow.ScopeAllChatAdministrators()))
+func WithCommandScope(scope BotCommandScope) CommandsOption {
+	return func(t *commandsTarget) {
+		t.scope = &scope
+	}
+}
+
+// WithCommandLanguage limits a command list to users with the given IETF
+// language code (e.g. "de"). Users whose language has no dedicated list see
+// the list set without a language.
+//
+// Example:
+//
+//	bot.SetBotCommands(germanCommands, teleflow.WithCommandLanguage("de"))
+func WithCommandLanguage(languageCode string) CommandsOption {
+	return func(t *commandsTarget) {
+		t.languageCode = languageCode
+	}
+}
+
+// SetLocalizedBotCommands sets one command list per language code. The entry
+// under the empty language code is used as the fallback for all other languages.
+// Options such as WithCommandScope apply to every list.
+//
+// Example:
+//
+//	err := bot.SetLocalizedBotCommands(map[string]map[string]string{
+//		"":   {"start": "Start the bot", "help": "Show help"},
+//		"de": {"start": "Bot starten", "help": "Hilfe anzeigen"},
+//	}, teleflow.WithCommandScope(teleflow.ScopeAllPrivateChats()))
+func (b *Bot) SetLocalizedBotCommands(commandsByLanguage map[string]map[string]string, options ...CommandsOption) error {
+	languages := make([]string, 0, len(commandsByLanguage))
+	for lang := range commandsByLanguage {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+
+	for _, lang := range languages {
+		opts := append(append([]CommandsOption(nil), options...), WithCommandLanguage(lang))
+		if err := b.SetBotCommands(commandsByLanguage[lang], opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedBotCommands converts a command map into a list ordered by command name,
+// so the menu order is stable between restarts.
+func sortedBotCommands(commands map[string]string) []tgbotapi.BotCommand {
+	tgCommands := make([]tgbotapi.BotCommand, 0, len(commands))
+	for cmd, desc := range commands {
+		tgCommands = append(tgCommands, tgbotapi.BotCommand{Command: cmd, Description: desc})
+	}
+	sort.Slice(tgCommands, func(i, j int) bool { return tgCommands[i].Command < tgCommands[j].Command })
+	return tgCommands
+}
@@ -0,0 +1,71 @@
+package teleflow
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSetBotCommands_ScopeAndLanguage(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	err := bot.SetBotCommands(map[string]string{"start": "Start", "ban": "Ban a user"},
+		WithCommandScope(ScopeChatAdministrators(-100)),
+		WithCommandLanguage("de"),
+	)
+	if err != nil {
+		t.Fatalf("SetBotCommands failed: %v", err)
+	}
+
+	cfg, ok := mockClient.RequestCalls[0].(tgbotapi.SetMyCommandsConfig)
+	if !ok {
+		t.Fatalf("Expected SetMyCommandsConfig, got %T", mockClient.RequestCalls[0])
+	}
+	if cfg.Scope == nil || cfg.Scope.Type != "chat_administrators" || cfg.Scope.ChatID != -100 {
+		t.Errorf("Unexpected scope: %+v", cfg.Scope)
+	}
+	if cfg.LanguageCode != "de" {
+		t.Errorf("Expected language 'de', got %q", cfg.LanguageCode)
+	}
+	if len(cfg.Commands) != 2 || cfg.Commands[0].Command != "ban" || cfg.Commands[1].Command != "start" {
+		t.Errorf("Expected commands sorted by name, got %+v", cfg.Commands)
+	}
+}
+
+func TestSetBotCommands_ClearWithScope(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	if err := bot.SetBotCommands(nil, WithCommandScope(ScopeAllGroupChats())); err != nil {
+		t.Fatalf("SetBotCommands failed: %v", err)
+	}
+
+	cfg, ok := mockClient.RequestCalls[0].(tgbotapi.DeleteMyCommandsConfig)
+	if !ok {
+		t.Fatalf("Expected DeleteMyCommandsConfig, got %T", mockClient.RequestCalls[0])
+	}
+	if cfg.Scope == nil || cfg.Scope.Type != "all_group_chats" {
+		t.Errorf("Unexpected scope: %+v", cfg.Scope)
+	}
+}
+
+func TestSetLocalizedBotCommands(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	err := bot.SetLocalizedBotCommands(map[string]map[string]string{
+		"":   {"start": "Start the bot"},
+		"de": {"start": "Bot starten"},
+	}, WithCommandScope(ScopeAllPrivateChats()))
+	if err != nil {
+		t.Fatalf("SetLocalizedBotCommands failed: %v", err)
+	}
+
+	if len(mockClient.RequestCalls) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(mockClient.RequestCalls))
+	}
+	for i, lang := range []string{"", "de"} {
+		cfg := mockClient.RequestCalls[i].(tgbotapi.SetMyCommandsConfig)
+		if cfg.LanguageCode != lang || cfg.Scope == nil || cfg.Scope.Type != "all_private_chats" {
+			t.Errorf("Request %d: unexpected config %+v", i, cfg)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// BotPool runs several bots with different tokens that share the same logic,
+// e.g. white-label deployments of one bot under several brands. Shared setup
+// functions register flows, handlers and middleware on every bot in the pool;
+// each bot can then be customized individually. Templates registered with
+// AddTemplate are global and therefore shared automatically.
+type BotPool struct {
+	mu      sync.Mutex
+	options []BotOption // Options applied to every bot before its own options
+	setups  []func(*Bot)
+	bots    map[string]*Bot
+	order   []string
+
+	newBot func(token string, options ...BotOption) (*Bot, error) // Replaced in tests
+}
+
+// NewBotPool creates an empty pool. The options are applied to every bot added
+// with Add, before the bot's own options.
+//
+// Example:
+//
+//	pool := teleflow.NewBotPool(teleflow.WithFlowConfig(flowConfig))
+//	pool.Configure(func(b *teleflow.Bot) {
+//		b.UseMiddleware(teleflow.LoggingMiddleware())
+//		b.HandleCommand("start", handleStart)
+//		b.RegisterFlow(orderFlow)
+//	})
+//	pool.Add("brand_a", tokenA)
+//	brandB, _ := pool.Add("brand_b", tokenB, teleflow.WithAccessManager(brandBAccess))
+//	brandB.HandleCommand("about", aboutBrandB) // per-bot override
+//	log.Fatal(pool.Start())
+func NewBotPool(options ...BotOption) *BotPool {
+	return &BotPool{
+		options: options,
+		bots:    make(map[string]*Bot),
+		newBot:  NewBot,
+	}
+}
+
+// Configure registers a setup function that is run on every bot in the pool,
+// including bots already added. Setup functions run in registration order, so
+// register middleware before the handlers it should wrap.
+func (p *BotPool) Configure(setup func(b *Bot)) *BotPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.setups = append(p.setups, setup)
+	for _, name := range p.order {
+		setup(p.bots[name])
+	}
+	return p
+}
+
+// Add creates a bot for token, runs the shared setup functions on it and adds it
+// to the pool under name. The returned bot can be customized further; handlers
+// registered on it replace shared handlers with the same command or text.
+func (p *BotPool) Add(name, token string, options ...BotOption) (*Bot, error) {
+	allOptions := append(append([]BotOption(nil), p.options...), options...)
+	bot, err := p.newBot(token, allOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bot %q: %w", name, err)
+	}
+	if err := p.AddBot(name, bot); err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+// AddBot adds an existing bot to the pool and runs the shared setup functions on it.
+func (p *BotPool) AddBot(name string, bot *Bot) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.bots[name]; exists {
+		return fmt.Errorf("bot %q already exists in pool", name)
+	}
+	for _, setup := range p.setups {
+		setup(bot)
+	}
+	p.bots[name] = bot
+	p.order = append(p.order, name)
+	return nil
+}
+
+// Bot returns the bot registered under name, or nil.
+func (p *BotPool) Bot(name string) *Bot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.bots[name]
+}
+
+// Names returns the names of all bots in the pool in the order they were added.
+func (p *BotPool) Names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.order...)
+}
+
+// Start starts every bot in the pool and blocks until all of them have stopped.
+// It returns the errors of the bots that stopped with one.
+func (p *BotPool) Start() error {
+	p.mu.Lock()
+	names := append([]string(nil), p.order...)
+	bots := make([]*Bot, len(names))
+	for i, name := range names {
+		bots[i] = p.bots[name]
+	}
+	p.mu.Unlock()
+
+	if len(bots) == 0 {
+		return fmt.Errorf("bot pool is empty")
+	}
+
+	errs := make([]error, len(bots))
+	var wg sync.WaitGroup
+	for i, bot := range bots {
+		wg.Add(1)
+		go func(i int, bot *Bot) {
+			defer wg.Done()
+			if err := bot.Start(); err != nil {
+				errs[i] = fmt.Errorf("bot %q: %w", names[i], err)
+			}
+		}(i, bot)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
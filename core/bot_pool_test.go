@@ -0,0 +1,97 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func createTestBotPool() (*BotPool, map[string]*MockTelegramClient) {
+	clients := make(map[string]*MockTelegramClient)
+	pool := NewBotPool()
+	pool.newBot = func(token string, options ...BotOption) (*Bot, error) {
+		if token == "bad" {
+			return nil, errors.New("unauthorized")
+		}
+		bot, client, _, _ := createTestBot(options...)
+		clients[token] = client
+		return bot, nil
+	}
+	return pool, clients
+}
+
+func TestBotPool_SharedSetupAndOverrides(t *testing.T) {
+	pool, clients := createTestBotPool()
+
+	var calls []string
+	pool.Configure(func(b *Bot) {
+		b.HandleCommand("start", func(ctx *Context, command, args string) error {
+			calls = append(calls, "shared")
+			return nil
+		})
+	})
+
+	if _, err := pool.Add("a", "token-a"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	botB, err := pool.Add("b", "token-b")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	botB.HandleCommand("start", func(ctx *Context, command, args string) error {
+		calls = append(calls, "override")
+		return nil
+	})
+
+	// Setup registered after bots were added is applied to them as well
+	pool.Configure(func(b *Bot) { b.RegisterFlow(createTestFlow()) })
+
+	pool.Bot("a").processUpdate(createStartUpdate(""))
+	pool.Bot("b").processUpdate(createStartUpdate(""))
+	if len(calls) != 2 || calls[0] != "shared" || calls[1] != "override" {
+		t.Errorf("Expected shared then override handler, got %v", calls)
+	}
+
+	for _, name := range pool.Names() {
+		if _, ok := pool.Bot(name).flowManager.flows["test-flow"]; !ok {
+			t.Errorf("Expected bot %q to have the shared flow", name)
+		}
+	}
+	if len(clients) != 2 {
+		t.Errorf("Expected a client per token, got %d", len(clients))
+	}
+}
+
+func TestBotPool_Errors(t *testing.T) {
+	pool, _ := createTestBotPool()
+
+	if err := pool.Start(); err == nil {
+		t.Error("Expected error when starting an empty pool")
+	}
+	if _, err := pool.Add("bad", "bad"); err == nil {
+		t.Error("Expected error for a bot that cannot be created")
+	}
+	if _, err := pool.Add("a", "token-a"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := pool.Add("a", "token-a2"); err == nil {
+		t.Error("Expected error for a duplicate bot name")
+	}
+}
+
+func TestBotPool_Start(t *testing.T) {
+	pool, clients := createTestBotPool()
+	_, _ = pool.Add("a", "token-a")
+	_, _ = pool.Add("b", "token-b")
+
+	for _, client := range clients {
+		updates := make(chan tgbotapi.Update)
+		close(updates)
+		client.GetUpdatesChanFunc = func(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel { return updates }
+	}
+
+	if err := pool.Start(); err != nil {
+		t.Errorf("Expected all bots to stop cleanly, got %v", err)
+	}
+}
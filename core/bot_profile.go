@@ -0,0 +1,87 @@
+package teleflow
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BotProfile holds the texts shown on the bot's profile page.
+// Empty fields are left unchanged by SetBotProfile.
+type BotProfile struct {
+	Name             string // Bot name, 0-64 characters
+	Description      string // Shown in an empty chat with the bot, 0-512 characters
+	ShortDescription string // Shown on the profile page and in shared links, 0-120 characters
+}
+
+// SetMyName changes the bot's name for users with the given language code.
+// An empty language code sets the default name.
+//
+// Example:
+//
+//	err := bot.SetMyName("Pizza Bot", "")
+//	err = bot.SetMyName("Pizza-Bot", "de")
+func (b *Bot) SetMyName(name, languageCode string) error {
+	return b.setProfileText("setMyName", "name", name, languageCode)
+}
+
+// SetMyDescription changes the description shown in an empty chat with the bot
+// for users with the given language code. An empty language code sets the default.
+//
+// Example:
+//
+//	err := bot.SetMyDescription("Order pizza in three taps.", "")
+func (b *Bot) SetMyDescription(description, languageCode string) error {
+	return b.setProfileText("setMyDescription", "description", description, languageCode)
+}
+
+// SetMyShortDescription changes the short description shown on the bot's profile
+// page for users with the given language code. An empty language code sets the default.
+//
+// Example:
+//
+//	err := bot.SetMyShortDescription("Fast pizza ordering", "")
+func (b *Bot) SetMyShortDescription(shortDescription, languageCode string) error {
+	return b.setProfileText("setMyShortDescription", "short_description", shortDescription, languageCode)
+}
+
+// SetBotProfile applies all non-empty fields of profile for the given language code,
+// so the complete profile can be configured at startup.
+//
+// Example:
+//
+//	err := bot.SetBotProfile("", teleflow.BotProfile{
+//		Name:             "Pizza Bot",
+//		Description:      "Order pizza in three taps.",
+//		ShortDescription: "Fast pizza ordering",
+//	})
+func (b *Bot) SetBotProfile(languageCode string, profile BotProfile) error {
+	if profile.Name != "" {
+		if err := b.SetMyName(profile.Name, languageCode); err != nil {
+			return err
+		}
+	}
+	if profile.Description != "" {
+		if err := b.SetMyDescription(profile.Description, languageCode); err != nil {
+			return err
+		}
+	}
+	if profile.ShortDescription != "" {
+		if err := b.SetMyShortDescription(profile.ShortDescription, languageCode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setProfileText calls one of the setMy* profile methods.
+func (b *Bot) setProfileText(endpoint, field, value, languageCode string) error {
+	params := tgbotapi.Params{}
+	params.AddNonEmpty(field, value)
+	params.AddNonEmpty("language_code", languageCode)
+
+	if _, err := makeRawRequest(b.sender, endpoint, params); err != nil {
+		return fmt.Errorf("failed to %s: %w", endpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package teleflow
+
+import "testing"
+
+func TestSetBotProfile(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	err := bot.SetBotProfile("de", BotProfile{
+		Name:             "Pizza-Bot",
+		ShortDescription: "Schnell Pizza bestellen",
+	})
+	if err != nil {
+		t.Fatalf("SetBotProfile failed: %v", err)
+	}
+
+	calls := mockClient.MakeRequestCalls
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 requests for non-empty fields, got %d", len(calls))
+	}
+	if calls[0].Endpoint != "setMyName" || calls[0].Params["name"] != "Pizza-Bot" || calls[0].Params["language_code"] != "de" {
+		t.Errorf("Unexpected name request: %+v", calls[0])
+	}
+	if calls[1].Endpoint != "setMyShortDescription" || calls[1].Params["short_description"] != "Schnell Pizza bestellen" {
+		t.Errorf("Unexpected short description request: %+v", calls[1])
+	}
+}
+
+func TestSetMyDescription_DefaultLanguage(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	if err := bot.SetMyDescription("Order pizza", ""); err != nil {
+		t.Fatalf("SetMyDescription failed: %v", err)
+	}
+
+	call := mockClient.MakeRequestCalls[0]
+	if call.Endpoint != "setMyDescription" || call.Params["description"] != "Order pizza" {
+		t.Errorf("Unexpected request: %+v", call)
+	}
+	if _, ok := call.Params["language_code"]; ok {
+		t.Error("Expected no language_code for the default description")
+	}
+}
@@ -18,6 +18,14 @@ type MockTelegramClient struct {
 	GetUpdatesChanCalls []tgbotapi.UpdateConfig
 	GetMeFunc           func() (tgbotapi.User, error)
 	GetMeCalls          int
+	MakeRequestFunc     func(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+	MakeRequestCalls    []MockRawRequest
+}
+
+// MockRawRequest records a call to MockTelegramClient.MakeRequest.
+type MockRawRequest struct {
+	Endpoint string
+	Params   tgbotapi.Params
 }
 
 func NewMockTelegramClient() *MockTelegramClient {
@@ -52,6 +60,14 @@ func (m *MockTelegramClient) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbota
 	return make(chan tgbotapi.Update)
 }
 
+func (m *MockTelegramClient) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	m.MakeRequestCalls = append(m.MakeRequestCalls, MockRawRequest{Endpoint: endpoint, Params: params})
+	if m.MakeRequestFunc != nil {
+		return m.MakeRequestFunc(endpoint, params)
+	}
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
 func (m *MockTelegramClient) GetMe() (tgbotapi.User, error) {
 	m.GetMeCalls++
 	if m.GetMeFunc != nil {
@@ -202,8 +218,8 @@ func (m *MockPromptComposer) ComposeAndSend(ctx *Context, config *PromptConfig)
 
 type MockPromptKeyboardActions struct {
 	BuildKeyboardFunc       func(ctx *Context, keyboardFunc KeyboardFunc) (interface{}, error)
-	GetCallbackDataFunc     func(userID int64, uuid string) (interface{}, bool)
-	CleanupUserMappingsFunc func(userID int64)
+	GetCallbackDataFunc     func(userID, chatID int64, uuid string) (interface{}, bool)
+	CleanupUserMappingsFunc func(userID, chatID int64)
 
 	BuildKeyboardCalls []struct {
 		Ctx          *Context
@@ -211,9 +227,10 @@ type MockPromptKeyboardActions struct {
 	}
 	GetCallbackDataCalls []struct {
 		UserID int64
+		ChatID int64
 		UUID   string
 	}
-	CleanupUserMappingsCalls []int64
+	CleanupUserMappingsCalls []flowKey
 }
 
 func NewMockPromptKeyboardActions() *MockPromptKeyboardActions {
@@ -224,9 +241,10 @@ func NewMockPromptKeyboardActions() *MockPromptKeyboardActions {
 		}, 0),
 		GetCallbackDataCalls: make([]struct {
 			UserID int64
+			ChatID int64
 			UUID   string
 		}, 0),
-		CleanupUserMappingsCalls: make([]int64, 0),
+		CleanupUserMappingsCalls: make([]flowKey, 0),
 	}
 }
 
@@ -241,21 +259,22 @@ func (m *MockPromptKeyboardActions) BuildKeyboard(ctx *Context, keyboardFunc Key
 	return tgbotapi.InlineKeyboardMarkup{}, nil
 }
 
-func (m *MockPromptKeyboardActions) GetCallbackData(userID int64, uuid string) (interface{}, bool) {
+func (m *MockPromptKeyboardActions) GetCallbackData(userID, chatID int64, uuid string) (interface{}, bool) {
 	m.GetCallbackDataCalls = append(m.GetCallbackDataCalls, struct {
 		UserID int64
+		ChatID int64
 		UUID   string
-	}{userID, uuid})
+	}{userID, chatID, uuid})
 	if m.GetCallbackDataFunc != nil {
-		return m.GetCallbackDataFunc(userID, uuid)
+		return m.GetCallbackDataFunc(userID, chatID, uuid)
 	}
 	return nil, false
 }
 
-func (m *MockPromptKeyboardActions) CleanupUserMappings(userID int64) {
-	m.CleanupUserMappingsCalls = append(m.CleanupUserMappingsCalls, userID)
+func (m *MockPromptKeyboardActions) CleanupUserMappings(userID, chatID int64) {
+	m.CleanupUserMappingsCalls = append(m.CleanupUserMappingsCalls, flowKey{UserID: userID, ChatID: chatID})
 	if m.CleanupUserMappingsFunc != nil {
-		m.CleanupUserMappingsFunc(userID)
+		m.CleanupUserMappingsFunc(userID, chatID)
 	}
 }
 
@@ -311,7 +330,7 @@ func NewMockFlowManager() *MockFlowManager {
 	}
 }
 
-func (m *MockFlowManager) isUserInFlow(userID int64) bool {
+func (m *MockFlowManager) isUserInFlow(userID, chatID int64) bool {
 	m.IsUserInFlowCalls = append(m.IsUserInFlowCalls, userID)
 	if m.IsUserInFlowFunc != nil {
 		return m.IsUserInFlowFunc(userID)
@@ -319,13 +338,21 @@ func (m *MockFlowManager) isUserInFlow(userID int64) bool {
 	return false
 }
 
-func (m *MockFlowManager) cancelFlow(userID int64) {
+func (m *MockFlowManager) currentStep(userID, chatID int64) (string, string, bool) {
+	return "", "", false
+}
+
+func (m *MockFlowManager) cancelFlow(userID, chatID int64, ctx *Context) {
 	m.CancelFlowCalls = append(m.CancelFlowCalls, userID)
 	if m.CancelFlowFunc != nil {
 		m.CancelFlowFunc(userID)
 	}
 }
 
+func (m *MockFlowManager) updateKeyboard(userID, chatID int64, keyboard KeyboardFunc, ctx *Context) error {
+	return nil
+}
+
 func (m *MockFlowManager) HandleUpdate(ctx *Context) (bool, error) {
 	m.HandleUpdateCalls = append(m.HandleUpdateCalls, ctx)
 	if m.HandleUpdateFunc != nil {
@@ -334,7 +361,15 @@ func (m *MockFlowManager) HandleUpdate(ctx *Context) (bool, error) {
 	return false, nil
 }
 
-func (m *MockFlowManager) startFlow(userID int64, flowName string, ctx *Context) error {
+func (m *MockFlowManager) flowProgress(userID, chatID int64) (FlowProgress, bool) {
+	return FlowProgress{}, false
+}
+
+func (m *MockFlowManager) startFlowWith(userID, chatID int64, flowName string, opts flowStartOptions, ctx *Context) error {
+	return m.startFlow(userID, chatID, flowName, ctx)
+}
+
+func (m *MockFlowManager) startFlow(userID, chatID int64, flowName string, ctx *Context) error {
 	m.StartFlowCalls = append(m.StartFlowCalls, struct {
 		UserID   int64
 		FlowName string
@@ -346,7 +381,7 @@ func (m *MockFlowManager) startFlow(userID int64, flowName string, ctx *Context)
 	return nil
 }
 
-func (m *MockFlowManager) setUserFlowData(userID int64, key string, value interface{}) error {
+func (m *MockFlowManager) setUserFlowData(userID, chatID int64, key string, value interface{}) error {
 	m.SetUserFlowDataCalls = append(m.SetUserFlowDataCalls, struct {
 		UserID int64
 		Key    string
@@ -358,7 +393,7 @@ func (m *MockFlowManager) setUserFlowData(userID int64, key string, value interf
 	return nil
 }
 
-func (m *MockFlowManager) getUserFlowData(userID int64, key string) (interface{}, bool) {
+func (m *MockFlowManager) getUserFlowData(userID, chatID int64, key string) (interface{}, bool) {
 	m.GetUserFlowDataCalls = append(m.GetUserFlowDataCalls, struct {
 		UserID int64
 		Key    string
@@ -1015,3 +1050,39 @@ func TestBot_ProcessUpdate_Scenarios(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteMessages_Batches(t *testing.T) {
+	mockClient := &MockTelegramClient{}
+	ids := make([]int, 150)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+
+	if err := deleteMessages(mockClient, 42, ids); err != nil {
+		t.Fatalf("deleteMessages failed: %v", err)
+	}
+	if len(mockClient.MakeRequestCalls) != 2 || len(mockClient.RequestCalls) != 0 {
+		t.Fatalf("Expected 2 batch calls, got %d batch and %d single", len(mockClient.MakeRequestCalls), len(mockClient.RequestCalls))
+	}
+	if chatID := mockClient.MakeRequestCalls[1].Params["chat_id"]; chatID != "42" {
+		t.Errorf("Expected chat_id 42, got %s", chatID)
+	}
+}
+
+func TestDeleteMessages_FallsBackToSingleDeletes(t *testing.T) {
+	mockClient := &MockTelegramClient{
+		MakeRequestFunc: func(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+			return nil, errors.New("method not found")
+		},
+	}
+
+	if err := deleteMessages(mockClient, 42, []int{7, 8}); err != nil {
+		t.Fatalf("deleteMessages failed: %v", err)
+	}
+	if len(mockClient.RequestCalls) != 2 {
+		t.Fatalf("Expected 2 single deletes, got %d", len(mockClient.RequestCalls))
+	}
+	if del := mockClient.RequestCalls[1].(tgbotapi.DeleteMessageConfig); del.MessageID != 8 {
+		t.Errorf("Expected message 8 to be deleted, got %d", del.MessageID)
+	}
+}
@@ -0,0 +1,149 @@
+package teleflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BusinessConnection describes the bot's connection to a Telegram Business
+// account, through which it receives the account's private chats and replies on
+// its behalf.
+type BusinessConnection struct {
+	ID         string        `json:"id"`
+	User       tgbotapi.User `json:"user"`         // Business account owner
+	UserChatID int64         `json:"user_chat_id"` // Private chat with the owner
+	Date       int64         `json:"date"`         // Unix time the connection was established
+	CanReply   bool          `json:"can_reply"`    // The bot may send messages in the account's chats
+	IsEnabled  bool          `json:"is_enabled"`   // False once the owner disconnects the bot
+}
+
+// ConnectedAt returns when the connection was established.
+func (c BusinessConnection) ConnectedAt() time.Time {
+	return time.Unix(c.Date, 0)
+}
+
+// BusinessConnectionHandlerFunc handles a business account connecting the bot,
+// changing its rights or disconnecting it.
+type BusinessConnectionHandlerFunc func(ctx *Context, conn BusinessConnection) error
+
+// businessMessage is a business_message update: a message in a private chat of
+// a connected business account.
+type businessMessage struct {
+	tgbotapi.Message
+	BusinessConnectionID string `json:"business_connection_id"`
+}
+
+// HandleBusinessConnection registers the handler for business_connection
+// updates, e.g. to store which accounts use the bot. The context targets the
+// owner's private chat with the bot.
+//
+// Messages customers send to a connected account arrive as business messages
+// and are routed like any other message: to the customer's flow, commands and
+// text handlers. ctx.BusinessConnectionID() identifies the account, and prompts
+// sent in reply go out on its behalf. Messages the owner sends also arrive as
+// business messages, from the owner's user ID.
+//
+// Like reactions, business updates reach teleflow through webhooks and
+// ProcessExternalUpdateJSON; long polling with Start does not deliver them.
+//
+// Example:
+//
+//	bot.HandleBusinessConnection(func(ctx *teleflow.Context, conn teleflow.BusinessConnection) error {
+//		if !conn.IsEnabled {
+//			return store.Disconnect(conn.ID)
+//		}
+//		return store.Connect(conn.ID, conn.User.ID)
+//	})
+func (b *Bot) HandleBusinessConnection(handler BusinessConnectionHandlerFunc) {
+	b.businessConnectionHandler = handler
+}
+
+// BusinessConnectionID returns the business connection the current update
+// arrived through, or "" for updates from the bot's own chats.
+func (c *Context) BusinessConnectionID() string {
+	return c.businessConnectionID
+}
+
+// processBusinessConnection runs the business connection handler.
+func (b *Bot) processBusinessConnection(conn BusinessConnection) {
+	if b.businessConnectionHandler == nil {
+		return
+	}
+
+	ctx := newContext(tgbotapi.Update{}, b.sender, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+	ctx.userID, ctx.chatID = conn.User.ID, conn.UserChatID
+	ctx.telegramClient = b.sender.forContext(ctx)
+	ctx.clock = b.clock
+
+	wrappedHandler := func(ctx *Context) error {
+		return b.businessConnectionHandler(ctx, conn)
+	}
+	if err := b.stats.track("business_connection", func() error { return b.applyMiddleware(wrappedHandler)(ctx) }); err != nil {
+		b.deadLetter(ctx, fmt.Errorf("business connection: %w", err), 1)
+	}
+}
+
+// processBusinessMessage routes a business message like a regular message,
+// replying through its business connection.
+func (b *Bot) processBusinessMessage(updateID int, msg businessMessage) {
+	message := msg.Message
+	if message.From == nil || message.Chat == nil {
+		return
+	}
+	b.processUpdateVia(tgbotapi.Update{UpdateID: updateID, Message: &message}, msg.BusinessConnectionID)
+}
+
+// sendBusiness sends a composed message on behalf of a business account. The
+// library cannot add business_connection_id to its requests, so the message is
+// sent as a raw request.
+func (pc *PromptComposer) sendBusiness(ctx *Context, msg *composedMessage) error {
+	params := tgbotapi.Params{}
+	params["business_connection_id"] = msg.businessConnectionID
+	params.AddNonZero64("chat_id", ctx.ChatID())
+
+	var replyMarkup interface{}
+	if msg.keyboard != nil {
+		replyMarkup = msg.keyboard
+	} else if markup := pc.takeReplyMarkup(ctx, msg); markup != nil {
+		replyMarkup = markup
+	}
+	if err := params.AddInterface("reply_markup", replyMarkup); err != nil {
+		return fmt.Errorf("failed to encode reply markup: %w", err)
+	}
+	if msg.parseMode != ParseModeNone {
+		params["parse_mode"] = string(msg.parseMode)
+	}
+
+	endpoint, entitiesKey := "sendMessage", "entities"
+	switch {
+	case msg.image != nil:
+		photo := msg.image.fileID
+		if photo == "" && strings.HasPrefix(msg.image.filePath, "http") {
+			photo = msg.image.filePath
+		}
+		if photo == "" {
+			return fmt.Errorf("business messages can only send images by URL or file_id")
+		}
+		endpoint, entitiesKey = "sendPhoto", "caption_entities"
+		params["photo"] = photo
+		params.AddNonEmpty("caption", msg.text)
+	case msg.text != "":
+		params["text"] = msg.text
+	case replyMarkup != nil:
+		params["text"] = "\u200B" // Zero-width space carrying the keyboard
+	default:
+		return nil
+	}
+	if len(msg.entities) > 0 {
+		if err := params.AddInterface(entitiesKey, msg.entities); err != nil {
+			return fmt.Errorf("failed to encode entities: %w", err)
+		}
+	}
+
+	sent, err := sendRawMessage(clientFor(pc.botAPI, ctx), endpoint, params)
+	pc.recordSent(ctx, sent, err, msg.keyboard != nil, msg.image != nil)
+	return err
+}
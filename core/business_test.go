@@ -0,0 +1,72 @@
+package teleflow
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBusinessConnectionAndMessages(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+
+	var connections []BusinessConnection
+	bot.HandleBusinessConnection(func(ctx *Context, conn BusinessConnection) error {
+		connections = append(connections, conn)
+		return nil
+	})
+	var connectionIDs []string
+	bot.HandleText("hours?", func(ctx *Context, text string) error {
+		connectionIDs = append(connectionIDs, ctx.BusinessConnectionID())
+		return ctx.SendPromptText("We are open 9-18.")
+	})
+
+	connection := `{"update_id":1,"business_connection":{"id":"conn1","user":{"id":5,"first_name":"Shop"},"user_chat_id":5,"date":1700000000,"can_reply":true,"is_enabled":true}}`
+	if err := bot.ProcessExternalUpdateJSON([]byte(connection)); err != nil {
+		t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+	}
+	if len(connections) != 1 || connections[0].ID != "conn1" || connections[0].User.ID != 5 || !connections[0].CanReply {
+		t.Fatalf("Unexpected connections %+v", connections)
+	}
+
+	message := func(text string) []byte {
+		return []byte(fmt.Sprintf(`{"update_id":2,"business_message":{"message_id":10,"business_connection_id":"conn1","from":{"id":77,"first_name":"Ann"},"chat":{"id":77,"type":"private"},"date":1700000000,"text":%q}}`, text))
+	}
+	if err := bot.ProcessExternalUpdateJSON(message("hours?")); err != nil {
+		t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+	}
+
+	if len(connectionIDs) != 1 || connectionIDs[0] != "conn1" {
+		t.Fatalf("Expected the text handler to see the connection, got %v", connectionIDs)
+	}
+	if len(client.SendCalls) != 0 || len(client.MakeRequestCalls) != 1 {
+		t.Fatalf("Expected the reply as one raw request, got %d sends and %d raw requests", len(client.SendCalls), len(client.MakeRequestCalls))
+	}
+	reply := client.MakeRequestCalls[0]
+	if reply.Endpoint != "sendMessage" || reply.Params["business_connection_id"] != "conn1" || reply.Params["chat_id"] != "77" || reply.Params["text"] != "We are open 9-18." {
+		t.Errorf("Unexpected reply %+v", reply)
+	}
+
+	// Regular messages are still answered directly
+	bot.processUpdate(createFlowTestContext(77, "hours?", nil).update)
+	if len(client.SendCalls) != 1 || connectionIDs[1] != "" {
+		t.Errorf("Expected a direct reply outside the business connection, got %d sends", len(client.SendCalls))
+	}
+}
+
+func TestPromptConfig_BusinessConnectionImage(t *testing.T) {
+	client := NewMockTelegramClient()
+	pc := createTestPromptComposer(client, newTemplateManager())
+	ctx := createTestContext()
+	ctx.telegramClient = client
+
+	err := pc.ComposeAndSend(ctx, &PromptConfig{Message: "New menu", Image: "https://example.com/menu.jpg", BusinessConnectionID: "conn1"})
+	if err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+	if len(client.MakeRequestCalls) != 1 || client.MakeRequestCalls[0].Endpoint != "sendPhoto" || client.MakeRequestCalls[0].Params["photo"] != "https://example.com/menu.jpg" {
+		t.Fatalf("Expected a raw sendPhoto, got %+v", client.MakeRequestCalls)
+	}
+
+	if err := pc.ComposeAndSend(ctx, &PromptConfig{Image: []byte{0xff, 0xd8}, BusinessConnectionID: "conn1"}); err == nil {
+		t.Error("Expected uploaded images to be rejected for business messages")
+	}
+}
@@ -0,0 +1,307 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CaptchaKind selects the challenge presented by a captcha flow.
+type CaptchaKind int
+
+const (
+	CaptchaEmoji      CaptchaKind = iota // "Tap the 🍎" with a row of emoji buttons
+	CaptchaArithmetic                    // "What is 3 + 4?" answered with a button or by typing
+)
+
+const (
+	captchaAnswerKey  = "__captcha_answer"
+	captchaOptionsKey = "__captcha_options"
+)
+
+// captchaEmojis is the pool of emojis used for emoji challenges.
+var captchaEmojis = []string{"🍎", "🚗", "🐶", "⚽", "🌵", "🎸", "🚀", "🍕", "🐱", "🌙", "🎁", "🔑"}
+
+// CaptchaExempter can be implemented by an AccessManager to let trusted users
+// (e.g. known members re-joining, or users invited by an admin) skip the captcha
+// that EnableCaptcha starts for new group members.
+type CaptchaExempter interface {
+	// IsCaptchaExempt reports whether the user described by ctx may join without solving a captcha.
+	IsCaptchaExempt(ctx *PermissionContext) bool
+}
+
+// CaptchaConfig configures the captcha flow created by NewCaptchaFlow and EnableCaptcha.
+// Zero values fall back to sensible defaults.
+type CaptchaConfig struct {
+	FlowName            string        // Name of the registered flow (default "captcha")
+	Kind                CaptchaKind   // Type of challenge (default CaptchaEmoji)
+	Timeout             time.Duration // Time allowed to solve the captcha (default 2 minutes)
+	MaxAttempts         int           // Wrong answers allowed before failing (default 3)
+	KickOnFailure       bool          // Remove the user from the group on failure or timeout
+	RestrictUntilSolved bool          // Mute new members until they solve the captcha, then restore the chat's default permissions
+	Greeting            string        // Text shown above the challenge
+	SuccessMessage      string        // Message sent after solving (empty sends nothing)
+	FailureMessage      string        // Message sent after failing or timing out
+}
+
+// withDefaults returns a copy of the configuration with unset fields filled in.
+func (cfg CaptchaConfig) withDefaults() CaptchaConfig {
+	if cfg.FlowName == "" {
+		cfg.FlowName = "captcha"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.Greeting == "" {
+		cfg.Greeting = "👋 Welcome! Please confirm you're human."
+	}
+	if cfg.FailureMessage == "" {
+		cfg.FailureMessage = "🚫 Verification failed."
+	}
+	return cfg
+}
+
+// newChallenge generates a question, its answer and the answer options shown as buttons.
+func (cfg CaptchaConfig) newChallenge() (string, string, []string) {
+	if cfg.Kind == CaptchaArithmetic {
+		a, b := rand.Intn(9)+1, rand.Intn(9)+1
+		answer := a + b
+		options := []string{strconv.Itoa(answer)}
+		for len(options) < 4 {
+			candidate := strconv.Itoa(answer + rand.Intn(9) - 4)
+			if !containsString(options, candidate) && candidate != "0" {
+				options = append(options, candidate)
+			}
+		}
+		rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		return fmt.Sprintf("What is %d + %d?", a, b), strconv.Itoa(answer), options
+	}
+
+	pool := append([]string(nil), captchaEmojis...)
+	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	options := pool[:4]
+	answer := options[rand.Intn(len(options))]
+	return fmt.Sprintf("Tap the %s button.", answer), answer, options
+}
+
+// NewCaptchaFlow builds a single-step flow that asks the user to solve a simple
+// challenge. The flow is scoped per user per chat, so several new members of a
+// group can solve their captchas independently. Wrong answers generate a fresh
+// challenge until MaxAttempts is reached, after which the user is optionally
+// removed from the chat.
+//
+// Most bots use EnableCaptcha, which registers this flow and starts it
+// automatically for new group members. The flow can also be registered and
+// started manually with ctx.StartFlow.
+//
+// Example:
+//
+//	flow, err := teleflow.NewCaptchaFlow(teleflow.CaptchaConfig{Kind: teleflow.CaptchaArithmetic})
+func NewCaptchaFlow(config CaptchaConfig) (*Flow, error) {
+	cfg := config.withDefaults()
+
+	return NewFlow(cfg.FlowName).
+		Scope(FlowScopeUserChat).
+		WithTimeout(cfg.Timeout).
+		OnButtonClick(DeleteMessage).
+		OnMaxRetries(func(ctx *Context, stepName string) ProcessResult {
+			if cfg.KickOnFailure {
+				if err := ctx.kickChatMember(); err != nil {
+					log.Printf("[CAPTCHA] Failed to remove user %d from chat %d: %v", ctx.UserID(), ctx.ChatID(), err)
+				}
+			}
+			return CancelFlow().WithPrompt(cfg.FailureMessage)
+		}).
+		Step("challenge").
+		Prompt(func(ctx *Context) string {
+			question, answer, options := cfg.newChallenge()
+			_ = ctx.SetFlowData(captchaAnswerKey, answer)
+			_ = ctx.SetFlowData(captchaOptionsKey, options)
+			return cfg.Greeting + "\n\n" + question
+		}).
+		WithPromptKeyboard(func(ctx *Context) *PromptKeyboardBuilder {
+			options, _ := ctx.GetFlowData(captchaOptionsKey)
+			kb := NewPromptKeyboard()
+			for _, option := range options.([]string) {
+				kb.ButtonCallback(option, option)
+			}
+			return kb
+		}).
+		Process(func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+			answer, _ := ctx.GetFlowData(captchaAnswerKey)
+
+			var given string
+			if buttonClick != nil {
+				given, _ = buttonClick.Data.(string)
+			} else if cfg.Kind == CaptchaArithmetic {
+				given = strings.TrimSpace(input)
+			}
+
+			if given != "" && given == answer {
+				return CompleteFlow()
+			}
+			return Retry().WithMaxAttempts(cfg.MaxAttempts)
+		}).
+		OnComplete(func(ctx *Context) error {
+			if cfg.RestrictUntilSolved {
+				if err := ctx.setChatMemberMuted(false); err != nil {
+					log.Printf("[CAPTCHA] Failed to lift restrictions for user %d in chat %d: %v", ctx.UserID(), ctx.ChatID(), err)
+				}
+			}
+			if cfg.SuccessMessage != "" {
+				return ctx.sendSimpleText(cfg.SuccessMessage)
+			}
+			return nil
+		}).
+		Build()
+}
+
+// EnableCaptcha registers a captcha flow and starts it automatically for every
+// user who joins a group the bot administers. Bots and users exempted by the
+// AccessManager (see CaptchaExempter) are let through. Users who do not solve the
+// captcha within the timeout are treated like users who exhausted their attempts.
+//
+// The bot needs the "ban users" admin right for KickOnFailure and RestrictUntilSolved.
+//
+// Example:
+//
+//	err := bot.EnableCaptcha(teleflow.CaptchaConfig{
+//		Kind:                teleflow.CaptchaEmoji,
+//		Timeout:             time.Minute,
+//		KickOnFailure:       true,
+//		RestrictUntilSolved: true,
+//	})
+func (b *Bot) EnableCaptcha(config CaptchaConfig) error {
+	cfg := config.withDefaults()
+
+	flow, err := NewCaptchaFlow(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build captcha flow: %w", err)
+	}
+
+	b.RegisterFlow(flow)
+	b.captcha = &cfg
+	return nil
+}
+
+// handleNewChatMembers starts the captcha for every new human member of the chat.
+func (b *Bot) handleNewChatMembers(ctx *Context) {
+	for _, member := range ctx.update.Message.NewChatMembers {
+		if member.IsBot {
+			continue
+		}
+
+		permCtx := &PermissionContext{
+			UserID:  member.ID,
+			ChatID:  ctx.ChatID(),
+			IsGroup: ctx.IsGroup(),
+			Update:  &ctx.update,
+		}
+		if exempter, ok := b.accessManager.(CaptchaExempter); ok && exempter.IsCaptchaExempt(permCtx) {
+			continue
+		}
+
+		memberCtx := ctx.forUser(member.ID)
+		if b.captcha.RestrictUntilSolved {
+			if err := memberCtx.setChatMemberMuted(true); err != nil {
+				log.Printf("[CAPTCHA] Failed to restrict user %d in chat %d: %v", member.ID, ctx.ChatID(), err)
+			}
+		}
+
+		if err := memberCtx.StartFlow(b.captcha.FlowName); err != nil {
+			log.Printf("[CAPTCHA] Failed to start captcha for user %d in chat %d: %v", member.ID, ctx.ChatID(), err)
+			continue
+		}
+
+		state := b.flowManager.currentState(member.ID, ctx.ChatID())
+		ctx.Retain() // memberCtx sends through ctx when the captcha expires
+		b.clock.AfterFunc(b.captcha.Timeout, func() {
+			b.expireCaptcha(memberCtx, state)
+		})
+	}
+}
+
+// expireCaptcha fails a captcha that is still unsolved when its timeout elapses.
+// It cancels the flow like any other timeout, under the chat's conversation lock
+// so it cannot race an answer being handled.
+func (b *Bot) expireCaptcha(ctx *Context, state *userFlowState) {
+	defer b.lockConversation(ctx)()
+	if state == nil || b.flowManager.currentState(ctx.UserID(), ctx.ChatID()) != state {
+		return
+	}
+	b.flowManager.cancelFlowWithReason(ctx.UserID(), ctx.ChatID(), ctx, CancelReasonTimeout)
+
+	if b.captcha.KickOnFailure {
+		if err := ctx.kickChatMember(); err != nil {
+			log.Printf("[CAPTCHA] Failed to remove user %d from chat %d: %v", ctx.UserID(), ctx.ChatID(), err)
+		}
+	}
+	if err := ctx.sendSimpleText(b.captcha.FailureMessage); err != nil {
+		log.Printf("[CAPTCHA] Failed to send timeout message to chat %d: %v", ctx.ChatID(), err)
+	}
+}
+
+// kickChatMember removes the current user from the current chat without banning
+// them permanently, so they may join again later.
+func (c *Context) kickChatMember() error {
+	member := tgbotapi.ChatMemberConfig{ChatID: c.ChatID(), UserID: c.UserID()}
+	if _, err := c.telegramClient.Request(tgbotapi.BanChatMemberConfig{ChatMemberConfig: member}); err != nil {
+		return err
+	}
+	_, err := c.telegramClient.Request(tgbotapi.UnbanChatMemberConfig{ChatMemberConfig: member, OnlyIfBanned: true})
+	return err
+}
+
+// setChatMemberMuted restricts the current user from sending anything to the
+// current chat, or restores the usual member permissions.
+func (c *Context) setChatMemberMuted(muted bool) error {
+	permissions := &tgbotapi.ChatPermissions{}
+	if !muted {
+		defaults, err := c.chatDefaultPermissions()
+		if err != nil {
+			return err
+		}
+		permissions = defaults
+	}
+	_, err := c.telegramClient.Request(tgbotapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: c.ChatID(), UserID: c.UserID()},
+		Permissions:      permissions,
+	})
+	return err
+}
+
+// chatDefaultPermissions fetches the permissions the current chat grants its
+// members, so lifting a restriction never gives more than the chat allows.
+func (c *Context) chatDefaultPermissions() (*tgbotapi.ChatPermissions, error) {
+	resp, err := c.telegramClient.Request(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: c.ChatID()}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat %d: %w", c.ChatID(), err)
+	}
+	var chat tgbotapi.Chat
+	if err := json.Unmarshal(resp.Result, &chat); err != nil {
+		return nil, fmt.Errorf("failed to decode chat %d: %w", c.ChatID(), err)
+	}
+	if chat.Permissions == nil {
+		return nil, fmt.Errorf("chat %d did not report default permissions", c.ChatID())
+	}
+	return chat.Permissions, nil
+}
+
+// containsString reports whether values contains s.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
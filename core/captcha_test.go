@@ -0,0 +1,161 @@
+package teleflow
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func createCaptchaJoinUpdate(chatID int64, members ...tgbotapi.User) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: 1,
+		Message: &tgbotapi.Message{
+			MessageID:      1,
+			From:           &tgbotapi.User{ID: members[0].ID},
+			Chat:           &tgbotapi.Chat{ID: chatID, Type: "supergroup"},
+			NewChatMembers: members,
+		},
+	}
+}
+
+func createCaptchaAnswerUpdate(chatID, userID int64, text string) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: 2,
+		Message: &tgbotapi.Message{
+			MessageID: 2,
+			From:      &tgbotapi.User{ID: userID},
+			Chat:      &tgbotapi.Chat{ID: chatID, Type: "supergroup"},
+			Text:      text,
+		},
+	}
+}
+
+func countRequests[T tgbotapi.Chattable](calls []tgbotapi.Chattable) int {
+	count := 0
+	for _, c := range calls {
+		if _, ok := c.(T); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCaptcha_SolvedByNewMember(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	chatID, userID := int64(-100), int64(777)
+	mockClient.RequestFunc = func(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		if _, ok := c.(tgbotapi.ChatInfoConfig); ok {
+			return &tgbotapi.APIResponse{Ok: true, Result: []byte(`{"id":-100,"type":"supergroup","permissions":{"can_send_messages":true}}`)}, nil
+		}
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+
+	err := bot.EnableCaptcha(CaptchaConfig{
+		Kind:                CaptchaArithmetic,
+		Timeout:             time.Hour,
+		RestrictUntilSolved: true,
+	})
+	if err != nil {
+		t.Fatalf("EnableCaptcha failed: %v", err)
+	}
+
+	bot.processUpdate(createCaptchaJoinUpdate(chatID, tgbotapi.User{ID: userID}, tgbotapi.User{ID: 888, IsBot: true}))
+
+	if !bot.flowManager.isUserInFlow(userID, chatID) {
+		t.Fatal("Expected new member to be in captcha flow")
+	}
+	if bot.flowManager.isUserInFlow(888, chatID) {
+		t.Error("Bots should not receive a captcha")
+	}
+	if got := countRequests[tgbotapi.RestrictChatMemberConfig](mockClient.RequestCalls); got != 1 {
+		t.Errorf("Expected 1 restrict request after join, got %d", got)
+	}
+
+	answer, ok := bot.flowManager.getUserFlowData(userID, chatID, captchaAnswerKey)
+	if !ok {
+		t.Fatal("Expected captcha answer to be stored in flow data")
+	}
+
+	bot.processUpdate(createCaptchaAnswerUpdate(chatID, userID, answer.(string)))
+
+	if bot.flowManager.isUserInFlow(userID, chatID) {
+		t.Error("Expected captcha flow to complete after correct answer")
+	}
+	if got := countRequests[tgbotapi.RestrictChatMemberConfig](mockClient.RequestCalls); got != 2 {
+		t.Errorf("Expected restrictions to be lifted, got %d restrict requests", got)
+	}
+	lifted := mockClient.RequestCalls[len(mockClient.RequestCalls)-1].(tgbotapi.RestrictChatMemberConfig)
+	if want := (tgbotapi.ChatPermissions{CanSendMessages: true}); *lifted.Permissions != want {
+		t.Errorf("Expected the chat's default permissions to be restored, got %+v", *lifted.Permissions)
+	}
+}
+
+func TestCaptcha_KickAfterMaxAttempts(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	chatID, userID := int64(-100), int64(777)
+
+	err := bot.EnableCaptcha(CaptchaConfig{
+		Kind:          CaptchaArithmetic,
+		Timeout:       time.Hour,
+		MaxAttempts:   2,
+		KickOnFailure: true,
+	})
+	if err != nil {
+		t.Fatalf("EnableCaptcha failed: %v", err)
+	}
+
+	bot.processUpdate(createCaptchaJoinUpdate(chatID, tgbotapi.User{ID: userID}))
+	for i := 0; i < 2; i++ {
+		bot.processUpdate(createCaptchaAnswerUpdate(chatID, userID, "wrong"))
+	}
+
+	if bot.flowManager.isUserInFlow(userID, chatID) {
+		t.Error("Expected captcha flow to be cancelled after max attempts")
+	}
+	if got := countRequests[tgbotapi.BanChatMemberConfig](mockClient.RequestCalls); got != 1 {
+		t.Errorf("Expected user to be kicked once, got %d ban requests", got)
+	}
+}
+
+func TestCaptcha_Timeout(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	chatID, userID := int64(-100), int64(777)
+
+	err := bot.EnableCaptcha(CaptchaConfig{
+		Kind:          CaptchaEmoji,
+		Timeout:       time.Hour,
+		KickOnFailure: true,
+	})
+	if err != nil {
+		t.Fatalf("EnableCaptcha failed: %v", err)
+	}
+
+	var reasons []CancelReason
+	bot.flowManager.flows["captcha"].OnCancel = func(ctx *Context) error {
+		reasons = append(reasons, ctx.CancelReason())
+		return nil
+	}
+
+	bot.processUpdate(createCaptchaJoinUpdate(chatID, tgbotapi.User{ID: userID}))
+
+	state := bot.flowManager.currentState(userID, chatID)
+	ctx := newContext(createCaptchaAnswerUpdate(chatID, userID, ""), mockClient, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+
+	bot.expireCaptcha(ctx, state)
+	if bot.flowManager.isUserInFlow(userID, chatID) {
+		t.Error("Expected captcha flow to be cancelled on timeout")
+	}
+	if got := countRequests[tgbotapi.BanChatMemberConfig](mockClient.RequestCalls); got != 1 {
+		t.Errorf("Expected user to be kicked on timeout, got %d ban requests", got)
+	}
+	if len(reasons) != 1 || reasons[0] != CancelReasonTimeout {
+		t.Errorf("Expected OnCancel with reason %q, got %v", CancelReasonTimeout, reasons)
+	}
+
+	// A second expiry for the same run must be a no-op
+	bot.expireCaptcha(ctx, state)
+	if got := countRequests[tgbotapi.BanChatMemberConfig](mockClient.RequestCalls); got != 1 {
+		t.Errorf("Expected no additional kick, got %d ban requests", got)
+	}
+}
@@ -0,0 +1,124 @@
+package teleflow
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ChatBoostSource describes how a chat boost was obtained.
+type ChatBoostSource struct {
+	Source            string         `json:"source"` // "premium", "gift_code" or "giveaway"
+	User              *tgbotapi.User `json:"user,omitempty"`
+	GiveawayMessageID int            `json:"giveaway_message_id,omitempty"`
+	IsUnclaimed       bool           `json:"is_unclaimed,omitempty"`
+}
+
+// chatBoost is a boost added to a chat.
+type chatBoost struct {
+	BoostID        string          `json:"boost_id"`
+	AddDate        int64           `json:"add_date"`
+	ExpirationDate int64           `json:"expiration_date"`
+	Source         ChatBoostSource `json:"source"`
+}
+
+// chatBoostUpdated is the chat_boost update.
+type chatBoostUpdated struct {
+	Chat  tgbotapi.Chat `json:"chat"`
+	Boost chatBoost     `json:"boost"`
+}
+
+// chatBoostRemoved is the removed_chat_boost update.
+type chatBoostRemoved struct {
+	Chat       tgbotapi.Chat   `json:"chat"`
+	BoostID    string          `json:"boost_id"`
+	RemoveDate int64           `json:"remove_date"`
+	Source     ChatBoostSource `json:"source"`
+}
+
+// ChatBoostEvent is a boost added to or removed from a chat the bot
+// administers.
+type ChatBoostEvent struct {
+	ChatID    int64           // Boosted chat
+	BoostID   string          // Identifier of the boost, the same when it is removed
+	Removed   bool            // The boost was removed or expired
+	Date      time.Time       // When the boost was added, or removed if Removed
+	ExpiresAt time.Time       // When the boost expires unless renewed (zero if Removed)
+	Source    ChatBoostSource // How the boost was obtained
+}
+
+// User returns the user who boosted the chat, or nil for unclaimed giveaway
+// boosts.
+func (e ChatBoostEvent) User() *tgbotapi.User {
+	return e.Source.User
+}
+
+// ChatBoostHandlerFunc handles a boost added to or removed from a chat.
+type ChatBoostHandlerFunc func(ctx *Context, event ChatBoostEvent) error
+
+// HandleChatBoost registers the handler for chat_boost and removed_chat_boost
+// updates. The bot must administer the chat and request both update types in
+// allowed_updates. The context's user is the booster, if known, and its chat is
+// the boosted chat, which for channels the bot can post to.
+//
+// Like reactions, boosts reach teleflow through webhooks and
+// ProcessExternalUpdateJSON; long polling with Start does not deliver them.
+//
+// Example:
+//
+//	bot.HandleChatBoost(func(ctx *teleflow.Context, event teleflow.ChatBoostEvent) error {
+//		if event.Removed || event.User() == nil {
+//			return nil
+//		}
+//		return ctx.SendPromptText("Thank you for the boost, " + event.User().FirstName + "!")
+//	})
+func (b *Bot) HandleChatBoost(handler ChatBoostHandlerFunc) {
+	b.chatBoostHandler = handler
+}
+
+// event converts the chat_boost update.
+func (u chatBoostUpdated) event() ChatBoostEvent {
+	return ChatBoostEvent{
+		ChatID:    u.Chat.ID,
+		BoostID:   u.Boost.BoostID,
+		Date:      time.Unix(u.Boost.AddDate, 0),
+		ExpiresAt: time.Unix(u.Boost.ExpirationDate, 0),
+		Source:    u.Boost.Source,
+	}
+}
+
+// event converts the removed_chat_boost update.
+func (u chatBoostRemoved) event() ChatBoostEvent {
+	return ChatBoostEvent{
+		ChatID:  u.Chat.ID,
+		BoostID: u.BoostID,
+		Removed: true,
+		Date:    time.Unix(u.RemoveDate, 0),
+		Source:  u.Source,
+	}
+}
+
+// processChatBoost runs the chat boost handler for the event.
+func (b *Bot) processChatBoost(chat tgbotapi.Chat, event ChatBoostEvent) {
+	if b.chatBoostHandler == nil {
+		return
+	}
+
+	ctx := newContext(tgbotapi.Update{}, b.sender, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+	ctx.chatID = chat.ID
+	ctx.isGroup = chat.IsGroup() || chat.IsSuperGroup()
+	ctx.isChannel = chat.IsChannel()
+	if user := event.User(); user != nil {
+		ctx.userID = user.ID
+	}
+	ctx.telegramClient = b.sender.forContext(ctx)
+	ctx.clock = b.clock
+
+	wrappedHandler := func(ctx *Context) error {
+		return b.chatBoostHandler(ctx, event)
+	}
+	if err := b.stats.track("chat_boost", func() error { return b.applyMiddleware(wrappedHandler)(ctx) }); err != nil {
+		b.deadLetter(ctx, fmt.Errorf("chat boost: %w", err), 1)
+	}
+}
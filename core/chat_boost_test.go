@@ -0,0 +1,40 @@
+package teleflow
+
+import "testing"
+
+func TestHandleChatBoost(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+
+	var events []ChatBoostEvent
+	bot.HandleChatBoost(func(ctx *Context, event ChatBoostEvent) error {
+		events = append(events, event)
+		if !event.Removed && event.User() != nil {
+			return ctx.SendPromptText("Thanks for the boost!")
+		}
+		return nil
+	})
+
+	updates := []string{
+		`{"update_id":1,"chat_boost":{"chat":{"id":-100,"type":"channel"},"boost":{"boost_id":"b1","add_date":1700000000,"expiration_date":1702592000,"source":{"source":"premium","user":{"id":7,"first_name":"Ann"}}}}}`,
+		`{"update_id":2,"removed_chat_boost":{"chat":{"id":-100,"type":"channel"},"boost_id":"b1","remove_date":1702592000,"source":{"source":"premium","user":{"id":7,"first_name":"Ann"}}}}`,
+	}
+	for _, update := range updates {
+		if err := bot.ProcessExternalUpdateJSON([]byte(update)); err != nil {
+			t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected two events, got %d", len(events))
+	}
+	added, removed := events[0], events[1]
+	if added.Removed || added.BoostID != "b1" || added.ChatID != -100 || added.User().ID != 7 || added.ExpiresAt.Unix() != 1702592000 {
+		t.Errorf("Unexpected boost event %+v", added)
+	}
+	if !removed.Removed || removed.Date.Unix() != 1702592000 || !removed.ExpiresAt.IsZero() {
+		t.Errorf("Unexpected removal event %+v", removed)
+	}
+	if len(client.SendCalls) != 1 {
+		t.Errorf("Expected one thank-you message, got %d", len(client.SendCalls))
+	}
+}
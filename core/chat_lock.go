@@ -0,0 +1,76 @@
+package teleflow
+
+import "sync"
+
+// chatLockStripes is the number of mutexes shared by all chats.
+const chatLockStripes = 256
+
+// chatLocks serializes the processing of updates that belong to the same chat, so
+// concurrently dispatched updates of one conversation never interleave. Chats are
+// spread over a fixed set of mutexes: memory does not grow with the number of
+// chats, at the cost of two chats occasionally waiting on the same stripe.
+type chatLocks struct {
+	stripes [chatLockStripes]sync.Mutex
+}
+
+// lock acquires the mutex for key and returns the function that releases it.
+func (l *chatLocks) lock(key int64) func() {
+	mu := &l.stripes[chatStripe(key)]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// tryLock acquires the mutex for key if it is free and returns the function
+// that releases it. It reports false if the mutex is held.
+func (l *chatLocks) tryLock(key int64) (func(), bool) {
+	mu := &l.stripes[chatStripe(key)]
+	if !mu.TryLock() {
+		return nil, false
+	}
+	return mu.Unlock, true
+}
+
+// chatStripe maps a chat ID onto a stripe. Chat IDs are often sequential or share
+// low bits, so they are mixed with a multiplicative hash first.
+func chatStripe(key int64) uint64 {
+	return ((uint64(key) * 0x9E3779B97F4A7C15) >> 56) % chatLockStripes
+}
+
+// WithChatOrdering controls whether updates of the same chat are processed one at
+// a time. It is enabled by default: updates are handled concurrently, but two
+// updates of one conversation never run side by side, so a flow step or handler
+// never sees its state changed halfway by another update of the chat. Updates
+// that arrive at nearly the same time may still be handled in either order.
+// Disable it only for bots whose handlers are safe to run in parallel within a
+// chat.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithChatOrdering(false))
+func WithChatOrdering(enabled bool) BotOption {
+	return func(b *Bot) {
+		if enabled {
+			b.chatLocks = &chatLocks{}
+		} else {
+			b.chatLocks = nil
+		}
+	}
+}
+
+// lockConversation serializes updates of the context's chat, or of the user for
+// updates without a chat such as inline queries. It returns the release function.
+//
+// The lock is not reentrant and chats share stripes, so it must not be taken
+// again while an update is being handled: public APIs that lock a conversation,
+// such as ResumeFlow and StartFlowFor, deadlock when called from a handler,
+// flow step or middleware and must say so in their documentation.
+func (b *Bot) lockConversation(ctx *Context) func() {
+	key := ctx.ChatID()
+	if key == 0 {
+		key = ctx.UserID()
+	}
+	if b.chatLocks == nil || key == 0 {
+		return func() {}
+	}
+	return b.chatLocks.lock(key)
+}
@@ -0,0 +1,65 @@
+package teleflow
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func runConcurrentChatUpdates(bot *Bot, chatIDs ...int64) int64 {
+	var running, maxRunning atomic.Int64
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		now := running.Add(1)
+		for {
+			seen := maxRunning.Load()
+			if now <= seen || maxRunning.CompareAndSwap(seen, now) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for _, chatID := range chatIDs {
+		wg.Add(1)
+		go func(chatID int64) {
+			defer wg.Done()
+			bot.ProcessExternalUpdate(tgbotapi.Update{Message: &tgbotapi.Message{
+				From: &tgbotapi.User{ID: chatID},
+				Chat: &tgbotapi.Chat{ID: chatID, Type: "private"},
+				Text: "hello",
+			}})
+		}(chatID)
+	}
+	wg.Wait()
+	return maxRunning.Load()
+}
+
+func TestChatOrdering_SerializesSameChat(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	if got := runConcurrentChatUpdates(bot, 42, 42, 42, 42); got != 1 {
+		t.Errorf("Expected updates of one chat to run one at a time, got %d in parallel", got)
+	}
+}
+
+func TestChatOrdering_Disabled(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithChatOrdering(false))
+	if got := runConcurrentChatUpdates(bot, 42, 42, 42, 42); got < 2 {
+		t.Errorf("Expected updates to run in parallel with ordering disabled, got %d", got)
+	}
+}
+
+func TestChatStripe_SpreadsSequentialChats(t *testing.T) {
+	used := make(map[uint64]bool)
+	for chatID := int64(1); chatID <= 64; chatID++ {
+		used[chatStripe(chatID)] = true
+	}
+	if len(used) < 48 {
+		t.Errorf("Expected sequential chat IDs to use most stripes, got %d distinct of 64", len(used))
+	}
+}
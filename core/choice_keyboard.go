@@ -0,0 +1,119 @@
+package teleflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Choice is one option of a ChoiceKeyboard: the button label and the value it stands for.
+type Choice[T any] struct {
+	Label string
+	Value T
+}
+
+// ChoiceKeyboard turns a fixed set of options into an inline keyboard and maps the
+// user's answer back to the typed value of the chosen option. Users may click a
+// button or type an option's label; anything else is rejected with a retry.
+//
+// Example:
+//
+//	plans := teleflow.NewOrderedChoiceKeyboard(
+//		teleflow.Choice[Plan]{Label: "Free", Value: PlanFree},
+//		teleflow.Choice[Plan]{Label: "Pro", Value: PlanPro},
+//	)
+//
+//	flow.Step("plan").
+//		Prompt("Which plan?").
+//		WithPromptKeyboard(plans.Keyboard()).
+//		Process(plans.Process(func(ctx *teleflow.Context, plan Plan) teleflow.ProcessResult {
+//			ctx.SetFlowData("plan", plan)
+//			return teleflow.NextStep()
+//		}))
+type ChoiceKeyboard[T any] struct {
+	choices []Choice[T]
+	perRow  int
+	message string
+}
+
+// NewChoiceKeyboard creates a ChoiceKeyboard from a label-to-value map.
+// Buttons are ordered by label; use NewOrderedChoiceKeyboard to control the order.
+func NewChoiceKeyboard[T any](choices map[string]T) *ChoiceKeyboard[T] {
+	labels := make([]string, 0, len(choices))
+	for label := range choices {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	ordered := make([]Choice[T], 0, len(labels))
+	for _, label := range labels {
+		ordered = append(ordered, Choice[T]{Label: label, Value: choices[label]})
+	}
+	return NewOrderedChoiceKeyboard(ordered...)
+}
+
+// NewOrderedChoiceKeyboard creates a ChoiceKeyboard whose buttons appear in the given order.
+func NewOrderedChoiceKeyboard[T any](choices ...Choice[T]) *ChoiceKeyboard[T] {
+	return &ChoiceKeyboard[T]{choices: choices, perRow: 1}
+}
+
+// PerRow sets how many buttons are placed on each row. The default is one.
+func (ck *ChoiceKeyboard[T]) PerRow(perRow int) *ChoiceKeyboard[T] {
+	ck.perRow = perRow
+	return ck
+}
+
+// InvalidMessage sets the message shown when the answer matches none of the options.
+func (ck *ChoiceKeyboard[T]) InvalidMessage(message string) *ChoiceKeyboard[T] {
+	ck.message = message
+	return ck
+}
+
+// Keyboard returns the keyboard function for PromptBuilder.WithPromptKeyboard.
+func (ck *ChoiceKeyboard[T]) Keyboard() KeyboardFunc {
+	return func(ctx *Context) *PromptKeyboardBuilder {
+		return ButtonsFromSlice(NewPromptKeyboard(), ck.choices, ck.perRow,
+			func(choice Choice[T]) string { return choice.Label },
+			func(choice Choice[T]) interface{} { return choice.Value },
+		)
+	}
+}
+
+// Value resolves the user's answer to the value of the chosen option.
+// Button clicks carry the value itself; typed text is matched case-insensitively
+// against the labels.
+func (ck *ChoiceKeyboard[T]) Value(input string, buttonClick *ButtonClick) (T, bool) {
+	if buttonClick != nil {
+		value, ok := buttonClick.Data.(T)
+		return value, ok
+	}
+
+	trimmed := strings.TrimSpace(input)
+	for _, choice := range ck.choices {
+		if strings.EqualFold(trimmed, choice.Label) {
+			return choice.Value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Process wraps a handler that receives the chosen value. Answers that match no
+// option make the step retry with the invalid-choice message.
+func (ck *ChoiceKeyboard[T]) Process(handler func(ctx *Context, value T) ProcessResult) ProcessFunc {
+	return func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+		value, ok := ck.Value(input, buttonClick)
+		if !ok {
+			return validationRetry(ck.invalidChoice())
+		}
+		return handler(ctx, value)
+	}
+}
+
+func (ck *ChoiceKeyboard[T]) invalidChoice() error {
+	labels := make([]string, len(ck.choices))
+	for i, choice := range ck.choices {
+		labels[i] = choice.Label
+	}
+	return validationFailed(fmt.Sprintf("❗ Please choose one of: %s.", strings.Join(labels, ", ")), []string{ck.message})
+}
@@ -0,0 +1,60 @@
+package teleflow
+
+import (
+	"testing"
+)
+
+type testPlan int
+
+const (
+	testPlanFree testPlan = iota
+	testPlanPro
+)
+
+func TestChoiceKeyboard_Keyboard(t *testing.T) {
+	ck := NewChoiceKeyboard(map[string]testPlan{"Pro": testPlanPro, "Free": testPlanFree}).PerRow(2)
+	kb := ck.Keyboard()(nil)
+	markup := kb.Build()
+
+	if len(markup.InlineKeyboard) != 1 || len(markup.InlineKeyboard[0]) != 2 {
+		t.Fatalf("Expected one row with two buttons, got %v", markup.InlineKeyboard)
+	}
+	first := markup.InlineKeyboard[0][0]
+	if first.Text != "Free" || kb.uuidMapping[*first.CallbackData] != testPlanFree {
+		t.Errorf("Expected buttons sorted by label with typed values, got %q -> %v", first.Text, kb.uuidMapping[*first.CallbackData])
+	}
+}
+
+func TestChoiceKeyboard_Process(t *testing.T) {
+	ck := NewOrderedChoiceKeyboard(
+		Choice[testPlan]{Label: "Free", Value: testPlanFree},
+		Choice[testPlan]{Label: "Pro", Value: testPlanPro},
+	)
+	var got testPlan
+	process := ck.Process(func(ctx *Context, plan testPlan) ProcessResult {
+		got = plan
+		return NextStep()
+	})
+
+	if result := process(nil, "", &ButtonClick{Data: testPlanPro}); result.Action != actionNextStep || got != testPlanPro {
+		t.Errorf("Expected click to select Pro, got action %v and plan %v", result.Action, got)
+	}
+	if result := process(nil, " free ", nil); result.Action != actionNextStep || got != testPlanFree {
+		t.Errorf("Expected typed label to select Free, got action %v and plan %v", result.Action, got)
+	}
+
+	result := process(nil, "Enterprise", nil)
+	if result.Action != actionRetryStep || result.Prompt == nil || result.Prompt.Message != "❗ Please choose one of: Free, Pro." {
+		t.Errorf("Expected retry with choice list, got %+v", result)
+	}
+
+	// Data from another keyboard has the wrong type and is rejected
+	if result := process(nil, "", &ButtonClick{Data: "stale"}); result.Action != actionRetryStep {
+		t.Errorf("Expected retry for foreign button data, got %v", result.Action)
+	}
+
+	ck.InvalidMessage("Pick a plan")
+	if result := process(nil, "?", nil); result.Prompt.Message != "Pick a plan" {
+		t.Errorf("Expected custom message, got %v", result.Prompt.Message)
+	}
+}
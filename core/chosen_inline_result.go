@@ -0,0 +1,63 @@
+package teleflow
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ChosenInlineResultHandlerFunc handles the inline result a user picked and
+// sent to a chat.
+type ChosenInlineResultHandlerFunc func(ctx *Context, result *tgbotapi.ChosenInlineResult) error
+
+// HandleChosenInlineResult registers the handler for chosen_inline_result
+// updates, e.g. to log which results users pick. Telegram only sends them once
+// inline feedback is enabled with @BotFather's /setinlinefeedback. The context's
+// user is the user who picked the result; there is no chat, because the result
+// was sent to a chat the bot may not be a member of.
+//
+// If the result carried an inline keyboard, result.InlineMessageID identifies
+// the sent message, and ctx.EditInlineMessage can follow up on it.
+//
+// Example:
+//
+//	bot.HandleChosenInlineResult(func(ctx *teleflow.Context, result *tgbotapi.ChosenInlineResult) error {
+//		analytics.Track(ctx.UserID(), "inline_pick", result.ResultID, result.Query)
+//		if result.InlineMessageID == "" {
+//			return nil
+//		}
+//		return ctx.EditInlineMessage(result.InlineMessageID, "Poll: "+result.Query+" (voting open)")
+//	})
+func (b *Bot) HandleChosenInlineResult(handler ChosenInlineResultHandlerFunc) {
+	wrappedHandler := func(ctx *Context) error {
+		return handler(ctx, ctx.update.ChosenInlineResult)
+	}
+	b.chosenInlineResultHandler = b.applyMiddleware(wrappedHandler)
+}
+
+// EditInlineMessage replaces the text of a message sent via the bot in inline
+// mode, identified by its inline message ID. The message's inline keyboard is
+// removed.
+func (c *Context) EditInlineMessage(inlineMessageID, text string) error {
+	edit := tgbotapi.EditMessageTextConfig{
+		BaseEdit:              tgbotapi.BaseEdit{InlineMessageID: inlineMessageID},
+		Text:                  text,
+		DisableWebPagePreview: true,
+	}
+	if _, err := c.telegramClient.Request(edit); err != nil {
+		return fmt.Errorf("failed to edit inline message: %w", err)
+	}
+	return nil
+}
+
+// handleChosenInlineResult runs the registered chosen inline result handler.
+func (b *Bot) handleChosenInlineResult(ctx *Context) {
+	if b.chosenInlineResultHandler == nil {
+		return
+	}
+	if err := b.stats.track("chosen_inline_result", func() error { return b.chosenInlineResultHandler(ctx) }); err != nil {
+		log.Printf("Chosen inline result handler error for UserID %d: %v", ctx.UserID(), err)
+		b.deadLetter(ctx, fmt.Errorf("chosen inline result: %w", err), 1)
+	}
+}
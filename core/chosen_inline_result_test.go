@@ -0,0 +1,38 @@
+package teleflow
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestChosenInlineResult(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		t.Error("Chosen inline results must not reach text handlers")
+		return nil
+	})
+
+	var picked []string
+	bot.HandleChosenInlineResult(func(ctx *Context, result *tgbotapi.ChosenInlineResult) error {
+		if ctx.UserID() != 7 || ctx.ChatID() != 0 {
+			t.Errorf("Expected user 7 without a chat, got %d/%d", ctx.UserID(), ctx.ChatID())
+		}
+		picked = append(picked, result.ResultID)
+		return ctx.EditInlineMessage(result.InlineMessageID, "Poll: "+result.Query+" (closed)")
+	})
+	bot.processUpdate(tgbotapi.Update{ChosenInlineResult: &tgbotapi.ChosenInlineResult{
+		ResultID: "poll_1", From: &tgbotapi.User{ID: 7}, InlineMessageID: "inline_1", Query: "lunch?",
+	}})
+
+	if len(picked) != 1 || picked[0] != "poll_1" {
+		t.Fatalf("Expected the chosen result, got %v", picked)
+	}
+	if len(client.RequestCalls) != 1 {
+		t.Fatalf("Expected one edit, got %d", len(client.RequestCalls))
+	}
+	edit := client.RequestCalls[0].(tgbotapi.EditMessageTextConfig)
+	if edit.InlineMessageID != "inline_1" || edit.Text != "Poll: lunch? (closed)" {
+		t.Errorf("Unexpected edit %+v", edit)
+	}
+}
@@ -0,0 +1,60 @@
+package teleflow
+
+import "time"
+
+// Clock is the source of time for flow timestamps, timeouts and the built-in
+// rate limiters. Tests can inject a fake clock with WithClock and move time
+// forward instead of sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc calls f in its own goroutine once d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a call scheduled with Clock.AfterFunc.
+type Timer interface {
+	// Stop prevents the call from happening. It returns false if the call
+	// already happened or the timer was stopped before.
+	Stop() bool
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// SystemClock returns the Clock backed by the time package, used by default.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// WithClock makes the bot read the time from clock.
+//
+// Example:
+//
+//	clock := teleflowtest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+//	bot, _ := teleflowtest.NewBot(t, teleflow.WithClock(clock))
+//	// ...
+//	clock.Advance(2 * time.Minute) // expire the captcha without waiting
+func WithClock(clock Clock) BotOption {
+	return func(b *Bot) {
+		b.clock = clock
+	}
+}
+
+// Now returns the current time of the bot's clock. Middleware and handlers
+// should use it instead of time.Now so that tests can control time.
+func (c *Context) Now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
@@ -0,0 +1,53 @@
+package teleflow
+
+import (
+	"testing"
+	"time"
+)
+
+// stepClock is a Clock whose time is set by the test.
+type stepClock struct {
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	return c.now
+}
+
+func (c *stepClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func TestRateLimitMiddleware_UsesClock(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	handler := &mockHandler{}
+	limited := RateLimitMiddleware(1)(handler.Handle)
+
+	run := func() {
+		ctx := createMiddlewareTestContext("message", 42)
+		ctx.clock = clock
+		if err := limited(ctx); err != nil {
+			t.Fatalf("Handler failed: %v", err)
+		}
+	}
+
+	run()
+	clock.now = clock.now.Add(30 * time.Second)
+	run()
+	if handler.callCount != 1 {
+		t.Fatalf("Expected second request within a minute to be limited, got %d calls", handler.callCount)
+	}
+
+	clock.now = clock.now.Add(31 * time.Second)
+	run()
+	if handler.callCount != 2 {
+		t.Errorf("Expected request after a minute to pass, got %d calls", handler.callCount)
+	}
+}
+
+func TestContext_NowDefaultsToSystemClock(t *testing.T) {
+	ctx := createMiddlewareTestContext("message", 42)
+	if since := time.Since(ctx.Now()); since < 0 || since > time.Minute {
+		t.Errorf("Expected system time, got %v", ctx.Now())
+	}
+}
@@ -0,0 +1,93 @@
+package teleflow
+
+import (
+	"sort"
+	"strings"
+)
+
+// CommandRouter dispatches a command to subcommand handlers based on the first
+// word of its arguments, so "/admin ban 42" runs the "ban" handler of the "admin"
+// command with args "42". Routers are returned by Bot.HandleCommand and can be
+// nested with Group for deeper hierarchies.
+type CommandRouter struct {
+	name     string                    // Command or subcommand name
+	path     string                    // Full invocation, e.g. "/admin config"
+	handler  CommandHandlerFunc        // Runs when no subcommand matches (may be nil)
+	subs     map[string]*CommandRouter // Subcommands keyed by lower-case name
+	subOrder []string                  // Subcommand names in registration order
+}
+
+// newCommandRouter creates a router for a command with an optional fallback handler.
+func newCommandRouter(name, path string, handler CommandHandlerFunc) *CommandRouter {
+	return &CommandRouter{
+		name:    name,
+		path:    path,
+		handler: handler,
+		subs:    make(map[string]*CommandRouter),
+	}
+}
+
+// Sub registers a handler for a subcommand and returns the parent router, so
+// sibling subcommands can be chained. The handler receives the subcommand name
+// and the remaining arguments.
+//
+// Example:
+//
+//	bot.HandleCommand("admin", nil).
+//		Sub("users", func(ctx *teleflow.Context, command, args string) error {
+//			return ctx.SendPromptText("Listing users...")
+//		}).
+//		Sub("ban", banUser)
+func (r *CommandRouter) Sub(name string, handler CommandHandlerFunc) *CommandRouter {
+	r.addSub(name, handler)
+	return r
+}
+
+// Group registers a subcommand that has subcommands of its own and returns its
+// router. The optional handler runs when none of the nested subcommands match.
+//
+// Example:
+//
+//	admin := bot.HandleCommand("admin", nil)
+//	admin.Group("config", nil).
+//		Sub("get", getConfig).
+//		Sub("set", setConfig) // "/admin config set key value"
+func (r *CommandRouter) Group(name string, handler CommandHandlerFunc) *CommandRouter {
+	return r.addSub(name, handler)
+}
+
+// addSub creates or replaces the router for a subcommand.
+func (r *CommandRouter) addSub(name string, handler CommandHandlerFunc) *CommandRouter {
+	key := strings.ToLower(name)
+	if _, exists := r.subs[key]; !exists {
+		r.subOrder = append(r.subOrder, name)
+	}
+	sub := newCommandRouter(name, r.path+" "+name, handler)
+	r.subs[key] = sub
+	return sub
+}
+
+// dispatch routes the arguments to a matching subcommand, falling back to the
+// router's own handler or a usage message listing the available subcommands.
+func (r *CommandRouter) dispatch(ctx *Context, command, args string) error {
+	trimmed := strings.TrimSpace(args)
+	first, rest, _ := strings.Cut(trimmed, " ")
+	if sub, ok := r.subs[strings.ToLower(first)]; ok && first != "" {
+		return sub.dispatch(ctx, sub.name, strings.TrimSpace(rest))
+	}
+
+	if r.handler != nil {
+		return r.handler(ctx, command, args)
+	}
+	if len(r.subs) > 0 {
+		return ctx.sendSimpleText("Usage: " + r.usage())
+	}
+	return nil
+}
+
+// usage describes the available subcommands, e.g. "/admin <ban | users>".
+func (r *CommandRouter) usage() string {
+	names := append([]string(nil), r.subOrder...)
+	sort.Strings(names)
+	return r.path + " <" + strings.Join(names, " | ") + ">"
+}
@@ -0,0 +1,98 @@
+package teleflow
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func createCommandUpdate(command, text string) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: 1,
+		Message: &tgbotapi.Message{
+			MessageID: 1,
+			From:      &tgbotapi.User{ID: 42},
+			Chat:      &tgbotapi.Chat{ID: 42, Type: "private"},
+			Text:      text,
+			Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command) + 1}},
+		},
+	}
+}
+
+func TestCommandRouter_Subcommands(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	var called, gotCommand, gotArgs string
+	record := func(name string) CommandHandlerFunc {
+		return func(ctx *Context, command, args string) error {
+			called, gotCommand, gotArgs = name, command, args
+			return nil
+		}
+	}
+
+	admin := bot.HandleCommand("admin", nil).
+		Sub("users", record("users")).
+		Sub("ban", record("ban"))
+	admin.Group("config", record("config")).
+		Sub("set", record("config_set"))
+
+	tests := []struct {
+		text            string
+		expectedHandler string
+		expectedCommand string
+		expectedArgs    string
+	}{
+		{"/admin users", "users", "users", ""},
+		{"/admin BAN 42 spam", "ban", "ban", "42 spam"},
+		{"/admin config set lang en", "config_set", "set", "lang en"},
+		{"/admin config show", "config", "config", "show"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			called, gotCommand, gotArgs = "", "", ""
+			bot.processUpdate(createCommandUpdate("admin", tt.text))
+			if called != tt.expectedHandler || gotCommand != tt.expectedCommand || gotArgs != tt.expectedArgs {
+				t.Errorf("Expected %s(%q, %q), got %s(%q, %q)",
+					tt.expectedHandler, tt.expectedCommand, tt.expectedArgs, called, gotCommand, gotArgs)
+			}
+		})
+	}
+
+	// Unknown subcommand without a fallback handler replies with usage
+	called = ""
+	mockClient.SendCalls = nil
+	bot.processUpdate(createCommandUpdate("admin", "/admin unknown"))
+	if called != "" {
+		t.Errorf("Expected no handler for unknown subcommand, got %s", called)
+	}
+	if len(mockClient.SendCalls) != 1 {
+		t.Fatalf("Expected usage message, got %d messages", len(mockClient.SendCalls))
+	}
+	if msg := mockClient.SendCalls[0].(tgbotapi.MessageConfig); msg.Text != "Usage: /admin <ban | config | users>" {
+		t.Errorf("Unexpected usage message: %q", msg.Text)
+	}
+}
+
+func TestCommandRouter_FallbackHandler(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	var called string
+	bot.HandleCommand("report", func(ctx *Context, command, args string) error {
+		called = "report"
+		return nil
+	}).Sub("weekly", func(ctx *Context, command, args string) error {
+		called = "weekly"
+		return nil
+	})
+
+	bot.processUpdate(createCommandUpdate("report", "/report"))
+	if called != "report" {
+		t.Errorf("Expected fallback handler, got %q", called)
+	}
+
+	bot.processUpdate(createCommandUpdate("report", "/report weekly"))
+	if called != "weekly" {
+		t.Errorf("Expected weekly subcommand, got %q", called)
+	}
+}
@@ -53,7 +53,7 @@ func TestConcurrentFlowDataOperations(t *testing.T) {
 	ctx := createFlowTestContext(userID, "test input", nil)
 	// Set the flowOps so SetFlowData/GetFlowData work
 	ctx.flowOps = fm
-	err := fm.startFlow(userID, "concurrent-test-flow", ctx)
+	err := fm.startFlow(userID, userID, "concurrent-test-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
@@ -77,7 +77,7 @@ func TestConcurrentFlowDataOperations(t *testing.T) {
 	}
 
 	// Verify the flow completed (user should no longer be in flow)
-	if fm.isUserInFlow(userID) {
+	if fm.isUserInFlow(userID, userID) {
 		t.Error("User should not be in flow after completion")
 	}
 }
@@ -107,7 +107,7 @@ func TestConcurrentFlowDataAccess(t *testing.T) {
 	ctx := createFlowTestContext(userID, "test", nil)
 
 	// Start the flow
-	err := fm.startFlow(userID, "data-access-test", ctx)
+	err := fm.startFlow(userID, userID, "data-access-test", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
@@ -126,7 +126,7 @@ func TestConcurrentFlowDataAccess(t *testing.T) {
 			for j := 0; j < numOperations; j++ {
 				key := fmt.Sprintf("key_%d_%d", index, j)
 				value := fmt.Sprintf("value_%d_%d", index, j)
-				err := fm.setUserFlowData(userID, key, value)
+				err := fm.setUserFlowData(userID, userID, key, value)
 				if err != nil {
 					t.Errorf("setUserFlowData failed: %v", err)
 				}
@@ -141,7 +141,7 @@ func TestConcurrentFlowDataAccess(t *testing.T) {
 			for j := 0; j < numOperations; j++ {
 				key := fmt.Sprintf("key_%d_%d", index, j)
 				// Try to get the data (might not exist yet, which is fine)
-				_, _ = fm.getUserFlowData(userID, key)
+				_, _ = fm.getUserFlowData(userID, userID, key)
 			}
 		}(i)
 	}
@@ -185,7 +185,7 @@ func TestMixedMutexOperations(t *testing.T) {
 	userID := int64(12345)
 	ctx := createFlowTestContext(userID, "test", nil)
 
-	err := fm.startFlow(userID, "mixed-ops-test", ctx)
+	err := fm.startFlow(userID, userID, "mixed-ops-test", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
@@ -204,18 +204,18 @@ func TestMixedMutexOperations(t *testing.T) {
 				switch j % 4 {
 				case 0:
 					// Flow state operation
-					_ = fm.isUserInFlow(userID)
+					_ = fm.isUserInFlow(userID, userID)
 				case 1:
 					// Flow data operation
 					key := fmt.Sprintf("mixed_key_%d_%d", index, j)
-					_ = fm.setUserFlowData(userID, key, "value")
+					_ = fm.setUserFlowData(userID, userID, key, "value")
 				case 2:
 					// Flow data operation
 					key := fmt.Sprintf("mixed_key_%d_%d", index, j-1)
-					_, _ = fm.getUserFlowData(userID, key)
+					_, _ = fm.getUserFlowData(userID, userID, key)
 				case 3:
 					// Flow state operation (reading user flows map)
-					_ = fm.isUserInFlow(userID)
+					_ = fm.isUserInFlow(userID, userID)
 				}
 			}
 		}(i)
@@ -1,7 +1,10 @@
 package teleflow
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -16,6 +19,7 @@ type Context struct {
 	flowOps         ContextFlowOperations // Interface for flow operations
 	promptSender    PromptSender          // Component for sending rich prompts
 	accessManager   AccessManager         // Access control manager
+	clock           Clock                 // Bot's clock, nil for the system clock
 
 	update tgbotapi.Update        // The original Telegram update
 	data   map[string]interface{} // Context-specific data storage
@@ -25,7 +29,36 @@ type Context struct {
 	isGroup   bool  // True if the update is from a group chat
 	isChannel bool  // True if the update is from a channel
 
+	flowScope FlowScope // Scope of the flow bound to this update, if any
+
 	pendingReplyKeyboard *ReplyKeyboard // Reply keyboard to be attached to next message
+
+	sentPrompts []sentPrompt // Messages sent by the prompt composer for this context
+	editTarget  sentPrompt   // Message the next prompt should replace instead of sending a new one
+
+	keyboardRefreshed bool // UpdateKeyboard changed the flow's last prompt during this update
+
+	cancelReason CancelReason // Why the flow is being cancelled, set for OnCancel handlers
+	flowPrompt   *flowPrompt  // Flow step whose prompt is being composed
+
+	reaction *ReactionInput // Reaction processed as a flow step's answer
+	job      *flowJob       // Asynchronous step job running with this context
+	event    interface{}    // Out-of-band event delivered by ResumeFlow
+
+	businessConnectionID string // Business connection the update arrived through, used for replies
+
+	passport    *PassportData // Decrypted Telegram Passport data of the message
+	passportErr error         // Why the message's Passport data could not be decrypted
+
+	retained bool // The context is used after its update was handled and must not be pooled
+}
+
+// sentPrompt describes a message delivered by the prompt composer.
+type sentPrompt struct {
+	ChatID         int64
+	MessageID      int
+	InlineKeyboard bool // The message was sent with an inline keyboard attached
+	Photo          bool // The message is a photo with a caption
 }
 
 // newContext creates a new Context instance for handling a Telegram update.
@@ -39,24 +72,33 @@ func newContext(
 	ps PromptSender,
 	am AccessManager,
 ) *Context {
-	ctx := &Context{
-		telegramClient:  client,
-		templateManager: tm,
-		flowOps:         fo,
-		promptSender:    ps,
-		accessManager:   am,
-		update:          update,
-		data:            make(map[string]interface{}),
-	}
-
-	ctx.userID = ctx.extractUserID(update)
-	ctx.chatID = ctx.extractChatID(update)
-	ctx.isGroup = update.Message != nil && (update.Message.Chat.IsGroup() || update.Message.Chat.IsSuperGroup())
-	ctx.isChannel = update.Message != nil && update.Message.Chat.IsChannel()
-
+	ctx := &Context{data: make(map[string]interface{})}
+	ctx.init(update, client, tm, fo, ps, am)
 	return ctx
 }
 
+// init binds an empty context to an update and the bot's components.
+func (c *Context) init(
+	update tgbotapi.Update,
+	client TelegramClient,
+	tm TemplateManager,
+	fo ContextFlowOperations,
+	ps PromptSender,
+	am AccessManager,
+) {
+	c.telegramClient = client
+	c.templateManager = tm
+	c.flowOps = fo
+	c.promptSender = ps
+	c.accessManager = am
+	c.update = update
+
+	c.userID = c.extractUserID(update)
+	c.chatID = c.extractChatID(update)
+	c.isGroup = update.Message != nil && (update.Message.Chat.IsGroup() || update.Message.Chat.IsSuperGroup())
+	c.isChannel = update.Message != nil && update.Message.Chat.IsChannel()
+}
+
 // UserID returns the Telegram user ID associated with this update.
 // This ID uniquely identifies the user across all chats and is consistent
 // across all interactions with the bot.
@@ -93,7 +135,7 @@ func (c *Context) SetFlowData(key string, value interface{}) error {
 		return fmt.Errorf("user not in a flow, cannot set flow data")
 	}
 
-	return c.flowOps.setUserFlowData(c.UserID(), key, value)
+	return c.flowOps.setUserFlowData(c.UserID(), c.ChatID(), key, value)
 }
 
 // GetFlowData retrieves data specific to the user's current flow.
@@ -104,27 +146,92 @@ func (c *Context) GetFlowData(key string) (interface{}, bool) {
 		return nil, false
 	}
 
-	return c.flowOps.getUserFlowData(c.UserID(), key)
+	return c.flowOps.getUserFlowData(c.UserID(), c.ChatID(), key)
 }
 
 // StartFlow initiates a named flow for the current user.
 // The flow must be previously registered with the bot using RegisterFlow.
-// Returns an error if the flow doesn't exist or cannot be started.
+// Returns an error if the flow doesn't exist or cannot be started. If the
+// AccessManager denies the flow's permission, the user is shown the denial prompt
+// and the error wraps ErrFlowPermissionDenied.
 func (c *Context) StartFlow(flowName string) error {
 
-	return c.flowOps.startFlow(c.UserID(), flowName, c)
+	return c.flowOps.startFlow(c.UserID(), c.ChatID(), flowName, c)
+}
+
+// StartFlowWith initiates a named flow for the current user with the given
+// initial flow data. Unlike StartFlow, the context's own data is not copied into
+// the flow; the map is copied, so later changes to it do not affect the flow.
+//
+// Example:
+//
+//	ctx.StartFlowWith("transfer", map[string]interface{}{"preset_amount": 100})
+func (c *Context) StartFlowWith(flowName string, data map[string]interface{}) error {
+	return c.flowOps.startFlowWith(c.UserID(), c.ChatID(), flowName, flowStartOptions{Data: data}, c)
+}
+
+// StartFlowAt initiates a named flow for the current user at the given step
+// instead of the first one, e.g. to resume a checkout from a reminder. Like
+// StartFlow, the context's data is copied into the flow. Returns an error if the
+// step does not exist in the flow.
+//
+// Example:
+//
+//	ctx.Set("order_id", orderID)
+//	ctx.StartFlowAt("order", "select_shipping")
+func (c *Context) StartFlowAt(flowName, stepName string) error {
+	return c.flowOps.startFlowWith(c.UserID(), c.ChatID(), flowName, flowStartOptions{Data: c.data, Step: stepName}, c)
 }
 
 // isUserInFlow checks if the current user is in any active flow.
 // This is used internally to determine flow state.
 func (c *Context) isUserInFlow() bool {
-	return c.flowOps.isUserInFlow(c.UserID())
+	return c.flowOps.isUserInFlow(c.UserID(), c.ChatID())
+}
+
+// CurrentFlow returns the name and current step of the flow the user is in, and
+// false if the user is not in a flow.
+func (c *Context) CurrentFlow() (flowName, stepName string, ok bool) {
+	if c.flowOps == nil {
+		return "", "", false
+	}
+	return c.flowOps.currentStep(c.UserID(), c.ChatID())
 }
 
 // CancelFlow cancels the current user's active flow.
 // If the user is not in a flow, this operation has no effect.
 func (c *Context) CancelFlow() {
-	c.flowOps.cancelFlow(c.UserID())
+	c.flowOps.cancelFlow(c.UserID(), c.ChatID(), c)
+}
+
+// CancelReason returns why the flow is being cancelled. It is set while the
+// flow's OnCancel handler runs and empty otherwise.
+func (c *Context) CancelReason() CancelReason {
+	return c.cancelReason
+}
+
+// UpdateKeyboard replaces the inline keyboard of the flow's last prompt without
+// resending the message, which suits keyboards that change as the user clicks them
+// (counters, toggles, pagination). A nil or empty keyboard removes the keyboard.
+// Returns an error if the user is not in a flow or the flow has not sent a prompt yet.
+//
+// A step that returns Retry() without a prompt after calling UpdateKeyboard stays on
+// the step without sending its prompt again and without counting a retry attempt.
+//
+// Example:
+//
+//	Process(func(ctx *teleflow.Context, input string, click *teleflow.ButtonClick) teleflow.ProcessResult {
+//		if click != nil && click.Data == "toggle" {
+//			enabled = !enabled
+//			ctx.UpdateKeyboard(settingsKeyboard(enabled))
+//			return teleflow.Retry()
+//		}
+//		return teleflow.NextStep()
+//	})
+func (c *Context) UpdateKeyboard(keyboard *PromptKeyboardBuilder) error {
+	return c.flowOps.updateKeyboard(c.UserID(), c.ChatID(), func(*Context) *PromptKeyboardBuilder {
+		return keyboard
+	}, c)
 }
 
 // SendPrompt sends a rich prompt message with optional images, keyboards, and templates.
@@ -146,10 +253,20 @@ func (c *Context) SendPrompt(prompt *PromptConfig) error {
 		return fmt.Errorf("PromptSender not initialized - this should not happen as initialization is automatic")
 	}
 
+	var sequence []*PromptConfig
+	for _, part := range prompt.Sequence {
+		if part != nil {
+			sequence = append(sequence, &PromptConfig{Message: part.Message, Image: part.Image, TemplateData: part.TemplateData})
+		}
+	}
 	return c.promptSender.ComposeAndSend(c, &PromptConfig{
 		Message:      prompt.Message,
 		Image:        prompt.Image,
 		TemplateData: prompt.TemplateData,
+		Sequence:     sequence,
+
+		RemoveReplyKeyboard:  prompt.RemoveReplyKeyboard,
+		OneTimeReplyKeyboard: prompt.OneTimeReplyKeyboard,
 	})
 }
 
@@ -179,6 +296,36 @@ func (c *Context) SendPromptWithTemplate(templateName string, data map[string]in
 	})
 }
 
+// SendTo sends a prompt to another chat, such as an admin channel or another user's
+// private chat. Like SendPrompt, inline keyboards are not sent. Messages sent this
+// way are not tracked by the current flow.
+//
+// Example:
+//
+//	err := ctx.SendTo(recipientID, &teleflow.PromptConfig{
+//		Message: "💸 You received a transfer!",
+//	})
+func (c *Context) SendTo(chatID int64, prompt *PromptConfig) error {
+	target := c.forChat(chatID)
+	target.sentPrompts = nil
+	target.editTarget = sentPrompt{}
+	return target.SendPrompt(prompt)
+}
+
+// SendTemplateTo sends a named template to another chat.
+//
+// Example:
+//
+//	err := ctx.SendTemplateTo(adminChannelID, "new_order", map[string]interface{}{
+//		"order_id": orderID,
+//	})
+func (c *Context) SendTemplateTo(chatID int64, templateName string, data map[string]interface{}) error {
+	return c.SendTo(chatID, &PromptConfig{
+		Message:      "template:" + templateName,
+		TemplateData: data,
+	})
+}
+
 // AddTemplate registers a new message template with the specified parse mode.
 // Templates support Go template syntax and can include custom functions.
 //
@@ -226,6 +373,183 @@ func (c *Context) IsChannel() bool {
 	return c.isChannel
 }
 
+// FileInput describes a file attached to the incoming message.
+// Photos report the largest available size; MimeType is "image/jpeg" for photos
+// since Telegram always recompresses them.
+type FileInput struct {
+	Kind         string // "photo", "document", "video", "animation", "audio" or "voice"
+	FileID       string // File identifier, usable to download or resend the file
+	FileUniqueID string // Identifier that stays the same over time and across bots
+	FileName     string // Original file name (if provided by the client)
+	MimeType     string // MIME type as reported by Telegram
+	FileSize     int    // File size in bytes (0 if unknown)
+	Width        int    // Width in pixels for photos, videos and animations
+	Height       int    // Height in pixels for photos, videos and animations
+}
+
+// File returns the file attached to the current message, or nil if the update
+// does not carry a photo, document, video, animation, audio or voice message.
+//
+// Example:
+//
+//	if file := ctx.File(); file != nil {
+//		log.Printf("Received %s %s (%d bytes)", file.Kind, file.FileName, file.FileSize)
+//	}
+func (c *Context) File() *FileInput {
+	msg := c.update.Message
+	if msg == nil {
+		return nil
+	}
+
+	switch {
+	case len(msg.Photo) > 0:
+		photo := msg.Photo[len(msg.Photo)-1]
+		return &FileInput{Kind: "photo", FileID: photo.FileID, FileUniqueID: photo.FileUniqueID,
+			MimeType: "image/jpeg", FileSize: photo.FileSize, Width: photo.Width, Height: photo.Height}
+	case msg.Animation != nil:
+		a := msg.Animation
+		return &FileInput{Kind: "animation", FileID: a.FileID, FileUniqueID: a.FileUniqueID, FileName: a.FileName,
+			MimeType: a.MimeType, FileSize: a.FileSize, Width: a.Width, Height: a.Height}
+	case msg.Document != nil:
+		d := msg.Document
+		return &FileInput{Kind: "document", FileID: d.FileID, FileUniqueID: d.FileUniqueID, FileName: d.FileName,
+			MimeType: d.MimeType, FileSize: d.FileSize}
+	case msg.Video != nil:
+		v := msg.Video
+		return &FileInput{Kind: "video", FileID: v.FileID, FileUniqueID: v.FileUniqueID, FileName: v.FileName,
+			MimeType: v.MimeType, FileSize: v.FileSize, Width: v.Width, Height: v.Height}
+	case msg.Audio != nil:
+		a := msg.Audio
+		return &FileInput{Kind: "audio", FileID: a.FileID, FileUniqueID: a.FileUniqueID, FileName: a.FileName,
+			MimeType: a.MimeType, FileSize: a.FileSize}
+	case msg.Voice != nil:
+		v := msg.Voice
+		return &FileInput{Kind: "voice", FileID: v.FileID, FileUniqueID: v.FileUniqueID,
+			MimeType: v.MimeType, FileSize: v.FileSize}
+	}
+	return nil
+}
+
+// forUser returns a copy of the context that acts on behalf of another user in the same chat.
+// The copy starts with empty context data and no pending reply keyboard.
+func (c *Context) forUser(userID int64) *Context {
+	clone := *c
+	clone.userID = userID
+	clone.data = make(map[string]interface{})
+	clone.pendingReplyKeyboard = nil
+	return &clone
+}
+
+// forChat returns a copy of the context that targets another chat.
+// It is used to act on messages a flow sent to a chat other than the current one.
+func (c *Context) forChat(chatID int64) *Context {
+	clone := *c
+	clone.chatID = chatID
+	clone.pendingReplyKeyboard = nil
+	return &clone
+}
+
+// callbackOwner returns the key under which inline keyboard callback mappings are
+// stored: the state key of the flow bound to the update, so that the user's flows
+// in other chats keep their buttons. Chat-scoped flows share their buttons
+// between all members, so mappings belong to the chat.
+func (c *Context) callbackOwner() flowKey {
+	return newFlowKey(c.flowScope, c.userID, c.chatID)
+}
+
+// isChatAdmin reports whether the current user is an administrator or the creator
+// of the current chat. Private chats always report true.
+func (c *Context) isChatAdmin() bool {
+	admin, err := c.lookupChatAdmin()
+	return err == nil && admin
+}
+
+// lookupChatAdmin asks Telegram whether the current user is an administrator or
+// the creator of the current chat. Private chats always report true.
+func (c *Context) lookupChatAdmin() (bool, error) {
+	if c.chatID == c.userID {
+		return true, nil
+	}
+
+	memberCfg := tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: c.chatID,
+			UserID: c.userID,
+		},
+	}
+	resp, err := c.telegramClient.Request(memberCfg)
+	if err != nil {
+		return false, err
+	}
+
+	var member tgbotapi.ChatMember
+	if err := json.Unmarshal(resp.Result, &member); err != nil {
+		return false, err
+	}
+	return member.IsAdministrator() || member.IsCreator(), nil
+}
+
+// chatAdminTTL is how long a chatAdminCache trusts an administrator lookup.
+const chatAdminTTL = time.Minute
+
+// chatAdminKey identifies a user within a chat.
+type chatAdminKey struct {
+	ChatID int64
+	UserID int64
+}
+
+// chatAdminEntry holds a cached administrator lookup and its expiry time.
+type chatAdminEntry struct {
+	admin   bool
+	expires time.Time
+}
+
+// chatAdminCache remembers for chatAdminTTL whether users administer chats, so
+// checks made on every group message do not call getChatMember each time.
+// Failed lookups are not cached.
+type chatAdminCache struct {
+	mu        sync.Mutex
+	entries   map[chatAdminKey]chatAdminEntry
+	nextPrune time.Time // When expired entries are next removed
+}
+
+// newChatAdminCache creates an empty chatAdminCache.
+func newChatAdminCache() *chatAdminCache {
+	return &chatAdminCache{entries: make(map[chatAdminKey]chatAdminEntry)}
+}
+
+// isChatAdmin reports whether the context's user administers its chat, using a
+// cached result while it is fresh.
+func (cache *chatAdminCache) isChatAdmin(ctx *Context) bool {
+	key := chatAdminKey{ChatID: ctx.ChatID(), UserID: ctx.UserID()}
+	now := ctx.Now()
+
+	cache.mu.Lock()
+	entry, ok := cache.entries[key]
+	cache.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.admin
+	}
+
+	admin, err := ctx.lookupChatAdmin()
+	if err != nil {
+		return false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if !now.Before(cache.nextPrune) {
+		for k, e := range cache.entries {
+			if !now.Before(e.expires) {
+				delete(cache.entries, k)
+			}
+		}
+		cache.nextPrune = now.Add(chatAdminTTL)
+	}
+	cache.entries[key] = chatAdminEntry{admin: admin, expires: now.Add(chatAdminTTL)}
+	return admin
+}
+
 // getPermissionContext creates a PermissionContext for access control decisions.
 // Returns nil if no access manager is configured.
 func (c *Context) getPermissionContext() *PermissionContext {
@@ -242,7 +566,8 @@ func (c *Context) getPermissionContext() *PermissionContext {
 }
 
 // extractUserID extracts the user ID from different types of Telegram updates.
-// Supports both message updates and callback query updates.
+// Supports message, callback query, pre-checkout, shipping query and chosen
+// inline result updates.
 func (c *Context) extractUserID(update tgbotapi.Update) int64 {
 	if update.Message != nil {
 		return update.Message.From.ID
@@ -250,6 +575,15 @@ func (c *Context) extractUserID(update tgbotapi.Update) int64 {
 	if update.CallbackQuery != nil {
 		return update.CallbackQuery.From.ID
 	}
+	if update.PreCheckoutQuery != nil {
+		return update.PreCheckoutQuery.From.ID
+	}
+	if update.ShippingQuery != nil {
+		return update.ShippingQuery.From.ID
+	}
+	if update.ChosenInlineResult != nil {
+		return update.ChosenInlineResult.From.ID
+	}
 	return 0
 }
 
@@ -266,7 +600,7 @@ func (c *Context) answerCallbackQuery(text string) error {
 }
 
 // extractChatID extracts the chat ID from different types of Telegram updates.
-// Supports both message updates and callback query updates.
+// Supports message, callback query, pre-checkout and shipping query updates.
 func (c *Context) extractChatID(update tgbotapi.Update) int64 {
 	if update.Message != nil {
 		return update.Message.Chat.ID
@@ -274,6 +608,12 @@ func (c *Context) extractChatID(update tgbotapi.Update) int64 {
 	if update.CallbackQuery != nil && update.CallbackQuery.Message != nil {
 		return update.CallbackQuery.Message.Chat.ID
 	}
+	if update.PreCheckoutQuery != nil {
+		return update.PreCheckoutQuery.From.ID // The query carries no chat; use the private chat
+	}
+	if update.ShippingQuery != nil {
+		return update.ShippingQuery.From.ID
+	}
 	return 0
 }
 
@@ -289,10 +629,31 @@ func (c *Context) sendSimpleText(text string) error {
 		c.pendingReplyKeyboard = nil // Clear after use
 	}
 
+	// Chats of business accounts can only be written to through their connection
+	if c.businessConnectionID != "" {
+		params := tgbotapi.Params{"business_connection_id": c.businessConnectionID, "text": text}
+		params.AddNonZero64("chat_id", c.ChatID())
+		if err := params.AddInterface("reply_markup", msg.ReplyMarkup); err != nil {
+			return err
+		}
+		_, err := sendRawMessage(c.telegramClient, "sendMessage", params)
+		return err
+	}
+
 	_, err := c.telegramClient.Send(msg)
 	return err
 }
 
+// DeleteMessages deletes messages of the current chat in as few requests as possible,
+// using Telegram's deleteMessages batch method.
+//
+// Example:
+//
+//	err := ctx.DeleteMessages([]int{noticeID, receiptID})
+func (c *Context) DeleteMessages(messageIDs []int) error {
+	return deleteMessages(c.telegramClient, c.chatID, messageIDs)
+}
+
 // SetPendingReplyKeyboard sets a reply keyboard to be attached to the next outgoing message.
 // The keyboard will be automatically attached and cleared when the next message is sent.
 //
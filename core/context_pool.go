@@ -0,0 +1,77 @@
+package teleflow
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// contextPool recycles the contexts of handled updates when WithContextPooling is set.
+var contextPool = sync.Pool{
+	New: func() interface{} {
+		return &Context{data: make(map[string]interface{})}
+	},
+}
+
+// WithContextPooling reuses Context values between updates instead of allocating a
+// new one for each, which lowers garbage collection pressure for bots that handle
+// many updates per second.
+//
+// With pooling enabled a Context is only valid until its update has been handled.
+// Handlers and middleware must not keep it, or anything obtained from it, for
+// later use, e.g. in a goroutine, unless they call ctx.Retain first.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithContextPooling())
+func WithContextPooling() BotOption {
+	return func(b *Bot) {
+		b.poolContexts = true
+	}
+}
+
+// Retain keeps the context usable after its update has been handled. It is only
+// needed with WithContextPooling, and must be called before the handler returns.
+//
+// Example:
+//
+//	ctx.Retain()
+//	go func() {
+//		report := buildReport()
+//		ctx.SendPromptText(report)
+//	}()
+func (c *Context) Retain() {
+	c.retained = true
+}
+
+// acquireContext returns a pooled context bound to the update.
+func acquireContext(
+	update tgbotapi.Update,
+	client TelegramClient,
+	tm TemplateManager,
+	fo ContextFlowOperations,
+	ps PromptSender,
+	am AccessManager,
+) *Context {
+	ctx := contextPool.Get().(*Context)
+	ctx.init(update, client, tm, fo, ps, am)
+	return ctx
+}
+
+// releaseContext returns a context to the pool unless it was retained.
+func releaseContext(ctx *Context) {
+	if ctx.retained {
+		return
+	}
+	ctx.reset()
+	contextPool.Put(ctx)
+}
+
+// reset clears everything a context learned while handling an update. The data
+// map is emptied and kept to save its allocation; other references are dropped so
+// that a pooled context does not keep old updates or flow messages alive.
+func (c *Context) reset() {
+	data := c.data
+	clear(data)
+	*c = Context{data: data}
+}
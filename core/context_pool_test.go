@@ -0,0 +1,99 @@
+package teleflow
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func createPoolTestUpdate(userID int64, text string) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		From: &tgbotapi.User{ID: userID},
+		Chat: &tgbotapi.Chat{ID: userID, Type: "private"},
+		Text: text,
+	}}
+}
+
+func TestContextReset_ClearsUpdateState(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	ctx := acquireContext(createPoolTestUpdate(42, "hi"), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	ctx.Set("secret", "value")
+	ctx.SetPendingReplyKeyboard(BuildReplyKeyboard([]string{"A"}, 1))
+	ctx.sentPrompts = []sentPrompt{{ChatID: 42, MessageID: 1}}
+
+	ctx.reset()
+
+	if _, ok := ctx.Get("secret"); ok || ctx.data == nil {
+		t.Error("Expected an empty, reusable data map")
+	}
+	if ctx.UserID() != 0 || ctx.pendingReplyKeyboard != nil || ctx.sentPrompts != nil || ctx.telegramClient != nil {
+		t.Errorf("Expected all update state to be cleared, got %+v", ctx)
+	}
+}
+
+func TestContextPooling_RetainedContextIsNotReused(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithContextPooling())
+	var kept *Context
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		if text == "keep" {
+			ctx.Set("note", "kept")
+			ctx.Retain()
+			kept = ctx
+		}
+		return nil
+	})
+
+	bot.processUpdate(createPoolTestUpdate(42, "keep"))
+	for i := 0; i < 10; i++ {
+		bot.processUpdate(createPoolTestUpdate(int64(100+i), "other"))
+	}
+
+	if kept.UserID() != 42 {
+		t.Errorf("Expected retained context to keep its user, got %d", kept.UserID())
+	}
+	if note, _ := kept.Get("note"); note != "kept" {
+		t.Errorf("Expected retained context to keep its data, got %v", note)
+	}
+}
+
+func TestContextPooling_NoDataLeaksBetweenUpdates(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithContextPooling())
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		if _, ok := ctx.Get("previous"); ok {
+			t.Errorf("Update for user %d saw data from an earlier update", ctx.UserID())
+		}
+		ctx.Set("previous", ctx.UserID())
+		return nil
+	})
+
+	for i := 0; i < 20; i++ {
+		bot.processUpdate(createPoolTestUpdate(int64(i+1), "hello"))
+	}
+}
+
+// BenchmarkProcessUpdate measures a simple text update going through middleware
+// and a handler, with and without context pooling.
+func BenchmarkProcessUpdate(b *testing.B) {
+	for _, pooled := range []bool{false, true} {
+		name := "new"
+		var options []BotOption
+		if pooled {
+			name = "pooled"
+			options = append(options, WithContextPooling())
+		}
+		b.Run(name, func(b *testing.B) {
+			bot, _, _, _ := createTestBot(options...)
+			bot.DefaultHandler(func(ctx *Context, text string) error {
+				ctx.Set("seen", true)
+				return nil
+			})
+			update := createPoolTestUpdate(42, "hello")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bot.processUpdate(update)
+			}
+		})
+	}
+}
@@ -130,7 +130,7 @@ type contextMockFlowOperations struct {
 	CancelFlowFunc      func(userID int64)
 }
 
-func (m *contextMockFlowOperations) setUserFlowData(userID int64, key string, value interface{}) error {
+func (m *contextMockFlowOperations) setUserFlowData(userID, chatID int64, key string, value interface{}) error {
 	m.SetUserFlowDataCalls = append(m.SetUserFlowDataCalls, struct {
 		UserID int64
 		Key    string
@@ -142,7 +142,7 @@ func (m *contextMockFlowOperations) setUserFlowData(userID int64, key string, va
 	return nil
 }
 
-func (m *contextMockFlowOperations) getUserFlowData(userID int64, key string) (interface{}, bool) {
+func (m *contextMockFlowOperations) getUserFlowData(userID, chatID int64, key string) (interface{}, bool) {
 	m.GetUserFlowDataCalls = append(m.GetUserFlowDataCalls, struct {
 		UserID int64
 		Key    string
@@ -153,7 +153,15 @@ func (m *contextMockFlowOperations) getUserFlowData(userID int64, key string) (i
 	return nil, false
 }
 
-func (m *contextMockFlowOperations) startFlow(userID int64, flowName string, ctx *Context) error {
+func (m *contextMockFlowOperations) flowProgress(userID, chatID int64) (FlowProgress, bool) {
+	return FlowProgress{}, false
+}
+
+func (m *contextMockFlowOperations) startFlowWith(userID, chatID int64, flowName string, opts flowStartOptions, ctx *Context) error {
+	return m.startFlow(userID, chatID, flowName, ctx)
+}
+
+func (m *contextMockFlowOperations) startFlow(userID, chatID int64, flowName string, ctx *Context) error {
 	m.StartFlowCalls = append(m.StartFlowCalls, struct {
 		UserID   int64
 		FlowName string
@@ -165,7 +173,7 @@ func (m *contextMockFlowOperations) startFlow(userID int64, flowName string, ctx
 	return nil
 }
 
-func (m *contextMockFlowOperations) isUserInFlow(userID int64) bool {
+func (m *contextMockFlowOperations) isUserInFlow(userID, chatID int64) bool {
 	m.IsUserInFlowCalls = append(m.IsUserInFlowCalls, userID)
 	if m.IsUserInFlowFunc != nil {
 		return m.IsUserInFlowFunc(userID)
@@ -173,13 +181,21 @@ func (m *contextMockFlowOperations) isUserInFlow(userID int64) bool {
 	return false
 }
 
-func (m *contextMockFlowOperations) cancelFlow(userID int64) {
+func (m *contextMockFlowOperations) currentStep(userID, chatID int64) (string, string, bool) {
+	return "", "", false
+}
+
+func (m *contextMockFlowOperations) cancelFlow(userID, chatID int64, ctx *Context) {
 	m.CancelFlowCalls = append(m.CancelFlowCalls, userID)
 	if m.CancelFlowFunc != nil {
 		m.CancelFlowFunc(userID)
 	}
 }
 
+func (m *contextMockFlowOperations) updateKeyboard(userID, chatID int64, keyboard KeyboardFunc, ctx *Context) error {
+	return nil
+}
+
 type contextMockPromptSender struct {
 	ComposeAndSendCalls []struct {
 		Ctx    *Context
@@ -595,6 +611,43 @@ func TestContext_SendPromptWithTemplate(t *testing.T) {
 	}
 }
 
+// Test SendTo and SendTemplateTo
+func TestContext_SendTo(t *testing.T) {
+	update := tgbotapi.Update{
+		Message: &tgbotapi.Message{
+			From: &tgbotapi.User{ID: 12345},
+			Chat: &tgbotapi.Chat{ID: 67890, Type: "private"},
+		},
+	}
+
+	ctx, _, _, _, mockPS, _ := createContextTestInstance(update)
+	ctx.sentPrompts = []sentPrompt{{ChatID: 67890, MessageID: 1}}
+
+	if err := ctx.SendTo(-100200, &PromptConfig{Message: "new order"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := ctx.SendTemplateTo(555, "transfer", map[string]interface{}{"amount": 10}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(mockPS.ComposeAndSendCalls) != 2 {
+		t.Fatalf("Expected 2 ComposeAndSend calls, got %d", len(mockPS.ComposeAndSendCalls))
+	}
+	first, second := mockPS.ComposeAndSendCalls[0], mockPS.ComposeAndSendCalls[1]
+	if first.Ctx.ChatID() != -100200 || first.Ctx.UserID() != 12345 || first.Config.Message != "new order" {
+		t.Errorf("Expected prompt for chat -100200, got chat %d and config %+v", first.Ctx.ChatID(), first.Config)
+	}
+	if len(first.Ctx.sentPrompts) != 0 {
+		t.Error("Expected the target context to start without tracked prompts")
+	}
+	if second.Ctx.ChatID() != 555 || second.Config.Message != "template:transfer" || second.Config.TemplateData["amount"] != 10 {
+		t.Errorf("Expected template for chat 555, got chat %d and config %+v", second.Ctx.ChatID(), second.Config)
+	}
+	if ctx.ChatID() != 67890 || len(ctx.sentPrompts) != 1 {
+		t.Error("Expected the original context to be unchanged")
+	}
+}
+
 // Test template management wrappers
 func TestContext_TemplateManagement(t *testing.T) {
 	update := tgbotapi.Update{
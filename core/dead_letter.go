@@ -0,0 +1,305 @@
+package teleflow
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// DeadLetter is an update whose processing failed.
+type DeadLetter struct {
+	Update   tgbotapi.Update `json:"update"`
+	Error    string          `json:"error"`
+	Attempts int             `json:"attempts"` // Times the handler ran before giving up
+	FailedAt time.Time       `json:"failed_at"`
+}
+
+// DeadLetterQueue stores failed updates until they are reprocessed with
+// Bot.ReprocessDeadLetters. Implementations must be safe for concurrent use.
+type DeadLetterQueue interface {
+	// Push stores a failed update.
+	Push(letter DeadLetter) error
+
+	// Pop removes and returns up to n of the oldest letters.
+	Pop(n int) ([]DeadLetter, error)
+}
+
+// deadLetterConfig is the dead-letter setup of a bot.
+type deadLetterConfig struct {
+	queue   DeadLetterQueue
+	retries int
+}
+
+// WithDeadLetterQueue stores updates whose handler still fails after retries
+// further attempts in queue, so that they can be reprocessed later with
+// Bot.ReprocessDeadLetters. Updates failing inside a flow are stored without
+// retrying, as the flow may already have moved on. Permission denials are not
+// considered failures.
+//
+// A retry runs the whole handler again, and so does reprocessing a letter:
+// messages it sent before failing are sent again and other side effects are
+// repeated. With retries above 0 or replayed letters, handlers must be
+// idempotent, e.g. by checking whether an order was already placed before
+// placing it.
+//
+// Example:
+//
+//	dlq, err := teleflow.NewFileDeadLetterQueue("dead-letters.jsonl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bot, err := teleflow.NewBot(token, teleflow.WithDeadLetterQueue(dlq, 2))
+func WithDeadLetterQueue(queue DeadLetterQueue, retries int) BotOption {
+	return func(b *Bot) {
+		if retries < 0 {
+			retries = 0
+		}
+		b.deadLetters = &deadLetterConfig{queue: queue, retries: retries}
+	}
+}
+
+// handlerRetries returns how often a failed handler is run again.
+func (b *Bot) handlerRetries() int {
+	if b.deadLetters == nil {
+		return 0
+	}
+	return b.deadLetters.retries
+}
+
+// deadLetter stores an update that failed after attempts runs.
+func (b *Bot) deadLetter(ctx *Context, err error, attempts int) {
+	if b.deadLetters == nil || isFlowRefusal(err) {
+		return
+	}
+	letter := DeadLetter{Update: ctx.update, Error: err.Error(), Attempts: attempts, FailedAt: ctx.Now()}
+	if pushErr := b.deadLetters.queue.Push(letter); pushErr != nil {
+		log.Printf("[DEAD_LETTER] Failed to store update %d of user %d: %v", ctx.update.UpdateID, ctx.UserID(), pushErr)
+	}
+}
+
+// ReprocessDeadLetters takes up to limit letters from the dead-letter queue and
+// dispatches their updates again like new ones, each in its own goroutine, so
+// it is safe to call from a handler. Updates that fail again are put back into
+// the queue. It returns the number of updates dispatched without waiting for
+// them to be processed.
+//
+// Example:
+//
+//	bot.HandleCommand("replay_failed", func(ctx *teleflow.Context, command, args string) error {
+//		n, err := bot.ReprocessDeadLetters(100)
+//		if err != nil {
+//			return err
+//		}
+//		return ctx.SendPromptText(fmt.Sprintf("Replaying %d updates", n))
+//	})
+func (b *Bot) ReprocessDeadLetters(limit int) (int, error) {
+	if b.deadLetters == nil {
+		return 0, fmt.Errorf("no dead-letter queue configured")
+	}
+	letters, err := b.deadLetters.queue.Pop(limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	for _, letter := range letters {
+		b.activeHandlers.Add(1)
+		go func(update tgbotapi.Update) {
+			defer b.activeHandlers.Add(-1)
+			b.processUpdate(update)
+		}(letter.Update)
+	}
+	return len(letters), nil
+}
+
+// MemoryDeadLetterQueue keeps dead letters in memory. Letters are lost when the
+// process exits.
+type MemoryDeadLetterQueue struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// NewMemoryDeadLetterQueue creates an empty MemoryDeadLetterQueue.
+func NewMemoryDeadLetterQueue() *MemoryDeadLetterQueue {
+	return &MemoryDeadLetterQueue{}
+}
+
+// Push stores a letter.
+func (q *MemoryDeadLetterQueue) Push(letter DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = append(q.letters, letter)
+	return nil
+}
+
+// Pop removes and returns up to n of the oldest letters.
+func (q *MemoryDeadLetterQueue) Pop(n int) ([]DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n = min(n, len(q.letters))
+	letters := append([]DeadLetter(nil), q.letters[:n]...)
+	q.letters = q.letters[n:]
+	return letters, nil
+}
+
+// Len returns the number of stored letters.
+func (q *MemoryDeadLetterQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.letters)
+}
+
+// FileDeadLetterQueue stores dead letters in a file, one JSON object per line.
+type FileDeadLetterQueue struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileDeadLetterQueue creates a queue stored in the file at path, which is
+// created if it does not exist.
+func NewFileDeadLetterQueue(path string) (*FileDeadLetterQueue, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	file.Close()
+	return &FileDeadLetterQueue{path: path}, nil
+}
+
+// Push appends a letter to the file.
+func (q *FileDeadLetterQueue) Push(letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	file, err := os.OpenFile(q.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Pop removes and returns up to n of the oldest letters. The remaining letters
+// are written to a temporary file that replaces the queue file.
+func (q *FileDeadLetterQueue) Pop(n int) ([]DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.Open(q.path)
+	if err != nil {
+		return nil, err
+	}
+	var letters []DeadLetter
+	var rest [][]byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if len(letters) >= n {
+			rest = append(rest, append([]byte(nil), line...))
+			continue
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(line, &letter); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("corrupt dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(letters) == 0 {
+		return nil, nil
+	}
+
+	tmp := q.path + ".tmp"
+	var data []byte
+	for _, line := range rest {
+		data = append(append(data, line...), '\n')
+	}
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// RedisListClient is the part of a Redis client NewRedisDeadLetterQueue needs.
+// With go-redis it takes a few lines of glue:
+//
+//	type redisList struct{ rdb *redis.Client }
+//
+//	func (r redisList) RPush(key string, value []byte) error {
+//		return r.rdb.RPush(context.Background(), key, value).Err()
+//	}
+//
+//	func (r redisList) LPop(key string) ([]byte, error) {
+//		value, err := r.rdb.LPop(context.Background(), key).Bytes()
+//		if errors.Is(err, redis.Nil) {
+//			return nil, nil
+//		}
+//		return value, err
+//	}
+type RedisListClient interface {
+	// RPush appends value to the list at key.
+	RPush(key string, value []byte) error
+	// LPop removes and returns the first value of the list at key, or nil if the
+	// list is empty.
+	LPop(key string) ([]byte, error)
+}
+
+// redisDeadLetterQueue stores dead letters in a Redis list.
+type redisDeadLetterQueue struct {
+	client RedisListClient
+	key    string
+}
+
+// NewRedisDeadLetterQueue creates a queue stored in the Redis list at key.
+func NewRedisDeadLetterQueue(client RedisListClient, key string) DeadLetterQueue {
+	return &redisDeadLetterQueue{client: client, key: key}
+}
+
+func (q *redisDeadLetterQueue) Push(letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return q.client.RPush(q.key, data)
+}
+
+func (q *redisDeadLetterQueue) Pop(n int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	for len(letters) < n {
+		data, err := q.client.LPop(q.key)
+		if err != nil {
+			return letters, err
+		}
+		if data == nil {
+			break
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(data, &letter); err != nil {
+			return letters, fmt.Errorf("corrupt dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
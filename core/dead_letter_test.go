@@ -0,0 +1,137 @@
+package teleflow
+
+import (
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDeadLetterQueue_RetriesAndStoresFailures(t *testing.T) {
+	queue := NewMemoryDeadLetterQueue()
+	bot, _, _, _ := createTestBot(WithDeadLetterQueue(queue, 2))
+
+	calls, failUntil := 0, 2
+	bot.HandleCommand("pay", func(ctx *Context, command, args string) error {
+		calls++
+		if calls <= failUntil {
+			return errors.New("payment provider down")
+		}
+		return nil
+	})
+
+	// Succeeds on the last retry
+	bot.ProcessExternalUpdate(commandUpdate(42, "pay"))
+	if calls != 3 || queue.Len() != 0 {
+		t.Fatalf("Expected 3 attempts and no dead letter, got %d attempts and %d letters", calls, queue.Len())
+	}
+
+	// Fails on every attempt
+	calls, failUntil = 0, 100
+	bot.ProcessExternalUpdate(commandUpdate(42, "pay"))
+	if calls != 3 || queue.Len() != 1 {
+		t.Fatalf("Expected 3 attempts and a dead letter, got %d attempts and %d letters", calls, queue.Len())
+	}
+
+	// Reprocessing after the outage succeeds and empties the queue
+	calls, failUntil = 0, 0
+	n, err := bot.ReprocessDeadLetters(10)
+	waitForActiveHandlers(bot)
+	if err != nil || n != 1 || calls != 1 || queue.Len() != 0 {
+		t.Errorf("Expected one successful reprocess, got n=%d err=%v calls=%d letters=%d", n, err, calls, queue.Len())
+	}
+}
+
+func TestFileDeadLetterQueue(t *testing.T) {
+	queue, err := NewFileDeadLetterQueue(filepath.Join(t.TempDir(), "dlq.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileDeadLetterQueue failed: %v", err)
+	}
+	for id := 1; id <= 3; id++ {
+		if err := queue.Push(DeadLetter{Update: tgbotapi.Update{UpdateID: id}, Error: "boom", Attempts: 1}); err != nil {
+			t.Fatalf("Push failed: %v", err)
+		}
+	}
+
+	letters, err := queue.Pop(2)
+	if err != nil || len(letters) != 2 || letters[0].Update.UpdateID != 1 || letters[1].Update.UpdateID != 2 {
+		t.Fatalf("Expected the two oldest letters, got %+v (%v)", letters, err)
+	}
+	letters, err = queue.Pop(10)
+	if err != nil || len(letters) != 1 || letters[0].Update.UpdateID != 3 || letters[0].Error != "boom" {
+		t.Fatalf("Expected the remaining letter, got %+v (%v)", letters, err)
+	}
+	if letters, _ := queue.Pop(10); len(letters) != 0 {
+		t.Errorf("Expected an empty queue, got %+v", letters)
+	}
+}
+
+// memoryList is a RedisListClient backed by a slice.
+type memoryList map[string][][]byte
+
+func (l memoryList) RPush(key string, value []byte) error {
+	l[key] = append(l[key], value)
+	return nil
+}
+
+func (l memoryList) LPop(key string) ([]byte, error) {
+	if len(l[key]) == 0 {
+		return nil, nil
+	}
+	value := l[key][0]
+	l[key] = l[key][1:]
+	return value, nil
+}
+
+func TestRedisDeadLetterQueue(t *testing.T) {
+	list := memoryList{}
+	queue := NewRedisDeadLetterQueue(list, "teleflow:dlq")
+	queue.Push(DeadLetter{Update: tgbotapi.Update{UpdateID: 1}})
+	queue.Push(DeadLetter{Update: tgbotapi.Update{UpdateID: 2}})
+
+	letters, err := queue.Pop(5)
+	if err != nil || len(letters) != 2 || letters[1].Update.UpdateID != 2 || len(list["teleflow:dlq"]) != 0 {
+		t.Errorf("Expected both letters in order, got %+v (%v)", letters, err)
+	}
+}
+
+func TestReprocessDeadLetters_FromHandler(t *testing.T) {
+	queue := NewMemoryDeadLetterQueue()
+	queue.Push(DeadLetter{Update: commandUpdate(42, "pay"), Error: "boom", Attempts: 1})
+	bot, _, _, _ := createTestBot(WithDeadLetterQueue(queue, 0))
+
+	var paid atomic.Int32
+	bot.HandleCommand("pay", func(ctx *Context, command, args string) error {
+		paid.Add(1)
+		return nil
+	})
+	bot.HandleCommand("replay", func(ctx *Context, command, args string) error {
+		_, err := bot.ReprocessDeadLetters(10)
+		return err
+	})
+
+	done := make(chan struct{})
+	go func() {
+		bot.ProcessExternalUpdate(commandUpdate(42, "replay"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected replaying from a handler of the same chat not to deadlock")
+	}
+	waitForActiveHandlers(bot)
+	if paid.Load() != 1 {
+		t.Errorf("Expected the letter to be replayed, got %d calls", paid.Load())
+	}
+}
+
+// waitForActiveHandlers waits up to a second for dispatched updates to finish.
+func waitForActiveHandlers(bot *Bot) {
+	for deadline := time.Now().Add(time.Second); bot.activeHandlers.Load() > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+}
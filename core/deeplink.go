@@ -0,0 +1,97 @@
+package teleflow
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+)
+
+// DeepLinkHandlerFunc handles a /start deep link. It receives the payload with
+// the registered prefix already stripped, e.g. "123" for "ref_123" registered
+// under the prefix "ref_".
+type DeepLinkHandlerFunc func(ctx *Context, payload string) error
+
+// deepLinkRoute pairs a payload prefix with its handler.
+type deepLinkRoute struct {
+	prefix  string
+	handler func(ctx *Context, payload string) error
+}
+
+// deepLinkPayloadPattern matches payloads Telegram accepts verbatim in t.me links.
+var deepLinkPayloadPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// HandleDeepLink registers a handler for /start payloads beginning with prefix.
+// Payloads are matched as sent and, when no route matches, after decoding them
+// as unpadded base64url (the encoding used by DeepLinkURL for payloads Telegram
+// would otherwise reject). When several prefixes match, the longest one wins.
+// Unmatched payloads fall through to the regular "start" command handler.
+//
+// Example:
+//
+//	bot.HandleDeepLink("ref_", func(ctx *teleflow.Context, referrer string) error {
+//		ctx.Set("referrer", referrer)
+//		return ctx.StartFlow("registration")
+//	})
+//	bot.HandleDeepLink("order_", func(ctx *teleflow.Context, orderID string) error {
+//		return ctx.StartFlow("resume_order")
+//	})
+func (b *Bot) HandleDeepLink(prefix string, handler DeepLinkHandlerFunc) {
+	route := deepLinkRoute{prefix: prefix}
+	route.handler = func(ctx *Context, payload string) error {
+		wrappedHandler := func(ctx *Context) error {
+			return handler(ctx, payload)
+		}
+		return b.stats.track("deeplink:"+prefix, func() error {
+			return b.applyMiddleware(wrappedHandler)(ctx)
+		})
+	}
+	b.deepLinks = append(b.deepLinks, route)
+}
+
+// DeepLinkURL returns a t.me link that opens the bot with the given /start payload.
+// Payloads containing characters Telegram does not allow are base64url-encoded;
+// HandleDeepLink decodes them transparently.
+//
+// Example:
+//
+//	link := bot.DeepLinkURL("ref_" + strconv.FormatInt(ctx.UserID(), 10))
+func (b *Bot) DeepLinkURL(payload string) string {
+	if !deepLinkPayloadPattern.MatchString(payload) {
+		payload = base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+	return "https://t.me/" + b.self.UserName + "?start=" + payload
+}
+
+// handleDeepLink routes a /start payload to the matching deep-link handler.
+// It reports whether a handler was found.
+func (b *Bot) handleDeepLink(ctx *Context, payload string) (bool, error) {
+	if payload == "" || len(b.deepLinks) == 0 {
+		return false, nil
+	}
+
+	if route, ok := b.matchDeepLink(payload); ok {
+		return true, route.handler(ctx, strings.TrimPrefix(payload, route.prefix))
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return false, nil
+	}
+	if route, ok := b.matchDeepLink(string(decoded)); ok {
+		return true, route.handler(ctx, strings.TrimPrefix(string(decoded), route.prefix))
+	}
+	return false, nil
+}
+
+// matchDeepLink returns the route with the longest prefix matching payload.
+func (b *Bot) matchDeepLink(payload string) (deepLinkRoute, bool) {
+	var best deepLinkRoute
+	found := false
+	for _, route := range b.deepLinks {
+		if strings.HasPrefix(payload, route.prefix) && (!found || len(route.prefix) > len(best.prefix)) {
+			best = route
+			found = true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,80 @@
+package teleflow
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func createStartUpdate(payload string) tgbotapi.Update {
+	text := "/start"
+	if payload != "" {
+		text += " " + payload
+	}
+	return tgbotapi.Update{
+		UpdateID: 1,
+		Message: &tgbotapi.Message{
+			MessageID: 1,
+			From:      &tgbotapi.User{ID: 42},
+			Chat:      &tgbotapi.Chat{ID: 42, Type: "private"},
+			Text:      text,
+			Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 6}},
+		},
+	}
+}
+
+func TestHandleDeepLink_Routing(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	var route, got string
+	bot.HandleDeepLink("ref_", func(ctx *Context, payload string) error {
+		route, got = "ref", payload
+		return nil
+	})
+	bot.HandleDeepLink("ref_vip_", func(ctx *Context, payload string) error {
+		route, got = "vip", payload
+		return nil
+	})
+	bot.HandleCommand("start", func(ctx *Context, command, args string) error {
+		route, got = "start", strings.TrimSpace(args)
+		return nil
+	})
+
+	tests := []struct {
+		name          string
+		payload       string
+		expectedRoute string
+		expectedValue string
+	}{
+		{"plain prefix", "ref_123", "ref", "123"},
+		{"longest prefix wins", "ref_vip_7", "vip", "7"},
+		{"base64url payload", base64.RawURLEncoding.EncodeToString([]byte("ref_a b")), "ref", "a b"},
+		{"unmatched payload", "other", "start", "other"},
+		{"no payload", "", "start", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, got = "", ""
+			bot.processUpdate(createStartUpdate(tt.payload))
+			if route != tt.expectedRoute || got != tt.expectedValue {
+				t.Errorf("Expected %s(%q), got %s(%q)", tt.expectedRoute, tt.expectedValue, route, got)
+			}
+		})
+	}
+}
+
+func TestDeepLinkURL(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	if got := bot.DeepLinkURL("ref_123"); got != "https://t.me/TestBot?start=ref_123" {
+		t.Errorf("Unexpected plain deep link: %s", got)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString([]byte("order 1/2"))
+	if got := bot.DeepLinkURL("order 1/2"); got != "https://t.me/TestBot?start="+encoded {
+		t.Errorf("Unexpected encoded deep link: %s", got)
+	}
+}
@@ -0,0 +1,271 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// DryRunMessage describes an outgoing Bot API call captured in dry-run mode.
+type DryRunMessage struct {
+	Method      string             // Bot API method, e.g. "sendMessage" or "editMessageText"
+	ChatID      int64              // Target chat, 0 if the call has none
+	MessageID   int                // Message being edited or deleted, 0 for new messages
+	Text        string             // Message text or photo caption
+	ParseMode   string             // Parse mode of Text
+	ReplyMarkup interface{}        // Inline or reply keyboard attached to the message
+	Photo       bool               // The call sends or edits a photo
+	Request     tgbotapi.Chattable // The complete request, nil for raw method calls
+}
+
+// DryRunSink receives every call a bot in dry-run mode would have made.
+type DryRunSink func(msg DryRunMessage)
+
+// WithDryRun keeps the bot from sending anything to Telegram. Prompts, templates
+// and keyboards are still rendered, and every outgoing call is passed to sink
+// instead, answered with a successful fake response. Send hooks still run first.
+// Use it to preview copy changes safely or to test rendering offline. Updates
+// are still received as usual, and read-only calls such as getChat and
+// getChatMember still go to Telegram so admin checks keep working.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithDryRun(teleflow.DryRunWriter(os.Stdout)))
+func WithDryRun(sink DryRunSink) BotOption {
+	return func(b *Bot) {
+		b.dryRun = sink
+	}
+}
+
+// DryRunWriter returns a sink that prints a readable preview of each call to w.
+func DryRunWriter(w io.Writer) DryRunSink {
+	var mu sync.Mutex
+	return func(msg DryRunMessage) {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "[%s chat=%d", msg.Method, msg.ChatID)
+		if msg.MessageID != 0 {
+			fmt.Fprintf(&sb, " message=%d", msg.MessageID)
+		}
+		if msg.ParseMode != "" {
+			fmt.Fprintf(&sb, " parse=%s", msg.ParseMode)
+		}
+		if msg.Photo {
+			sb.WriteString(" photo")
+		}
+		sb.WriteString("]\n")
+		if msg.Text != "" {
+			sb.WriteString(msg.Text)
+			sb.WriteString("\n")
+		}
+		if buttons := dryRunButtons(msg.ReplyMarkup); buttons != "" {
+			sb.WriteString(buttons)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = io.WriteString(w, sb.String())
+	}
+}
+
+// RenderPrompt renders a prompt for a user in a chat and returns the calls that
+// sending it would make, without sending anything. Templates, keyboards, images
+// and send hooks are applied as for a real prompt. Use it to preview prompts or
+// to snapshot them in tests.
+//
+// Example:
+//
+//	messages, err := bot.RenderPrompt(userID, userID, &teleflow.PromptConfig{
+//		Message:      "template:order_summary",
+//		TemplateData: map[string]interface{}{"total": "$42"},
+//	})
+func (b *Bot) RenderPrompt(userID, chatID int64, prompt *PromptConfig) ([]DryRunMessage, error) {
+	var messages []DryRunMessage
+	capture := &hookedClient{bot: b, next: newDryRunClient(b.api, func(msg DryRunMessage) {
+		messages = append(messages, msg)
+	})}
+	// A separate composer keeps the bot's keyboard mappings and image cache untouched
+	composer := newPromptComposer(capture, newMessageHandler(b.templateManager), newImageHandler(), newPromptKeyboardHandler())
+
+	chat := &tgbotapi.Chat{ID: chatID, Type: "private"}
+	if chatID != userID {
+		chat.Type = "supergroup"
+	}
+	update := tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: userID}, Chat: chat}}
+	ctx := newContext(update, capture, b.templateManager, b.flowManager, composer, b.accessManager)
+	ctx.telegramClient = capture.forContext(ctx)
+	ctx.clock = b.clock
+
+	err := composer.ComposeAndSend(ctx, prompt)
+	return messages, err
+}
+
+// dryRunButtons renders keyboard rows as "[Label] [Label]" lines.
+func dryRunButtons(markup interface{}) string {
+	var rows [][]string
+	switch kb := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		for _, row := range kb.InlineKeyboard {
+			var labels []string
+			for _, button := range row {
+				labels = append(labels, button.Text)
+			}
+			rows = append(rows, labels)
+		}
+	case *tgbotapi.InlineKeyboardMarkup:
+		return dryRunButtons(*kb)
+	case tgbotapi.ReplyKeyboardMarkup:
+		for _, row := range kb.Keyboard {
+			var labels []string
+			for _, button := range row {
+				labels = append(labels, button.Text)
+			}
+			rows = append(rows, labels)
+		}
+	}
+
+	var sb strings.Builder
+	for _, row := range rows {
+		sb.WriteString("[" + strings.Join(row, "] [") + "]\n")
+	}
+	return sb.String()
+}
+
+// dryRunClient is a TelegramClient that reports calls to a sink instead of
+// making them. Receiving updates and GetMe still go to the real client.
+type dryRunClient struct {
+	next   TelegramClient
+	sink   DryRunSink
+	nextID atomic.Int64
+}
+
+func newDryRunClient(next TelegramClient, sink DryRunSink) *dryRunClient {
+	return &dryRunClient{next: next, sink: sink}
+}
+
+// Send reports c and returns a fake message with a fresh message ID.
+func (d *dryRunClient) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	msg := DescribeChattable(c)
+	d.sink(msg)
+
+	messageID := msg.MessageID
+	if messageID == 0 {
+		messageID = int(d.nextID.Add(1))
+	}
+	return tgbotapi.Message{
+		MessageID: messageID,
+		Chat:      &tgbotapi.Chat{ID: msg.ChatID},
+		Text:      msg.Text,
+	}, nil
+}
+
+// Request reports c and returns a successful response. Read-only calls are
+// made with the real client instead.
+func (d *dryRunClient) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	if isReadOnlyChattable(c) {
+		return d.next.Request(c)
+	}
+	d.sink(DescribeChattable(c))
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+}
+
+// MakeRequest reports a raw method call and returns a successful response: a
+// fake message for methods that send or edit one, true otherwise. Read-only
+// methods are called on the real client instead.
+func (d *dryRunClient) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	if strings.HasPrefix(endpoint, "get") {
+		return makeRawRequest(d.next, endpoint, params)
+	}
+	msg := describeRawRequest(endpoint, params)
+	d.sink(msg)
+
+	if !strings.HasPrefix(endpoint, "send") && !strings.HasPrefix(endpoint, "edit") {
+		return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+	}
+	messageID, _ := strconv.Atoi(params["message_id"])
+	if messageID == 0 {
+		messageID = int(d.nextID.Add(1))
+	}
+	result, err := json.Marshal(tgbotapi.Message{
+		MessageID: messageID,
+		Chat:      &tgbotapi.Chat{ID: msg.ChatID},
+		Text:      msg.Text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: result}, nil
+}
+
+// isReadOnlyChattable reports whether c only reads data from Telegram.
+func isReadOnlyChattable(c tgbotapi.Chattable) bool {
+	switch c.(type) {
+	case tgbotapi.ChatInfoConfig, tgbotapi.GetChatMemberConfig, tgbotapi.ChatAdministratorsConfig,
+		tgbotapi.ChatMemberCountConfig, tgbotapi.FileConfig, tgbotapi.UserProfilePhotosConfig,
+		tgbotapi.GetGameHighScoresConfig, tgbotapi.GetStickerSetConfig, tgbotapi.GetMyCommandsConfig,
+		tgbotapi.UpdateConfig:
+		return true
+	}
+	return false
+}
+
+// GetUpdatesChan delegates to the real client.
+func (d *dryRunClient) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	return d.next.GetUpdatesChan(config)
+}
+
+// GetMe delegates to the real client.
+func (d *dryRunClient) GetMe() (tgbotapi.User, error) {
+	return d.next.GetMe()
+}
+
+// describeRawRequest summarizes a raw method call like DescribeChattable.
+func describeRawRequest(endpoint string, params tgbotapi.Params) DryRunMessage {
+	var chatID int64
+	_, _ = fmt.Sscan(params["chat_id"], &chatID)
+	return DryRunMessage{Method: endpoint, ChatID: chatID, Text: params["text"]}
+}
+
+// DescribeChattable summarizes an outgoing call the way dry-run mode reports it:
+// method, target chat and message, text and keyboard. It is also handy in send
+// hooks and tests that inspect what the bot sends.
+func DescribeChattable(c tgbotapi.Chattable) DryRunMessage {
+	msg := DryRunMessage{Request: c}
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		msg.Method, msg.ChatID, msg.Text, msg.ParseMode, msg.ReplyMarkup = "sendMessage", cfg.ChatID, cfg.Text, cfg.ParseMode, cfg.ReplyMarkup
+	case tgbotapi.PhotoConfig:
+		msg.Method, msg.ChatID, msg.Text, msg.ParseMode, msg.ReplyMarkup = "sendPhoto", cfg.ChatID, cfg.Caption, cfg.ParseMode, cfg.ReplyMarkup
+		msg.Photo = true
+	case tgbotapi.EditMessageTextConfig:
+		msg.Method, msg.ChatID, msg.MessageID, msg.Text, msg.ParseMode = "editMessageText", cfg.ChatID, cfg.MessageID, cfg.Text, cfg.ParseMode
+		if cfg.ReplyMarkup != nil {
+			msg.ReplyMarkup = *cfg.ReplyMarkup
+		}
+	case tgbotapi.EditMessageMediaConfig:
+		msg.Method, msg.ChatID, msg.MessageID = "editMessageMedia", cfg.ChatID, cfg.MessageID
+		if photo, ok := cfg.Media.(tgbotapi.InputMediaPhoto); ok {
+			msg.Text, msg.ParseMode, msg.Photo = photo.Caption, photo.ParseMode, true
+		}
+		if cfg.ReplyMarkup != nil {
+			msg.ReplyMarkup = *cfg.ReplyMarkup
+		}
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		msg.Method, msg.ChatID, msg.MessageID = "editMessageReplyMarkup", cfg.ChatID, cfg.MessageID
+		if cfg.ReplyMarkup != nil {
+			msg.ReplyMarkup = *cfg.ReplyMarkup
+		}
+	case tgbotapi.DeleteMessageConfig:
+		msg.Method, msg.ChatID, msg.MessageID = "deleteMessage", cfg.ChatID, cfg.MessageID
+	case tgbotapi.CallbackConfig:
+		msg.Method, msg.Text = "answerCallbackQuery", cfg.Text
+	default:
+		msg.Method = strings.TrimPrefix(fmt.Sprintf("%T", c), "tgbotapi.")
+	}
+	return msg
+}
@@ -0,0 +1,112 @@
+package teleflow
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDryRun_CapturesInsteadOfSending(t *testing.T) {
+	var captured []DryRunMessage
+	bot, mockClient, _, _ := createTestBot(WithDryRun(func(msg DryRunMessage) {
+		captured = append(captured, msg)
+	}))
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+
+	err := bot.promptComposer.ComposeAndSend(ctx, &PromptConfig{
+		Message: "<b>Pick</b>",
+		Keyboard: func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("Yes", "yes").ButtonCallback("No", "no")
+		},
+	})
+	if err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+	if err := bot.DeleteMessage(ctx, 7); err != nil {
+		t.Fatalf("DeleteMessage failed: %v", err)
+	}
+
+	if len(mockClient.SendCalls) != 0 || len(mockClient.RequestCalls) != 0 {
+		t.Errorf("Expected nothing to reach Telegram, got %d sends and %d requests", len(mockClient.SendCalls), len(mockClient.RequestCalls))
+	}
+	if len(captured) != 2 {
+		t.Fatalf("Expected 2 captured calls, got %d", len(captured))
+	}
+	if msg := captured[0]; msg.Method != "sendMessage" || msg.ChatID != 42 || msg.Text != "<b>Pick</b>" || dryRunButtons(msg.ReplyMarkup) != "[Yes] [No]\n" {
+		t.Errorf("Unexpected captured prompt: %+v", msg)
+	}
+	if msg := captured[1]; msg.Method != "deleteMessage" || msg.MessageID != 7 {
+		t.Errorf("Unexpected captured delete: %+v", msg)
+	}
+	if len(ctx.sentPrompts) != 1 || ctx.sentPrompts[0].MessageID == 0 {
+		t.Errorf("Expected the fake message to be tracked like a real one, got %+v", ctx.sentPrompts)
+	}
+}
+
+func TestDryRunWriter(t *testing.T) {
+	var out strings.Builder
+	sink := DryRunWriter(&out)
+	sink(DescribeChattable(tgbotapi.MessageConfig{
+		BaseChat:  tgbotapi.BaseChat{ChatID: 42, ReplyMarkup: tgbotapi.NewReplyKeyboard(tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("Menu")))},
+		Text:      "Hello",
+		ParseMode: "HTML",
+	}))
+
+	want := "[sendMessage chat=42 parse=HTML]\nHello\n[Menu]\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+func TestBot_RenderPrompt(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	messages, err := bot.RenderPrompt(42, 42, &PromptConfig{
+		Message:  "Intro",
+		Sequence: []*PromptConfig{{Message: "First"}},
+		Keyboard: func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("Go", "go")
+		},
+	})
+	if err != nil {
+		t.Fatalf("RenderPrompt failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Text != "First" || messages[1].Text != "Intro" || dryRunButtons(messages[1].ReplyMarkup) != "[Go]\n" {
+		t.Errorf("Unexpected rendered messages: %+v", messages)
+	}
+	if len(mockClient.SendCalls) != 0 {
+		t.Errorf("Expected nothing to be sent, got %d sends", len(mockClient.SendCalls))
+	}
+	if mappings := bot.promptKeyboardHandler.(*PromptKeyboardHandler).uuidMappings[flowKey{UserID: 42}]; len(mappings) != 0 {
+		t.Errorf("Expected the bot's keyboard mappings to stay untouched, got %v", mappings)
+	}
+}
+
+func TestDryRun_ForwardsReadsAndStubsRawSends(t *testing.T) {
+	var captured []DryRunMessage
+	bot, mockClient, _, _ := createTestBot(WithDryRun(func(msg DryRunMessage) {
+		captured = append(captured, msg)
+	}))
+
+	if _, err := bot.sender.Request(tgbotapi.GetChatMemberConfig{ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: -100, UserID: 42}}); err != nil {
+		t.Fatalf("getChatMember failed: %v", err)
+	}
+	if _, err := makeRawRequest(bot.sender, "getChat", tgbotapi.Params{"chat_id": "-100"}); err != nil {
+		t.Fatalf("Raw getChat failed: %v", err)
+	}
+	if len(mockClient.RequestCalls) != 1 || len(mockClient.MakeRequestCalls) != 1 {
+		t.Errorf("Expected read-only calls to reach Telegram, got %d requests and %d raw calls", len(mockClient.RequestCalls), len(mockClient.MakeRequestCalls))
+	}
+
+	sent, err := sendRawMessage(bot.sender, "sendMessage", tgbotapi.Params{"chat_id": "42", "text": "Hi"})
+	if err != nil {
+		t.Fatalf("Raw sendMessage failed: %v", err)
+	}
+	if sent.MessageID == 0 || sent.Chat == nil || sent.Chat.ID != 42 {
+		t.Errorf("Expected a fake sent message, got %+v", sent)
+	}
+	if len(mockClient.MakeRequestCalls) != 1 || len(captured) != 1 || captured[0].Method != "sendMessage" {
+		t.Errorf("Expected the raw send to be captured only, got %+v", captured)
+	}
+}
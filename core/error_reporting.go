@@ -0,0 +1,179 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+// ErrorReport describes a handler error or panic together with the update it
+// happened on.
+type ErrorReport struct {
+	Err   error       // Error returned by the handler, or one describing the panic
+	Panic interface{} // Value the handler panicked with, nil for returned errors
+	Stack []byte      // Stack trace of the panic, nil for returned errors
+	Time  time.Time
+
+	UpdateID   int
+	UpdateType string // "message", "command", "callback_query", ...
+	UserID     int64
+	ChatID     int64
+	Command    string // Command without the slash, empty if the update is not a command
+	Flow       string // Flow the user was in when the error happened, empty if none
+	Step       string // Step of Flow the error happened in
+}
+
+// Reporter receives error reports, e.g. to forward them to an error tracker.
+type Reporter interface {
+	Report(report ErrorReport)
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(report ErrorReport)
+
+// Report calls f(report).
+func (f ReporterFunc) Report(report ErrorReport) {
+	f(report)
+}
+
+// ErrorReportingMiddleware reports handler errors and panics to reporter. A
+// panic is turned into an error, so the bot replies with its usual error
+// message instead of crashing. Updates handled by flows do not pass through
+// middleware; use WithErrorReporter to report errors of flows as well.
+//
+// Example with Sentry:
+//
+//	bot.UseMiddleware(teleflow.ErrorReportingMiddleware(teleflow.ReporterFunc(func(r teleflow.ErrorReport) {
+//		sentry.WithScope(func(scope *sentry.Scope) {
+//			scope.SetUser(sentry.User{ID: strconv.FormatInt(r.UserID, 10)})
+//			scope.SetTags(map[string]string{"command": r.Command, "flow": r.Flow, "step": r.Step})
+//			sentry.CaptureException(r.Err)
+//		})
+//	})))
+func ErrorReportingMiddleware(reporter Reporter) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) (err error) {
+			// Report where the error happened, not the state the handler left
+			flow, step, _ := ctx.CurrentFlow()
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic in handler: %v", r)
+					report := newErrorReport(ctx, err, flow, step)
+					report.Panic = r
+					report.Stack = debug.Stack()
+					reporter.Report(report)
+				}
+			}()
+
+			if err = next(ctx); err != nil {
+				reporter.Report(newErrorReport(ctx, err, flow, step))
+			}
+			return err
+		}
+	}
+}
+
+// WithErrorReporter reports errors and panics of handlers and flows to
+// reporter: it adds ErrorReportingMiddleware and also reports errors returned
+// by flow steps, validators, prompts and OnComplete, including recovered
+// panics. Refusals such as a denied permission are not reported.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithErrorReporter(teleflow.LogReporter(nil)))
+func WithErrorReporter(reporter Reporter) BotOption {
+	return func(b *Bot) {
+		b.errorReporter = reporter
+		b.middleware = append(b.middleware, ErrorReportingMiddleware(reporter))
+	}
+}
+
+// reportFlowError reports an error returned while a flow handled an update, at
+// the flow and step the update reached.
+func (b *Bot) reportFlowError(ctx *Context, err error, flow, step string) {
+	if b.errorReporter == nil || isFlowRefusal(err) {
+		return
+	}
+	report := newErrorReport(ctx, err, flow, step)
+	var fpe *FlowPanicError
+	if errors.As(err, &fpe) {
+		report.Panic = fpe.Value
+		report.Stack = fpe.Stack
+		report.Flow = fpe.Flow
+		if fpe.Step != "" {
+			report.Step = fpe.Step
+		}
+	}
+	b.errorReporter.Report(report)
+}
+
+func newErrorReport(ctx *Context, err error, flow, step string) ErrorReport {
+	var scrubber secretScrubber
+	report := ErrorReport{
+		Err:      scrubber.scrubError(err), // Reporters never see bot tokens
+		Time:     ctx.Now(),
+		UpdateID: ctx.update.UpdateID,
+		UserID:   ctx.UserID(),
+		ChatID:   ctx.ChatID(),
+	}
+
+	update := ctx.update
+	switch {
+	case update.Message != nil && update.Message.IsCommand():
+		report.UpdateType = "command"
+		report.Command = update.Message.Command()
+	case update.Message != nil:
+		report.UpdateType = "message"
+	case update.EditedMessage != nil:
+		report.UpdateType = "edited_message"
+	case update.CallbackQuery != nil:
+		report.UpdateType = "callback_query"
+	case update.InlineQuery != nil:
+		report.UpdateType = "inline_query"
+	case update.ChosenInlineResult != nil:
+		report.UpdateType = "chosen_inline_result"
+	case update.ChannelPost != nil:
+		report.UpdateType = "channel_post"
+	default:
+		report.UpdateType = "other"
+	}
+
+	report.Flow, report.Step = flow, step
+	return report
+}
+
+// LogReporter returns a Reporter that writes reports to logger, or to the
+// standard logger if logger is nil. Stack traces of panics are included.
+func LogReporter(logger *log.Logger) Reporter {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return ReporterFunc(func(r ErrorReport) {
+		logger.Printf("[ERROR_REPORT] %s from user %d in chat %d (command=%q flow=%q step=%q): %v",
+			r.UpdateType, r.UserID, r.ChatID, r.Command, r.Flow, r.Step, r.Err)
+		if r.Stack != nil {
+			logger.Printf("[ERROR_REPORT] %s", r.Stack)
+		}
+	})
+}
+
+// SlogReporter returns a Reporter that logs reports to logger at error level.
+func SlogReporter(logger *slog.Logger) Reporter {
+	return ReporterFunc(func(r ErrorReport) {
+		attrs := []any{
+			"update_type", r.UpdateType,
+			"user_id", r.UserID,
+			"chat_id", r.ChatID,
+			"command", r.Command,
+			"flow", r.Flow,
+			"step", r.Step,
+		}
+		if r.Stack != nil {
+			attrs = append(attrs, "stack", string(r.Stack))
+		}
+		logger.Error(r.Err.Error(), attrs...)
+	})
+}
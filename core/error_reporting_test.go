@@ -0,0 +1,124 @@
+package teleflow
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func commandUpdate(userID int64, command string) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: 5, Message: &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: userID},
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Text:      "/" + command,
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Length: len(command) + 1}},
+	}}
+}
+
+func TestErrorReportingMiddleware(t *testing.T) {
+	var reports []ErrorReport
+	bot, _, _, _ := createTestBot()
+	bot.UseMiddleware(ErrorReportingMiddleware(ReporterFunc(func(r ErrorReport) {
+		reports = append(reports, r)
+	})))
+	bot.RegisterFlow(createTestFlow())
+
+	failure := errors.New("database unavailable")
+	bot.HandleCommand("order", func(ctx *Context, command, args string) error {
+		if err := ctx.StartFlow("test-flow"); err != nil {
+			return err
+		}
+		return failure
+	})
+	bot.HandleCommand("crash", func(ctx *Context, command, args string) error {
+		panic("nil map")
+	})
+
+	bot.ProcessExternalUpdate(commandUpdate(42, "order"))
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+	r := reports[0]
+	if !errors.Is(r.Err, failure) || r.Panic != nil || r.UpdateID != 5 || r.UserID != 42 || r.ChatID != 42 {
+		t.Errorf("Unexpected report: %+v", r)
+	}
+	// The flow was started by the failing handler, so the error happened outside it
+	if r.UpdateType != "command" || r.Command != "order" || r.Flow != "" || r.Step != "" {
+		t.Errorf("Expected command context without the flow started by the handler, got %+v", r)
+	}
+
+	bot.ProcessExternalUpdate(commandUpdate(7, "crash"))
+	if len(reports) != 2 {
+		t.Fatalf("Expected the panic to be reported, got %d reports", len(reports))
+	}
+	r = reports[1]
+	if r.Panic != "nil map" || len(r.Stack) == 0 || !strings.Contains(r.Err.Error(), "nil map") || r.Flow != "" {
+		t.Errorf("Unexpected panic report: %+v", r)
+	}
+}
+
+func TestWithErrorReporter_ReportsFlowErrors(t *testing.T) {
+	var reports []ErrorReport
+	bot, _, _, _ := createTestBot(WithErrorReporter(ReporterFunc(func(r ErrorReport) {
+		reports = append(reports, r)
+	})))
+	failure := errors.New("ledger unavailable")
+	flow, err := NewFlow("transfer").
+		Step("amount").
+		Prompt("How much?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			if input == "crash" {
+				panic("nil map")
+			}
+			return NextStep()
+		}).
+		Step("confirm").
+		Prompt("Confirm?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return CompleteFlow()
+		}).
+		OnComplete(func(ctx *Context) error { return failure }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	bot.HandleCommand("transfer", func(ctx *Context, command, args string) error {
+		return ctx.StartFlow("transfer")
+	})
+
+	bot.ProcessExternalUpdate(commandUpdate(42, "transfer"))
+	bot.ProcessExternalUpdate(createPoolTestUpdate(42, "crash"))
+	if len(reports) != 1 {
+		t.Fatalf("Expected the step panic to be reported, got %d reports", len(reports))
+	}
+	if r := reports[0]; r.Panic != "nil map" || len(r.Stack) == 0 || r.Flow != "transfer" || r.Step != "amount" {
+		t.Errorf("Unexpected panic report: %+v", r)
+	}
+
+	// The panic cancelled the flow; run it again up to OnComplete
+	bot.ProcessExternalUpdate(commandUpdate(42, "transfer"))
+	bot.ProcessExternalUpdate(createPoolTestUpdate(42, "100"))
+	bot.ProcessExternalUpdate(createPoolTestUpdate(42, "yes"))
+	if len(reports) != 2 {
+		t.Fatalf("Expected the OnComplete error to be reported, got %d reports", len(reports))
+	}
+	if r := reports[1]; !errors.Is(r.Err, failure) || r.Flow != "transfer" || r.Step != "confirm" {
+		t.Errorf("Expected the error at the step it happened in, got %+v", r)
+	}
+}
+
+func TestLogReporter(t *testing.T) {
+	var buf bytes.Buffer
+	LogReporter(log.New(&buf, "", 0)).Report(ErrorReport{
+		Err: errors.New("boom"), UpdateType: "command", UserID: 1, ChatID: 2, Command: "order",
+	})
+	if out := buf.String(); !strings.Contains(out, `command="order"`) || !strings.Contains(out, "boom") {
+		t.Errorf("Unexpected log output: %q", out)
+	}
+}
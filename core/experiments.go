@@ -0,0 +1,114 @@
+package teleflow
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Variant is one version of a message in an Experiment.
+type Variant struct {
+	Name    string      // Name reported to the exposure hook
+	Weight  int         // Share of users relative to the other variants; 0 never shows it
+	Message MessageSpec // String, template reference, function or *TextBuilder
+}
+
+// Experiment is an A/B test of message variants. Each user is assigned a variant
+// by hashing their ID with the experiment name, so they keep seeing the same
+// copy, and users are split between the variants by weight. An *Experiment can
+// be used wherever a MessageSpec is accepted, e.g. as a step prompt.
+type Experiment struct {
+	Name     string
+	Variants []Variant
+}
+
+// ExposureFunc is called each time a user is shown a variant of an experiment,
+// so analytics can attribute conversions to the copy the user saw.
+type ExposureFunc func(ctx *Context, experiment, variant string)
+
+// ABTest returns an experiment showing each user one of the variants.
+//
+// Example:
+//
+//	welcome := teleflow.ABTest("welcome_copy",
+//		teleflow.Variant{Name: "short", Weight: 50, Message: "template:welcome_short"},
+//		teleflow.Variant{Name: "story", Weight: 50, Message: "template:welcome_story"},
+//	)
+//
+//	flow.Step("welcome").Prompt(welcome).Process(...)
+//
+//	// Other parts of the prompt can follow the assignment
+//	if welcome.VariantFor(ctx.UserID()).Name == "story" {
+//		...
+//	}
+func ABTest(name string, variants ...Variant) *Experiment {
+	return &Experiment{Name: name, Variants: variants}
+}
+
+// WithExposureHook reports every experiment variant shown to a user to hook,
+// e.g. to record it in an analytics system.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithExposureHook(
+//		func(ctx *teleflow.Context, experiment, variant string) {
+//			analytics.Track(ctx.UserID(), "experiment_exposure", experiment, variant)
+//		},
+//	))
+func WithExposureHook(hook ExposureFunc) BotOption {
+	return func(b *Bot) {
+		b.exposureHook = hook
+	}
+}
+
+// VariantFor returns the variant assigned to a user. When no variant has a
+// positive weight the first one is used; without variants it returns the zero
+// Variant, whose message is empty.
+func (e *Experiment) VariantFor(userID int64) Variant {
+	total := 0
+	for _, variant := range e.Variants {
+		if variant.Weight > 0 {
+			total += variant.Weight
+		}
+	}
+	if total == 0 {
+		if len(e.Variants) == 0 {
+			return Variant{}
+		}
+		return e.Variants[0]
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(e.Name))
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], uint64(userID))
+	h.Write(id[:])
+	bucket := int(h.Sum64() % uint64(total))
+
+	for _, variant := range e.Variants {
+		if variant.Weight <= 0 {
+			continue
+		}
+		if bucket < variant.Weight {
+			return variant
+		}
+		bucket -= variant.Weight
+	}
+	return e.Variants[len(e.Variants)-1]
+}
+
+// renderExperiment renders the variant of an experiment assigned to the
+// context's user and reports the exposure.
+func (mr *messageHandler) renderExperiment(experiment *Experiment, config *PromptConfig, ctx *Context) (string, ParseMode, error) {
+	var userID int64
+	if ctx != nil {
+		userID = ctx.UserID()
+	}
+	variant := experiment.VariantFor(userID)
+	if mr.onExposure != nil && ctx != nil {
+		mr.onExposure(ctx, experiment.Name, variant.Name)
+	}
+
+	variantConfig := *config
+	variantConfig.Message = variant.Message
+	return mr.renderMessage(&variantConfig, ctx)
+}
@@ -0,0 +1,71 @@
+package teleflow
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestExperiment_VariantFor(t *testing.T) {
+	experiment := ABTest("welcome",
+		Variant{Name: "a", Weight: 30},
+		Variant{Name: "off", Weight: 0},
+		Variant{Name: "b", Weight: 70},
+	)
+
+	counts := map[string]int{}
+	for userID := int64(1); userID <= 10000; userID++ {
+		variant := experiment.VariantFor(userID)
+		if variant.Name != experiment.VariantFor(userID).Name {
+			t.Fatalf("Expected user %d to keep their variant", userID)
+		}
+		counts[variant.Name]++
+	}
+	if counts["off"] != 0 {
+		t.Errorf("Expected a variant without weight never to be shown, got %d", counts["off"])
+	}
+	if counts["a"] < 2700 || counts["a"] > 3300 {
+		t.Errorf("Expected about 30%% of users in variant a, got %d", counts["a"])
+	}
+
+	if variant := ABTest("empty").VariantFor(1); variant.Name != "" || variant.Message != nil {
+		t.Errorf("Expected the zero variant without variants, got %+v", variant)
+	}
+}
+
+func TestExperiment_PromptAndExposureHook(t *testing.T) {
+	type exposure struct {
+		userID              int64
+		experiment, variant string
+	}
+	var exposures []exposure
+	bot, client, _, _ := createTestBot(WithExposureHook(func(ctx *Context, experiment, variant string) {
+		exposures = append(exposures, exposure{ctx.UserID(), experiment, variant})
+	}))
+
+	var sent []string
+	client.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+			sent = append(sent, msg.Text)
+		}
+		return tgbotapi.Message{MessageID: 123}, nil
+	}
+
+	experiment := ABTest("greeting",
+		Variant{Name: "hi", Weight: 1, Message: "Hi!"},
+		Variant{Name: "hello", Weight: 1, Message: func(ctx *Context) string { return "Hello!" }},
+	)
+	ctx := bot.contextFor(42, 42)
+	if err := ctx.SendPrompt(&PromptConfig{Message: experiment}); err != nil {
+		t.Fatalf("SendPrompt failed: %v", err)
+	}
+
+	want := map[string]string{"hi": "Hi!", "hello": "Hello!"}
+	variant := experiment.VariantFor(42).Name
+	if len(sent) != 1 || sent[0] != want[variant] {
+		t.Errorf("Expected the text of variant %s, got %v", variant, sent)
+	}
+	if len(exposures) != 1 || exposures[0] != (exposure{42, "greeting", variant}) {
+		t.Errorf("Expected one exposure of variant %s, got %+v", variant, exposures)
+	}
+}
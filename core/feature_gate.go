@@ -0,0 +1,41 @@
+package teleflow
+
+// FeatureGate decides whether a feature is enabled for a user, so gradual
+// rollouts and kill switches can be backed by a feature flag service. Features
+// are named like the keys of Bot.Stats: "command:/balance" for commands,
+// "text:Help" for text handlers and "flow:transfer" for flows.
+type FeatureGate interface {
+	IsEnabled(feature string, userID int64) bool
+}
+
+// FeatureGateFunc adapts a function to the FeatureGate interface.
+type FeatureGateFunc func(feature string, userID int64) bool
+
+// IsEnabled calls f.
+func (f FeatureGateFunc) IsEnabled(feature string, userID int64) bool {
+	return f(feature, userID)
+}
+
+// WithFeatureGate consults gate before a command or text handler runs and
+// before a flow starts. A disabled command or text is handled as if it had no
+// handler, falling back to the default handler. A disabled flow is refused as
+// if disabled with Bot.DisableFlow, with the generic maintenance notice.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithFeatureGate(
+//		teleflow.FeatureGateFunc(func(feature string, userID int64) bool {
+//			return flags.BoolVariation(feature, flagUser(userID), true)
+//		}),
+//	))
+func WithFeatureGate(gate FeatureGate) BotOption {
+	return func(b *Bot) {
+		b.featureGate = gate
+	}
+}
+
+// featureEnabled asks the FeatureGate, if any, whether a feature is enabled for
+// a user.
+func featureEnabled(gate FeatureGate, feature string, userID int64) bool {
+	return gate == nil || gate.IsEnabled(feature, userID)
+}
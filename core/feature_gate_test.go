@@ -0,0 +1,44 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFeatureGate(t *testing.T) {
+	var asked []string
+	gate := FeatureGateFunc(func(feature string, userID int64) bool {
+		asked = append(asked, feature)
+		return userID != 42
+	})
+	bot, _, _, _ := createTestBot(WithFeatureGate(gate))
+
+	var called []string
+	bot.HandleCommand("beta", func(ctx *Context, command, args string) error {
+		called = append(called, "beta")
+		return nil
+	})
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		called = append(called, "default")
+		return nil
+	})
+	bot.RegisterFlow(createTestFlow())
+
+	bot.processUpdate(createCommandUpdate("beta", "/beta"))
+	if len(called) != 1 || called[0] != "default" {
+		t.Errorf("Expected the gated command to fall back to the default handler, got %v", called)
+	}
+	if len(asked) != 1 || asked[0] != "command:/beta" {
+		t.Errorf("Expected the gate to be asked for the command, got %v", asked)
+	}
+
+	if err := bot.StartFlowFor(42, 42, "test-flow", nil); !errors.Is(err, ErrFlowDisabled) {
+		t.Errorf("Expected ErrFlowDisabled for a gated flow, got %v", err)
+	}
+	if err := bot.StartFlowFor(7, 7, "test-flow", nil); err != nil {
+		t.Errorf("Expected the flow to start for other users, got %v", err)
+	}
+	if asked[len(asked)-1] != "flow:test-flow" {
+		t.Errorf("Expected the gate to be asked for the flow, got %v", asked)
+	}
+}
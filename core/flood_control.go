@@ -0,0 +1,264 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// FloodAction selects what flood control does when a user exceeds a FloodRule. Actions can be combined, e.g. FloodWarn | FloodNotifyAdmins.
+type FloodAction uint8
+
+const (
+	FloodWarn         FloodAction = 1 << iota // Post a warning in the chat
+	FloodMute                                 // Restrict the user from sending messages for MuteDuration
+	FloodNotifyAdmins                         // Send a private notice to every chat administrator
+)
+
+// FloodRule describes the allowed message rate in a chat and the reaction when it is exceeded.
+// A rule with MaxMessages of zero disables flood control.
+type FloodRule struct {
+	MaxMessages  int           // Messages allowed per user within Window
+	Window       time.Duration // Sliding window for counting messages (default 10 seconds)
+	Action       FloodAction   // What to do when the limit is exceeded (default FloodWarn)
+	MuteDuration time.Duration // How long FloodMute restricts the user (default 5 minutes)
+	WarnMessage  string        // Warning text, may contain %s for the user's name
+}
+
+// FloodControlConfig configures WithFloodControl and FloodControlMiddleware.
+type FloodControlConfig struct {
+	Default FloodRule           // Rule applied to every group without an entry in PerChat
+	PerChat map[int64]FloodRule // Chat-specific rules, keyed by chat ID
+}
+
+// ruleFor returns the effective rule for a chat with defaults filled in.
+func (cfg FloodControlConfig) ruleFor(chatID int64) FloodRule {
+	rule, ok := cfg.PerChat[chatID]
+	if !ok {
+		rule = cfg.Default
+	}
+	if rule.Window <= 0 {
+		rule.Window = 10 * time.Second
+	}
+	if rule.Action == 0 {
+		rule.Action = FloodWarn
+	}
+	if rule.MuteDuration <= 0 {
+		rule.MuteDuration = 5 * time.Minute
+	}
+	if rule.WarnMessage == "" {
+		rule.WarnMessage = "⚠️ %s, please slow down."
+	}
+	return rule
+}
+
+// floodKey identifies a user within a chat.
+type floodKey struct {
+	ChatID int64
+	UserID int64
+}
+
+// floodState tracks a user's recent messages in a chat.
+type floodState struct {
+	timestamps []time.Time
+	triggered  time.Time     // When the rule last fired, to avoid reacting to every message
+	window     time.Duration // Window of the rule last applied, after which the state is stale
+}
+
+// stale reports whether the state no longer affects any decision at now.
+func (s *floodState) stale(now time.Time) bool {
+	cutoff := now.Add(-s.window)
+	if len(s.timestamps) > 0 && s.timestamps[len(s.timestamps)-1].After(cutoff) {
+		return false
+	}
+	return !s.triggered.After(cutoff)
+}
+
+// floodPruneInterval is how often flood control drops the state of users who
+// stopped posting.
+const floodPruneInterval = time.Minute
+
+// floodGuard counts the messages users post in group chats and reacts when a
+// chat's FloodRule is exceeded.
+type floodGuard struct {
+	config    FloodControlConfig
+	mu        sync.Mutex
+	states    map[floodKey]*floodState
+	nextPrune time.Time
+	admins    *chatAdminCache
+}
+
+func newFloodGuard(config FloodControlConfig) *floodGuard {
+	return &floodGuard{
+		config: config,
+		states: make(map[floodKey]*floodState),
+		admins: newChatAdminCache(),
+	}
+}
+
+// allow counts the update's message and reports whether it may be handled. The
+// configured action fires at most once per window for a user over the limit.
+func (g *floodGuard) allow(ctx *Context) bool {
+	msg := ctx.update.Message
+	if msg == nil || !ctx.IsGroup() {
+		return true
+	}
+
+	rule := g.config.ruleFor(ctx.ChatID())
+	if rule.MaxMessages <= 0 {
+		return true
+	}
+
+	now := ctx.Now()
+	key := floodKey{ChatID: ctx.ChatID(), UserID: ctx.UserID()}
+
+	g.mu.Lock()
+	if !now.Before(g.nextPrune) {
+		for k, st := range g.states {
+			if st.stale(now) {
+				delete(g.states, k)
+			}
+		}
+		g.nextPrune = now.Add(floodPruneInterval)
+	}
+	state, exists := g.states[key]
+	if !exists {
+		state = &floodState{}
+		g.states[key] = state
+	}
+	state.window = rule.Window
+	state.timestamps = append(pruneTimestamps(state.timestamps, now.Add(-rule.Window)), now)
+	exceeded := len(state.timestamps) > rule.MaxMessages
+	react := exceeded && now.Sub(state.triggered) >= rule.Window
+	if react {
+		state.triggered = now
+	}
+	g.mu.Unlock()
+
+	if !exceeded || g.admins.isChatAdmin(ctx) {
+		return true
+	}
+	if react {
+		ctx.applyFloodAction(rule, msg.From)
+	}
+	return false
+}
+
+// WithFloodControl tracks how often each user posts in group chats and reacts
+// when a chat's FloodRule is exceeded. Every group message is counted before it
+// reaches flows or handlers, including messages no handler matches; messages
+// over the limit are dropped and the configured action fires at most once per
+// window. Chat administrators and private chats are never limited;
+// administrator status is looked up once a minute per user and chat, and only
+// for users over the limit.
+//
+// Muting requires the bot to have the "ban users" admin right in the group.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithFloodControl(teleflow.FloodControlConfig{
+//		Default: teleflow.FloodRule{MaxMessages: 5, Window: 10 * time.Second},
+//		PerChat: map[int64]teleflow.FloodRule{
+//			-1001234567890: {MaxMessages: 3, Action: teleflow.FloodMute | teleflow.FloodNotifyAdmins},
+//		},
+//	}))
+func WithFloodControl(config FloodControlConfig) BotOption {
+	return func(b *Bot) {
+		b.flood = newFloodGuard(config)
+	}
+}
+
+// FloodControlMiddleware applies flood control like WithFloodControl, but only
+// to messages that reach the wrapped handler. Flow input and messages no
+// handler matches are not counted, so in groups use WithFloodControl instead.
+//
+// Example:
+//
+//	bot.UseMiddleware(teleflow.FloodControlMiddleware(teleflow.FloodControlConfig{
+//		Default: teleflow.FloodRule{MaxMessages: 5, Window: 10 * time.Second},
+//	}))
+func FloodControlMiddleware(config FloodControlConfig) MiddlewareFunc {
+	guard := newFloodGuard(config)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) error {
+			if !guard.allow(ctx) {
+				return nil
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// pruneTimestamps drops timestamps older than cutoff, reusing the slice.
+func pruneTimestamps(timestamps []time.Time, cutoff time.Time) []time.Time {
+	kept := timestamps[:0]
+	for _, ts := range timestamps {
+		if ts.After(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	return kept
+}
+
+// applyFloodAction performs the actions configured in rule against the current user.
+func (c *Context) applyFloodAction(rule FloodRule, user *tgbotapi.User) {
+	name := fmt.Sprintf("user %d", c.UserID())
+	if user != nil {
+		name = user.FirstName
+		if user.UserName != "" {
+			name = "@" + user.UserName
+		}
+	}
+
+	if rule.Action&FloodMute != 0 {
+		_, err := c.telegramClient.Request(tgbotapi.RestrictChatMemberConfig{
+			ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: c.ChatID(), UserID: c.UserID()},
+			UntilDate:        c.Now().Add(rule.MuteDuration).Unix(),
+			Permissions:      &tgbotapi.ChatPermissions{},
+		})
+		if err != nil {
+			log.Printf("[FLOOD] Failed to mute user %d in chat %d: %v", c.UserID(), c.ChatID(), err)
+		}
+	}
+
+	if rule.Action&FloodWarn != 0 {
+		if err := c.sendSimpleText(fmt.Sprintf(rule.WarnMessage, name)); err != nil {
+			log.Printf("[FLOOD] Failed to warn user %d in chat %d: %v", c.UserID(), c.ChatID(), err)
+		}
+	}
+
+	if rule.Action&FloodNotifyAdmins != 0 {
+		c.notifyChatAdmins(fmt.Sprintf("⚠️ %s is flooding chat %d.", name, c.ChatID()))
+	}
+}
+
+// notifyChatAdmins sends text privately to every human administrator of the current chat.
+// Admins who never started the bot cannot be reached; failures are only logged.
+func (c *Context) notifyChatAdmins(text string) {
+	resp, err := c.telegramClient.Request(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: c.ChatID()},
+	})
+	if err != nil {
+		log.Printf("[FLOOD] Failed to get administrators of chat %d: %v", c.ChatID(), err)
+		return
+	}
+
+	var admins []tgbotapi.ChatMember
+	if err := json.Unmarshal(resp.Result, &admins); err != nil {
+		log.Printf("[FLOOD] Failed to decode administrators of chat %d: %v", c.ChatID(), err)
+		return
+	}
+
+	for _, admin := range admins {
+		if admin.User == nil || admin.User.IsBot {
+			continue
+		}
+		if _, err := c.telegramClient.Send(tgbotapi.NewMessage(admin.User.ID, text)); err != nil {
+			log.Printf("[FLOOD] Failed to notify admin %d: %v", admin.User.ID, err)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package teleflow
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"sync"
 	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // errorStrategy defines the internal enumeration of error handling strategies.
@@ -14,18 +16,30 @@ const (
 	errorStrategyCancel errorStrategy = iota
 	errorStrategyRetry
 	errorStrategyIgnore
+	errorStrategyFunc
 )
 
 const (
 	// defaultErrorMessageCancel is the default message shown when a flow is cancelled due to an error.
 	defaultErrorMessageCancel = "❗ A technical error occurred. Flow has been cancelled."
+
+	// defaultMaxRetriesMessage is shown when a step exceeds its retry limit and the flow has no OnMaxRetries handler.
+	defaultMaxRetriesMessage = "🚫 Too many invalid attempts. Flow has been cancelled."
+
+	// defaultPendingValidationMessage is shown while a step's asynchronous validators run.
+	defaultPendingValidationMessage = "⏳ Checking…"
 )
 
+// ErrFlowPermissionDenied is returned by StartFlow when the AccessManager denies a
+// flow's required permission. The user has already been shown the denial prompt.
+var ErrFlowPermissionDenied = errors.New("flow permission denied")
+
 // ErrorConfig defines how flows should handle errors during step processing.
 // It specifies both the action to take and an optional user-facing message.
 type ErrorConfig struct {
-	Action  errorStrategy // The strategy to use when handling errors
-	Message string        // Message to display to the user (optional)
+	Action  errorStrategy    // The strategy to use when handling errors
+	Message string           // Message to display to the user (optional)
+	Handler ErrorHandlerFunc // Decides the outcome for OnErrorFunc
 }
 
 // ON_ERROR_SILENT is a special constant that can be used as a message
@@ -92,6 +106,30 @@ func OnErrorIgnore(message ...string) *ErrorConfig {
 	}
 }
 
+// OnErrorFunc creates an ErrorConfig that lets a function decide how the flow
+// continues after an error. The handler runs without flow locks held and can
+// use the context to inspect flow data, notify the user or alert admins; the
+// ProcessResult it returns is applied to the failed step.
+//
+// Example:
+//
+//	flow := teleflow.NewFlow("example").
+//		OnError(teleflow.OnErrorFunc(func(ctx *teleflow.Context, err error) teleflow.ProcessResult {
+//			if errors.Is(err, teleflow.ErrChatNotFound) {
+//				return teleflow.CancelFlow()
+//			}
+//			notifyAdmins(ctx, err)
+//			return teleflow.Retry().WithPrompt("Something went wrong, please try again.")
+//		})).
+//		// ... define steps
+//		Build()
+func OnErrorFunc(handler ErrorHandlerFunc) *ErrorConfig {
+	return &ErrorConfig{
+		Action:  errorStrategyFunc,
+		Handler: handler,
+	}
+}
+
 // FlowConfig configures global flow behavior and command handling.
 // It defines exit commands, help commands, and default message processing actions.
 type FlowConfig struct {
@@ -100,76 +138,324 @@ type FlowConfig struct {
 	AllowGlobalCommands bool                 // Whether global commands work during flows
 	HelpCommands        []string             // Commands considered "help" commands
 	OnProcessAction     ProcessMessageAction // Default action for processing messages
+	StripKeyboardsOnEnd bool                 // Remove inline keyboards from step messages when a flow ends
+
+	GlobalCommandWhitelist []string           // Commands that work during flows, e.g. {"/balance"}; replaces AllowGlobalCommands when set
+	Interruption           InterruptionPolicy // What happens to the flow while such a command runs
+
+	// MaxActiveFlows caps the flows in progress across all users, 0 for no cap.
+	// Starting a flow at the cap evicts the least recently active flow without
+	// running its OnCancel handler; the buttons of its prompts stop working. A
+	// warning is logged above 90% of the cap.
+	MaxActiveFlows int
+
+	// StateTTL removes flows that have been idle for longer, so the states of
+	// users who simply disappear do not accumulate. OnExpired is called for each
+	// removed flow. 0 keeps idle flows until their own timeout, if any.
+	StateTTL  time.Duration
+	OnExpired FlowExpiredFunc
+}
+
+// flowKey identifies a stored flow state. Depending on the flow's scope,
+// either UserID, ChatID or both are set; unused fields are zero.
+type flowKey struct {
+	UserID int64
+	ChatID int64
+}
+
+// newFlowKey builds the state key for a flow with the given scope.
+func newFlowKey(scope FlowScope, userID, chatID int64) flowKey {
+	switch scope {
+	case FlowScopeChat:
+		return flowKey{ChatID: chatID}
+	case FlowScopeUserChat:
+		return flowKey{UserID: userID, ChatID: chatID}
+	default:
+		return flowKey{UserID: userID}
+	}
 }
 
 // flowManager manages all active conversation flows and their state.
 // It handles flow registration, user state tracking, and flow execution.
 // This is an internal component not exposed to bot users directly.
 type flowManager struct {
-	flows       map[string]*Flow         // Registered flows by name
-	userFlows   map[int64]*userFlowState // Active user flow states
-	muUserFlows sync.RWMutex             // Mutex for thread-safe flow operations
-	flowConfig  *FlowConfig              // Global flow configuration
+	flows      map[string]*Flow // Registered flows by name
+	shards     []flowShard      // Active flow states keyed by scope, spread over shards
+	flowConfig *FlowConfig      // Global flow configuration
 
 	promptSender   PromptSender          // Component for sending prompts
 	keyboardAccess PromptKeyboardActions // Handler for keyboard interactions
 	messageCleaner MessageCleaner        // Component for message management
+	clock          Clock                 // Source of flow timestamps
+	startLimits    flowStartTracker      // Recent starts and aborts of flows with a StartLimit
+	capacity       flowCapacity          // Recency index and evictions for FlowConfig.MaxActiveFlows
+	disabled       disabledFlows         // Flows closed for new entries by Bot.DisableFlow
+	featureGate    FeatureGate           // Enables flows per user (nil for all)
+	admins         *chatAdminCache       // Recent administrator lookups for InputFromAdmins
 }
 
 func newFlowManager(config *FlowConfig, pSender PromptSender, kAccess PromptKeyboardActions, mCleaner MessageCleaner) *flowManager {
 	return &flowManager{
 		flows:          make(map[string]*Flow),
-		userFlows:      make(map[int64]*userFlowState),
+		shards:         newFlowShards(flowStateShards),
 		flowConfig:     config,
 		promptSender:   pSender,
 		keyboardAccess: kAccess,
 		messageCleaner: mCleaner,
+		clock:          systemClock{},
+		admins:         newChatAdminCache(),
 	}
 }
 
-func (fm *flowManager) isUserInFlow(userID int64) bool {
-	fm.muUserFlows.RLock()
-	defer fm.muUserFlows.RUnlock()
-	_, exists := fm.userFlows[userID]
+// lookupState_nolock finds the flow state that applies to a user in a chat.
+// More specific scopes win: a per-user-per-chat flow shadows a chat-wide flow,
+// which in turn shadows the user's personal flow. The caller must hold the
+// user's and chat's stateLocks.
+func (fm *flowManager) lookupState_nolock(userID, chatID int64) (flowKey, *userFlowState, bool) {
+	for _, key := range stateKeys(userID, chatID) {
+		if key.UserID == 0 && key.ChatID == 0 {
+			continue
+		}
+		if state, ok := fm.getState_nolock(key); ok {
+			return key, state, true
+		}
+	}
+	return flowKey{}, nil, false
+}
+
+// removeState_nolock deletes the flow state that applies to the context's user and chat
+// and returns it, or nil if there was none.
+func (fm *flowManager) removeState_nolock(ctx *Context) *userFlowState {
+	key, state, ok := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID())
+	if ok {
+		fm.deleteState_nolock(key)
+	}
+	return state
+}
+
+func (fm *flowManager) isUserInFlow(userID, chatID int64) bool {
+	locks := fm.stateLocks(userID, chatID)
+	locks.RLock()
+	defer locks.RUnlock()
+	_, _, exists := fm.lookupState_nolock(userID, chatID)
 	return exists
 }
 
-func (fm *flowManager) cancelFlow(userID int64) {
-	fm.muUserFlows.Lock()
-	defer fm.muUserFlows.Unlock()
-	delete(fm.userFlows, userID)
+func (fm *flowManager) cancelFlow(userID, chatID int64, ctx *Context) {
+	fm.cancelFlowWithReason(userID, chatID, ctx, CancelReasonManual)
+}
+
+// cancelFlowWithReason cancels the flow that applies to a user in a chat and runs
+// its OnCancel handler when a context is given.
+func (fm *flowManager) cancelFlowWithReason(userID, chatID int64, ctx *Context, reason CancelReason) {
+	locks := fm.stateLocks(userID, chatID)
+	locks.Lock()
+	_, state, ok := fm.lookupState_nolock(userID, chatID)
+	if ok {
+		fm.cleanupMappings(state.Key)
+		if ctx != nil {
+			fm.runOnCancel_withLockRelease(ctx, locks, state, reason)
+		}
+		fm.deleteIfCurrent_nolock(state)
+		fm.startLimits.aborted(fm.flows[state.FlowName], userID, fm.clock.Now())
+	}
+	locks.Unlock()
+
+	if ok && ctx != nil {
+		fm.stripKeyboards(ctx, state)
+	}
+}
+
+// activeFlowCount returns the number of flows currently in progress.
+func (fm *flowManager) activeFlowCount() int {
+	count := 0
+	for i := range fm.shards {
+		shard := &fm.shards[i]
+		shard.mu.RLock()
+		count += len(shard.states)
+		shard.mu.RUnlock()
+	}
+	return count
+}
+
+// currentState returns the flow state that applies to a user in a chat, or nil.
+// The pointer identifies a particular run of a flow and can be compared later
+// with another currentState result.
+func (fm *flowManager) currentState(userID, chatID int64) *userFlowState {
+	locks := fm.stateLocks(userID, chatID)
+	locks.RLock()
+	defer locks.RUnlock()
+	_, state, _ := fm.lookupState_nolock(userID, chatID)
+	return state
+}
+
+// currentStep returns the flow a user is in and its current step.
+func (fm *flowManager) currentStep(userID, chatID int64) (string, string, bool) {
+	locks := fm.stateLocks(userID, chatID)
+	locks.RLock()
+	defer locks.RUnlock()
+	_, state, ok := fm.lookupState_nolock(userID, chatID)
+	if !ok {
+		return "", "", false
+	}
+	return state.FlowName, state.CurrentStep, true
 }
 
 type Flow struct {
 	Name            string
 	Steps           map[string]*flowStep
 	Order           []string
+	OnStart         func(*Context) error
 	OnComplete      func(*Context) error
+	OnCancel        func(*Context) error
 	OnError         *ErrorConfig
 	OnProcessAction ProcessMessageAction
 	Timeout         time.Duration
+	Scope           FlowScope
+	InputPolicy     ChatInputPolicy
+	OnMaxRetries    MaxRetriesHandler
+	EditInPlace     bool     // Steps edit the previous prompt message instead of sending a new one
+	ExitCommands    []string // Replaces FlowConfig.ExitCommands when not nil
+	ExitMessage     string   // Replaces FlowConfig.ExitMessage when not empty
+	GlobalCommands  []string // Replaces FlowConfig.GlobalCommandWhitelist when not nil
+
+	PauseOnInterrupt bool // Pause the flow around global commands, as InterruptPauseResume does
+
+	RequiredPermission     string      // Permission checked before the flow starts
+	PermissionDeniedPrompt MessageSpec // Prompt shown when a permission check fails
+
+	StartLimit   *FlowStartLimit // Per-user cap on starts and aborted runs, nil for none
+	RedactedKeys []string        // Flow data keys hidden from Bot.ActiveFlows
 }
 
 type flowStep struct {
 	Name         string
 	PromptConfig *PromptConfig
 	ProcessFunc  ProcessFunc
+	Validators   []Validator
+
+	RequiredPermission string // Permission checked before the step's prompt is sent
+	AsyncValidators    []Validator
+	PendingPrompt      MessageSpec
+	DeleteUserInput    bool         // Delete the user's text input after processing
+	OnError            *ErrorConfig // Overrides Flow.OnError for this step
+	Help               MessageSpec  // Answer to help commands sent during this step
+	AcceptReactions    bool         // Reactions to the step's prompt are processed as answers
+	Reactions          []string     // Emoji accepted as answers, empty for any reaction
+	Job                JobFunc      // Background job started once the step's prompt is sent
+	MaxAttempts        int          // Retries allowed before OnMaxRetries when a result sets no limit
+}
+
+// errorConfig returns the error strategy for a step: the step's own OnError,
+// else the flow's, else cancelling the flow.
+func (f *Flow) errorConfig(stepName string) *ErrorConfig {
+	if step := f.Steps[stepName]; step != nil && step.OnError != nil {
+		return step.OnError
+	}
+	if f.OnError != nil {
+		return f.OnError
+	}
+	return &ErrorConfig{Action: errorStrategyCancel, Message: defaultErrorMessageCancel}
+}
+
+// pendingPrompt returns the message shown while asynchronous validators or the
+// step's job run.
+func (s *flowStep) pendingPrompt() MessageSpec {
+	if s.PendingPrompt != nil && s.PendingPrompt != "" {
+		return s.PendingPrompt
+	}
+	if s.Job != nil {
+		return defaultJobRunningMessage
+	}
+	return defaultPendingValidationMessage
+}
+
+// validate runs the step's validators against a text input.
+// Button clicks are produced by the bot's own keyboards and are not validated.
+func (s *flowStep) validate(ctx *Context, input string, buttonClick *ButtonClick) error {
+	if buttonClick != nil || len(s.Validators) == 0 {
+		return nil
+	}
+	return Chain(s.Validators...)(ctx, input)
 }
 
 type userFlowState struct {
+	Key           flowKey
 	FlowName      string
 	CurrentStep   string
 	Data          map[string]interface{}
 	StartedAt     time.Time
 	LastActive    time.Time
 	LastMessageID int
+	RetryCount    int
+
+	ValidationPending bool         // An asynchronous validation for the current step is running
+	JobRunning        bool         // The current step's background job is running
+	WaitingForEvent   bool         // The current step returned WaitForEvent and awaits ResumeFlow
+	Cancelling        bool         // The flow's OnCancel handler is running
+	HandlingError     bool         // An OnErrorFunc handler result is being applied
+	LastPrompt        sentPrompt   // Most recent step prompt, edited by EditInPlace flows
+	KeyboardPrompts   []sentPrompt // Step prompts sent with an inline keyboard that is still shown
+	PromptMessages    []sentPrompt // Every message of the most recent step prompt, including its sequence
+}
+
+// trackPrompts records the messages of a step prompt delivered to the user.
+func (s *userFlowState) trackPrompts(messages []sentPrompt) {
+	if len(messages) > 0 {
+		s.PromptMessages = append([]sentPrompt(nil), messages...)
+	}
+	for _, sent := range messages {
+		s.LastMessageID = sent.MessageID
+		s.LastPrompt = sent
+		// An edited prompt keeps its message ID; record it only once
+		s.forgetPrompt(sent.ChatID, sent.MessageID)
+		if sent.InlineKeyboard {
+			s.KeyboardPrompts = append(s.KeyboardPrompts, sent)
+		}
+	}
+}
+
+// isPromptMessage reports whether a message belongs to the current step's prompt.
+func (s *userFlowState) isPromptMessage(chatID int64, messageID int) bool {
+	for _, sent := range s.PromptMessages {
+		if sent.ChatID == chatID && sent.MessageID == messageID {
+			return true
+		}
+	}
+	return s.LastMessageID == messageID
+}
+
+// forgetPrompt drops a message whose keyboard was already removed or which was deleted.
+func (s *userFlowState) forgetPrompt(chatID int64, messageID int) {
+	kept := s.KeyboardPrompts[:0]
+	for _, sent := range s.KeyboardPrompts {
+		if sent.ChatID != chatID || sent.MessageID != messageID {
+			kept = append(kept, sent)
+		}
+	}
+	s.KeyboardPrompts = kept
 }
 
 func (fm *flowManager) registerFlow(flow *Flow) {
 	fm.flows[flow.Name] = flow
 }
 
-func (fm *flowManager) startFlow(userID int64, flowName string, ctx *Context) error {
+// flowStartOptions describes how a flow is started.
+type flowStartOptions struct {
+	Data map[string]interface{} // Initial flow data, copied into the flow
+	Step string                 // Step to start at, the first step if empty
+}
+
+// startFlow starts a flow with a copy of the context's data as its flow data.
+func (fm *flowManager) startFlow(userID, chatID int64, flowName string, ctx *Context) error {
+	var opts flowStartOptions
+	if ctx != nil {
+		opts.Data = ctx.data
+	}
+	return fm.startFlowWith(userID, chatID, flowName, opts, ctx)
+}
+
+func (fm *flowManager) startFlowWith(userID, chatID int64, flowName string, opts flowStartOptions, ctx *Context) error {
 	flow, exists := fm.flows[flowName]
 	if !exists {
 		return fmt.Errorf("flow %s not found", flowName)
@@ -178,28 +464,77 @@ func (fm *flowManager) startFlow(userID int64, flowName string, ctx *Context) er
 	if len(flow.Order) == 0 {
 		return fmt.Errorf("flow %s has no steps", flowName)
 	}
+	if err := fm.checkFlowEnabled(ctx, flow); err != nil {
+		return err
+	}
+
+	startStep := flow.Order[0]
+	if opts.Step != "" {
+		if _, ok := flow.Steps[opts.Step]; !ok {
+			return fmt.Errorf("step %s not found in flow %s", opts.Step, flowName)
+		}
+		startStep = opts.Step
+	}
+
+	initialData := make(map[string]interface{}, len(opts.Data))
+	for key, value := range opts.Data {
+		initialData[key] = value
+	}
 
-	initialData := make(map[string]interface{})
 	if ctx != nil {
-		for key, value := range ctx.data {
-			initialData[key] = value
+		if err := fm.checkPermission(ctx, flow, "", flow.RequiredPermission); err != nil {
+			fm.denyPermission(ctx, flow, flow.RequiredPermission, err)
+			return fmt.Errorf("%w: %s", ErrFlowPermissionDenied, flowName)
+		}
+		firstStep := flow.Steps[startStep]
+		if firstStep != nil && firstStep.RequiredPermission != "" {
+			if err := fm.checkPermission(ctx, flow, firstStep.Name, firstStep.RequiredPermission); err != nil {
+				fm.denyPermission(ctx, flow, firstStep.RequiredPermission, err)
+				return fmt.Errorf("%w: %s", ErrFlowPermissionDenied, flowName)
+			}
+		}
+		if err := fm.checkStartLimit(ctx, flow); err != nil {
+			return err
 		}
 	}
 
+	key := newFlowKey(flow.Scope, userID, chatID)
+	now := fm.clock.Now()
 	userState := &userFlowState{
+		Key:         key,
 		FlowName:    flowName,
-		CurrentStep: flow.Order[0],
+		CurrentStep: startStep,
 		Data:        initialData,
-		StartedAt:   time.Now(),
-		LastActive:  time.Now(),
+		StartedAt:   now,
+		LastActive:  now,
 	}
 
-	fm.muUserFlows.Lock()
-	fm.userFlows[userID] = userState
-	fm.muUserFlows.Unlock()
+	fm.makeRoomFor(userState)
+	shard := fm.shardFor(key)
+	shard.mu.Lock()
+	if previous, ok := fm.getState_nolock(key); ok {
+		fm.startLimits.aborted(fm.flows[previous.FlowName], userID, now)
+	}
+	fm.putState_nolock(key, userState)
+	shard.mu.Unlock()
+	fm.warnNearCapacity()
 
 	if ctx != nil {
-		return fm.renderStepPrompt(ctx, flow, flow.Order[0], userState)
+		ctx.flowScope = flow.Scope
+		if flow.OnStart != nil {
+			var err error
+			if panicErr := protect(flow.Name, "", func() { err = flow.OnStart(ctx) }); panicErr != nil {
+				err = panicErr
+			}
+			if err != nil {
+				locks := fm.contextLocks(ctx)
+				locks.Lock()
+				fm.deleteIfCurrent_nolock(userState)
+				locks.Unlock()
+				return fmt.Errorf("flow %s aborted by OnStart: %w", flowName, err)
+			}
+		}
+		return fm.renderStepPrompt(ctx, flow, startStep, userState)
 	}
 
 	return nil
@@ -219,20 +554,53 @@ func (fm *flowManager) renderStepPrompt_withLockRelease(ctx *Context, flow *Flow
 		return fmt.Errorf("step %s has no prompt configuration", stepName)
 	}
 
-	// Data copy removed - flow data should be accessed via GetFlowData() only
+	// Templates see a copy of the flow data as .Flow.Data
+	data := copyFlowData(userState.Data)
+
+	if flow.EditInPlace {
+		ctx.editTarget = userState.LastPrompt
+	}
 
 	// Release the mutex before prompt rendering to avoid deadlock
 	// Prompt functions may call GetFlowData/SetFlowData which need the same mutex
-	fm.muUserFlows.Unlock()
+	locks := fm.contextLocks(ctx)
+	locks.Unlock()
+
+	if step.RequiredPermission != "" {
+		var permErr error
+		panicErr := protect(flow.Name, stepName, func() {
+			if permErr = fm.checkPermission(ctx, flow, stepName, step.RequiredPermission); permErr != nil {
+				fm.denyPermission(ctx, flow, step.RequiredPermission, permErr)
+			}
+		})
+		if panicErr != nil {
+			locks.Lock()
+			log.Printf("[FLOW_PERMISSION_PANIC] Flow: %s, Step: %s, User: %d, Error: %v", flow.Name, stepName, ctx.UserID(), panicErr)
+			_, _ = fm.cancelFlowAction_nolock(ctx, CancelReasonError)
+			return panicErr
+		}
+		if permErr != nil {
+			locks.Lock()
+			_, _ = fm.cancelFlowAction_nolock(ctx, CancelReasonPermissionDenied)
+			return nil
+		}
+	}
 
-	err := fm.promptSender.ComposeAndSend(ctx, step.PromptConfig)
+	ctx.sentPrompts = nil
+	var err error
+	if panicErr := protect(flow.Name, stepName, func() { err = fm.composeFlowPrompt(ctx, flow, stepName, data, step.PromptConfig) }); panicErr != nil {
+		err = panicErr
+	}
+	ctx.editTarget = sentPrompt{}
 
 	// Re-acquire the mutex after prompt rendering
-	fm.muUserFlows.Lock()
+	locks.Lock()
 
 	if err != nil {
 		return fm.handleRenderError_nolock(ctx, err, flow, stepName, userState)
 	}
+	userState.trackPrompts(ctx.sentPrompts)
+	fm.startJob_nolock(ctx, flow, step, userState)
 
 	return nil
 }
@@ -247,72 +615,187 @@ func (fm *flowManager) renderStepPrompt_nolock(ctx *Context, flow *Flow, stepNam
 		return fmt.Errorf("step %s has no prompt configuration", stepName)
 	}
 
-	// Data copy removed - flow data should be accessed via GetFlowData() only
+	locks := fm.contextLocks(ctx)
+	locks.RLock()
+	data := copyFlowData(userState.Data)
+	locks.RUnlock()
 
-	err := fm.promptSender.ComposeAndSend(ctx, step.PromptConfig)
+	ctx.sentPrompts = nil
+	var err error
+	if panicErr := protect(flow.Name, stepName, func() { err = fm.composeFlowPrompt(ctx, flow, stepName, data, step.PromptConfig) }); panicErr != nil {
+		err = panicErr
+	}
 
 	if err != nil {
+		// Error strategies change the flow state, they run under the lock
+		locks.Lock()
+		defer locks.Unlock()
 		return fm.handleRenderError_nolock(ctx, err, flow, stepName, userState)
 	}
 
+	// The first prompt is rendered by startFlow without holding the mutex
+	shard := fm.shardFor(userState.Key)
+	shard.mu.Lock()
+	userState.trackPrompts(ctx.sentPrompts)
+	fm.startJob_nolock(ctx, flow, step, userState)
+	shard.mu.Unlock()
+
 	return nil
 }
+
+// checkPermission asks the context's AccessManager whether the user may start the
+// flow (stepName empty) or enter one of its steps. Without an AccessManager
+// everything is allowed.
+func (fm *flowManager) checkPermission(ctx *Context, flow *Flow, stepName, permission string) error {
+	permCtx := ctx.getPermissionContext()
+	if permCtx == nil {
+		return nil
+	}
+	permCtx.FlowName = flow.Name
+	permCtx.StepName = stepName
+	permCtx.Permission = permission
+	permCtx.Update = &ctx.update
+	return ctx.accessManager.CheckPermission(permCtx)
+}
+
+// denyPermission shows the flow's denial prompt after a failed permission check.
+func (fm *flowManager) denyPermission(ctx *Context, flow *Flow, permission string, err error) {
+	log.Printf("[FLOW_PERMISSION_DENIED] Flow: %s, User: %d, Permission: %q, Error: %v", flow.Name, ctx.UserID(), permission, err)
+
+	prompt := flow.PermissionDeniedPrompt
+	if prompt == nil || prompt == "" {
+		prompt = "🚫 " + err.Error()
+	}
+	sendErr := fm.promptSender.ComposeAndSend(ctx, &PromptConfig{
+		Message:      prompt,
+		TemplateData: map[string]interface{}{"permission": permission, "error": err.Error()},
+	})
+	if sendErr != nil {
+		log.Printf("[FLOW_ERROR_NOTIFY_FAILED] Failed to notify user %d: %v", ctx.UserID(), sendErr)
+	}
+}
+
 func (fm *flowManager) HandleUpdate(ctx *Context) (bool, error) {
 	// First, acquire lock to get flow state info
-	fm.muUserFlows.Lock()
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
 
-	userID := ctx.UserID()
-	userState, exists := fm.userFlows[userID]
+	key, userState, exists := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID())
 	if !exists {
-		fm.muUserFlows.Unlock()
+		locks.Unlock()
 		return false, nil
 	}
 
 	flow := fm.flows[userState.FlowName]
 	if flow == nil {
-		delete(fm.userFlows, userID)
-		fm.muUserFlows.Unlock()
+		fm.deleteState_nolock(key)
+		locks.Unlock()
 		return false, fmt.Errorf("flow %s not found", userState.FlowName)
 	}
 
 	currentStep := flow.Steps[userState.CurrentStep]
 	if currentStep == nil {
-		delete(fm.userFlows, userID)
-		fm.muUserFlows.Unlock()
+		fm.deleteState_nolock(key)
+		locks.Unlock()
 		return false, fmt.Errorf("step %s not found", userState.CurrentStep)
 	}
 
-	userState.LastActive = time.Now()
+	if ctx.reaction != nil && (!currentStep.acceptsReaction(ctx.reaction) || !userState.isPromptMessage(ctx.ChatID(), ctx.reaction.MessageID)) {
+		locks.Unlock()
+		return false, nil
+	}
+
+	ctx.flowScope = flow.Scope
+	if flow.Scope == FlowScopeChat && flow.InputPolicy == InputFromAdmins {
+		// Release the lock while asking Telegram about the member's status
+		locks.Unlock()
+		if !fm.admins.isChatAdmin(ctx) {
+			return false, nil
+		}
+		locks.Lock()
+		if _, stillActive := fm.getState_nolock(key); !stillActive {
+			locks.Unlock()
+			return false, nil
+		}
+	}
+
+	now := fm.clock.Now()
+	if flow.Timeout > 0 && now.Sub(userState.LastActive) > flow.Timeout {
+		// The flow expired while the user was away; the update is handled as if
+		// there was no flow
+		log.Printf("[FLOW_TIMEOUT] Flow: %s, Step: %s, User: %d", flow.Name, userState.CurrentStep, ctx.UserID())
+		fm.cleanupMappings(key)
+		fm.stripKeyboards_withLockRelease(ctx, fm.cancelState_nolock(ctx, CancelReasonTimeout))
+		locks.Unlock()
+		return false, nil
+	}
+	fm.touchState_nolock(userState, now)
+
+	if userState.ValidationPending || userState.JobRunning {
+		// An asynchronous check or job is still running; remind the user instead of processing new input
+		locks.Unlock()
+		return true, fm.promptSender.ComposeAndSend(ctx, &PromptConfig{Message: currentStep.pendingPrompt()})
+	}
 
 	input, buttonClick := fm.extractInputData(ctx)
 
 	// Data copy removed - flow data should be accessed via GetFlowData() only
 
 	if currentStep.ProcessFunc == nil {
-		fm.muUserFlows.Unlock()
+		locks.Unlock()
 		return true, fmt.Errorf("step %s has no process function", userState.CurrentStep)
 	}
 
+	promptMessages := userState.PromptMessages
+	runningState := userState
+
 	// Release the lock before calling ProcessFunc to avoid deadlock
 	// ProcessFunc might call SetFlowData which needs flowDataMutex
-	fm.muUserFlows.Unlock()
+	locks.Unlock()
+
+	// Call validators and ProcessFunc without holding any locks
+	var result ProcessResult
+	validateAsync := false
+	panicErr := protect(flow.Name, currentStep.Name, func() {
+		if err := currentStep.validate(ctx, input, buttonClick); err != nil {
+			result = validationRetry(err)
+		} else if buttonClick == nil && len(currentStep.AsyncValidators) > 0 {
+			validateAsync = true
+		} else {
+			result = currentStep.ProcessFunc(ctx, input, buttonClick)
+		}
+	})
+	if panicErr != nil {
+		if buttonClick != nil {
+			_ = ctx.answerCallbackQuery("")
+		}
+		return true, fm.handleStepPanic(ctx, key, runningState, flow, panicErr)
+	}
+	if validateAsync {
+		fm.deleteUserInput(ctx, flow, currentStep)
+		return true, fm.startAsyncValidation(ctx, key, flow, currentStep, input)
+	}
 
-	// Call ProcessFunc without holding any locks
-	result := currentStep.ProcessFunc(ctx, input, buttonClick)
+	if buttonClick == nil {
+		fm.deleteUserInput(ctx, flow, currentStep)
+	}
 
+	var messageIDToDelete int
+	var messageIDsToDelete []int
 	if buttonClick != nil {
 		if err := ctx.answerCallbackQuery(""); err != nil {
 
 			_ = err
 		}
 
-		var messageIDToDelete int
-		if ctx.update.CallbackQuery != nil && ctx.update.CallbackQuery.Message != nil {
+		// A keyboard refreshed by UpdateKeyboard must survive the click that triggered it
+		if ctx.update.CallbackQuery != nil && ctx.update.CallbackQuery.Message != nil && !ctx.keyboardRefreshed {
 			messageIDToDelete = ctx.update.CallbackQuery.Message.MessageID
+			messageIDsToDelete = promptMessageIDs(promptMessages, ctx.ChatID(), messageIDToDelete)
 		}
 
 		if messageIDToDelete > 0 {
-			if err := fm.handleMessageAction(ctx, flow, messageIDToDelete); err != nil {
+			if err := fm.handleMessageAction(ctx, flow, messageIDsToDelete); err != nil {
 				log.Printf("Error handling message action for UserID %d: %v", ctx.UserID(), err)
 
 			}
@@ -320,31 +803,112 @@ func (fm *flowManager) HandleUpdate(ctx *Context) (bool, error) {
 	}
 
 	// Re-acquire lock for state modifications
-	fm.muUserFlows.Lock()
-	defer fm.muUserFlows.Unlock()
+	locks.Lock()
+	defer locks.Unlock()
 
 	// Re-check that user is still in flow (in case it was cancelled during ProcessFunc)
-	userState, exists = fm.userFlows[userID]
+	userState, exists = fm.getState_nolock(key)
 	if !exists {
 		return true, nil // Flow was cancelled, but we handled the update
 	}
 
 	// Bidirectional sync removed - use SetFlowData() to modify flow data
 
+	if messageIDToDelete > 0 && flow.OnProcessAction&(ProcessDeleteMessage|ProcessDeleteKeyboard) != 0 {
+		userState.forgetPrompt(ctx.ChatID(), messageIDToDelete)
+		if flow.OnProcessAction&ProcessDeleteMessage != 0 {
+			for _, messageID := range messageIDsToDelete {
+				userState.forgetPrompt(ctx.ChatID(), messageID)
+			}
+			if userState.LastPrompt.MessageID == messageIDToDelete {
+				userState.LastPrompt = sentPrompt{}
+				userState.PromptMessages = nil
+			}
+		}
+	}
+
 	return fm.handleProcessResult_nolock(ctx, result, userState, flow)
 }
 
+// startAsyncValidation marks the step as waiting for a slow validation, tells the
+// user that the input is being checked and runs the step's asynchronous validators
+// in the background.
+func (fm *flowManager) startAsyncValidation(ctx *Context, key flowKey, flow *Flow, step *flowStep, input string) error {
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
+	userState, exists := fm.getState_nolock(key)
+	if !exists {
+		locks.Unlock()
+		return nil
+	}
+	userState.ValidationPending = true
+	locks.Unlock()
+
+	if err := fm.promptSender.ComposeAndSend(ctx, &PromptConfig{Message: step.pendingPrompt()}); err != nil {
+		log.Printf("[FLOW_ASYNC_VALIDATION] Failed to send pending prompt to user %d: %v", ctx.UserID(), err)
+	}
+
+	ctx.Retain() // The validation outlives the update
+	go fm.completeAsyncValidation(ctx, key, flow, step, userState, input)
+	return nil
+}
+
+// completeAsyncValidation runs the asynchronous validators and then either retries
+// the step with the validation error or hands the input to the step's ProcessFunc.
+// Results are discarded if the flow moved on or ended while the check was running.
+func (fm *flowManager) completeAsyncValidation(ctx *Context, key flowKey, flow *Flow, step *flowStep, userState *userFlowState, input string) {
+	var validationErr error
+	panicErr := protect(flow.Name, step.Name, func() { validationErr = Chain(step.AsyncValidators...)(ctx, input) })
+
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
+	if current, exists := fm.getState_nolock(key); !exists || current != userState || !userState.ValidationPending || userState.CurrentStep != step.Name {
+		locks.Unlock()
+		return
+	}
+	userState.ValidationPending = false
+	locks.Unlock()
+
+	var result ProcessResult
+	if panicErr == nil {
+		panicErr = protect(flow.Name, step.Name, func() {
+			if validationErr != nil {
+				result = validationRetry(validationErr)
+			} else {
+				result = step.ProcessFunc(ctx, input, nil)
+			}
+		})
+	}
+	if panicErr != nil {
+		_ = fm.handleStepPanic(ctx, key, userState, flow, panicErr)
+		return
+	}
+
+	locks.Lock()
+	defer locks.Unlock()
+
+	if current, exists := fm.getState_nolock(key); !exists || current != userState {
+		return
+	}
+	if _, err := fm.handleProcessResult_nolock(ctx, result, userState, flow); err != nil {
+		log.Printf("[FLOW_ASYNC_VALIDATION] Flow: %s, Step: %s, User: %d, Error: %v", flow.Name, step.Name, ctx.UserID(), err)
+	}
+}
+
 func (fm *flowManager) extractInputData(ctx *Context) (string, *ButtonClick) {
 	var input string
 	var buttonClick *ButtonClick
 
-	if ctx.update.Message != nil {
+	if ctx.reaction != nil {
+		input = ctx.reaction.Emoji
+	} else if ctx.update.Message != nil {
 		input = ctx.update.Message.Text
 	} else if ctx.update.CallbackQuery != nil {
 		input = ctx.update.CallbackQuery.Data
 		var originalData interface{} = input
 
-		if mappedData, found := fm.keyboardAccess.GetCallbackData(ctx.UserID(), input); found {
+		owner := ctx.callbackOwner()
+		if mappedData, found := fm.keyboardAccess.GetCallbackData(owner.UserID, owner.ChatID, input); found {
 			originalData = mappedData
 		}
 
@@ -361,9 +925,30 @@ func (fm *flowManager) extractInputData(ctx *Context) (string, *ButtonClick) {
 }
 
 func (fm *flowManager) handleProcessResult_nolock(ctx *Context, result ProcessResult, userState *userFlowState, flow *Flow) (bool, error) {
+	userState.WaitingForEvent = result.Action == actionWaitForEvent
+
+	if result.Action == actionRetryStep && result.Prompt == nil && ctx.keyboardRefreshed {
+		// The step refreshed its keyboard in place; keep the prompt as it is
+		return true, nil
+	}
+
+	if result.Action == actionRetryStep {
+		userState.RetryCount++
+		maxAttempts := result.MaxAttempts
+		if step := flow.Steps[userState.CurrentStep]; maxAttempts == 0 && step != nil {
+			maxAttempts = step.MaxAttempts
+		}
+		if maxAttempts > 0 && userState.RetryCount >= maxAttempts {
+			return fm.handleMaxRetries_nolock(ctx, userState, flow)
+		}
+	}
 
 	if result.Prompt != nil {
-		if err := fm.renderInformationalPrompt(ctx, result.Prompt); err != nil {
+		var err error
+		if panicErr := protect(flow.Name, userState.CurrentStep, func() { err = fm.renderInformationalPrompt(ctx, flow, userState, result.Prompt) }); panicErr != nil {
+			err = panicErr
+		}
+		if err != nil {
 
 			return true, fm.handleRenderError_nolock(ctx, err, flow, userState.CurrentStep, userState)
 		}
@@ -390,21 +975,60 @@ func (fm *flowManager) handleProcessResult_nolock(ctx *Context, result ProcessRe
 		return fm.completeFlow_nolock(ctx, flow)
 
 	case actionCancelFlow:
-		return fm.cancelFlowAction_nolock(ctx)
+		return fm.cancelFlowAction_nolock(ctx, CancelReasonCancelFlow)
+
+	case actionWaitForEvent:
+		return true, nil
 
 	default:
 		return true, fmt.Errorf("unknown ProcessAction: %d", result.Action)
 	}
 }
 
-func (fm *flowManager) renderInformationalPrompt(ctx *Context, config *PromptConfig) error {
+// handleMaxRetries_nolock is invoked when a step returned Retry() more often than
+// its WithMaxAttempts limit allows. The flow's OnMaxRetries handler decides what
+// happens next; without a handler the flow is cancelled with a notice.
+func (fm *flowManager) handleMaxRetries_nolock(ctx *Context, userState *userFlowState, flow *Flow) (bool, error) {
+	stepName := userState.CurrentStep
+	userState.RetryCount = 0
+
+	log.Printf("[FLOW_MAX_RETRIES] Flow: %s, Step: %s, User: %d", flow.Name, stepName, ctx.UserID())
+
+	if flow.OnMaxRetries == nil {
+		fm.notifyUserIfNeeded(ctx, defaultMaxRetriesMessage)
+		return fm.cancelFlowAction_nolock(ctx, CancelReasonMaxRetries)
+	}
+
+	// Release the lock while the handler runs, it may access flow data
+	locks := fm.contextLocks(ctx)
+	locks.Unlock()
+	var result ProcessResult
+	panicErr := protect(flow.Name, stepName, func() { result = flow.OnMaxRetries(ctx, stepName) })
+	locks.Lock()
+
+	if _, state, exists := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID()); !exists || state != userState {
+		return true, panicErr
+	}
+	if panicErr != nil {
+		log.Printf("[FLOW_MAX_RETRIES_PANIC] Flow: %s, Step: %s, User: %d, Error: %v", flow.Name, stepName, ctx.UserID(), panicErr)
+		_, _ = fm.cancelFlowAction_nolock(ctx, CancelReasonMaxRetries)
+		return true, panicErr
+	}
+
+	// A handler asking for yet another retry starts a fresh attempt window
+	result.MaxAttempts = 0
+	return fm.handleProcessResult_nolock(ctx, result, userState, flow)
+}
+
+func (fm *flowManager) renderInformationalPrompt(ctx *Context, flow *Flow, userState *userFlowState, config *PromptConfig) error {
 
 	infoPrompt := &PromptConfig{
-		Message: config.Message,
-		Image:   config.Image,
+		Message:      config.Message,
+		Image:        config.Image,
+		TemplateData: config.TemplateData,
 	}
 
-	return fm.promptSender.ComposeAndSend(ctx, infoPrompt)
+	return fm.composeFlowPrompt(ctx, flow, userState.CurrentStep, copyFlowData(userState.Data), infoPrompt)
 }
 
 func (fm *flowManager) advanceToNextStep(ctx *Context, userState *userFlowState, flow *Flow) (bool, error) {
@@ -422,12 +1046,13 @@ func (fm *flowManager) advanceToNextStep(ctx *Context, userState *userFlowState,
 	}
 
 	if currentIndex+1 >= len(flow.Order) {
-
-		return fm.completeFlow(ctx, flow)
+		// The caller holds the lock; completing must not take it again
+		return fm.completeFlow_nolock(ctx, flow)
 	}
 
 	nextStepName := flow.Order[currentIndex+1]
 	userState.CurrentStep = nextStepName
+	userState.RetryCount = 0
 
 	return true, fm.renderStepPrompt_withLockRelease(ctx, flow, nextStepName, userState)
 }
@@ -438,13 +1063,9 @@ func (fm *flowManager) goToSpecificStep(ctx *Context, userState *userFlowState,
 	}
 
 	userState.CurrentStep = targetStep
+	userState.RetryCount = 0
 	return true, fm.renderStepPrompt_withLockRelease(ctx, flow, targetStep, userState)
 }
-func (fm *flowManager) completeFlow(ctx *Context, flow *Flow) (bool, error) {
-	fm.muUserFlows.Lock()
-	defer fm.muUserFlows.Unlock()
-	return fm.completeFlow_nolock(ctx, flow)
-}
 
 func (fm *flowManager) completeFlow_nolock(ctx *Context, flow *Flow) (bool, error) {
 	userID := ctx.UserID()
@@ -453,19 +1074,22 @@ func (fm *flowManager) completeFlow_nolock(ctx *Context, flow *Flow) (bool, erro
 	if flow.OnComplete != nil {
 		// Release the lock before calling OnComplete to avoid deadlock
 		// OnComplete handler may call GetFlowData/SetFlowData which need the same mutex
-		fm.muUserFlows.Unlock()
+		locks := fm.contextLocks(ctx)
+		locks.Unlock()
 
-		onCompleteErr = flow.OnComplete(ctx)
+		if panicErr := protect(flow.Name, "", func() { onCompleteErr = flow.OnComplete(ctx) }); panicErr != nil {
+			onCompleteErr = panicErr
+		}
 
 		// Re-acquire the lock after OnComplete completes
-		fm.muUserFlows.Lock()
+		locks.Lock()
 	} else {
 		log.Printf("[FLOW_COMPLETE] Flow %s called for user %d without completion handler", flow.Name, userID)
 	}
 
 	// Always cleanup user flow and keyboard mappings regardless of OnComplete result
-	fm.keyboardAccess.CleanupUserMappings(userID)
-	delete(fm.userFlows, userID)
+	fm.cleanupMappings_nolock(ctx)
+	fm.stripKeyboards_withLockRelease(ctx, fm.removeState_nolock(ctx))
 
 	// Return the OnComplete error if there was one
 	if onCompleteErr != nil {
@@ -480,16 +1104,8 @@ func (fm *flowManager) handleRenderError_nolock(ctx *Context, renderErr error, f
 
 	fm.logRenderError(renderErr, stepName, flow.Name, ctx.UserID())
 
-	action := errorStrategyCancel
-	config := &ErrorConfig{
-		Action:  errorStrategyCancel,
-		Message: defaultErrorMessageCancel,
-	}
-
-	if flow.OnError != nil {
-		action = flow.OnError.Action
-		config = flow.OnError
-	}
+	config := flow.errorConfig(stepName)
+	action := config.Action
 
 	log.Printf("[FLOW_ERROR_ACTION] Flow: %s, Step: %s, User: %d, Action: %s",
 		flow.Name, stepName, ctx.UserID(), fm.getActionName(action))
@@ -511,6 +1127,10 @@ func (fm *flowManager) handleRenderError_nolock(ctx *Context, renderErr error, f
 		}
 		return fm.handleErrorStrategyIgnore(ctx, config, originalPrompt, userState, flow)
 
+	case errorStrategyFunc:
+		_, err := fm.handleErrorStrategyFunc_nolock(ctx, config, renderErr, userState, flow)
+		return err
+
 	default:
 
 		fm.handleErrorStrategyCancel_nolock(ctx, &ErrorConfig{
@@ -524,7 +1144,7 @@ func (fm *flowManager) handleRenderError_nolock(ctx *Context, renderErr error, f
 func (fm *flowManager) handleErrorStrategyCancel_nolock(ctx *Context, config *ErrorConfig) {
 
 	fm.notifyUserIfNeeded(ctx, config.Message)
-	delete(fm.userFlows, ctx.UserID())
+	fm.stripKeyboards_withLockRelease(ctx, fm.cancelState_nolock(ctx, CancelReasonError))
 }
 
 func (fm *flowManager) handleErrorStrategyRetry(ctx *Context, config *ErrorConfig) {
@@ -542,15 +1162,48 @@ func (fm *flowManager) handleErrorStrategyIgnore(ctx *Context, config *ErrorConf
 			Keyboard: originalPrompt.Keyboard,
 		}
 
-		if err := fm.promptSender.ComposeAndSend(ctx, fallbackPrompt); err != nil {
+		ctx.sentPrompts = nil
+		if err := fm.composeFlowPrompt(ctx, flow, userState.CurrentStep, copyFlowData(userState.Data), fallbackPrompt); err != nil {
 
 			_, err := fm.advanceToNextStep(ctx, userState, flow)
 			return err
 		}
+		userState.trackPrompts(ctx.sentPrompts)
 	}
 	return nil
 }
 
+// handleErrorStrategyFunc_nolock runs an OnErrorFunc handler with the lock
+// released and applies the result it returns. An error raised while that result
+// is applied, or a handler that panics, cancels the flow so a persistent failure
+// cannot loop through the handler.
+func (fm *flowManager) handleErrorStrategyFunc_nolock(ctx *Context, config *ErrorConfig, flowErr error, userState *userFlowState, flow *Flow) (bool, error) {
+	if config.Handler == nil || userState.HandlingError {
+		fm.handleErrorStrategyCancel_nolock(ctx, OnErrorCancel())
+		return true, nil
+	}
+
+	locks := fm.contextLocks(ctx)
+	locks.Unlock()
+	var result ProcessResult
+	panicErr := protect(flow.Name, userState.CurrentStep, func() { result = config.Handler(ctx, flowErr) })
+	locks.Lock()
+
+	if _, state, exists := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID()); !exists || state != userState {
+		return true, panicErr
+	}
+	if panicErr != nil {
+		log.Printf("[FLOW_ERROR_HANDLER_PANIC] Flow: %s, Step: %s, User: %d, Error: %v", flow.Name, userState.CurrentStep, ctx.UserID(), panicErr)
+		fm.handleErrorStrategyCancel_nolock(ctx, OnErrorCancel())
+		return true, panicErr
+	}
+
+	userState.HandlingError = true
+	defer func() { userState.HandlingError = false }()
+	result.MaxAttempts = 0
+	return fm.handleProcessResult_nolock(ctx, result, userState, flow)
+}
+
 func (fm *flowManager) logRenderError(err error, stepName, flowName string, userID int64) {
 	log.Printf("[FLOW_RENDER_ERROR] Flow: %s, Step: %s, User: %d, Error: %v",
 		flowName, stepName, userID, err)
@@ -576,46 +1229,252 @@ func (fm *flowManager) getActionName(action errorStrategy) string {
 		return "RETRY"
 	case errorStrategyIgnore:
 		return "IGNORE"
+	case errorStrategyFunc:
+		return "FUNC"
 	default:
 		return "UNKNOWN"
 	}
 }
 
-func (fm *flowManager) cancelFlowAction_nolock(ctx *Context) (bool, error) {
+func (fm *flowManager) cancelFlowAction_nolock(ctx *Context, reason CancelReason) (bool, error) {
 
-	fm.keyboardAccess.CleanupUserMappings(ctx.UserID())
+	fm.cleanupMappings_nolock(ctx)
 
-	delete(fm.userFlows, ctx.UserID())
+	fm.stripKeyboards_withLockRelease(ctx, fm.cancelState_nolock(ctx, reason))
 	return true, nil
 }
 
-func (fm *flowManager) handleMessageAction(ctx *Context, flow *Flow, messageID int) error {
-	switch flow.OnProcessAction {
-	case ProcessDeleteMessage:
-		return fm.deletePreviousMessage(ctx, messageID)
-	case ProcessDeleteKeyboard:
-		return fm.deletePreviousKeyboard(ctx, messageID)
-	case ProcessKeepMessage:
-
+// cancelState_nolock runs the OnCancel handler of the flow that applies to the
+// context and removes its state. It returns the cancelled state, or nil if there
+// was none.
+func (fm *flowManager) cancelState_nolock(ctx *Context, reason CancelReason) *userFlowState {
+	_, state, ok := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID())
+	if !ok {
 		return nil
+	}
+	fm.runOnCancel_withLockRelease(ctx, fm.contextLocks(ctx), state, reason)
+	fm.deleteIfCurrent_nolock(state)
+	fm.startLimits.aborted(fm.flows[state.FlowName], ctx.UserID(), fm.clock.Now())
+	return state
+}
+
+// runOnCancel_withLockRelease calls the OnCancel handler of the state's flow with
+// the lock released, so the handler can still read the flow data. A cancellation
+// requested by the handler itself does not run it again.
+func (fm *flowManager) runOnCancel_withLockRelease(ctx *Context, locks stateLocks, state *userFlowState, reason CancelReason) {
+	flow := fm.flows[state.FlowName]
+	if flow == nil || flow.OnCancel == nil || state.Cancelling {
+		return
+	}
+	state.Cancelling = true
+	log.Printf("[FLOW_CANCEL] Flow: %s, Step: %s, User: %d, Reason: %s", flow.Name, state.CurrentStep, ctx.UserID(), reason)
+
+	locks.Unlock()
+	ctx.cancelReason = reason
+	var err error
+	if panicErr := protect(flow.Name, state.CurrentStep, func() { err = flow.OnCancel(ctx) }); panicErr != nil {
+		err = panicErr
+	}
+	ctx.cancelReason = ""
+	locks.Lock()
+
+	if err != nil {
+		log.Printf("[FLOW_CANCEL] Flow: %s, User: %d, OnCancel error: %v", flow.Name, ctx.UserID(), err)
+	}
+}
+
+// cleanupMappings removes the inline keyboard callback mappings of the flow stored
+// under key.
+func (fm *flowManager) cleanupMappings(key flowKey) {
+	fm.keyboardAccess.CleanupUserMappings(key.UserID, key.ChatID)
+}
+
+// cleanupMappings_nolock removes the callback mappings of the flow that applies to
+// the context's user and chat.
+func (fm *flowManager) cleanupMappings_nolock(ctx *Context) {
+	if key, _, ok := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID()); ok {
+		fm.cleanupMappings(key)
+	}
+}
+
+// deleteIfCurrent_nolock removes a flow state unless it has already been removed
+// or replaced, e.g. by a flow started from an OnCancel handler.
+func (fm *flowManager) deleteIfCurrent_nolock(state *userFlowState) {
+	if current, ok := fm.getState_nolock(state.Key); ok && current == state {
+		fm.deleteState_nolock(state.Key)
+	}
+}
+
+// stripKeyboards removes the inline keyboards from the step prompts of a finished
+// flow, so that stale buttons can no longer be clicked. It only acts when
+// FlowConfig.StripKeyboardsOnEnd is set. The state must already be removed from
+// the manager.
+func (fm *flowManager) stripKeyboards(ctx *Context, state *userFlowState) {
+	if state == nil || !fm.stripsKeyboards() {
+		return
+	}
+	for _, sent := range state.KeyboardPrompts {
+		msgCtx := ctx
+		if sent.ChatID != ctx.ChatID() {
+			msgCtx = ctx.forChat(sent.ChatID)
+		}
+		if err := fm.messageCleaner.EditMessageReplyMarkup(msgCtx, sent.MessageID, nil); err != nil {
+			log.Printf("[FLOW_KEYBOARD_CLEANUP] Failed to remove keyboard from message %d for user %d: %v", sent.MessageID, ctx.UserID(), err)
+		}
+	}
+	state.KeyboardPrompts = nil
+}
+
+func (fm *flowManager) stripsKeyboards() bool {
+	return fm.flowConfig != nil && fm.flowConfig.StripKeyboardsOnEnd
+}
+
+// stripKeyboards_withLockRelease runs stripKeyboards without holding the mutex,
+// since the edits go through send hooks that may access flow data.
+func (fm *flowManager) stripKeyboards_withLockRelease(ctx *Context, state *userFlowState) {
+	if state == nil || len(state.KeyboardPrompts) == 0 || !fm.stripsKeyboards() {
+		return
+	}
+	locks := fm.contextLocks(ctx)
+	locks.Unlock()
+	panicErr := protect(state.FlowName, state.CurrentStep, func() { fm.stripKeyboards(ctx, state) })
+	locks.Lock()
+	if panicErr != nil {
+		log.Printf("[FLOW_KEYBOARD_CLEANUP] Panic while removing keyboards for user %d: %v", ctx.UserID(), panicErr)
+	}
+}
+
+// updateKeyboard builds a new inline keyboard and puts it on the flow's last prompt.
+func (fm *flowManager) updateKeyboard(userID, chatID int64, keyboard KeyboardFunc, ctx *Context) error {
+	locks := fm.stateLocks(userID, chatID)
+	locks.RLock()
+	_, state, ok := fm.lookupState_nolock(userID, chatID)
+	var target sentPrompt
+	if ok {
+		target = state.LastPrompt
+	}
+	locks.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("user not in a flow, cannot update keyboard")
+	}
+	if target.MessageID == 0 {
+		return fmt.Errorf("flow %s has no prompt message to update", state.FlowName)
+	}
+
+	built, err := fm.keyboardAccess.BuildKeyboard(ctx, keyboard)
+	if err != nil {
+		return fmt.Errorf("keyboard building failed: %w", err)
+	}
+	var markup interface{}
+	if inline, isInline := built.(tgbotapi.InlineKeyboardMarkup); isInline && numButtons(inline) > 0 {
+		markup = inline
+	}
+
+	msgCtx := ctx
+	if target.ChatID != ctx.ChatID() {
+		msgCtx = ctx.forChat(target.ChatID)
+	}
+	if err := fm.messageCleaner.EditMessageReplyMarkup(msgCtx, target.MessageID, markup); err != nil && !errors.Is(err, ErrMessageNotModified) {
+		return err
+	}
+	ctx.keyboardRefreshed = true
+
+	locks.Lock()
+	defer locks.Unlock()
+	if state.LastPrompt.MessageID == target.MessageID {
+		state.LastPrompt.InlineKeyboard = markup != nil
+	}
+	state.forgetPrompt(target.ChatID, target.MessageID)
+	if markup != nil {
+		target.InlineKeyboard = true
+		state.KeyboardPrompts = append(state.KeyboardPrompts, target)
+	}
+	return nil
+}
+
+// handleMessageAction applies the flow's message action to a clicked prompt.
+// messageIDs holds the clicked message last, preceded by the other messages of
+// its prompt sequence, which are deleted along with it.
+func (fm *flowManager) handleMessageAction(ctx *Context, flow *Flow, messageIDs []int) error {
+	switch {
+	case flow.OnProcessAction&ProcessDeleteMessage != 0:
+		if len(messageIDs) > 1 {
+			return fm.deletePreviousMessages(ctx, messageIDs)
+		}
+		return fm.deletePreviousMessage(ctx, messageIDs[0])
+	case flow.OnProcessAction&ProcessDeleteKeyboard != 0:
+		return fm.deletePreviousKeyboard(ctx, messageIDs[len(messageIDs)-1])
 	default:
 
 		return nil
 	}
 }
 
+// promptMessageIDs returns the IDs of all messages of the prompt that contains the
+// clicked message, ending with the clicked message. A message that does not belong
+// to the most recent prompt stands alone.
+func promptMessageIDs(promptMessages []sentPrompt, chatID int64, clickedID int) []int {
+	var ids []int
+	found := false
+	for _, sent := range promptMessages {
+		if sent.ChatID != chatID {
+			continue
+		}
+		if sent.MessageID == clickedID {
+			found = true
+			continue
+		}
+		ids = append(ids, sent.MessageID)
+	}
+	if !found {
+		return []int{clickedID}
+	}
+	return append(ids, clickedID)
+}
+
+// deleteUserInput removes the user's text message once a step has processed it,
+// if the flow or the step asks for it.
+func (fm *flowManager) deleteUserInput(ctx *Context, flow *Flow, step *flowStep) {
+	if flow.OnProcessAction&ProcessDeleteUserMessage == 0 && !step.DeleteUserInput {
+		return
+	}
+	if ctx.update.Message == nil {
+		return
+	}
+	if err := fm.deletePreviousMessage(ctx, ctx.update.Message.MessageID); err != nil {
+		log.Printf("Error deleting input message for UserID %d: %v", ctx.UserID(), err)
+	}
+}
+
 func (fm *flowManager) deletePreviousMessage(ctx *Context, messageID int) error {
 	return fm.messageCleaner.DeleteMessage(ctx, messageID)
 }
 
+// deletePreviousMessages deletes several messages, in one call if the message
+// cleaner is a BatchMessageCleaner.
+func (fm *flowManager) deletePreviousMessages(ctx *Context, messageIDs []int) error {
+	if batch, ok := fm.messageCleaner.(BatchMessageCleaner); ok {
+		return batch.DeleteMessages(ctx, messageIDs)
+	}
+	var errs []error
+	for _, messageID := range messageIDs {
+		if err := fm.messageCleaner.DeleteMessage(ctx, messageID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (fm *flowManager) deletePreviousKeyboard(ctx *Context, messageID int) error {
 	return fm.messageCleaner.EditMessageReplyMarkup(ctx, messageID, nil)
 }
-func (fm *flowManager) setUserFlowData(userID int64, key string, value interface{}) error {
-	fm.muUserFlows.Lock()
-	defer fm.muUserFlows.Unlock()
+func (fm *flowManager) setUserFlowData(userID, chatID int64, key string, value interface{}) error {
+	locks := fm.stateLocks(userID, chatID)
+	locks.Lock()
+	defer locks.Unlock()
 
-	userState, exists := fm.userFlows[userID]
+	_, userState, exists := fm.lookupState_nolock(userID, chatID)
 	if !exists {
 		return fmt.Errorf("user %d not in a flow", userID)
 	}
@@ -628,11 +1487,12 @@ func (fm *flowManager) setUserFlowData(userID int64, key string, value interface
 	return nil
 }
 
-func (fm *flowManager) getUserFlowData(userID int64, key string) (interface{}, bool) {
-	fm.muUserFlows.RLock()
-	defer fm.muUserFlows.RUnlock()
+func (fm *flowManager) getUserFlowData(userID, chatID int64, key string) (interface{}, bool) {
+	locks := fm.stateLocks(userID, chatID)
+	locks.RLock()
+	defer locks.RUnlock()
 
-	userState, exists := fm.userFlows[userID]
+	_, userState, exists := fm.lookupState_nolock(userID, chatID)
 	if !exists {
 		return nil, false
 	}
@@ -73,6 +73,44 @@ func (fb *FlowBuilder) OnComplete(handler func(*Context) error) *FlowBuilder {
 	return fb
 }
 
+// OnStart sets a callback function that is executed when the flow starts, before
+// the first prompt is sent. It can preload flow data with ctx.SetFlowData or log
+// analytics. Returning an error aborts the flow: no prompt is sent and StartFlow
+// returns the error, so the handler should tell the user why.
+//
+// Example:
+//
+//	flow.OnStart(func(ctx *teleflow.Context) error {
+//		account, err := accounts.Get(ctx.UserID())
+//		if err != nil {
+//			ctx.SendPromptText("Please link your account first with /link.")
+//			return err
+//		}
+//		return ctx.SetFlowData("balance", account.Balance)
+//	})
+func (fb *FlowBuilder) OnStart(handler func(*Context) error) *FlowBuilder {
+	fb.onStart = handler
+	return fb
+}
+
+// OnCancel sets a callback function that is executed when the flow is cancelled
+// before completing: by an exit command, a CancelFlow() result, a timeout, an
+// error strategy or ctx.CancelFlow(). ctx.CancelReason() tells them apart.
+// Flow data is still available through ctx.GetFlowData.
+//
+// Example:
+//
+//	flow.OnCancel(func(ctx *teleflow.Context) error {
+//		if ctx.CancelReason() == teleflow.CancelReasonTimeout {
+//			return ctx.SendPromptText("Your order expired, send /order to start again.")
+//		}
+//		return ctx.SendPromptText("Order cancelled.")
+//	})
+func (fb *FlowBuilder) OnCancel(handler func(*Context) error) *FlowBuilder {
+	fb.onCancel = handler
+	return fb
+}
+
 // OnError configures how the flow handles errors during step processing.
 // This applies to all steps in the flow unless overridden at the step level.
 // The error configuration determines whether to cancel, retry, or ignore errors.
@@ -97,13 +135,137 @@ func (fb *FlowBuilder) WithTimeout(duration time.Duration) *FlowBuilder {
 	return fb
 }
 
+// OnMaxRetries sets the handler invoked when a step exceeds the retry limit set
+// with ProcessResult.WithMaxAttempts. The handler returns the result to apply,
+// which makes it easy to bail out gracefully or escalate to a human.
+// Without a handler, the flow is cancelled with a short notice.
+//
+// Example:
+//
+//	flow.OnMaxRetries(func(ctx *teleflow.Context, stepName string) teleflow.ProcessResult {
+//		return teleflow.CancelFlow().WithPrompt("Let's try again later. A support agent will contact you.")
+//	})
+func (fb *FlowBuilder) OnMaxRetries(handler MaxRetriesHandler) *FlowBuilder {
+	fb.onMaxRetries = handler
+	return fb
+}
+
+// Scope sets how the flow's state is keyed. By default a flow belongs to the user
+// who started it. FlowScopeChat lets a whole group go through a flow together
+// (e.g. setting up a poll), while FlowScopeUserChat allows a user to run
+// independent copies of the flow in different chats.
+//
+// Example:
+//
+//	flow.Scope(teleflow.FlowScopeChat).AcceptInputFrom(teleflow.InputFromAdmins)
+func (fb *FlowBuilder) Scope(scope FlowScope) *FlowBuilder {
+	fb.scope = scope
+	return fb
+}
+
+// AcceptInputFrom restricts which chat members may answer a chat-scoped flow.
+// Messages from members who are not allowed to answer are not consumed by the flow
+// and continue to the regular handlers. The policy has no effect for other scopes.
+// InputFromAdmins remembers each member's administrator status for a minute, so
+// promotions and demotions take up to a minute to apply.
+//
+// Example:
+//
+//	flow.Scope(teleflow.FlowScopeChat).AcceptInputFrom(teleflow.InputFromAdmins)
+func (fb *FlowBuilder) AcceptInputFrom(policy ChatInputPolicy) *FlowBuilder {
+	fb.inputPolicy = policy
+	return fb
+}
+
+// EditInPlace makes every step after the first edit the previous step's prompt
+// message instead of sending a new one, so the whole flow lives in a single message.
+// When the message cannot be edited (it was deleted, is too old, or a text prompt
+// follows a photo prompt) a new message is sent and later steps edit that one.
+//
+// Example:
+//
+//	teleflow.NewFlow("settings").EditInPlace()
+func (fb *FlowBuilder) EditInPlace() *FlowBuilder {
+	fb.editInPlace = true
+	return fb
+}
+
+// ExitCommands replaces the global FlowConfig.ExitCommands for this flow, so a
+// sensitive flow can use a stricter exit than casual ones. Calling it without
+// commands leaves the flow without exit commands.
+//
+// Example:
+//
+//	teleflow.NewFlow("transfer").ExitCommands("/abort").ExitMessage("Transfer aborted.")
+func (fb *FlowBuilder) ExitCommands(commands ...string) *FlowBuilder {
+	fb.exitCommands = append([]string{}, commands...)
+	return fb
+}
+
+// ExitMessage replaces the global FlowConfig.ExitMessage shown when the user
+// leaves this flow with an exit command.
+func (fb *FlowBuilder) ExitMessage(message string) *FlowBuilder {
+	fb.exitMessage = message
+	return fb
+}
+
+// AllowCommands replaces FlowConfig.GlobalCommandWhitelist for this flow: only the
+// listed commands run their regular handlers while the user is in the flow. Calling
+// it without commands blocks all global commands in the flow.
+//
+// Example:
+//
+//	teleflow.NewFlow("transfer").AllowCommands("/balance")
+func (fb *FlowBuilder) AllowCommands(commands ...string) *FlowBuilder {
+	fb.globalCommands = append([]string{}, commands...)
+	return fb
+}
+
+// PauseOnInterrupt applies InterruptPauseResume to this flow whatever the
+// FlowConfig says: while a global command allowed during the flow runs, the flow
+// is paused, and afterwards its current step's prompt is sent again.
+//
+// Example:
+//
+//	teleflow.NewFlow("transfer").AllowCommands("/balance").PauseOnInterrupt()
+func (fb *FlowBuilder) PauseOnInterrupt() *FlowBuilder {
+	fb.pauseInterrupts = true
+	return fb
+}
+
+// RequirePermission restricts the flow to users the AccessManager grants permission.
+// The check runs before the first prompt is sent; users without the permission
+// receive the denial prompt (see OnPermissionDenied) and the flow does not start.
+//
+// Example:
+//
+//	teleflow.NewFlow("transfer").RequirePermission("transfer:execute")
+func (fb *FlowBuilder) RequirePermission(permission string) *FlowBuilder {
+	fb.permission = permission
+	return fb
+}
+
+// OnPermissionDenied sets the prompt shown when a flow or step permission check fails.
+// The prompt may be a string, template reference or function; templates receive
+// "permission" and "error" as data. By default the AccessManager's error is shown.
+//
+// Example:
+//
+//	flow.OnPermissionDenied("template:permission_denied")
+func (fb *FlowBuilder) OnPermissionDenied(prompt MessageSpec) *FlowBuilder {
+	fb.deniedPrompt = prompt
+	return fb
+}
+
 // OnButtonClick configures the default action to take when inline keyboard buttons are clicked.
 // This can be overridden at the step level if needed. Options include keeping the message,
-// deleting the entire message, or just removing the keyboard buttons.
+// deleting the entire message, or just removing the keyboard buttons. Adding DeleteUserMessage
+// also deletes every text message the user sends in answer to a step.
 //
 // Example:
 //
 //	flow.OnButtonClick(teleflow.DeleteMessage) // Delete messages after button clicks
+//	flow.OnButtonClick(teleflow.DeleteButtons | teleflow.DeleteUserMessage)
 func (fb *FlowBuilder) OnButtonClick(action ButtonClickAction) *FlowBuilder {
 	fb.onProcessAction = ProcessMessageAction(action)
 	return fb
@@ -134,6 +296,21 @@ func (fb *FlowBuilder) Build() (*Flow, error) {
 		OnError:         fb.onError,
 		OnProcessAction: fb.onProcessAction,
 		Timeout:         fb.timeout,
+		Scope:           fb.scope,
+		InputPolicy:     fb.inputPolicy,
+		OnMaxRetries:    fb.onMaxRetries,
+		EditInPlace:     fb.editInPlace,
+		ExitCommands:    fb.exitCommands,
+		ExitMessage:     fb.exitMessage,
+		GlobalCommands:  fb.globalCommands,
+
+		PauseOnInterrupt: fb.pauseInterrupts,
+
+		RequiredPermission:     fb.permission,
+		PermissionDeniedPrompt: fb.deniedPrompt,
+
+		StartLimit:   fb.startLimit,
+		RedactedKeys: fb.redactedKeys,
 	}
 
 	for _, stepName := range fb.order {
@@ -143,7 +320,7 @@ func (fb *FlowBuilder) Build() (*Flow, error) {
 			return nil, fmt.Errorf("step '%s' must have a prompt configuration", stepName)
 		}
 
-		if stepBuilder.processFunc == nil {
+		if stepBuilder.processFunc == nil && stepBuilder.job == nil {
 			return nil, fmt.Errorf("step '%s' must have a process function", stepName)
 		}
 
@@ -151,6 +328,18 @@ func (fb *FlowBuilder) Build() (*Flow, error) {
 			Name:         stepBuilder.name,
 			PromptConfig: stepBuilder.promptConfig,
 			ProcessFunc:  stepBuilder.processFunc,
+			Validators:   stepBuilder.validators,
+
+			RequiredPermission: stepBuilder.permission,
+			AsyncValidators:    stepBuilder.asyncValidators,
+			PendingPrompt:      stepBuilder.pendingPrompt,
+			DeleteUserInput:    stepBuilder.deleteInput,
+			OnError:            stepBuilder.onError,
+			Help:               stepBuilder.help,
+			AcceptReactions:    stepBuilder.acceptReactions,
+			Reactions:          stepBuilder.reactions,
+			Job:                stepBuilder.job,
+			MaxAttempts:        stepBuilder.maxAttempts,
 		}
 
 		flow.Steps[stepName] = flowStep
@@ -159,6 +348,8 @@ func (fb *FlowBuilder) Build() (*Flow, error) {
 	if fb.onComplete != nil {
 		flow.OnComplete = fb.onComplete
 	}
+	flow.OnCancel = fb.onCancel
+	flow.OnStart = fb.onStart
 
 	return flow, nil
 }
@@ -220,6 +411,48 @@ func (pb *PromptBuilder) WithImage(image ImageSpec) *PromptBuilder {
 	return pb
 }
 
+// WithSequence adds messages that are sent, in order, before the prompt itself.
+// Use it when a step needs several messages, for example a photo followed by a
+// description and then a question with buttons. A nil prompt message sends only
+// the sequence.
+//
+// Example:
+//
+//	step.Prompt("Add it to your cart?").
+//		WithSequence(
+//			&teleflow.PromptConfig{Image: productPhoto},
+//			&teleflow.PromptConfig{Message: "template:product_details"},
+//		).
+//		WithPromptKeyboard(yesNoKeyboard)
+func (pb *PromptBuilder) WithSequence(messages ...*PromptConfig) *PromptBuilder {
+	pb.promptConfig.Sequence = append(pb.promptConfig.Sequence, messages...)
+	return pb
+}
+
+// RemoveReplyKeyboard hides the reply keyboard, such as the AccessManager's main menu,
+// while the step waits for an answer. It reappears with the next prompt.
+//
+// Example:
+//
+//	step.Prompt("Type your new nickname:").RemoveReplyKeyboard()
+func (pb *PromptBuilder) RemoveReplyKeyboard() *PromptBuilder {
+	pb.promptConfig.RemoveReplyKeyboard = true
+	return pb
+}
+
+// WithOneTimeReplyKeyboard shows a reply keyboard for this step only. Telegram hides
+// it once the user presses a button; the regular reply keyboard returns with the
+// next prompt.
+//
+// Example:
+//
+//	step.Prompt("Share your phone number?").
+//		WithOneTimeReplyKeyboard(teleflow.BuildReplyKeyboard([]string{"Skip"}, 1).Resize())
+func (pb *PromptBuilder) WithOneTimeReplyKeyboard(keyboard *ReplyKeyboard) *PromptBuilder {
+	pb.promptConfig.OneTimeReplyKeyboard = keyboard
+	return pb
+}
+
 // WithPromptKeyboard adds an inline keyboard to the prompt.
 // The keyboard function receives the context and returns a keyboard builder.
 //
@@ -257,6 +490,148 @@ func (pb *PromptBuilder) Process(processFunc ProcessFunc) *StepBuilder {
 	return pb.stepBuilder
 }
 
+// Validate adds input validators to the step. Validators run in order before the
+// step's ProcessFunc; if one fails, the step is retried with the validator's
+// error message as the prompt and ProcessFunc is not called.
+//
+// Example:
+//
+//	flow.Step("email").
+//		Prompt("What's your email?").
+//		Process(saveEmail).
+//		Validate(teleflow.EmailValidator("That doesn't look like an email address."))
+func (sb *StepBuilder) Validate(validators ...Validator) *StepBuilder {
+	sb.validators = append(sb.validators, validators...)
+	return sb
+}
+
+// MaxAttempts limits how many times the step may be retried, whether the retry
+// comes from a failed validator or from the ProcessFunc returning Retry(). Once
+// the user has failed n times in a row, the flow's OnMaxRetries handler is
+// invoked; without a handler the flow is cancelled. A Retry() result with its
+// own WithMaxAttempts limit overrides it.
+//
+// Example:
+//
+//	flow.Step("amount").
+//		Prompt("How much?").
+//		Process(saveAmount).
+//		Validate(teleflow.NumberValidator()).
+//		MaxAttempts(3)
+func (sb *StepBuilder) MaxAttempts(n int) *StepBuilder {
+	sb.maxAttempts = n
+	return sb
+}
+
+// ValidateAsync adds validators that may take a while, such as checking a promo code
+// against an external API. Once the regular validators pass, the pending prompt
+// (default "⏳ Checking…") is sent and the validators run in the background.
+// When they finish, the input is either handed to the step's ProcessFunc or the
+// step is retried with the validation error as the prompt.
+// Messages received while the check is running are answered with the pending prompt.
+//
+// Example:
+//
+//	flow.Step("promo").
+//		Prompt("Enter your promo code:").
+//		Process(applyPromo).
+//		ValidateAsync("⏳ Checking your code…", func(ctx *teleflow.Context, code string) error {
+//			if !promoService.IsValid(code) {
+//				return &teleflow.ValidationError{Message: "❌ This code is not valid."}
+//			}
+//			return nil
+//		})
+func (sb *StepBuilder) ValidateAsync(pendingPrompt MessageSpec, validators ...Validator) *StepBuilder {
+	sb.pendingPrompt = pendingPrompt
+	sb.asyncValidators = append(sb.asyncValidators, validators...)
+	return sb
+}
+
+// RequirePermission restricts entering the step to users the AccessManager grants
+// permission. The check runs before the step's prompt is sent; on denial the
+// flow's denial prompt is shown and the flow is cancelled.
+//
+// Example:
+//
+//	flow.Step("confirm_transfer").
+//		Prompt("Confirm the transfer?").
+//		Process(executeTransfer).
+//		RequirePermission("transfer:execute")
+func (sb *StepBuilder) RequirePermission(permission string) *StepBuilder {
+	sb.permission = permission
+	return sb
+}
+
+// DeleteUserInput removes the user's text message from the chat as soon as the step
+// has processed it, whatever the outcome. Use it for sensitive input such as passwords
+// or card numbers. To do this for every step, pass DeleteUserMessage to OnButtonClick.
+//
+// Example:
+//
+//	flow.Step("password").
+//		Prompt("Enter your password:").
+//		Process(checkPassword).
+//		DeleteUserInput()
+func (sb *StepBuilder) DeleteUserInput() *StepBuilder {
+	sb.deleteInput = true
+	return sb
+}
+
+// OnError overrides the flow's error strategy for this step, e.g. to retry a
+// step that calls a payment provider while the rest of the flow keeps the
+// flow-level OnError setting.
+//
+// Example:
+//
+//	flow.Step("pay").
+//		Prompt("Confirm the payment?").
+//		Process(chargeCard).
+//		OnError(teleflow.OnErrorRetry("The payment service is busy, please try again."))
+func (sb *StepBuilder) OnError(config *ErrorConfig) *StepBuilder {
+	sb.onError = config
+	return sb
+}
+
+// Help sets the answer to help commands (FlowConfig.HelpCommands) sent while the
+// user is at this step. Like a prompt, it can be text, a "template:name" reference
+// or a function; it receives the template data "flow", "step", "step_number",
+// "total_steps" and "exit_command". Steps without help use the FlowHelpTemplate
+// if registered, else a short text with the step number and the exit command.
+//
+// Example:
+//
+//	flow.Step("iban").
+//		Prompt("Enter the recipient's IBAN:").
+//		Process(saveIBAN).
+//		Help("The IBAN is printed on your bank card or statement, e.g. DE89 3704 0044 0532 0130 00.")
+func (sb *StepBuilder) Help(message MessageSpec) *StepBuilder {
+	sb.help = message
+	return sb
+}
+
+// AcceptReactions lets the user answer the step by reacting to its prompt, a
+// lighter confirmation than an inline keyboard. The ProcessFunc receives the
+// emoji as input and details from ctx.Reaction(). Only the listed emoji are
+// accepted; without arguments any reaction is. Reactions reach the bot through
+// webhooks or ProcessReaction, see MessageReactionUpdated.
+//
+// Example:
+//
+//	flow.Step("confirm").
+//		Prompt("Send the report to the team? React 👍 or 👎").
+//		Process(func(ctx *teleflow.Context, input string, click *teleflow.ButtonClick) teleflow.ProcessResult {
+//			if input == "👍" {
+//				return teleflow.CompleteFlow()
+//			}
+//			return teleflow.CancelFlow()
+//		}).
+//		AcceptReactions("👍", "👎")
+func (sb *StepBuilder) AcceptReactions(emoji ...string) *StepBuilder {
+	sb.acceptReactions = true
+	sb.reactions = emoji
+	return sb
+}
+
 // Step allows adding another step to the flow from within a StepBuilder.
 // This provides a convenient way to chain step definitions.
 func (sb *StepBuilder) Step(name string) *StepBuilder {
@@ -269,6 +644,11 @@ func (sb *StepBuilder) OnComplete(handler func(*Context) error) *FlowBuilder {
 	return sb.flowBuilder.OnComplete(handler)
 }
 
+// OnCancel allows setting the cancellation handler from within a StepBuilder.
+func (sb *StepBuilder) OnCancel(handler func(*Context) error) *FlowBuilder {
+	return sb.flowBuilder.OnCancel(handler)
+}
+
 // Build constructs the final Flow from within a StepBuilder.
 // This provides a convenient way to build the flow after defining the last step.
 func (sb *StepBuilder) Build() (*Flow, error) {
@@ -0,0 +1,132 @@
+package teleflow
+
+import (
+	"container/list"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// flowCapacityWarning is the share of FlowConfig.MaxActiveFlows above which a
+// warning is logged.
+const flowCapacityWarning = 0.9
+
+// flowCapacity keeps active flows within FlowConfig.MaxActiveFlows. When a cap
+// is set it indexes the active flows from most to least recently active, so
+// the flow to evict is found without scanning every shard.
+type flowCapacity struct {
+	evicted atomic.Int64 // Flows evicted since the bot was created
+	warned  atomic.Bool  // The warning was logged and the count has not dropped since
+
+	mu    sync.Mutex                // Guards lru and elems; taken after shard locks, never before
+	lru   list.List                 // *userFlowState values, most recently active first
+	elems map[flowKey]*list.Element // Position of each active flow in lru
+}
+
+// maxActiveFlows returns the configured cap on active flows, 0 for none.
+func (fm *flowManager) maxActiveFlows() int {
+	if fm.flowConfig == nil || fm.flowConfig.MaxActiveFlows <= 0 {
+		return 0
+	}
+	return fm.flowConfig.MaxActiveFlows
+}
+
+// put records state as the most recently active flow under its key.
+func (c *flowCapacity) put(state *userFlowState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put_nolock(state)
+}
+
+func (c *flowCapacity) put_nolock(state *userFlowState) {
+	if elem, ok := c.elems[state.Key]; ok {
+		elem.Value = state
+		c.lru.MoveToFront(elem)
+		return
+	}
+	if c.elems == nil {
+		c.elems = make(map[flowKey]*list.Element)
+	}
+	c.elems[state.Key] = c.lru.PushFront(state)
+}
+
+// touch marks the flow stored under key as the most recently active one.
+func (c *flowCapacity) touch(key flowKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.elems[key]; ok {
+		c.lru.MoveToFront(elem)
+	}
+}
+
+// remove drops the flow stored under key from the index.
+func (c *flowCapacity) remove(key flowKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.elems[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.elems, key)
+	}
+}
+
+// admit reserves room for state within limit and returns the least recently
+// active flows that must be evicted for it. The check and the reservation are
+// made under one lock, so concurrent starts cannot overshoot the limit.
+// Replacing a flow already stored under the same key needs no room.
+func (c *flowCapacity) admit(state *userFlowState, limit int) []*userFlowState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, replacing := c.elems[state.Key]; replacing {
+		return nil
+	}
+	var evicted []*userFlowState
+	for c.lru.Len() >= limit {
+		oldest := c.lru.Back()
+		victim := c.lru.Remove(oldest).(*userFlowState)
+		delete(c.elems, victim.Key)
+		evicted = append(evicted, victim)
+	}
+	c.put_nolock(state)
+	return evicted
+}
+
+// makeRoomFor evicts the least recently active flows so that state can be
+// stored without exceeding FlowConfig.MaxActiveFlows. Evicted flows lose their
+// inline keyboard mappings; their OnCancel handlers do not run. No shard may
+// be locked.
+func (fm *flowManager) makeRoomFor(state *userFlowState) {
+	limit := fm.maxActiveFlows()
+	if limit == 0 {
+		return
+	}
+	for _, victim := range fm.capacity.admit(state, limit) {
+		shard := fm.shardFor(victim.Key)
+		shard.mu.Lock()
+		fm.deleteIfCurrent_nolock(victim)
+		shard.mu.Unlock()
+
+		fm.cleanupMappings(victim.Key)
+
+		fm.capacity.evicted.Add(1)
+		log.Printf("[FLOW_EVICTED] Flow: %s, Step: %s, User: %d, Chat: %d, LastActive: %s",
+			victim.FlowName, victim.CurrentStep, victim.Key.UserID, victim.Key.ChatID, victim.LastActive.Format(time.RFC3339))
+	}
+}
+
+// warnNearCapacity logs a warning once the active flows pass 90% of
+// FlowConfig.MaxActiveFlows, and again after they dropped below it.
+func (fm *flowManager) warnNearCapacity() {
+	limit := fm.maxActiveFlows()
+	if limit == 0 {
+		return
+	}
+	count := fm.activeFlowCount()
+	if float64(count) < flowCapacityWarning*float64(limit) {
+		fm.capacity.warned.Store(false)
+		return
+	}
+	if fm.capacity.warned.CompareAndSwap(false, true) {
+		log.Printf("[FLOW_CAPACITY] %d of %d active flows in use; the least recently active flows will be evicted at the limit", count, limit)
+	}
+}
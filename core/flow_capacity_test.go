@@ -0,0 +1,113 @@
+package teleflow
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxActiveFlows_EvictsLeastRecentlyActive(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bot, _, _, _ := createTestBot(WithClock(clock), WithFlowConfig(FlowConfig{
+		ExitCommands:   []string{"/cancel"},
+		MaxActiveFlows: 2,
+	}))
+	flow, err := NewFlow("survey").
+		Step("answer").
+		Prompt("Your answer?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return Retry()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	for userID := int64(1); userID <= 2; userID++ {
+		if err := bot.StartFlowFor(userID, userID, "survey", nil); err != nil {
+			t.Fatalf("StartFlowFor failed: %v", err)
+		}
+		clock.now = clock.now.Add(time.Minute)
+	}
+	// User 1 becomes the most recently active
+	bot.processUpdate(createPoolTestUpdate(1, "yes"))
+	clock.now = clock.now.Add(time.Minute)
+
+	if err := bot.StartFlowFor(3, 3, "survey", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	if _, inFlow := bot.GetUserFlow(2, 2); inFlow {
+		t.Error("Expected the least recently active flow to be evicted")
+	}
+	for _, userID := range []int64{1, 3} {
+		if _, inFlow := bot.GetUserFlow(userID, userID); !inFlow {
+			t.Errorf("Expected user %d to keep their flow", userID)
+		}
+	}
+
+	// Restarting an existing flow needs no room
+	if err := bot.StartFlowFor(1, 1, "survey", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	if count := bot.flowManager.activeFlowCount(); count != 2 {
+		t.Errorf("Expected 2 active flows, got %d", count)
+	}
+	if evicted := bot.flowManager.capacity.evicted.Load(); evicted != 1 {
+		t.Errorf("Expected 1 eviction, got %d", evicted)
+	}
+}
+
+func TestMaxActiveFlows_CleansUpAndHoldsUnderConcurrency(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithFlowConfig(FlowConfig{
+		ExitCommands:   []string{"/cancel"},
+		MaxActiveFlows: 5,
+	}))
+	flow, err := NewFlow("survey").
+		Step("answer").
+		Prompt("Your answer?").
+		WithPromptKeyboard(func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("Yes", "yes")
+		}).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return Retry()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	keyboards := bot.promptKeyboardHandler.(*PromptKeyboardHandler)
+
+	if err := bot.StartFlowFor(1, 1, "survey", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	if len(keyboards.uuidMappings[flowKey{UserID: 1}]) == 0 {
+		t.Fatal("Expected the prompt to register callback mappings")
+	}
+
+	var wg sync.WaitGroup
+	for userID := int64(2); userID <= 20; userID++ {
+		wg.Add(1)
+		go func(userID int64) {
+			defer wg.Done()
+			_ = bot.flowManager.startFlow(userID, userID, "survey", nil)
+		}(userID)
+	}
+	wg.Wait()
+
+	if count := bot.flowManager.activeFlowCount(); count != 5 {
+		t.Errorf("Expected concurrent starts to stay at the limit of 5, got %d", count)
+	}
+	if evicted := bot.flowManager.capacity.evicted.Load(); evicted != 15 {
+		t.Errorf("Expected 15 evictions, got %d", evicted)
+	}
+	if _, inFlow := bot.GetUserFlow(1, 1); inFlow {
+		t.Error("Expected the first flow to be evicted")
+	}
+	keyboards.mu.RLock()
+	defer keyboards.mu.RUnlock()
+	if _, ok := keyboards.uuidMappings[flowKey{UserID: 1}]; ok {
+		t.Error("Expected the evicted flow's callback mappings to be removed")
+	}
+}
@@ -0,0 +1,109 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrFlowNotWaiting is returned by ResumeFlow when the user has no flow parked
+// with WaitForEvent.
+var ErrFlowNotWaiting = errors.New("no flow is waiting for an event")
+
+// ResumeFlow delivers an out-of-band event, such as a confirmed payment webhook,
+// a human approval or a finished job, to the flow the user is running in their
+// private chat with the bot. The flow's current step must have returned
+// WaitForEvent; its ProcessFunc runs again as if the user had answered, with
+// empty input and ctx.Event() returning eventData, and its result is applied.
+// It returns ErrFlowNotWaiting if no step is waiting.
+//
+// Example:
+//
+//	http.HandleFunc("/approvals", func(w http.ResponseWriter, r *http.Request) {
+//		approval := decodeApproval(r)
+//		if err := bot.ResumeFlow(approval.UserID, approval); err != nil {
+//			log.Printf("approval for user %d not delivered: %v", approval.UserID, err)
+//		}
+//	})
+func (b *Bot) ResumeFlow(userID int64, eventData interface{}) error {
+	return b.ResumeFlowIn(userID, userID, eventData)
+}
+
+// ResumeFlowIn is ResumeFlow for a flow the user runs in another chat, such as a
+// group.
+//
+// ResumeFlow and ResumeFlowIn wait until no update of the chat is being
+// processed, so they must not be called from a handler, flow step or
+// middleware: the conversation lock held there is not reentrant and the call
+// would deadlock. Use ResumeFlowAsync from handlers, e.g. when an admin approves
+// a request in a group.
+func (b *Bot) ResumeFlowIn(userID, chatID int64, eventData interface{}) error {
+	ctx := b.contextFor(userID, chatID)
+	ctx.event = eventData
+	defer b.lockConversation(ctx)()
+	return b.flowManager.resumeWithEvent(ctx)
+}
+
+// ResumeFlowAsync is ResumeFlowIn run in its own goroutine, safe to call from
+// handlers. The returned channel receives the result once the event has been
+// delivered; it can be ignored.
+//
+// Example:
+//
+//	bot.HandleCommand("approve", func(ctx *teleflow.Context, command, args string) error {
+//		requester, _ := strconv.ParseInt(args, 10, 64)
+//		bot.ResumeFlowAsync(requester, requester, Approval{By: ctx.UserID()})
+//		return ctx.SendPromptText("Approved")
+//	})
+func (b *Bot) ResumeFlowAsync(userID, chatID int64, eventData interface{}) <-chan error {
+	result := make(chan error, 1)
+	b.activeHandlers.Add(1)
+	go func() {
+		defer b.activeHandlers.Add(-1)
+		result <- b.ResumeFlowIn(userID, chatID, eventData)
+	}()
+	return result
+}
+
+// Event returns the event passed to ResumeFlow while a waiting step processes
+// it, and nil for updates from the user.
+func (c *Context) Event() interface{} {
+	return c.event
+}
+
+// resumeWithEvent runs the ProcessFunc of the step waiting for the context's
+// event and applies its result.
+func (fm *flowManager) resumeWithEvent(ctx *Context) error {
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
+	key, userState, exists := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID())
+	if !exists || !userState.WaitingForEvent {
+		locks.Unlock()
+		return ErrFlowNotWaiting
+	}
+	flow := fm.flows[userState.FlowName]
+	var step *flowStep
+	if flow != nil {
+		step = flow.Steps[userState.CurrentStep]
+	}
+	if step == nil || step.ProcessFunc == nil {
+		locks.Unlock()
+		return fmt.Errorf("step %s of flow %s cannot process events", userState.CurrentStep, userState.FlowName)
+	}
+	userState.WaitingForEvent = false
+	fm.touchState_nolock(userState, fm.clock.Now())
+	ctx.flowScope = flow.Scope
+	locks.Unlock()
+
+	var result ProcessResult
+	if panicErr := protect(flow.Name, step.Name, func() { result = step.ProcessFunc(ctx, "", nil) }); panicErr != nil {
+		return fm.handleStepPanic(ctx, key, userState, flow, panicErr)
+	}
+
+	locks.Lock()
+	defer locks.Unlock()
+	if current, exists := fm.getState_nolock(key); !exists || current != userState {
+		return nil // The flow ended while the event was processed
+	}
+	_, err := fm.handleProcessResult_nolock(ctx, result, userState, flow)
+	return err
+}
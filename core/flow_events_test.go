@@ -0,0 +1,115 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResumeFlow(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	userID := int64(42)
+
+	var inputs []string
+	completed := false
+	flow, err := NewFlow("approval").
+		Step("request").
+		Prompt("What do you need?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			if decision, ok := ctx.Event().(string); ok {
+				if decision != "approved" {
+					return CancelFlow()
+				}
+				ctx.SetFlowData("decision", decision)
+				return CompleteFlow()
+			}
+			inputs = append(inputs, input)
+			return WaitForEvent().WithPrompt("⏳ Waiting for a manager…")
+		}).
+		OnComplete(func(ctx *Context) error {
+			decision, _ := ctx.GetFlowData("decision")
+			completed = decision == "approved"
+			return nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	if err := bot.ResumeFlow(userID, "approved"); !errors.Is(err, ErrFlowNotWaiting) {
+		t.Fatalf("Expected ErrFlowNotWaiting without a flow, got %v", err)
+	}
+	if err := bot.StartFlowFor(userID, userID, "approval", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	if err := bot.ResumeFlow(userID, "approved"); !errors.Is(err, ErrFlowNotWaiting) {
+		t.Fatalf("Expected ErrFlowNotWaiting before the step parks, got %v", err)
+	}
+
+	bot.processUpdate(createPoolTestUpdate(userID, "a new laptop"))
+	bot.processUpdate(createPoolTestUpdate(userID, "any news?"))
+	if len(inputs) != 2 || inputs[1] != "any news?" {
+		t.Fatalf("Expected messages to reach the waiting step, got %v", inputs)
+	}
+
+	if err := bot.ResumeFlow(userID, "approved"); err != nil {
+		t.Fatalf("ResumeFlow failed: %v", err)
+	}
+	if !completed {
+		t.Error("Expected the event to complete the flow")
+	}
+	if _, inFlow := bot.GetUserFlow(userID, userID); inFlow {
+		t.Error("Expected the flow to end")
+	}
+	if err := bot.ResumeFlow(userID, "approved"); !errors.Is(err, ErrFlowNotWaiting) {
+		t.Errorf("Expected ErrFlowNotWaiting after the flow ended, got %v", err)
+	}
+}
+
+func TestResumeFlowAsync_FromHandler(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	userID := int64(7)
+	flow, err := NewFlow("approval").
+		Step("request").
+		Prompt("What do you need?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			if ctx.Event() != nil {
+				return CompleteFlow()
+			}
+			return WaitForEvent()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	if err := bot.StartFlowFor(userID, userID, "approval", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	bot.processUpdate(createPoolTestUpdate(userID, "a new laptop"))
+
+	// The approving admin's chat shares the requester's lock stripe
+	adminID := userID + 1
+	for chatStripe(adminID) != chatStripe(userID) {
+		adminID++
+	}
+	var result <-chan error
+	bot.HandleCommand("approve", func(ctx *Context, command, args string) error {
+		result = bot.ResumeFlowAsync(userID, userID, "approved")
+		return nil
+	})
+	bot.processUpdate(commandUpdate(adminID, "approve"))
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("ResumeFlowAsync failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the event to be delivered once the handler returned")
+	}
+	if _, inFlow := bot.GetUserFlow(userID, userID); inFlow {
+		t.Error("Expected the event to complete the flow")
+	}
+}
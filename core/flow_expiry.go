@@ -0,0 +1,121 @@
+package teleflow
+
+import (
+	"log"
+	"time"
+)
+
+// maxFlowSweepInterval is the longest time between two sweeps for flows idle
+// beyond FlowConfig.StateTTL.
+const maxFlowSweepInterval = time.Minute
+
+// FlowExpiredFunc is called for each flow removed because it was idle beyond
+// FlowConfig.StateTTL. ctx addresses the flow's user and chat, so the hook can
+// tell the user that their session expired; ctx.UserID() is 0 for flows bound
+// to a whole chat.
+type FlowExpiredFunc func(ctx *Context, state FlowStateSnapshot)
+
+// SweepExpiredFlows removes the flows that have been idle for longer than
+// FlowConfig.StateTTL and calls FlowConfig.OnExpired for each. It returns the
+// number of flows removed. Start and WebhookHandler sweep periodically on their
+// own; call it directly when updates are delivered another way.
+func (b *Bot) SweepExpiredFlows() int {
+	ttl := b.flowConfig.StateTTL
+	if ttl <= 0 {
+		return 0
+	}
+	expired := b.flowManager.removeIdleStates(b.clock.Now().Add(-ttl), b.tryLockFlowConversation)
+	for _, state := range expired {
+		log.Printf("[FLOW_EXPIRED] Flow: %s, Step: %s, User: %d, Chat: %d, LastActive: %s",
+			state.FlowName, state.CurrentStep, state.Key.UserID, state.Key.ChatID, state.LastActive.Format(time.RFC3339))
+		b.flowManager.cleanupMappings(state.Key)
+		if b.flowConfig.OnExpired != nil {
+			b.runExpiredHook(state)
+		}
+	}
+	return len(expired)
+}
+
+// runExpiredHook calls FlowConfig.OnExpired for a removed flow state.
+func (b *Bot) runExpiredHook(state *userFlowState) {
+	chatID := state.Key.ChatID
+	if chatID == 0 {
+		chatID = state.Key.UserID // Personal flows are answered in the private chat
+	}
+	ctx := b.contextFor(state.Key.UserID, chatID)
+	snapshot := state.snapshot()
+	if panicErr := protect(state.FlowName, state.CurrentStep, func() { b.flowConfig.OnExpired(ctx, snapshot) }); panicErr != nil {
+		log.Printf("[FLOW_EXPIRED] OnExpired failed for flow %s: %v", state.FlowName, panicErr)
+	}
+}
+
+// startFlowSweeper starts sweeping for expired flows in the background, once per
+// bot, when FlowConfig.StateTTL is set.
+func (b *Bot) startFlowSweeper() {
+	ttl := b.flowConfig.StateTTL
+	if ttl <= 0 {
+		return
+	}
+	interval := min(ttl, maxFlowSweepInterval)
+	b.sweeperOnce.Do(func() {
+		var sweep func()
+		sweep = func() {
+			b.SweepExpiredFlows()
+			b.clock.AfterFunc(interval, sweep)
+		}
+		b.clock.AfterFunc(interval, sweep)
+	})
+}
+
+// tryLockFlowConversation takes the conversation lock of the chat a flow state
+// belongs to, or of the user's private chat for personal flows, without
+// waiting. It reports false if an update of the conversation, or of a chat
+// sharing its lock stripe, is being handled; the next sweep tries again.
+func (b *Bot) tryLockFlowConversation(key flowKey) (func(), bool) {
+	conversation := key.ChatID
+	if conversation == 0 {
+		conversation = key.UserID
+	}
+	if b.chatLocks == nil {
+		return func() {}, true
+	}
+	return b.chatLocks.tryLock(conversation)
+}
+
+// removeIdleStates removes and returns the flow states last active before
+// cutoff. Flows whose asynchronous job is still running are kept, and so are
+// flows whose conversation tryLock cannot take right now.
+func (fm *flowManager) removeIdleStates(cutoff time.Time, tryLock func(flowKey) (func(), bool)) []*userFlowState {
+	idle := func(state *userFlowState) bool {
+		return state.LastActive.Before(cutoff) && !state.JobRunning
+	}
+
+	var candidates []flowKey
+	for i := range fm.shards {
+		shard := &fm.shards[i]
+		shard.mu.RLock()
+		for key, state := range shard.states {
+			if idle(state) {
+				candidates = append(candidates, key)
+			}
+		}
+		shard.mu.RUnlock()
+	}
+
+	var expired []*userFlowState
+	for _, key := range candidates {
+		release, ok := tryLock(key)
+		if !ok {
+			continue
+		}
+		shard := fm.shardFor(key)
+		shard.mu.Lock()
+		if state, ok := shard.states[key]; ok && idle(state) {
+			fm.deleteState_nolock(key)
+			expired = append(expired, state)
+		}
+		shard.mu.Unlock()
+		release()
+	}
+	return expired
+}
@@ -0,0 +1,97 @@
+package teleflow
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSweepExpiredFlows(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var expired []FlowStateSnapshot
+	bot, client, _, _ := createTestBot(WithClock(clock), WithFlowConfig(FlowConfig{
+		ExitCommands: []string{"/cancel"},
+		StateTTL:     time.Hour,
+		OnExpired: func(ctx *Context, state FlowStateSnapshot) {
+			expired = append(expired, state)
+			_ = ctx.SendPromptText("⌛ Your session expired.")
+		},
+	}))
+	flow, err := NewFlow("signup").
+		Step("name").
+		Prompt("Your name?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return Retry()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	var sentTo []int64
+	client.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		if msg, ok := c.(tgbotapi.MessageConfig); ok && msg.Text == "⌛ Your session expired." {
+			sentTo = append(sentTo, msg.ChatID)
+		}
+		return tgbotapi.Message{MessageID: 123}, nil
+	}
+
+	if err := bot.StartFlowFor(1, 1, "signup", map[string]interface{}{"ref": "ad"}); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	clock.now = clock.now.Add(45 * time.Minute)
+	if err := bot.StartFlowFor(2, 2, "signup", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+
+	clock.now = clock.now.Add(30 * time.Minute)
+	if removed := bot.SweepExpiredFlows(); removed != 1 {
+		t.Fatalf("Expected 1 expired flow, got %d", removed)
+	}
+	if _, inFlow := bot.GetUserFlow(1, 1); inFlow {
+		t.Error("Expected the idle flow to be removed")
+	}
+	if _, inFlow := bot.GetUserFlow(2, 2); !inFlow {
+		t.Error("Expected the recent flow to be kept")
+	}
+	if len(expired) != 1 || expired[0].FlowName != "signup" || expired[0].Data["ref"] != "ad" {
+		t.Errorf("Expected OnExpired with the flow's snapshot, got %+v", expired)
+	}
+	if len(sentTo) != 1 || sentTo[0] != 1 {
+		t.Errorf("Expected the expiry notice in the user's chat, got %v", sentTo)
+	}
+}
+
+func TestFlowSweeper_UsesClockAndSkipsBusyConversations(t *testing.T) {
+	clock := &manualClock{stepClock: stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	bot, _, _, _ := createTestBot(WithClock(clock), WithFlowConfig(FlowConfig{StateTTL: time.Minute}))
+	flow, err := NewFlow("signup").
+		Step("name").
+		Prompt("Your name?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return Retry()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	if err := bot.StartFlowFor(1, 1, "signup", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+
+	bot.startFlowSweeper()
+	release := bot.chatLocks.lock(1) // An update of the conversation is being handled
+	clock.fire(2 * time.Minute)
+	if _, inFlow := bot.GetUserFlow(1, 1); !inFlow {
+		t.Error("Expected the sweeper to skip a conversation that is being handled")
+	}
+
+	release()
+	clock.fire(time.Minute)
+	if _, inFlow := bot.GetUserFlow(1, 1); inFlow {
+		t.Error("Expected the rescheduled sweep to remove the idle flow")
+	}
+}
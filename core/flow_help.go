@@ -0,0 +1,92 @@
+package teleflow
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// FlowHelpTemplate is the template used to answer a help command during a flow
+// whose current step has no help of its own. Register a template under this name
+// to replace the built-in help text. Like step help, it receives the template data
+// "flow", "step", "step_number", "total_steps" and "exit_command".
+const FlowHelpTemplate = "flow_help"
+
+// isHelpCommand reports whether the message is one of FlowConfig.HelpCommands.
+func (b *Bot) isHelpCommand(message *tgbotapi.Message) bool {
+	if !message.IsCommand() {
+		return false
+	}
+	commandName := message.Command()
+	for _, helpCmd := range b.flowConfig.HelpCommands {
+		if "/"+commandName == helpCmd || commandName == helpCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// sendFlowHelp answers a help command sent during a flow with the current step's
+// help, the FlowHelpTemplate, or a short built-in text, in that order.
+func (fm *flowManager) sendFlowHelp(ctx *Context, exitCommands []string) {
+	locks := fm.contextLocks(ctx)
+	locks.RLock()
+	_, state, ok := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID())
+	if !ok {
+		locks.RUnlock()
+		return
+	}
+	flowName, stepName, flowData := state.FlowName, state.CurrentStep, copyFlowData(state.Data)
+	locks.RUnlock()
+
+	flow := fm.flows[flowName]
+	if flow == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"flow":         flowName,
+		"step":         stepName,
+		"step_number":  flow.stepNumber(stepName),
+		"total_steps":  len(flow.Order),
+		"exit_command": "",
+	}
+	if len(exitCommands) > 0 {
+		data["exit_command"] = exitCommands[0]
+	}
+
+	var message MessageSpec
+	switch step := flow.Steps[stepName]; {
+	case step != nil && step.Help != nil:
+		message = step.Help
+	case ctx.templateManager != nil && ctx.templateManager.HasTemplate(FlowHelpTemplate):
+		message = "template:" + FlowHelpTemplate
+	default:
+		message = defaultFlowHelp(data)
+	}
+
+	if err := fm.composeFlowPrompt(ctx, flow, stepName, flowData, &PromptConfig{Message: message, TemplateData: data}); err != nil {
+		log.Printf("[FLOW_HELP] Flow: %s, Step: %s, User: %d, Error: %v", flowName, stepName, ctx.UserID(), err)
+	}
+}
+
+// stepNumber returns the 1-based position of a step in the flow's order, or 0.
+func (f *Flow) stepNumber(stepName string) int {
+	for i, name := range f.Order {
+		if name == stepName {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// defaultFlowHelp is the help text used when neither the step nor the bot
+// provides one.
+func defaultFlowHelp(data map[string]interface{}) string {
+	text := fmt.Sprintf("ℹ️ You are at step %d of %d. Answer the last message to continue.", data["step_number"], data["total_steps"])
+	if exit := data["exit_command"]; exit != "" {
+		text += fmt.Sprintf("\nSend %s to leave.", exit)
+	}
+	return text
+}
@@ -0,0 +1,133 @@
+package teleflow
+
+import "testing"
+
+func TestBot_FlowHelp(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+	flow := createTestFlow()
+	flow.Steps["step2"].Help = "Enter your age in years"
+	bot.RegisterFlow(flow)
+	bot.HandleCommand("help", func(ctx *Context, command, args string) error {
+		return ctx.sendSimpleText("global help")
+	})
+
+	if err := bot.StartFlowFor(42, 42, "test-flow", nil); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	client.SendCalls = nil
+	bot.processUpdate(commandUpdate(42, "help"))
+	want := "ℹ️ You are at step 1 of 2. Answer the last message to continue.\nSend /cancel to leave."
+	if texts := sentText(client); len(texts) != 1 || texts[0] != want {
+		t.Errorf("Expected default flow help, got %q", texts)
+	}
+
+	bot.processUpdate(createCaptchaAnswerUpdate(42, 42, "John"))
+	client.SendCalls = nil
+	bot.processUpdate(commandUpdate(42, "help"))
+	if texts := sentText(client); len(texts) != 1 || texts[0] != "Enter your age in years" {
+		t.Errorf("Expected step help, got %q", texts)
+	}
+	if snapshot, _ := bot.GetUserFlow(42, 42); snapshot.CurrentStep != "step2" {
+		t.Errorf("Expected help not to change the step, got %s", snapshot.CurrentStep)
+	}
+}
+
+func TestBot_GlobalCommandWhitelist(t *testing.T) {
+	tests := []struct {
+		name         string
+		flowCommands []string
+		wantBalance  bool
+		wantTransfer bool
+	}{
+		{"config whitelist", nil, true, false},
+		{"flow override", []string{"/transfer"}, false, true},
+		{"flow blocks all", []string{}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := FlowConfig{ExitCommands: []string{"/cancel"}, GlobalCommandWhitelist: []string{"/balance"}}
+			bot, _, _, _ := createTestBot(WithFlowConfig(config))
+			flow := createTestFlow()
+			flow.GlobalCommands = tt.flowCommands
+			bot.RegisterFlow(flow)
+
+			ran := map[string]bool{}
+			for _, command := range []string{"balance", "transfer"} {
+				command := command
+				bot.HandleCommand(command, func(ctx *Context, _, _ string) error {
+					ran[command] = true
+					return nil
+				})
+			}
+
+			if err := bot.StartFlowFor(42, 42, "test-flow", nil); err != nil {
+				t.Fatalf("Failed to start flow: %v", err)
+			}
+			bot.processUpdate(commandUpdate(42, "balance"))
+			bot.processUpdate(commandUpdate(42, "transfer"))
+
+			if ran["balance"] != tt.wantBalance || ran["transfer"] != tt.wantTransfer {
+				t.Errorf("Expected balance=%v transfer=%v, got %v", tt.wantBalance, tt.wantTransfer, ran)
+			}
+		})
+	}
+}
+
+func TestBot_AllowGlobalCommandsWithoutWhitelist(t *testing.T) {
+	config := FlowConfig{ExitCommands: []string{"/cancel"}, AllowGlobalCommands: true, HelpCommands: []string{"/help"}}
+	bot, _, _, _ := createTestBot(WithFlowConfig(config))
+	bot.RegisterFlow(createTestFlow())
+
+	ran := map[string]bool{}
+	for _, command := range []string{"help", "transfer"} {
+		command := command
+		bot.HandleCommand(command, func(ctx *Context, _, _ string) error {
+			ran[command] = true
+			return nil
+		})
+	}
+
+	if err := bot.StartFlowFor(42, 42, "test-flow", nil); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	bot.processUpdate(commandUpdate(42, "help"))
+	bot.processUpdate(commandUpdate(42, "transfer"))
+
+	if !ran["help"] || ran["transfer"] {
+		t.Errorf("Expected only the help handler to run during the flow, got %v", ran)
+	}
+}
+
+func TestBot_InterruptPauseResume(t *testing.T) {
+	config := FlowConfig{
+		ExitCommands:           []string{"/cancel"},
+		GlobalCommandWhitelist: []string{"/balance"},
+		Interruption:           InterruptPauseResume,
+	}
+	bot, client, _, _ := createTestBot(WithFlowConfig(config))
+	bot.RegisterFlow(createTestFlow())
+
+	inFlow := true
+	bot.HandleCommand("balance", func(ctx *Context, _, _ string) error {
+		_, _, inFlow = ctx.CurrentFlow()
+		return ctx.sendSimpleText("Balance: 100")
+	})
+
+	if err := bot.StartFlowFor(42, 42, "test-flow", nil); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	client.SendCalls = nil
+	bot.processUpdate(commandUpdate(42, "balance"))
+
+	if inFlow {
+		t.Error("Expected the flow to be paused while the command runs")
+	}
+	if texts := sentText(client); len(texts) != 2 || texts[0] != "Balance: 100" || texts[1] != "Enter your name:" {
+		t.Errorf("Expected command reply followed by the step prompt, got %q", texts)
+	}
+	if snapshot, ok := bot.GetUserFlow(42, 42); !ok || snapshot.CurrentStep != "step1" {
+		t.Errorf("Expected flow to be resumed at step1, got %+v (active=%v)", snapshot, ok)
+	}
+}
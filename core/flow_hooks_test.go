@@ -0,0 +1,181 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// startCancelTestFlow starts createTestFlow with an OnCancel handler that records
+// the reason and the flow data it could see.
+func startCancelTestFlow(t *testing.T, fm *flowManager) (userID int64, reasons *[]CancelReason, names *[]interface{}) {
+	t.Helper()
+	reasons = &[]CancelReason{}
+	names = &[]interface{}{}
+
+	flow := createTestFlow()
+	flow.OnCancel = func(ctx *Context) error {
+		*reasons = append(*reasons, ctx.CancelReason())
+		name, _ := ctx.GetFlowData("name")
+		*names = append(*names, name)
+		return nil
+	}
+	flow.Steps["step2"].ProcessFunc = func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+		return CancelFlow()
+	}
+	fm.registerFlow(flow)
+
+	userID = 12345
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "John", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	return userID, reasons, names
+}
+
+func TestFlowManager_OnCancelForCancelFlowResult(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+	userID, reasons, names := startCancelTestFlow(t, fm)
+
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "cancel", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	if len(*reasons) != 1 || (*reasons)[0] != CancelReasonCancelFlow {
+		t.Errorf("Expected one cancel_flow cancellation, got %v", *reasons)
+	}
+	if (*names)[0] != "John" {
+		t.Errorf("Expected OnCancel to read flow data, got %v", (*names)[0])
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected flow state to be removed")
+	}
+}
+
+func TestFlowManager_OnCancelForManualCancel(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+	userID, reasons, _ := startCancelTestFlow(t, fm)
+
+	createFlowTestContext(userID, "", fm).CancelFlow()
+
+	if len(*reasons) != 1 || (*reasons)[0] != CancelReasonManual {
+		t.Errorf("Expected one manual cancellation, got %v", *reasons)
+	}
+}
+
+func TestFlowManager_OnCancelForTimeout(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	fm.clock = clock
+	userID, reasons, _ := startCancelTestFlow(t, fm)
+
+	clock.now = clock.now.Add(11 * time.Minute)
+	handled, err := fm.HandleUpdate(createFlowTestContext(userID, "30", fm))
+	if err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	if handled {
+		t.Error("Expected an update for an expired flow to reach the regular handlers")
+	}
+	if len(*reasons) != 1 || (*reasons)[0] != CancelReasonTimeout {
+		t.Errorf("Expected one timeout cancellation, got %v", *reasons)
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected expired flow to be removed")
+	}
+}
+
+func TestBot_OnCancelForExitCommand(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	var reasons []CancelReason
+	flow := createTestFlow()
+	flow.OnCancel = func(ctx *Context) error {
+		reasons = append(reasons, ctx.CancelReason())
+		return nil
+	}
+	bot.RegisterFlow(flow)
+
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if err := ctx.StartFlow("test-flow"); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	bot.processUpdate(commandUpdate(42, "cancel"))
+
+	if len(reasons) != 1 || reasons[0] != CancelReasonExitCommand {
+		t.Errorf("Expected one exit_command cancellation, got %v", reasons)
+	}
+}
+
+func TestFlowManager_OnStartRunsBeforeFirstPrompt(t *testing.T) {
+	fm, mockSender, _, _ := createTestFlowManager()
+	flow := createTestFlow()
+	flow.OnStart = func(ctx *Context) error {
+		if calls := mockSender.getComposeAndSendCalls(); len(calls) != 0 {
+			t.Errorf("Expected OnStart before the first prompt, got %d prompts", len(calls))
+		}
+		return ctx.SetFlowData("balance", 100)
+	}
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	if value, ok := fm.getUserFlowData(userID, userID, "balance"); !ok || value != 100 {
+		t.Errorf("Expected OnStart to preload flow data, got %v", value)
+	}
+	if calls := mockSender.getComposeAndSendCalls(); len(calls) != 1 {
+		t.Errorf("Expected first prompt after OnStart, got %d prompts", len(calls))
+	}
+}
+
+func TestFlowManager_OnStartAbortsFlow(t *testing.T) {
+	fm, mockSender, _, _ := createTestFlowManager()
+	flow := createTestFlow()
+	preconditionErr := errors.New("account not linked")
+	flow.OnStart = func(ctx *Context) error { return preconditionErr }
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm))
+	if !errors.Is(err, preconditionErr) {
+		t.Fatalf("Expected OnStart error, got %v", err)
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected aborted flow not to be active")
+	}
+	if calls := mockSender.getComposeAndSendCalls(); len(calls) != 0 {
+		t.Errorf("Expected no prompt for an aborted flow, got %d", len(calls))
+	}
+}
+
+func TestBot_FlowExitCommandOverride(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+	flow := createTestFlow()
+	flow.ExitCommands = []string{"/abort"}
+	flow.ExitMessage = "Transfer aborted"
+	bot.RegisterFlow(flow)
+
+	if err := bot.StartFlowFor(42, 42, "test-flow", nil); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	// The global exit command is consumed by the flow as regular input
+	bot.processUpdate(commandUpdate(42, "cancel"))
+	if snapshot, ok := bot.GetUserFlow(42, 42); !ok || snapshot.CurrentStep != "step2" {
+		t.Fatalf("Expected global exit command to be ignored, got %+v (active=%v)", snapshot, ok)
+	}
+
+	client.SendCalls = nil
+	bot.processUpdate(commandUpdate(42, "abort"))
+	if _, ok := bot.GetUserFlow(42, 42); ok {
+		t.Fatal("Expected flow exit command to cancel the flow")
+	}
+	if texts := sentText(client); len(texts) != 1 || texts[0] != "Transfer aborted" {
+		t.Errorf("Expected flow exit message, got %v", texts)
+	}
+}
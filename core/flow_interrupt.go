@@ -0,0 +1,62 @@
+package teleflow
+
+import "log"
+
+// InterruptionPolicy decides what happens to an active flow when the user sends a
+// global command that is allowed during flows (see FlowConfig.GlobalCommandWhitelist).
+type InterruptionPolicy int
+
+const (
+	// InterruptContinue runs the command and leaves the flow untouched; the user
+	// answers the flow's last prompt afterwards. It is the default.
+	InterruptContinue InterruptionPolicy = iota
+
+	// InterruptPauseResume pauses the flow while the command runs, so the command
+	// sees the user as not being in a flow, and then sends the current step's
+	// prompt again. If the command starts another flow, the paused flow is dropped.
+	InterruptPauseResume
+)
+
+// pauseForInterruption pauses the flow that applies to the context when the
+// interruption policy asks for it, and returns the paused state for
+// resumeFlow, or nil.
+func (b *Bot) pauseForInterruption(ctx *Context) *userFlowState {
+	fm := b.flowManager
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
+	defer locks.Unlock()
+
+	_, state, ok := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID())
+	if !ok {
+		return nil
+	}
+	flow := fm.flows[state.FlowName]
+	if flow == nil || (!flow.PauseOnInterrupt && b.flowConfig.Interruption != InterruptPauseResume) {
+		return nil
+	}
+	fm.deleteState_nolock(state.Key)
+	return state
+}
+
+// resumeFlow restores a paused flow and sends its current step's prompt again as
+// a new message. Nothing happens if another flow was started in the meantime.
+func (fm *flowManager) resumeFlow(ctx *Context, state *userFlowState) error {
+	flow := fm.flows[state.FlowName]
+	if flow == nil {
+		return nil
+	}
+
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
+	defer locks.Unlock()
+
+	if _, current, ok := fm.lookupState_nolock(ctx.UserID(), ctx.ChatID()); ok {
+		log.Printf("[FLOW_INTERRUPT] Flow %s of user %d dropped, %s was started while it was paused", state.FlowName, ctx.UserID(), current.FlowName)
+		return nil
+	}
+	fm.putState_nolock(state.Key, state)
+
+	ctx.flowScope = flow.Scope
+	state.LastPrompt = sentPrompt{}
+	return fm.renderStepPrompt_withLockRelease(ctx, flow, state.CurrentStep, state)
+}
@@ -0,0 +1,150 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultJobRunningMessage answers messages sent while a step's job runs.
+const defaultJobRunningMessage = "⏳ Still working on it…"
+
+// JobFunc is the background work of an asynchronous step, see
+// PromptBuilder.RunAsync. It may report progress with ctx.ReportProgress.
+type JobFunc func(ctx *Context) error
+
+// flowJob is the job of an asynchronous step running for a context.
+type flowJob struct {
+	prompt sentPrompt // Prompt message edited by ReportProgress
+}
+
+// RunAsync makes the step run job in the background instead of waiting for the
+// user's answer. The step's prompt is the "working…" message: it is sent, the
+// job starts, and ctx.ReportProgress edits the message as the job goes on. When
+// the job returns nil the flow moves to the next step. When it returns an error
+// or panics, the step's error strategy applies: OnErrorCancel (the default)
+// cancels the flow, OnErrorRetry shows the prompt again and reruns the job,
+// OnErrorIgnore moves on and OnErrorFunc decides with a ProcessResult.
+//
+// Messages the user sends while the job runs are answered with the step's
+// pending prompt (see ValidateAsync), by default "⏳ Still working on it…". If
+// the flow is cancelled in the meantime, the job keeps running but its result
+// is discarded. Progress edits remove an inline keyboard from the prompt.
+//
+// Example:
+//
+//	flow.Step("export").
+//		Prompt("⏳ Exporting your data…").
+//		RunAsync(func(ctx *teleflow.Context) error {
+//			for i, table := range tables {
+//				if err := export(ctx.UserID(), table); err != nil {
+//					return err
+//				}
+//				ctx.ReportProgress(fmt.Sprintf("⏳ Exporting your data… %d/%d", i+1, len(tables)))
+//			}
+//			return nil
+//		}).
+//		Step("done").
+//		Prompt("✅ Your export is ready.").
+//		Process(...)
+func (pb *PromptBuilder) RunAsync(job JobFunc) *StepBuilder {
+	pb.stepBuilder.promptConfig = pb.promptConfig
+	pb.stepBuilder.job = job
+	return pb.stepBuilder
+}
+
+// ReportProgress replaces the text of the running asynchronous step's prompt,
+// or its caption if the prompt is a photo. It can only be called from a
+// JobFunc.
+func (c *Context) ReportProgress(message string) error {
+	if c.job == nil {
+		return errors.New("ReportProgress called outside an asynchronous step")
+	}
+	prompt := c.job.prompt
+	if prompt.MessageID == 0 {
+		return errors.New("the step's prompt was not sent")
+	}
+
+	var edit tgbotapi.Chattable
+	if prompt.Photo {
+		edit = tgbotapi.NewEditMessageCaption(prompt.ChatID, prompt.MessageID, message)
+	} else {
+		edit = tgbotapi.NewEditMessageText(prompt.ChatID, prompt.MessageID, message)
+	}
+	if _, err := c.telegramClient.Request(edit); err != nil && !errors.Is(err, ErrMessageNotModified) {
+		return fmt.Errorf("failed to report progress: %w", err)
+	}
+	return nil
+}
+
+// startJob_nolock starts the job of the step whose prompt was just sent. The
+// state's shard must be locked.
+func (fm *flowManager) startJob_nolock(ctx *Context, flow *Flow, step *flowStep, userState *userFlowState) {
+	if step.Job == nil || userState.JobRunning {
+		return
+	}
+	userState.JobRunning = true
+	ctx.job = &flowJob{prompt: userState.LastPrompt}
+
+	ctx.Retain() // The job outlives the update
+	go fm.runJob(ctx, userState.Key, flow, step, userState)
+}
+
+// runJob runs a step's job and moves the flow on when it finishes. The result
+// is discarded if the flow moved on or ended while the job was running.
+func (fm *flowManager) runJob(ctx *Context, key flowKey, flow *Flow, step *flowStep, userState *userFlowState) {
+	var jobErr error
+	if panicErr := protect(flow.Name, step.Name, func() { jobErr = step.Job(ctx) }); panicErr != nil {
+		var fpe *FlowPanicError
+		if errors.As(panicErr, &fpe) {
+			log.Printf("[FLOW_PANIC] Flow: %s, Step: %s, User: %d, Panic: %v\n%s", fpe.Flow, fpe.Step, ctx.UserID(), fpe.Value, fpe.Stack)
+		}
+		jobErr = panicErr
+	}
+
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
+	defer locks.Unlock()
+
+	if current, exists := fm.getState_nolock(key); !exists || current != userState || !userState.JobRunning || userState.CurrentStep != step.Name {
+		return
+	}
+	userState.JobRunning = false
+	ctx.job = nil
+
+	var err error
+	if jobErr == nil {
+		_, err = fm.handleProcessResult_nolock(ctx, NextStep(), userState, flow)
+	} else {
+		err = fm.handleJobError_nolock(ctx, jobErr, flow, userState)
+	}
+	if err != nil {
+		log.Printf("[FLOW_JOB] Flow: %s, Step: %s, User: %d, Error: %v", flow.Name, step.Name, ctx.UserID(), err)
+	}
+}
+
+// handleJobError_nolock applies the step's error strategy after its job failed.
+func (fm *flowManager) handleJobError_nolock(ctx *Context, jobErr error, flow *Flow, userState *userFlowState) error {
+	config := flow.errorConfig(userState.CurrentStep)
+	log.Printf("[FLOW_JOB_ERROR] Flow: %s, Step: %s, User: %d, Action: %s, Error: %v",
+		flow.Name, userState.CurrentStep, ctx.UserID(), fm.getActionName(config.Action), jobErr)
+
+	switch config.Action {
+	case errorStrategyRetry:
+		fm.notifyUserIfNeeded(ctx, config.Message)
+		return fm.renderStepPrompt_withLockRelease(ctx, flow, userState.CurrentStep, userState)
+	case errorStrategyIgnore:
+		fm.notifyUserIfNeeded(ctx, config.Message)
+		_, err := fm.advanceToNextStep(ctx, userState, flow)
+		return err
+	case errorStrategyFunc:
+		_, err := fm.handleErrorStrategyFunc_nolock(ctx, config, jobErr, userState, flow)
+		return err
+	default:
+		fm.cleanupMappings(userState.Key)
+		fm.handleErrorStrategyCancel_nolock(ctx, config)
+		return nil
+	}
+}
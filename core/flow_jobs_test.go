@@ -0,0 +1,128 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// jobTestBot returns a bot whose client reports sent texts and edits on channels,
+// so that tests can follow a job running in the background.
+func jobTestBot(t *testing.T) (*Bot, <-chan string, <-chan string) {
+	bot, client, _, _ := createTestBot()
+	sent, edits := make(chan string, 10), make(chan string, 10)
+	messageID := 100
+	client.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		msg, ok := c.(tgbotapi.MessageConfig)
+		if !ok {
+			t.Errorf("Unexpected message %T", c)
+		}
+		sent <- msg.Text
+		messageID++
+		return tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: msg.ChatID}}, nil
+	}
+	client.RequestFunc = func(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		if edit, ok := c.(tgbotapi.EditMessageTextConfig); ok {
+			edits <- edit.Text
+		}
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+	return bot, sent, edits
+}
+
+func expectText(t *testing.T, ch <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for %q", want)
+	}
+}
+
+func TestRunAsync_ReportsProgressAndAdvances(t *testing.T) {
+	bot, sent, edits := jobTestBot(t)
+	proceed, finish := make(chan struct{}), make(chan struct{})
+
+	flow, err := NewFlow("export").
+		Step("export").
+		Prompt("⏳ Exporting…").
+		RunAsync(func(ctx *Context) error {
+			<-proceed
+			if err := ctx.ReportProgress("⏳ Exporting… 50%"); err != nil {
+				t.Errorf("ReportProgress failed: %v", err)
+			}
+			<-finish
+			return nil
+		}).
+		Step("done").
+		Prompt("✅ Your export is ready.").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return CompleteFlow() }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	userID := int64(42)
+	if err := bot.StartFlowFor(userID, userID, "export", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	expectText(t, sent, "⏳ Exporting…")
+
+	// Messages sent while the job runs are not processed
+	bot.processUpdate(createPoolTestUpdate(userID, "are you done?"))
+	expectText(t, sent, defaultJobRunningMessage)
+
+	close(proceed)
+	expectText(t, edits, "⏳ Exporting… 50%")
+	close(finish)
+	expectText(t, sent, "✅ Your export is ready.")
+
+	if snapshot, ok := bot.GetUserFlow(userID, userID); !ok || snapshot.CurrentStep != "done" {
+		t.Errorf("Expected the flow to move to the next step, got %+v", snapshot)
+	}
+}
+
+func TestRunAsync_FailureCancelsFlow(t *testing.T) {
+	bot, sent, _ := jobTestBot(t)
+
+	cancelled := make(chan CancelReason, 1)
+	flow, err := NewFlow("export").
+		Step("export").
+		Prompt("⏳ Exporting…").
+		RunAsync(func(ctx *Context) error { return errors.New("storage unavailable") }).
+		OnCancel(func(ctx *Context) error {
+			cancelled <- ctx.CancelReason()
+			return nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	if err := bot.StartFlowFor(42, 42, "export", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	expectText(t, sent, "⏳ Exporting…")
+	expectText(t, sent, defaultErrorMessageCancel)
+	select {
+	case reason := <-cancelled:
+		if reason != CancelReasonError {
+			t.Errorf("Expected the flow to be cancelled by the error, got %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the flow to be cancelled")
+	}
+}
+
+func TestContext_ReportProgressOutsideJob(t *testing.T) {
+	if err := createTestContext().ReportProgress("50%"); err == nil {
+		t.Error("Expected ReportProgress outside a job to fail")
+	}
+}
@@ -0,0 +1,172 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultFlowStartLimitWindow is the window of a FlowStartLimit without one.
+const defaultFlowStartLimitWindow = time.Hour
+
+// defaultFlowStartLimitMessage is shown when a FlowStartLimit refuses a start.
+const defaultFlowStartLimitMessage = "⏳ You have started this too often. Please try again later."
+
+// ErrFlowStartLimited is returned by StartFlow when a flow's FlowStartLimit
+// refuses the start. The user has already been shown the refusal message.
+var ErrFlowStartLimited = errors.New("flow start limit reached")
+
+// FlowStartLimit caps how often a single user may start a flow, protecting
+// flows with expensive setups from being spammed. A limit of zero is not
+// enforced.
+type FlowStartLimit struct {
+	MaxStarts  int           // Starts allowed per user within Window
+	MaxAborted int           // Cancelled or restarted runs allowed per user within Window
+	Window     time.Duration // Period the limits apply to, one hour by default
+
+	// Message is shown when a start is refused. Templates receive "flow" and
+	// "retry_after", the time.Duration until the next start is allowed.
+	Message MessageSpec
+}
+
+// LimitStarts caps how often each user may start the flow. Once a user has
+// started it MaxStarts times, or abandoned it MaxAborted times by cancelling it
+// or starting it over, within the window, further starts are refused with the
+// limit's message and StartFlow returns an error wrapping ErrFlowStartLimited.
+// Completed runs do not count as aborted.
+//
+// Example:
+//
+//	teleflow.NewFlow("transfer").
+//		LimitStarts(teleflow.FlowStartLimit{
+//			MaxStarts:  20,
+//			MaxAborted: 5,
+//			Message:    "template:transfer_limited",
+//		})
+//
+//	teleflow.AddTemplate("transfer_limited", "⏳ Too many unfinished transfers. Try again in {{.retry_after}}.", teleflow.ParseModeNone)
+func (fb *FlowBuilder) LimitStarts(limit FlowStartLimit) *FlowBuilder {
+	if limit.Window <= 0 {
+		limit.Window = defaultFlowStartLimitWindow
+	}
+	fb.startLimit = &limit
+	return fb
+}
+
+// flowLimitKey identifies the start history of a user in a flow.
+type flowLimitKey struct {
+	Flow   string
+	UserID int64
+}
+
+// flowLimitHistory holds the recent starts and aborts of a user in a flow.
+type flowLimitHistory struct {
+	starts []time.Time
+	aborts []time.Time
+}
+
+// flowStartTracker records flow starts and aborts for flows with a FlowStartLimit.
+type flowStartTracker struct {
+	mu      sync.Mutex
+	history map[flowLimitKey]*flowLimitHistory
+}
+
+// allow records a start of the flow by the user if its limit permits it. When
+// it does not, the returned duration tells when the next start is allowed.
+func (t *flowStartTracker) allow(flow *Flow, userID int64, now time.Time) (bool, time.Duration) {
+	limit := flow.StartLimit
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	history := t.entry(flowLimitKey{Flow: flow.Name, UserID: userID})
+	cutoff := now.Add(-limit.Window)
+	history.starts = pruneTimestamps(history.starts, cutoff)
+	history.aborts = pruneTimestamps(history.aborts, cutoff)
+
+	var retryAfter time.Duration
+	if limit.MaxStarts > 0 && len(history.starts) >= limit.MaxStarts {
+		retryAfter = history.starts[len(history.starts)-limit.MaxStarts].Sub(cutoff)
+	}
+	if limit.MaxAborted > 0 && len(history.aborts) >= limit.MaxAborted {
+		if wait := history.aborts[len(history.aborts)-limit.MaxAborted].Sub(cutoff); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+	history.starts = append(history.starts, now)
+	return true, 0
+}
+
+// aborted records that the user abandoned a run of the flow.
+func (t *flowStartTracker) aborted(flow *Flow, userID int64, now time.Time) {
+	if flow == nil || flow.StartLimit == nil || flow.StartLimit.MaxAborted <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	history := t.entry(flowLimitKey{Flow: flow.Name, UserID: userID})
+	history.aborts = append(pruneTimestamps(history.aborts, now.Add(-flow.StartLimit.Window)), now)
+}
+
+// entry returns the history stored under key, creating it if needed. The
+// tracker must be locked.
+func (t *flowStartTracker) entry(key flowLimitKey) *flowLimitHistory {
+	history := t.history[key]
+	if history == nil {
+		history = &flowLimitHistory{}
+		if t.history == nil {
+			t.history = make(map[flowLimitKey]*flowLimitHistory)
+		}
+		t.history[key] = history
+	}
+	return history
+}
+
+// forget drops the start history of a user.
+func (t *flowStartTracker) forget(userID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.history {
+		if key.UserID == userID {
+			delete(t.history, key)
+		}
+	}
+}
+
+// checkStartLimit records a start of the flow, or shows the limit's message and
+// returns an error wrapping ErrFlowStartLimited when the user has reached it.
+func (fm *flowManager) checkStartLimit(ctx *Context, flow *Flow) error {
+	if flow.StartLimit == nil {
+		return nil
+	}
+	allowed, retryAfter := fm.startLimits.allow(flow, ctx.UserID(), fm.clock.Now())
+	if allowed {
+		return nil
+	}
+	retryAfter = retryAfter.Round(time.Second)
+	log.Printf("[FLOW_START_LIMITED] Flow: %s, User: %d, RetryAfter: %s", flow.Name, ctx.UserID(), retryAfter)
+
+	message := flow.StartLimit.Message
+	if message == nil || message == "" {
+		message = defaultFlowStartLimitMessage
+	}
+	sendErr := fm.promptSender.ComposeAndSend(ctx, &PromptConfig{
+		Message:      message,
+		TemplateData: map[string]interface{}{"flow": flow.Name, "retry_after": retryAfter},
+	})
+	if sendErr != nil {
+		log.Printf("[FLOW_ERROR_NOTIFY_FAILED] Failed to notify user %d: %v", ctx.UserID(), sendErr)
+	}
+	return fmt.Errorf("%w: %s", ErrFlowStartLimited, flow.Name)
+}
+
+// isFlowRefusal reports whether err is a flow start refused after the user was
+// already told why, which needs neither a generic error reply nor a retry.
+func isFlowRefusal(err error) bool {
+	return errors.Is(err, ErrFlowPermissionDenied) || errors.Is(err, ErrFlowStartLimited) || errors.Is(err, ErrFlowDisabled)
+}
@@ -0,0 +1,118 @@
+package teleflow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func limitedTestFlow(t *testing.T, limit FlowStartLimit) *Flow {
+	t.Helper()
+	flow, err := NewFlow("transfer").
+		LimitStarts(limit).
+		Step("amount").
+		Prompt("How much?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return CompleteFlow()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	return flow
+}
+
+func TestLimitStarts_MaxStarts(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bot, client, _, _ := createTestBot(WithClock(clock))
+	bot.RegisterFlow(limitedTestFlow(t, FlowStartLimit{MaxStarts: 2, Message: "Too many transfers"}))
+	userID := int64(42)
+
+	var sent []string
+	client.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+			sent = append(sent, msg.Text)
+		}
+		return tgbotapi.Message{MessageID: 123}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := bot.StartFlowFor(userID, userID, "transfer", nil); err != nil {
+			t.Fatalf("Start %d failed: %v", i+1, err)
+		}
+		bot.processUpdate(createPoolTestUpdate(userID, "10"))
+		clock.now = clock.now.Add(10 * time.Minute)
+	}
+
+	err := bot.StartFlowFor(userID, userID, "transfer", nil)
+	if !errors.Is(err, ErrFlowStartLimited) {
+		t.Fatalf("Expected ErrFlowStartLimited, got %v", err)
+	}
+	if _, inFlow := bot.GetUserFlow(userID, userID); inFlow {
+		t.Error("Expected the refused flow not to start")
+	}
+	if last := sent[len(sent)-1]; last != "Too many transfers" {
+		t.Errorf("Expected the refusal message, got %q", last)
+	}
+
+	clock.now = clock.now.Add(40 * time.Minute)
+	if err := bot.StartFlowFor(userID, userID, "transfer", nil); err != nil {
+		t.Errorf("Expected a start once the window moved on, got %v", err)
+	}
+	if err := bot.StartFlowFor(userID+1, userID+1, "transfer", nil); err != nil {
+		t.Errorf("Expected other users not to be limited, got %v", err)
+	}
+}
+
+func TestLimitStarts_MaxAborted(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bot, client, _, _ := createTestBot(WithClock(clock))
+	bot.RegisterFlow(limitedTestFlow(t, FlowStartLimit{MaxAborted: 2}))
+	userID := int64(42)
+
+	var sent []string
+	client.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+			sent = append(sent, msg.Text)
+		}
+		return tgbotapi.Message{MessageID: 123}, nil
+	}
+
+	// Completed runs do not count
+	for i := 0; i < 3; i++ {
+		if err := bot.StartFlowFor(userID, userID, "transfer", nil); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		bot.processUpdate(createPoolTestUpdate(userID, "10"))
+	}
+
+	// One run cancelled, one started over
+	if err := bot.StartFlowFor(userID, userID, "transfer", nil); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	bot.processUpdate(createPoolTestUpdate(userID, "/cancel"))
+	if err := bot.StartFlowFor(userID, userID, "transfer", nil); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := bot.StartFlowFor(userID, userID, "transfer", nil); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+
+	err := bot.StartFlowFor(userID, userID, "transfer", nil)
+	if !errors.Is(err, ErrFlowStartLimited) {
+		t.Fatalf("Expected ErrFlowStartLimited after two aborted runs, got %v", err)
+	}
+	if last := sent[len(sent)-1]; !strings.Contains(last, "too often") {
+		t.Errorf("Expected the default refusal message, got %q", last)
+	}
+
+	if err := bot.PurgeUserData(userID); err != nil {
+		t.Fatalf("PurgeUserData failed: %v", err)
+	}
+	if err := bot.StartFlowFor(userID, userID, "transfer", nil); err != nil {
+		t.Errorf("Expected purging the user to reset the limit, got %v", err)
+	}
+}
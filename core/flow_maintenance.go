@@ -0,0 +1,94 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// defaultFlowDisabledMessage is shown when a disabled flow is started without a
+// message of its own.
+const defaultFlowDisabledMessage = "🛠 This feature is temporarily unavailable. Please try again later."
+
+// ErrFlowDisabled is returned by StartFlow when the flow was disabled with
+// Bot.DisableFlow. The user has already been shown the maintenance message.
+var ErrFlowDisabled = errors.New("flow disabled")
+
+// disabledFlows holds the flows closed for new entries, with their messages.
+type disabledFlows struct {
+	mu       sync.RWMutex
+	messages map[string]MessageSpec
+}
+
+// DisableFlow stops new entries into a flow, e.g. while the backend behind it is
+// down. Users starting it are shown message instead, which may be a string,
+// template reference or function; templates receive "flow" as data. A nil
+// message shows a generic maintenance notice. Users already inside the flow can
+// finish it. The flow does not have to be registered yet.
+//
+// Example:
+//
+//	bot.DisableFlow("transfer", "template:transfer_maintenance")
+//	defer bot.EnableFlow("transfer")
+func (b *Bot) DisableFlow(name string, message MessageSpec) {
+	if message == nil || message == "" {
+		message = defaultFlowDisabledMessage
+	}
+	fm := b.flowManager
+	fm.disabled.mu.Lock()
+	defer fm.disabled.mu.Unlock()
+	if fm.disabled.messages == nil {
+		fm.disabled.messages = make(map[string]MessageSpec)
+	}
+	fm.disabled.messages[name] = message
+	log.Printf("[FLOW_DISABLED] Flow: %s", name)
+}
+
+// EnableFlow opens a flow disabled with DisableFlow for new entries again.
+func (b *Bot) EnableFlow(name string) {
+	fm := b.flowManager
+	fm.disabled.mu.Lock()
+	defer fm.disabled.mu.Unlock()
+	if _, ok := fm.disabled.messages[name]; ok {
+		delete(fm.disabled.messages, name)
+		log.Printf("[FLOW_ENABLED] Flow: %s", name)
+	}
+}
+
+// IsFlowDisabled reports whether a flow is closed for new entries.
+func (b *Bot) IsFlowDisabled(name string) bool {
+	_, disabled := b.flowManager.disabledMessage(name)
+	return disabled
+}
+
+// disabledMessage returns the maintenance message of a disabled flow.
+func (fm *flowManager) disabledMessage(name string) (MessageSpec, bool) {
+	fm.disabled.mu.RLock()
+	defer fm.disabled.mu.RUnlock()
+	message, ok := fm.disabled.messages[name]
+	return message, ok
+}
+
+// checkFlowEnabled returns an error wrapping ErrFlowDisabled if the flow is
+// disabled, or turned off for the user by the FeatureGate, after showing its
+// maintenance message when there is a context.
+func (fm *flowManager) checkFlowEnabled(ctx *Context, flow *Flow) error {
+	message, disabled := fm.disabledMessage(flow.Name)
+	if !disabled && ctx != nil && !featureEnabled(fm.featureGate, "flow:"+flow.Name, ctx.UserID()) {
+		message, disabled = defaultFlowDisabledMessage, true
+	}
+	if !disabled {
+		return nil
+	}
+	if ctx != nil {
+		sendErr := fm.promptSender.ComposeAndSend(ctx, &PromptConfig{
+			Message:      message,
+			TemplateData: map[string]interface{}{"flow": flow.Name},
+		})
+		if sendErr != nil {
+			log.Printf("[FLOW_ERROR_NOTIFY_FAILED] Failed to notify user %d: %v", ctx.UserID(), sendErr)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrFlowDisabled, flow.Name)
+}
@@ -0,0 +1,69 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDisableFlow(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+	flow, err := NewFlow("transfer").
+		Step("amount").
+		Prompt("How much?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return CompleteFlow()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	bot.HandleCommand("transfer", func(ctx *Context, command, args string) error {
+		return ctx.StartFlow("transfer")
+	})
+
+	var sent []string
+	client.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+			sent = append(sent, msg.Text)
+		}
+		return tgbotapi.Message{MessageID: 123}, nil
+	}
+
+	// A user already inside the flow can finish it
+	if err := bot.StartFlowFor(1, 1, "transfer", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	bot.DisableFlow("transfer", "Transfers are down for maintenance")
+	if !bot.IsFlowDisabled("transfer") {
+		t.Fatal("Expected the flow to be disabled")
+	}
+	completed := false
+	flow.OnComplete = func(ctx *Context) error {
+		completed = true
+		return nil
+	}
+	bot.processUpdate(createPoolTestUpdate(1, "10"))
+	if !completed {
+		t.Error("Expected the running flow to complete")
+	}
+
+	sent = nil
+	bot.processUpdate(createCommandUpdate("transfer", "/transfer"))
+	if len(sent) != 1 || sent[0] != "Transfers are down for maintenance" {
+		t.Errorf("Expected only the maintenance message, got %v", sent)
+	}
+	if _, inFlow := bot.GetUserFlow(42, 42); inFlow {
+		t.Error("Expected the disabled flow not to start")
+	}
+	if err := bot.StartFlowFor(3, 3, "transfer", nil); !errors.Is(err, ErrFlowDisabled) {
+		t.Errorf("Expected ErrFlowDisabled, got %v", err)
+	}
+
+	bot.EnableFlow("transfer")
+	if err := bot.StartFlowFor(3, 3, "transfer", nil); err != nil {
+		t.Errorf("Expected the enabled flow to start, got %v", err)
+	}
+}
@@ -63,8 +63,8 @@ type mockPromptKeyboardActions struct {
 	buildKeyboardCalls   []KeyboardFunc
 	buildKeyboardReturns []interface{}
 	buildKeyboardError   error
-	callbackData         map[int64]map[string]interface{}
-	cleanupUserCalls     []int64
+	callbackData         map[flowKey]map[string]interface{}
+	cleanupUserCalls     []flowKey
 	mu                   sync.Mutex
 }
 
@@ -80,10 +80,10 @@ func (m *mockPromptKeyboardActions) BuildKeyboard(ctx *Context, keyboardFunc Key
 	return nil, m.buildKeyboardError
 }
 
-func (m *mockPromptKeyboardActions) GetCallbackData(userID int64, uuid string) (interface{}, bool) {
+func (m *mockPromptKeyboardActions) GetCallbackData(userID, chatID int64, uuid string) (interface{}, bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if userMappings, exists := m.callbackData[userID]; exists {
+	if userMappings, exists := m.callbackData[flowKey{UserID: userID, ChatID: chatID}]; exists {
 		if data, found := userMappings[uuid]; found {
 			return data, true
 		}
@@ -91,23 +91,25 @@ func (m *mockPromptKeyboardActions) GetCallbackData(userID int64, uuid string) (
 	return nil, false
 }
 
-func (m *mockPromptKeyboardActions) CleanupUserMappings(userID int64) {
+func (m *mockPromptKeyboardActions) CleanupUserMappings(userID, chatID int64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.cleanupUserCalls = append(m.cleanupUserCalls, userID)
-	delete(m.callbackData, userID)
+	key := flowKey{UserID: userID, ChatID: chatID}
+	m.cleanupUserCalls = append(m.cleanupUserCalls, key)
+	delete(m.callbackData, key)
 }
 
 func (m *mockPromptKeyboardActions) setCallbackData(userID int64, uuid string, data interface{}) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.callbackData == nil {
-		m.callbackData = make(map[int64]map[string]interface{})
+		m.callbackData = make(map[flowKey]map[string]interface{})
 	}
-	if m.callbackData[userID] == nil {
-		m.callbackData[userID] = make(map[string]interface{})
+	key := flowKey{UserID: userID}
+	if m.callbackData[key] == nil {
+		m.callbackData[key] = make(map[string]interface{})
 	}
-	m.callbackData[userID][uuid] = data
+	m.callbackData[key][uuid] = data
 }
 
 // reset method removed as it's unused
@@ -141,6 +143,18 @@ func (m *mockMessageCleaner) DeleteMessage(ctx *Context, messageID int) error {
 	return m.deleteMessageError
 }
 
+func (m *mockMessageCleaner) DeleteMessages(ctx *Context, messageIDs []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, messageID := range messageIDs {
+		m.deleteMessageCalls = append(m.deleteMessageCalls, messageCall{
+			userID:    ctx.UserID(),
+			messageID: messageID,
+		})
+	}
+	return m.deleteMessageError
+}
+
 func (m *mockMessageCleaner) EditMessageReplyMarkup(ctx *Context, messageID int, replyMarkup interface{}) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -183,7 +197,7 @@ func createTestFlowManager() (*flowManager, *mockPromptSender, *mockPromptKeyboa
 	}
 
 	mockSender := &mockPromptSender{}
-	mockKeyboard := &mockPromptKeyboardActions{callbackData: make(map[int64]map[string]interface{})}
+	mockKeyboard := &mockPromptKeyboardActions{callbackData: make(map[flowKey]map[string]interface{})}
 	mockCleaner := &mockMessageCleaner{}
 
 	fm := newFlowManager(config, mockSender, mockKeyboard, mockCleaner)
@@ -325,8 +339,8 @@ func TestNewFlowManager(t *testing.T) {
 		t.Error("flows map not initialized")
 	}
 
-	if fm.userFlows == nil {
-		t.Error("userFlows map not initialized")
+	if len(fm.shards) != flowStateShards {
+		t.Errorf("Expected %d flow state shards, got %d", flowStateShards, len(fm.shards))
 	}
 
 	if fm.flowConfig != config {
@@ -381,7 +395,7 @@ func assertError(t *testing.T, err error, expectedError bool, errorContains stri
 
 func assertFlowStartedSuccessfully(t *testing.T, fm *flowManager, mockSender *mockPromptSender, userID int64, ctxProvided bool) {
 	t.Helper()
-	if !fm.isUserInFlow(userID) {
+	if !fm.isUserInFlow(userID, userID) {
 		t.Error("User should be in flow after startFlow")
 	}
 
@@ -434,7 +448,7 @@ func TestStartFlow(t *testing.T) {
 
 			mockSender.reset()
 
-			err := fm.startFlow(tt.userID, tt.flowName, tt.ctx)
+			err := fm.startFlow(tt.userID, tt.userID, tt.flowName, tt.ctx)
 
 			assertError(t, err, tt.expectedError, tt.errorContains)
 
@@ -457,7 +471,7 @@ func TestStartFlowWithEmptySteps(t *testing.T) {
 	fm.registerFlow(emptyFlow)
 
 	ctx := createFlowTestContext(12345, "", fm)
-	err := fm.startFlow(12345, "empty-flow", ctx)
+	err := fm.startFlow(12345, 12345, "empty-flow", ctx)
 
 	if err == nil {
 		t.Error("Expected error for flow with no steps")
@@ -476,24 +490,24 @@ func TestIsUserInFlow(t *testing.T) {
 	userID := int64(12345)
 
 	// User not in flow initially
-	if fm.isUserInFlow(userID) {
+	if fm.isUserInFlow(userID, userID) {
 		t.Error("User should not be in flow initially")
 	}
 
 	// Start flow
 	ctx := createFlowTestContext(userID, "", fm)
-	err := fm.startFlow(userID, "test-flow", ctx)
+	err := fm.startFlow(userID, userID, "test-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
 
 	// User should be in flow now
-	if !fm.isUserInFlow(userID) {
+	if !fm.isUserInFlow(userID, userID) {
 		t.Error("User should be in flow after startFlow")
 	}
 
 	// Different user should not be in flow
-	if fm.isUserInFlow(67890) {
+	if fm.isUserInFlow(67890, 67890) {
 		t.Error("Different user should not be in flow")
 	}
 }
@@ -507,21 +521,21 @@ func TestCancelFlow(t *testing.T) {
 	ctx := createFlowTestContext(userID, "", fm)
 
 	// Start flow
-	err := fm.startFlow(userID, "test-flow", ctx)
+	err := fm.startFlow(userID, userID, "test-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
 
 	// Verify user is in flow
-	if !fm.isUserInFlow(userID) {
+	if !fm.isUserInFlow(userID, userID) {
 		t.Error("User should be in flow")
 	}
 
 	// Cancel flow
-	fm.cancelFlow(userID)
+	fm.cancelFlow(userID, userID, ctx)
 
 	// Verify user is no longer in flow
-	if fm.isUserInFlow(userID) {
+	if fm.isUserInFlow(userID, userID) {
 		t.Error("User should not be in flow after cancellation")
 	}
 }
@@ -535,25 +549,25 @@ func TestSetUserFlowData(t *testing.T) {
 	ctx := createFlowTestContext(userID, "", fm)
 
 	// Test setting data for user not in flow
-	err := fm.setUserFlowData(userID, "key1", "value1")
+	err := fm.setUserFlowData(userID, userID, "key1", "value1")
 	if err == nil {
 		t.Error("Expected error when setting data for user not in flow")
 	}
 
 	// Start flow
-	err = fm.startFlow(userID, "test-flow", ctx)
+	err = fm.startFlow(userID, userID, "test-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
 
 	// Test setting data for user in flow
-	err = fm.setUserFlowData(userID, "key1", "value1")
+	err = fm.setUserFlowData(userID, userID, "key1", "value1")
 	if err != nil {
 		t.Errorf("Unexpected error setting flow data: %v", err)
 	}
 
 	// Verify data was set
-	value, exists := fm.getUserFlowData(userID, "key1")
+	value, exists := fm.getUserFlowData(userID, userID, "key1")
 	if !exists {
 		t.Error("Flow data should exist after setting")
 	}
@@ -570,7 +584,7 @@ func TestGetUserFlowData(t *testing.T) {
 	userID := int64(12345)
 
 	// Test getting data for user not in flow
-	value, exists := fm.getUserFlowData(userID, "key1")
+	value, exists := fm.getUserFlowData(userID, userID, "key1")
 	if exists {
 		t.Error("Should not find data for user not in flow")
 	}
@@ -580,24 +594,24 @@ func TestGetUserFlowData(t *testing.T) {
 
 	// Start flow
 	ctx := createFlowTestContext(userID, "", fm)
-	err := fm.startFlow(userID, "test-flow", ctx)
+	err := fm.startFlow(userID, userID, "test-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
 
 	// Test getting non-existent key
-	_, exists = fm.getUserFlowData(userID, "non-existent")
+	_, exists = fm.getUserFlowData(userID, userID, "non-existent")
 	if exists {
 		t.Error("Should not find non-existent key")
 	}
 
 	// Set and get data
-	err = fm.setUserFlowData(userID, "testkey", "testvalue")
+	err = fm.setUserFlowData(userID, userID, "testkey", "testvalue")
 	if err != nil {
 		t.Fatalf("Failed to set flow data: %v", err)
 	}
 
-	value, exists = fm.getUserFlowData(userID, "testkey")
+	value, exists = fm.getUserFlowData(userID, userID, "testkey")
 	if !exists {
 		t.Error("Should find existing key")
 	}
@@ -630,7 +644,7 @@ func TestHandleUpdateUserInFlow(t *testing.T) {
 	ctx := createFlowTestContext(userID, "", fm)
 
 	// Start flow
-	err := fm.startFlow(userID, "test-flow", ctx)
+	err := fm.startFlow(userID, userID, "test-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
@@ -687,7 +701,7 @@ func TestHandleUpdateWithCallback(t *testing.T) {
 	ctx := createFlowTestContext(userID, "", fm)
 
 	// Start flow
-	err := fm.startFlow(userID, "callback-flow", ctx)
+	err := fm.startFlow(userID, userID, "callback-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
@@ -710,7 +724,7 @@ func TestHandleUpdateWithCallback(t *testing.T) {
 	}
 
 	// User should no longer be in flow (completed)
-	if fm.isUserInFlow(userID) {
+	if fm.isUserInFlow(userID, userID) {
 		t.Error("User should not be in flow after completion")
 	}
 }
@@ -721,15 +735,16 @@ func TestHandleUpdateFlowNotFound(t *testing.T) {
 	userID := int64(12345)
 
 	// Manually add user flow state with non-existent flow
-	fm.muUserFlows.Lock()
-	fm.userFlows[userID] = &userFlowState{
+	locks := fm.stateLocks(userID, userID)
+	locks.Lock()
+	fm.putState_nolock(flowKey{UserID: userID}, &userFlowState{
 		FlowName:    "non-existent-flow",
 		CurrentStep: "step1",
 		Data:        make(map[string]interface{}),
 		StartedAt:   time.Now(),
 		LastActive:  time.Now(),
-	}
-	fm.muUserFlows.Unlock()
+	})
+	locks.Unlock()
 
 	ctx := createFlowTestContext(userID, "test", fm)
 	handled, err := fm.HandleUpdate(ctx)
@@ -743,7 +758,7 @@ func TestHandleUpdateFlowNotFound(t *testing.T) {
 	}
 
 	// User should be removed from flow
-	if fm.isUserInFlow(userID) {
+	if fm.isUserInFlow(userID, userID) {
 		t.Error("User should be removed from flow when flow not found")
 	}
 }
@@ -756,15 +771,16 @@ func TestHandleUpdateStepNotFound(t *testing.T) {
 	userID := int64(12345)
 
 	// Manually add user flow state with non-existent step
-	fm.muUserFlows.Lock()
-	fm.userFlows[userID] = &userFlowState{
+	locks := fm.stateLocks(userID, userID)
+	locks.Lock()
+	fm.putState_nolock(flowKey{UserID: userID}, &userFlowState{
 		FlowName:    "test-flow",
 		CurrentStep: "non-existent-step",
 		Data:        make(map[string]interface{}),
 		StartedAt:   time.Now(),
 		LastActive:  time.Now(),
-	}
-	fm.muUserFlows.Unlock()
+	})
+	locks.Unlock()
 
 	ctx := createFlowTestContext(userID, "test", fm)
 	handled, err := fm.HandleUpdate(ctx)
@@ -778,7 +794,7 @@ func TestHandleUpdateStepNotFound(t *testing.T) {
 	}
 
 	// User should be removed from flow
-	if fm.isUserInFlow(userID) {
+	if fm.isUserInFlow(userID, userID) {
 		t.Error("User should be removed from flow when step not found")
 	}
 }
@@ -801,7 +817,7 @@ func TestConcurrentAccess(t *testing.T) {
 
 		// Start flow for each user
 		ctx := createFlowTestContext(userID, "", fm)
-		err := fm.startFlow(userID, "test-flow", ctx)
+		err := fm.startFlow(userID, userID, "test-flow", ctx)
 		if err != nil {
 			t.Fatalf("Failed to start flow for user %d: %v", userID, err)
 		}
@@ -811,7 +827,7 @@ func TestConcurrentAccess(t *testing.T) {
 			defer wg.Done()
 			key := fmt.Sprintf("key%d", index)
 			value := fmt.Sprintf("value%d", index)
-			if err := fm.setUserFlowData(uid, key, value); err != nil {
+			if err := fm.setUserFlowData(uid, uid, key, value); err != nil {
 				errors <- err
 			}
 		}(userID, i)
@@ -822,7 +838,7 @@ func TestConcurrentAccess(t *testing.T) {
 			key := fmt.Sprintf("key%d", index)
 			// Add small delay to allow setter to run first
 			time.Sleep(time.Millisecond)
-			_, _ = fm.getUserFlowData(uid, key)
+			_, _ = fm.getUserFlowData(uid, uid, key)
 			// Getting data shouldn't error, even if key doesn't exist yet
 		}(userID, i)
 	}
@@ -863,7 +879,7 @@ func TestMessageCleanerInteractions(t *testing.T) {
 	ctx := createFlowTestContext(userID, "", fm)
 
 	// Start flow
-	err := fm.startFlow(userID, "cleanup-flow", ctx)
+	err := fm.startFlow(userID, userID, "cleanup-flow", ctx)
 	if err != nil {
 		t.Fatalf("Failed to start flow: %v", err)
 	}
@@ -893,6 +909,22 @@ func TestMessageCleanerInteractions(t *testing.T) {
 	}
 }
 
+func TestDeletePreviousMessages_WithoutBatchCleaner(t *testing.T) {
+	fm, _, _, mockCleaner := createTestFlowManager()
+	// Hide the mock's DeleteMessages so only the MessageCleaner methods remain
+	fm.messageCleaner = struct{ MessageCleaner }{mockCleaner}
+
+	ctx := createFlowTestContext(12345, "", fm)
+	if err := fm.deletePreviousMessages(ctx, []int{455, 456}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	deleteCalls := mockCleaner.getDeleteMessageCalls()
+	if len(deleteCalls) != 2 || deleteCalls[0].messageID != 455 || deleteCalls[1].messageID != 456 {
+		t.Errorf("Expected messages to be deleted one at a time, got %+v", deleteCalls)
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -940,11 +972,11 @@ func TestErrorHandling(t *testing.T) {
 			}
 
 			// Start flow (this should trigger the error)
-			_ = fm.startFlow(userID, "test-flow", ctx)
+			_ = fm.startFlow(userID, userID, "test-flow", ctx)
 
 			// Check if user is still in flow based on error strategy
-			if fm.isUserInFlow(userID) != tt.expectUserInFlow {
-				t.Errorf("Expected user in flow: %v, got: %v", tt.expectUserInFlow, fm.isUserInFlow(userID))
+			if fm.isUserInFlow(userID, userID) != tt.expectUserInFlow {
+				t.Errorf("Expected user in flow: %v, got: %v", tt.expectUserInFlow, fm.isUserInFlow(userID, userID))
 			}
 
 			// Reset sender error for cleanup
@@ -953,4 +985,589 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
-// Helper function
+func TestFlowManager_ChatScopedFlow(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+
+	flow := createTestFlow()
+	flow.Scope = FlowScopeChat
+	fm.registerFlow(flow)
+
+	groupID := int64(-100123)
+	starter := int64(111)
+	member := int64(222)
+
+	startCtx := createFlowTestContext(starter, "", fm)
+	startCtx.chatID = groupID
+	if err := fm.startFlow(starter, groupID, "test-flow", startCtx); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	if !fm.isUserInFlow(member, groupID) {
+		t.Error("Expected any member of the chat to see the shared flow")
+	}
+	if fm.isUserInFlow(starter, starter) {
+		t.Error("Chat-scoped flow should not follow the starter into a private chat")
+	}
+
+	memberCtx := createFlowTestContext(member, "Alice", fm)
+	memberCtx.chatID = groupID
+	handled, err := fm.HandleUpdate(memberCtx)
+	if err != nil || !handled {
+		t.Fatalf("Expected member input to be handled, got handled=%v err=%v", handled, err)
+	}
+
+	if value, ok := fm.getUserFlowData(starter, groupID, "name"); !ok || value != "Alice" {
+		t.Errorf("Expected shared flow data name=Alice, got %v (exists: %v)", value, ok)
+	}
+	if state, _ := fm.getState_nolock(flowKey{ChatID: groupID}); state == nil || state.CurrentStep != "step2" {
+		t.Errorf("Expected shared flow to advance to step2, got %+v", state)
+	}
+}
+
+func TestBot_UserChatFlowsKeepButtonsPerChat(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithFlowConfig(FlowConfig{ExitCommands: []string{"/cancel"}}))
+	var clicked interface{}
+	flow, err := NewFlow("vote").
+		Scope(FlowScopeUserChat).
+		Step("choose").
+		Prompt("Your vote?").
+		WithPromptKeyboard(func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("Yes", "yes")
+		}).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			if click != nil {
+				clicked = click.Data
+			}
+			return CompleteFlow()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	keyboards := bot.promptKeyboardHandler.(*PromptKeyboardHandler)
+
+	userID, chatA, chatB := int64(42), int64(-100), int64(-200)
+	for _, chatID := range []int64{chatA, chatB} {
+		if err := bot.StartFlowFor(userID, chatID, "vote", nil); err != nil {
+			t.Fatalf("StartFlowFor failed: %v", err)
+		}
+	}
+
+	// Leaving the flow in chat A must not touch the buttons of chat B
+	bot.processUpdate(createCaptchaAnswerUpdate(chatA, userID, "/cancel"))
+	if _, ok := keyboards.uuidMappings[flowKey{UserID: userID, ChatID: chatA}]; ok {
+		t.Error("Expected the mappings of the cancelled flow to be removed")
+	}
+	var uuid string
+	for id := range keyboards.uuidMappings[flowKey{UserID: userID, ChatID: chatB}] {
+		uuid = id
+	}
+	if uuid == "" {
+		t.Fatal("Expected the flow in chat B to keep its callback mappings")
+	}
+
+	bot.processUpdate(tgbotapi.Update{
+		UpdateID: 3,
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:      "cb",
+			From:    &tgbotapi.User{ID: userID},
+			Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: chatB, Type: "supergroup"}},
+			Data:    uuid,
+		},
+	})
+	if clicked != "yes" {
+		t.Errorf("Expected the click in chat B to resolve to %q, got %v", "yes", clicked)
+	}
+	if _, inFlow := bot.GetUserFlow(userID, chatB); inFlow {
+		t.Error("Expected the flow in chat B to complete")
+	}
+}
+
+func TestFlowManager_InputFromAdminsCachesLookups(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+
+	flow := createTestFlow()
+	flow.Scope = FlowScopeChat
+	flow.InputPolicy = InputFromAdmins
+	fm.registerFlow(flow)
+
+	groupID := int64(-100123)
+	startCtx := createFlowTestContext(111, "", fm)
+	startCtx.chatID = groupID
+	if err := fm.startFlow(111, groupID, "test-flow", startCtx); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	client := &contextMockTelegramClient{
+		RequestFunc: func(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+			return &tgbotapi.APIResponse{Ok: true, Result: []byte(`{"status":"member","user":{"id":222}}`)}, nil
+		},
+	}
+	for i := 0; i < 3; i++ {
+		memberCtx := createFlowTestContext(222, "Alice", fm)
+		memberCtx.chatID = groupID
+		memberCtx.telegramClient = client
+		if handled, err := fm.HandleUpdate(memberCtx); handled || err != nil {
+			t.Fatalf("Expected input from a non-admin to pass through, got handled=%v err=%v", handled, err)
+		}
+	}
+
+	if got := countRequests[tgbotapi.GetChatMemberConfig](client.RequestCalls); got != 1 {
+		t.Errorf("Expected one admin lookup for repeated messages, got %d", got)
+	}
+}
+
+func TestFlowManager_RetryMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name         string
+		onMaxRetries MaxRetriesHandler
+		expectInFlow bool
+		expectStep   string
+	}{
+		{
+			name:         "cancels without handler",
+			expectInFlow: false,
+		},
+		{
+			name: "handler redirects to another step",
+			onMaxRetries: func(ctx *Context, stepName string) ProcessResult {
+				return GoToStep("step2")
+			},
+			expectInFlow: true,
+			expectStep:   "step2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, _, _, _ := createTestFlowManager()
+
+			flow := createTestFlow()
+			flow.Steps["step1"].ProcessFunc = func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+				return Retry().WithMaxAttempts(2)
+			}
+			flow.OnMaxRetries = tt.onMaxRetries
+			fm.registerFlow(flow)
+
+			userID := int64(12345)
+			if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+				t.Fatalf("Failed to start flow: %v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if _, err := fm.HandleUpdate(createFlowTestContext(userID, "bad", fm)); err != nil {
+					t.Fatalf("HandleUpdate failed: %v", err)
+				}
+			}
+
+			if fm.isUserInFlow(userID, userID) != tt.expectInFlow {
+				t.Fatalf("Expected user in flow: %v", tt.expectInFlow)
+			}
+			if tt.expectInFlow {
+				state, _ := fm.getState_nolock(flowKey{UserID: userID})
+				if state.CurrentStep != tt.expectStep {
+					t.Errorf("Expected step %s, got %s", tt.expectStep, state.CurrentStep)
+				}
+				if state.RetryCount != 0 {
+					t.Errorf("Expected retry counter to reset, got %d", state.RetryCount)
+				}
+			}
+		})
+	}
+}
+
+func createKeyboardCleanupBot(strip bool) (*Bot, *MockTelegramClient) {
+	bot, mockClient, _, _ := createTestBot(WithFlowConfig(FlowConfig{
+		ExitCommands:        []string{"/cancel"},
+		ExitMessage:         "Cancelled",
+		StripKeyboardsOnEnd: strip,
+	}))
+	nextID := 100
+	mockClient.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		nextID++
+		return tgbotapi.Message{MessageID: nextID}, nil
+	}
+
+	keyboard := func(ctx *Context) *PromptKeyboardBuilder {
+		return NewPromptKeyboard().ButtonCallback("Yes", "yes")
+	}
+	flow, _ := NewFlow("survey").
+		Step("first").
+		Prompt("First?").
+		WithPromptKeyboard(keyboard).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return NextStep() }).
+		Step("second").
+		Prompt("Second?").
+		WithPromptKeyboard(keyboard).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return CompleteFlow() }).
+		Build()
+	bot.RegisterFlow(flow)
+	return bot, mockClient
+}
+
+func strippedMessageIDs(mockClient *MockTelegramClient) []int {
+	var ids []int
+	for _, c := range mockClient.RequestCalls {
+		if edit, ok := c.(tgbotapi.EditMessageReplyMarkupConfig); ok {
+			ids = append(ids, edit.MessageID)
+		}
+	}
+	return ids
+}
+
+func TestFlowKeyboardCleanup_OnCancel(t *testing.T) {
+	bot, mockClient := createKeyboardCleanupBot(true)
+	newCtx := func(text string) *Context {
+		return newContext(createCaptchaAnswerUpdate(42, 42, text), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	}
+
+	if err := newCtx("").StartFlow("survey"); err != nil {
+		t.Fatalf("StartFlow failed: %v", err)
+	}
+	if _, err := bot.flowManager.HandleUpdate(newCtx("go on")); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	newCtx("").CancelFlow()
+
+	ids := strippedMessageIDs(mockClient)
+	if len(ids) != 2 || ids[0] != 101 || ids[1] != 102 {
+		t.Errorf("Expected keyboards of messages 101 and 102 to be removed, got %v", ids)
+	}
+}
+
+func TestFlowKeyboardCleanup_OnComplete(t *testing.T) {
+	bot, mockClient := createKeyboardCleanupBot(true)
+	newCtx := func(text string) *Context {
+		return newContext(createCaptchaAnswerUpdate(42, 42, text), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	}
+
+	_ = newCtx("").StartFlow("survey")
+	locks := bot.flowManager.stateLocks(42, 42)
+	locks.Lock()
+	_, state, _ := bot.flowManager.lookupState_nolock(42, 42)
+	state.CurrentStep = "second"
+	locks.Unlock()
+
+	if _, err := bot.flowManager.HandleUpdate(newCtx("done")); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	if bot.flowManager.isUserInFlow(42, 42) {
+		t.Fatal("Expected flow to be completed")
+	}
+	if ids := strippedMessageIDs(mockClient); len(ids) != 1 || ids[0] != 101 {
+		t.Errorf("Expected keyboard of message 101 to be removed, got %v", ids)
+	}
+}
+
+func TestFlowKeyboardCleanup_Disabled(t *testing.T) {
+	bot, mockClient := createKeyboardCleanupBot(false)
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+
+	_ = ctx.StartFlow("survey")
+	ctx.CancelFlow()
+
+	if ids := strippedMessageIDs(mockClient); len(ids) != 0 {
+		t.Errorf("Expected no keyboard edits, got %v", ids)
+	}
+}
+
+func TestDeleteUserInput_FlowLevel(t *testing.T) {
+	fm, _, _, mockCleaner := createTestFlowManager()
+	flow := createTestFlow()
+	flow.OnProcessAction = ProcessDeleteKeyboard | ProcessDeleteUserMessage
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "John", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	calls := mockCleaner.getDeleteMessageCalls()
+	if len(calls) != 1 || calls[0].messageID != 123 {
+		t.Errorf("Expected user message 123 to be deleted, got %v", calls)
+	}
+}
+
+func TestDeleteUserInput_StepLevelOnValidationFailure(t *testing.T) {
+	fm, _, _, mockCleaner := createTestFlowManager()
+	flow, err := NewFlow("login").
+		Step("password").
+		Prompt("Password?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return CompleteFlow() }).
+		Validate(func(ctx *Context, input string) error { return errors.New("too short") }).
+		DeleteUserInput().
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	_ = fm.startFlow(userID, userID, "login", createFlowTestContext(userID, "", fm))
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "abc", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	if calls := mockCleaner.getDeleteMessageCalls(); len(calls) != 1 || calls[0].messageID != 123 {
+		t.Errorf("Expected rejected input to be deleted, got %v", calls)
+	}
+	if !fm.isUserInFlow(userID, userID) {
+		t.Error("Expected user to remain in flow after validation failure")
+	}
+}
+
+func createEditInPlaceBot(editErr error) (*Bot, *MockTelegramClient) {
+	bot, mockClient, _, _ := createTestBot()
+	nextID := 100
+	mockClient.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		if edit, ok := c.(tgbotapi.EditMessageTextConfig); ok {
+			if editErr != nil {
+				return tgbotapi.Message{}, editErr
+			}
+			return tgbotapi.Message{MessageID: edit.MessageID}, nil
+		}
+		nextID++
+		return tgbotapi.Message{MessageID: nextID}, nil
+	}
+
+	keyboard := func(ctx *Context) *PromptKeyboardBuilder {
+		return NewPromptKeyboard().ButtonCallback("Next", "next")
+	}
+	flow, _ := NewFlow("wizard").
+		EditInPlace().
+		Step("first").
+		Prompt("First?").
+		WithPromptKeyboard(keyboard).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return NextStep() }).
+		Step("second").
+		Prompt("Second?").
+		WithPromptKeyboard(keyboard).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return CompleteFlow() }).
+		Build()
+	bot.RegisterFlow(flow)
+	return bot, mockClient
+}
+
+func TestEditInPlace_EditsPreviousPrompt(t *testing.T) {
+	bot, mockClient := createEditInPlaceBot(nil)
+	newCtx := func(text string) *Context {
+		return newContext(createCaptchaAnswerUpdate(42, 42, text), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	}
+
+	if err := newCtx("").StartFlow("wizard"); err != nil {
+		t.Fatalf("StartFlow failed: %v", err)
+	}
+	if _, err := bot.flowManager.HandleUpdate(newCtx("go")); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	if len(mockClient.SendCalls) != 2 {
+		t.Fatalf("Expected 2 sends, got %d", len(mockClient.SendCalls))
+	}
+	edit, ok := mockClient.SendCalls[1].(tgbotapi.EditMessageTextConfig)
+	if !ok {
+		t.Fatalf("Expected second prompt to be an edit, got %T", mockClient.SendCalls[1])
+	}
+	if edit.MessageID != 101 || edit.Text != "Second?" || edit.ReplyMarkup == nil {
+		t.Errorf("Unexpected edit: message %d, text %q, markup %v", edit.MessageID, edit.Text, edit.ReplyMarkup)
+	}
+
+	state := bot.flowManager.currentState(42, 42)
+	if len(state.KeyboardPrompts) != 1 {
+		t.Errorf("Expected the edited message to be tracked once, got %v", state.KeyboardPrompts)
+	}
+}
+
+func TestEditInPlace_FallsBackToNewMessage(t *testing.T) {
+	bot, mockClient := createEditInPlaceBot(errors.New("message to edit not found"))
+	newCtx := func(text string) *Context {
+		return newContext(createCaptchaAnswerUpdate(42, 42, text), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	}
+
+	_ = newCtx("").StartFlow("wizard")
+	if _, err := bot.flowManager.HandleUpdate(newCtx("go")); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	if len(mockClient.SendCalls) != 3 {
+		t.Fatalf("Expected start, failed edit and fresh send, got %d calls", len(mockClient.SendCalls))
+	}
+	msg, ok := mockClient.SendCalls[2].(tgbotapi.MessageConfig)
+	if !ok || msg.Text != "Second?" {
+		t.Errorf("Expected a fresh prompt after the failed edit, got %#v", mockClient.SendCalls[2])
+	}
+	if state := bot.flowManager.currentState(42, 42); state.LastMessageID != 102 {
+		t.Errorf("Expected later steps to edit message 102, got %d", state.LastMessageID)
+	}
+}
+
+func TestUpdateKeyboard_RefreshesLastPrompt(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	enabled := false
+	settingsKeyboard := func(on bool) *PromptKeyboardBuilder {
+		label := "Notifications: off"
+		if on {
+			label = "Notifications: on"
+		}
+		return NewPromptKeyboard().ButtonCallback(label, "toggle")
+	}
+	flow, _ := NewFlow("settings").
+		OnButtonClick(DeleteButtons).
+		Step("toggle").
+		Prompt("Settings").
+		WithPromptKeyboard(func(ctx *Context) *PromptKeyboardBuilder { return settingsKeyboard(enabled) }).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			enabled = !enabled
+			if err := ctx.UpdateKeyboard(settingsKeyboard(enabled)); err != nil {
+				t.Errorf("UpdateKeyboard failed: %v", err)
+			}
+			return Retry()
+		}).
+		Build()
+	bot.RegisterFlow(flow)
+
+	startCtx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if err := startCtx.StartFlow("settings"); err != nil {
+		t.Fatalf("StartFlow failed: %v", err)
+	}
+	prompt := mockClient.SendCalls[0].(tgbotapi.MessageConfig)
+	callbackData := *prompt.ReplyMarkup.(*tgbotapi.InlineKeyboardMarkup).InlineKeyboard[0][0].CallbackData
+
+	click := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "cb",
+		From:    &tgbotapi.User{ID: 42},
+		Data:    callbackData,
+		Message: &tgbotapi.Message{MessageID: 123, Chat: &tgbotapi.Chat{ID: 42, Type: "supergroup"}},
+	}}
+	clickCtx := newContext(click, bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if _, err := bot.flowManager.HandleUpdate(clickCtx); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	if len(mockClient.SendCalls) != 1 {
+		t.Errorf("Expected the prompt not to be resent, got %d sends", len(mockClient.SendCalls))
+	}
+	var edits []tgbotapi.EditMessageReplyMarkupConfig
+	for _, c := range mockClient.RequestCalls {
+		if edit, ok := c.(tgbotapi.EditMessageReplyMarkupConfig); ok {
+			edits = append(edits, edit)
+		}
+	}
+	if len(edits) != 1 {
+		t.Fatalf("Expected exactly one keyboard edit, got %d", len(edits))
+	}
+	if edits[0].MessageID != 123 || edits[0].ReplyMarkup.InlineKeyboard[0][0].Text != "Notifications: on" {
+		t.Errorf("Unexpected keyboard edit: %+v", edits[0])
+	}
+	if state := bot.flowManager.currentState(42, 42); state == nil || state.RetryCount != 0 {
+		t.Errorf("Expected flow to stay on the step without counting a retry")
+	}
+}
+
+func TestUpdateKeyboard_NotInFlow(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if err := ctx.UpdateKeyboard(NewPromptKeyboard().ButtonCallback("A", "a")); err == nil {
+		t.Error("Expected error when not in a flow")
+	}
+}
+
+func TestDeleteMessage_RemovesWholePromptSequence(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	nextID := 100
+	mockClient.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		nextID++
+		return tgbotapi.Message{MessageID: nextID}, nil
+	}
+
+	flow, _ := NewFlow("order").
+		OnButtonClick(DeleteMessage).
+		Step("confirm").
+		Prompt("Confirm?").
+		WithSequence(&PromptConfig{Message: "Order summary"}).
+		WithPromptKeyboard(func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("Yes", "yes")
+		}).
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return NextStep() }).
+		Step("done").
+		Prompt("Anything else?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return CompleteFlow() }).
+		Build()
+	bot.RegisterFlow(flow)
+
+	startCtx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if err := startCtx.StartFlow("order"); err != nil {
+		t.Fatalf("StartFlow failed: %v", err)
+	}
+	prompt := mockClient.SendCalls[1].(tgbotapi.MessageConfig)
+	callbackData := *prompt.ReplyMarkup.(*tgbotapi.InlineKeyboardMarkup).InlineKeyboard[0][0].CallbackData
+
+	click := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "cb",
+		From:    &tgbotapi.User{ID: 42},
+		Data:    callbackData,
+		Message: &tgbotapi.Message{MessageID: 102, Chat: &tgbotapi.Chat{ID: 42, Type: "private"}},
+	}}
+	clickCtx := newContext(click, bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if _, err := bot.flowManager.HandleUpdate(clickCtx); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	if len(mockClient.MakeRequestCalls) != 1 || mockClient.MakeRequestCalls[0].Endpoint != "deleteMessages" {
+		t.Fatalf("Expected one deleteMessages call, got %v", mockClient.MakeRequestCalls)
+	}
+	if ids := mockClient.MakeRequestCalls[0].Params["message_ids"]; ids != "[101,102]" {
+		t.Errorf("Expected summary and prompt to be deleted, got %s", ids)
+	}
+}
+
+func TestFlowManager_StartFlowWith(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+	fm.registerFlow(createTestFlow())
+
+	userID := int64(12345)
+	ctx := createFlowTestContext(userID, "", fm)
+	ctx.Set("implicit", true)
+	data := map[string]interface{}{"preset_amount": 100}
+
+	if err := ctx.StartFlowWith("test-flow", data); err != nil {
+		t.Fatalf("StartFlowWith failed: %v", err)
+	}
+	data["preset_amount"] = 200
+
+	if value, ok := fm.getUserFlowData(userID, userID, "preset_amount"); !ok || value != 100 {
+		t.Errorf("Expected seeded flow data 100, got %v", value)
+	}
+	if _, ok := fm.getUserFlowData(userID, userID, "implicit"); ok {
+		t.Error("Expected context data not to be copied into the flow")
+	}
+}
+
+func TestFlowManager_StartFlowAt(t *testing.T) {
+	fm, mockSender, _, _ := createTestFlowManager()
+	fm.registerFlow(createTestFlow())
+
+	userID := int64(12345)
+	ctx := createFlowTestContext(userID, "", fm)
+	if err := ctx.StartFlowAt("test-flow", "missing"); err == nil {
+		t.Fatal("Expected error for unknown step")
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Fatal("Expected no flow after failed start")
+	}
+
+	if err := ctx.StartFlowAt("test-flow", "step2"); err != nil {
+		t.Fatalf("StartFlowAt failed: %v", err)
+	}
+	if step := currentStepOf(fm, userID); step != "step2" {
+		t.Errorf("Expected flow to start at step2, got %q", step)
+	}
+	if calls := mockSender.getComposeAndSendCalls(); len(calls) != 1 || calls[0].Message != "Enter your age:" {
+		t.Errorf("Expected step2 prompt, got %+v", calls)
+	}
+}
@@ -0,0 +1,76 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// FlowPanicError is returned when a flow's code panics: a ProcessFunc, a
+// validator, a prompt or keyboard function, or OnComplete. The panic is
+// recovered and handled with the flow's OnError strategy, so the flow state stays
+// consistent.
+type FlowPanicError struct {
+	Flow  string
+	Step  string
+	Value interface{} // Value passed to panic
+	Stack []byte      // Stack trace of the panicking goroutine
+}
+
+// Error describes the panic.
+func (e *FlowPanicError) Error() string {
+	return fmt.Sprintf("panic in flow %s, step %s: %v", e.Flow, e.Step, e.Value)
+}
+
+// protect runs fn and turns a panic into a *FlowPanicError.
+func protect(flowName, stepName string, fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &FlowPanicError{Flow: flowName, Step: stepName, Value: r, Stack: debug.Stack()}
+		}
+	}()
+	fn()
+	return nil
+}
+
+// handleStepPanic applies the flow's OnError strategy after the current step's
+// ProcessFunc or validators panicked: the flow is cancelled, the step is kept
+// for another attempt, the flow moves on to the next step, or an OnErrorFunc
+// handler decides. Nothing happens if
+// the flow already ended or was replaced. It returns the panic error.
+func (fm *flowManager) handleStepPanic(ctx *Context, key flowKey, userState *userFlowState, flow *Flow, panicErr error) error {
+	var fpe *FlowPanicError
+	if errors.As(panicErr, &fpe) {
+		log.Printf("[FLOW_PANIC] Flow: %s, Step: %s, User: %d, Panic: %v\n%s", fpe.Flow, fpe.Step, ctx.UserID(), fpe.Value, fpe.Stack)
+	}
+
+	locks := fm.contextLocks(ctx)
+	locks.Lock()
+	defer locks.Unlock()
+	if current, exists := fm.getState_nolock(key); !exists || current != userState {
+		return panicErr
+	}
+
+	config := flow.errorConfig(userState.CurrentStep)
+	log.Printf("[FLOW_ERROR_ACTION] Flow: %s, Step: %s, User: %d, Action: %s",
+		flow.Name, userState.CurrentStep, ctx.UserID(), fm.getActionName(config.Action))
+
+	switch config.Action {
+	case errorStrategyRetry:
+		fm.handleErrorStrategyRetry(ctx, config)
+	case errorStrategyIgnore:
+		fm.notifyUserIfNeeded(ctx, config.Message)
+		if _, err := fm.advanceToNextStep(ctx, userState, flow); err != nil {
+			return errors.Join(panicErr, err)
+		}
+	case errorStrategyFunc:
+		if _, err := fm.handleErrorStrategyFunc_nolock(ctx, config, panicErr, userState, flow); err != nil && err != panicErr {
+			return errors.Join(panicErr, err)
+		}
+	default:
+		fm.cleanupMappings(key)
+		fm.handleErrorStrategyCancel_nolock(ctx, config)
+	}
+	return panicErr
+}
@@ -0,0 +1,238 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func startPanickingFlow(t *testing.T, onError *ErrorConfig) (*flowManager, *MockTelegramClient, int64) {
+	t.Helper()
+	fm, _, _, _ := createTestFlowManager()
+
+	flow := createTestFlow()
+	flow.OnError = onError
+	flow.Steps["step1"].ProcessFunc = func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+		panic("boom")
+	}
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	return fm, NewMockTelegramClient(), userID
+}
+
+func currentStepOf(fm *flowManager, userID int64) string {
+	locks := fm.stateLocks(userID, userID)
+	locks.RLock()
+	defer locks.RUnlock()
+	if state, ok := fm.getState_nolock(flowKey{UserID: userID}); ok {
+		return state.CurrentStep
+	}
+	return ""
+}
+
+func sentText(client *MockTelegramClient) []string {
+	var texts []string
+	for _, c := range client.SendCalls {
+		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+			texts = append(texts, msg.Text)
+		}
+	}
+	return texts
+}
+
+func TestFlowManager_ProcessFuncPanicCancelsFlow(t *testing.T) {
+	fm, client, userID := startPanickingFlow(t, nil)
+
+	ctx := createFlowTestContext(userID, "John", fm)
+	ctx.telegramClient = client
+	handled, err := fm.HandleUpdate(ctx)
+
+	var panicErr *FlowPanicError
+	if !handled || !errors.As(err, &panicErr) {
+		t.Fatalf("Expected handled update with *FlowPanicError, got handled=%v err=%v", handled, err)
+	}
+	if panicErr.Flow != "test-flow" || panicErr.Step != "step1" || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("Unexpected panic error: %+v", panicErr)
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected flow to be cancelled after panic")
+	}
+	if texts := sentText(client); len(texts) != 1 || texts[0] != defaultErrorMessageCancel {
+		t.Errorf("Expected cancel notification, got %v", texts)
+	}
+}
+
+func TestFlowManager_ProcessFuncPanicRetry(t *testing.T) {
+	fm, client, userID := startPanickingFlow(t, OnErrorRetry("try again"))
+
+	ctx := createFlowTestContext(userID, "John", fm)
+	ctx.telegramClient = client
+	if _, err := fm.HandleUpdate(ctx); err == nil {
+		t.Fatal("Expected panic error")
+	}
+
+	if step := currentStepOf(fm, userID); step != "step1" {
+		t.Errorf("Expected flow to stay on step1, got %q", step)
+	}
+	if texts := sentText(client); len(texts) != 1 || texts[0] != "try again" {
+		t.Errorf("Expected retry notification, got %v", texts)
+	}
+}
+
+func TestFlowManager_ProcessFuncPanicIgnore(t *testing.T) {
+	fm, _, userID := startPanickingFlow(t, OnErrorIgnore(ON_ERROR_SILENT))
+
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "John", fm)); err == nil {
+		t.Fatal("Expected panic error")
+	}
+	if step := currentStepOf(fm, userID); step != "step2" {
+		t.Errorf("Expected flow to move on to step2, got %q", step)
+	}
+}
+
+func TestFlowManager_OnCompletePanicClearsState(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+
+	flow := createTestFlow()
+	flow.OnComplete = func(ctx *Context) error { panic("complete failed") }
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "John", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	_, err := fm.HandleUpdate(createFlowTestContext(userID, "30", fm))
+	var panicErr *FlowPanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected *FlowPanicError from OnComplete, got %v", err)
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected flow state to be removed after OnComplete panic")
+	}
+}
+
+func TestFlowManager_OnMaxRetriesPanicKeepsLocks(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+
+	flow := createTestFlow()
+	flow.Steps["step1"].ProcessFunc = func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+		return Retry().WithMaxAttempts(1)
+	}
+	flow.OnMaxRetries = func(ctx *Context, stepName string) ProcessResult { panic("max retries failed") }
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		_, err = fm.HandleUpdate(createFlowTestContext(userID, "John", fm))
+	}
+	var panicErr *FlowPanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "max retries failed" {
+		t.Fatalf("Expected *FlowPanicError from OnMaxRetries, got %v", err)
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected flow to be cancelled after OnMaxRetries panic")
+	}
+
+	// The locks must be usable again
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to restart flow: %v", err)
+	}
+}
+
+func TestFlowManager_OnErrorFunc(t *testing.T) {
+	var handled error
+	fm, client, userID := startPanickingFlow(t, OnErrorFunc(func(ctx *Context, err error) ProcessResult {
+		handled = err
+		return GoToStep("step2").WithPrompt("Skipping ahead")
+	}))
+
+	ctx := createFlowTestContext(userID, "John", fm)
+	ctx.telegramClient = client
+	if _, err := fm.HandleUpdate(ctx); err == nil {
+		t.Fatal("Expected panic error")
+	}
+
+	var panicErr *FlowPanicError
+	if !errors.As(handled, &panicErr) {
+		t.Fatalf("Expected handler to receive *FlowPanicError, got %v", handled)
+	}
+	if step := currentStepOf(fm, userID); step != "step2" {
+		t.Errorf("Expected handler result to move flow to step2, got %q", step)
+	}
+}
+
+func TestFlowManager_OnErrorFuncRenderErrorDoesNotLoop(t *testing.T) {
+	fm, mockSender, _, _ := createTestFlowManager()
+	calls := 0
+	flow := createTestFlow()
+	flow.OnError = OnErrorFunc(func(ctx *Context, err error) ProcessResult {
+		calls++
+		return Retry()
+	})
+	fm.registerFlow(flow)
+
+	mockSender.composeAndSendError = errors.New("render failed")
+	userID := int64(12345)
+	_ = fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm))
+
+	if calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", calls)
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected flow to be cancelled when the handler's retry fails to render")
+	}
+}
+
+func TestFlowManager_StepErrorOverride(t *testing.T) {
+	fm, client, userID := startPanickingFlow(t, OnErrorCancel())
+	flow := fm.flows["test-flow"]
+	flow.Steps["step1"].OnError = OnErrorRetry("payment service busy")
+
+	ctx := createFlowTestContext(userID, "John", fm)
+	ctx.telegramClient = client
+	if _, err := fm.HandleUpdate(ctx); err == nil {
+		t.Fatal("Expected panic error")
+	}
+	if step := currentStepOf(fm, userID); step != "step1" {
+		t.Errorf("Expected step override to keep the flow on step1, got %q", step)
+	}
+	if texts := sentText(client); len(texts) != 1 || texts[0] != "payment service busy" {
+		t.Errorf("Expected step-level retry message, got %v", texts)
+	}
+
+	if config := flow.errorConfig("step2"); config.Action != errorStrategyCancel {
+		t.Errorf("Expected other steps to keep the flow-level strategy, got %s", fm.getActionName(config.Action))
+	}
+}
+
+func TestStepBuilder_OnError(t *testing.T) {
+	process := func(ctx *Context, input string, click *ButtonClick) ProcessResult { return NextStep() }
+	flow, err := NewFlow("checkout").
+		OnError(OnErrorCancel()).
+		Step("pay").Prompt("Pay?").Process(process).OnError(OnErrorRetry()).
+		Step("done").Prompt("Done").Process(process).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if flow.Steps["pay"].OnError == nil || flow.Steps["pay"].OnError.Action != errorStrategyRetry {
+		t.Errorf("Expected step-level retry strategy, got %+v", flow.Steps["pay"].OnError)
+	}
+	if flow.Steps["done"].OnError != nil {
+		t.Errorf("Expected no override on other steps, got %+v", flow.Steps["done"].OnError)
+	}
+}
@@ -0,0 +1,76 @@
+package teleflow
+
+import "fmt"
+
+// flowProgressKey is the template data key under which flow prompts receive the
+// current FlowProgress.
+const flowProgressKey = "FlowProgress"
+
+// FlowProgress tells how far a user got in a flow, counted along the order in
+// which the flow's steps were defined. Templates of flow prompts receive it as
+// {{.FlowProgress}}, which renders as "Step 2 of 5", or field by field as
+// {{.FlowProgress.Current}}/{{.FlowProgress.Total}}.
+type FlowProgress struct {
+	Current int    // 1-based position of the current step
+	Total   int    // Number of steps in the flow
+	Step    string // Name of the current step
+}
+
+// String renders the progress as "Step 2 of 5".
+func (p FlowProgress) String() string {
+	return fmt.Sprintf("Step %d of %d", p.Current, p.Total)
+}
+
+// progress returns the flow's progress at the given step.
+func (f *Flow) progress(stepName string) FlowProgress {
+	return FlowProgress{Current: f.stepNumber(stepName), Total: len(f.Order), Step: stepName}
+}
+
+// flowPrompt identifies the flow step whose prompt is being composed.
+type flowPrompt struct {
+	flow *Flow
+	step string
+	data map[string]interface{} // Copy of the flow data
+}
+
+// composeFlowPrompt sends a prompt on behalf of a flow step. While it is composed,
+// the context knows the step and a copy of the flow data, so templates and Prompt
+// funcs can show the flow's progress and answers without taking the flow locks.
+func (fm *flowManager) composeFlowPrompt(ctx *Context, flow *Flow, stepName string, data map[string]interface{}, config *PromptConfig) error {
+	previous := ctx.flowPrompt
+	ctx.flowPrompt = &flowPrompt{flow: flow, step: stepName, data: data}
+	defer func() { ctx.flowPrompt = previous }()
+	return fm.promptSender.ComposeAndSend(ctx, config)
+}
+
+// flowProgress returns the progress of the flow that applies to a user in a chat.
+func (fm *flowManager) flowProgress(userID, chatID int64) (FlowProgress, bool) {
+	flowName, stepName, ok := fm.currentStep(userID, chatID)
+	if !ok {
+		return FlowProgress{}, false
+	}
+	flow := fm.flows[flowName]
+	if flow == nil {
+		return FlowProgress{}, false
+	}
+	return flow.progress(stepName), true
+}
+
+// FlowProgress returns how far the user got in their current flow, and false if
+// the user is not in a flow. Prompt functions can use it to show a progress line.
+//
+// Example:
+//
+//	Prompt(func(ctx *teleflow.Context) string {
+//		progress, _ := ctx.FlowProgress()
+//		return progress.String() + "\nWhere should we ship your order?"
+//	})
+func (c *Context) FlowProgress() (FlowProgress, bool) {
+	if c.flowPrompt != nil {
+		return c.flowPrompt.flow.progress(c.flowPrompt.step), true
+	}
+	if c.flowOps == nil {
+		return FlowProgress{}, false
+	}
+	return c.flowOps.flowProgress(c.UserID(), c.ChatID())
+}
@@ -0,0 +1,94 @@
+package teleflow
+
+import (
+	"sort"
+	"time"
+)
+
+// redactedValue replaces the values of redacted flow data keys in ActiveFlows.
+const redactedValue = "[redacted]"
+
+// FlowStateSnapshot is a copy of the state of an active flow.
+type FlowStateSnapshot struct {
+	UserID      int64 // User the flow belongs to, 0 for flows bound to a whole chat
+	ChatID      int64 // Chat the flow is bound to, 0 for personal flows
+	FlowName    string
+	CurrentStep string
+	Data        map[string]interface{} // Copy of the flow data
+	StartedAt   time.Time
+	LastActive  time.Time
+}
+
+// RedactData hides the values of the given flow data keys, such as card numbers
+// or passwords, from Bot.ActiveFlows, which is meant for dashboards and admin
+// commands.
+//
+// Example:
+//
+//	teleflow.NewFlow("transfer").RedactData("iban", "pin")
+func (fb *FlowBuilder) RedactData(keys ...string) *FlowBuilder {
+	fb.redactedKeys = append(fb.redactedKeys, keys...)
+	return fb
+}
+
+// GetUserFlow returns the flow that applies to a user in a chat, and false if
+// the user is not in a flow there.
+func (b *Bot) GetUserFlow(userID, chatID int64) (FlowStateSnapshot, bool) {
+	fm := b.flowManager
+	locks := fm.stateLocks(userID, chatID)
+	locks.RLock()
+	defer locks.RUnlock()
+
+	_, state, ok := fm.lookupState_nolock(userID, chatID)
+	if !ok {
+		return FlowStateSnapshot{}, false
+	}
+	return state.snapshot(), true
+}
+
+// ActiveFlows returns snapshots of all flows in progress, oldest first, for
+// dashboards and admin commands. Values of the keys a flow marks with RedactData
+// are replaced by "[redacted]".
+//
+// Example:
+//
+//	for _, flow := range bot.ActiveFlows() {
+//		fmt.Printf("%d: %s/%s, idle %s\n", flow.UserID, flow.FlowName, flow.CurrentStep, time.Since(flow.LastActive))
+//	}
+func (b *Bot) ActiveFlows() []FlowStateSnapshot {
+	fm := b.flowManager
+	var snapshots []FlowStateSnapshot
+	for i := range fm.shards {
+		shard := &fm.shards[i]
+		shard.mu.RLock()
+		for _, state := range shard.states {
+			snapshot := state.snapshot()
+			if flow := fm.flows[state.FlowName]; flow != nil {
+				for _, key := range flow.RedactedKeys {
+					if _, ok := snapshot.Data[key]; ok {
+						snapshot.Data[key] = redactedValue
+					}
+				}
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		shard.mu.RUnlock()
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].StartedAt.Before(snapshots[j].StartedAt)
+	})
+	return snapshots
+}
+
+// snapshot copies the state. The state's shard must be locked.
+func (s *userFlowState) snapshot() FlowStateSnapshot {
+	return FlowStateSnapshot{
+		UserID:      s.Key.UserID,
+		ChatID:      s.Key.ChatID,
+		FlowName:    s.FlowName,
+		CurrentStep: s.CurrentStep,
+		Data:        copyFlowData(s.Data),
+		StartedAt:   s.StartedAt,
+		LastActive:  s.LastActive,
+	}
+}
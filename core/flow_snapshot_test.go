@@ -0,0 +1,132 @@
+package teleflow
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestBot_GetUserFlow(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	bot.RegisterFlow(createTestFlow())
+
+	if _, ok := bot.GetUserFlow(42, 42); ok {
+		t.Fatal("Expected no flow before start")
+	}
+
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, "Alice"), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if err := ctx.StartFlow("test-flow"); err != nil {
+		t.Fatalf("StartFlow failed: %v", err)
+	}
+	bot.ProcessExternalUpdate(createCaptchaAnswerUpdate(42, 42, "Alice"))
+
+	snapshot, ok := bot.GetUserFlow(42, 42)
+	if !ok || snapshot.FlowName != "test-flow" || snapshot.CurrentStep != "step2" {
+		t.Fatalf("Unexpected snapshot: %+v", snapshot)
+	}
+	if snapshot.Data["name"] != "Alice" || snapshot.StartedAt.IsZero() {
+		t.Errorf("Expected flow data and start time, got %+v", snapshot)
+	}
+
+	snapshot.Data["name"] = "changed"
+	if value, _ := bot.flowManager.getUserFlowData(42, 42, "name"); value != "Alice" {
+		t.Errorf("Expected snapshot data to be a copy, flow data is %v", value)
+	}
+}
+
+func TestBot_StartFlowFor(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+	bot.RegisterFlow(createTestFlow())
+
+	if err := bot.StartFlowFor(777, 777, "test-flow", map[string]interface{}{"agent": int64(42)}); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+
+	snapshot, ok := bot.GetUserFlow(777, 777)
+	if !ok || snapshot.CurrentStep != "step1" || snapshot.Data["agent"] != int64(42) {
+		t.Fatalf("Expected user 777 in step1 with seeded data, got %+v (active=%v)", snapshot, ok)
+	}
+	if len(client.SendCalls) != 1 {
+		t.Fatalf("Expected first prompt to be sent, got %d sends", len(client.SendCalls))
+	}
+	if msg, ok := client.SendCalls[0].(tgbotapi.MessageConfig); !ok || msg.ChatID != 777 || msg.Text != "Enter your name:" {
+		t.Errorf("Expected first prompt in chat 777, got %+v", client.SendCalls[0])
+	}
+
+	if err := bot.StartFlowFor(777, 777, "missing", nil); err == nil {
+		t.Error("Expected error for unknown flow")
+	}
+}
+
+func TestBot_StartFlowFor_LocksTargetChat(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	flow := createTestFlow()
+	var isGroup bool
+	flow.OnStart = func(ctx *Context) error {
+		isGroup = ctx.IsGroup()
+		return nil
+	}
+	bot.RegisterFlow(flow)
+
+	release := bot.chatLocks.lock(-100) // An update of the group is being handled
+	started := make(chan error, 1)
+	go func() {
+		started <- bot.StartFlowFor(777, -100, "test-flow", nil)
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("Expected StartFlowFor to wait for the update of the target chat")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	if err := <-started; err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	if !isGroup {
+		t.Error("Expected a chat with a negative ID to count as a group")
+	}
+}
+
+func TestBot_ActiveFlows(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bot, _, _, _ := createTestBot(WithClock(clock))
+	flow, err := NewFlow("transfer").
+		RedactData("iban").
+		Step("amount").
+		Prompt("How much?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			return Retry()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	if flows := bot.ActiveFlows(); len(flows) != 0 {
+		t.Fatalf("Expected no active flows, got %+v", flows)
+	}
+	if err := bot.StartFlowFor(2, 2, "transfer", map[string]interface{}{"iban": "DE89", "currency": "EUR"}); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if err := bot.StartFlowFor(1, 1, "transfer", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+
+	flows := bot.ActiveFlows()
+	if len(flows) != 2 || flows[0].UserID != 2 || flows[1].UserID != 1 {
+		t.Fatalf("Expected both flows oldest first, got %+v", flows)
+	}
+	if flows[0].FlowName != "transfer" || flows[0].CurrentStep != "amount" {
+		t.Errorf("Unexpected snapshot %+v", flows[0])
+	}
+	if flows[0].Data["iban"] != "[redacted]" || flows[0].Data["currency"] != "EUR" {
+		t.Errorf("Expected only the redacted key to be hidden, got %v", flows[0].Data)
+	}
+	if snapshot, _ := bot.GetUserFlow(2, 2); snapshot.Data["iban"] != "DE89" {
+		t.Errorf("Expected the flow data itself to be unchanged, got %v", snapshot.Data)
+	}
+}
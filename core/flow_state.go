@@ -0,0 +1,142 @@
+package teleflow
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// flowStateShards is the number of buckets the active flow states are spread over.
+const flowStateShards = 64
+
+// flowShard holds the states of the flows whose keys hash to it, guarded by its
+// own mutex so that flows of unrelated users do not contend for one lock.
+type flowShard struct {
+	mu     sync.RWMutex
+	states map[flowKey]*userFlowState
+}
+
+func newFlowShards(n int) []flowShard {
+	shards := make([]flowShard, n)
+	for i := range shards {
+		shards[i].states = make(map[flowKey]*userFlowState)
+	}
+	return shards
+}
+
+// stateLocks is the set of shards that may hold the flow applying to a user in a
+// chat: the shards of the keys lookupState_nolock considers. Holding all of them
+// makes the lookup and any change to the found state atomic. Shards are always
+// locked in ascending order, so two lock sets cannot deadlock.
+type stateLocks struct {
+	shards [3]*flowShard
+	n      int
+}
+
+func (l stateLocks) Lock() {
+	for i := 0; i < l.n; i++ {
+		l.shards[i].mu.Lock()
+	}
+}
+
+func (l stateLocks) Unlock() {
+	for i := l.n - 1; i >= 0; i-- {
+		l.shards[i].mu.Unlock()
+	}
+}
+
+func (l stateLocks) RLock() {
+	for i := 0; i < l.n; i++ {
+		l.shards[i].mu.RLock()
+	}
+}
+
+func (l stateLocks) RUnlock() {
+	for i := l.n - 1; i >= 0; i-- {
+		l.shards[i].mu.RUnlock()
+	}
+}
+
+// stateKeys returns the keys under which a flow applying to a user in a chat may
+// be stored, most specific first.
+func stateKeys(userID, chatID int64) [3]flowKey {
+	return [3]flowKey{
+		{UserID: userID, ChatID: chatID},
+		{ChatID: chatID},
+		{UserID: userID},
+	}
+}
+
+// shardIndex maps a flow key onto a shard. Keys are placed by chat when they have
+// one, so a chat's flows share a shard, and a private chat's flows all land on the
+// same shard as the user's personal flow: most lookups then take a single lock.
+func (fm *flowManager) shardIndex(key flowKey) int {
+	id := key.ChatID
+	if id == 0 {
+		id = key.UserID
+	}
+	return int(((uint64(id) * 0x9E3779B97F4A7C15) >> 32) % uint64(len(fm.shards)))
+}
+
+func (fm *flowManager) shardFor(key flowKey) *flowShard {
+	return &fm.shards[fm.shardIndex(key)]
+}
+
+// stateLocks returns the locks that guard the flow state of a user in a chat.
+func (fm *flowManager) stateLocks(userID, chatID int64) stateLocks {
+	var indexes [3]int
+	n := 0
+	for _, key := range stateKeys(userID, chatID) {
+		if key.UserID == 0 && key.ChatID == 0 {
+			continue
+		}
+		indexes[n] = fm.shardIndex(key)
+		n++
+	}
+	sort.Ints(indexes[:n])
+
+	var locks stateLocks
+	for i := 0; i < n; i++ {
+		if locks.n > 0 && &fm.shards[indexes[i]] == locks.shards[locks.n-1] {
+			continue
+		}
+		locks.shards[locks.n] = &fm.shards[indexes[i]]
+		locks.n++
+	}
+	return locks
+}
+
+// contextLocks returns the locks that guard the flow state of the context's user and chat.
+func (fm *flowManager) contextLocks(ctx *Context) stateLocks {
+	return fm.stateLocks(ctx.UserID(), ctx.ChatID())
+}
+
+// getState_nolock returns the state stored under key. The key's shard must be locked.
+func (fm *flowManager) getState_nolock(key flowKey) (*userFlowState, bool) {
+	state, ok := fm.shardFor(key).states[key]
+	return state, ok
+}
+
+// putState_nolock stores a state under key. The key's shard must be locked.
+func (fm *flowManager) putState_nolock(key flowKey, state *userFlowState) {
+	fm.shardFor(key).states[key] = state
+	if fm.maxActiveFlows() > 0 {
+		fm.capacity.put(state)
+	}
+}
+
+// deleteState_nolock removes the state stored under key. The key's shard must be locked.
+func (fm *flowManager) deleteState_nolock(key flowKey) {
+	delete(fm.shardFor(key).states, key)
+	if fm.maxActiveFlows() > 0 {
+		fm.capacity.remove(key)
+	}
+}
+
+// touchState_nolock marks the state as last active at now. Its shard must be locked.
+func (fm *flowManager) touchState_nolock(state *userFlowState, now time.Time) {
+	state.LastActive = now
+	if fm.maxActiveFlows() > 0 {
+		fm.capacity.touch(state.Key)
+	}
+}
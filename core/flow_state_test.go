@@ -0,0 +1,86 @@
+package teleflow
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStateLocks_OrderedAndDistinct(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+
+	if locks := fm.stateLocks(12345, 12345); locks.n != 1 {
+		t.Errorf("Expected a private chat to take a single lock, got %d", locks.n)
+	}
+	locks := fm.stateLocks(12345, -100200)
+	if locks.n > 2 {
+		t.Errorf("Expected a group chat to take at most 2 locks, got %d", locks.n)
+	}
+	for i := 1; i < locks.n; i++ {
+		if locks.shards[i-1] == locks.shards[i] {
+			t.Fatal("Expected each shard to be locked only once")
+		}
+	}
+
+	fm.shards = newFlowShards(1)
+	if locks := fm.stateLocks(12345, -100200); locks.n != 1 {
+		t.Errorf("Expected keys sharing a shard to lock it once, got %d", locks.n)
+	}
+	locks.Lock()
+	locks.Unlock()
+}
+
+func TestFlowState_LookupAcrossShards(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+	groupID, userID := int64(-100200), int64(12345)
+
+	locks := fm.stateLocks(userID, groupID)
+	locks.Lock()
+	fm.putState_nolock(flowKey{UserID: userID}, &userFlowState{FlowName: "personal"})
+	fm.putState_nolock(flowKey{ChatID: groupID}, &userFlowState{FlowName: "group"})
+	locks.Unlock()
+
+	if state := fm.currentState(userID, groupID); state == nil || state.FlowName != "group" {
+		t.Errorf("Expected the chat flow to shadow the personal flow, got %+v", state)
+	}
+	if state := fm.currentState(userID, userID); state == nil || state.FlowName != "personal" {
+		t.Errorf("Expected the personal flow in the private chat, got %+v", state)
+	}
+	if count := fm.activeFlowCount(); count != 2 {
+		t.Errorf("Expected 2 active flows, got %d", count)
+	}
+}
+
+// BenchmarkFlowState_Parallel measures flow data access by many users at once,
+// comparing a single shard (one global lock) with the default sharding.
+func BenchmarkFlowState_Parallel(b *testing.B) {
+	const users = 10000
+
+	for _, shards := range []int{1, flowStateShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			fm, _, _, _ := createTestFlowManager()
+			fm.shards = newFlowShards(shards)
+			for userID := int64(1); userID <= users; userID++ {
+				key := flowKey{UserID: userID}
+				fm.shardFor(key).states[key] = &userFlowState{
+					Key:        key,
+					FlowName:   "bench",
+					Data:       make(map[string]interface{}),
+					LastActive: time.Now(),
+				}
+			}
+
+			var next atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				userID := next.Add(7919)%users + 1
+				for pb.Next() {
+					_ = fm.setUserFlowData(userID, userID, "step", userID)
+					_, _ = fm.getUserFlowData(userID, userID, "step")
+					fm.isUserInFlow(userID, userID)
+					userID = userID%users + 1
+				}
+			})
+		})
+	}
+}
@@ -10,10 +10,24 @@ type FlowBuilder struct {
 	steps           map[string]*StepBuilder // Map of step name to step builder
 	order           []string                // Order of steps as they were added
 	onComplete      func(*Context) error    // Callback when flow completes successfully
+	onCancel        func(*Context) error    // Callback when flow is cancelled
+	onStart         func(*Context) error    // Callback before the first prompt is sent
 	onError         *ErrorConfig            // Error handling configuration
 	onProcessAction ProcessMessageAction    // Default action for processing messages
 	currentStep     *StepBuilder            // Currently being built step
 	timeout         time.Duration           // Flow timeout duration
+	onMaxRetries    MaxRetriesHandler       // Called when a step exceeds its retry limit
+	scope           FlowScope               // How flow state is keyed (user, chat, user in chat)
+	inputPolicy     ChatInputPolicy         // Who may answer a chat-scoped flow
+	permission      string                  // Permission required to start the flow
+	deniedPrompt    MessageSpec             // Prompt shown when a permission check fails
+	editInPlace     bool                    // Edit the previous prompt instead of sending new ones
+	exitCommands    []string                // Exit commands replacing FlowConfig.ExitCommands, nil for the global ones
+	exitMessage     string                  // Exit message replacing FlowConfig.ExitMessage
+	globalCommands  []string                // Commands replacing FlowConfig.GlobalCommandWhitelist, nil for the global ones
+	pauseInterrupts bool                    // Pause the flow around global commands
+	startLimit      *FlowStartLimit         // Per-user cap on starts and aborted runs
+	redactedKeys    []string                // Flow data keys hidden from ActiveFlows
 }
 
 // StepBuilder represents a single step in a conversation flow.
@@ -23,24 +37,58 @@ type StepBuilder struct {
 	name         string        // Step name for identification and navigation
 	promptConfig *PromptConfig // Configuration for the prompt to display
 	processFunc  ProcessFunc   // Function to process user input
+	validators   []Validator   // Input validators run before processFunc
+	permission   string        // Permission required to enter the step
 	flowBuilder  *FlowBuilder  // Reference to parent flow builder
+
+	asyncValidators []Validator  // Slow validators run in the background after validators pass
+	pendingPrompt   MessageSpec  // Prompt shown while asyncValidators run
+	deleteInput     bool         // Delete the user's text input after processing
+	onError         *ErrorConfig // Overrides the flow's error strategy for this step
+	help            MessageSpec  // Answer to help commands sent during this step
+	acceptReactions bool         // Reactions to the prompt are processed as answers
+	reactions       []string     // Emoji accepted as answers, empty for any
+	job             JobFunc      // Background job run instead of waiting for an answer
+	maxAttempts     int          // Retries allowed before OnMaxRetries, 0 for unlimited
 }
 
 // PromptConfig defines the configuration for a prompt message in a flow step.
 // It can include text messages, images, keyboards, and template data for dynamic content.
+//
+// A prompt can also consist of several messages: the messages in Sequence are sent in
+// order, followed by the prompt's own Message, Image and Keyboard if any are set. All
+// messages are rendered before the first one is sent. Sequence messages without their
+// own TemplateData use the prompt's.
 type PromptConfig struct {
 	Message      MessageSpec            // Message content (string, function, or template)
-	Image        ImageSpec              // Optional image (URL, file path, or bytes)
+	Entities     []MessageEntity        // Formatting of a plain text message, instead of a parse mode
+	Image        ImageSpec              // Optional image (URL, file path, bytes, reader, or file_id)
 	Keyboard     KeyboardFunc           // Optional keyboard generator function
 	TemplateData map[string]interface{} // Data for template rendering
+	Sequence     []*PromptConfig        // Messages sent before this one, in order
+
+	// RemoveReplyKeyboard hides the reply keyboard (such as the AccessManager's main
+	// menu) while the prompt is shown. The next prompt without this option shows the
+	// menu again. Has no effect on a message that carries an inline keyboard.
+	RemoveReplyKeyboard bool
+	// OneTimeReplyKeyboard replaces the reply keyboard for this prompt with one that
+	// Telegram hides after the user presses a button.
+	OneTimeReplyKeyboard *ReplyKeyboard
+	// BusinessConnectionID sends the prompt on behalf of a Telegram Business
+	// account. Prompts answering a business message use its connection unless
+	// set. Images must be URLs or file_ids, as uploads are not supported.
+	BusinessConnectionID string
 }
 
 // MessageSpec represents various ways to specify message content.
-// Can be a string, a function that returns a string, or template reference.
+// Can be a string, a function that returns a string, template reference, a
+// *TextBuilder or an *Experiment choosing between them.
 type MessageSpec interface{}
 
 // ImageSpec represents various ways to specify image content.
-// Can be a URL string, file path, byte slice, or function returning any of these.
+// Can be a URL string, file path, byte slice, io.Reader, ImageFileID, or function
+// returning a string, byte slice or io.Reader. Uploaded images are remembered by
+// content hash, so sending the same bytes again reuses Telegram's file_id.
 type ImageSpec interface{}
 
 // KeyboardFunc is a function that generates an inline keyboard for a prompt.
@@ -66,11 +114,35 @@ type ButtonClick struct {
 // It specifies what action to take next (continue, retry, jump to step, etc.)
 // and can include an optional prompt to display.
 type ProcessResult struct {
-	Action     processAction // What action to take (next step, retry, etc.)
-	TargetStep string        // Target step name for jump actions
-	Prompt     *PromptConfig // Optional prompt to display before action
+	Action      processAction // What action to take (next step, retry, etc.)
+	TargetStep  string        // Target step name for jump actions
+	Prompt      *PromptConfig // Optional prompt to display before action
+	MaxAttempts int           // Retry limit for the current step (0 means unlimited)
 }
 
+// MaxRetriesHandler decides what happens when a step exceeds the limit set with
+// ProcessResult.WithMaxAttempts. It receives the name of the step that failed and
+// returns the result to apply instead, e.g. GoToStep("talk_to_human") or CancelFlow().
+type MaxRetriesHandler func(ctx *Context, stepName string) ProcessResult
+
+// ErrorHandlerFunc decides how a flow recovers from an error in one of its steps,
+// such as a failed prompt render or a panic in a ProcessFunc. It returns the
+// result to apply, e.g. Retry(), NextStep(), GoToStep("support") or CancelFlow().
+type ErrorHandlerFunc func(ctx *Context, err error) ProcessResult
+
+// CancelReason tells an OnCancel handler why its flow was cancelled.
+type CancelReason string
+
+const (
+	CancelReasonExitCommand      CancelReason = "exit_command"      // The user sent one of the exit commands
+	CancelReasonCancelFlow       CancelReason = "cancel_flow"       // A step returned CancelFlow()
+	CancelReasonTimeout          CancelReason = "timeout"           // The flow was inactive for longer than its timeout
+	CancelReasonManual           CancelReason = "manual"            // The bot called ctx.CancelFlow()
+	CancelReasonError            CancelReason = "error"             // The flow's OnError strategy cancelled it
+	CancelReasonMaxRetries       CancelReason = "max_retries"       // A step exceeded its retry limit without OnMaxRetries
+	CancelReasonPermissionDenied CancelReason = "permission_denied" // The user may not enter the next step
+)
+
 // WithPrompt adds a prompt message to a ProcessResult.
 // This allows displaying a message before executing the result action.
 //
@@ -118,13 +190,50 @@ func (pr ProcessResult) WithTemplateData(data map[string]interface{}) ProcessRes
 	return pr
 }
 
+// WithMaxAttempts limits how many times the current step may be retried.
+// Once the user has failed n times in a row, the flow's OnMaxRetries handler is
+// invoked instead of re-prompting; without a handler the flow is cancelled.
+// The counter resets whenever the flow moves to another step. Without it, the
+// step's StepBuilder.MaxAttempts limit applies.
+//
+// Example:
+//
+//	return teleflow.Retry().
+//		WithPrompt("Please enter a valid amount:").
+//		WithMaxAttempts(3)
+func (pr ProcessResult) WithMaxAttempts(n int) ProcessResult {
+	pr.MaxAttempts = n
+	return pr
+}
+
+// FlowScope determines who a running flow belongs to and therefore whose
+// messages advance it.
+type FlowScope int
+
+const (
+	FlowScopeUser     FlowScope = iota // One flow per user, followed across chats (default)
+	FlowScopeChat                      // One shared flow per chat, answered by its members
+	FlowScopeUserChat                  // One flow per user in each chat
+)
+
+// ChatInputPolicy controls which members of a group may answer a chat-scoped flow.
+type ChatInputPolicy int
+
+const (
+	InputFromAnyMember ChatInputPolicy = iota // Any chat member may answer (default)
+	InputFromAdmins                           // Only chat administrators and the creator may answer
+)
+
 // ButtonClickAction defines what happens to a message when its inline keyboard button is clicked.
+// DeleteUserMessage can be combined with the other actions, e.g. DeleteButtons | DeleteUserMessage.
 type ButtonClickAction int
 
 const (
 	KeepMessage   ButtonClickAction = iota // Do nothing (default)
 	DeleteMessage                          // Delete entire message with buttons
 	DeleteButtons                          // Delete only the inline buttons
+
+	DeleteUserMessage ButtonClickAction = 4 // Delete the user's text input once it has been processed
 )
 
 // ProcessMessageAction is an internal type for backward compatibility.
@@ -135,6 +244,8 @@ const (
 	ProcessKeepMessage    ProcessMessageAction = ProcessMessageAction(KeepMessage)
 	ProcessDeleteMessage  ProcessMessageAction = ProcessMessageAction(DeleteMessage)
 	ProcessDeleteKeyboard ProcessMessageAction = ProcessMessageAction(DeleteButtons)
+
+	ProcessDeleteUserMessage ProcessMessageAction = ProcessMessageAction(DeleteUserMessage)
 )
 
 // processAction defines internal actions that can be taken after processing user input.
@@ -146,6 +257,7 @@ const (
 	actionRetryStep
 	actionCompleteFlow
 	actionCancelFlow
+	actionWaitForEvent
 )
 
 // NextStep creates a ProcessResult that advances to the next step in the flow.
@@ -219,6 +331,28 @@ func CancelFlow() ProcessResult {
 	return ProcessResult{Action: actionCancelFlow}
 }
 
+// WaitForEvent creates a ProcessResult that parks the flow at the current step
+// until Bot.ResumeFlow delivers an out-of-band event, such as a payment webhook
+// or a human approval. The step's ProcessFunc then runs again with ctx.Event()
+// set. Messages the user sends meanwhile still reach the ProcessFunc, which can
+// return WaitForEvent again to keep waiting.
+//
+// Example:
+//
+//	func processRequest(ctx *teleflow.Context, input string, click *teleflow.ButtonClick) teleflow.ProcessResult {
+//		if approval, ok := ctx.Event().(Approval); ok {
+//			if approval.Granted {
+//				return teleflow.NextStep()
+//			}
+//			return teleflow.CancelFlow().WithPrompt("Your request was declined.")
+//		}
+//		approvals.Request(ctx.UserID(), input)
+//		return teleflow.WaitForEvent().WithPrompt("⏳ Waiting for a manager to approve…")
+//	}
+func WaitForEvent() ProcessResult {
+	return ProcessResult{Action: actionWaitForEvent}
+}
+
 // isTemplateMessage checks if a message string is a template reference.
 // Template references are prefixed with "template:" followed by the template name.
 // Returns true and the template name if it's a template, false otherwise.
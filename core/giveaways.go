@@ -0,0 +1,143 @@
+package teleflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Giveaway is a Telegram-native giveaway announced in a chat.
+type Giveaway struct {
+	MessageID                     int             `json:"-"` // Message announcing the giveaway
+	Chats                         []tgbotapi.Chat `json:"chats"`
+	WinnersSelectionDate          int64           `json:"winners_selection_date"`
+	WinnerCount                   int             `json:"winner_count"`
+	OnlyNewMembers                bool            `json:"only_new_members,omitempty"`
+	HasPublicWinners              bool            `json:"has_public_winners,omitempty"`
+	PrizeDescription              string          `json:"prize_description,omitempty"`
+	CountryCodes                  []string        `json:"country_codes,omitempty"`
+	PrizeStarCount                int             `json:"prize_star_count,omitempty"`
+	PremiumSubscriptionMonthCount int             `json:"premium_subscription_month_count,omitempty"`
+}
+
+// SelectionDate returns when the winners will be selected.
+func (g Giveaway) SelectionDate() time.Time {
+	return time.Unix(g.WinnersSelectionDate, 0)
+}
+
+// GiveawayWinners announces the winners of a giveaway with public winners.
+type GiveawayWinners struct {
+	Chat                          tgbotapi.Chat   `json:"chat"`
+	GiveawayMessageID             int             `json:"giveaway_message_id"`
+	WinnersSelectionDate          int64           `json:"winners_selection_date"`
+	WinnerCount                   int             `json:"winner_count"`
+	Winners                       []tgbotapi.User `json:"winners"`
+	AdditionalChatCount           int             `json:"additional_chat_count,omitempty"`
+	PrizeStarCount                int             `json:"prize_star_count,omitempty"`
+	PremiumSubscriptionMonthCount int             `json:"premium_subscription_month_count,omitempty"`
+	UnclaimedPrizeCount           int             `json:"unclaimed_prize_count,omitempty"`
+	OnlyNewMembers                bool            `json:"only_new_members,omitempty"`
+	WasRefunded                   bool            `json:"was_refunded,omitempty"`
+	PrizeDescription              string          `json:"prize_description,omitempty"`
+}
+
+// GiveawayHandlerFunc handles a giveaway announced in a chat.
+type GiveawayHandlerFunc func(ctx *Context, giveaway Giveaway) error
+
+// GiveawayWinnersHandlerFunc handles the announcement of a giveaway's winners.
+type GiveawayWinnersHandlerFunc func(ctx *Context, winners GiveawayWinners) error
+
+// HandleGiveaway registers the handler for giveaway messages in groups and
+// channels, e.g. to pin the announcement or remind members to join. The
+// context's chat is the chat of the message.
+//
+// Giveaway messages reach teleflow through webhooks and
+// ProcessExternalUpdateJSON; the telegram-bot-api library drops them during
+// long polling. Without a handler they are routed like other messages.
+//
+// Example:
+//
+//	bot.HandleGiveaway(func(ctx *teleflow.Context, giveaway teleflow.Giveaway) error {
+//		return ctx.SendPromptText(fmt.Sprintf("🎁 %d prizes! Winners are drawn on %s.",
+//			giveaway.WinnerCount, giveaway.SelectionDate().Format("2 Jan")))
+//	})
+func (b *Bot) HandleGiveaway(handler GiveawayHandlerFunc) {
+	b.giveawayHandler = handler
+}
+
+// HandleGiveawayWinners registers the handler for giveaway winner
+// announcements, e.g. to congratulate the winners.
+func (b *Bot) HandleGiveawayWinners(handler GiveawayWinnersHandlerFunc) {
+	b.giveawayWinnersHandler = handler
+}
+
+// messageExtras are message fields the telegram-bot-api library does not
+// decode.
+type messageExtras struct {
+	MessageID       int              `json:"message_id"`
+	Chat            tgbotapi.Chat    `json:"chat"`
+	From            *tgbotapi.User   `json:"from,omitempty"`
+	Giveaway        *Giveaway        `json:"giveaway,omitempty"`
+	GiveawayWinners *GiveawayWinners `json:"giveaway_winners,omitempty"`
+}
+
+// updateExtras decodes the messageExtras of an update's message or channel
+// post.
+type updateExtras struct {
+	Message     *messageExtras `json:"message,omitempty"`
+	ChannelPost *messageExtras `json:"channel_post,omitempty"`
+}
+
+// decodeUpdateExtras decodes the fields of data that updateEnvelope misses. Only
+// updates that mention a giveaway are decoded a second time.
+func decodeUpdateExtras(data []byte) (*messageExtras, error) {
+	if !bytes.Contains(data, []byte(`"giveaway`)) {
+		return nil, nil
+	}
+	var extras updateExtras
+	if err := json.Unmarshal(data, &extras); err != nil {
+		return nil, err
+	}
+	if extras.Message != nil {
+		return extras.Message, nil
+	}
+	return extras.ChannelPost, nil
+}
+
+// processGiveaway runs the giveaway handlers for a message. It reports whether
+// a handler took the message.
+func (b *Bot) processGiveaway(msg *messageExtras) bool {
+	if msg == nil {
+		return false
+	}
+	var handler func(ctx *Context) error
+	switch {
+	case msg.Giveaway != nil && b.giveawayHandler != nil:
+		giveaway := *msg.Giveaway
+		giveaway.MessageID = msg.MessageID
+		handler = func(ctx *Context) error { return b.giveawayHandler(ctx, giveaway) }
+	case msg.GiveawayWinners != nil && b.giveawayWinnersHandler != nil:
+		winners := *msg.GiveawayWinners
+		handler = func(ctx *Context) error { return b.giveawayWinnersHandler(ctx, winners) }
+	default:
+		return false
+	}
+
+	ctx := newContext(tgbotapi.Update{}, b.sender, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+	ctx.chatID = msg.Chat.ID
+	ctx.isGroup = msg.Chat.IsGroup() || msg.Chat.IsSuperGroup()
+	ctx.isChannel = msg.Chat.IsChannel()
+	if msg.From != nil {
+		ctx.userID = msg.From.ID
+	}
+	ctx.telegramClient = b.sender.forContext(ctx)
+	ctx.clock = b.clock
+
+	if err := b.stats.track("giveaway", func() error { return b.applyMiddleware(handler)(ctx) }); err != nil {
+		b.deadLetter(ctx, fmt.Errorf("giveaway: %w", err), 1)
+	}
+	return true
+}
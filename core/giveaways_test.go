@@ -0,0 +1,41 @@
+package teleflow
+
+import "testing"
+
+func TestGiveawayHandlers(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+
+	var giveaways []Giveaway
+	var winners []GiveawayWinners
+	bot.HandleGiveaway(func(ctx *Context, giveaway Giveaway) error {
+		giveaways = append(giveaways, giveaway)
+		if !ctx.IsChannel() || ctx.ChatID() != -100 {
+			t.Errorf("Expected the channel's context, got chat %d", ctx.ChatID())
+		}
+		return ctx.SendPromptText("Good luck everyone!")
+	})
+	bot.HandleGiveawayWinners(func(ctx *Context, w GiveawayWinners) error {
+		winners = append(winners, w)
+		return nil
+	})
+
+	updates := []string{
+		`{"update_id":1,"channel_post":{"message_id":50,"chat":{"id":-100,"type":"channel"},"date":1700000000,"giveaway":{"chats":[{"id":-100,"type":"channel"}],"winners_selection_date":1700600000,"winner_count":3,"prize_star_count":500}}}`,
+		`{"update_id":2,"channel_post":{"message_id":60,"chat":{"id":-100,"type":"channel"},"date":1700600000,"giveaway_winners":{"chat":{"id":-100,"type":"channel"},"giveaway_message_id":50,"winners_selection_date":1700600000,"winner_count":3,"winners":[{"id":1},{"id":2},{"id":3}]}}}`,
+	}
+	for _, update := range updates {
+		if err := bot.ProcessExternalUpdateJSON([]byte(update)); err != nil {
+			t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+		}
+	}
+
+	if len(giveaways) != 1 || giveaways[0].MessageID != 50 || giveaways[0].WinnerCount != 3 || giveaways[0].SelectionDate().Unix() != 1700600000 {
+		t.Errorf("Unexpected giveaways %+v", giveaways)
+	}
+	if len(winners) != 1 || winners[0].GiveawayMessageID != 50 || len(winners[0].Winners) != 3 {
+		t.Errorf("Unexpected winners %+v", winners)
+	}
+	if len(client.SendCalls) != 1 {
+		t.Errorf("Expected one message to the channel, got %d", len(client.SendCalls))
+	}
+}
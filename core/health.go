@@ -0,0 +1,147 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health pings the Bot API at most once per healthPingTTL and gives up on a
+// ping after healthPingTimeout.
+const (
+	healthPingTTL     = 5 * time.Second
+	healthPingTimeout = 5 * time.Second
+)
+
+// apiPing caches the result of the getMe ping made by Health.
+type apiPing struct {
+	mu      sync.Mutex
+	at      time.Time // When the cached ping was made (zero if none)
+	latency time.Duration
+	err     error
+}
+
+// HealthStatus is a snapshot of the bot's runtime health returned by Bot.Health.
+type HealthStatus struct {
+	Healthy        bool          `json:"healthy"`               // Receiving updates and the Bot API is reachable
+	Polling        bool          `json:"polling"`               // Start is receiving updates
+	Webhook        bool          `json:"webhook"`               // A WebhookHandler is receiving updates
+	LastUpdate     time.Time     `json:"last_update,omitempty"` // When the last update arrived (zero if none)
+	APIReachable   bool          `json:"api_reachable"`         // A getMe ping succeeded
+	APILatency     time.Duration `json:"api_latency"`           // Duration of the getMe ping
+	APIError       string        `json:"api_error,omitempty"`   // Error of the getMe ping, if any
+	QueuedUpdates  int           `json:"queued_updates"`        // Updates received but not yet dispatched
+	ActiveHandlers int64         `json:"active_handlers"`       // Updates currently being processed
+	ActiveFlows    int           `json:"active_flows"`          // Flows currently in progress
+	MaxActiveFlows int           `json:"max_active_flows"`      // FlowConfig.MaxActiveFlows, 0 for no cap
+	EvictedFlows   int64         `json:"evicted_flows"`         // Flows evicted to stay within MaxActiveFlows
+	Duplicates     int64         `json:"duplicate_updates"`     // Updates dropped by WithDeduplication
+	DroppedUpdates int64         `json:"dropped_updates"`       // Updates dropped by a full WithUpdateQueue queue
+	Uptime         time.Duration `json:"uptime,omitempty"`      // Time since StartedAt
+	StartedAt      time.Time     `json:"started_at,omitempty"`  // When Start or the first WebhookHandler was called
+}
+
+// Health reports whether the bot is receiving updates by polling or webhook,
+// when it last received an update, whether the Bot API answers a getMe ping,
+// and how much work is queued. The ping result is reused for 5 seconds and a
+// ping taking longer than that counts as a failure, so probes can call Health
+// frequently.
+//
+// Example:
+//
+//	status := bot.Health()
+//	if !status.Healthy {
+//		log.Printf("bot unhealthy: %+v", status)
+//	}
+func (b *Bot) Health() HealthStatus {
+	status := HealthStatus{
+		Polling:        b.polling.Load(),
+		Webhook:        b.webhook.Load(),
+		ActiveHandlers: b.activeHandlers.Load(),
+		ActiveFlows:    b.flowManager.activeFlowCount(),
+		MaxActiveFlows: b.flowManager.maxActiveFlows(),
+		EvictedFlows:   b.flowManager.capacity.evicted.Load(),
+		Duplicates:     b.duplicateUpdates.Load(),
+		DroppedUpdates: b.droppedUpdates.Load(),
+	}
+
+	if last := b.lastUpdate.Load(); last != 0 {
+		status.LastUpdate = time.Unix(0, last)
+	}
+	if started := b.startedAt.Load(); started != 0 {
+		status.StartedAt = time.Unix(0, started)
+		status.Uptime = b.clock.Now().Sub(status.StartedAt)
+	}
+
+	b.updatesMu.Lock()
+	if b.updates != nil {
+		status.QueuedUpdates = len(b.updates)
+	}
+	if b.queue != nil {
+		status.QueuedUpdates += len(b.queue.items)
+	}
+	b.updatesMu.Unlock()
+
+	latency, err := b.pingAPI()
+	status.APILatency = latency
+	status.APIReachable = err == nil
+	if err != nil {
+		status.APIError = err.Error()
+	}
+
+	status.Healthy = (status.Polling || status.Webhook) && status.APIReachable
+	return status
+}
+
+// pingAPI calls getMe, or returns the result of a ping made within the last
+// healthPingTTL.
+func (b *Bot) pingAPI() (time.Duration, error) {
+	b.ping.mu.Lock()
+	defer b.ping.mu.Unlock()
+	now := b.clock.Now()
+	if !b.ping.at.IsZero() && now.Sub(b.ping.at) < healthPingTTL {
+		return b.ping.latency, b.ping.err
+	}
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		_, err := b.api.GetMe()
+		result <- err
+	}()
+	var err error
+	select {
+	case err = <-result:
+	case <-time.After(healthPingTimeout):
+		err = errors.New("getMe timed out")
+	}
+
+	b.ping.at = now
+	b.ping.latency = time.Since(start)
+	b.ping.err = b.secrets.scrubError(err)
+	return b.ping.latency, b.ping.err
+}
+
+// HealthHandler returns an HTTP handler that reports Bot.Health as JSON. It
+// answers 200 OK when the bot is healthy and 503 Service Unavailable otherwise,
+// which makes it suitable for Kubernetes liveness and readiness probes.
+//
+// Example:
+//
+//	http.Handle("/healthz", bot.HealthHandler())
+//	go http.ListenAndServe(":8080", nil)
+func (b *Bot) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := b.Health()
+
+		w.Header().Set("Content-Type", "application/json")
+		if status.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
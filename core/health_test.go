@@ -0,0 +1,115 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestHealth_ReportsPollingAndLastUpdate(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+	updates := make(chan tgbotapi.Update, 10)
+	mockClient.GetUpdatesChanFunc = func(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+		return updates
+	}
+
+	if status := bot.Health(); status.Healthy || status.Polling {
+		t.Errorf("Expected unhealthy bot before Start, got %+v", status)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = bot.Start()
+		close(done)
+	}()
+
+	updates <- createCaptchaAnswerUpdate(42, 42, "hello")
+	deadline := time.Now().Add(time.Second)
+	for bot.Health().LastUpdate.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	status := bot.Health()
+	if !status.Healthy || !status.Polling || !status.APIReachable {
+		t.Errorf("Expected healthy polling bot, got %+v", status)
+	}
+	if status.LastUpdate.IsZero() || status.StartedAt.IsZero() {
+		t.Errorf("Expected last update and start timestamps, got %+v", status)
+	}
+
+	close(updates)
+	<-done
+	if bot.Health().Polling {
+		t.Error("Expected polling to stop when the update channel closes")
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bot, mockClient, _, _ := createTestBot(WithClock(clock))
+	bot.polling.Store(true)
+
+	recorder := httptest.NewRecorder()
+	bot.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", recorder.Code)
+	}
+
+	mockClient.GetMeFunc = func() (tgbotapi.User, error) {
+		return tgbotapi.User{}, errors.New("network down")
+	}
+	clock.now = clock.now.Add(healthPingTTL)
+	recorder = httptest.NewRecorder()
+	bot.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", recorder.Code)
+	}
+
+	var status HealthStatus
+	if err := json.NewDecoder(recorder.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if status.APIReachable || status.APIError != "network down" {
+		t.Errorf("Unexpected status body: %+v", status)
+	}
+}
+
+func TestHealth_WebhookAndCachedPing(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bot, mockClient, _, _ := createTestBot(WithClock(clock))
+
+	if _, err := bot.WebhookHandler(WebhookConfig{}); err != nil {
+		t.Fatalf("WebhookHandler failed: %v", err)
+	}
+	if status := bot.Health(); !status.Healthy || !status.Webhook || status.Polling {
+		t.Errorf("Expected a healthy webhook bot, got %+v", status)
+	}
+
+	bot.Health()
+	if mockClient.GetMeCalls != 1 {
+		t.Errorf("Expected the ping to be cached, got %d getMe calls", mockClient.GetMeCalls)
+	}
+	clock.now = clock.now.Add(healthPingTTL)
+	bot.Health()
+	if mockClient.GetMeCalls != 2 {
+		t.Errorf("Expected a new ping once the cache expired, got %d getMe calls", mockClient.GetMeCalls)
+	}
+}
+
+func TestHealth_LastUpdateUsesClock(t *testing.T) {
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bot, _, _, _ := createTestBot(WithClock(clock))
+	reaction := `{"update_id": 9, "message_reaction": {"chat": {"id": -100, "type": "supergroup"}, "message_id": 5, "user": {"id": 42}, "date": 0, "old_reaction": [], "new_reaction": [{"type": "emoji", "emoji": "👍"}]}}`
+	if err := bot.ProcessExternalUpdateJSON([]byte(reaction)); err != nil {
+		t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+	}
+
+	if last := bot.Health().LastUpdate; !last.Equal(clock.now) {
+		t.Errorf("Expected last update at %v from the bot's clock, got %v", clock.now, last)
+	}
+}
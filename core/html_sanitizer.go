@@ -0,0 +1,249 @@
+package teleflow
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// HTMLSanitizer cleans untrusted HTML, such as content from a CMS, so it can be
+// sent with ParseModeHTML. Tags Telegram supports are kept with their supported
+// attributes; other tags are removed but their text is kept, except for the
+// contents of script and style. Paragraphs, line breaks and list items become
+// line breaks. Text is re-escaped, so stray < and & never reach Telegram.
+type HTMLSanitizer struct {
+	// StripLinks turns links into their plain text.
+	StripLinks bool
+	// LinkSchemes are the URL schemes links may use. Defaults to http, https and
+	// tg. Links with other schemes, or relative links, become plain text.
+	LinkSchemes []string
+}
+
+// SanitizeHTML cleans s with the default HTMLSanitizer. HTML templates can use
+// it as the sanitize function.
+//
+// Example:
+//
+//	teleflow.AddTemplate("article", "<b>{{.title | escape}}</b>\n\n{{.body | sanitize}}", teleflow.ParseModeHTML)
+func SanitizeHTML(s string) string {
+	return HTMLSanitizer{}.Sanitize(s)
+}
+
+var (
+	sanitizerTagPattern  = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:\s+[^\s=>/]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s>"']+))?)*)\s*/?>`)
+	sanitizerAttrPattern = regexp.MustCompile(`([^\s=>/]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>"']+)))?`)
+)
+
+// sanitizerTagAliases maps tag names to the name they are written with.
+var sanitizerTagAliases = map[string]string{
+	"b": "b", "strong": "b",
+	"i": "i", "em": "i",
+	"u": "u", "ins": "u",
+	"s": "s", "strike": "s", "del": "s",
+	"a": "a", "code": "code", "pre": "pre", "blockquote": "blockquote",
+	"span": "span", "tg-spoiler": "tg-spoiler", "tg-emoji": "tg-emoji",
+}
+
+// sanitizerBreaks are tags that end a line when they open or close.
+var sanitizerBreaks = map[string]bool{"br": true, "p": true, "div": true, "li": true, "tr": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true}
+
+// Sanitize returns s reduced to the HTML Telegram accepts.
+func (p HTMLSanitizer) Sanitize(s string) string {
+	var out strings.Builder
+	var open []string
+	skip := ""
+
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			lt = len(s)
+		}
+		if skip == "" {
+			out.WriteString(EscapeHTML(html.UnescapeString(s[:lt])))
+		}
+		s = s[lt:]
+		if s == "" {
+			break
+		}
+
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				break
+			}
+			s = s[end+3:]
+			continue
+		}
+
+		m := sanitizerTagPattern.FindStringSubmatch(s)
+		if m == nil {
+			if skip == "" {
+				out.WriteString("&lt;")
+			}
+			s = s[1:]
+			continue
+		}
+		s = s[len(m[0]):]
+		closing, name, attrs := m[1] == "/", strings.ToLower(m[2]), m[3]
+
+		if skip != "" {
+			if closing && name == skip {
+				skip = ""
+			}
+			continue
+		}
+		if name == "script" || name == "style" {
+			if !closing && !strings.HasSuffix(m[0], "/>") {
+				skip = name
+			}
+			continue
+		}
+
+		if closing {
+			open = p.closeTag(&out, open, name)
+			continue
+		}
+
+		if name == "li" {
+			out.WriteString("\n• ")
+			continue
+		}
+		if sanitizerBreaks[name] {
+			out.WriteString("\n")
+			continue
+		}
+
+		tag, ok := p.openTag(open, name, attrs)
+		if alias, supported := sanitizerTagAliases[name]; supported {
+			if !ok {
+				// Remembered so its closing tag doesn't close an outer tag
+				alias = droppedTag + alias
+			}
+			open = append(open, alias)
+		}
+		out.WriteString(tag)
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		writeClosingTag(&out, open[i])
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// openTag returns the cleaned opening tag, or false when the tag is dropped.
+func (p HTMLSanitizer) openTag(open []string, name, attrs string) (string, bool) {
+	tag, ok := sanitizerTagAliases[name]
+	if !ok {
+		return "", false
+	}
+	// Telegram allows no entities inside code, and only code inside pre
+	parent := innermostTag(open)
+	switch parent {
+	case "code":
+		return "", false
+	case "pre":
+		if tag != "code" {
+			return "", false
+		}
+	}
+
+	values := map[string]string{}
+	for _, attr := range sanitizerAttrPattern.FindAllStringSubmatch(attrs, -1) {
+		values[strings.ToLower(attr[1])] = html.UnescapeString(attr[2] + attr[3] + attr[4])
+	}
+	_, hasExpandable := values["expandable"]
+
+	switch tag {
+	case "a":
+		href := strings.TrimSpace(values["href"])
+		if p.StripLinks || !p.allowedLink(href) {
+			return "", false
+		}
+		return `<a href="` + EscapeHTML(href) + `">`, true
+	case "span":
+		if values["class"] != "tg-spoiler" {
+			return "", false
+		}
+		return `<span class="tg-spoiler">`, true
+	case "tg-emoji":
+		id := values["emoji-id"]
+		if id == "" || strings.Trim(id, "0123456789") != "" {
+			return "", false
+		}
+		return `<tg-emoji emoji-id="` + id + `">`, true
+	case "code":
+		if class := values["class"]; strings.HasPrefix(class, "language-") && parent == "pre" {
+			return `<code class="` + EscapeHTML(class) + `">`, true
+		}
+		return "<code>", true
+	case "blockquote":
+		if hasExpandable {
+			return "<blockquote expandable>", true
+		}
+		return "<blockquote>", true
+	default:
+		return "<" + tag + ">", true
+	}
+}
+
+// closeTag closes name if it is open, closing any tags opened inside it first.
+// Closing tags that match nothing are dropped.
+func (p HTMLSanitizer) closeTag(out *strings.Builder, open []string, name string) []string {
+	if sanitizerBreaks[name] {
+		if name != "br" && name != "li" {
+			out.WriteString("\n")
+		}
+		return open
+	}
+	tag, ok := sanitizerTagAliases[name]
+	if !ok {
+		return open
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		if strings.TrimPrefix(open[i], droppedTag) != tag {
+			continue
+		}
+		for j := len(open) - 1; j >= i; j-- {
+			writeClosingTag(out, open[j])
+		}
+		return open[:i]
+	}
+	return open
+}
+
+// droppedTag marks supported tags on the open stack that were removed.
+const droppedTag = "-"
+
+// innermostTag returns the innermost tag that was kept, or "" for none.
+func innermostTag(open []string) string {
+	for i := len(open) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(open[i], droppedTag) {
+			return open[i]
+		}
+	}
+	return ""
+}
+
+func writeClosingTag(out *strings.Builder, tag string) {
+	if !strings.HasPrefix(tag, droppedTag) {
+		out.WriteString("</" + tag + ">")
+	}
+}
+
+func (p HTMLSanitizer) allowedLink(href string) bool {
+	schemes := p.LinkSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https", "tg"}
+	}
+	colon := strings.IndexByte(href, ':')
+	if colon <= 0 {
+		return false
+	}
+	scheme := strings.ToLower(href[:colon])
+	for _, allowed := range schemes {
+		if scheme == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package teleflow
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"keeps supported tags", "<strong>Hi</strong> <em>there</em> <del>old</del>", "<b>Hi</b> <i>there</i> <s>old</s>"},
+		{"strips unsupported tags", `<div class="x"><font color="red">red</font> <img src="a.png"></div>`, "red"},
+		{"paragraphs and lists", "<p>One</p><ul><li>a</li><li>b</li></ul>", "One\n\n• a\n• b"},
+		{"drops script contents", "safe<script>alert(1)</script> text", "safe text"},
+		{"escapes stray characters", "1 < 2 & 3 &gt; 2 &nbsp;ok", "1 &lt; 2 &amp; 3 &gt; 2  ok"},
+		{"keeps http links", `<a href="https://example.com/?a=1&amp;b=2" onclick="x()">site</a>`, `<a href="https://example.com/?a=1&amp;b=2">site</a>`},
+		{"drops javascript links", `<a href="javascript:alert(1)">click</a>`, "click"},
+		{"spoiler span only", `<span class="tg-spoiler">s<span style="x">t</span>u</span>`, `<span class="tg-spoiler">stu</span>`},
+		{"nothing inside code", "<code><b>x</b></code>", "<code>x</code>"},
+		{"code language inside pre", `<pre><code class="language-go">a := 1</code></pre>`, `<pre><code class="language-go">a := 1</code></pre>`},
+		{"closes unclosed tags", "<b>bold <i>both</b> after", "<b>bold <i>both</i></b> after"},
+		{"drops unmatched closing tags", "text</b></i>", "text"},
+		{"expandable quote", "<blockquote expandable>q</blockquote>", "<blockquote expandable>q</blockquote>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.in); got != tt.want {
+				t.Errorf("SanitizeHTML(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	strip := HTMLSanitizer{StripLinks: true}
+	if got := strip.Sanitize(`see <a href="https://evil.example">this</a>`); got != "see this" {
+		t.Errorf("Expected links to be stripped, got %q", got)
+	}
+}
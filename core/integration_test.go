@@ -506,7 +506,7 @@ func TestBot_MultiStepFlowIntegration(t *testing.T) {
 	}
 
 	// Verify flow state
-	if !bot.flowManager.isUserInFlow(testUserID) {
+	if !bot.flowManager.isUserInFlow(testUserID, testUserID) {
 		t.Error("Expected user to be in flow after starting")
 	}
 
@@ -715,7 +715,7 @@ func TestBot_MultiStepFlowIntegration(t *testing.T) {
 	}
 
 	// Verify user is no longer in flow
-	if bot.flowManager.isUserInFlow(testUserID) {
+	if bot.flowManager.isUserInFlow(testUserID, testUserID) {
 		t.Error("Expected user to no longer be in flow after completion")
 	}
 
@@ -18,16 +18,35 @@ type MessageCleaner interface {
 	EditMessageReplyMarkup(ctx *Context, messageID int, replyMarkup interface{}) error
 }
 
+// BatchMessageCleaner is an optional extension of MessageCleaner for cleaners
+// that can delete several messages in one call. Cleaners without it have their
+// messages deleted one at a time.
+type BatchMessageCleaner interface {
+	// DeleteMessages deletes several messages of the context's chat at once.
+	DeleteMessages(ctx *Context, messageIDs []int) error
+}
+
 // ContextFlowOperations defines methods for interacting with user flows from the context.
+// Every operation receives both the user and the chat so that chat-scoped flows
+// can be resolved alongside per-user flows.
 type ContextFlowOperations interface {
 	// SetUserFlowData sets flow-specific data for a user.
-	setUserFlowData(userID int64, key string, value interface{}) error
+	setUserFlowData(userID, chatID int64, key string, value interface{}) error
 	// GetUserFlowData retrieves flow-specific data for a user.
-	getUserFlowData(userID int64, key string) (interface{}, bool)
+	getUserFlowData(userID, chatID int64, key string) (interface{}, bool)
 	// StartFlow starts a flow for a user.
-	startFlow(userID int64, flowName string, ctx *Context) error
+	startFlow(userID, chatID int64, flowName string, ctx *Context) error
+	// StartFlowWith starts a flow for a user with explicit start options.
+	startFlowWith(userID, chatID int64, flowName string, opts flowStartOptions, ctx *Context) error
 	// IsUserInFlow checks if a user is currently in a flow.
-	isUserInFlow(userID int64) bool
-	// CancelFlow cancels the current flow for a user.
-	cancelFlow(userID int64)
+	isUserInFlow(userID, chatID int64) bool
+	// CurrentStep returns the flow a user is in and its current step.
+	currentStep(userID, chatID int64) (flowName, stepName string, ok bool)
+	// FlowProgress returns how far a user got in their flow.
+	flowProgress(userID, chatID int64) (FlowProgress, bool)
+	// CancelFlow cancels the current flow for a user. The context, if not nil,
+	// is used to clean up messages the flow sent.
+	cancelFlow(userID, chatID int64, ctx *Context)
+	// UpdateKeyboard replaces the inline keyboard of the flow's last prompt.
+	updateKeyboard(userID, chatID int64, keyboard KeyboardFunc, ctx *Context) error
 }
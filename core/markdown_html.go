@@ -0,0 +1,168 @@
+package teleflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// templateActionPattern matches Go template actions, which markdownToHTML copies
+// unchanged.
+var templateActionPattern = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+
+// markdownSpans are the paired markers of simple Markdown, longest first so
+// "**" is not read as two "*".
+var markdownSpans = []struct {
+	marker string
+	open   string
+	close  string
+}{
+	{"**", "<b>", "</b>"},
+	{"__", "<u>", "</u>"},
+	{"~~", "<s>", "</s>"},
+	{"||", "<tg-spoiler>", "</tg-spoiler>"},
+	{"*", "<i>", "</i>"},
+	{"_", "<i>", "</i>"},
+}
+
+// markdownToHTML converts a template written in simple Markdown to an HTML
+// template. It understands **bold**, *italic* or _italic_, __underline__,
+// ~~strikethrough~~, ||spoilers||, `code`, ```pre``` blocks and [links](url).
+// A backslash makes the next character literal. Other text is HTML-escaped, and
+// template actions are left as they are.
+func markdownToHTML(src string) string {
+	var actions []string
+	src = templateActionPattern.ReplaceAllStringFunc(src, func(action string) string {
+		actions = append(actions, action)
+		return fmt.Sprintf("\x00%d\x00", len(actions)-1)
+	})
+
+	out := convertMarkdownInline(src)
+
+	for i, action := range actions {
+		out = strings.Replace(out, fmt.Sprintf("\x00%d\x00", i), action, 1)
+	}
+	return out
+}
+
+func convertMarkdownInline(src string) string {
+	var out strings.Builder
+	for i := 0; i < len(src); {
+		rest := src[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1:
+			r, size := utf8.DecodeRuneInString(rest[1:])
+			out.WriteString(EscapeHTML(string(r)))
+			i += 1 + size
+			continue
+
+		case strings.HasPrefix(rest, "```"):
+			if end := strings.Index(rest[3:], "```"); end >= 0 {
+				out.WriteString(markdownPre(rest[3 : 3+end]))
+				i += 3 + end + 3
+				continue
+			}
+
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				out.WriteString("<code>" + EscapeHTML(rest[1:1+end]) + "</code>")
+				i += 1 + end + 1
+				continue
+			}
+
+		case rest[0] == '[':
+			if text, url, n, ok := markdownLink(rest); ok {
+				out.WriteString(`<a href="` + EscapeHTML(url) + `">` + convertMarkdownInline(text) + "</a>")
+				i += n
+				continue
+			}
+		}
+
+		if html, n, ok := markdownSpan(src, i); ok {
+			out.WriteString(html)
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		out.WriteString(EscapeHTML(string(r)))
+		i += size
+	}
+	return out.String()
+}
+
+// markdownSpan converts a formatting span starting at src[i], returning the HTML
+// and the number of bytes consumed. Single "*" and "_" only format whole words,
+// so snake_case and 2*3*4 stay as they are.
+func markdownSpan(src string, i int) (string, int, bool) {
+	for _, span := range markdownSpans {
+		if !strings.HasPrefix(src[i:], span.marker) {
+			continue
+		}
+		single := len(span.marker) == 1
+		if single && i > 0 && isWordRune(lastRune(src[:i])) {
+			return "", 0, false
+		}
+		start := i + len(span.marker)
+		for search := start; search < len(src); {
+			end := strings.Index(src[search:], span.marker)
+			if end < 0 {
+				break
+			}
+			end += search
+			after := end + len(span.marker)
+			if end == start || (single && after < len(src) && isWordRune(firstRune(src[after:]))) {
+				search = end + 1
+				continue
+			}
+			return span.open + convertMarkdownInline(src[start:end]) + span.close, after - i, true
+		}
+		return "", 0, false
+	}
+	return "", 0, false
+}
+
+// markdownLink parses [text](url) at the start of s.
+func markdownLink(s string) (text, url string, n int, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 0 {
+		return "", "", 0, false
+	}
+	closeURL := strings.IndexByte(s[closeText+2:], ')')
+	if closeURL < 0 {
+		return "", "", 0, false
+	}
+	return s[1:closeText], s[closeText+2 : closeText+2+closeURL], closeText + 2 + closeURL + 1, true
+}
+
+// markdownPre converts the contents of a ``` block. A language name on the
+// opening line becomes the code's language class.
+func markdownPre(block string) string {
+	if nl := strings.IndexByte(block, '\n'); nl >= 0 {
+		lang := block[:nl]
+		if lang != "" && !strings.ContainsAny(lang, " \t") {
+			return `<pre><code class="language-` + EscapeHTML(lang) + `">` + EscapeHTML(block[nl+1:]) + "</code></pre>"
+		}
+		if lang == "" {
+			block = block[nl+1:]
+		}
+	}
+	return "<pre>" + EscapeHTML(block) + "</pre>"
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
@@ -0,0 +1,42 @@
+package teleflow
+
+import "testing"
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"**Total:** 5 < 6 & *done*", "<b>Total:</b> 5 &lt; 6 &amp; <i>done</i>"},
+		{"__under__ ~~gone~~ _it_ ||hidden||", "<u>under</u> <s>gone</s> <i>it</i> <tg-spoiler>hidden</tg-spoiler>"},
+		{"**bold _and italic_**", "<b>bold <i>and italic</i></b>"},
+		{"snake_case_name and 2*3*4", "snake_case_name and 2*3*4"},
+		{"`a*b<c>` and ```go\nx := 1 < 2\n```", "<code>a*b&lt;c&gt;</code> and <pre><code class=\"language-go\">x := 1 &lt; 2\n</code></pre>"},
+		{"[**docs**](https://example.com/?a=1&b=2)", "<a href=\"https://example.com/?a=1&amp;b=2\"><b>docs</b></a>"},
+		{`\*not italic\* 1.5!`, "*not italic* 1.5!"},
+		{"**unclosed", "**unclosed"},
+		{"Hi **{{.name}}**, {{if .vip}}_VIP_{{end}} {{.a | printf \"%s*\"}}", "Hi <b>{{.name}}</b>, {{if .vip}}<i>VIP</i>{{end}} {{.a | printf \"%s*\"}}"},
+	}
+	for _, tt := range tests {
+		if got := markdownToHTML(tt.src); got != tt.want {
+			t.Errorf("markdownToHTML(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateManager_SimpleMarkdown(t *testing.T) {
+	tm := newTemplateManager()
+	if err := tm.AddTemplate("receipt", "**Paid** {{.amount}} for _{{.item | escape}}_ (incl. tax)!", ParseModeSimpleMarkdown); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+	text, mode, err := tm.RenderTemplate("receipt", map[string]interface{}{"amount": "$5", "item": "R&D"})
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if mode != ParseModeHTML {
+		t.Errorf("Expected template to be sent as HTML, got %q", mode)
+	}
+	if want := "<b>Paid</b> $5 for <i>R&amp;D</i> (incl. tax)!"; text != want {
+		t.Errorf("Expected %q, got %q", want, text)
+	}
+}
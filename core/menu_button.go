@@ -0,0 +1,101 @@
+package teleflow
+
+import (
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// MenuButtonConfig describes the button shown next to the message input field.
+// Use MenuButtonCommands, MenuButtonWebApp or MenuButtonDefault to create one.
+type MenuButtonConfig struct {
+	Type      string // "commands", "web_app" or "default"
+	Text      string // Button label for web_app buttons
+	WebAppURL string // HTTPS URL of the Web App for web_app buttons
+}
+
+// MenuButtonCommands shows the bot's command list when the menu button is pressed.
+func MenuButtonCommands() MenuButtonConfig {
+	return MenuButtonConfig{Type: "commands"}
+}
+
+// MenuButtonWebApp opens a Web App when the menu button is pressed.
+func MenuButtonWebApp(text, url string) MenuButtonConfig {
+	return MenuButtonConfig{Type: "web_app", Text: text, WebAppURL: url}
+}
+
+// MenuButtonDefault resets the menu button to Telegram's default behavior.
+func MenuButtonDefault() MenuButtonConfig {
+	return MenuButtonConfig{Type: "default"}
+}
+
+// toAPI converts the configuration into the MenuButton object expected by the Bot API.
+func (cfg MenuButtonConfig) toAPI() map[string]interface{} {
+	button := map[string]interface{}{"type": cfg.Type}
+	if cfg.Type == "web_app" {
+		button["text"] = cfg.Text
+		button["web_app"] = map[string]string{"url": cfg.WebAppURL}
+	}
+	return button
+}
+
+// WithMenuButton returns a BotOption that sets the default menu button for all
+// private chats when the bot starts. Use SetMenuButtonForChat or
+// ctx.UpdateMenuButton to change it for individual chats at runtime.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token,
+//		teleflow.WithMenuButton(teleflow.MenuButtonWebApp("Shop", "https://example.com/shop")),
+//	)
+func WithMenuButton(cfg MenuButtonConfig) BotOption {
+	return func(b *Bot) {
+		b.menuButton = &cfg
+	}
+}
+
+// SetMenuButtonForChat changes the menu button of a single private chat, e.g. after
+// a user logs in or their role changes. A chatID of 0 changes the default button.
+//
+// Example:
+//
+//	err := bot.SetMenuButtonForChat(userID, teleflow.MenuButtonWebApp("Dashboard", dashboardURL))
+func (b *Bot) SetMenuButtonForChat(chatID int64, cfg MenuButtonConfig) error {
+	return setChatMenuButton(b.sender, chatID, cfg)
+}
+
+// UpdateMenuButton changes the menu button of the current chat.
+//
+// Example:
+//
+//	if err := ctx.UpdateMenuButton(teleflow.MenuButtonCommands()); err != nil {
+//		return err
+//	}
+func (c *Context) UpdateMenuButton(cfg MenuButtonConfig) error {
+	return setChatMenuButton(c.telegramClient, c.ChatID(), cfg)
+}
+
+// applyStartupMenuButton sets the menu button configured with WithMenuButton.
+func (b *Bot) applyStartupMenuButton() {
+	if b.menuButton == nil {
+		return
+	}
+	if err := b.SetMenuButtonForChat(0, *b.menuButton); err != nil {
+		log.Printf("Warning: Failed to set menu button: %v", err)
+	}
+}
+
+// setChatMenuButton calls setChatMenuButton for chatID, or for the default button if chatID is 0.
+func setChatMenuButton(client TelegramClient, chatID int64, cfg MenuButtonConfig) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	if err := params.AddInterface("menu_button", cfg.toAPI()); err != nil {
+		return fmt.Errorf("failed to encode menu button: %w", err)
+	}
+
+	if _, err := makeRawRequest(client, "setChatMenuButton", params); err != nil {
+		return fmt.Errorf("failed to set menu button: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package teleflow
+
+import "testing"
+
+func TestSetMenuButtonForChat(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	if err := bot.SetMenuButtonForChat(42, MenuButtonWebApp("Shop", "https://example.com/shop")); err != nil {
+		t.Fatalf("SetMenuButtonForChat failed: %v", err)
+	}
+
+	call := mockClient.MakeRequestCalls[0]
+	if call.Endpoint != "setChatMenuButton" || call.Params["chat_id"] != "42" {
+		t.Errorf("Unexpected request: %+v", call)
+	}
+	expected := `{"text":"Shop","type":"web_app","web_app":{"url":"https://example.com/shop"}}`
+	if call.Params["menu_button"] != expected {
+		t.Errorf("Expected menu_button %s, got %s", expected, call.Params["menu_button"])
+	}
+}
+
+func TestContext_UpdateMenuButton(t *testing.T) {
+	mockClient := NewMockTelegramClient()
+	ctx := newContext(createStartUpdate(""), mockClient, NewMockTemplateManager(), NewMockFlowManager(), NewMockPromptComposer(), NewMockAccessManager())
+
+	if err := ctx.UpdateMenuButton(MenuButtonCommands()); err != nil {
+		t.Fatalf("UpdateMenuButton failed: %v", err)
+	}
+
+	call := mockClient.MakeRequestCalls[0]
+	if call.Params["chat_id"] != "42" || call.Params["menu_button"] != `{"type":"commands"}` {
+		t.Errorf("Unexpected request: %+v", call)
+	}
+}
+
+func TestWithMenuButton_AppliedOnStart(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot(WithMenuButton(MenuButtonDefault()))
+
+	bot.applyStartupMenuButton()
+
+	if len(mockClient.MakeRequestCalls) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(mockClient.MakeRequestCalls))
+	}
+	if _, ok := mockClient.MakeRequestCalls[0].Params["chat_id"]; ok {
+		t.Error("Expected default menu button request without chat_id")
+	}
+}
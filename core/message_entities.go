@@ -0,0 +1,83 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// MessageEntity marks a formatted range of a prompt's text, as an alternative to
+// a parse mode. Entities express formatting parse modes cannot, such as custom
+// emoji and mentions of users without a username. Offset and Length are counted
+// in UTF-16 code units; use UTF16Len to compute them.
+//
+// Example:
+//
+//	text := "Welcome, Ann 👋"
+//	ctx.SendPrompt(&teleflow.PromptConfig{
+//		Message: text,
+//		Entities: []teleflow.MessageEntity{
+//			{Type: "text_mention", Offset: teleflow.UTF16Len("Welcome, "), Length: 3, User: &tgbotapi.User{ID: annID}},
+//			{Type: "custom_emoji", Offset: teleflow.UTF16Len("Welcome, Ann "), Length: 2, CustomEmojiID: "5368324170671202286"},
+//		},
+//	})
+type MessageEntity struct {
+	Type          string         `json:"type"` // e.g. "bold", "text_link", "text_mention", "custom_emoji"
+	Offset        int            `json:"offset"`
+	Length        int            `json:"length"`
+	URL           string         `json:"url,omitempty"`             // For "text_link"
+	User          *tgbotapi.User `json:"user,omitempty"`            // For "text_mention"
+	Language      string         `json:"language,omitempty"`        // For "pre"
+	CustomEmojiID string         `json:"custom_emoji_id,omitempty"` // For "custom_emoji"
+}
+
+// UTF16Len returns the length of s in UTF-16 code units, the unit of entity
+// offsets and lengths.
+func UTF16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// errEntitiesWithParseMode is returned for prompts that combine entities with a
+// template that has a parse mode.
+var errEntitiesWithParseMode = errors.New("entities cannot be combined with a parse mode")
+
+// tgEntities converts entities to the library's type. It reports false when an
+// entity needs a field the library does not know, such as a custom emoji ID.
+func tgEntities(entities []MessageEntity) ([]tgbotapi.MessageEntity, bool) {
+	if len(entities) == 0 {
+		return nil, true
+	}
+	converted := make([]tgbotapi.MessageEntity, 0, len(entities))
+	for _, entity := range entities {
+		if entity.CustomEmojiID != "" {
+			return nil, false
+		}
+		converted = append(converted, tgbotapi.MessageEntity{
+			Type:     entity.Type,
+			Offset:   entity.Offset,
+			Length:   entity.Length,
+			URL:      entity.URL,
+			User:     entity.User,
+			Language: entity.Language,
+		})
+	}
+	return converted, true
+}
+
+// sendTextWithEntities sends a text message whose entities the library cannot
+// encode through the raw sendMessage method.
+func sendTextWithEntities(client TelegramClient, chatID int64, text string, entities []MessageEntity, replyMarkup interface{}) (tgbotapi.Message, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params.AddNonEmpty("text", text)
+	if err := params.AddInterface("entities", entities); err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("failed to encode entities: %w", err)
+	}
+	if err := params.AddInterface("reply_markup", replyMarkup); err != nil {
+		return tgbotapi.Message{}, fmt.Errorf("failed to encode reply markup: %w", err)
+	}
+
+	return sendRawMessage(client, "sendMessage", params)
+}
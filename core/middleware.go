@@ -145,7 +145,7 @@ func RateLimitMiddleware(requestsPerMinute int) MiddlewareFunc {
 	return func(next HandlerFunc) HandlerFunc {
 		return func(ctx *Context) error {
 			userID := ctx.UserID()
-			now := time.Now()
+			now := ctx.Now()
 
 			mutex.Lock()
 			defer mutex.Unlock()
@@ -25,12 +25,14 @@ func captureLogOutput(f func()) string {
 // Mock handler for testing
 type mockHandler struct {
 	called    bool
+	callCount int
 	err       error
 	sleepTime time.Duration
 }
 
 func (m *mockHandler) Handle(ctx *Context) error {
 	m.called = true
+	m.callCount++
 	if m.sleepTime > 0 {
 		time.Sleep(m.sleepTime)
 	}
@@ -891,3 +893,142 @@ func TestAuthMiddleware_NilAccessManager(t *testing.T) {
 
 	_ = wrappedHandler(ctx)
 }
+
+func TestFloodControlMiddleware_WarnsAndDropsExcessMessages(t *testing.T) {
+	middleware := FloodControlMiddleware(FloodControlConfig{
+		Default: FloodRule{MaxMessages: 2, Window: time.Minute},
+	})
+
+	handler := &mockHandler{}
+	wrapped := middleware(handler.Handle)
+	ctx, mockClient := createAuthMiddlewareTestContext("group_message", 42, -100)
+
+	for i := 0; i < 4; i++ {
+		if err := wrapped(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if handler.callCount != 2 {
+		t.Errorf("Expected handler to be called 2 times, got %d", handler.callCount)
+	}
+	if len(mockClient.SendCalls) != 1 {
+		t.Errorf("Expected exactly one warning message, got %d", len(mockClient.SendCalls))
+	}
+}
+
+func TestFloodControlMiddleware_CachesAdminLookups(t *testing.T) {
+	middleware := FloodControlMiddleware(FloodControlConfig{
+		Default: FloodRule{MaxMessages: 1, Window: time.Minute},
+	})
+
+	handler := &mockHandler{}
+	wrapped := middleware(handler.Handle)
+	ctx, mockClient := createAuthMiddlewareTestContext("group_message", 42, -100)
+	clock := &stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ctx.clock = clock
+	mockClient.RequestFunc = func(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		if _, ok := c.(tgbotapi.GetChatMemberConfig); ok {
+			return &tgbotapi.APIResponse{Ok: true, Result: []byte(`{"status":"member","user":{"id":42}}`)}, nil
+		}
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+
+	for i := 0; i < 4; i++ {
+		_ = wrapped(ctx)
+	}
+	if got := countRequests[tgbotapi.GetChatMemberConfig](mockClient.RequestCalls); got != 1 {
+		t.Errorf("Expected one admin lookup for repeated over-limit messages, got %d", got)
+	}
+
+	clock.now = clock.now.Add(chatAdminTTL + time.Second)
+	_ = wrapped(ctx)
+	_ = wrapped(ctx)
+	if got := countRequests[tgbotapi.GetChatMemberConfig](mockClient.RequestCalls); got != 2 {
+		t.Errorf("Expected the admin lookup to expire, got %d lookups", got)
+	}
+}
+
+func TestFloodState_Stale(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := &floodState{timestamps: []time.Time{now}, window: time.Minute}
+
+	if state.stale(now.Add(30 * time.Second)) {
+		t.Error("Expected state with messages inside the window to be kept")
+	}
+	if !state.stale(now.Add(time.Minute)) {
+		t.Error("Expected state to be stale once its messages left the window")
+	}
+	state.triggered = now.Add(time.Minute)
+	if state.stale(now.Add(90 * time.Second)) {
+		t.Error("Expected state to be kept while its last reaction is in the window")
+	}
+}
+
+func TestFloodControlMiddleware_PerChatRuleMutes(t *testing.T) {
+	middleware := FloodControlMiddleware(FloodControlConfig{
+		Default: FloodRule{MaxMessages: 10},
+		PerChat: map[int64]FloodRule{
+			-100: {MaxMessages: 1, Window: time.Minute, Action: FloodMute},
+		},
+	})
+
+	handler := &mockHandler{}
+	wrapped := middleware(handler.Handle)
+	ctx, mockClient := createAuthMiddlewareTestContext("group_message", 42, -100)
+
+	_ = wrapped(ctx)
+	_ = wrapped(ctx)
+
+	muted := false
+	for _, call := range mockClient.RequestCalls {
+		if restrict, ok := call.(tgbotapi.RestrictChatMemberConfig); ok && restrict.UserID == 42 && restrict.UntilDate > 0 {
+			muted = true
+		}
+	}
+	if !muted {
+		t.Error("Expected user to be muted by the per-chat rule")
+	}
+	if len(mockClient.SendCalls) != 0 {
+		t.Errorf("Expected no warning for mute-only rule, got %d messages", len(mockClient.SendCalls))
+	}
+}
+
+func TestWithFloodControl_CountsUnhandledGroupMessages(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot(WithFloodControl(FloodControlConfig{
+		Default: FloodRule{MaxMessages: 2, Window: time.Minute, Action: FloodMute},
+	}))
+	mockClient.RequestFunc = func(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		if _, ok := c.(tgbotapi.GetChatMemberConfig); ok {
+			return &tgbotapi.APIResponse{Ok: true, Result: []byte(`{"status":"member","user":{"id":42}}`)}, nil
+		}
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+
+	// No handler matches plain chatter
+	for i := 0; i < 3; i++ {
+		bot.processUpdate(createCaptchaAnswerUpdate(-100, 42, "spam"))
+	}
+
+	if got := countRequests[tgbotapi.RestrictChatMemberConfig](mockClient.RequestCalls); got != 1 {
+		t.Errorf("Expected the user to be muted once, got %d restrictions", got)
+	}
+}
+
+func TestFloodControlMiddleware_IgnoresPrivateChats(t *testing.T) {
+	middleware := FloodControlMiddleware(FloodControlConfig{
+		Default: FloodRule{MaxMessages: 1, Window: time.Minute},
+	})
+
+	handler := &mockHandler{}
+	wrapped := middleware(handler.Handle)
+	ctx, _ := createAuthMiddlewareTestContext("message", 42, 42)
+
+	for i := 0; i < 3; i++ {
+		_ = wrapped(ctx)
+	}
+
+	if handler.callCount != 3 {
+		t.Errorf("Expected all private messages to pass, got %d", handler.callCount)
+	}
+}
@@ -0,0 +1,193 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Money is an amount of a currency, stored in minor units (cents for USD) so
+// arithmetic on it is exact.
+type Money struct {
+	Minor    int64  // Amount in minor units of the currency
+	Currency string // ISO 4217 code, e.g. "USD"
+}
+
+// NewMoney converts an amount in major units, e.g. 12.5 dollars, to Money,
+// rounding to the minor units of the currency.
+func NewMoney(amount float64, currencyCode string) (Money, error) {
+	unit, scale, err := currencyScale(currencyCode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: int64(math.Round(amount * math.Pow10(scale))), Currency: unit.String()}, nil
+}
+
+// Float returns the amount in major units.
+func (m Money) Float() float64 {
+	_, scale, err := currencyScale(m.Currency)
+	if err != nil {
+		return float64(m.Minor)
+	}
+	return float64(m.Minor) / math.Pow10(scale)
+}
+
+// String returns the amount with its currency code, e.g. "1234.56 USD".
+func (m Money) String() string {
+	_, scale, err := currencyScale(m.Currency)
+	if err != nil {
+		return fmt.Sprintf("%d %s", m.Minor, m.Currency)
+	}
+	return fmt.Sprintf("%.*f %s", scale, m.Float(), m.Currency)
+}
+
+// Format formats the amount for a locale with the currency symbol, e.g.
+// "$1,234.56" for en and "1.234,56 €" for de. A symbol after the amount is
+// separated by a no-break space.
+func (m Money) Format(locale string) string {
+	unit, scale, err := currencyScale(m.Currency)
+	if err != nil {
+		return m.String()
+	}
+	tag := localeTag(locale)
+	printer := message.NewPrinter(tag)
+
+	amount := printer.Sprint(number.Decimal(math.Abs(m.Float()), number.Scale(scale)))
+	symbol := printer.Sprint(currency.NarrowSymbol(unit))
+	sign := ""
+	if m.Minor < 0 {
+		sign = "-"
+	}
+	if base, _ := tag.Base(); symbolAfterAmount[base.String()] {
+		return sign + amount + "\u00a0" + symbol
+	}
+	return sign + symbol + amount
+}
+
+// symbolAfterAmount lists languages that write the currency symbol after the
+// amount.
+var symbolAfterAmount = map[string]bool{
+	"cs": true, "da": true, "de": true, "es": true, "fi": true, "fr": true, "it": true,
+	"nb": true, "pl": true, "ru": true, "sv": true, "uk": true,
+}
+
+// ParseMoney parses user input such as "1,234.56", "1 234,56", "$12" or
+// "12.50 USD" as an amount of the currency. Either "." or "," may be the
+// decimal separator; spaces, apostrophes and the other separator group digits.
+func ParseMoney(input, currencyCode string) (Money, error) {
+	unit, scale, err := currencyScale(currencyCode)
+	if err != nil {
+		return Money{}, err
+	}
+
+	text := stripCurrency(strings.TrimSpace(input), unit)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimSpace(strings.TrimPrefix(text, "-"))
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, text)
+	if text == "" {
+		return Money{}, errors.New("amount is empty")
+	}
+
+	whole, fraction := splitDecimal(text)
+	whole = strings.NewReplacer(",", "", ".", "").Replace(whole)
+	if whole == "" {
+		whole = "0"
+	}
+	if len(fraction) > scale {
+		return Money{}, fmt.Errorf("%s allows at most %d decimal places", unit, scale)
+	}
+
+	var minor int64
+	for _, r := range whole + fraction + strings.Repeat("0", scale-len(fraction)) {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("invalid amount %q", input)
+		}
+		if minor > (math.MaxInt64-9)/10 {
+			return Money{}, fmt.Errorf("amount %q is too large", input)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: unit.String()}, nil
+}
+
+// splitDecimal splits an amount at its decimal separator. The last "." or ","
+// is the decimal separator unless it repeats, or it is followed by exactly three
+// digits and the amount has no other separator, as in "1,234".
+func splitDecimal(text string) (string, string) {
+	i := strings.LastIndexAny(text, ".,")
+	if i < 0 {
+		return text, ""
+	}
+	sep := text[i]
+	if strings.Count(text, string(sep)) > 1 {
+		return text, ""
+	}
+	whole, fraction := text[:i], text[i+1:]
+	otherSep := strings.ContainsAny(whole, ".,")
+	if len(fraction) == 3 && !otherSep && whole != "" && whole != "0" {
+		return text, ""
+	}
+	return whole, fraction
+}
+
+// stripCurrency removes the currency code or symbol before or after an amount.
+func stripCurrency(text string, unit currency.Unit) string {
+	printer := message.NewPrinter(language.English)
+	for _, marker := range []string{unit.String(), printer.Sprint(currency.Symbol(unit)), printer.Sprint(currency.NarrowSymbol(unit))} {
+		if len(text) >= len(marker) && strings.EqualFold(text[:len(marker)], marker) {
+			return strings.TrimSpace(text[len(marker):])
+		}
+		if len(text) >= len(marker) && strings.EqualFold(text[len(text)-len(marker):], marker) {
+			return strings.TrimSpace(text[:len(text)-len(marker)])
+		}
+	}
+	return text
+}
+
+// currencyScale returns the currency unit and its number of minor-unit digits.
+func currencyScale(currencyCode string) (currency.Unit, int, error) {
+	unit, err := currency.ParseISO(currencyCode)
+	if err != nil {
+		return currency.Unit{}, 0, fmt.Errorf("unknown currency %q", currencyCode)
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return unit, scale, nil
+}
+
+// formatMoney is the formatMoney template function. value is a Money, or a
+// number in major units together with a currency code:
+//
+//	{{formatMoney .Locale .price}}
+//	{{formatMoney .Locale .amount "EUR"}}
+func formatMoney(locale string, value interface{}, currencyCode ...string) string {
+	if money, ok := value.(Money); ok {
+		return money.Format(locale)
+	}
+	if money, ok := value.(*Money); ok && money != nil {
+		return money.Format(locale)
+	}
+	num, ok := templateNumber(value)
+	if !ok || len(currencyCode) == 0 {
+		return fmt.Sprint(value)
+	}
+	money, err := NewMoney(num, currencyCode[0])
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return money.Format(locale)
+}
@@ -0,0 +1,73 @@
+package teleflow
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"1,234.56", "USD", 123456, false},
+		{"1 234,56", "EUR", 123456, false},
+		{"1.234.567,5", "EUR", 123456750, false},
+		{"$12", "USD", 1200, false},
+		{"12.50 usd", "USD", 1250, false},
+		{"1,234", "USD", 123400, false},
+		{"0,5", "EUR", 50, false},
+		{"-3.10", "USD", -310, false},
+		{"1500", "JPY", 1500, false},
+		{"12.345", "USD", 1234500, false},
+		{"0.125", "USD", 0, true},
+		{"12.5", "JPY", 0, true},
+		{"abc", "USD", 0, true},
+		{"", "USD", 0, true},
+		{"1", "XYZW", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMoney(tt.input, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Minor != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got.Minor, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	usd := Money{Minor: 123456, Currency: "USD"}
+	eur := Money{Minor: -123456, Currency: "EUR"}
+
+	tests := []struct {
+		money  Money
+		locale string
+		want   string
+	}{
+		{usd, "en", "$1,234.56"},
+		{usd, "de", "1.234,56\u00a0$"},
+		{eur, "fr", "-1\u00a0234,56\u00a0€"},
+		{Money{Minor: 1500, Currency: "JPY"}, "ja", "￥1,500"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.Format(tt.locale); got != tt.want {
+			t.Errorf("Format(%v, %s) = %q, want %q", tt.money, tt.locale, got, tt.want)
+		}
+	}
+	if got := usd.String(); got != "1234.56 USD" {
+		t.Errorf("String() = %q", got)
+	}
+
+	tm := newTemplateManager()
+	if err := tm.AddTemplate("price", `{{formatMoney .Locale .price}} / {{formatMoney .Locale 9.5 "EUR"}}`, ParseModeNone); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+	got, _, err := tm.RenderTemplate("price", map[string]interface{}{"Locale": "de", "price": usd})
+	if err != nil || got != "1.234,56\u00a0$ / 9,50\u00a0€" {
+		t.Errorf("Unexpected rendering %q (err %v)", got, err)
+	}
+}
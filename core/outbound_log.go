@@ -0,0 +1,151 @@
+package teleflow
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultOutboundLogText is the number of characters of message text logged by
+// default.
+const defaultOutboundLogText = 200
+
+// redactedText replaces text matched by a redaction pattern.
+const redactedText = "[REDACTED]"
+
+// builtinRedactions are always applied to logged text: bot tokens and payment
+// card numbers.
+var builtinRedactions = []*regexp.Regexp{
+	botTokenPattern,
+	regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+}
+
+// OutboundLogEntry describes an outgoing Bot API call and its result.
+type OutboundLogEntry struct {
+	Method    string        // Bot API method, e.g. "sendMessage"
+	ChatID    int64         // Target chat, 0 if the call has none
+	MessageID int           // Message edited, or sent if the call created one
+	Text      string        // Redacted and truncated message text or caption
+	Keyboard  string        // Button labels by row, e.g. "[Yes | No] [Back]"
+	Duration  time.Duration // Time the call took
+	Error     string        // Error returned by the call, empty on success
+}
+
+// String formats the entry as a single log line.
+func (e OutboundLogEntry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[OUTBOUND] %s chat=%d", e.Method, e.ChatID)
+	if e.MessageID != 0 {
+		fmt.Fprintf(&b, " message=%d", e.MessageID)
+	}
+	fmt.Fprintf(&b, " took=%s", e.Duration.Round(time.Millisecond))
+	if e.Text != "" {
+		fmt.Fprintf(&b, " text=%q", e.Text)
+	}
+	if e.Keyboard != "" {
+		fmt.Fprintf(&b, " keyboard=%s", e.Keyboard)
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, " error=%q", e.Error)
+	}
+	return b.String()
+}
+
+// OutboundLogConfig configures the outbound message log enabled with
+// WithOutboundLog.
+type OutboundLogConfig struct {
+	// Logger receives each entry; nil writes entry.String() to the standard logger.
+	Logger func(entry OutboundLogEntry)
+
+	// MaxText is the number of characters of text kept, 200 by default.
+	MaxText int
+
+	// Redact lists patterns whose matches are replaced by "[REDACTED]" in the
+	// logged text, keyboard and error. Bot tokens and card numbers are always
+	// redacted.
+	Redact []*regexp.Regexp
+}
+
+// WithOutboundLog records every outgoing Bot API call with its chat, method,
+// truncated text, keyboard summary and result, for troubleshooting in
+// production. Matches of the configured patterns are redacted.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithOutboundLog(teleflow.OutboundLogConfig{
+//		Redact: []*regexp.Regexp{regexp.MustCompile(`IBAN [A-Z0-9 ]+`)},
+//	}))
+func WithOutboundLog(config OutboundLogConfig) BotOption {
+	return func(b *Bot) {
+		if config.MaxText <= 0 {
+			config.MaxText = defaultOutboundLogText
+		}
+		b.outboundLog = &config
+	}
+}
+
+// logOutbound writes the log entry of a call if the outbound log is enabled.
+func (b *Bot) logOutbound(call DryRunMessage, messageID int, duration time.Duration, err error) {
+	config := b.outboundLog
+	if config == nil {
+		return
+	}
+	entry := OutboundLogEntry{
+		Method:    call.Method,
+		ChatID:    call.ChatID,
+		MessageID: call.MessageID,
+		Text:      truncateRunes(b.secrets.scrub(redact(call.Text, config.Redact)), config.MaxText),
+		Keyboard:  b.secrets.scrub(redact(keyboardSummary(call.ReplyMarkup), config.Redact)),
+		Duration:  duration,
+	}
+	if entry.MessageID == 0 {
+		entry.MessageID = messageID
+	}
+	if err != nil {
+		entry.Error = b.secrets.scrub(redact(err.Error(), config.Redact))
+	}
+
+	if config.Logger != nil {
+		config.Logger(entry)
+		return
+	}
+	log.Print(entry.String())
+}
+
+// redact replaces matches of the built-in and the given patterns in text.
+func redact(text string, patterns []*regexp.Regexp) string {
+	if text == "" {
+		return text
+	}
+	for _, pattern := range builtinRedactions {
+		text = pattern.ReplaceAllString(text, redactedText)
+	}
+	for _, pattern := range patterns {
+		text = pattern.ReplaceAllString(text, redactedText)
+	}
+	return text
+}
+
+// keyboardSummary lists the button labels of a keyboard by row.
+func keyboardSummary(markup interface{}) string {
+	var rows []string
+	for _, row := range recordedButtons(markup) {
+		labels := make([]string, len(row))
+		for i, button := range row {
+			labels[i] = button.Text
+		}
+		rows = append(rows, "["+strings.Join(labels, " | ")+"]")
+	}
+	return strings.Join(rows, " ")
+}
+
+// truncateRunes shortens text to max characters, marking the cut with "…".
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
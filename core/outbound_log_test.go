@@ -0,0 +1,58 @@
+package teleflow
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestOutboundLog(t *testing.T) {
+	var entries []OutboundLogEntry
+	bot, client, _, _ := createTestBot(WithOutboundLog(OutboundLogConfig{
+		Logger:  func(entry OutboundLogEntry) { entries = append(entries, entry) },
+		MaxText: 40,
+		Redact:  []*regexp.Regexp{regexp.MustCompile(`PIN \d+`)},
+	}))
+	ctx := bot.contextFor(42, 42)
+
+	msg := tgbotapi.NewMessage(42, "Card 4111 1111 1111 1111, PIN 1234")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Yes", "y"), tgbotapi.NewInlineKeyboardButtonData("No", "n")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Back", "b")),
+	)
+	if _, err := ctx.telegramClient.Send(msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Method != "sendMessage" || entry.ChatID != 42 || entry.MessageID != 123 {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if entry.Text != "Card [REDACTED], [REDACTED]" {
+		t.Errorf("Expected the card number and PIN to be redacted, got %q", entry.Text)
+	}
+	if entry.Keyboard != "[Yes | No] [Back]" {
+		t.Errorf("Expected a keyboard summary, got %q", entry.Keyboard)
+	}
+
+	client.RequestFunc = func(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		return nil, errors.New("Post https://api.telegram.org/bot123456:ABCdefGHIjklMNOpqrSTUvwxYZ0123456789/editMessageText: timeout")
+	}
+	_, _ = ctx.telegramClient.Request(tgbotapi.NewEditMessageText(42, 7, strings.Repeat("a", 50)))
+	entry = entries[1]
+	if entry.MessageID != 7 || entry.Text != strings.Repeat("a", 40)+"…" {
+		t.Errorf("Expected the edited message with truncated text, got %+v", entry)
+	}
+	if entry.Error == "" || strings.Contains(entry.Error, "ABCdef") {
+		t.Errorf("Expected the error with the token redacted, got %q", entry.Error)
+	}
+	if line := entry.String(); !strings.HasPrefix(line, "[OUTBOUND] editMessageText chat=42 message=7") {
+		t.Errorf("Unexpected log line %q", line)
+	}
+}
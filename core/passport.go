@@ -0,0 +1,382 @@
+package teleflow
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ErrNoPassportKey is returned when Telegram Passport data arrives or is
+// requested but the bot was created without WithPassportKey.
+var ErrNoPassportKey = errors.New("telegram passport requires a private key, see WithPassportKey")
+
+// PassportData is the decrypted Telegram Passport data a user shared with the
+// bot.
+type PassportData struct {
+	Nonce    string            // Nonce passed to PassportRequestURL; verify it matches the request
+	Elements []PassportElement // Shared documents and details
+}
+
+// Element returns the element of the given type, e.g. "passport" or
+// "personal_details", or nil if the user did not share it.
+func (d *PassportData) Element(elementType string) *PassportElement {
+	for i := range d.Elements {
+		if d.Elements[i].Type == elementType {
+			return &d.Elements[i]
+		}
+	}
+	return nil
+}
+
+// PassportElement is a decrypted Telegram Passport element. Which fields are
+// set depends on Type.
+type PassportElement struct {
+	Type            string                    // e.g. "personal_details", "passport", "address", "utility_bill", "phone_number", "email"
+	PersonalDetails *tgbotapi.PersonalDetails // For "personal_details"
+	Document        *tgbotapi.IDDocumentData  // For "passport", "driver_license", "identity_card" and "internal_passport"
+	Address         *PassportAddress          // For "address"
+	PhoneNumber     string                    // For "phone_number"
+	Email           string                    // For "email"
+	FrontSide       *PassportFile             // Front side of an identity document
+	ReverseSide     *PassportFile             // Reverse side of a driver license or identity card
+	Selfie          *PassportFile             // Selfie of the user holding the document
+	Files           []PassportFile            // Scans of address documents, such as utility bills
+}
+
+// PassportAddress is the residential address of a Telegram Passport "address"
+// element.
+type PassportAddress struct {
+	StreetLine1 string `json:"street_line1"`
+	StreetLine2 string `json:"street_line2"`
+	City        string `json:"city"`
+	State       string `json:"state"`
+	CountryCode string `json:"country_code"`
+	PostCode    string `json:"post_code"`
+}
+
+// PassportFile is an encrypted file of a Telegram Passport element. Download it
+// by FileID, then decrypt it with Decrypt.
+type PassportFile struct {
+	tgbotapi.PassportFile
+	FileHash string // Base64 hash of the file, used to report errors in it
+
+	secret string
+}
+
+// Decrypt decrypts the downloaded contents of the file.
+func (f PassportFile) Decrypt(encrypted []byte) ([]byte, error) {
+	secret, err := base64.StdEncoding.DecodeString(f.secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file secret: %w", err)
+	}
+	hash, err := base64.StdEncoding.DecodeString(f.FileHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file hash: %w", err)
+	}
+	return decryptPassportValue(encrypted, secret, hash)
+}
+
+// PassportHandlerFunc handles Telegram Passport data a user shared.
+type PassportHandlerFunc func(ctx *Context, data *PassportData) error
+
+// WithPassportKey returns a BotOption that sets the private key Telegram
+// Passport data is encrypted for. Register the matching public key with
+// @BotFather and load the private key with ParsePassportKey.
+//
+// Example:
+//
+//	key, err := teleflow.ParsePassportKey(pemBytes)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bot, err := teleflow.NewBot(token, teleflow.WithPassportKey(key))
+func WithPassportKey(key *rsa.PrivateKey) BotOption {
+	return func(b *Bot) {
+		b.passportKey = key
+	}
+}
+
+// ParsePassportKey parses a PEM-encoded RSA private key in PKCS #1 or PKCS #8
+// form.
+func ParsePassportKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("passport key must be an RSA key")
+	}
+	return key, nil
+}
+
+// PassportRequestURL returns a link that asks the user to share the documents
+// in scope. Use a fresh nonce per request, e.g. a session ID, and compare it with
+// PassportData.Nonce when the data arrives. Send the link with a URL button.
+//
+// Example:
+//
+//	link, err := bot.PassportRequestURL(tgbotapi.PassportScope{V: 1, Data: []tgbotapi.PassportScopeElement{
+//		&tgbotapi.PassportScopeElementOne{Type: "personal_details"},
+//		&tgbotapi.PassportScopeElementOne{Type: "passport", Selfie: true},
+//	}}, sessionID)
+func (b *Bot) PassportRequestURL(scope tgbotapi.PassportScope, nonce string) (string, error) {
+	if b.passportKey == nil {
+		return "", ErrNoPassportKey
+	}
+	scopeJSON, err := json.Marshal(scope)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode scope: %w", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&b.passportKey.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("domain", "telegrampassport")
+	query.Set("bot_id", strconv.FormatInt(b.self.ID, 10))
+	query.Set("scope", string(scopeJSON))
+	query.Set("public_key", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})))
+	query.Set("nonce", nonce)
+	return "tg://resolve?" + query.Encode(), nil
+}
+
+// HandlePassportData registers the handler for Telegram Passport data users
+// share with the bot. It runs before the message reaches the user's flow, where
+// ctx.PassportData() returns the same data, so a KYC flow can wait for the
+// documents in a step. Passport messages never reach text handlers. Data that
+// cannot be decrypted is logged and not passed to the handler.
+//
+// Example:
+//
+//	bot.HandlePassportData(func(ctx *teleflow.Context, data *teleflow.PassportData) error {
+//		if data.Nonce != expectedNonce(ctx.UserID()) {
+//			return errors.New("unexpected passport nonce")
+//		}
+//		if passport := data.Element("passport"); passport != nil {
+//			return kyc.Submit(ctx.UserID(), passport.Document, data.Element("personal_details").PersonalDetails)
+//		}
+//		return nil
+//	})
+func (b *Bot) HandlePassportData(handler PassportHandlerFunc) {
+	wrappedHandler := func(ctx *Context) error {
+		return handler(ctx, ctx.passport)
+	}
+	b.passportHandler = b.applyMiddleware(wrappedHandler)
+}
+
+// PassportData returns the decrypted Telegram Passport data carried by the
+// current message. It returns nil and no error for other messages.
+func (c *Context) PassportData() (*PassportData, error) {
+	return c.passport, c.passportErr
+}
+
+// SetPassportDataErrors tells the current user which of their Passport
+// documents were rejected, using the tgbotapi.PassportElementError types. The
+// user cannot share the elements again until the errors are fixed.
+//
+// Example:
+//
+//	err := ctx.SetPassportDataErrors(tgbotapi.PassportElementErrorSelfie{
+//		Source: "selfie", Type: "passport", FileHash: passport.Selfie.FileHash, Message: "Your face is not visible.",
+//	})
+func (c *Context) SetPassportDataErrors(elementErrors ...tgbotapi.PassportElementError) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("user_id", c.UserID())
+	if err := params.AddInterface("errors", elementErrors); err != nil {
+		return fmt.Errorf("failed to encode passport errors: %w", err)
+	}
+	if _, err := makeRawRequest(c.telegramClient, "setPassportDataErrors", params); err != nil {
+		return fmt.Errorf("failed to set passport data errors: %w", err)
+	}
+	return nil
+}
+
+// handlePassportData decrypts the passport data of the message for the context
+// and runs the registered handler.
+func (b *Bot) handlePassportData(ctx *Context) {
+	if b.passportKey == nil {
+		ctx.passportErr = ErrNoPassportKey
+	} else {
+		ctx.passport, ctx.passportErr = decryptPassportData(b.passportKey, ctx.update.Message.PassportData)
+	}
+	if ctx.passportErr != nil {
+		log.Printf("Failed to decrypt passport data for UserID %d: %v", ctx.UserID(), ctx.passportErr)
+		return
+	}
+	if b.passportHandler == nil {
+		return
+	}
+	if err := b.stats.track("passport", func() error { return b.passportHandler(ctx) }); err != nil {
+		log.Printf("Passport handler error for UserID %d: %v", ctx.UserID(), err)
+		b.deadLetter(ctx, fmt.Errorf("passport: %w", err), 1)
+	}
+}
+
+// decryptPassportData decrypts the credentials with the bot's key and every
+// element with its credentials.
+func decryptPassportData(key *rsa.PrivateKey, data *tgbotapi.PassportData) (*PassportData, error) {
+	if data.Credentials == nil {
+		return nil, errors.New("passport data has no credentials")
+	}
+	credentials, err := decryptPassportCredentials(key, data.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := &PassportData{Nonce: credentials.Nonce}
+	for _, element := range data.Data {
+		value := credentials.Data[element.Type]
+		if value == nil {
+			value = &tgbotapi.SecureValue{}
+		}
+		out := PassportElement{Type: element.Type, PhoneNumber: element.PhoneNumber, Email: element.Email}
+
+		if element.Data != "" {
+			if value.Data == nil {
+				return nil, fmt.Errorf("no credentials for %s data", element.Type)
+			}
+			plain, err := decryptPassportField(element.Data, value.Data.Secret, value.Data.DataHash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s data: %w", element.Type, err)
+			}
+			if err := decodePassportElementData(&out, plain); err != nil {
+				return nil, fmt.Errorf("failed to decode %s data: %w", element.Type, err)
+			}
+		}
+
+		out.FrontSide = passportFile(element.FrontSide, value.FrontSide)
+		out.ReverseSide = passportFile(element.ReverseSide, value.ReverseSide)
+		out.Selfie = passportFile(element.Selfie, value.Selfie)
+		for i := range element.Files {
+			var fileCredentials *tgbotapi.FileCredentials
+			if i < len(value.Files) {
+				fileCredentials = value.Files[i]
+			}
+			if file := passportFile(&element.Files[i], fileCredentials); file != nil {
+				out.Files = append(out.Files, *file)
+			}
+		}
+		decrypted.Elements = append(decrypted.Elements, out)
+	}
+	return decrypted, nil
+}
+
+// decryptPassportCredentials decrypts the credentials' secret with the bot's
+// key and the credentials with the secret.
+func decryptPassportCredentials(key *rsa.PrivateKey, encrypted *tgbotapi.EncryptedCredentials) (*tgbotapi.Credentials, error) {
+	encryptedSecret, err := base64.StdEncoding.DecodeString(encrypted.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials secret: %w", err)
+	}
+	secret, err := rsa.DecryptOAEP(sha1.New(), nil, key, encryptedSecret, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials secret: %w", err)
+	}
+	hash, err := base64.StdEncoding.DecodeString(encrypted.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials hash: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials data: %w", err)
+	}
+	plain, err := decryptPassportValue(data, secret, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+
+	var credentials tgbotapi.Credentials
+	if err := json.Unmarshal(plain, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	return &credentials, nil
+}
+
+// decryptPassportField decrypts base64 element data with its base64 secret and
+// hash.
+func decryptPassportField(data, secret, hash string) ([]byte, error) {
+	values := make([][]byte, 3)
+	for i, s := range []string{data, secret, hash} {
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = decoded
+	}
+	return decryptPassportValue(values[0], values[1], values[2])
+}
+
+// decryptPassportValue decrypts data with AES-256-CBC, using the key and IV
+// derived from SHA-512(secret + hash), checks it against hash and removes the
+// random padding whose length is its first byte.
+func decryptPassportValue(data, secret, hash []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("encrypted data has an invalid length")
+	}
+	secretHash := sha512.Sum512(append(append([]byte{}, secret...), hash...))
+	block, err := aes.NewCipher(secretHash[:32])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, secretHash[32:48]).CryptBlocks(plain, data)
+
+	dataHash := sha256.Sum256(plain)
+	if !hmac.Equal(dataHash[:], hash) {
+		return nil, errors.New("data hash mismatch")
+	}
+	padding := int(plain[0])
+	if padding < 32 || padding > len(plain) {
+		return nil, errors.New("invalid data padding")
+	}
+	return plain[padding:], nil
+}
+
+// decodePassportElementData decodes the decrypted data of an element.
+func decodePassportElementData(element *PassportElement, plain []byte) error {
+	switch element.Type {
+	case "personal_details":
+		element.PersonalDetails = &tgbotapi.PersonalDetails{}
+		return json.Unmarshal(plain, element.PersonalDetails)
+	case "passport", "driver_license", "identity_card", "internal_passport":
+		element.Document = &tgbotapi.IDDocumentData{}
+		return json.Unmarshal(plain, element.Document)
+	case "address":
+		element.Address = &PassportAddress{}
+		return json.Unmarshal(plain, element.Address)
+	}
+	return nil
+}
+
+// passportFile pairs a file with its credentials. Files without credentials
+// cannot be decrypted and are left out.
+func passportFile(file *tgbotapi.PassportFile, credentials *tgbotapi.FileCredentials) *PassportFile {
+	if file == nil || credentials == nil {
+		return nil
+	}
+	return &PassportFile{PassportFile: *file, FileHash: credentials.FileHash, secret: credentials.Secret}
+}
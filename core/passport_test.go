@@ -0,0 +1,161 @@
+package teleflow
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// encryptPassportValue encrypts plain the way Telegram encrypts Passport data,
+// returning the data, its secret and its hash.
+func encryptPassportValue(t *testing.T, plain []byte) (data, secret, hash []byte) {
+	t.Helper()
+	padding := 32 + (aes.BlockSize-(len(plain)+32)%aes.BlockSize)%aes.BlockSize
+	padded := make([]byte, padding, padding+len(plain))
+	padded[0] = byte(padding)
+	padded = append(padded, plain...)
+
+	dataHash := sha256.Sum256(padded)
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+	secretHash := sha512.Sum512(append(append([]byte{}, secret...), dataHash[:]...))
+	block, err := aes.NewCipher(secretHash[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, secretHash[32:48]).CryptBlocks(data, padded)
+	return data, secret, dataHash[:]
+}
+
+func TestPassportData(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.StdEncoding.EncodeToString
+
+	details, detailsSecret, detailsHash := encryptPassportValue(t, []byte(`{"first_name":"Ada","last_name":"Lovelace","birth_date":"10.12.1815"}`))
+	scan, scanSecret, scanHash := encryptPassportValue(t, []byte("scan"))
+	credentials, _ := json.Marshal(tgbotapi.Credentials{Nonce: "session_1", Data: tgbotapi.SecureData{
+		"personal_details": {Data: &tgbotapi.DataCredentials{DataHash: b64(detailsHash), Secret: b64(detailsSecret)}},
+		"utility_bill":     {Files: []*tgbotapi.FileCredentials{{FileHash: b64(scanHash), Secret: b64(scanSecret)}}},
+	}})
+	encryptedCredentials, credentialsSecret, credentialsHash := encryptPassportValue(t, credentials)
+	encryptedSecret, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &key.PublicKey, credentialsSecret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passportData := &tgbotapi.PassportData{
+		Data: []tgbotapi.EncryptedPassportElement{
+			{Type: "personal_details", Data: b64(details)},
+			{Type: "utility_bill", Files: []tgbotapi.PassportFile{{FileID: "scan_1"}}},
+			{Type: "phone_number", PhoneNumber: "+441234567890"},
+		},
+		Credentials: &tgbotapi.EncryptedCredentials{Data: b64(encryptedCredentials), Hash: b64(credentialsHash), Secret: b64(encryptedSecret)},
+	}
+	update := tgbotapi.Update{Message: &tgbotapi.Message{
+		From: &tgbotapi.User{ID: 42}, Chat: &tgbotapi.Chat{ID: 42, Type: "private"}, PassportData: passportData,
+	}}
+
+	bot, client, _, _ := createTestBot()
+	WithPassportKey(key)(bot)
+
+	var received *PassportData
+	bot.HandlePassportData(func(ctx *Context, data *PassportData) error {
+		received = data
+		return ctx.SetPassportDataErrors(tgbotapi.PassportElementErrorFile{
+			Source: "file", Type: "utility_bill", FileHash: data.Element("utility_bill").Files[0].FileHash, Message: "The scan is blurry.",
+		})
+	})
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		t.Error("Passport messages must not reach text handlers")
+		return nil
+	})
+	bot.processUpdate(update)
+
+	if received == nil || received.Nonce != "session_1" || len(received.Elements) != 3 {
+		t.Fatalf("Expected the decrypted passport data, got %+v", received)
+	}
+	if personal := received.Element("personal_details").PersonalDetails; personal == nil || personal.FirstName != "Ada" || personal.BirthDate != "10.12.1815" {
+		t.Errorf("Unexpected personal details %+v", personal)
+	}
+	if phone := received.Element("phone_number").PhoneNumber; phone != "+441234567890" {
+		t.Errorf("Expected the phone number, got %q", phone)
+	}
+	file := received.Element("utility_bill").Files[0]
+	if plain, err := file.Decrypt(scan); err != nil || string(plain) != "scan" || file.FileID != "scan_1" {
+		t.Errorf("Expected the scan to decrypt, got %q, %v", plain, err)
+	}
+	if _, err := file.Decrypt(details); err == nil {
+		t.Error("Expected decrypting another file to fail the hash check")
+	}
+
+	request := client.MakeRequestCalls[len(client.MakeRequestCalls)-1]
+	if request.Endpoint != "setPassportDataErrors" || request.Params["user_id"] != "42" || !strings.Contains(request.Params["errors"], `"source":"file"`) {
+		t.Errorf("Unexpected passport errors request %+v", request)
+	}
+
+	// Data encrypted for another key is not passed to the handler
+	other, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	WithPassportKey(other)(bot)
+	received = nil
+	bot.processUpdate(update)
+	if received != nil {
+		t.Error("Expected undecryptable data to be dropped")
+	}
+}
+
+func TestPassportRequestURL(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	scope := tgbotapi.PassportScope{V: 1, Data: []tgbotapi.PassportScopeElement{&tgbotapi.PassportScopeElementOne{Type: "passport", Selfie: true}}}
+
+	if _, err := bot.PassportRequestURL(scope, "session_1"); err != ErrNoPassportKey {
+		t.Errorf("Expected ErrNoPassportKey, got %v", err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePassportKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	if err != nil || !parsed.Equal(key) {
+		t.Fatalf("ParsePassportKey failed: %v", err)
+	}
+	WithPassportKey(parsed)(bot)
+
+	link, err := bot.PassportRequestURL(scope, "session_1")
+	if err != nil {
+		t.Fatalf("PassportRequestURL failed: %v", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "tg" || u.Host != "resolve" {
+		t.Fatalf("Unexpected link %q", link)
+	}
+	query := u.Query()
+	if query.Get("domain") != "telegrampassport" || query.Get("nonce") != "session_1" || !strings.Contains(query.Get("scope"), `"selfie":true`) {
+		t.Errorf("Unexpected link query %v", query)
+	}
+	if !strings.HasPrefix(query.Get("public_key"), "-----BEGIN PUBLIC KEY-----") {
+		t.Errorf("Expected the PEM public key, got %q", query.Get("public_key"))
+	}
+}
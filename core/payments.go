@@ -0,0 +1,327 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// StarsCurrency is the currency code of Telegram Stars, the currency bots must
+// use for digital goods and services.
+const StarsCurrency = "XTR"
+
+// Invoice describes a payment request sent with ctx.SendInvoice. Amounts in
+// Prices are in the smallest units of the currency; for Stars that is a whole
+// number of stars.
+type Invoice struct {
+	Title         string                  // Product name, 1-32 characters
+	Description   string                  // Product description, 1-255 characters
+	Payload       string                  // Bot-defined payload returned in pre-checkout queries and payments, not shown to the user
+	Currency      string                  // ISO 4217 currency code, or StarsCurrency when empty
+	Prices        []tgbotapi.LabeledPrice // Price breakdown; Stars invoices take exactly one price
+	ProviderToken string                  // Payment provider token, empty for Stars
+	PhotoURL      string                  // URL of a product photo (optional)
+	Keyboard      KeyboardFunc            // Inline keyboard starting with ButtonPay (optional, defaults to a single Pay button)
+
+	// Checkout details for physical goods, not available for Stars
+	NeedName            bool // Ask for the user's full name
+	NeedPhoneNumber     bool // Ask for the user's phone number
+	NeedShippingAddress bool // Ask for the shipping address
+	Flexible            bool // The price depends on the shipping method; requires a shipping query handler
+}
+
+// StarsInvoice returns an invoice for a digital product priced in Telegram
+// Stars.
+//
+// Example:
+//
+//	invoice := teleflow.StarsInvoice("Premium week", "7 days of premium features", "premium_week", 50)
+//	return ctx.SendInvoice(invoice)
+func StarsInvoice(title, description, payload string, stars int) Invoice {
+	return Invoice{
+		Title:       title,
+		Description: description,
+		Payload:     payload,
+		Currency:    StarsCurrency,
+		Prices:      []tgbotapi.LabeledPrice{{Label: title, Amount: stars}},
+	}
+}
+
+// currency returns the invoice's currency, defaulting to Stars.
+func (inv Invoice) currency() string {
+	if inv.Currency == "" {
+		return StarsCurrency
+	}
+	return inv.Currency
+}
+
+// validate checks the rules Telegram enforces for the invoice's currency.
+func (inv Invoice) validate() error {
+	if inv.Title == "" || inv.Description == "" || inv.Payload == "" {
+		return errors.New("invoice requires a title, description and payload")
+	}
+	if len(inv.Prices) == 0 {
+		return errors.New("invoice requires at least one price")
+	}
+	if inv.currency() == StarsCurrency {
+		if len(inv.Prices) != 1 {
+			return errors.New("stars invoices take exactly one price")
+		}
+		if inv.ProviderToken != "" {
+			return errors.New("stars invoices must not set a provider token")
+		}
+		if inv.NeedName || inv.NeedPhoneNumber || inv.NeedShippingAddress || inv.Flexible {
+			return errors.New("stars invoices cannot collect checkout details")
+		}
+	} else if inv.ProviderToken == "" {
+		return fmt.Errorf("%s invoices require a provider token", inv.currency())
+	}
+	return nil
+}
+
+// SendInvoice sends an invoice to the current chat. The user pays from the
+// message's Pay button; the payment is then confirmed with the bot's
+// pre-checkout handler and delivered as a successful payment message.
+//
+// Example:
+//
+//	Process(func(ctx *teleflow.Context, input string, click *teleflow.ButtonClick) teleflow.ProcessResult {
+//		if err := ctx.SendInvoice(teleflow.StarsInvoice("Sticker pack", "20 exclusive stickers", "stickers_1", 100)); err != nil {
+//			return teleflow.Retry().WithPrompt("Could not create the invoice, please try again.")
+//		}
+//		return teleflow.NextStep()
+//	})
+func (c *Context) SendInvoice(inv Invoice) error {
+	if err := inv.validate(); err != nil {
+		return err
+	}
+	params, err := inv.params(c.ChatID())
+	if err != nil {
+		return err
+	}
+	if inv.Keyboard != nil {
+		keyboard, err := c.invoiceKeyboard(inv.Keyboard)
+		if err != nil {
+			return err
+		}
+		if err := params.AddInterface("reply_markup", keyboard); err != nil {
+			return fmt.Errorf("failed to encode invoice keyboard: %w", err)
+		}
+	}
+	if _, err := makeRawRequest(c.telegramClient, "sendInvoice", params); err != nil {
+		return fmt.Errorf("failed to send invoice: %w", err)
+	}
+	return nil
+}
+
+// invoiceKeyboard builds an invoice's keyboard, registering its callback buttons
+// like a prompt's.
+func (c *Context) invoiceKeyboard(keyboard KeyboardFunc) (interface{}, error) {
+	builder := keyboard(c)
+	if builder == nil {
+		return nil, nil
+	}
+	if composer, ok := c.promptSender.(*PromptComposer); ok && composer.keyboardHandler != nil {
+		return composer.keyboardHandler.buildInvoiceKeyboard(c, builder)
+	}
+	if err := builder.validateInvoiceKeyboard(); err != nil {
+		return nil, fmt.Errorf("invalid invoice keyboard: %w", err)
+	}
+	return builder.Build(), nil
+}
+
+// params encodes the invoice for the sendInvoice method. The request is built by
+// hand because the telegram-bot-api library always sends the provider token and
+// tip fields, which Stars invoices must omit.
+func (inv Invoice) params(chatID int64) (tgbotapi.Params, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", chatID)
+	params["title"] = inv.Title
+	params["description"] = inv.Description
+	params["payload"] = inv.Payload
+	params["currency"] = inv.currency()
+	params.AddNonEmpty("provider_token", inv.ProviderToken)
+	params.AddNonEmpty("photo_url", inv.PhotoURL)
+	params.AddBool("need_name", inv.NeedName)
+	params.AddBool("need_phone_number", inv.NeedPhoneNumber)
+	params.AddBool("need_shipping_address", inv.NeedShippingAddress)
+	params.AddBool("is_flexible", inv.Flexible)
+	if err := params.AddInterface("prices", inv.Prices); err != nil {
+		return nil, fmt.Errorf("failed to encode prices: %w", err)
+	}
+	return params, nil
+}
+
+// PreCheckoutHandlerFunc confirms an order before Telegram charges the user.
+// Returning nil accepts the order; an error declines it and its message is
+// shown to the user. Telegram cancels payments not answered within 10 seconds.
+type PreCheckoutHandlerFunc func(ctx *Context, query *tgbotapi.PreCheckoutQuery) error
+
+// PaymentHandlerFunc handles a successful payment, e.g. by delivering the goods.
+// Keep payment.TelegramPaymentChargeID to refund the payment later.
+type PaymentHandlerFunc func(ctx *Context, payment *tgbotapi.SuccessfulPayment) error
+
+// HandlePreCheckout registers the handler that confirms orders before payment.
+// Without a handler every order is accepted.
+//
+// Example:
+//
+//	bot.HandlePreCheckout(func(ctx *teleflow.Context, query *tgbotapi.PreCheckoutQuery) error {
+//		if !inStock(query.InvoicePayload) {
+//			return errors.New("Sorry, this item just sold out.")
+//		}
+//		return nil
+//	})
+func (b *Bot) HandlePreCheckout(handler PreCheckoutHandlerFunc) {
+	wrappedHandler := func(ctx *Context) error {
+		return handler(ctx, ctx.update.PreCheckoutQuery)
+	}
+	b.preCheckoutHandler = b.applyMiddleware(wrappedHandler)
+}
+
+// HandleSuccessfulPayment registers the handler for successful payments. It
+// runs before the payment message reaches the user's flow, so a flow step
+// waiting for the payment can rely on it having been recorded; the step sees the
+// payment through ctx.Payment(). Payment messages never reach text handlers.
+//
+// Example:
+//
+//	bot.HandleSuccessfulPayment(func(ctx *teleflow.Context, payment *tgbotapi.SuccessfulPayment) error {
+//		if err := store.GrantPremium(ctx.UserID(), payment.TelegramPaymentChargeID); err != nil {
+//			return err
+//		}
+//		return ctx.SendPromptText("Thank you! Premium is now active.")
+//	})
+func (b *Bot) HandleSuccessfulPayment(handler PaymentHandlerFunc) {
+	wrappedHandler := func(ctx *Context) error {
+		return handler(ctx, ctx.update.Message.SuccessfulPayment)
+	}
+	b.paymentHandler = b.applyMiddleware(wrappedHandler)
+}
+
+// ShippingHandlerFunc returns the shipping options available for the address in
+// a shipping query. An error tells the user delivery to the address is not
+// possible; its message is shown to them.
+type ShippingHandlerFunc func(ctx *Context, query *tgbotapi.ShippingQuery) ([]tgbotapi.ShippingOption, error)
+
+// HandleShippingQuery registers the handler that offers shipping options for
+// Flexible invoices once the user enters their address. Without a handler
+// shipping queries are declined.
+//
+// Example:
+//
+//	bot.HandleShippingQuery(func(ctx *teleflow.Context, query *tgbotapi.ShippingQuery) ([]tgbotapi.ShippingOption, error) {
+//		if query.ShippingAddress.CountryCode != "DE" {
+//			return nil, errors.New("We only ship within Germany.")
+//		}
+//		return []tgbotapi.ShippingOption{
+//			teleflow.ShippingOptionFor("standard", "Standard (5-7 days)", teleflow.Money{Minor: 499, Currency: "EUR"}),
+//			teleflow.ShippingOptionFor("express", "Express (1-2 days)", teleflow.Money{Minor: 1299, Currency: "EUR"}),
+//		}, nil
+//	})
+func (b *Bot) HandleShippingQuery(handler ShippingHandlerFunc) {
+	b.shippingHandler = handler
+}
+
+// ShippingOptionFor returns a shipping option with a single price line. The
+// price's currency must match the invoice's.
+func ShippingOptionFor(id, title string, price Money) tgbotapi.ShippingOption {
+	return tgbotapi.ShippingOption{
+		ID:     id,
+		Title:  title,
+		Prices: []tgbotapi.LabeledPrice{{Label: title, Amount: int(price.Minor)}},
+	}
+}
+
+// Payment returns the successful payment carried by the current message, or nil
+// for other updates.
+func (c *Context) Payment() *tgbotapi.SuccessfulPayment {
+	if c.update.Message == nil {
+		return nil
+	}
+	return c.update.Message.SuccessfulPayment
+}
+
+// RefundStarPayment refunds a payment made in Telegram Stars to the user who
+// made it.
+//
+// Example:
+//
+//	err := bot.RefundStarPayment(userID, payment.TelegramPaymentChargeID)
+func (b *Bot) RefundStarPayment(userID int64, chargeID string) error {
+	return refundStarPayment(b.sender, userID, chargeID)
+}
+
+// RefundStarPayment refunds a payment the current user made in Telegram Stars.
+func (c *Context) RefundStarPayment(chargeID string) error {
+	return refundStarPayment(c.telegramClient, c.UserID(), chargeID)
+}
+
+func refundStarPayment(client TelegramClient, userID int64, chargeID string) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("user_id", userID)
+	params.AddNonEmpty("telegram_payment_charge_id", chargeID)
+
+	if _, err := makeRawRequest(client, "refundStarPayment", params); err != nil {
+		return fmt.Errorf("failed to refund star payment: %w", err)
+	}
+	return nil
+}
+
+// handlePreCheckout answers a pre-checkout query with the registered handler's
+// verdict.
+func (b *Bot) handlePreCheckout(ctx *Context) {
+	query := ctx.update.PreCheckoutQuery
+	answer := tgbotapi.PreCheckoutConfig{PreCheckoutQueryID: query.ID, OK: true}
+
+	if b.preCheckoutHandler != nil {
+		if err := b.stats.track("precheckout", func() error { return b.preCheckoutHandler(ctx) }); err != nil {
+			answer.OK = false
+			answer.ErrorMessage = err.Error()
+		}
+	}
+
+	if _, err := ctx.telegramClient.Request(answer); err != nil {
+		log.Printf("Failed to answer pre-checkout query for UserID %d: %v", ctx.UserID(), err)
+	}
+}
+
+// handleShippingQuery answers a shipping query with the options the registered
+// handler returns.
+func (b *Bot) handleShippingQuery(ctx *Context) {
+	query := ctx.update.ShippingQuery
+	answer := tgbotapi.ShippingConfig{ShippingQueryID: query.ID, ErrorMessage: "Shipping is not available."}
+
+	if b.shippingHandler != nil {
+		var options []tgbotapi.ShippingOption
+		wrappedHandler := func(ctx *Context) error {
+			var err error
+			options, err = b.shippingHandler(ctx, query)
+			return err
+		}
+		err := b.stats.track("shipping", func() error { return b.applyMiddleware(wrappedHandler)(ctx) })
+		switch {
+		case err != nil:
+			answer.ErrorMessage = err.Error()
+		case len(options) > 0:
+			answer = tgbotapi.ShippingConfig{ShippingQueryID: query.ID, OK: true, ShippingOptions: options}
+		}
+	}
+
+	if _, err := ctx.telegramClient.Request(answer); err != nil {
+		log.Printf("Failed to answer shipping query for UserID %d: %v", ctx.UserID(), err)
+	}
+}
+
+// handleSuccessfulPayment runs the registered payment handler.
+func (b *Bot) handleSuccessfulPayment(ctx *Context) {
+	if b.paymentHandler == nil {
+		return
+	}
+	if err := b.stats.track("payment", func() error { return b.paymentHandler(ctx) }); err != nil {
+		log.Printf("Payment handler error for UserID %d: %v", ctx.UserID(), err)
+		b.deadLetter(ctx, fmt.Errorf("payment: %w", err), 1)
+	}
+}
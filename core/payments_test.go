@@ -0,0 +1,209 @@
+package teleflow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSendInvoice_Stars(t *testing.T) {
+	client := NewMockTelegramClient()
+	ctx := newContext(tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: 5}, Chat: &tgbotapi.Chat{ID: 5}}},
+		client, nil, nil, nil, nil)
+
+	if err := ctx.SendInvoice(StarsInvoice("Sticker pack", "20 stickers", "stickers_1", 100)); err != nil {
+		t.Fatalf("SendInvoice failed: %v", err)
+	}
+	if len(client.MakeRequestCalls) != 1 || client.MakeRequestCalls[0].Endpoint != "sendInvoice" {
+		t.Fatalf("Expected one sendInvoice call, got %+v", client.MakeRequestCalls)
+	}
+	params := client.MakeRequestCalls[0].Params
+	if params["currency"] != "XTR" || params["chat_id"] != "5" || params["payload"] != "stickers_1" {
+		t.Errorf("Unexpected invoice params %v", params)
+	}
+	if _, ok := params["provider_token"]; ok {
+		t.Error("Stars invoices must not send a provider token")
+	}
+	if params["prices"] != `[{"label":"Sticker pack","amount":100}]` {
+		t.Errorf("Unexpected prices %q", params["prices"])
+	}
+
+	invalid := []Invoice{
+		{Title: "Pack", Description: "Two prices", Payload: "p", Prices: []tgbotapi.LabeledPrice{{Label: "a", Amount: 1}, {Label: "b", Amount: 2}}},
+		{Title: "Pack", Description: "Fiat without token", Payload: "p", Currency: "USD", Prices: []tgbotapi.LabeledPrice{{Label: "a", Amount: 100}}},
+		{Title: "Pack", Description: "No price", Payload: "p"},
+	}
+	for _, inv := range invalid {
+		if err := ctx.SendInvoice(inv); err == nil {
+			t.Errorf("Expected %q to be rejected", inv.Description)
+		}
+	}
+	if len(client.MakeRequestCalls) != 1 {
+		t.Errorf("Invalid invoices must not be sent, got %d calls", len(client.MakeRequestCalls))
+	}
+}
+
+func TestPreCheckoutAnswers(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+	query := func(payload string) tgbotapi.Update {
+		return tgbotapi.Update{PreCheckoutQuery: &tgbotapi.PreCheckoutQuery{ID: payload, From: &tgbotapi.User{ID: 9}, Currency: "XTR", TotalAmount: 100, InvoicePayload: payload}}
+	}
+
+	// Without a handler every order is accepted
+	bot.processUpdate(query("a"))
+
+	bot.HandlePreCheckout(func(ctx *Context, q *tgbotapi.PreCheckoutQuery) error {
+		if ctx.UserID() != 9 {
+			t.Errorf("Expected the paying user, got %d", ctx.UserID())
+		}
+		if q.InvoicePayload == "sold_out" {
+			return errors.New("Sold out")
+		}
+		return nil
+	})
+	bot.processUpdate(query("sold_out"))
+
+	if len(client.RequestCalls) != 2 {
+		t.Fatalf("Expected two answers, got %d", len(client.RequestCalls))
+	}
+	if answer := client.RequestCalls[0].(tgbotapi.PreCheckoutConfig); !answer.OK || answer.PreCheckoutQueryID != "a" {
+		t.Errorf("Expected the order to be accepted, got %+v", answer)
+	}
+	if answer := client.RequestCalls[1].(tgbotapi.PreCheckoutConfig); answer.OK || answer.ErrorMessage != "Sold out" {
+		t.Errorf("Expected the order to be declined, got %+v", answer)
+	}
+}
+
+func TestSuccessfulPayment(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+
+	var recorded []string
+	bot.HandleSuccessfulPayment(func(ctx *Context, payment *tgbotapi.SuccessfulPayment) error {
+		recorded = append(recorded, payment.TelegramPaymentChargeID)
+		return nil
+	})
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		t.Error("Payment messages must not reach text handlers")
+		return nil
+	})
+
+	var paid *tgbotapi.SuccessfulPayment
+	flow, err := NewFlow("buy").
+		Step("pay").
+		Prompt("Pay for the sticker pack").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			if paid = ctx.Payment(); paid == nil {
+				return Retry()
+			}
+			return CompleteFlow()
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	userID := int64(42)
+	payment := func(chargeID string) tgbotapi.Update {
+		return tgbotapi.Update{Message: &tgbotapi.Message{
+			From: &tgbotapi.User{ID: userID}, Chat: &tgbotapi.Chat{ID: userID, Type: "private"},
+			SuccessfulPayment: &tgbotapi.SuccessfulPayment{Currency: "XTR", TotalAmount: 100, InvoicePayload: "stickers_1", TelegramPaymentChargeID: chargeID},
+		}}
+	}
+
+	bot.processUpdate(payment("charge_1"))
+	if err := bot.StartFlowFor(userID, userID, "buy", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+	bot.processUpdate(payment("charge_2"))
+
+	if len(recorded) != 2 || recorded[1] != "charge_2" {
+		t.Fatalf("Expected both payments to be recorded, got %v", recorded)
+	}
+	if paid == nil || paid.TelegramPaymentChargeID != "charge_2" || bot.flowManager.isUserInFlow(userID, userID) {
+		t.Errorf("Expected the flow step to complete with the payment, got %+v", paid)
+	}
+
+	if err := bot.RefundStarPayment(userID, "charge_2"); err != nil {
+		t.Fatalf("RefundStarPayment failed: %v", err)
+	}
+	refund := client.MakeRequestCalls[len(client.MakeRequestCalls)-1]
+	if refund.Endpoint != "refundStarPayment" || refund.Params["user_id"] != "42" || refund.Params["telegram_payment_charge_id"] != "charge_2" {
+		t.Errorf("Unexpected refund request %+v", refund)
+	}
+}
+
+func TestShippingQuery(t *testing.T) {
+	bot, client, _, _ := createTestBot()
+	query := func(country string) tgbotapi.Update {
+		return tgbotapi.Update{ShippingQuery: &tgbotapi.ShippingQuery{ID: country, From: &tgbotapi.User{ID: 9}, InvoicePayload: "order_1",
+			ShippingAddress: &tgbotapi.ShippingAddress{CountryCode: country, City: "Berlin"}}}
+	}
+
+	// Without a handler shipping is declined
+	bot.processUpdate(query("DE"))
+
+	bot.HandleShippingQuery(func(ctx *Context, q *tgbotapi.ShippingQuery) ([]tgbotapi.ShippingOption, error) {
+		if q.ShippingAddress.CountryCode != "DE" {
+			return nil, errors.New("We only ship within Germany.")
+		}
+		return []tgbotapi.ShippingOption{ShippingOptionFor("express", "Express", Money{Minor: 1299, Currency: "EUR"})}, nil
+	})
+	bot.processUpdate(query("DE"))
+	bot.processUpdate(query("FR"))
+
+	if len(client.RequestCalls) != 3 {
+		t.Fatalf("Expected three answers, got %d", len(client.RequestCalls))
+	}
+	if answer := client.RequestCalls[0].(tgbotapi.ShippingConfig); answer.OK || answer.ErrorMessage == "" {
+		t.Errorf("Expected shipping to be declined without a handler, got %+v", answer)
+	}
+	answer := client.RequestCalls[1].(tgbotapi.ShippingConfig)
+	if !answer.OK || len(answer.ShippingOptions) != 1 || answer.ShippingOptions[0].Prices[0].Amount != 1299 {
+		t.Errorf("Expected the express option for 1299 cents, got %+v", answer)
+	}
+	if answer := client.RequestCalls[2].(tgbotapi.ShippingConfig); answer.OK || answer.ErrorMessage != "We only ship within Germany." {
+		t.Errorf("Expected the address to be declined, got %+v", answer)
+	}
+
+	ctx := newContext(tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: 9}, Chat: &tgbotapi.Chat{ID: 9}}}, client, nil, nil, nil, nil)
+	if err := ctx.SendInvoice(Invoice{Title: "Jacket", Description: "Winter jacket", Payload: "order_1", Currency: "EUR", ProviderToken: "token",
+		Prices: []tgbotapi.LabeledPrice{{Label: "Jacket", Amount: 14999}}, NeedShippingAddress: true, Flexible: true}); err != nil {
+		t.Fatalf("SendInvoice failed: %v", err)
+	}
+	if params := client.MakeRequestCalls[0].Params; params["is_flexible"] != "true" || params["need_shipping_address"] != "true" || params["provider_token"] != "token" {
+		t.Errorf("Unexpected invoice params %v", params)
+	}
+	stars := StarsInvoice("Jacket", "Winter jacket", "order_1", 500)
+	stars.NeedShippingAddress = true
+	if err := ctx.SendInvoice(stars); err == nil {
+		t.Error("Expected Stars invoices with shipping to be rejected")
+	}
+}
+
+func TestSendInvoice_Keyboard(t *testing.T) {
+	client := NewMockTelegramClient()
+	ctx := createTestContext()
+	ctx.telegramClient = client
+
+	inv := StarsInvoice("Sticker pack", "20 stickers", "stickers_1", 100)
+	inv.Keyboard = func(*Context) *PromptKeyboardBuilder {
+		return NewPromptKeyboard().ButtonPay("Pay ⭐100").Row().ButtonCallback("Cancel", "cancel")
+	}
+	if err := ctx.SendInvoice(inv); err != nil {
+		t.Fatalf("SendInvoice failed: %v", err)
+	}
+	markup := client.MakeRequestCalls[0].Params["reply_markup"]
+	if !strings.Contains(markup, `"pay":true`) || !strings.Contains(markup, `"callback_data"`) {
+		t.Errorf("Expected the pay and cancel buttons, got %s", markup)
+	}
+
+	inv.Keyboard = func(*Context) *PromptKeyboardBuilder {
+		return NewPromptKeyboard().ButtonCallback("Cancel", "cancel").ButtonPay("Pay")
+	}
+	if err := ctx.SendInvoice(inv); err == nil {
+		t.Error("Expected a keyboard without a leading pay button to be rejected")
+	}
+}
@@ -0,0 +1,269 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// updatesConfig collects the options that control how the bot receives
+// updates, by long polling or webhook, and hands them to its handlers.
+type updatesConfig struct {
+	allowedUpdates []string           // Update types requested from Telegram (nil for Telegram's default)
+	offsets        OffsetStore        // Persists the polling offset across restarts (nil if disabled)
+	dedupe         DedupeStore        // Drops updates delivered more than once (nil if disabled)
+	dropPending    bool               // Skip the updates that queued up while the bot was down
+	poller         *poller            // Polling loop configured with WithPolling (nil for telegram-bot-api's)
+	queue          *UpdateQueueConfig // Bounded queue feeding a worker pool (nil for a goroutine per update)
+}
+
+// Defaults of the polling loop enabled with WithPolling.
+const (
+	defaultPollBackoffMin = time.Second
+	defaultPollBackoffMax = time.Minute
+	defaultPollAlertAfter = 5
+)
+
+// PollingBackoff controls how long Start waits after getUpdates fails. The
+// delay starts at Min and doubles with every consecutive failure up to Max.
+type PollingBackoff struct {
+	Min time.Duration // Delay after the first failure, 1s by default
+	Max time.Duration // Longest delay, 1m by default
+
+	// AlertAfter is the number of consecutive failures after which OnAlert is
+	// called, 5 by default.
+	AlertAfter int
+
+	// OnAlert is called for each failure once AlertAfter is reached, e.g. to
+	// page whoever runs the bot. It runs on the polling goroutine.
+	OnAlert func(failures int, err error)
+}
+
+// poller is the polling loop Start runs instead of telegram-bot-api's.
+type poller struct {
+	timeout time.Duration // Long-polling wait of each request, 0 for the default
+	limit   int           // Most updates per request, 0 for Telegram's default of 100
+	backoff PollingBackoff
+}
+
+// WithAllowedUpdates limits the updates Telegram sends the bot to the given
+// types, e.g. "message" and "callback_query", so it is not woken up for updates
+// it does not handle. The list is passed to getUpdates by Start and to
+// setWebhook by SetWebhook. Update types that Telegram only sends on request,
+// such as "message_reaction", "chat_member" or "chat_boost", must be listed
+// here to be received.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithAllowedUpdates(
+//		"message", "callback_query", "message_reaction",
+//	))
+func WithAllowedUpdates(types ...string) BotOption {
+	return func(b *Bot) {
+		b.updatesConfig.allowedUpdates = append([]string{}, types...)
+	}
+}
+
+// WithDropPendingUpdates makes the bot ignore the updates that queued up while
+// it was not running, for bots where answering hours-old messages after
+// downtime is worse than not answering them. Start skips past the backlog
+// before polling, also overriding a stored offset, and SetWebhook asks
+// Telegram to drop it.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithDropPendingUpdates())
+func WithDropPendingUpdates() BotOption {
+	return func(b *Bot) {
+		b.updatesConfig.dropPending = true
+	}
+}
+
+// startOffset returns the update_id long polling starts from: the one stored
+// with WithOffsetStore, or the one after the backlog with
+// WithDropPendingUpdates.
+func (b *Bot) startOffset() (int, error) {
+	offset := 0
+	if store := b.updatesConfig.offsets; store != nil {
+		var err error
+		if offset, err = store.LoadOffset(); err != nil {
+			return 0, fmt.Errorf("failed to load update offset: %w", err)
+		}
+	}
+
+	if b.updatesConfig.dropPending {
+		next, err := b.dropPendingUpdates()
+		if err != nil {
+			return 0, fmt.Errorf("failed to drop pending updates: %w", err)
+		}
+		if next > 0 {
+			offset = next
+		}
+	}
+	return offset, nil
+}
+
+// dropPendingUpdates asks for the newest pending update, which makes Telegram
+// forget the older ones, and returns the update_id after it, or 0 if nothing
+// was pending.
+func (b *Bot) dropPendingUpdates() (int, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero("offset", -1)
+	params.AddNonZero("limit", 1)
+	resp, err := makeRawRequest(b.api, "getUpdates", params)
+	if err != nil {
+		return 0, b.secrets.scrubError(err)
+	}
+	if resp == nil || len(resp.Result) == 0 {
+		return 0, nil
+	}
+
+	var pending []struct {
+		UpdateID int `json:"update_id"`
+	}
+	if err := json.Unmarshal(resp.Result, &pending); err != nil {
+		return 0, fmt.Errorf("failed to decode updates: %w", err)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	next := pending[len(pending)-1].UpdateID + 1
+	log.Printf("[UPDATES_DROPPED] Skipping pending updates before %d", next)
+	return next, nil
+}
+
+// WithPolling makes Start fetch updates with its own loop, waiting up to
+// timeout for updates in each request and asking for at most limit updates at
+// a time (1-100, 0 for Telegram's default). A timeout of 0 keeps the default
+// wait, which is shortened like any other to fit within WithAPITimeout. When
+// getUpdates fails the loop backs off exponentially instead of retrying every
+// few seconds; see WithPollingBackoff.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithPolling(30*time.Second, 50))
+func WithPolling(timeout time.Duration, limit int) BotOption {
+	return func(b *Bot) {
+		p := b.ensurePoller()
+		p.timeout = timeout
+		p.limit = limit
+	}
+}
+
+// WithPollingBackoff configures how Start backs off and alerts when getUpdates
+// fails repeatedly, e.g. because the token was revoked or the network is down.
+// It enables the polling loop of WithPolling with the default timeout if that
+// option is not given.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithPollingBackoff(teleflow.PollingBackoff{
+//		Max:        5 * time.Minute,
+//		AlertAfter: 10,
+//		OnAlert: func(failures int, err error) {
+//			alerting.Notify("telegram polling failing", failures, err)
+//		},
+//	}))
+func WithPollingBackoff(backoff PollingBackoff) BotOption {
+	return func(b *Bot) {
+		b.ensurePoller().backoff = backoff
+	}
+}
+
+// ensurePoller returns the polling loop configuration, creating it with the
+// defaults.
+func (b *Bot) ensurePoller() *poller {
+	if b.updatesConfig.poller == nil {
+		b.updatesConfig.poller = &poller{}
+	}
+	return b.updatesConfig.poller
+}
+
+// pollUpdates starts the polling loop at offset and returns the channel it
+// delivers updates to.
+func (b *Bot) pollUpdates(offset int) tgbotapi.UpdatesChannel {
+	p := b.updatesConfig.poller
+	backoff := p.backoff
+	if backoff.Min <= 0 {
+		backoff.Min = defaultPollBackoffMin
+	}
+	if backoff.Max < backoff.Min {
+		backoff.Max = max(defaultPollBackoffMax, backoff.Min)
+	}
+	if backoff.AlertAfter <= 0 {
+		backoff.AlertAfter = defaultPollAlertAfter
+	}
+	timeout := int(p.timeout / time.Second)
+	if p.timeout <= 0 {
+		timeout = b.apiConfig.pollTimeout()
+	} else if b.apiConfig.timeout > 0 {
+		timeout = min(timeout, b.apiConfig.pollTimeout())
+	}
+
+	updates := make(chan tgbotapi.Update, 100)
+	go func() {
+		failures := 0
+		delay := backoff.Min
+		for {
+			batch, err := b.getUpdates(offset, p.limit, timeout)
+			if err != nil {
+				failures++
+				log.Printf("[POLLING_FAILED] Attempt %d, retrying in %s: %v", failures, delay, err)
+				if failures >= backoff.AlertAfter && backoff.OnAlert != nil {
+					backoff.OnAlert(failures, err)
+				}
+				b.sleep(delay)
+				delay = min(delay*2, backoff.Max)
+				continue
+			}
+			if failures > 0 {
+				log.Printf("[POLLING_RECOVERED] After %d failed attempts", failures)
+				failures = 0
+				delay = backoff.Min
+			}
+
+			for _, update := range batch {
+				if update.UpdateID >= offset {
+					offset = update.UpdateID + 1
+					updates <- update
+				}
+			}
+		}
+	}()
+	return updates
+}
+
+// getUpdates makes one getUpdates request.
+func (b *Bot) getUpdates(offset, limit, timeout int) ([]tgbotapi.Update, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero("offset", offset)
+	params.AddNonZero("limit", limit)
+	params.AddNonZero("timeout", timeout)
+	if b.updatesConfig.allowedUpdates != nil {
+		if err := params.AddInterface("allowed_updates", b.updatesConfig.allowedUpdates); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := makeRawRequest(b.api, "getUpdates", params)
+	if err != nil {
+		return nil, b.secrets.scrubError(err)
+	}
+	var updates []tgbotapi.Update
+	if resp != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, &updates); err != nil {
+			return nil, fmt.Errorf("failed to decode updates: %w", err)
+		}
+	}
+	return updates, nil
+}
+
+// sleep waits for d on the bot's clock.
+func (b *Bot) sleep(d time.Duration) {
+	done := make(chan struct{})
+	b.clock.AfterFunc(d, func() { close(done) })
+	<-done
+}
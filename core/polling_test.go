@@ -0,0 +1,147 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestWithAllowedUpdates(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot(WithAllowedUpdates("message", "callback_query", "message_reaction"))
+	want := []string{"message", "callback_query", "message_reaction"}
+
+	mockClient.GetUpdatesChanFunc = func(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+		updates := make(chan tgbotapi.Update)
+		close(updates)
+		return updates
+	}
+	if err := bot.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if got := mockClient.GetUpdatesChanCalls[0].AllowedUpdates; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected getUpdates to request %v, got %v", want, got)
+	}
+
+	if err := bot.SetWebhook("https://bot.example.com/telegram", ""); err != nil {
+		t.Fatalf("SetWebhook failed: %v", err)
+	}
+	var got []string
+	call := mockClient.MakeRequestCalls[len(mockClient.MakeRequestCalls)-1]
+	if err := json.Unmarshal([]byte(call.Params["allowed_updates"]), &got); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected setWebhook to request %v, got %q", want, call.Params["allowed_updates"])
+	}
+}
+
+func TestAllowedUpdatesDefault(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	if err := bot.SetWebhook("https://bot.example.com/telegram", ""); err != nil {
+		t.Fatalf("SetWebhook failed: %v", err)
+	}
+	if _, ok := mockClient.MakeRequestCalls[0].Params["allowed_updates"]; ok {
+		t.Error("Expected allowed_updates to be left to Telegram by default")
+	}
+}
+
+func TestWithDropPendingUpdates(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot(WithDropPendingUpdates())
+	mockClient.MakeRequestFunc = func(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+		if endpoint == "getUpdates" {
+			return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage(`[{"update_id":77}]`)}, nil
+		}
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+	mockClient.GetUpdatesChanFunc = func(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+		updates := make(chan tgbotapi.Update)
+		close(updates)
+		return updates
+	}
+
+	if err := bot.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if call := mockClient.MakeRequestCalls[0]; call.Endpoint != "getUpdates" || call.Params["offset"] != "-1" {
+		t.Errorf("Expected the backlog to be skipped with offset -1, got %+v", call)
+	}
+	if offset := mockClient.GetUpdatesChanCalls[0].Offset; offset != 78 {
+		t.Errorf("Expected polling to start after the dropped update, got offset %d", offset)
+	}
+
+	if err := bot.SetWebhook("https://bot.example.com/telegram", ""); err != nil {
+		t.Fatalf("SetWebhook failed: %v", err)
+	}
+	if call := mockClient.MakeRequestCalls[len(mockClient.MakeRequestCalls)-1]; call.Params["drop_pending_updates"] != "true" {
+		t.Errorf("Expected setWebhook to drop pending updates, got %+v", call.Params)
+	}
+}
+
+func TestWithPolling_BacksOffAndAlerts(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []int
+	var firstParams tgbotapi.Params
+	requests := 0
+
+	bot, mockClient, _, _ := createTestBot(
+		WithPolling(10*time.Second, 5),
+		WithPollingBackoff(PollingBackoff{
+			Min:        time.Millisecond,
+			Max:        4 * time.Millisecond,
+			AlertAfter: 2,
+			OnAlert: func(failures int, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				alerts = append(alerts, failures)
+			},
+		}),
+	)
+	mockClient.MakeRequestFunc = func(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+		mu.Lock()
+		requests++
+		n := requests
+		if n == 1 {
+			firstParams = params
+		}
+		mu.Unlock()
+
+		switch {
+		case n <= 3:
+			return nil, errors.New("bad gateway")
+		case n == 4:
+			return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage(
+				`[{"update_id":5,"message":{"message_id":1,"from":{"id":7},"chat":{"id":7,"type":"private"},"date":0,"text":"hi"}}]`,
+			)}, nil
+		default:
+			select {}
+		}
+	}
+
+	received := make(chan string, 1)
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		received <- text
+		return nil
+	})
+	go bot.Start()
+
+	select {
+	case text := <-received:
+		if text != "hi" {
+			t.Errorf("Expected the polled message, got %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an update once getUpdates recovered")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(alerts, []int{2, 3}) {
+		t.Errorf("Expected alerts after the 2nd and 3rd failure, got %v", alerts)
+	}
+	if firstParams["limit"] != "5" || firstParams["timeout"] != "10" {
+		t.Errorf("Expected limit 5 and timeout 10, got %v", firstParams)
+	}
+}
@@ -2,9 +2,13 @@ package teleflow
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"log"
+	"regexp"
 	"strings"
+	"unicode/utf16"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -28,26 +32,82 @@ func newPromptComposer(botAPI TelegramClient, msgRenderer *messageHandler, imgHa
 	}
 }
 
+// composedMessage is a prompt that has been fully rendered and is ready to be sent.
+type composedMessage struct {
+	text      string
+	parseMode ParseMode
+	entities  []MessageEntity
+	image     *processedImage
+	keyboard  *tgbotapi.InlineKeyboardMarkup
+
+	replyMarkup interface{} // Reply keyboard change requested by the prompt, replacing the pending one
+
+	businessConnectionID string // Business account the message is sent on behalf of
+}
+
 func (pc *PromptComposer) ComposeAndSend(ctx *Context, promptConfig *PromptConfig) error {
 	if err := pc.validatePromptConfig(promptConfig); err != nil {
 		return fmt.Errorf("invalid PromptConfig: %w", err)
 	}
 
+	// Render every message of the sequence before sending anything, so that a
+	// failing template or keyboard does not leave half a prompt in the chat
+	var messages []*composedMessage
+	for i, part := range promptConfig.Sequence {
+		if part.TemplateData == nil {
+			partCopy := *part
+			partCopy.TemplateData = promptConfig.TemplateData
+			part = &partCopy
+		}
+		msg, err := pc.compose(ctx, part)
+		if err != nil {
+			return fmt.Errorf("sequence message %d: %w", i+1, err)
+		}
+		messages = append(messages, msg)
+	}
+	if promptConfig.Message != nil || promptConfig.Image != nil || promptConfig.Keyboard != nil {
+		msg, err := pc.compose(ctx, promptConfig)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, msg)
+	}
+
+	if target := ctx.editTarget; target.MessageID != 0 {
+		ctx.editTarget = sentPrompt{}
+		if len(messages) == 1 && messages[0].replyMarkup == nil && len(messages[0].entities) == 0 && messages[0].businessConnectionID == "" && pc.editInPlace(ctx, target, messages[0].image, messages[0].text, messages[0].parseMode, messages[0].keyboard) {
+			return nil
+		}
+	}
+
+	for _, msg := range messages {
+		if err := pc.send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compose renders the text, image and inline keyboard of a single prompt message.
+func (pc *PromptComposer) compose(ctx *Context, promptConfig *PromptConfig) (*composedMessage, error) {
 	messageText, parseMode, err := pc.messageRenderer.renderMessage(promptConfig, ctx)
 	if err != nil {
-		return fmt.Errorf("message rendering failed: %w", err)
+		return nil, fmt.Errorf("message rendering failed: %w", err)
+	}
+	if len(promptConfig.Entities) > 0 && parseMode != ParseModeNone {
+		return nil, fmt.Errorf("message rendering failed: %w (%s)", errEntitiesWithParseMode, parseMode)
 	}
 
 	processedImg, err := pc.imageHandler.processImage(promptConfig.Image, ctx)
 	if err != nil {
-		return fmt.Errorf("image processing failed: %w", err)
+		return nil, fmt.Errorf("image processing failed: %w", err)
 	}
 
 	var tgInlineKeyboard *tgbotapi.InlineKeyboardMarkup
 	if promptConfig.Keyboard != nil {
 		builtKeyboard, err := pc.keyboardHandler.BuildKeyboard(ctx, promptConfig.Keyboard)
 		if err != nil {
-			return fmt.Errorf("keyboard building failed: %w", err)
+			return nil, fmt.Errorf("keyboard building failed: %w", err)
 		}
 		if builtKeyboard != nil {
 
@@ -59,35 +119,92 @@ func (pc *PromptComposer) ComposeAndSend(ctx *Context, promptConfig *PromptConfi
 		}
 	}
 
+	msg := &composedMessage{text: messageText, parseMode: parseMode, entities: promptConfig.Entities, image: processedImg, keyboard: tgInlineKeyboard}
+	msg.businessConnectionID = promptConfig.BusinessConnectionID
+	if msg.businessConnectionID == "" {
+		msg.businessConnectionID = ctx.businessConnectionID
+	}
+	if promptConfig.OneTimeReplyKeyboard != nil {
+		oneTime := *promptConfig.OneTimeReplyKeyboard
+		oneTime.OneTimeKeyboard = true
+		msg.replyMarkup = oneTime.ToTgbotapi()
+	} else if promptConfig.RemoveReplyKeyboard {
+		msg.replyMarkup = tgbotapi.NewRemoveKeyboard(false)
+	}
+	return msg, nil
+}
+
+// takeReplyMarkup returns the reply keyboard markup for a message without an inline
+// keyboard: the prompt's own reply keyboard change, or else the context's pending
+// reply keyboard. The pending keyboard is consumed either way, so a prompt that
+// hides the keyboard is not followed by one that shows it again in the same update.
+func (pc *PromptComposer) takeReplyMarkup(ctx *Context, msg *composedMessage) interface{} {
+	if msg.replyMarkup != nil {
+		ctx.pendingReplyKeyboard = nil
+		return msg.replyMarkup
+	}
+	if ctx.pendingReplyKeyboard != nil {
+		markup := ctx.pendingReplyKeyboard.ToTgbotapi()
+		ctx.pendingReplyKeyboard = nil // Clear after use
+		return markup
+	}
+	return nil
+}
+
+// send delivers a composed message to the context's chat.
+func (pc *PromptComposer) send(ctx *Context, msg *composedMessage) error {
+	processedImg, messageText, parseMode, tgInlineKeyboard := msg.image, msg.text, msg.parseMode, msg.keyboard
+
+	if processedImg != nil && captionLength(messageText, parseMode) > maxCaptionLength {
+		// Too long for a caption: send the photo first and the text, with the keyboard, after it
+		if err := pc.send(ctx, &composedMessage{image: processedImg, replyMarkup: msg.replyMarkup, businessConnectionID: msg.businessConnectionID}); err != nil {
+			return err
+		}
+		return pc.send(ctx, &composedMessage{text: messageText, parseMode: parseMode, entities: msg.entities, keyboard: tgInlineKeyboard, businessConnectionID: msg.businessConnectionID})
+	}
+	if msg.businessConnectionID != "" {
+		return pc.sendBusiness(ctx, msg)
+	}
+
+	entities, libraryEntities := tgEntities(msg.entities)
+
 	if processedImg != nil {
 
-		photoMsg := tgbotapi.NewPhoto(ctx.ChatID(), nil)
-		if processedImg.data != nil {
-			photoMsg.File = tgbotapi.FileBytes{Name: "image.jpg", Bytes: processedImg.data}
-		} else if processedImg.filePath != "" {
-			if strings.HasPrefix(processedImg.filePath, "http") {
-				photoMsg.File = tgbotapi.FileURL(processedImg.filePath)
-			} else {
-				photoMsg.File = tgbotapi.FilePath(processedImg.filePath)
-			}
-		} else {
-			return fmt.Errorf("processed image has no data or path")
+		photoFile, err := photoFileData(processedImg)
+		if err != nil {
+			return err
 		}
+		photoMsg := tgbotapi.NewPhoto(ctx.ChatID(), photoFile)
 
 		photoMsg.Caption = messageText
 		if parseMode != ParseModeNone {
 			photoMsg.ParseMode = string(parseMode)
 		}
+		if !libraryEntities {
+			return fmt.Errorf("custom emoji entities are not supported in photo captions")
+		}
+		photoMsg.CaptionEntities = entities
 		if tgInlineKeyboard != nil {
 			photoMsg.ReplyMarkup = tgInlineKeyboard
-		} else if ctx.pendingReplyKeyboard != nil {
-			// Attach pending reply keyboard if no inline keyboard is present
-			photoMsg.ReplyMarkup = ctx.pendingReplyKeyboard.ToTgbotapi()
-			ctx.pendingReplyKeyboard = nil // Clear after use
+		} else if markup := pc.takeReplyMarkup(ctx, msg); markup != nil {
+			// Attach the reply keyboard if no inline keyboard is present
+			photoMsg.ReplyMarkup = markup
 		}
 		// Log before sending photo message
 		logChattable("Sending photo message", photoMsg)
-		_, err = pc.botAPI.Send(photoMsg)
+		sent, err := clientFor(pc.botAPI, ctx).Send(photoMsg)
+		if err != nil && processedImg.hash != "" && processedImg.fileID != "" {
+			// Telegram rejected the cached file_id; upload the image again
+			log.Printf("[PROMPT_IMAGE] Cached file_id rejected, uploading image again: %v", err)
+			pc.imageHandler.forgetFileID(processedImg.hash)
+			processedImg.fileID = ""
+			photoMsg.File, _ = photoFileData(processedImg)
+			sent, err = clientFor(pc.botAPI, ctx).Send(photoMsg)
+		}
+		if err == nil {
+			pc.rememberUpload(processedImg, sent)
+		}
+		pc.recordSent(ctx, sent, err, tgInlineKeyboard != nil, true)
 		return err
 	} else if messageText != "" {
 
@@ -95,16 +212,23 @@ func (pc *PromptComposer) ComposeAndSend(ctx *Context, promptConfig *PromptConfi
 		if parseMode != ParseModeNone {
 			textMsg.ParseMode = string(parseMode)
 		}
+		textMsg.Entities = entities
 		if tgInlineKeyboard != nil {
 			textMsg.ReplyMarkup = tgInlineKeyboard
-		} else if ctx.pendingReplyKeyboard != nil {
-			// Attach pending reply keyboard if no inline keyboard is present
-			textMsg.ReplyMarkup = ctx.pendingReplyKeyboard.ToTgbotapi()
-			ctx.pendingReplyKeyboard = nil // Clear after use
+		} else if markup := pc.takeReplyMarkup(ctx, msg); markup != nil {
+			// Attach the reply keyboard if no inline keyboard is present
+			textMsg.ReplyMarkup = markup
+		}
+		if !libraryEntities {
+			logChattable("Sending text message with custom entities", textMsg)
+			sent, err := sendTextWithEntities(clientFor(pc.botAPI, ctx), ctx.ChatID(), messageText, msg.entities, textMsg.ReplyMarkup)
+			pc.recordSent(ctx, sent, err, tgInlineKeyboard != nil, false)
+			return err
 		}
 		// Log before sending text message
 		logChattable("Sending text message", textMsg)
-		_, err = pc.botAPI.Send(textMsg)
+		sent, err := clientFor(pc.botAPI, ctx).Send(textMsg)
+		pc.recordSent(ctx, sent, err, tgInlineKeyboard != nil, false)
 		return err
 	} else if tgInlineKeyboard != nil {
 
@@ -112,25 +236,158 @@ func (pc *PromptComposer) ComposeAndSend(ctx *Context, promptConfig *PromptConfi
 		invisibleMsg.ReplyMarkup = tgInlineKeyboard
 		// Log before sending invisible message for keyboard
 		logChattable("Sending invisible message for keyboard", invisibleMsg)
-		_, err = pc.botAPI.Send(invisibleMsg)
+		sent, err := clientFor(pc.botAPI, ctx).Send(invisibleMsg)
+		pc.recordSent(ctx, sent, err, true, false)
 		return err
-	} else if ctx.pendingReplyKeyboard != nil {
-		// Send invisible message with pending reply keyboard if no other content
+	} else if markup := pc.takeReplyMarkup(ctx, msg); markup != nil {
+		// Send invisible message with the reply keyboard if no other content
 		invisibleMsg := tgbotapi.NewMessage(ctx.ChatID(), "\u200B")
-		invisibleMsg.ReplyMarkup = ctx.pendingReplyKeyboard.ToTgbotapi()
-		ctx.pendingReplyKeyboard = nil // Clear after use
+		invisibleMsg.ReplyMarkup = markup
 		// Log before sending invisible message for pending reply keyboard
 		logChattable("Sending invisible message for pending reply keyboard", invisibleMsg)
-		_, err = pc.botAPI.Send(invisibleMsg)
+		sent, err := clientFor(pc.botAPI, ctx).Send(invisibleMsg)
+		pc.recordSent(ctx, sent, err, false, false)
 		return err
 	}
 
 	return nil
 }
 
+// recordSent remembers a successfully delivered prompt on the context so that
+// flows can later find the message again, e.g. to strip its keyboard.
+func (pc *PromptComposer) recordSent(ctx *Context, sent tgbotapi.Message, err error, inlineKeyboard, photo bool) {
+	if err != nil || sent.MessageID == 0 {
+		return
+	}
+	ctx.sentPrompts = append(ctx.sentPrompts, sentPrompt{ChatID: ctx.ChatID(), MessageID: sent.MessageID, InlineKeyboard: inlineKeyboard, Photo: photo})
+}
+
+// editInPlace replaces the content of a previously sent prompt instead of sending a
+// new message. Text can only replace text and photos only photos, and reply keyboards
+// cannot be attached to edited messages. It reports whether the edit succeeded; when
+// it did not, the caller sends a fresh message.
+func (pc *PromptComposer) editInPlace(ctx *Context, target sentPrompt, img *processedImage, text string, parseMode ParseMode, keyboard *tgbotapi.InlineKeyboardMarkup) bool {
+	if target.ChatID != ctx.ChatID() || ctx.pendingReplyKeyboard != nil {
+		return false
+	}
+
+	var editMsg tgbotapi.Chattable
+	switch {
+	case img != nil && target.Photo && captionLength(text, parseMode) <= maxCaptionLength:
+		photoFile, err := photoFileData(img)
+		if err != nil {
+			return false
+		}
+		media := tgbotapi.NewInputMediaPhoto(photoFile)
+		media.Caption = text
+		if parseMode != ParseModeNone {
+			media.ParseMode = string(parseMode)
+		}
+		editMsg = tgbotapi.EditMessageMediaConfig{
+			BaseEdit: tgbotapi.BaseEdit{ChatID: target.ChatID, MessageID: target.MessageID, ReplyMarkup: keyboard},
+			Media:    media,
+		}
+	case img == nil && !target.Photo && text != "":
+		textEdit := tgbotapi.NewEditMessageText(target.ChatID, target.MessageID, text)
+		if parseMode != ParseModeNone {
+			textEdit.ParseMode = string(parseMode)
+		}
+		textEdit.ReplyMarkup = keyboard
+		editMsg = textEdit
+	default:
+		return false
+	}
+
+	logChattable("Editing prompt message", editMsg)
+	sent, err := clientFor(pc.botAPI, ctx).Send(editMsg)
+	// An unchanged prompt is already shown as it should be
+	if err != nil && !errors.Is(err, ErrMessageNotModified) {
+		log.Printf("[PROMPT_EDIT_FAILED] Could not edit message %d in chat %d, sending a new one: %v", target.MessageID, target.ChatID, err)
+		return false
+	}
+	if sent.MessageID == 0 {
+		sent.MessageID = target.MessageID
+	}
+	if img != nil {
+		pc.rememberUpload(img, sent)
+	}
+	pc.recordSent(ctx, sent, nil, keyboard != nil, img != nil)
+	return true
+}
+
+// rememberUpload caches the file_id Telegram assigned to a freshly uploaded image,
+// so that sending the same bytes again reuses it instead of uploading them.
+func (pc *PromptComposer) rememberUpload(img *processedImage, sent tgbotapi.Message) {
+	if img.hash == "" || img.fileID != "" || len(sent.Photo) == 0 {
+		return
+	}
+	// Telegram lists the sizes it generated from smallest to largest
+	pc.imageHandler.rememberFileID(img.hash, sent.Photo[len(sent.Photo)-1].FileID)
+}
+
+// maxCaptionLength is the longest photo caption Telegram accepts.
+const maxCaptionLength = 1024
+
+// captionLength estimates the length Telegram counts for a caption: formatting markup
+// is not counted and characters are measured in UTF-16 code units. Markdown links are
+// counted with their URL, so the estimate errs on the long side.
+func captionLength(text string, parseMode ParseMode) int {
+	visible := text
+	switch parseMode {
+	case ParseModeHTML:
+		visible = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, ""))
+	case ParseModeMarkdown, ParseModeMarkdownV2:
+		var b strings.Builder
+		escaped := false
+		for _, r := range text {
+			switch {
+			case escaped:
+				b.WriteRune(r)
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case strings.ContainsRune("*_~|`", r):
+			default:
+				b.WriteRune(r)
+			}
+		}
+		visible = b.String()
+	}
+	return len(utf16.Encode([]rune(visible)))
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// photoFileData converts a processed image into the file reference sent to Telegram.
+func photoFileData(img *processedImage) (tgbotapi.RequestFileData, error) {
+	switch {
+	case img.fileID != "":
+		return tgbotapi.FileID(img.fileID), nil
+	case img.data != nil:
+		return tgbotapi.FileBytes{Name: "image.jpg", Bytes: img.data}, nil
+	case strings.HasPrefix(img.filePath, "http"):
+		return tgbotapi.FileURL(img.filePath), nil
+	case img.filePath != "":
+		return tgbotapi.FilePath(img.filePath), nil
+	default:
+		return nil, fmt.Errorf("processed image has no data or path")
+	}
+}
+
 func (pc *PromptComposer) validatePromptConfig(config *PromptConfig) error {
-	if config.Message == nil && config.Image == nil && config.Keyboard == nil {
-		return fmt.Errorf("PromptConfig must have at least one of Message, Image, or Keyboard specified")
+	if config.Message == nil && config.Image == nil && config.Keyboard == nil && len(config.Sequence) == 0 {
+		return fmt.Errorf("PromptConfig must have at least one of Message, Image, Keyboard, or Sequence specified")
+	}
+	for i, part := range config.Sequence {
+		if part == nil {
+			return fmt.Errorf("sequence message %d is nil", i+1)
+		}
+		if len(part.Sequence) > 0 {
+			return fmt.Errorf("sequence message %d cannot contain a sequence", i+1)
+		}
+		if err := pc.validatePromptConfig(part); err != nil {
+			return fmt.Errorf("sequence message %d: %w", i+1, err)
+		}
 	}
 	return nil
 }
@@ -1,7 +1,9 @@
 package teleflow
 
 import (
+	"bytes"
 	"errors"
+	"strings"
 	"testing"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -162,6 +164,20 @@ func TestPromptComposer_ValidatePromptConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid config with sequence only",
+			config: &PromptConfig{
+				Sequence: []*PromptConfig{{Message: "first"}, {Image: "test.jpg"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid config - nested sequence",
+			config: &PromptConfig{
+				Sequence: []*PromptConfig{{Sequence: []*PromptConfig{{Message: "nested"}}}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -605,3 +621,305 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestPromptComposer_ComposeAndSend_Sequence(t *testing.T) {
+	mockClient := &mockTelegramClient{}
+	mockTM := &mockTemplateManager{}
+	composer := createTestPromptComposer(mockClient, mockTM)
+	ctx := createTestContext()
+
+	config := &PromptConfig{
+		Message: "Add it to your cart?",
+		Keyboard: func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("Yes", "yes")
+		},
+		TemplateData: map[string]interface{}{"name": "Lamp"},
+		Sequence: []*PromptConfig{
+			{Image: []byte("fake-image-data")},
+			{Message: "template:product"},
+		},
+	}
+
+	var renderedData map[string]interface{}
+	mockTM.renderFunc = func(name string, data map[string]interface{}) (string, ParseMode, error) {
+		renderedData = data
+		return "Product details", ParseModeHTML, nil
+	}
+
+	if err := composer.ComposeAndSend(ctx, config); err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+
+	if len(mockClient.sentMessages) != 3 {
+		t.Fatalf("Expected 3 messages sent, got %d", len(mockClient.sentMessages))
+	}
+	if _, ok := mockClient.sentMessages[0].(tgbotapi.PhotoConfig); !ok {
+		t.Errorf("Expected first message to be a photo, got %T", mockClient.sentMessages[0])
+	}
+	if msg := mockClient.sentMessages[1].(tgbotapi.MessageConfig); msg.Text != "Product details" {
+		t.Errorf("Expected second message to be the rendered template, got %q", msg.Text)
+	}
+	if renderedData["name"] != "Lamp" {
+		t.Errorf("Expected sequence message to inherit template data, got %v", renderedData)
+	}
+	if msg := mockClient.sentMessages[2].(tgbotapi.MessageConfig); msg.Text != "Add it to your cart?" || msg.ReplyMarkup == nil {
+		t.Errorf("Expected last message to carry the keyboard, got %#v", msg)
+	}
+	if len(ctx.sentPrompts) != 3 || !ctx.sentPrompts[2].InlineKeyboard {
+		t.Errorf("Expected all three messages to be recorded, got %v", ctx.sentPrompts)
+	}
+}
+
+func TestPromptComposer_ComposeAndSend_SequenceRendersBeforeSending(t *testing.T) {
+	mockClient := &mockTelegramClient{}
+	mockTM := &mockTemplateManager{
+		renderFunc: func(name string, data map[string]interface{}) (string, ParseMode, error) {
+			return "", ParseModeNone, errors.New("template render failed")
+		},
+	}
+	composer := createTestPromptComposer(mockClient, mockTM)
+
+	err := composer.ComposeAndSend(createTestContext(), &PromptConfig{
+		Sequence: []*PromptConfig{
+			{Message: "First"},
+			{Message: "template:broken"},
+		},
+	})
+	if err == nil || !contains(err.Error(), "sequence message 2") {
+		t.Fatalf("Expected error for the second sequence message, got %v", err)
+	}
+	if len(mockClient.sentMessages) != 0 {
+		t.Errorf("Expected nothing to be sent, got %d messages", len(mockClient.sentMessages))
+	}
+}
+
+func TestPromptComposer_ImageFileIDCache(t *testing.T) {
+	rejectFileID := false
+	mockClient := &mockTelegramClient{
+		sendFunc: func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+			photo := c.(tgbotapi.PhotoConfig)
+			if _, ok := photo.File.(tgbotapi.FileID); ok && rejectFileID {
+				return tgbotapi.Message{}, errors.New("wrong file identifier")
+			}
+			return tgbotapi.Message{
+				MessageID: 1,
+				Photo:     []tgbotapi.PhotoSize{{FileID: "small"}, {FileID: "large"}},
+			}, nil
+		},
+	}
+	composer := createTestPromptComposer(mockClient, &mockTemplateManager{})
+	logo := []byte("logo-bytes")
+
+	for i := 0; i < 2; i++ {
+		if err := composer.ComposeAndSend(createTestContext(), &PromptConfig{Image: logo}); err != nil {
+			t.Fatalf("ComposeAndSend failed: %v", err)
+		}
+	}
+
+	if _, ok := mockClient.sentMessages[0].(tgbotapi.PhotoConfig).File.(tgbotapi.FileBytes); !ok {
+		t.Errorf("Expected first send to upload bytes")
+	}
+	if file := mockClient.sentMessages[1].(tgbotapi.PhotoConfig).File; file != tgbotapi.FileID("large") {
+		t.Errorf("Expected second send to reuse the largest file_id, got %#v", file)
+	}
+
+	// A rejected file_id falls back to uploading the bytes again
+	rejectFileID = true
+	if err := composer.ComposeAndSend(createTestContext(), &PromptConfig{Image: bytes.NewReader(logo)}); err != nil {
+		t.Fatalf("Expected fallback upload to succeed, got %v", err)
+	}
+	if len(mockClient.sentMessages) != 4 {
+		t.Fatalf("Expected a retry after the rejected file_id, got %d sends", len(mockClient.sentMessages))
+	}
+	if _, ok := mockClient.sentMessages[3].(tgbotapi.PhotoConfig).File.(tgbotapi.FileBytes); !ok {
+		t.Errorf("Expected retry to upload bytes")
+	}
+}
+
+func TestPromptComposer_ImageFileIDSpec(t *testing.T) {
+	mockClient := &mockTelegramClient{}
+	composer := createTestPromptComposer(mockClient, &mockTemplateManager{})
+
+	if err := composer.ComposeAndSend(createTestContext(), &PromptConfig{Image: ImageFileID("AgACAgIAAxk")}); err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+	if file := mockClient.sentMessages[0].(tgbotapi.PhotoConfig).File; file != tgbotapi.FileID("AgACAgIAAxk") {
+		t.Errorf("Expected file_id to be sent as is, got %#v", file)
+	}
+}
+
+func TestPromptComposer_TemplatedCaption(t *testing.T) {
+	mockClient := &mockTelegramClient{}
+	mockTM := &mockTemplateManager{
+		renderFunc: func(name string, data map[string]interface{}) (string, ParseMode, error) {
+			return "<b>" + data["name"].(string) + "</b>", ParseModeHTML, nil
+		},
+	}
+	composer := createTestPromptComposer(mockClient, mockTM)
+
+	err := composer.ComposeAndSend(createTestContext(), &PromptConfig{
+		Message:      "template:product",
+		Image:        []byte("photo"),
+		TemplateData: map[string]interface{}{"name": "Lamp"},
+	})
+	if err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+
+	if len(mockClient.sentMessages) != 1 {
+		t.Fatalf("Expected a single photo message, got %d", len(mockClient.sentMessages))
+	}
+	photo := mockClient.sentMessages[0].(tgbotapi.PhotoConfig)
+	if photo.Caption != "<b>Lamp</b>" || photo.ParseMode != "HTML" {
+		t.Errorf("Expected HTML caption, got %q (%s)", photo.Caption, photo.ParseMode)
+	}
+}
+
+func TestPromptComposer_LongCaptionSentSeparately(t *testing.T) {
+	mockClient := &mockTelegramClient{}
+	composer := createTestPromptComposer(mockClient, &mockTemplateManager{})
+	longText := strings.Repeat("a", maxCaptionLength+1)
+
+	err := composer.ComposeAndSend(createTestContext(), &PromptConfig{
+		Message: longText,
+		Image:   []byte("photo"),
+		Keyboard: func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("OK", "ok")
+		},
+	})
+	if err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+
+	if len(mockClient.sentMessages) != 2 {
+		t.Fatalf("Expected photo and text messages, got %d", len(mockClient.sentMessages))
+	}
+	if photo := mockClient.sentMessages[0].(tgbotapi.PhotoConfig); photo.Caption != "" || photo.ReplyMarkup != nil {
+		t.Errorf("Expected bare photo, got caption %d chars and markup %v", len(photo.Caption), photo.ReplyMarkup)
+	}
+	if msg := mockClient.sentMessages[1].(tgbotapi.MessageConfig); msg.Text != longText || msg.ReplyMarkup == nil {
+		t.Errorf("Expected text message with the keyboard")
+	}
+}
+
+func TestPromptComposer_ReplyKeyboardOverrides(t *testing.T) {
+	mainMenu := BuildReplyKeyboard([]string{"Home"}, 1)
+
+	t.Run("remove", func(t *testing.T) {
+		mockClient := &mockTelegramClient{}
+		composer := createTestPromptComposer(mockClient, &mockTemplateManager{})
+		ctx := createTestContext()
+		ctx.pendingReplyKeyboard = mainMenu
+
+		if err := composer.ComposeAndSend(ctx, &PromptConfig{Message: "Type your name", RemoveReplyKeyboard: true}); err != nil {
+			t.Fatalf("ComposeAndSend failed: %v", err)
+		}
+		msg := mockClient.sentMessages[0].(tgbotapi.MessageConfig)
+		if _, ok := msg.ReplyMarkup.(tgbotapi.ReplyKeyboardRemove); !ok {
+			t.Errorf("Expected keyboard removal, got %T", msg.ReplyMarkup)
+		}
+		if ctx.pendingReplyKeyboard != nil {
+			t.Error("Expected pending main menu to be consumed")
+		}
+	})
+
+	t.Run("one-time", func(t *testing.T) {
+		mockClient := &mockTelegramClient{}
+		composer := createTestPromptComposer(mockClient, &mockTemplateManager{})
+		ctx := createTestContext()
+		ctx.pendingReplyKeyboard = mainMenu
+		skip := BuildReplyKeyboard([]string{"Skip"}, 1)
+
+		if err := composer.ComposeAndSend(ctx, &PromptConfig{Message: "Phone?", OneTimeReplyKeyboard: skip}); err != nil {
+			t.Fatalf("ComposeAndSend failed: %v", err)
+		}
+		markup, ok := mockClient.sentMessages[0].(tgbotapi.MessageConfig).ReplyMarkup.(tgbotapi.ReplyKeyboardMarkup)
+		if !ok || !markup.OneTimeKeyboard || markup.Keyboard[0][0].Text != "Skip" {
+			t.Errorf("Expected one-time Skip keyboard, got %+v", markup)
+		}
+		if skip.OneTimeKeyboard {
+			t.Error("Expected the caller's keyboard to be left unchanged")
+		}
+	})
+
+	t.Run("inline keyboard wins", func(t *testing.T) {
+		mockClient := &mockTelegramClient{}
+		composer := createTestPromptComposer(mockClient, &mockTemplateManager{})
+
+		err := composer.ComposeAndSend(createTestContext(), &PromptConfig{
+			Message:             "Pick",
+			RemoveReplyKeyboard: true,
+			Keyboard: func(ctx *Context) *PromptKeyboardBuilder {
+				return NewPromptKeyboard().ButtonCallback("OK", "ok")
+			},
+		})
+		if err != nil {
+			t.Fatalf("ComposeAndSend failed: %v", err)
+		}
+		if _, ok := mockClient.sentMessages[0].(tgbotapi.MessageConfig).ReplyMarkup.(*tgbotapi.InlineKeyboardMarkup); !ok {
+			t.Errorf("Expected inline keyboard to be attached")
+		}
+	})
+}
+
+func TestCaptionLength(t *testing.T) {
+	tests := []struct {
+		text      string
+		parseMode ParseMode
+		want      int
+	}{
+		{"plain", ParseModeNone, 5},
+		{"<b>bold</b> &amp; more", ParseModeHTML, 11},
+		{"*bold* and \\_under\\_", ParseModeMarkdownV2, 16},
+		{"😀", ParseModeNone, 2},
+	}
+	for _, tt := range tests {
+		if got := captionLength(tt.text, tt.parseMode); got != tt.want {
+			t.Errorf("captionLength(%q, %q) = %d, want %d", tt.text, tt.parseMode, got, tt.want)
+		}
+	}
+}
+
+func TestPromptComposer_Entities(t *testing.T) {
+	client := NewMockTelegramClient()
+	composer := createTestPromptComposer(client, newTemplateManager())
+	user := &tgbotapi.User{ID: 42, FirstName: "Ann"}
+
+	err := composer.ComposeAndSend(createTestContext(), &PromptConfig{
+		Message:  "Hi Ann",
+		Entities: []MessageEntity{{Type: "text_mention", Offset: 3, Length: 3, User: user}},
+	})
+	if err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+	msg := client.SendCalls[0].(tgbotapi.MessageConfig)
+	if len(msg.Entities) != 1 || msg.Entities[0].User != user || msg.ParseMode != "" {
+		t.Errorf("Expected text mention entity without parse mode, got %+v", msg)
+	}
+
+	// Custom emoji entities are sent through the raw method
+	text := "Done ✅"
+	err = composer.ComposeAndSend(createTestContext(), &PromptConfig{
+		Message:  text,
+		Entities: []MessageEntity{{Type: "custom_emoji", Offset: UTF16Len("Done "), Length: UTF16Len("✅"), CustomEmojiID: "5368324170671202286"}},
+	})
+	if err != nil {
+		t.Fatalf("ComposeAndSend failed: %v", err)
+	}
+	if len(client.MakeRequestCalls) != 1 || client.MakeRequestCalls[0].Endpoint != "sendMessage" {
+		t.Fatalf("Expected raw sendMessage, got %+v", client.MakeRequestCalls)
+	}
+	params := client.MakeRequestCalls[0].Params
+	if want := `[{"type":"custom_emoji","offset":5,"length":1,"custom_emoji_id":"5368324170671202286"}]`; params["entities"] != want || params["text"] != text {
+		t.Errorf("Unexpected raw params %v", params)
+	}
+
+	// Entities describe plain text, so templates with a parse mode are rejected
+	tm := newTemplateManager()
+	_ = tm.AddTemplate("bold", "<b>x</b>", ParseModeHTML)
+	composer = createTestPromptComposer(client, tm)
+	err = composer.ComposeAndSend(createTestContext(), &PromptConfig{Message: "template:bold", Entities: []MessageEntity{{Type: "bold", Length: 1}}})
+	if !errors.Is(err, errEntitiesWithParseMode) {
+		t.Errorf("Expected errEntitiesWithParseMode, got %v", err)
+	}
+}
@@ -1,33 +1,127 @@
 package teleflow
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
+// maxImageSize is the largest image the bot uploads.
+const maxImageSize = 50 * 1024 * 1024
+
+// maxCachedFileIDs bounds the number of uploaded images whose file_id is remembered.
+const maxCachedFileIDs = 1024
+
+// ImageFileID refers to a photo already stored on Telegram's servers by its file_id.
+// Sending a file_id is instant and does not upload anything.
+//
+// Example:
+//
+//	step.Prompt("Our logo").WithImage(teleflow.ImageFileID("AgACAgIAAxkBAAIB..."))
+type ImageFileID string
+
 type processedImage struct {
 	data []byte
 
 	isBase64 bool
 
 	filePath string
+
+	fileID string // Telegram file_id to send instead of uploading data
+	hash   string // Content hash of data, used as the file_id cache key
 }
 
-type imageHandler struct{}
+type imageHandler struct {
+	mu      sync.Mutex
+	fileIDs map[string]string // file_id of uploaded images by content hash
+}
 
 func newImageHandler() *imageHandler {
-	return &imageHandler{}
+	return &imageHandler{fileIDs: make(map[string]string)}
+}
+
+// cachedFileID returns the file_id Telegram assigned when the image was first uploaded.
+func (ih *imageHandler) cachedFileID(hash string) (string, bool) {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+	fileID, ok := ih.fileIDs[hash]
+	return fileID, ok
+}
+
+// rememberFileID caches the file_id of an uploaded image so it is not uploaded again.
+func (ih *imageHandler) rememberFileID(hash, fileID string) {
+	if hash == "" || fileID == "" {
+		return
+	}
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+	if _, exists := ih.fileIDs[hash]; !exists && len(ih.fileIDs) >= maxCachedFileIDs {
+		for evict := range ih.fileIDs {
+			delete(ih.fileIDs, evict)
+			break
+		}
+	}
+	ih.fileIDs[hash] = fileID
+}
+
+// forgetFileID drops a cached file_id that Telegram no longer accepts.
+func (ih *imageHandler) forgetFileID(hash string) {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+	delete(ih.fileIDs, hash)
+}
+
+// withCachedFileID fills in the file_id of image data that was uploaded before.
+func (ih *imageHandler) withCachedFileID(img *processedImage) *processedImage {
+	if img == nil || len(img.data) == 0 {
+		return img
+	}
+	sum := sha256.Sum256(img.data)
+	img.hash = hex.EncodeToString(sum[:])
+	if fileID, ok := ih.cachedFileID(img.hash); ok {
+		img.fileID = fileID
+	}
+	return img
 }
 
 func (ih *imageHandler) processImage(imageSpec ImageSpec, ctx *Context) (*processedImage, error) {
+	img, err := ih.resolveImage(imageSpec, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ih.withCachedFileID(img), nil
+}
+
+func (ih *imageHandler) resolveImage(imageSpec ImageSpec, ctx *Context) (*processedImage, error) {
 	if imageSpec == nil {
 		return nil, nil
 	}
 
 	switch img := imageSpec.(type) {
+	case ImageFileID:
+		if img == "" {
+			return nil, nil
+		}
+		return &processedImage{fileID: string(img)}, nil
+
+	case io.Reader:
+
+		return ih.processReader(img)
+
+	case func(*Context) io.Reader:
+
+		reader := img(ctx)
+		if reader == nil {
+			return nil, nil
+		}
+		return ih.processReader(reader)
+
 	case string:
 
 		return ih.processStaticImage(img)
@@ -53,7 +147,7 @@ func (ih *imageHandler) processImage(imageSpec ImageSpec, ctx *Context) (*proces
 		return ih.processRawBytes(imageBytes)
 
 	default:
-		return nil, fmt.Errorf("unsupported image type: %T (expected string, []byte, func(*Context) string, or func(*Context) []byte)", img)
+		return nil, fmt.Errorf("unsupported image type: %T (expected string, []byte, io.Reader, ImageFileID, func(*Context) string, func(*Context) []byte, or func(*Context) io.Reader)", img)
 	}
 }
 
@@ -95,7 +189,7 @@ func (ih *imageHandler) processFileImage(filePath string) (*processedImage, erro
 		return nil, fmt.Errorf("image file not found: %s", filePath)
 	}
 
-	if info.Size() > 50*1024*1024 {
+	if info.Size() > maxImageSize {
 		return nil, fmt.Errorf("image file too large: %d bytes (max 50MB)", info.Size())
 	}
 
@@ -139,7 +233,7 @@ func (ih *imageHandler) processRawBytes(imageBytes []byte) (*processedImage, err
 		return nil, nil
 	}
 
-	if len(imageBytes) > 50*1024*1024 {
+	if len(imageBytes) > maxImageSize {
 		return nil, fmt.Errorf("image data too large: %d bytes (max 50MB)", len(imageBytes))
 	}
 
@@ -148,3 +242,15 @@ func (ih *imageHandler) processRawBytes(imageBytes []byte) (*processedImage, err
 		isBase64: false,
 	}, nil
 }
+
+func (ih *imageHandler) processReader(reader io.Reader) (*processedImage, error) {
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	return ih.processRawBytes(data)
+}
@@ -40,6 +40,21 @@ func (kb *PromptKeyboardBuilder) ButtonUrl(text string, url string) *PromptKeybo
 	return kb
 }
 
+// ButtonPay adds the button that pays an invoice. It is only valid in the
+// keyboard of an invoice sent with ctx.SendInvoice, where it must be the first
+// button; prompts with a pay button fail to send. The text may contain "⭐" or
+// "XTR", which Telegram replaces with the Stars icon.
+//
+// Example:
+//
+//	invoice.Keyboard = func(ctx *teleflow.Context) *teleflow.PromptKeyboardBuilder {
+//		return teleflow.NewPromptKeyboard().ButtonPay("Pay ⭐100").Row().ButtonCallback("Cancel", "cancel")
+//	}
+func (kb *PromptKeyboardBuilder) ButtonPay(text string) *PromptKeyboardBuilder {
+	kb.currentRow = append(kb.currentRow, tgbotapi.InlineKeyboardButton{Text: text, Pay: true})
+	return kb
+}
+
 func (kb *PromptKeyboardBuilder) Row() *PromptKeyboardBuilder {
 	if len(kb.currentRow) > 0 {
 		kb.rows = append(kb.rows, kb.currentRow)
@@ -48,6 +63,63 @@ func (kb *PromptKeyboardBuilder) Row() *PromptKeyboardBuilder {
 	return kb
 }
 
+// URLButton describes an inline button that opens a link.
+type URLButton struct {
+	Text string
+	URL  string
+}
+
+// ButtonURLRow adds the given link buttons as a row of their own.
+//
+// Example:
+//
+//	kb.ButtonURLRow(
+//		teleflow.URLButton{Text: "Docs", URL: "https://example.com/docs"},
+//		teleflow.URLButton{Text: "Support", URL: "https://example.com/support"},
+//	)
+func (kb *PromptKeyboardBuilder) ButtonURLRow(buttons ...URLButton) *PromptKeyboardBuilder {
+	kb.Row()
+	for _, button := range buttons {
+		kb.ButtonUrl(button.Text, button.URL)
+	}
+	return kb.Row()
+}
+
+// ButtonsFromSlice adds a callback button for every item, perRow buttons per row.
+// The buttons start on a new row, and buttons added afterwards start on a new row
+// too. A perRow of zero or less puts all buttons on one row.
+//
+// Example:
+//
+//	teleflow.ButtonsFromSlice(teleflow.NewPromptKeyboard(), accounts, 2,
+//		func(a Account) string { return a.Name },
+//		func(a Account) interface{} { return a.ID },
+//	).ButtonCallback("❌ Cancel", "cancel")
+func ButtonsFromSlice[T any](kb *PromptKeyboardBuilder, items []T, perRow int, label func(T) string, data func(T) interface{}) *PromptKeyboardBuilder {
+	return addChunkedButtons(kb, items, perRow, func(item T) {
+		kb.ButtonCallback(label(item), data(item))
+	})
+}
+
+// ButtonURLsFromSlice adds a link button for every item, perRow buttons per row.
+// Rows are arranged as in ButtonsFromSlice.
+func ButtonURLsFromSlice[T any](kb *PromptKeyboardBuilder, items []T, perRow int, label func(T) string, url func(T) string) *PromptKeyboardBuilder {
+	return addChunkedButtons(kb, items, perRow, func(item T) {
+		kb.ButtonUrl(label(item), url(item))
+	})
+}
+
+func addChunkedButtons[T any](kb *PromptKeyboardBuilder, items []T, perRow int, add func(T)) *PromptKeyboardBuilder {
+	kb.Row()
+	for i, item := range items {
+		if perRow > 0 && i > 0 && i%perRow == 0 {
+			kb.Row()
+		}
+		add(item)
+	}
+	return kb.Row()
+}
+
 func (kb *PromptKeyboardBuilder) Build() tgbotapi.InlineKeyboardMarkup {
 
 	if len(kb.currentRow) > 0 {
@@ -69,3 +141,39 @@ func (kb *PromptKeyboardBuilder) validateBuilder() error {
 
 	return nil
 }
+
+// payButtonIndexes returns the positions of pay buttons, counting buttons row by
+// row.
+func (kb *PromptKeyboardBuilder) payButtonIndexes() []int {
+	var indexes []int
+	i := 0
+	scan := func(row []tgbotapi.InlineKeyboardButton) {
+		for _, button := range row {
+			if button.Pay {
+				indexes = append(indexes, i)
+			}
+			i++
+		}
+	}
+	for _, row := range kb.rows {
+		scan(row)
+	}
+	scan(kb.currentRow)
+	return indexes
+}
+
+// validateInvoiceKeyboard checks that the first button is the keyboard's only
+// pay button, as Telegram requires for invoices.
+func (kb *PromptKeyboardBuilder) validateInvoiceKeyboard() error {
+	if err := kb.validateBuilder(); err != nil {
+		return err
+	}
+	pay := kb.payButtonIndexes()
+	if len(pay) == 0 || pay[0] != 0 {
+		return fmt.Errorf("the first button of an invoice keyboard must be a pay button")
+	}
+	if len(pay) > 1 {
+		return fmt.Errorf("an invoice keyboard can only have one pay button")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package teleflow
+
+import (
+	"fmt"
+	"testing"
+)
+
+func rowSizes(kb *PromptKeyboardBuilder) []int {
+	var sizes []int
+	for _, row := range kb.Build().InlineKeyboard {
+		sizes = append(sizes, len(row))
+	}
+	return sizes
+}
+
+func TestButtonsFromSlice_ChunksRows(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	kb := ButtonsFromSlice(NewPromptKeyboard().ButtonCallback("Top", "top"), items, 2,
+		func(i int) string { return fmt.Sprintf("Item %d", i) },
+		func(i int) interface{} { return i },
+	).ButtonCallback("Cancel", "cancel")
+
+	if got := fmt.Sprint(rowSizes(kb)); got != "[1 2 2 1 1]" {
+		t.Errorf("Expected rows [1 2 2 1 1], got %s", got)
+	}
+
+	markup := kb.Build()
+	button := markup.InlineKeyboard[2][1]
+	if button.Text != "Item 4" || kb.uuidMapping[*button.CallbackData] != 4 {
+		t.Errorf("Expected button for item 4, got %q -> %v", button.Text, kb.uuidMapping[*button.CallbackData])
+	}
+}
+
+func TestButtonsFromSlice_SingleRow(t *testing.T) {
+	kb := ButtonsFromSlice(NewPromptKeyboard(), []string{"a", "b", "c"}, 0,
+		func(s string) string { return s },
+		func(s string) interface{} { return s },
+	)
+	if got := fmt.Sprint(rowSizes(kb)); got != "[3]" {
+		t.Errorf("Expected a single row of 3, got %s", got)
+	}
+}
+
+func TestButtonURLRow(t *testing.T) {
+	kb := NewPromptKeyboard().
+		ButtonCallback("A", "a").
+		ButtonURLRow(URLButton{Text: "Docs", URL: "https://example.com/docs"}, URLButton{Text: "Help", URL: "https://example.com/help"}).
+		ButtonCallback("B", "b")
+
+	if got := fmt.Sprint(rowSizes(kb)); got != "[1 2 1]" {
+		t.Errorf("Expected rows [1 2 1], got %s", got)
+	}
+	if url := kb.Build().InlineKeyboard[1][0].URL; url == nil || *url != "https://example.com/docs" {
+		t.Errorf("Expected docs URL, got %v", url)
+	}
+}
+
+func TestButtonURLsFromSlice(t *testing.T) {
+	links := []URLButton{{"One", "https://1.example"}, {"Two", "https://2.example"}, {"Three", "https://3.example"}}
+	kb := ButtonURLsFromSlice(NewPromptKeyboard(), links, 2,
+		func(l URLButton) string { return l.Text },
+		func(l URLButton) string { return l.URL },
+	)
+	if got := fmt.Sprint(rowSizes(kb)); got != "[2 1]" {
+		t.Errorf("Expected rows [2 1], got %s", got)
+	}
+}
+
+func TestButtonPay_Placement(t *testing.T) {
+	cases := []struct {
+		name  string
+		kb    *PromptKeyboardBuilder
+		valid bool
+	}{
+		{"pay first", NewPromptKeyboard().ButtonPay("Pay").Row().ButtonCallback("Cancel", "cancel"), true},
+		{"pay alone", NewPromptKeyboard().ButtonPay("Pay ⭐100"), true},
+		{"pay second", NewPromptKeyboard().ButtonCallback("Cancel", "cancel").ButtonPay("Pay"), false},
+		{"two pay buttons", NewPromptKeyboard().ButtonPay("Pay").ButtonPay("Pay again"), false},
+		{"no pay button", NewPromptKeyboard().ButtonCallback("Cancel", "cancel"), false},
+	}
+	for _, c := range cases {
+		if err := c.kb.validateInvoiceKeyboard(); (err == nil) != c.valid {
+			t.Errorf("%s: expected valid=%v, got %v", c.name, c.valid, err)
+		}
+	}
+
+	handler := newPromptKeyboardHandler()
+	_, err := handler.BuildKeyboard(createTestContext(), func(*Context) *PromptKeyboardBuilder {
+		return NewPromptKeyboard().ButtonPay("Pay")
+	})
+	if err == nil {
+		t.Error("Expected prompts with a pay button to be rejected")
+	}
+}
@@ -12,24 +12,27 @@ type PromptKeyboardActions interface {
 	// It registers callback UUIDs and their associated data for the user.
 	BuildKeyboard(ctx *Context, keyboardFunc KeyboardFunc) (interface{}, error)
 
-	// GetCallbackData retrieves the data associated with a specific callback UUID for a user.
+	// GetCallbackData retrieves the data associated with a specific callback UUID.
+	// Mappings belong to the flow identified by userID and chatID: chatID is 0 for
+	// flows that follow a user across chats, userID is 0 for flows shared by a chat.
 	// It returns the data and a boolean indicating if the UUID was found.
-	GetCallbackData(userID int64, uuid string) (interface{}, bool)
+	GetCallbackData(userID, chatID int64, uuid string) (interface{}, bool)
 
-	// CleanupUserMappings removes all callback UUID mappings for a given user.
+	// CleanupUserMappings removes all callback UUID mappings of the flow identified
+	// by userID and chatID, leaving the user's flows in other chats untouched.
 	// This is typically called when a user's session or flow ends.
-	CleanupUserMappings(userID int64)
+	CleanupUserMappings(userID, chatID int64)
 }
 
 type PromptKeyboardHandler struct {
-	userUUIDMappings map[int64]map[string]interface{}
+	uuidMappings map[flowKey]map[string]interface{}
 
 	mu sync.RWMutex
 }
 
 func newPromptKeyboardHandler() *PromptKeyboardHandler {
 	return &PromptKeyboardHandler{
-		userUUIDMappings: make(map[int64]map[string]interface{}),
+		uuidMappings: make(map[flowKey]map[string]interface{}),
 	}
 }
 
@@ -46,40 +49,58 @@ func (pkh *PromptKeyboardHandler) BuildKeyboard(ctx *Context, keyboardFunc Keybo
 	if err := builder.validateBuilder(); err != nil {
 		return nil, fmt.Errorf("invalid inline keyboard: %w", err)
 	}
+	if len(builder.payButtonIndexes()) > 0 {
+		return nil, fmt.Errorf("invalid inline keyboard: pay buttons can only be used on invoices")
+	}
+
+	return pkh.register(ctx, builder), nil
+}
 
+// buildInvoiceKeyboard builds the keyboard of an invoice, whose first button
+// must be a pay button.
+func (pkh *PromptKeyboardHandler) buildInvoiceKeyboard(ctx *Context, builder *PromptKeyboardBuilder) (interface{}, error) {
+	if err := builder.validateInvoiceKeyboard(); err != nil {
+		return nil, fmt.Errorf("invalid invoice keyboard: %w", err)
+	}
+	return pkh.register(ctx, builder), nil
+}
+
+// register stores the builder's callback mappings for the user and returns the
+// built keyboard, or nil if it has no buttons.
+func (pkh *PromptKeyboardHandler) register(ctx *Context, builder *PromptKeyboardBuilder) interface{} {
 	pkh.mu.Lock()
 	defer pkh.mu.Unlock()
 
-	userID := ctx.UserID()
-	if pkh.userUUIDMappings[userID] == nil {
-		pkh.userUUIDMappings[userID] = make(map[string]interface{})
+	owner := ctx.callbackOwner()
+	if pkh.uuidMappings[owner] == nil {
+		pkh.uuidMappings[owner] = make(map[string]interface{})
 	}
 
 	for uuid, data := range builder.uuidMapping {
-		pkh.userUUIDMappings[userID][uuid] = data
+		pkh.uuidMappings[owner][uuid] = data
 	}
 
 	builtKeyboard := builder.Build()
 	if numButtons(builtKeyboard) == 0 {
-		return nil, nil
+		return nil
 	}
 
-	return builtKeyboard, nil
+	return builtKeyboard
 }
 
-func (pkh *PromptKeyboardHandler) GetCallbackData(userID int64, uuid string) (interface{}, bool) {
+func (pkh *PromptKeyboardHandler) GetCallbackData(userID, chatID int64, uuid string) (interface{}, bool) {
 	pkh.mu.RLock()
 	defer pkh.mu.RUnlock()
 
-	if userMappings, exists := pkh.userUUIDMappings[userID]; exists {
+	if userMappings, exists := pkh.uuidMappings[flowKey{UserID: userID, ChatID: chatID}]; exists {
 		data, found := userMappings[uuid]
 		return data, found
 	}
 	return nil, false
 }
 
-func (pkh *PromptKeyboardHandler) CleanupUserMappings(userID int64) {
+func (pkh *PromptKeyboardHandler) CleanupUserMappings(userID, chatID int64) {
 	pkh.mu.Lock()
 	defer pkh.mu.Unlock()
-	delete(pkh.userUUIDMappings, userID)
+	delete(pkh.uuidMappings, flowKey{UserID: userID, ChatID: chatID})
 }
@@ -6,6 +6,7 @@ import (
 
 type messageHandler struct {
 	templateManager TemplateManager
+	onExposure      ExposureFunc // Reports experiment variants shown to users (nil if disabled)
 }
 
 func newMessageHandler(tm TemplateManager) *messageHandler {
@@ -22,30 +23,36 @@ func (mr *messageHandler) renderMessage(config *PromptConfig, ctx *Context) (str
 	switch msg := config.Message.(type) {
 	case string:
 
-		return mr.handleStringMessage(msg, config)
+		return mr.handleStringMessage(msg, config, ctx)
 
 	case func(*Context) string:
 
 		result := msg(ctx)
-		return mr.handleStringMessage(result, config)
+		return mr.handleStringMessage(result, config, ctx)
+
+	case *TextBuilder:
+		return msg.String(), msg.ParseMode(), nil
+
+	case *Experiment:
+		return mr.renderExperiment(msg, config, ctx)
 
 	default:
-		return "", ParseModeNone, fmt.Errorf("unsupported message type: %T (expected string or func(*Context) string)", msg)
+		return "", ParseModeNone, fmt.Errorf("unsupported message type: %T (expected string, func(*Context) string, *TextBuilder or *Experiment)", msg)
 	}
 }
 
-func (mr *messageHandler) handleStringMessage(message string, config *PromptConfig) (string, ParseMode, error) {
+func (mr *messageHandler) handleStringMessage(message string, config *PromptConfig, ctx *Context) (string, ParseMode, error) {
 
 	isTemplate, templateName := isTemplateMessage(message)
 	if isTemplate {
 
-		return mr.renderTemplateMessage(templateName, config)
+		return mr.renderTemplateMessage(templateName, config, ctx)
 	}
 
 	return message, ParseModeNone, nil
 }
 
-func (mr *messageHandler) renderTemplateMessage(templateName string, config *PromptConfig) (string, ParseMode, error) {
+func (mr *messageHandler) renderTemplateMessage(templateName string, config *PromptConfig, ctx *Context) (string, ParseMode, error) {
 
 	if !mr.templateManager.HasTemplate(templateName) {
 		return "", ParseModeNone, fmt.Errorf("template '%s' not found", templateName)
@@ -56,6 +63,7 @@ func (mr *messageHandler) renderTemplateMessage(templateName string, config *Pro
 	if templateData == nil {
 		templateData = make(map[string]interface{})
 	}
+	templateData = withBuiltinTemplateData(templateData, ctx)
 
 	renderedText, parseMode, err := mr.templateManager.RenderTemplate(templateName, templateData)
 	if err != nil {
@@ -289,7 +289,7 @@ func TestMessageHandler_HandleStringMessage(t *testing.T) {
 
 			handler := newMessageHandler(mockTM)
 
-			text, mode, err := handler.handleStringMessage(tt.message, tt.config)
+			text, mode, err := handler.handleStringMessage(tt.message, tt.config, nil)
 
 			if tt.expectedError {
 				if err == nil {
@@ -389,7 +389,7 @@ func TestMessageHandler_RenderTemplateMessage(t *testing.T) {
 
 			handler := newMessageHandler(mockTM)
 
-			text, mode, err := handler.renderTemplateMessage(tt.templateName, tt.config)
+			text, mode, err := handler.renderTemplateMessage(tt.templateName, tt.config, nil)
 
 			if tt.expectedError {
 				if err == nil {
@@ -435,7 +435,7 @@ func TestMessageHandler_TemplateDataExplicitOnly(t *testing.T) {
 		},
 	}
 
-	_, _, err := handler.renderTemplateMessage("test", config)
+	_, _, err := handler.renderTemplateMessage("test", config, nil)
 	if err != nil {
 		t.Fatalf("renderTemplateMessage failed: %v", err)
 	}
@@ -477,7 +477,7 @@ func TestTemplateExplicitDataBehavior(t *testing.T) {
 			TemplateData: nil, // No explicit template data
 		}
 
-		renderedText, _, err := handler.renderTemplateMessage("test_template", config)
+		renderedText, _, err := handler.renderTemplateMessage("test_template", config, nil)
 		if err != nil {
 			t.Fatalf("renderTemplateMessage failed: %v", err)
 		}
@@ -503,7 +503,7 @@ func TestTemplateExplicitDataBehavior(t *testing.T) {
 				"flow_var":     "override_flow_value", // This should override any potential flow data
 			},
 		}
-		renderedText, _, err := handler.renderTemplateMessage("test_template", config)
+		renderedText, _, err := handler.renderTemplateMessage("test_template", config, nil)
 		if err != nil {
 			t.Fatalf("renderTemplateMessage failed: %v", err)
 		}
@@ -529,3 +529,60 @@ func TestTemplateExplicitDataBehavior(t *testing.T) {
 		}
 	})
 }
+
+func TestTemplateFlowProgress(t *testing.T) {
+	tm := newTemplateManager()
+	if err := tm.AddTemplate("progress", "{{.FlowProgress}} ({{.FlowProgress.Current}}/{{.FlowProgress.Total}})", ParseModeNone); err != nil {
+		t.Fatalf("Failed to add template: %v", err)
+	}
+	handler := newMessageHandler(tm)
+	config := &PromptConfig{Message: "template:progress"}
+
+	ctx := &Context{flowPrompt: &flowPrompt{flow: createTestFlow(), step: "step2"}}
+	text, _, err := handler.renderMessage(config, ctx)
+	if err != nil {
+		t.Fatalf("renderMessage failed: %v", err)
+	}
+	if text != "Step 2 of 2 (2/2)" {
+		t.Errorf("Expected progress in template, got %q", text)
+	}
+	if config.TemplateData != nil {
+		t.Error("Expected prompt's template data to be left untouched")
+	}
+
+	progress, ok := ctx.FlowProgress()
+	if !ok || progress.Current != 2 || progress.Step != "step2" {
+		t.Errorf("Expected ctx.FlowProgress to report step2, got %+v", progress)
+	}
+}
+
+func TestTemplateBuiltinData(t *testing.T) {
+	tm := newTemplateManager()
+	text := "{{.User.FirstName}} in {{.Chat.ID}}: {{.Flow.Name}}/{{.Flow.Step}} amount={{.Flow.Data.amount}} note={{.note}}"
+	if err := tm.AddTemplate("summary", text, ParseModeNone); err != nil {
+		t.Fatalf("Failed to add template: %v", err)
+	}
+	handler := newMessageHandler(tm)
+
+	ctx := createFlowTestContext(7, "", nil)
+	ctx.update.Message.From.FirstName = "Ann"
+	ctx.flowPrompt = &flowPrompt{flow: createTestFlow(), step: "step2", data: map[string]interface{}{"amount": 50}}
+
+	config := &PromptConfig{Message: "template:summary", TemplateData: map[string]interface{}{"note": "hi"}}
+	got, _, err := handler.renderMessage(config, ctx)
+	if err != nil {
+		t.Fatalf("renderMessage failed: %v", err)
+	}
+	if want := "Ann in 7: test-flow/step2 amount=50 note=hi"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if _, exists := config.TemplateData["Flow"]; exists {
+		t.Error("Expected prompt's template data not to be modified")
+	}
+
+	// Explicit template data takes precedence over the reserved keys
+	config.TemplateData["User"] = map[string]string{"FirstName": "Override"}
+	if got, _, _ := handler.renderMessage(config, ctx); !strings.HasPrefix(got, "Override in 7") {
+		t.Errorf("Expected explicit .User to win, got %q", got)
+	}
+}
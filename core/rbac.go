@@ -0,0 +1,281 @@
+package teleflow
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// RoleStore persists role assignments for RBACAccessManager. Implement it to keep
+// roles in a database; MemoryRoleStore is provided for tests and small bots.
+type RoleStore interface {
+	// GetRoles returns the names of the roles assigned to a user.
+	GetRoles(userID int64) ([]string, error)
+	// AssignRole gives a role to a user. Assigning a role twice is not an error.
+	AssignRole(userID int64, role string) error
+	// RevokeRole removes a role from a user. Revoking a missing role is not an error.
+	RevokeRole(userID int64, role string) error
+}
+
+// MemoryRoleStore is an in-memory RoleStore safe for concurrent use.
+type MemoryRoleStore struct {
+	mu    sync.RWMutex
+	roles map[int64][]string
+}
+
+// NewMemoryRoleStore creates an empty in-memory role store.
+func NewMemoryRoleStore() *MemoryRoleStore {
+	return &MemoryRoleStore{roles: make(map[int64][]string)}
+}
+
+// GetRoles returns the roles assigned to a user.
+func (s *MemoryRoleStore) GetRoles(userID int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.roles[userID]...), nil
+}
+
+// AssignRole gives a role to a user.
+func (s *MemoryRoleStore) AssignRole(userID int64, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !containsString(s.roles[userID], role) {
+		s.roles[userID] = append(s.roles[userID], role)
+	}
+	return nil
+}
+
+// RevokeRole removes a role from a user.
+func (s *MemoryRoleStore) RevokeRole(userID int64, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.roles[userID][:0]
+	for _, r := range s.roles[userID] {
+		if r != role {
+			kept = append(kept, r)
+		}
+	}
+	s.roles[userID] = kept
+	return nil
+}
+
+// rbacRole is a role definition with its permissions and optional menu keyboard.
+type rbacRole struct {
+	name        string
+	permissions []string
+	keyboard    *ReplyKeyboard
+}
+
+// RBACAccessManager is a role-based AccessManager. Roles grant permissions,
+// commands and flows require permissions, and users are assigned roles through
+// a RoleStore. Permissions are free-form strings; a trailing "*" grants every
+// permission with that prefix (e.g. "transfer:*"), and "*" grants everything.
+//
+// Commands and flows without a requirement are open to everyone. The reply
+// keyboard of a user is the keyboard of the first role (in definition order)
+// they hold that has one.
+//
+// Example:
+//
+//	rbac := teleflow.NewRBACAccessManager(teleflow.NewMemoryRoleStore()).
+//		DefineRole("user", "profile:*").
+//		DefineRole("admin", "*").
+//		DefaultRole("user").
+//		RequireForCommand("ban", "users:ban").
+//		RequireForFlow("transfer", "transfer:execute")
+//	rbac.AssignRole(adminID, "admin")
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithAccessManager(rbac))
+type RBACAccessManager struct {
+	store RoleStore
+
+	mu           sync.RWMutex
+	roles        map[string]*rbacRole
+	roleOrder    []string
+	defaultRoles []string
+	commands     map[string]string // Command name to required permission
+	flows        map[string]string // Flow name to required permission
+	deniedText   string
+}
+
+// NewRBACAccessManager creates a role-based AccessManager backed by store.
+func NewRBACAccessManager(store RoleStore) *RBACAccessManager {
+	return &RBACAccessManager{
+		store:      store,
+		roles:      make(map[string]*rbacRole),
+		commands:   make(map[string]string),
+		flows:      make(map[string]string),
+		deniedText: "you don't have permission to do that",
+	}
+}
+
+// DefineRole declares a role and the permissions it grants. Defining a role
+// again replaces its permissions.
+func (m *RBACAccessManager) DefineRole(name string, permissions ...string) *RBACAccessManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	role, exists := m.roles[name]
+	if !exists {
+		role = &rbacRole{name: name}
+		m.roles[name] = role
+		m.roleOrder = append(m.roleOrder, name)
+	}
+	role.permissions = permissions
+	return m
+}
+
+// RoleKeyboard sets the reply keyboard shown to users holding role.
+func (m *RBACAccessManager) RoleKeyboard(role string, keyboard *ReplyKeyboard) *RBACAccessManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.roles[role]; ok {
+		r.keyboard = keyboard
+	}
+	return m
+}
+
+// DefaultRole grants a role to every user in addition to their assigned roles.
+func (m *RBACAccessManager) DefaultRole(role string) *RBACAccessManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaultRoles = append(m.defaultRoles, role)
+	return m
+}
+
+// RequireForCommand restricts a command (without the leading "/") to users holding permission.
+func (m *RBACAccessManager) RequireForCommand(command, permission string) *RBACAccessManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands[strings.TrimPrefix(command, "/")] = permission
+	return m
+}
+
+// RequireForFlow restricts starting a flow to users holding permission.
+func (m *RBACAccessManager) RequireForFlow(flowName, permission string) *RBACAccessManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flows[flowName] = permission
+	return m
+}
+
+// DeniedMessage sets the text of the error returned when access is denied.
+func (m *RBACAccessManager) DeniedMessage(text string) *RBACAccessManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deniedText = text
+	return m
+}
+
+// AssignRole gives a defined role to a user.
+func (m *RBACAccessManager) AssignRole(userID int64, role string) error {
+	m.mu.RLock()
+	_, exists := m.roles[role]
+	m.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("role %q is not defined", role)
+	}
+	return m.store.AssignRole(userID, role)
+}
+
+// RevokeRole removes a role from a user.
+func (m *RBACAccessManager) RevokeRole(userID int64, role string) error {
+	return m.store.RevokeRole(userID, role)
+}
+
+// RolesOf returns the roles a user holds, including default roles.
+func (m *RBACAccessManager) RolesOf(userID int64) ([]string, error) {
+	assigned, err := m.store.GetRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	roles := append([]string(nil), m.defaultRoles...)
+	for _, role := range assigned {
+		if !containsString(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// HasPermission reports whether any of the user's roles grants permission.
+func (m *RBACAccessManager) HasPermission(userID int64, permission string) (bool, error) {
+	roles, err := m.RolesOf(userID)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range roles {
+		role, ok := m.roles[name]
+		if !ok {
+			continue
+		}
+		for _, granted := range role.permissions {
+			if permissionMatches(granted, permission) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// CheckPermission implements AccessManager. It checks the permission declared by
+// a flow or step, or else the one required for the command or flow in ctx, and
+// allows everything else.
+func (m *RBACAccessManager) CheckPermission(ctx *PermissionContext) error {
+	m.mu.RLock()
+	required := ctx.Permission
+	if required == "" && ctx.FlowName != "" && ctx.StepName == "" {
+		required = m.flows[ctx.FlowName]
+	} else if required == "" && ctx.FlowName == "" {
+		required = m.commands[ctx.Command]
+	}
+	deniedText := m.deniedText
+	m.mu.RUnlock()
+
+	if required == "" {
+		return nil
+	}
+
+	allowed, err := m.HasPermission(ctx.UserID, required)
+	if err != nil {
+		return fmt.Errorf("failed to load roles: %w", err)
+	}
+	if !allowed {
+		return fmt.Errorf("%s", deniedText)
+	}
+	return nil
+}
+
+// GetReplyKeyboard implements AccessManager by returning the keyboard of the
+// user's first role that has one.
+func (m *RBACAccessManager) GetReplyKeyboard(ctx *PermissionContext) *ReplyKeyboard {
+	roles, err := m.RolesOf(ctx.UserID)
+	if err != nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range m.roleOrder {
+		if role := m.roles[name]; role.keyboard != nil && containsString(roles, name) {
+			return role.keyboard
+		}
+	}
+	return nil
+}
+
+// permissionMatches reports whether a granted permission covers the required one.
+func permissionMatches(granted, required string) bool {
+	if granted == required || granted == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(granted, "*"); ok {
+		return strings.HasPrefix(required, prefix)
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func createTestRBAC() *RBACAccessManager {
+	return NewRBACAccessManager(NewMemoryRoleStore()).
+		DefineRole("user", "profile:*").
+		DefineRole("operator", "transfer:execute", "users:list").
+		DefineRole("admin", "*").
+		DefaultRole("user").
+		RequireForCommand("/ban", "users:ban").
+		RequireForCommand("users", "users:list").
+		RequireForFlow("transfer", "transfer:execute")
+}
+
+func TestRBACAccessManager_Permissions(t *testing.T) {
+	rbac := createTestRBAC()
+	if err := rbac.AssignRole(2, "operator"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if err := rbac.AssignRole(3, "admin"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *PermissionContext
+		allowed bool
+	}{
+		{"open command", &PermissionContext{UserID: 1, Command: "start"}, true},
+		{"user denied command", &PermissionContext{UserID: 1, Command: "ban"}, false},
+		{"operator allowed command", &PermissionContext{UserID: 2, Command: "users"}, true},
+		{"operator denied command", &PermissionContext{UserID: 2, Command: "ban"}, false},
+		{"admin wildcard", &PermissionContext{UserID: 3, Command: "ban"}, true},
+		{"user denied flow", &PermissionContext{UserID: 1, FlowName: "transfer"}, false},
+		{"operator allowed flow", &PermissionContext{UserID: 2, FlowName: "transfer"}, true},
+		{"open flow", &PermissionContext{UserID: 1, FlowName: "registration"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rbac.CheckPermission(tt.ctx)
+			if (err == nil) != tt.allowed {
+				t.Errorf("Expected allowed=%v, got error %v", tt.allowed, err)
+			}
+		})
+	}
+
+	if ok, _ := rbac.HasPermission(1, "profile:edit"); !ok {
+		t.Error("Expected default role prefix permission to match")
+	}
+}
+
+func TestRBACAccessManager_RoleAssignment(t *testing.T) {
+	rbac := createTestRBAC()
+
+	if err := rbac.AssignRole(1, "missing"); err == nil {
+		t.Error("Expected error when assigning an undefined role")
+	}
+
+	_ = rbac.AssignRole(1, "admin")
+	if err := rbac.CheckPermission(&PermissionContext{UserID: 1, Command: "ban"}); err != nil {
+		t.Errorf("Expected admin to be allowed, got %v", err)
+	}
+
+	_ = rbac.RevokeRole(1, "admin")
+	if err := rbac.CheckPermission(&PermissionContext{UserID: 1, Command: "ban"}); err == nil {
+		t.Error("Expected access to be denied after revoking the role")
+	}
+
+	roles, _ := rbac.RolesOf(1)
+	if len(roles) != 1 || roles[0] != "user" {
+		t.Errorf("Expected only the default role, got %v", roles)
+	}
+}
+
+func TestRBACAccessManager_RoleKeyboard(t *testing.T) {
+	userKeyboard := NewReplyKeyboard().AddButton("Profile").Build()
+	adminKeyboard := NewReplyKeyboard().AddButton("Admin").Build()
+	rbac := createTestRBAC().
+		RoleKeyboard("admin", adminKeyboard).
+		RoleKeyboard("user", userKeyboard)
+	_ = rbac.AssignRole(3, "admin")
+
+	if kb := rbac.GetReplyKeyboard(&PermissionContext{UserID: 1}); kb != userKeyboard {
+		t.Error("Expected user keyboard for a regular user")
+	}
+	// "user" is defined before "admin", so it wins for users holding both
+	if kb := rbac.GetReplyKeyboard(&PermissionContext{UserID: 3}); kb != userKeyboard {
+		t.Error("Expected the first defined role's keyboard")
+	}
+}
+
+func TestContext_StartFlowChecksPermission(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	bot.RegisterFlow(createTestFlow())
+
+	rbac := createTestRBAC().RequireForFlow("test-flow", "transfer:execute")
+	ctx := newContext(createStartUpdate(""), bot.api, bot.templateManager, bot.flowManager, bot.promptComposer, rbac)
+	if err := ctx.StartFlow("test-flow"); !errors.Is(err, ErrFlowPermissionDenied) {
+		t.Fatalf("Expected ErrFlowPermissionDenied, got %v", err)
+	}
+	if bot.flowManager.isUserInFlow(42, 42) {
+		t.Error("User should not be in flow after denial")
+	}
+
+	_ = rbac.AssignRole(42, "operator")
+	if err := ctx.StartFlow("test-flow"); err != nil {
+		t.Fatalf("Expected StartFlow to succeed, got %v", err)
+	}
+}
+
+func TestFlowPermissions_FlowAndStepLevel(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	flow, err := NewFlow("transfer").
+		RequirePermission("transfer:start").
+		OnPermissionDenied("No access").
+		Step("amount").
+		Prompt("How much?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return NextStep() }).
+		Step("confirm").
+		Prompt("Confirm?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return CompleteFlow() }).
+		RequirePermission("transfer:execute").
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	rbac := createTestRBAC().DefineRole("starter", "transfer:start")
+	bot.accessManager = rbac
+	newCtx := func(text string) *Context {
+		return newContext(createCaptchaAnswerUpdate(42, 42, text), bot.api, bot.templateManager, bot.flowManager, bot.promptComposer, rbac)
+	}
+	lastText := func() string {
+		return mockClient.SendCalls[len(mockClient.SendCalls)-1].(tgbotapi.MessageConfig).Text
+	}
+
+	// Flow-level denial
+	if err := newCtx("").StartFlow("transfer"); !errors.Is(err, ErrFlowPermissionDenied) {
+		t.Fatalf("Expected ErrFlowPermissionDenied, got %v", err)
+	}
+	if lastText() != "No access" {
+		t.Errorf("Expected denial prompt, got %q", lastText())
+	}
+
+	// Flow allowed, but the confirm step is not
+	_ = rbac.AssignRole(42, "starter")
+	if err := newCtx("").StartFlow("transfer"); err != nil {
+		t.Fatalf("Expected flow to start, got %v", err)
+	}
+	if _, err := bot.flowManager.HandleUpdate(newCtx("100")); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	if bot.flowManager.isUserInFlow(42, 42) {
+		t.Error("Expected flow to be cancelled when entering a denied step")
+	}
+	if lastText() != "No access" {
+		t.Errorf("Expected denial prompt for step, got %q", lastText())
+	}
+}
@@ -0,0 +1,148 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ReactionType is a reaction on a message: an emoji, or a custom emoji for
+// Telegram Premium users.
+type ReactionType struct {
+	Type          string `json:"type"` // "emoji", "custom_emoji" or "paid"
+	Emoji         string `json:"emoji,omitempty"`
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"`
+}
+
+// MessageReactionUpdated is the message_reaction update Telegram sends when a
+// user changes their reactions to a message. The telegram-bot-api library does
+// not decode it, so it reaches teleflow through webhooks,
+// ProcessExternalUpdateJSON or ProcessReaction. Long polling with Start does not
+// deliver reactions. Bots must request the update in allowed_updates.
+type MessageReactionUpdated struct {
+	Chat        tgbotapi.Chat  `json:"chat"`
+	MessageID   int            `json:"message_id"`
+	User        *tgbotapi.User `json:"user,omitempty"`
+	Date        int            `json:"date"`
+	OldReaction []ReactionType `json:"old_reaction"`
+	NewReaction []ReactionType `json:"new_reaction"`
+}
+
+// ReactionInput is a reaction a flow step accepted as its answer. The step's
+// ProcessFunc receives the emoji as input and the reaction from ctx.Reaction().
+type ReactionInput struct {
+	Emoji         string // Emoji the user added, empty for custom emoji
+	CustomEmojiID string // ID of the custom emoji the user added
+	MessageID     int    // Prompt message the user reacted to
+	UserID        int64  // ID of the user who reacted
+	ChatID        int64  // ID of the chat of the message
+}
+
+// addedReaction returns the reaction the user added in the update, or nil when
+// reactions were only removed.
+func (r MessageReactionUpdated) addedReaction() *ReactionInput {
+	for _, reaction := range r.NewReaction {
+		if slices.Contains(r.OldReaction, reaction) {
+			continue
+		}
+		input := &ReactionInput{Emoji: reaction.Emoji, CustomEmojiID: reaction.CustomEmojiID, MessageID: r.MessageID, ChatID: r.Chat.ID}
+		if r.User != nil {
+			input.UserID = r.User.ID
+		}
+		return input
+	}
+	return nil
+}
+
+// acceptsReaction reports whether the step takes the reaction as its answer.
+func (s *flowStep) acceptsReaction(reaction *ReactionInput) bool {
+	if !s.AcceptReactions {
+		return false
+	}
+	if len(s.Reactions) == 0 {
+		return true
+	}
+	return reaction.Emoji != "" && slices.Contains(s.Reactions, reaction.Emoji)
+}
+
+// Reaction returns the reaction being processed when a flow step accepts
+// reactions as answers, or nil for other updates.
+func (c *Context) Reaction() *ReactionInput {
+	return c.reaction
+}
+
+// ProcessReaction handles a message_reaction update. A reaction the user adds
+// to the current prompt of a step that accepts reactions is processed as the
+// step's answer; other reactions are ignored. Anonymous reactions in channels
+// and groups have no user and are ignored as well.
+func (b *Bot) ProcessReaction(update MessageReactionUpdated) {
+	reaction := update.addedReaction()
+	if reaction == nil || reaction.UserID == 0 {
+		return
+	}
+
+	ctx := newContext(tgbotapi.Update{}, b.sender, b.templateManager, b.flowManager, b.promptComposer, b.accessManager)
+	ctx.userID, ctx.chatID = reaction.UserID, reaction.ChatID
+	ctx.isGroup = update.Chat.IsGroup() || update.Chat.IsSuperGroup()
+	ctx.reaction = reaction
+	ctx.telegramClient = b.sender.forContext(ctx)
+	ctx.clock = b.clock
+	defer b.lockConversation(ctx)()
+
+	if _, err := b.flowManager.HandleUpdate(ctx); err != nil {
+		b.deadLetter(ctx, fmt.Errorf("reaction: %w", err), 1)
+	}
+}
+
+// updateEnvelope decodes the update types the telegram-bot-api library does not
+// know alongside the ones it does.
+type updateEnvelope struct {
+	tgbotapi.Update
+	MessageReaction  *MessageReactionUpdated `json:"message_reaction,omitempty"`
+	ChatBoost        *chatBoostUpdated       `json:"chat_boost,omitempty"`
+	RemovedChatBoost *chatBoostRemoved       `json:"removed_chat_boost,omitempty"`
+
+	BusinessConnection *BusinessConnection `json:"business_connection,omitempty"`
+	BusinessMessage    *businessMessage    `json:"business_message,omitempty"`
+
+	message *messageExtras // Fields of the message or channel post the library drops
+}
+
+// decodeUpdate decodes an update in the JSON format Telegram sends.
+func decodeUpdate(data []byte) (updateEnvelope, error) {
+	var envelope updateEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return envelope, err
+	}
+	var err error
+	envelope.message, err = decodeUpdateExtras(data)
+	return envelope, err
+}
+
+// processEnvelope processes a decoded update.
+func (b *Bot) processEnvelope(envelope updateEnvelope) {
+	if b.isDuplicate(envelope.UpdateID) {
+		return
+	}
+	b.lastUpdate.Store(b.clock.Now().UnixNano())
+	if b.processGiveaway(envelope.message) {
+		return
+	}
+
+	switch {
+	case envelope.MessageReaction != nil:
+		b.ProcessReaction(*envelope.MessageReaction)
+	case envelope.ChatBoost != nil:
+		b.processChatBoost(envelope.ChatBoost.Chat, envelope.ChatBoost.event())
+	case envelope.RemovedChatBoost != nil:
+		b.processChatBoost(envelope.RemovedChatBoost.Chat, envelope.RemovedChatBoost.event())
+	case envelope.BusinessConnection != nil:
+		b.processBusinessConnection(*envelope.BusinessConnection)
+	case envelope.BusinessMessage != nil:
+		b.processBusinessMessage(envelope.UpdateID, *envelope.BusinessMessage)
+	default:
+		b.processUpdate(envelope.Update)
+	}
+}
@@ -0,0 +1,70 @@
+package teleflow
+
+import (
+	"fmt"
+	"testing"
+)
+
+func reactionJSON(userID int64, messageID int, oldEmoji, newEmoji string) []byte {
+	reaction := func(emoji string) string {
+		if emoji == "" {
+			return "[]"
+		}
+		return fmt.Sprintf(`[{"type":"emoji","emoji":%q}]`, emoji)
+	}
+	return []byte(fmt.Sprintf(`{"update_id":1,"message_reaction":{"chat":{"id":%d,"type":"private"},"message_id":%d,"user":{"id":%d},"date":0,"old_reaction":%s,"new_reaction":%s}}`,
+		userID, messageID, userID, reaction(oldEmoji), reaction(newEmoji)))
+}
+
+func TestReactionsAsFlowInput(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	var answers []string
+	var reacted *ReactionInput
+	flow, err := NewFlow("confirm").
+		Step("ask").
+		Prompt("Send the report? React 👍 or 👎").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult {
+			answers = append(answers, input)
+			reacted = ctx.Reaction()
+			return CompleteFlow()
+		}).
+		AcceptReactions("👍", "👎").
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+
+	userID := int64(777)
+	if err := bot.StartFlowFor(userID, userID, "confirm", nil); err != nil {
+		t.Fatalf("StartFlowFor failed: %v", err)
+	}
+
+	// Reactions to other messages, unlisted emoji and removals are ignored
+	for _, update := range [][]byte{
+		reactionJSON(userID, 999, "", "👍"),
+		reactionJSON(userID, 123, "", "🔥"),
+		reactionJSON(userID, 123, "👍", ""),
+	} {
+		if err := bot.ProcessExternalUpdateJSON(update); err != nil {
+			t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+		}
+	}
+	if len(answers) != 0 || !bot.flowManager.isUserInFlow(userID, userID) {
+		t.Fatalf("Expected ignored reactions, got answers %v", answers)
+	}
+
+	if err := bot.ProcessExternalUpdateJSON(reactionJSON(userID, 123, "", "👍")); err != nil {
+		t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+	}
+	if len(answers) != 1 || answers[0] != "👍" {
+		t.Fatalf("Expected the reaction as the step's answer, got %v", answers)
+	}
+	if reacted == nil || reacted.MessageID != 123 || reacted.UserID != userID {
+		t.Errorf("Expected ctx.Reaction() to describe the reaction, got %+v", reacted)
+	}
+	if bot.flowManager.isUserInFlow(userID, userID) {
+		t.Error("Expected the flow to complete")
+	}
+}
@@ -0,0 +1,399 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SessionEvent is one entry of a recorded session: either an incoming update or
+// an outgoing call made while processing one.
+type SessionEvent struct {
+	Time     time.Time         `json:"time"`
+	Update   *tgbotapi.Update  `json:"update,omitempty"`    // Incoming update
+	UpdateID int               `json:"update_id,omitempty"` // Update a response was made for, 0 outside update processing
+	Response *RecordedResponse `json:"response,omitempty"`  // Outgoing call
+}
+
+// RecordedResponse describes an outgoing Bot API call in a recorded session.
+type RecordedResponse struct {
+	Method    string             `json:"method"`
+	ChatID    int64              `json:"chat_id,omitempty"`
+	MessageID int                `json:"message_id,omitempty"` // Message edited or deleted, or the ID Telegram gave a new message
+	Text      string             `json:"text,omitempty"`
+	ParseMode string             `json:"parse_mode,omitempty"`
+	Photo     bool               `json:"photo,omitempty"`
+	Buttons   [][]RecordedButton `json:"buttons,omitempty"`
+	Error     string             `json:"error,omitempty"` // Error returned by Telegram
+}
+
+// RecordedButton is a keyboard button of a RecordedResponse.
+type RecordedButton struct {
+	Text string `json:"text"`
+	Data string `json:"data,omitempty"` // Callback data of inline buttons
+	URL  string `json:"url,omitempty"`
+}
+
+// Recorder writes every update a bot receives and every call it makes to a
+// session log, one JSON SessionEvent per line. Load the log with LoadSession and
+// feed it back with Bot.Replay to reproduce a conversation in a test.
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	err     error
+	capture func(SessionEvent) // Receives events instead of w while replaying
+}
+
+// NewRecorder creates a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// NewFileRecorder creates a Recorder appending to the file at path.
+func NewFileRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	r := NewRecorder(file)
+	r.closer = file
+	return r, nil
+}
+
+// WithRecorder records the bot's session with r.
+//
+// Example:
+//
+//	recorder, err := teleflow.NewFileRecorder("session.jsonl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer recorder.Close()
+//	bot, err := teleflow.NewBot(token, teleflow.WithRecorder(recorder))
+func WithRecorder(r *Recorder) BotOption {
+	return func(b *Bot) {
+		b.recorder.Store(r)
+	}
+}
+
+// Close returns the first write error the recorder encountered and closes the
+// file of a NewFileRecorder. Events are written as they happen, so the log is
+// complete even if the bot stops without closing it.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	err := r.err
+	r.mu.Unlock()
+	if r.closer != nil {
+		err = errors.Join(err, r.closer.Close())
+	}
+	return err
+}
+
+func (r *Recorder) record(event SessionEvent) {
+	event.Time = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.capture != nil {
+		r.capture(event)
+		return
+	}
+	if r.err != nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err == nil {
+		data = append(data, '\n')
+		_, err = r.w.Write(data)
+	}
+	r.err = err
+}
+
+func (r *Recorder) recordUpdate(update tgbotapi.Update) {
+	if r.capture != nil {
+		return
+	}
+	r.record(SessionEvent{Update: &update})
+}
+
+// recordResponse records a call made on behalf of ctx. messageID is the ID of a
+// newly sent message.
+func (r *Recorder) recordResponse(ctx *Context, call DryRunMessage, messageID int, err error) {
+	response := &RecordedResponse{
+		Method:    call.Method,
+		ChatID:    call.ChatID,
+		MessageID: call.MessageID,
+		Text:      call.Text,
+		ParseMode: call.ParseMode,
+		Photo:     call.Photo,
+		Buttons:   recordedButtons(call.ReplyMarkup),
+	}
+	if response.MessageID == 0 {
+		response.MessageID = messageID
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	event := SessionEvent{Response: response}
+	if ctx != nil {
+		event.UpdateID = ctx.update.UpdateID
+	}
+	r.record(event)
+}
+
+func recordedButtons(markup interface{}) [][]RecordedButton {
+	var rows [][]RecordedButton
+	switch kb := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		for _, row := range kb.InlineKeyboard {
+			var buttons []RecordedButton
+			for _, button := range row {
+				recorded := RecordedButton{Text: button.Text}
+				if button.CallbackData != nil {
+					recorded.Data = *button.CallbackData
+				}
+				if button.URL != nil {
+					recorded.URL = *button.URL
+				}
+				buttons = append(buttons, recorded)
+			}
+			rows = append(rows, buttons)
+		}
+	case *tgbotapi.InlineKeyboardMarkup:
+		return recordedButtons(*kb)
+	case tgbotapi.ReplyKeyboardMarkup:
+		for _, row := range kb.Keyboard {
+			var buttons []RecordedButton
+			for _, button := range row {
+				buttons = append(buttons, RecordedButton{Text: button.Text})
+			}
+			rows = append(rows, buttons)
+		}
+	}
+	return rows
+}
+
+// Session is a recorded session loaded with LoadSession.
+type Session struct {
+	Events []SessionEvent
+}
+
+// LoadSession reads a session log written by a Recorder.
+func LoadSession(r io.Reader) (*Session, error) {
+	session := &Session{}
+	decoder := json.NewDecoder(r)
+	for {
+		var event SessionEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			return session, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode session event %d: %w", len(session.Events)+1, err)
+		}
+		session.Events = append(session.Events, event)
+	}
+}
+
+// LoadSessionFile reads the session log at path.
+func LoadSessionFile(path string) (*Session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer file.Close()
+	return LoadSession(file)
+}
+
+// ReplayMismatch is a response that differs between the recording and the
+// replay. Expected or Actual is nil if the response is missing on that side.
+type ReplayMismatch struct {
+	UpdateID int // Update the response was made for
+	Index    int // Position of the response among those of the update
+	Expected *RecordedResponse
+	Actual   *RecordedResponse
+}
+
+// ReplayReport is the result of Bot.Replay.
+type ReplayReport struct {
+	Updates    int // Number of updates replayed
+	Mismatches []ReplayMismatch
+}
+
+// OK reports whether the bot responded exactly as recorded.
+func (r *ReplayReport) OK() bool {
+	return len(r.Mismatches) == 0
+}
+
+// Err returns an error describing all mismatches, or nil if there are none.
+func (r *ReplayReport) Err() error {
+	var errs []error
+	for _, m := range r.Mismatches {
+		switch {
+		case m.Actual == nil:
+			errs = append(errs, fmt.Errorf("update %d, response %d: missing %s %q", m.UpdateID, m.Index, m.Expected.Method, m.Expected.Text))
+		case m.Expected == nil:
+			errs = append(errs, fmt.Errorf("update %d, response %d: unexpected %s %q", m.UpdateID, m.Index, m.Actual.Method, m.Actual.Text))
+		default:
+			errs = append(errs, fmt.Errorf("update %d, response %d: expected %s %q, got %s %q",
+				m.UpdateID, m.Index, m.Expected.Method, m.Expected.Text, m.Actual.Method, m.Actual.Text))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Replay feeds the updates of a recorded session through the bot one at a time
+// and compares its responses with the recorded ones. Message IDs and callback
+// data that differ between the runs (e.g. the random data of inline buttons)
+// are translated, so button clicks reach the replayed messages.
+//
+// Replay is meant for tests: the bot must not process other updates meanwhile,
+// and responses sent after an update has been handled, e.g. by async
+// validators, are not compared.
+//
+// Example:
+//
+//	session, err := teleflow.LoadSessionFile("testdata/bug-1234.jsonl")
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	if err := bot.Replay(session).Err(); err != nil {
+//		t.Error(err)
+//	}
+func (b *Bot) Replay(session *Session) *ReplayReport {
+	expected := make(map[int][]*RecordedResponse)
+	for _, event := range session.Events {
+		if event.Response != nil {
+			expected[event.UpdateID] = append(expected[event.UpdateID], event.Response)
+		}
+	}
+
+	var (
+		mu     sync.Mutex
+		actual []*RecordedResponse
+	)
+	capture := &Recorder{capture: func(event SessionEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		actual = append(actual, event.Response)
+	}}
+	previous := b.recorder.Swap(capture)
+	defer b.recorder.Store(previous)
+
+	report := &ReplayReport{}
+	replay := newReplayMapping()
+	for _, event := range session.Events {
+		if event.Update == nil {
+			continue
+		}
+		report.Updates++
+		mu.Lock()
+		actual = nil
+		mu.Unlock()
+
+		b.processUpdate(replay.translateUpdate(*event.Update))
+
+		mu.Lock()
+		got := actual
+		mu.Unlock()
+		want := expected[event.Update.UpdateID]
+		for i := 0; i < len(want) || i < len(got); i++ {
+			mismatch := ReplayMismatch{UpdateID: event.Update.UpdateID, Index: i}
+			if i < len(want) {
+				mismatch.Expected = want[i]
+			}
+			if i < len(got) {
+				mismatch.Actual = got[i]
+			}
+			if mismatch.Expected == nil || mismatch.Actual == nil || !replay.matches(mismatch.Expected, mismatch.Actual) {
+				report.Mismatches = append(report.Mismatches, mismatch)
+			}
+		}
+	}
+	return report
+}
+
+// replayMapping translates recorded message IDs and callback data to the ones
+// of the replay.
+type replayMapping struct {
+	messageIDs   map[int]int
+	callbackData map[string]string
+}
+
+func newReplayMapping() *replayMapping {
+	return &replayMapping{messageIDs: make(map[int]int), callbackData: make(map[string]string)}
+}
+
+func (m *replayMapping) messageID(recorded int) int {
+	if id, ok := m.messageIDs[recorded]; ok {
+		return id
+	}
+	return recorded
+}
+
+// matches compares a recorded response with a replayed one and learns the
+// translations of the new message and its buttons.
+func (m *replayMapping) matches(want, got *RecordedResponse) bool {
+	switch want.Method {
+	case "sendMessage", "sendPhoto":
+		if want.MessageID != 0 && got.MessageID != 0 {
+			m.messageIDs[want.MessageID] = got.MessageID
+		}
+	default:
+		if m.messageID(want.MessageID) != got.MessageID {
+			return false
+		}
+	}
+
+	if want.Method != got.Method || want.ChatID != got.ChatID || want.Text != got.Text ||
+		want.ParseMode != got.ParseMode || want.Photo != got.Photo || len(want.Buttons) != len(got.Buttons) {
+		return false
+	}
+	for i := range want.Buttons {
+		if len(want.Buttons[i]) != len(got.Buttons[i]) {
+			return false
+		}
+		for j, button := range want.Buttons[i] {
+			replayed := got.Buttons[i][j]
+			if button.Text != replayed.Text || button.URL != replayed.URL {
+				return false
+			}
+			if button.Data != "" {
+				m.callbackData[button.Data] = replayed.Data
+			}
+		}
+	}
+	return true
+}
+
+// translateUpdate returns a copy of a recorded update that refers to the
+// replayed messages and buttons.
+func (m *replayMapping) translateUpdate(update tgbotapi.Update) tgbotapi.Update {
+	if update.Message != nil && update.Message.ReplyToMessage != nil {
+		message := *update.Message
+		reply := *message.ReplyToMessage
+		reply.MessageID = m.messageID(reply.MessageID)
+		message.ReplyToMessage = &reply
+		update.Message = &message
+	}
+	if update.CallbackQuery != nil {
+		query := *update.CallbackQuery
+		if data, ok := m.callbackData[query.Data]; ok {
+			query.Data = data
+		}
+		if query.Message != nil {
+			message := *query.Message
+			message.MessageID = m.messageID(message.MessageID)
+			query.Message = &message
+		}
+		update.CallbackQuery = &query
+	}
+	return update
+}
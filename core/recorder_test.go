@@ -0,0 +1,127 @@
+package teleflow
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func newReplayTestBot(t *testing.T, firstMessageID int, prompt string, options ...BotOption) (*Bot, *MockTelegramClient) {
+	bot, mockClient, _, _ := createTestBot(options...)
+	nextID := firstMessageID
+	mockClient.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		nextID++
+		return tgbotapi.Message{MessageID: nextID}, nil
+	}
+
+	flow, err := NewFlow("pick").
+		Step("choose").
+		Prompt(prompt).
+		WithPromptKeyboard(func(ctx *Context) *PromptKeyboardBuilder {
+			return NewPromptKeyboard().ButtonCallback("Red", "red").ButtonCallback("Blue", "blue")
+		}).
+		Process(func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+			if buttonClick == nil {
+				return Retry()
+			}
+			ctx.SetFlowData("color", buttonClick.Data)
+			return CompleteFlow()
+		}).
+		OnComplete(func(ctx *Context) error {
+			color, _ := ctx.GetFlowData("color")
+			return ctx.SendPromptText("You picked " + color.(string))
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build flow: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	bot.HandleCommand("start", func(ctx *Context, command, args string) error {
+		return ctx.StartFlow("pick")
+	})
+	return bot, mockClient
+}
+
+func recordPickSession(t *testing.T) *Session {
+	var log bytes.Buffer
+	bot, mockClient := newReplayTestBot(t, 500, "Pick a color", WithRecorder(NewRecorder(&log)))
+
+	user := &tgbotapi.User{ID: 42}
+	chat := &tgbotapi.Chat{ID: 42, Type: "private"}
+	bot.ProcessExternalUpdate(tgbotapi.Update{UpdateID: 1, Message: &tgbotapi.Message{
+		MessageID: 1, From: user, Chat: chat, Text: "/start",
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Length: 6}},
+	}})
+
+	keyboard := mockClient.SendCalls[0].(tgbotapi.MessageConfig).ReplyMarkup.(*tgbotapi.InlineKeyboardMarkup)
+	bot.ProcessExternalUpdate(tgbotapi.Update{UpdateID: 2, CallbackQuery: &tgbotapi.CallbackQuery{
+		ID: "cb", From: user, Data: *keyboard.InlineKeyboard[0][1].CallbackData,
+		Message: &tgbotapi.Message{MessageID: 501, Chat: chat},
+	}})
+
+	session, err := LoadSession(&log)
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	return session
+}
+
+func TestRecorder_RecordsUpdatesAndResponses(t *testing.T) {
+	session := recordPickSession(t)
+
+	var updates int
+	var texts []string
+	for _, event := range session.Events {
+		if event.Update != nil {
+			updates++
+		}
+		if event.Response != nil && event.Response.Method == "sendMessage" {
+			texts = append(texts, event.Response.Text)
+			if event.UpdateID == 0 {
+				t.Errorf("Expected response to reference its update")
+			}
+		}
+	}
+	if updates != 2 {
+		t.Errorf("Expected 2 recorded updates, got %d", updates)
+	}
+	if strings.Join(texts, "|") != "Pick a color|You picked blue" {
+		t.Errorf("Unexpected recorded messages: %v", texts)
+	}
+	if first := session.Events[1].Response; first == nil || first.MessageID != 501 || len(first.Buttons) != 1 || len(first.Buttons[0]) != 2 {
+		t.Errorf("Expected prompt with its message ID and buttons, got %+v", first)
+	}
+}
+
+func TestReplay_ReproducesSession(t *testing.T) {
+	session := recordPickSession(t)
+
+	bot, mockClient := newReplayTestBot(t, 0, "Pick a color")
+	report := bot.Replay(session)
+	if err := report.Err(); err != nil {
+		t.Fatalf("Expected replay to match, got: %v", err)
+	}
+	if report.Updates != 2 {
+		t.Errorf("Expected 2 replayed updates, got %d", report.Updates)
+	}
+	last := mockClient.SendCalls[len(mockClient.SendCalls)-1].(tgbotapi.MessageConfig)
+	if last.Text != "You picked blue" {
+		t.Errorf("Expected button click to be replayed, got %q", last.Text)
+	}
+}
+
+func TestReplay_ReportsMismatches(t *testing.T) {
+	session := recordPickSession(t)
+
+	bot, _ := newReplayTestBot(t, 0, "Choose a color")
+	report := bot.Replay(session)
+	if report.OK() || len(report.Mismatches) == 0 {
+		t.Fatal("Expected changed prompt to be reported")
+	}
+	mismatch := report.Mismatches[0]
+	if mismatch.UpdateID != 1 || mismatch.Expected.Text != "Pick a color" || mismatch.Actual.Text != "Choose a color" {
+		t.Errorf("Unexpected mismatch: %+v", mismatch)
+	}
+}
@@ -0,0 +1,75 @@
+package teleflow
+
+import (
+	"regexp"
+	"strings"
+)
+
+// botTokenPattern matches Telegram bot tokens, also inside request URLs such as
+// https://api.telegram.org/bot<token>/sendMessage.
+var botTokenPattern = regexp.MustCompile(`\d{6,12}:[A-Za-z0-9_-]{30,}`)
+
+// secretScrubber removes secrets from error messages and log lines.
+type secretScrubber struct {
+	literals []string         // Known secrets, such as the bot's own token
+	patterns []*regexp.Regexp // Patterns configured with WithSecretPatterns
+}
+
+// WithSecretPatterns removes matches of patterns, e.g. API keys or database
+// passwords, from the errors teleflow returns from Telegram calls, logs, stores
+// as dead letters and writes to the outbound log. Bot tokens are always
+// removed.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithSecretPatterns(
+//		regexp.MustCompile(`sk_live_[A-Za-z0-9]+`),
+//	))
+func WithSecretPatterns(patterns ...*regexp.Regexp) BotOption {
+	return func(b *Bot) {
+		b.secrets.patterns = append(b.secrets.patterns, patterns...)
+	}
+}
+
+// scrub replaces secrets in text with "[REDACTED]".
+func (s *secretScrubber) scrub(text string) string {
+	for _, literal := range s.literals {
+		if literal != "" {
+			text = strings.ReplaceAll(text, literal, redactedText)
+		}
+	}
+	text = botTokenPattern.ReplaceAllString(text, redactedText)
+	for _, pattern := range s.patterns {
+		text = pattern.ReplaceAllString(text, redactedText)
+	}
+	return text
+}
+
+// scrubError returns err with secrets removed from its message. errors.Is and
+// errors.As still see the original error; err is returned as is when its
+// message holds no secret.
+func (s *secretScrubber) scrubError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	scrubbed := s.scrub(message)
+	if scrubbed == message {
+		return err
+	}
+	return &scrubbedError{message: scrubbed, err: err}
+}
+
+// scrubbedError is an error whose message had secrets removed.
+type scrubbedError struct {
+	message string
+	err     error
+}
+
+func (e *scrubbedError) Error() string {
+	return e.message
+}
+
+func (e *scrubbedError) Unwrap() error {
+	return e.err
+}
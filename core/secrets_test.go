@@ -0,0 +1,57 @@
+package teleflow
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSecretScrubbing_ClientErrors(t *testing.T) {
+	bot, client, _, _ := createTestBot(WithSecretPatterns(regexp.MustCompile(`sk_live_[A-Za-z0-9]+`)))
+	networkErr := errors.New("Post \"https://api.telegram.org/bot123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw/sendMessage\": dial tcp: timeout (key sk_live_abc123)")
+	client.SendFunc = func(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+		return tgbotapi.Message{}, networkErr
+	}
+
+	_, err := bot.contextFor(42, 42).telegramClient.Send(tgbotapi.NewMessage(42, "hi"))
+	if err == nil {
+		t.Fatal("Expected the send to fail")
+	}
+	if strings.Contains(err.Error(), "AAHdqTcvCH1") || strings.Contains(err.Error(), "sk_live_abc123") {
+		t.Errorf("Expected the token and secret to be removed, got %q", err)
+	}
+	if !strings.Contains(err.Error(), "dial tcp: timeout") {
+		t.Errorf("Expected the rest of the message to be kept, got %q", err)
+	}
+	if !errors.Is(err, networkErr) {
+		t.Error("Expected errors.Is to still find the original error")
+	}
+}
+
+func TestSecretScrubbing_DeadLettersAndReports(t *testing.T) {
+	queue := NewMemoryDeadLetterQueue()
+	var reported []ErrorReport
+	bot, _, _, _ := createTestBot(WithDeadLetterQueue(queue, 0), WithSecretPatterns(regexp.MustCompile(`password=\S+`)))
+	bot.UseMiddleware(ErrorReportingMiddleware(ReporterFunc(func(r ErrorReport) {
+		reported = append(reported, r)
+	})))
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		return errors.New("db postgres://app:password=hunter2 failed, token 123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw")
+	})
+
+	bot.processUpdate(createPoolTestUpdate(42, "hi"))
+
+	letters, _ := queue.Pop(10)
+	if len(letters) != 1 {
+		t.Fatalf("Expected one dead letter, got %d", len(letters))
+	}
+	if strings.Contains(letters[0].Error, "hunter2") || strings.Contains(letters[0].Error, "AAHdqTcvCH1") {
+		t.Errorf("Expected secrets to be removed from the dead letter, got %q", letters[0].Error)
+	}
+	if len(reported) != 1 || strings.Contains(reported[0].Err.Error(), "AAHdqTcvCH1") {
+		t.Errorf("Expected the reported error without the token, got %+v", reported)
+	}
+}
@@ -0,0 +1,193 @@
+package teleflow
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SendHook inspects or rewrites an outgoing Bot API call before it is made.
+// ctx is the context of the update being processed, or nil for calls made
+// outside update processing (e.g. SetBotCommands at startup). Returning a
+// different Chattable replaces the call; returning nil and no error skips the
+// call entirely (the caller sees a successful, empty result); returning an
+// error aborts the call with that error.
+type SendHook func(ctx *Context, c tgbotapi.Chattable) (tgbotapi.Chattable, error)
+
+// UseSendHook adds a hook that runs for every outgoing Send and Request call,
+// including prompts sent by flows. Hooks run in the order they were added.
+//
+// Methods the telegram-bot-api library has no Chattable for, such as
+// sendInvoice, deleteMessages or sendMessage with reply parameters, are called
+// by name and only reach hooks added with UseRawSendHook.
+//
+// Example:
+//
+//	// Append a footer to every text message
+//	bot.UseSendHook(func(ctx *teleflow.Context, c tgbotapi.Chattable) (tgbotapi.Chattable, error) {
+//		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+//			msg.Text += "\n\n— Sent by ExampleBot"
+//			return msg, nil
+//		}
+//		return c, nil
+//	})
+func (b *Bot) UseSendHook(hook SendHook) {
+	b.sendHooksMu.Lock()
+	defer b.sendHooksMu.Unlock()
+	b.sendHooks = append(b.sendHooks, hook)
+}
+
+// RawSendHook inspects or rewrites an outgoing Bot API call made by method name
+// rather than through a Chattable. ctx is as for SendHook. Returning different
+// params replaces them; returning nil params and no error skips the call (the
+// caller sees a successful, empty result); returning an error aborts the call.
+type RawSendHook func(ctx *Context, endpoint string, params tgbotapi.Params) (tgbotapi.Params, error)
+
+// UseRawSendHook adds a hook that runs for every outgoing raw Bot API call.
+// Hooks run in the order they were added.
+//
+// Example:
+//
+//	// Log every invoice the bot sends
+//	bot.UseRawSendHook(func(ctx *teleflow.Context, endpoint string, params tgbotapi.Params) (tgbotapi.Params, error) {
+//		if endpoint == "sendInvoice" {
+//			log.Printf("Invoice %q to chat %s", params["payload"], params["chat_id"])
+//		}
+//		return params, nil
+//	})
+func (b *Bot) UseRawSendHook(hook RawSendHook) {
+	b.sendHooksMu.Lock()
+	defer b.sendHooksMu.Unlock()
+	b.rawSendHooks = append(b.rawSendHooks, hook)
+}
+
+// hookedClient is a TelegramClient that runs the bot's send hooks before
+// delegating to the real client.
+type hookedClient struct {
+	next TelegramClient
+	bot  *Bot
+	ctx  *Context // Context passed to hooks (nil outside update processing)
+}
+
+// forContext returns a client that passes ctx to the send hooks.
+func (h *hookedClient) forContext(ctx *Context) *hookedClient {
+	return &hookedClient{next: h.next, bot: h.bot, ctx: ctx}
+}
+
+// applyHooks runs all send hooks on c. A nil result means the call is skipped.
+func (h *hookedClient) applyHooks(c tgbotapi.Chattable) (tgbotapi.Chattable, error) {
+	h.bot.sendHooksMu.RLock()
+	hooks := h.bot.sendHooks
+	h.bot.sendHooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		var err error
+		if c, err = hook(h.ctx, c); err != nil || c == nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// applyRawHooks runs all raw send hooks on params. A nil result means the call
+// is skipped.
+func (h *hookedClient) applyRawHooks(endpoint string, params tgbotapi.Params) (tgbotapi.Params, error) {
+	h.bot.sendHooksMu.RLock()
+	hooks := h.bot.rawSendHooks
+	h.bot.sendHooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		var err error
+		if params, err = hook(h.ctx, endpoint, params); err != nil || params == nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+// Send runs the send hooks and sends the resulting Chattable. Bot API errors
+// are returned as *APIError or *ErrRateLimited.
+func (h *hookedClient) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	c, err := h.applyHooks(c)
+	if err != nil || c == nil {
+		return tgbotapi.Message{}, err
+	}
+	start := time.Now()
+	msg, err := h.next.Send(c)
+	if err != nil {
+		err = h.bot.secrets.scrubError(classifyAPIError(DescribeChattable(c).Method, err))
+	}
+	if r := h.bot.recorder.Load(); r != nil {
+		r.recordResponse(h.ctx, DescribeChattable(c), msg.MessageID, err)
+	}
+	h.bot.logOutbound(DescribeChattable(c), msg.MessageID, time.Since(start), err)
+	return msg, err
+}
+
+// Request runs the send hooks and makes the resulting request. Bot API errors
+// are returned as *APIError or *ErrRateLimited.
+func (h *hookedClient) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	c, err := h.applyHooks(c)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+	start := time.Now()
+	resp, err := h.next.Request(c)
+	if err != nil {
+		err = h.bot.secrets.scrubError(classifyAPIError(DescribeChattable(c).Method, err))
+	}
+	if r := h.bot.recorder.Load(); r != nil {
+		r.recordResponse(h.ctx, DescribeChattable(c), 0, err)
+	}
+	h.bot.logOutbound(DescribeChattable(c), 0, time.Since(start), err)
+	return resp, err
+}
+
+// GetUpdatesChan delegates to the real client.
+func (h *hookedClient) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	return h.next.GetUpdatesChan(config)
+}
+
+// GetMe delegates to the real client.
+func (h *hookedClient) GetMe() (tgbotapi.User, error) {
+	return h.next.GetMe()
+}
+
+// MakeRequest runs the raw send hooks and delegates the resulting request to
+// the real client. Raw requests have no Chattable representation and are not
+// passed to send hooks.
+func (h *hookedClient) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	requester, ok := h.next.(rawRequester)
+	if !ok {
+		return nil, fmt.Errorf("telegram client does not support the %s method", endpoint)
+	}
+	params, err := h.applyRawHooks(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+	start := time.Now()
+	resp, err := requester.MakeRequest(endpoint, params)
+	if err != nil {
+		err = h.bot.secrets.scrubError(classifyAPIError(endpoint, err))
+	}
+	if r := h.bot.recorder.Load(); r != nil {
+		r.recordResponse(h.ctx, describeRawRequest(endpoint, params), 0, err)
+	}
+	h.bot.logOutbound(describeRawRequest(endpoint, params), 0, time.Since(start), err)
+	return resp, err
+}
+
+// clientFor returns the client to use for calls made on behalf of ctx.
+func clientFor(client TelegramClient, ctx *Context) TelegramClient {
+	if hooked, ok := client.(*hookedClient); ok && ctx != nil {
+		return hooked.forContext(ctx)
+	}
+	return client
+}
@@ -0,0 +1,94 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSendHooks_RewriteAndContext(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	var hookCtx *Context
+	bot.UseSendHook(func(ctx *Context, c tgbotapi.Chattable) (tgbotapi.Chattable, error) {
+		hookCtx = ctx
+		if msg, ok := c.(tgbotapi.MessageConfig); ok {
+			msg.Text += " [footer]"
+			return msg, nil
+		}
+		return c, nil
+	})
+	bot.HandleText("hi", func(ctx *Context, text string) error {
+		return ctx.SendPromptText("hello")
+	})
+
+	bot.processUpdate(createCaptchaAnswerUpdate(42, 42, "hi"))
+
+	if len(mockClient.SendCalls) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(mockClient.SendCalls))
+	}
+	if msg := mockClient.SendCalls[0].(tgbotapi.MessageConfig); msg.Text != "hello [footer]" {
+		t.Errorf("Expected decorated text, got %q", msg.Text)
+	}
+	if hookCtx == nil || hookCtx.UserID() != 42 {
+		t.Error("Expected hook to receive the update context")
+	}
+
+	// Calls outside update processing get a nil context
+	_ = bot.SetBotCommands(map[string]string{"start": "Start"})
+	if hookCtx != nil {
+		t.Error("Expected nil context for calls outside update processing")
+	}
+}
+
+func TestSendHooks_SkipAndError(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	bot.UseSendHook(func(ctx *Context, c tgbotapi.Chattable) (tgbotapi.Chattable, error) {
+		if _, ok := c.(tgbotapi.MessageConfig); ok {
+			return nil, nil // dry run
+		}
+		return nil, errors.New("blocked")
+	})
+
+	if _, err := bot.sender.Send(tgbotapi.NewMessage(1, "text")); err != nil {
+		t.Errorf("Expected skipped send to succeed, got %v", err)
+	}
+	if len(mockClient.SendCalls) != 0 {
+		t.Error("Expected skipped send not to reach the client")
+	}
+
+	if _, err := bot.sender.Request(tgbotapi.NewDeleteMessage(1, 2)); err == nil || err.Error() != "blocked" {
+		t.Errorf("Expected hook error, got %v", err)
+	}
+	if len(mockClient.RequestCalls) != 0 {
+		t.Error("Expected aborted request not to reach the client")
+	}
+}
+
+func TestRawSendHooks_RewriteAndSkip(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	bot.UseRawSendHook(func(ctx *Context, endpoint string, params tgbotapi.Params) (tgbotapi.Params, error) {
+		if endpoint == "deleteMessages" {
+			return nil, nil
+		}
+		params["text"] += " [footer]"
+		return params, nil
+	})
+
+	if _, err := makeRawRequest(bot.sender, "sendMessage", tgbotapi.Params{"chat_id": "1", "text": "hello"}); err != nil {
+		t.Fatalf("Expected raw request to succeed, got %v", err)
+	}
+	if len(mockClient.MakeRequestCalls) != 1 || mockClient.MakeRequestCalls[0].Params["text"] != "hello [footer]" {
+		t.Errorf("Expected rewritten raw request, got %+v", mockClient.MakeRequestCalls)
+	}
+
+	if _, err := makeRawRequest(bot.sender, "deleteMessages", tgbotapi.Params{"chat_id": "1"}); err != nil {
+		t.Errorf("Expected skipped raw request to succeed, got %v", err)
+	}
+	if len(mockClient.MakeRequestCalls) != 1 {
+		t.Error("Expected skipped raw request not to reach the client")
+	}
+}
@@ -0,0 +1,114 @@
+package teleflow
+
+import (
+	"sync"
+	"time"
+)
+
+// HandlerStats summarizes the executions of a single handler or flow step.
+type HandlerStats struct {
+	Count         int64         // Number of executions
+	Errors        int64         // Executions that returned an error
+	TotalDuration time.Duration // Sum of all execution durations
+	MaxDuration   time.Duration // Slowest execution
+	LastDuration  time.Duration // Duration of the most recent execution
+}
+
+// AvgDuration returns the mean execution time.
+func (s HandlerStats) AvgDuration() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Count)
+}
+
+// ErrorRate returns the fraction of executions that failed, between 0 and 1.
+func (s HandlerStats) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Count)
+}
+
+// StatsSnapshot maps handler keys to their statistics. Keys have the form
+// "command:/start", "text:Hello", "deeplink:ref_", "default" and
+// "flow:<flow>/<step>" for flow steps.
+type StatsSnapshot map[string]HandlerStats
+
+// statsCollector records handler durations and errors.
+type statsCollector struct {
+	mu       sync.Mutex
+	handlers map[string]*HandlerStats
+}
+
+// newStatsCollector creates an empty collector.
+func newStatsCollector() *statsCollector {
+	return &statsCollector{handlers: make(map[string]*HandlerStats)}
+}
+
+// track runs fn and records its duration and result under key.
+func (s *statsCollector) track(key string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	s.record(key, time.Since(start), err)
+	return err
+}
+
+// record adds one execution to the statistics of key.
+func (s *statsCollector) record(key string, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.handlers[key]
+	if !ok {
+		stats = &HandlerStats{}
+		s.handlers[key] = stats
+	}
+	stats.Count++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.TotalDuration += duration
+	stats.LastDuration = duration
+	if duration > stats.MaxDuration {
+		stats.MaxDuration = duration
+	}
+}
+
+// snapshot copies the current statistics.
+func (s *statsCollector) snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(StatsSnapshot, len(s.handlers))
+	for key, stats := range s.handlers {
+		snapshot[key] = *stats
+	}
+	return snapshot
+}
+
+// reset discards all statistics.
+func (s *statsCollector) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = make(map[string]*HandlerStats)
+}
+
+// Stats returns processing durations and error counts for every command, text,
+// deep-link and default handler and for every flow step that has run since the
+// bot was created or ResetStats was called.
+//
+// Example:
+//
+//	for key, s := range bot.Stats() {
+//		log.Printf("%-30s n=%d avg=%v max=%v errors=%.1f%%",
+//			key, s.Count, s.AvgDuration(), s.MaxDuration, s.ErrorRate()*100)
+//	}
+func (b *Bot) Stats() StatsSnapshot {
+	return b.stats.snapshot()
+}
+
+// ResetStats discards all statistics collected so far.
+func (b *Bot) ResetStats() {
+	b.stats.reset()
+}
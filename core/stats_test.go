@@ -0,0 +1,59 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStats_RecordsHandlersAndFlowSteps(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	bot.HandleCommand("fail", func(ctx *Context, command, args string) error {
+		return errors.New("boom")
+	})
+	bot.HandleText("hi", func(ctx *Context, text string) error { return nil })
+	bot.RegisterFlow(createTestFlow())
+
+	bot.processUpdate(createCommandUpdate("fail", "/fail"))
+	bot.processUpdate(createCaptchaAnswerUpdate(42, 42, "hi"))
+	bot.processUpdate(createCaptchaAnswerUpdate(42, 42, "hi"))
+
+	ctx := newContext(createCaptchaAnswerUpdate(42, 42, ""), bot.api, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	if err := ctx.StartFlow("test-flow"); err != nil {
+		t.Fatalf("StartFlow failed: %v", err)
+	}
+	bot.processUpdate(createCaptchaAnswerUpdate(42, 42, "Alice"))
+
+	stats := bot.Stats()
+
+	if s := stats["command:/fail"]; s.Count != 1 || s.Errors != 1 || s.ErrorRate() != 1 {
+		t.Errorf("Unexpected command stats: %+v", s)
+	}
+	if s := stats["text:hi"]; s.Count != 2 || s.Errors != 0 {
+		t.Errorf("Unexpected text stats: %+v", s)
+	}
+	if s := stats["flow:test-flow/step1"]; s.Count != 1 {
+		t.Errorf("Unexpected flow step stats: %+v (all: %v)", s, stats)
+	}
+
+	bot.ResetStats()
+	if len(bot.Stats()) != 0 {
+		t.Error("Expected ResetStats to clear statistics")
+	}
+}
+
+func TestHandlerStats_Averages(t *testing.T) {
+	var zero HandlerStats
+	if zero.AvgDuration() != 0 || zero.ErrorRate() != 0 {
+		t.Error("Expected zero values for empty stats")
+	}
+
+	collector := newStatsCollector()
+	collector.record("x", 10, nil)
+	collector.record("x", 30, errors.New("fail"))
+
+	s := collector.snapshot()["x"]
+	if s.AvgDuration() != 20 || s.MaxDuration != 30 || s.LastDuration != 30 || s.ErrorRate() != 0.5 {
+		t.Errorf("Unexpected aggregated stats: %+v", s)
+	}
+}
@@ -0,0 +1,196 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxMessageLength is the longest text Telegram accepts in a message, in
+// characters.
+const maxMessageLength = 4096
+
+// Minimum time between edits of a streamed message. Telegram allows about one
+// message per second in a private chat and 20 per minute in a group.
+const (
+	streamPrivateInterval = time.Second
+	streamGroupInterval   = 3 * time.Second
+)
+
+// StreamMessage is a message that grows as text is appended, e.g. the reply of
+// a language model arriving token by token. Appended text is batched into edits
+// no more often than Telegram's rate limits allow; when Telegram still reports
+// a rate limit the next edit waits for it. Text beyond Telegram's message length
+// continues in a new message. Create it with ctx.NewStreamMessage and Close it
+// once the text is complete.
+type StreamMessage struct {
+	mu       sync.Mutex
+	client   TelegramClient
+	clock    Clock
+	chatID   int64
+	interval time.Duration
+
+	messageID int       // Message being edited, 0 before the first send
+	text      []rune    // Text of the current message
+	sent      int       // Length of text as last sent
+	nextEdit  time.Time // Earliest time of the next edit
+	timer     Timer     // Scheduled edit, nil if none is pending
+	err       error     // First error of a scheduled edit, returned by the next call
+	closed    bool
+}
+
+// NewStreamMessage returns a StreamMessage for the current chat. Nothing is
+// sent until text is appended. Edits may still be flushed after the handler
+// returns, with send hooks seeing this context, so the context is retained
+// (see Context.Retain) and not reused by WithContextPooling.
+//
+// Example:
+//
+//	stream := ctx.NewStreamMessage()
+//	for token := range llm.Complete(prompt) {
+//		if err := stream.Append(token); err != nil {
+//			return err
+//		}
+//	}
+//	return stream.Close()
+func (c *Context) NewStreamMessage() *StreamMessage {
+	clock := c.clock
+	if clock == nil {
+		clock = SystemClock()
+	}
+	interval := streamPrivateInterval
+	if c.IsGroup() || c.IsChannel() {
+		interval = streamGroupInterval
+	}
+	c.Retain()
+	return &StreamMessage{client: c.telegramClient, clock: clock, chatID: c.ChatID(), interval: interval}
+}
+
+// Append adds text to the message. The first text is sent right away; later
+// text is edited in once the rate limit allows, without blocking the caller.
+// Errors of edits made in the background are returned by the next call.
+func (s *StreamMessage) Append(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("stream message is closed")
+	}
+	if err := s.takeErr(); err != nil {
+		return err
+	}
+
+	s.text = append(s.text, []rune(text)...)
+	for len(s.text) > maxMessageLength {
+		// Finish the full message and continue the overflow in a new one
+		overflow := append([]rune(nil), s.text[maxMessageLength:]...)
+		s.text = s.text[:maxMessageLength]
+		if err := s.flush(); err != nil {
+			s.text = append(s.text, overflow...)
+			return err
+		}
+		s.messageID, s.text, s.sent = 0, overflow, 0
+	}
+
+	if s.messageID == 0 || !s.clock.Now().Before(s.nextEdit) {
+		return s.retryLater(s.flush())
+	}
+	if s.timer == nil {
+		s.timer = s.clock.AfterFunc(s.nextEdit.Sub(s.clock.Now()), s.scheduledFlush)
+	}
+	return nil
+}
+
+// Text returns the text of the message being streamed.
+func (s *StreamMessage) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return string(s.text)
+}
+
+// Close sends the remaining text right away, ignoring the edit interval, and
+// stops the stream. If Telegram rate limits this final edit, the returned error
+// is an *ErrRateLimited and the remaining text is not sent.
+func (s *StreamMessage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if err := s.takeErr(); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// scheduledFlush is the edit scheduled by Append.
+func (s *StreamMessage) scheduledFlush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.timer == nil || s.clock.Now().Before(s.nextEdit) {
+		return // Stopped, or superseded by a later edit
+	}
+	s.timer = nil
+	if err := s.retryLater(s.flush()); err != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// retryLater schedules another edit when Telegram rate limited the last one. The
+// first message of the stream cannot be retried in the background.
+func (s *StreamMessage) retryLater(err error) error {
+	var limited *ErrRateLimited
+	if !errors.As(err, &limited) || s.messageID == 0 {
+		return err
+	}
+	s.timer = s.clock.AfterFunc(limited.RetryAfter, s.scheduledFlush)
+	return nil
+}
+
+// takeErr returns and clears the error of a scheduled edit.
+func (s *StreamMessage) takeErr() error {
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// flush sends or edits the message to show all text appended so far.
+func (s *StreamMessage) flush() error {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.text) == s.sent || len(s.text) == 0 {
+		return nil
+	}
+
+	var err error
+	text := string(s.text)
+	if s.messageID == 0 {
+		msg := tgbotapi.NewMessage(s.chatID, text)
+		msg.DisableWebPagePreview = true
+		var sent tgbotapi.Message
+		if sent, err = s.client.Send(msg); err == nil {
+			s.messageID = sent.MessageID
+		}
+	} else {
+		edit := tgbotapi.NewEditMessageText(s.chatID, s.messageID, text)
+		edit.DisableWebPagePreview = true
+		_, err = s.client.Request(edit)
+	}
+
+	now := s.clock.Now()
+	if err != nil {
+		var limited *ErrRateLimited
+		if errors.As(err, &limited) {
+			s.nextEdit = now.Add(limited.RetryAfter)
+		}
+		return fmt.Errorf("failed to stream message: %w", err)
+	}
+	s.sent = len(s.text)
+	s.nextEdit = now.Add(s.interval)
+	return nil
+}
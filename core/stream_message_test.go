@@ -0,0 +1,130 @@
+package teleflow
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// manualClock is a Clock whose scheduled calls run when the test fires them.
+type manualClock struct {
+	stepClock
+	pending []func()
+}
+
+type manualTimer struct{ stopped *bool }
+
+func (t manualTimer) Stop() bool {
+	wasStopped := *t.stopped
+	*t.stopped = true
+	return !wasStopped
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	stopped := false
+	c.pending = append(c.pending, func() {
+		if !stopped {
+			f()
+		}
+	})
+	return manualTimer{stopped: &stopped}
+}
+
+// fire advances the clock and runs the scheduled calls.
+func (c *manualClock) fire(d time.Duration) {
+	c.now = c.now.Add(d)
+	pending := c.pending
+	c.pending = nil
+	for _, f := range pending {
+		f()
+	}
+}
+
+func TestStreamMessage_BatchesEdits(t *testing.T) {
+	client := NewMockTelegramClient()
+	clock := &manualClock{stepClock: stepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	ctx := newContext(tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: 5}, Chat: &tgbotapi.Chat{ID: 5, Type: "private"}}},
+		client, nil, nil, nil, nil)
+	ctx.clock = clock
+
+	stream := ctx.NewStreamMessage()
+	for _, token := range []string{"Hello", ",", " world"} {
+		if err := stream.Append(token); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if len(client.SendCalls) != 1 || len(client.RequestCalls) != 0 {
+		t.Fatalf("Expected only the first text to be sent, got %d sends and %d edits", len(client.SendCalls), len(client.RequestCalls))
+	}
+
+	clock.fire(time.Second)
+	if len(client.RequestCalls) != 1 || client.RequestCalls[0].(tgbotapi.EditMessageTextConfig).Text != "Hello, world" {
+		t.Fatalf("Expected one batched edit, got %+v", client.RequestCalls)
+	}
+
+	// A rate limited edit is retried after the requested delay
+	client.RequestFunc = func(tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		return nil, &ErrRateLimited{RetryAfter: 5 * time.Second, APIError: &APIError{Code: 429}}
+	}
+	clock.now = clock.now.Add(time.Second)
+	if err := stream.Append("!"); err != nil {
+		t.Fatalf("Rate limited edits must be retried, got %v", err)
+	}
+	client.RequestFunc = nil
+	clock.fire(5 * time.Second)
+	if last := client.RequestCalls[len(client.RequestCalls)-1].(tgbotapi.EditMessageTextConfig); last.Text != "Hello, world!" {
+		t.Errorf("Expected the retried edit, got %q", last.Text)
+	}
+
+	edits := len(client.RequestCalls)
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(client.RequestCalls) != edits {
+		t.Error("Close must not repeat an edit without new text")
+	}
+	if err := stream.Append("more"); err == nil {
+		t.Error("Expected Append after Close to fail")
+	}
+}
+
+func TestStreamMessage_SplitsLongText(t *testing.T) {
+	client := NewMockTelegramClient()
+	ctx := newContext(tgbotapi.Update{Message: &tgbotapi.Message{From: &tgbotapi.User{ID: 5}, Chat: &tgbotapi.Chat{ID: 5, Type: "private"}}},
+		client, nil, nil, nil, nil)
+
+	stream := ctx.NewStreamMessage()
+	if err := stream.Append(strings.Repeat("a", maxMessageLength+10)); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if len(client.SendCalls) != 2 {
+		t.Fatalf("Expected the text to continue in a second message, got %d messages", len(client.SendCalls))
+	}
+	if first := client.SendCalls[0].(tgbotapi.MessageConfig).Text; len(first) != maxMessageLength {
+		t.Errorf("Expected a full first message, got %d characters", len(first))
+	}
+	if stream.Text() != strings.Repeat("a", 10) {
+		t.Errorf("Expected the overflow in the current message, got %q", stream.Text())
+	}
+}
+
+func TestStreamMessage_RetainsPooledContext(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithContextPooling())
+	var streamCtx *Context
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		streamCtx = ctx
+		return ctx.NewStreamMessage().Append("thinking…")
+	})
+	bot.processUpdate(createPoolTestUpdate(42, "hi"))
+
+	if !streamCtx.retained {
+		t.Fatal("Expected the stream to retain its context")
+	}
+	other := acquireContext(createPoolTestUpdate(7, "next"), bot.sender, bot.templateManager, bot.flowManager, bot.promptComposer, bot.accessManager)
+	defer releaseContext(other)
+	if other == streamCtx {
+		t.Error("Expected the streaming context not to be reused for another update")
+	}
+}
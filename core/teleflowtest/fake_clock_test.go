@@ -0,0 +1,55 @@
+package teleflowtest
+
+import (
+	"testing"
+	"time"
+
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+func TestFakeClock_Advance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	var fired []time.Duration
+	clock.AfterFunc(2*time.Minute, func() { fired = append(fired, clock.Now().Sub(start)) })
+	clock.AfterFunc(time.Minute, func() {
+		fired = append(fired, clock.Now().Sub(start))
+		clock.AfterFunc(30*time.Second, func() { fired = append(fired, clock.Now().Sub(start)) })
+	})
+	stopped := clock.AfterFunc(90*time.Second, func() { t.Error("Stopped timer fired") })
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Expected Stop to succeed only once")
+	}
+
+	clock.Advance(100 * time.Second)
+	if len(fired) != 2 || fired[0] != time.Minute || fired[1] != 90*time.Second {
+		t.Errorf("Expected timers at 1m and 1m30s, got %v", fired)
+	}
+	if clock.PendingTimers() != 1 || clock.Now() != start.Add(100*time.Second) {
+		t.Errorf("Expected one pending timer at 1m40s, got %d at %v", clock.PendingTimers(), clock.Now())
+	}
+
+	clock.Advance(time.Minute)
+	if len(fired) != 3 || fired[2] != 2*time.Minute {
+		t.Errorf("Expected the last timer at 2m, got %v", fired)
+	}
+}
+
+func TestFakeClock_FlowTimestamps(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	tester := NewFlowTester(t, newTransferFlow(t), teleflow.WithClock(clock))
+
+	tester.Start()
+	clock.Advance(5 * time.Minute)
+	tester.Send("50")
+
+	snapshot, ok := tester.Harness.Bot.GetUserFlow(tester.UserID, tester.ChatID)
+	if !ok {
+		t.Fatal("Expected an active flow")
+	}
+	if !snapshot.StartedAt.Equal(start) || !snapshot.LastActive.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("Expected timestamps from the fake clock, got started %v, last active %v", snapshot.StartedAt, snapshot.LastActive)
+	}
+}
@@ -0,0 +1,259 @@
+package teleflowtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+// Call is an outgoing Bot API call captured by FakeTelegram. For a new message,
+// MessageID is the ID FakeTelegram assigned to it.
+type Call = teleflow.DryRunMessage
+
+// BotUser is the account FakeTelegram reports from GetMe.
+var BotUser = tgbotapi.User{ID: 1000, IsBot: true, FirstName: "Test Bot", UserName: "teleflowtest_bot"}
+
+// FakeTelegram is an in-memory teleflow.TelegramClient. It records every call the
+// bot makes and answers with successful responses, so bots can be tested without
+// network access. It is safe for concurrent use.
+type FakeTelegram struct {
+	mu          sync.Mutex
+	calls       []Call
+	nextID      int
+	members     map[[2]int64]string
+	failures    map[string]error
+	updatesChan chan tgbotapi.Update
+}
+
+// NewFakeTelegram creates an empty FakeTelegram.
+func NewFakeTelegram() *FakeTelegram {
+	return &FakeTelegram{
+		members:     make(map[[2]int64]string),
+		failures:    make(map[string]error),
+		updatesChan: make(chan tgbotapi.Update, 100),
+	}
+}
+
+// SetChatMember sets the status ("member", "administrator", "creator", ...)
+// reported for a user in a chat. Unknown members are reported as "member".
+func (f *FakeTelegram) SetChatMember(chatID, userID int64, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.members[[2]int64{chatID, userID}] = status
+}
+
+// FailMethod makes every following call of the Bot API method, e.g.
+// "sendMessage", fail with err. Failed calls are not recorded. A nil err makes
+// the method succeed again.
+func (f *FakeTelegram) FailMethod(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.failures, method)
+		return
+	}
+	f.failures[method] = err
+}
+
+// record stores a call, unless its method is configured to fail with an error.
+func (f *FakeTelegram) record(call Call) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failures[call.Method]; err != nil {
+		return err
+	}
+	f.calls = append(f.calls, call)
+	return nil
+}
+
+// Send records c and returns a message with a fresh message ID.
+func (f *FakeTelegram) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	call := teleflow.DescribeChattable(c)
+	f.mu.Lock()
+	if call.MessageID == 0 {
+		f.nextID++
+		call.MessageID = f.nextID
+	}
+	f.mu.Unlock()
+	if err := f.record(call); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	messageID := call.MessageID
+
+	msg := tgbotapi.Message{
+		MessageID: messageID,
+		From:      &BotUser,
+		Chat:      &tgbotapi.Chat{ID: call.ChatID},
+		Text:      call.Text,
+	}
+	if call.Photo {
+		msg.Caption = call.Text
+		msg.Text = ""
+		msg.Photo = []tgbotapi.PhotoSize{{FileID: fmt.Sprintf("photo-%d", messageID), Width: 800, Height: 600}}
+	}
+	return msg, nil
+}
+
+// Request records c and returns a successful response.
+func (f *FakeTelegram) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	if err := f.record(teleflow.DescribeChattable(c)); err != nil {
+		return nil, err
+	}
+
+	if cfg, ok := c.(tgbotapi.GetChatMemberConfig); ok {
+		f.mu.Lock()
+		status, known := f.members[[2]int64{cfg.ChatID, cfg.UserID}]
+		f.mu.Unlock()
+		if !known {
+			status = "member"
+		}
+		member, _ := json.Marshal(tgbotapi.ChatMember{User: &tgbotapi.User{ID: cfg.UserID}, Status: status})
+		return &tgbotapi.APIResponse{Ok: true, Result: member}, nil
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+}
+
+// MakeRequest records a raw method call and returns a successful response.
+func (f *FakeTelegram) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	var chatID int64
+	_, _ = fmt.Sscan(params["chat_id"], &chatID)
+	if err := f.record(Call{Method: endpoint, ChatID: chatID, Text: params["text"]}); err != nil {
+		return nil, err
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+}
+
+// GetUpdatesChan returns the channel fed by Push.
+func (f *FakeTelegram) GetUpdatesChan(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	return f.updatesChan
+}
+
+// GetMe returns BotUser.
+func (f *FakeTelegram) GetMe() (tgbotapi.User, error) {
+	return BotUser, nil
+}
+
+// Push queues an update for a bot started with Start. Most tests call
+// Harness.Dispatch instead, which processes the update synchronously.
+func (f *FakeTelegram) Push(update tgbotapi.Update) {
+	f.updatesChan <- update
+}
+
+// Calls returns all recorded calls in order.
+func (f *FakeTelegram) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Messages returns the recorded calls that sent or edited a message with text
+// or a photo, in order.
+func (f *FakeTelegram) Messages() []Call {
+	var messages []Call
+	for _, call := range f.Calls() {
+		switch call.Method {
+		case "sendMessage", "sendPhoto", "editMessageText", "editMessageMedia":
+			messages = append(messages, call)
+		}
+	}
+	return messages
+}
+
+// LastMessage returns the most recent message, and false if nothing was sent.
+func (f *FakeTelegram) LastMessage() (Call, bool) {
+	messages := f.Messages()
+	if len(messages) == 0 {
+		return Call{}, false
+	}
+	return messages[len(messages)-1], true
+}
+
+// Reset forgets all recorded calls.
+func (f *FakeTelegram) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// ButtonData returns the callback data of the most recently sent inline button
+// with the given label, together with the message carrying it, for use with
+// UpdateBuilder.Callback.
+func (f *FakeTelegram) ButtonData(label string) (data string, message Call, ok bool) {
+	calls := f.Calls()
+	for i := len(calls) - 1; i >= 0; i-- {
+		markup, isInline := inlineMarkup(calls[i].ReplyMarkup)
+		if !isInline {
+			continue
+		}
+		for _, row := range markup.InlineKeyboard {
+			for _, button := range row {
+				if button.Text == label && button.CallbackData != nil {
+					return *button.CallbackData, calls[i], true
+				}
+			}
+		}
+	}
+	return "", Call{}, false
+}
+
+func inlineMarkup(markup interface{}) (tgbotapi.InlineKeyboardMarkup, bool) {
+	switch kb := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		return kb, true
+	case *tgbotapi.InlineKeyboardMarkup:
+		if kb != nil {
+			return *kb, true
+		}
+	}
+	return tgbotapi.InlineKeyboardMarkup{}, false
+}
+
+// AssertSent fails the test unless some message's text contains substr.
+func (f *FakeTelegram) AssertSent(t testing.TB, substr string) {
+	t.Helper()
+	var texts []string
+	for _, msg := range f.Messages() {
+		if strings.Contains(msg.Text, substr) {
+			return
+		}
+		texts = append(texts, fmt.Sprintf("%q", msg.Text))
+	}
+	t.Errorf("no message contains %q; sent: [%s]", substr, strings.Join(texts, ", "))
+}
+
+// AssertNotSent fails the test if any message's text contains substr.
+func (f *FakeTelegram) AssertNotSent(t testing.TB, substr string) {
+	t.Helper()
+	for _, msg := range f.Messages() {
+		if strings.Contains(msg.Text, substr) {
+			t.Errorf("unexpected message containing %q: %q", substr, msg.Text)
+			return
+		}
+	}
+}
+
+// AssertLastText fails the test unless the most recent message's text is want.
+func (f *FakeTelegram) AssertLastText(t testing.TB, want string) {
+	t.Helper()
+	last, ok := f.LastMessage()
+	if !ok {
+		t.Errorf("expected last message %q, but nothing was sent", want)
+		return
+	}
+	if last.Text != want {
+		t.Errorf("expected last message %q, got %q", want, last.Text)
+	}
+}
+
+// AssertMessageCount fails the test unless exactly n messages were sent.
+func (f *FakeTelegram) AssertMessageCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(f.Messages()); got != n {
+		t.Errorf("expected %d messages, got %d", n, got)
+	}
+}
@@ -0,0 +1,249 @@
+package teleflowtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+// startCommand is the command FlowTester registers on its bot to start the
+// flow under test.
+const startCommand = "teleflowtest_start"
+
+// FlowTester runs a scripted conversation through a single flow and checks step
+// transitions, flow data and prompts along the way. Each action processes the
+// update synchronously; prompt expectations look at the messages sent in
+// response to the most recent action. A failed expectation fails the test but
+// lets the script continue.
+//
+// Example:
+//
+//	tester := teleflowtest.NewFlowTester(t, transferFlow)
+//	tester.Start().ExpectStep("amount").ExpectPromptContains("How much")
+//	tester.Send("abc").ExpectStep("amount").ExpectPromptContains("valid number")
+//	tester.Send("50").ExpectStep("confirm").ExpectPromptContains("$50")
+//	tester.Click("✅ Confirm").ExpectCompleted().ExpectData("amount", 50)
+type FlowTester struct {
+	Harness *Harness
+	UserID  int64 // User the conversation is held with, 42 by default
+	ChatID  int64 // Chat of the conversation, the user's private chat by default
+
+	t         testing.TB
+	flowName  string
+	startData map[string]interface{}
+	seen      int                    // Number of messages sent before the last action
+	completed map[string]interface{} // Flow data at completion, nil while not completed
+}
+
+// NewFlowTester creates a bot configured with options, registers flow on it and
+// returns a tester for it. The flow is started with Start.
+func NewFlowTester(t testing.TB, flow *teleflow.Flow, options ...teleflow.BotOption) *FlowTester {
+	t.Helper()
+	ft := &FlowTester{Harness: New(t, options...), UserID: 42, ChatID: 42, t: t, flowName: flow.Name}
+
+	// Register a copy so the caller's flow is left untouched
+	tested := *flow
+	onComplete := flow.OnComplete
+	tested.OnComplete = func(ctx *teleflow.Context) error {
+		if snapshot, ok := ft.Harness.Bot.GetUserFlow(ctx.UserID(), ctx.ChatID()); ok {
+			ft.completed = snapshot.Data
+		}
+		if onComplete != nil {
+			return onComplete(ctx)
+		}
+		return nil
+	}
+	ft.Harness.Bot.RegisterFlow(&tested)
+	ft.Harness.Bot.HandleCommand(startCommand, func(ctx *teleflow.Context, command, args string) error {
+		for key, value := range ft.startData {
+			ctx.Set(key, value)
+		}
+		return ctx.StartFlow(ft.flowName)
+	})
+	return ft
+}
+
+// Start starts the flow.
+func (ft *FlowTester) Start() *FlowTester {
+	return ft.StartWith(nil)
+}
+
+// StartWith starts the flow with data available to it through the context.
+func (ft *FlowTester) StartWith(data map[string]interface{}) *FlowTester {
+	ft.startData = data
+	return ft.dispatch(ft.update().Command(startCommand, ""))
+}
+
+// Send sends a text message.
+func (ft *FlowTester) Send(text string) *FlowTester {
+	return ft.dispatch(ft.update().Text(text))
+}
+
+// Click clicks the most recently sent inline button with the given label.
+func (ft *FlowTester) Click(label string) *FlowTester {
+	ft.t.Helper()
+	data, message, ok := ft.Harness.Telegram.ButtonData(label)
+	if !ok {
+		ft.t.Errorf("teleflowtest: no inline button %q was sent", label)
+		return ft
+	}
+	return ft.dispatch(ft.update().Callback(data, message.MessageID))
+}
+
+// Dispatch processes a custom update, e.g. a photo, in the tester's chat.
+func (ft *FlowTester) Dispatch(update *UpdateBuilder) *FlowTester {
+	return ft.dispatch(update)
+}
+
+func (ft *FlowTester) update() *UpdateBuilder {
+	update := NewUpdate().From(ft.UserID)
+	if ft.ChatID != ft.UserID {
+		update.InGroup(ft.ChatID, "")
+	}
+	return update
+}
+
+func (ft *FlowTester) dispatch(update *UpdateBuilder) *FlowTester {
+	ft.seen = len(ft.Harness.Telegram.Messages())
+	ft.completed = nil
+	ft.Harness.Dispatch(update.Build())
+	return ft
+}
+
+// Prompts returns the messages sent in response to the last action.
+func (ft *FlowTester) Prompts() []Call {
+	messages := ft.Harness.Telegram.Messages()
+	if ft.seen > len(messages) {
+		return nil
+	}
+	return messages[ft.seen:]
+}
+
+// ExpectStep checks that the flow is waiting at step.
+func (ft *FlowTester) ExpectStep(step string) *FlowTester {
+	ft.t.Helper()
+	snapshot, ok := ft.Harness.Bot.GetUserFlow(ft.UserID, ft.ChatID)
+	switch {
+	case !ok:
+		ft.t.Errorf("expected flow %s at step %q, but no flow is active", ft.flowName, step)
+	case snapshot.FlowName != ft.flowName || snapshot.CurrentStep != step:
+		ft.t.Errorf("expected flow %s at step %q, got flow %s at step %q", ft.flowName, step, snapshot.FlowName, snapshot.CurrentStep)
+	}
+	return ft
+}
+
+// ExpectCompleted checks that the last action completed the flow.
+func (ft *FlowTester) ExpectCompleted() *FlowTester {
+	ft.t.Helper()
+	if ft.completed == nil {
+		ft.t.Errorf("expected flow %s to complete", ft.flowName)
+	} else if _, ok := ft.Harness.Bot.GetUserFlow(ft.UserID, ft.ChatID); ok {
+		ft.t.Errorf("expected flow %s to have ended, but a flow is still active", ft.flowName)
+	}
+	return ft
+}
+
+// ExpectEnded checks that no flow is active, whether it completed or was
+// cancelled.
+func (ft *FlowTester) ExpectEnded() *FlowTester {
+	ft.t.Helper()
+	if snapshot, ok := ft.Harness.Bot.GetUserFlow(ft.UserID, ft.ChatID); ok {
+		ft.t.Errorf("expected no active flow, got flow %s at step %q", snapshot.FlowName, snapshot.CurrentStep)
+	}
+	return ft
+}
+
+// ExpectData checks a flow data value with reflect.DeepEqual. After the flow
+// completed, the data it completed with is checked.
+func (ft *FlowTester) ExpectData(key string, want interface{}) *FlowTester {
+	ft.t.Helper()
+	data := ft.completed
+	if data == nil {
+		snapshot, ok := ft.Harness.Bot.GetUserFlow(ft.UserID, ft.ChatID)
+		if !ok {
+			ft.t.Errorf("expected flow data %q, but no flow is active", key)
+			return ft
+		}
+		data = snapshot.Data
+	}
+	got, ok := data[key]
+	if !ok {
+		ft.t.Errorf("expected flow data %q = %v, but it is not set", key, want)
+	} else if !reflect.DeepEqual(got, want) {
+		ft.t.Errorf("expected flow data %q = %v (%T), got %v (%T)", key, want, want, got, got)
+	}
+	return ft
+}
+
+// ExpectPrompt checks that a message with exactly text was sent in response to
+// the last action.
+func (ft *FlowTester) ExpectPrompt(text string) *FlowTester {
+	ft.t.Helper()
+	for _, message := range ft.Prompts() {
+		if message.Text == text {
+			return ft
+		}
+	}
+	ft.t.Errorf("expected prompt %q, got %s", text, ft.describePrompts())
+	return ft
+}
+
+// ExpectPromptContains checks that a message containing substr was sent in
+// response to the last action.
+func (ft *FlowTester) ExpectPromptContains(substr string) *FlowTester {
+	ft.t.Helper()
+	for _, message := range ft.Prompts() {
+		if strings.Contains(message.Text, substr) {
+			return ft
+		}
+	}
+	ft.t.Errorf("expected a prompt containing %q, got %s", substr, ft.describePrompts())
+	return ft
+}
+
+// ExpectNoPrompt checks that nothing was sent in response to the last action.
+func (ft *FlowTester) ExpectNoPrompt() *FlowTester {
+	ft.t.Helper()
+	if prompts := ft.Prompts(); len(prompts) > 0 {
+		ft.t.Errorf("expected no prompt, got %s", ft.describePrompts())
+	}
+	return ft
+}
+
+// ExpectButtons checks the labels of the inline buttons of the last message
+// sent in response to the last action, in order.
+func (ft *FlowTester) ExpectButtons(labels ...string) *FlowTester {
+	ft.t.Helper()
+	prompts := ft.Prompts()
+	if len(prompts) == 0 {
+		ft.t.Errorf("expected buttons %v, but no prompt was sent", labels)
+		return ft
+	}
+	var got []string
+	if markup, ok := inlineMarkup(prompts[len(prompts)-1].ReplyMarkup); ok {
+		for _, row := range markup.InlineKeyboard {
+			for _, button := range row {
+				got = append(got, button.Text)
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, labels) && !(len(got) == 0 && len(labels) == 0) {
+		ft.t.Errorf("expected buttons %v, got %v", labels, got)
+	}
+	return ft
+}
+
+func (ft *FlowTester) describePrompts() string {
+	prompts := ft.Prompts()
+	if len(prompts) == 0 {
+		return "no prompts"
+	}
+	texts := make([]string, len(prompts))
+	for i, prompt := range prompts {
+		texts[i] = fmt.Sprintf("%q", prompt.Text)
+	}
+	return strings.Join(texts, ", ")
+}
@@ -0,0 +1,83 @@
+package teleflowtest
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+func newTransferFlow(t *testing.T) *teleflow.Flow {
+	flow, err := teleflow.NewFlow("transfer").
+		Step("amount").
+		Prompt("How much do you want to send?").
+		Process(func(ctx *teleflow.Context, input string, buttonClick *teleflow.ButtonClick) teleflow.ProcessResult {
+			amount, err := strconv.Atoi(input)
+			if err != nil {
+				return teleflow.Retry().WithPrompt("Please enter a valid number.")
+			}
+			ctx.SetFlowData("amount", amount)
+			return teleflow.NextStep()
+		}).
+		Step("confirm").
+		Prompt(func(ctx *teleflow.Context) string {
+			amount, _ := ctx.GetFlowData("amount")
+			recipient, _ := ctx.GetFlowData("recipient")
+			return fmt.Sprintf("Send $%d to %v?", amount, recipient)
+		}).
+		WithPromptKeyboard(func(ctx *teleflow.Context) *teleflow.PromptKeyboardBuilder {
+			return teleflow.NewPromptKeyboard().ButtonCallback("Confirm", "yes").ButtonCallback("Cancel", "no")
+		}).
+		Process(func(ctx *teleflow.Context, input string, buttonClick *teleflow.ButtonClick) teleflow.ProcessResult {
+			if buttonClick != nil && buttonClick.Data == "yes" {
+				return teleflow.CompleteFlow()
+			}
+			return teleflow.GoToStep("amount")
+		}).
+		OnComplete(func(ctx *teleflow.Context) error {
+			return ctx.SendPromptText("Done!")
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build flow: %v", err)
+	}
+	return flow
+}
+
+func TestFlowTester(t *testing.T) {
+	tester := NewFlowTester(t, newTransferFlow(t))
+
+	tester.StartWith(map[string]interface{}{"recipient": "Bob"}).
+		ExpectStep("amount").
+		ExpectPrompt("How much do you want to send?")
+	tester.Send("abc").ExpectStep("amount").ExpectPromptContains("valid number")
+	tester.Send("50").
+		ExpectStep("confirm").
+		ExpectData("amount", 50).
+		ExpectPromptContains("$50 to Bob").
+		ExpectButtons("Confirm", "Cancel")
+	tester.Click("Confirm").ExpectCompleted().ExpectData("amount", 50).ExpectPrompt("Done!")
+}
+
+func TestFlowTester_ReportsFailures(t *testing.T) {
+	recorder := &failureRecorder{TB: t}
+	tester := NewFlowTester(recorder, newTransferFlow(t))
+
+	tester.Start().ExpectStep("confirm").ExpectPromptContains("$50").ExpectCompleted()
+	if len(recorder.failures) != 3 {
+		t.Errorf("Expected 3 failed expectations, got %d: %v", len(recorder.failures), recorder.failures)
+	}
+}
+
+// failureRecorder collects failures instead of failing the test.
+type failureRecorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *failureRecorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *failureRecorder) Helper() {}
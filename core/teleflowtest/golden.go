@@ -0,0 +1,155 @@
+package teleflowtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden write the
+// snapshots it is given instead of comparing them:
+//
+//	TELEFLOW_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "TELEFLOW_UPDATE_GOLDEN"
+
+// SnapshotPrompt renders prompt with bot for user 42 in their private chat and
+// returns its snapshot. It fails the test if rendering fails.
+func SnapshotPrompt(t testing.TB, bot *teleflow.Bot, prompt *teleflow.PromptConfig) string {
+	t.Helper()
+	messages, err := bot.RenderPrompt(42, 42, prompt)
+	if err != nil {
+		t.Fatalf("teleflowtest: failed to render prompt: %v", err)
+	}
+	return FormatSnapshot(messages)
+}
+
+// SnapshotTemplate renders a registered template with data and returns its
+// snapshot.
+func SnapshotTemplate(t testing.TB, bot *teleflow.Bot, name string, data map[string]interface{}) string {
+	t.Helper()
+	return SnapshotPrompt(t, bot, &teleflow.PromptConfig{Message: "template:" + name, TemplateData: data})
+}
+
+// FormatSnapshot renders calls as normalized text: one block per call with its
+// method and parse mode, the text, and the keyboard one row per line. Callback
+// data is left out because inline buttons get random data on every render.
+//
+//	--- sendMessage HTML
+//	Send <b>$50</b> to Bob?
+//	[✅ Confirm] [❌ Cancel]
+//	[Help → https://example.com/help]
+func FormatSnapshot(calls []Call) string {
+	var sb strings.Builder
+	for _, call := range calls {
+		sb.WriteString("--- " + call.Method)
+		if call.ParseMode != "" {
+			sb.WriteString(" " + call.ParseMode)
+		}
+		if call.Photo {
+			sb.WriteString(" photo")
+		}
+		sb.WriteString("\n")
+		if call.Text != "" {
+			sb.WriteString(call.Text + "\n")
+		}
+		sb.WriteString(snapshotKeyboard(call.ReplyMarkup))
+	}
+	return normalizeSnapshot(sb.String())
+}
+
+func snapshotKeyboard(markup interface{}) string {
+	var sb strings.Builder
+	if inline, ok := inlineMarkup(markup); ok {
+		for _, row := range inline.InlineKeyboard {
+			labels := make([]string, len(row))
+			for i, button := range row {
+				labels[i] = "[" + button.Text
+				if button.URL != nil {
+					labels[i] += " → " + *button.URL
+				}
+				labels[i] += "]"
+			}
+			sb.WriteString(strings.Join(labels, " ") + "\n")
+		}
+		return sb.String()
+	}
+
+	switch kb := markup.(type) {
+	case tgbotapi.ReplyKeyboardMarkup:
+		for _, row := range kb.Keyboard {
+			labels := make([]string, len(row))
+			for i, button := range row {
+				labels[i] = "(" + button.Text + ")"
+			}
+			sb.WriteString(strings.Join(labels, " ") + "\n")
+		}
+	case tgbotapi.ReplyKeyboardRemove:
+		sb.WriteString("(remove keyboard)\n")
+	}
+	return sb.String()
+}
+
+// normalizeSnapshot unifies line endings and drops trailing whitespace, so
+// snapshots compare equal across platforms and editors.
+func normalizeSnapshot(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// AssertGolden compares got with the golden file testdata/<name>.golden and
+// fails the test with both versions if they differ. With UpdateGoldenEnv set,
+// the file is written instead.
+//
+// Example:
+//
+//	teleflowtest.AssertGolden(t, "order_summary", teleflowtest.SnapshotTemplate(t, bot, "order_summary", data))
+func AssertGolden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	got = normalizeSnapshot(got)
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("teleflowtest: failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("teleflowtest: failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("teleflowtest: failed to read golden file (run with %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if normalizeSnapshot(string(want)) != got {
+		t.Errorf("snapshot %s differs from %s\n%s", name, path, diffSnapshots(normalizeSnapshot(string(want)), got))
+	}
+}
+
+// diffSnapshots lists the lines that differ between two snapshots.
+func diffSnapshots(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var sb strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&sb, "line %d:\n- %s\n+ %s\n", i+1, w, g)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,40 @@
+package teleflowtest
+
+import (
+	"strings"
+	"testing"
+
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+func TestSnapshotPrompt_Golden(t *testing.T) {
+	if err := teleflow.AddTemplate("teleflowtest_confirm", "Send <b>${{.amount}}</b> to {{.recipient}}?", teleflow.ParseModeHTML); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+	bot, fake := NewBot(t)
+
+	snapshot := SnapshotPrompt(t, bot, &teleflow.PromptConfig{
+		Message:      "template:teleflowtest_confirm",
+		TemplateData: map[string]interface{}{"amount": 50, "recipient": "Bob"},
+		Keyboard: func(ctx *teleflow.Context) *teleflow.PromptKeyboardBuilder {
+			return teleflow.NewPromptKeyboard().
+				ButtonCallback("✅ Confirm", "yes").ButtonCallback("❌ Cancel", "no").Row().
+				ButtonUrl("Help", "https://example.com/help")
+		},
+	})
+	AssertGolden(t, "confirm_transfer", snapshot)
+
+	if len(fake.Calls()) != 0 {
+		t.Errorf("Expected rendering not to send anything, got %v", fake.Calls())
+	}
+}
+
+func TestFormatSnapshot_Normalizes(t *testing.T) {
+	got := FormatSnapshot([]Call{{Method: "sendMessage", Text: "Hello  \r\nWorld\t"}})
+	if got != "--- sendMessage\nHello\nWorld\n" {
+		t.Errorf("Unexpected snapshot %q", got)
+	}
+	if diff := diffSnapshots("a\nb\n", "a\nc\n"); !strings.Contains(diff, "- b\n+ c") {
+		t.Errorf("Unexpected diff %q", diff)
+	}
+}
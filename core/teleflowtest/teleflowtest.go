@@ -0,0 +1,83 @@
+// Package teleflowtest provides utilities for testing teleflow bots without
+// network access: FakeTelegram records the bot's outgoing calls, UpdateBuilder
+// builds incoming updates, and Harness wires both to a real Bot.
+//
+// Example:
+//
+//	func TestRegistration(t *testing.T) {
+//		h := teleflowtest.New(t)
+//		h.Bot.RegisterFlow(registrationFlow)
+//		h.Bot.HandleCommand("start", startHandler)
+//
+//		h.SendCommand(42, "start", "")
+//		h.Telegram.AssertLastText(t, "What is your name?")
+//		h.SendText(42, "Alice")
+//		h.Click(42, "✅ Confirm")
+//		h.Telegram.AssertSent(t, "Welcome, Alice")
+//	}
+package teleflowtest
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+// NewBot creates a fully wired Bot backed by a new FakeTelegram. It fails the
+// test if the bot cannot be created.
+func NewBot(t testing.TB, options ...teleflow.BotOption) (*teleflow.Bot, *FakeTelegram) {
+	t.Helper()
+	fake := NewFakeTelegram()
+	bot, err := teleflow.NewBotWithClient(fake, options...)
+	if err != nil {
+		t.Fatalf("teleflowtest: failed to create bot: %v", err)
+	}
+	return bot, fake
+}
+
+// Harness drives a Bot backed by FakeTelegram. Updates are processed
+// synchronously, so the bot's replies can be asserted right after each call.
+type Harness struct {
+	Bot      *teleflow.Bot
+	Telegram *FakeTelegram
+
+	t testing.TB
+}
+
+// New creates a Harness with a new Bot configured with options.
+func New(t testing.TB, options ...teleflow.BotOption) *Harness {
+	t.Helper()
+	bot, fake := NewBot(t, options...)
+	return &Harness{Bot: bot, Telegram: fake, t: t}
+}
+
+// Dispatch processes an update, such as one built with NewUpdate.
+func (h *Harness) Dispatch(update tgbotapi.Update) {
+	h.Bot.ProcessExternalUpdate(update)
+}
+
+// SendText processes a text message from the user in their private chat.
+func (h *Harness) SendText(userID int64, text string) {
+	h.Dispatch(NewUpdate().From(userID).Text(text).Build())
+}
+
+// SendCommand processes a command from the user in their private chat.
+func (h *Harness) SendCommand(userID int64, command, args string) {
+	h.Dispatch(NewUpdate().From(userID).Command(command, args).Build())
+}
+
+// Click processes the user clicking the most recently sent inline button with
+// the given label. It fails the test if no such button was sent.
+func (h *Harness) Click(userID int64, label string) {
+	h.t.Helper()
+	data, message, ok := h.Telegram.ButtonData(label)
+	if !ok {
+		h.t.Fatalf("teleflowtest: no inline button %q was sent", label)
+	}
+	update := NewUpdate().From(userID)
+	if message.ChatID != userID {
+		update.InChat(tgbotapi.Chat{ID: message.ChatID, Type: "supergroup"})
+	}
+	h.Dispatch(update.Callback(data, message.MessageID).Build())
+}
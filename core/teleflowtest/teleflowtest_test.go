@@ -0,0 +1,101 @@
+package teleflowtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	teleflow "github.com/kslamph/teleflow/core"
+)
+
+func newRegistrationHarness(t *testing.T) *Harness {
+	h := New(t)
+	flow, err := teleflow.NewFlow("registration").
+		Step("name").
+		Prompt("What is your name?").
+		Process(func(ctx *teleflow.Context, input string, buttonClick *teleflow.ButtonClick) teleflow.ProcessResult {
+			ctx.SetFlowData("name", input)
+			return teleflow.NextStep()
+		}).
+		Step("confirm").
+		Prompt("Is that correct?").
+		WithPromptKeyboard(func(ctx *teleflow.Context) *teleflow.PromptKeyboardBuilder {
+			return teleflow.NewPromptKeyboard().ButtonCallback("Yes", "yes").ButtonCallback("No", "no")
+		}).
+		Process(func(ctx *teleflow.Context, input string, buttonClick *teleflow.ButtonClick) teleflow.ProcessResult {
+			if buttonClick == nil || buttonClick.Data != "yes" {
+				return teleflow.GoToStep("name")
+			}
+			return teleflow.CompleteFlow()
+		}).
+		OnComplete(func(ctx *teleflow.Context) error {
+			name, _ := ctx.GetFlowData("name")
+			return ctx.SendPromptText("Welcome, " + name.(string) + "!")
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build flow: %v", err)
+	}
+	h.Bot.RegisterFlow(flow)
+	h.Bot.HandleCommand("start", func(ctx *teleflow.Context, command, args string) error {
+		return ctx.StartFlow("registration")
+	})
+	return h
+}
+
+func TestHarness_DrivesFlow(t *testing.T) {
+	h := newRegistrationHarness(t)
+
+	h.SendCommand(42, "start", "")
+	h.Telegram.AssertLastText(t, "What is your name?")
+
+	h.SendText(42, "Alice")
+	h.Telegram.AssertLastText(t, "Is that correct?")
+
+	h.Click(42, "Yes")
+	h.Telegram.AssertSent(t, "Welcome, Alice!")
+
+	message, _ := h.Telegram.LastMessage()
+	if message.ChatID != 42 {
+		t.Errorf("Expected reply in chat 42, got %d", message.ChatID)
+	}
+}
+
+func TestFakeTelegram_FailMethod(t *testing.T) {
+	h := newRegistrationHarness(t)
+	h.Telegram.FailMethod("sendMessage", errors.New("boom"))
+
+	h.SendCommand(42, "start", "")
+	h.Telegram.AssertMessageCount(t, 0)
+
+	h.Telegram.FailMethod("sendMessage", nil)
+	h.SendCommand(42, "start", "")
+	h.Telegram.AssertLastText(t, "What is your name?")
+}
+
+func TestUpdateBuilder(t *testing.T) {
+	command := NewUpdate().From(7).InGroup(-100, "Team").Command("start", "ref_1").Build()
+	if !command.Message.IsCommand() || command.Message.Command() != "start" || command.Message.CommandArguments() != "ref_1" {
+		t.Errorf("Expected /start command with arguments, got %+v", command.Message)
+	}
+	if command.Message.Chat.ID != -100 || command.Message.From.ID != 7 {
+		t.Errorf("Expected group chat and sender, got chat %d from %d", command.Message.Chat.ID, command.Message.From.ID)
+	}
+
+	callback := NewUpdate().From(7).Callback("data", 15).Build()
+	if callback.CallbackQuery.Data != "data" || callback.CallbackQuery.Message.MessageID != 15 || callback.CallbackQuery.Message.Chat.ID != 7 {
+		t.Errorf("Unexpected callback query: %+v", callback.CallbackQuery)
+	}
+	if callback.UpdateID <= command.UpdateID {
+		t.Errorf("Expected increasing update IDs, got %d after %d", callback.UpdateID, command.UpdateID)
+	}
+
+	photo := NewUpdate().Photo("file-1", "caption").Build()
+	if largest := photo.Message.Photo[len(photo.Message.Photo)-1]; largest.FileID != "file-1" {
+		t.Errorf("Expected largest photo to use the file ID, got %s", largest.FileID)
+	}
+	document := NewUpdate().Document("file-2", "report.pdf", "application/pdf").Build()
+	if !strings.HasSuffix(document.Message.Document.FileName, ".pdf") {
+		t.Errorf("Unexpected document: %+v", document.Message.Document)
+	}
+}
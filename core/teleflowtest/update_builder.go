@@ -0,0 +1,177 @@
+package teleflowtest
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var lastUpdateID atomic.Int64
+
+// UpdateBuilder builds Telegram updates for tests. By default an update comes
+// from user 1 in their private chat.
+//
+// Example:
+//
+//	update := teleflowtest.NewUpdate().From(42).InGroup(-100123, "Team").Text("hello").Build()
+type UpdateBuilder struct {
+	user      tgbotapi.User
+	chat      tgbotapi.Chat
+	messageID int
+	build     func(b *UpdateBuilder, update *tgbotapi.Update)
+}
+
+// NewUpdate starts an update from user 1 in their private chat.
+func NewUpdate() *UpdateBuilder {
+	return (&UpdateBuilder{}).From(1)
+}
+
+// From sets the sender. Unless InChat or InGroup is used, the update comes from
+// the user's private chat.
+func (b *UpdateBuilder) From(userID int64) *UpdateBuilder {
+	b.user = tgbotapi.User{ID: userID, FirstName: "User", UserName: "user" + itoa(userID)}
+	if b.chat.Type == "" || b.chat.Type == "private" {
+		b.chat = tgbotapi.Chat{ID: userID, Type: "private"}
+	}
+	return b
+}
+
+// WithUser replaces the sender with a fully specified user.
+func (b *UpdateBuilder) WithUser(user tgbotapi.User) *UpdateBuilder {
+	b.From(user.ID)
+	b.user = user
+	return b
+}
+
+// InChat sets the chat the update belongs to.
+func (b *UpdateBuilder) InChat(chat tgbotapi.Chat) *UpdateBuilder {
+	b.chat = chat
+	return b
+}
+
+// InGroup places the update in a supergroup.
+func (b *UpdateBuilder) InGroup(chatID int64, title string) *UpdateBuilder {
+	return b.InChat(tgbotapi.Chat{ID: chatID, Type: "supergroup", Title: title})
+}
+
+// MessageID sets the ID of the user's message. By default it equals the update ID.
+func (b *UpdateBuilder) MessageID(id int) *UpdateBuilder {
+	b.messageID = id
+	return b
+}
+
+// Text makes the update a text message.
+func (b *UpdateBuilder) Text(text string) *UpdateBuilder {
+	b.build = func(b *UpdateBuilder, update *tgbotapi.Update) {
+		update.Message = b.message()
+		update.Message.Text = text
+	}
+	return b
+}
+
+// Command makes the update a command message such as "/start payload".
+func (b *UpdateBuilder) Command(command, args string) *UpdateBuilder {
+	text := "/" + strings.TrimPrefix(command, "/")
+	commandLength := len(text)
+	if args != "" {
+		text += " " + args
+	}
+	b.build = func(b *UpdateBuilder, update *tgbotapi.Update) {
+		update.Message = b.message()
+		update.Message.Text = text
+		update.Message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: commandLength}}
+	}
+	return b
+}
+
+// Callback makes the update an inline button click on the given bot message.
+// FakeTelegram.ButtonData finds the data and message of a button by its label.
+func (b *UpdateBuilder) Callback(data string, messageID int) *UpdateBuilder {
+	b.build = func(b *UpdateBuilder, update *tgbotapi.Update) {
+		chat := b.chat
+		update.CallbackQuery = &tgbotapi.CallbackQuery{
+			ID:   "cb" + itoa(int64(update.UpdateID)),
+			From: &b.user,
+			Data: data,
+			Message: &tgbotapi.Message{
+				MessageID: messageID,
+				From:      &BotUser,
+				Chat:      &chat,
+				Date:      int(time.Now().Unix()),
+			},
+		}
+	}
+	return b
+}
+
+// Photo makes the update a photo message with an optional caption.
+func (b *UpdateBuilder) Photo(fileID, caption string) *UpdateBuilder {
+	b.build = func(b *UpdateBuilder, update *tgbotapi.Update) {
+		update.Message = b.message()
+		update.Message.Caption = caption
+		update.Message.Photo = []tgbotapi.PhotoSize{
+			{FileID: fileID + "-small", FileUniqueID: fileID + "-small", Width: 90, Height: 90},
+			{FileID: fileID, FileUniqueID: fileID, Width: 800, Height: 800},
+		}
+	}
+	return b
+}
+
+// Document makes the update a file message.
+func (b *UpdateBuilder) Document(fileID, fileName, mimeType string) *UpdateBuilder {
+	b.build = func(b *UpdateBuilder, update *tgbotapi.Update) {
+		update.Message = b.message()
+		update.Message.Document = &tgbotapi.Document{FileID: fileID, FileUniqueID: fileID, FileName: fileName, MimeType: mimeType}
+	}
+	return b
+}
+
+// Location makes the update a shared location.
+func (b *UpdateBuilder) Location(latitude, longitude float64) *UpdateBuilder {
+	b.build = func(b *UpdateBuilder, update *tgbotapi.Update) {
+		update.Message = b.message()
+		update.Message.Location = &tgbotapi.Location{Latitude: latitude, Longitude: longitude}
+	}
+	return b
+}
+
+// Contact makes the update a shared contact of the sender.
+func (b *UpdateBuilder) Contact(phoneNumber string) *UpdateBuilder {
+	b.build = func(b *UpdateBuilder, update *tgbotapi.Update) {
+		update.Message = b.message()
+		update.Message.Contact = &tgbotapi.Contact{PhoneNumber: phoneNumber, FirstName: b.user.FirstName, UserID: b.user.ID}
+	}
+	return b
+}
+
+// Build returns the update with a fresh update ID. An update without content is
+// an empty text message.
+func (b *UpdateBuilder) Build() tgbotapi.Update {
+	update := tgbotapi.Update{UpdateID: int(lastUpdateID.Add(1))}
+	build := b.build
+	if build == nil {
+		build = func(b *UpdateBuilder, update *tgbotapi.Update) { update.Message = b.message() }
+	}
+	build(b, &update)
+	if update.Message != nil && update.Message.MessageID == 0 {
+		update.Message.MessageID = update.UpdateID
+	}
+	return update
+}
+
+func (b *UpdateBuilder) message() *tgbotapi.Message {
+	user, chat := b.user, b.chat
+	return &tgbotapi.Message{
+		MessageID: b.messageID,
+		From:      &user,
+		Chat:      &chat,
+		Date:      int(time.Now().Unix()),
+	}
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
@@ -1,6 +1,11 @@
 package teleflow
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	"encoding/json"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
 
 // TelegramClient defines an interface for interacting with the Telegram Bot API.
 // It abstracts the underlying Telegram client, allowing for mock implementations
@@ -23,3 +28,36 @@ type TelegramClient interface {
 	// It returns a User object representing the bot, or an error.
 	GetMe() (tgbotapi.User, error)
 }
+
+// rawRequester is implemented by clients that can call Bot API methods by name,
+// such as *tgbotapi.BotAPI. It gives access to API methods that have no
+// Chattable config in the telegram-bot-api library yet.
+type rawRequester interface {
+	MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+}
+
+// makeRawRequest calls the Bot API method endpoint through client. It fails if
+// the client cannot make raw requests.
+func makeRawRequest(client TelegramClient, endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	requester, ok := client.(rawRequester)
+	if !ok {
+		return nil, fmt.Errorf("telegram client does not support the %s method", endpoint)
+	}
+	return requester.MakeRequest(endpoint, params)
+}
+
+// sendRawMessage calls a Bot API method that returns the message it sent, such
+// as sendMessage, through client.
+func sendRawMessage(client TelegramClient, endpoint string, params tgbotapi.Params) (tgbotapi.Message, error) {
+	resp, err := makeRawRequest(client, endpoint, params)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	var sent tgbotapi.Message
+	if resp != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, &sent); err != nil {
+			return tgbotapi.Message{}, fmt.Errorf("failed to decode sent message: %w", err)
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,86 @@
+package teleflow
+
+// Reserved template data keys that prompts receive in addition to their own
+// TemplateData. A key the prompt's TemplateData sets itself is not replaced.
+const (
+	templateFlowKey = "Flow" // TemplateFlow of the flow whose prompt is rendered
+	templateUserKey = "User" // TemplateUser who triggered the update
+	templateChatKey = "Chat" // TemplateChat the update belongs to
+)
+
+// TemplateFlow is available to templates of flow prompts as .Flow, so they can
+// reference collected answers, e.g. {{.Flow.Data.amount}}, without each step
+// assembling TemplateData.
+type TemplateFlow struct {
+	Name     string
+	Step     string
+	Data     map[string]interface{} // Copy of the flow data
+	Progress FlowProgress
+}
+
+// TemplateUser is available to templates as .User, e.g. {{.User.FirstName}}.
+type TemplateUser struct {
+	ID           int64
+	Username     string
+	FirstName    string
+	LastName     string
+	LanguageCode string
+}
+
+// TemplateChat is available to templates as .Chat, e.g. {{.Chat.Title}}.
+type TemplateChat struct {
+	ID    int64
+	Type  string
+	Title string
+}
+
+// copyFlowData returns a shallow copy of flow data.
+func copyFlowData(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		copied[key] = value
+	}
+	return copied
+}
+
+// withBuiltinTemplateData adds the reserved .Flow, .FlowProgress, .User, .Chat
+// and .Locale values for the context to a prompt's template data. The prompt's
+// map is not modified.
+func withBuiltinTemplateData(data map[string]interface{}, ctx *Context) map[string]interface{} {
+	if ctx == nil {
+		return data
+	}
+
+	builtins := map[string]interface{}{templateLocaleKey: defaultLocale}
+	if ctx.UserID() != 0 {
+		user := TemplateUser{ID: ctx.UserID()}
+		if from := ctx.update.SentFrom(); from != nil {
+			user.Username, user.FirstName, user.LastName, user.LanguageCode = from.UserName, from.FirstName, from.LastName, from.LanguageCode
+		}
+		builtins[templateUserKey] = user
+		if user.LanguageCode != "" {
+			builtins[templateLocaleKey] = user.LanguageCode
+		}
+	}
+	if ctx.ChatID() != 0 {
+		chat := TemplateChat{ID: ctx.ChatID()}
+		if from := ctx.update.FromChat(); from != nil && from.ID == ctx.ChatID() {
+			chat.Type, chat.Title = from.Type, from.Title
+		}
+		builtins[templateChatKey] = chat
+	}
+	if prompt := ctx.flowPrompt; prompt != nil {
+		progress := prompt.flow.progress(prompt.step)
+		builtins[flowProgressKey] = progress
+		builtins[templateFlowKey] = TemplateFlow{Name: prompt.flow.Name, Step: prompt.step, Data: prompt.data, Progress: progress}
+	}
+
+	merged := make(map[string]interface{}, len(data)+len(builtins))
+	for key, value := range builtins {
+		merged[key] = value
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,308 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateIssueKind classifies a problem found by ValidateTemplates.
+type TemplateIssueKind string
+
+const (
+	TemplateIssueSyntax          TemplateIssueKind = "syntax"           // The template no longer parses, e.g. it calls an undefined function
+	TemplateIssueMissingTemplate TemplateIssueKind = "missing_template" // {{template "name"}} refers to a template that is not registered
+	TemplateIssueMissingField    TemplateIssueKind = "missing_field"    // The sample data lacks a field the template uses
+	TemplateIssueRender          TemplateIssueKind = "render"           // Executing the template with the sample data failed
+	TemplateIssueMarkup          TemplateIssueKind = "markup"           // The rendered text is not valid for the template's parse mode
+)
+
+// TemplateIssue is one problem found in a registered template.
+type TemplateIssue struct {
+	Template string
+	Kind     TemplateIssueKind
+	Message  string
+}
+
+func (i TemplateIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Template, i.Kind, i.Message)
+}
+
+// TemplateReport is the result of ValidateTemplates.
+type TemplateReport struct {
+	Checked []string        // Names of the templates that were checked
+	Issues  []TemplateIssue // Problems found, ordered by template name
+}
+
+// OK reports whether no issues were found.
+func (r *TemplateReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns an error listing all issues, or nil if there are none.
+func (r *TemplateReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	lines := make([]string, len(r.Issues))
+	for i, issue := range r.Issues {
+		lines[i] = issue.String()
+	}
+	return fmt.Errorf("%d template issue(s):\n%s", len(r.Issues), strings.Join(lines, "\n"))
+}
+
+// ValidateTemplates checks every registered template and returns a report, so a
+// bot can refuse to start with broken templates instead of failing on a user's
+// message. Each template is checked for calls to undefined functions and
+// references to unregistered partials. Templates with an entry in samples are
+// also rendered with that data: fields missing from it are reported, and the
+// output is checked for unbalanced HTML tags or invalid Markdown escaping.
+//
+// Example:
+//
+//	report := bot.ValidateTemplates(map[string]map[string]interface{}{
+//		"balance": {"name": "Ann", "balance": 10},
+//	})
+//	if err := report.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+func (b *Bot) ValidateTemplates(samples map[string]map[string]interface{}) *TemplateReport {
+	return validateTemplates(b.templateManager, samples)
+}
+
+func validateTemplates(tm TemplateManager, samples map[string]map[string]interface{}) *TemplateReport {
+	report := &TemplateReport{}
+	names := tm.ListTemplates()
+	sort.Strings(names)
+
+	for _, name := range names {
+		info := tm.GetTemplateInfo(name)
+		if info == nil || info.Template == nil || info.Template.Tree == nil {
+			continue
+		}
+		report.Checked = append(report.Checked, name)
+		add := func(kind TemplateIssueKind, format string, args ...interface{}) {
+			report.Issues = append(report.Issues, TemplateIssue{Template: name, Kind: kind, Message: fmt.Sprintf(format, args...)})
+		}
+
+		// Parsing the tree again catches functions that are not available for the parse mode
+		if _, err := template.New(name).Funcs(getTemplateFuncs(info.ParseMode)).Parse(info.Template.Tree.Root.String()); err != nil {
+			add(TemplateIssueSyntax, "%v", err)
+		}
+
+		defined := make(map[string]bool)
+		for _, tmpl := range info.Template.Templates() {
+			defined[tmpl.Name()] = true
+		}
+		for _, partial := range templateReferences(info.Template.Tree.Root) {
+			if !defined[partial] && !tm.HasTemplate(partial) {
+				add(TemplateIssueMissingTemplate, "template %q is not registered", partial)
+			}
+		}
+
+		data, ok := samples[name]
+		if !ok {
+			continue
+		}
+		text, err := renderStrict(tm, info, data)
+		if err != nil {
+			if errors.Is(err, errNoValue) {
+				add(TemplateIssueMissingField, "sample data lacks a field the template uses")
+			} else if field, missing := missingField(err); missing {
+				add(TemplateIssueMissingField, "sample data has no field %q", field)
+			} else {
+				add(TemplateIssueRender, "%v", err)
+			}
+			continue
+		}
+		if err := checkRenderedMarkup(text, info.ParseMode); err != nil {
+			add(TemplateIssueMarkup, "%v", err)
+		}
+	}
+	return report
+}
+
+// templateReferences returns the names of the templates invoked below node.
+func templateReferences(node parse.Node) []string {
+	var names []string
+	var walk func(parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.TemplateNode:
+			names = append(names, n.Name)
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(node)
+	return names
+}
+
+// renderStrict renders a template and fails on fields missing from data. The
+// built-in manager supports this directly; other managers are rendered normally
+// and checked for the "<no value>" placeholder instead.
+func renderStrict(tm TemplateManager, info *TemplateInfo, data map[string]interface{}) (string, error) {
+	if builtin, ok := tm.(*templateManager); ok {
+		exec, err := builtin.executable(info)
+		if err != nil {
+			return "", err
+		}
+		strict, err := exec.Clone()
+		if err != nil {
+			return "", err
+		}
+		var buf strings.Builder
+		err = strict.Option("missingkey=error").ExecuteTemplate(&buf, info.Name, data)
+		return buf.String(), err
+	}
+
+	text, _, err := tm.RenderTemplate(info.Name, data)
+	if err == nil && strings.Contains(text, "<no value>") {
+		return text, errNoValue
+	}
+	return text, err
+}
+
+// errNoValue reports rendered text that contains the placeholder for a missing field.
+var errNoValue = errors.New("rendered text contains <no value>")
+
+// missingField extracts the field name from a missingkey=error execution error.
+func missingField(err error) (string, bool) {
+	const marker = `map has no entry for key "`
+	msg := err.Error()
+	start := strings.Index(msg, marker)
+	if start < 0 {
+		return "", false
+	}
+	rest := msg[start+len(marker):]
+	end := strings.Index(rest, `"`)
+	if end < 0 {
+		return "", false
+	}
+	return rest[:end], true
+}
+
+// checkRenderedMarkup validates rendered text against its parse mode.
+func checkRenderedMarkup(text string, parseMode ParseMode) error {
+	switch parseMode {
+	case ParseModeHTML:
+		return validateHTML(text)
+	case ParseModeMarkdown:
+		return validateMarkdown(text)
+	case ParseModeMarkdownV2:
+		return checkMarkdownV2(text)
+	}
+	return nil
+}
+
+// checkMarkdownV2 reports the first place where text breaks Telegram's MarkdownV2
+// rules: reserved characters outside of entities must be escaped with a
+// backslash, and every entity must be closed.
+func checkMarkdownV2(text string) error {
+	runes := []rune(text)
+	open := map[string]bool{}
+	linkDepth := 0
+	lineStart := true
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		atLineStart := lineStart
+		lineStart = r == '\n'
+
+		switch r {
+		case '\\':
+			if i+1 == len(runes) {
+				return fmt.Errorf("trailing backslash")
+			}
+			i++
+		case '`':
+			fence := "`"
+			if i+2 < len(runes) && runes[i+1] == '`' && runes[i+2] == '`' {
+				fence = "```"
+			}
+			end := indexRunes(runes, i+len(fence), fence)
+			if end < 0 {
+				return fmt.Errorf("unclosed code entity at position %d", i)
+			}
+			i = end + len(fence) - 1
+		case '*', '~':
+			open[string(r)] = !open[string(r)]
+		case '_':
+			marker := "_"
+			if i+1 < len(runes) && runes[i+1] == '_' {
+				marker = "__"
+				i++
+			}
+			open[marker] = !open[marker]
+		case '|':
+			if i+1 >= len(runes) || runes[i+1] != '|' {
+				return fmt.Errorf("unescaped '|' at position %d", i)
+			}
+			open["||"] = !open["||"]
+			i++
+		case '[':
+			linkDepth++
+		case ']':
+			if linkDepth == 0 {
+				return fmt.Errorf("unescaped ']' at position %d", i)
+			}
+			linkDepth--
+			if i+1 < len(runes) && runes[i+1] == '(' {
+				end := indexRunes(runes, i+2, ")")
+				if end < 0 {
+					return fmt.Errorf("unclosed link URL at position %d", i+1)
+				}
+				i = end
+			}
+		case '>':
+			if !atLineStart {
+				return fmt.Errorf("unescaped '>' at position %d", i)
+			}
+		case '#', '+', '-', '=', '{', '}', '.', '!', '(', ')':
+			return fmt.Errorf("unescaped '%c' at position %d", r, i)
+		}
+	}
+
+	if linkDepth > 0 {
+		return fmt.Errorf("unclosed '['")
+	}
+	for _, marker := range []string{"*", "_", "__", "~", "||"} {
+		if open[marker] {
+			return fmt.Errorf("unclosed '%s' entity", marker)
+		}
+	}
+	return nil
+}
+
+// indexRunes returns the index of the first unescaped occurrence of sep in
+// runes at or after from, or -1.
+func indexRunes(runes []rune, from int, sep string) int {
+	target := []rune(sep)
+	for i := from; i+len(target) <= len(runes); i++ {
+		if runes[i] == '\\' {
+			i++
+			continue
+		}
+		if string(runes[i:i+len(target)]) == sep {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,187 @@
+package teleflow
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// templateLocaleKey holds the locale templates pass to the format functions,
+// e.g. {{formatNumber .Locale .amount}}. It defaults to the Telegram language
+// code of the user; setting "Locale" in TemplateData overrides it.
+const templateLocaleKey = "Locale"
+
+// defaultLocale is used when the user has no language code.
+const defaultLocale = "en"
+
+// Date styles accepted by formatDate. Any other style is used as a Go time
+// layout as is.
+const (
+	DateStyleDate     = "date"     // Date only, e.g. 31.12.2024 for de
+	DateStyleTime     = "time"     // Time only, e.g. 3:04 PM for en
+	DateStyleDateTime = "datetime" // Date and time
+)
+
+// dateLayouts are the numeric date and time layouts of a locale.
+type dateLayouts struct {
+	date string
+	time string
+}
+
+var defaultDateLayouts = dateLayouts{date: "2006-01-02", time: "15:04"}
+
+// localeDateLayouts maps base languages to their usual numeric layouts. Locales
+// that are missing fall back to ISO 8601.
+var localeDateLayouts = map[string]dateLayouts{
+	"en": {date: "02/01/2006", time: "15:04"},
+	"de": {date: "02.01.2006", time: "15:04"},
+	"ru": {date: "02.01.2006", time: "15:04"},
+	"uk": {date: "02.01.2006", time: "15:04"},
+	"pl": {date: "02.01.2006", time: "15:04"},
+	"cs": {date: "02.01.2006", time: "15:04"},
+	"tr": {date: "02.01.2006", time: "15:04"},
+	"fi": {date: "2.1.2006", time: "15.04"},
+	"nb": {date: "02.01.2006", time: "15:04"},
+	"da": {date: "02.01.2006", time: "15.04"},
+	"fr": {date: "02/01/2006", time: "15:04"},
+	"es": {date: "02/01/2006", time: "15:04"},
+	"it": {date: "02/01/2006", time: "15:04"},
+	"pt": {date: "02/01/2006", time: "15:04"},
+	"nl": {date: "02-01-2006", time: "15:04"},
+	"ja": {date: "2006/01/02", time: "15:04"},
+	"zh": {date: "2006/01/02", time: "15:04"},
+	"ko": {date: "2006. 01. 02.", time: "15:04"},
+}
+
+// localeTemplateFuncs returns the locale-aware formatting functions available to
+// all templates. Each takes the locale as its first argument.
+func localeTemplateFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"formatNumber":  formatNumber,
+		"formatPercent": formatPercent,
+		"formatDate":    formatDate,
+		"formatMoney":   formatMoney,
+	}
+}
+
+// localeTag parses a BCP 47 or Telegram language code, falling back to English.
+func localeTag(locale string) language.Tag {
+	tag, err := language.Parse(locale)
+	if err != nil || locale == "" {
+		return language.English
+	}
+	return tag
+}
+
+// formatNumber formats value with the grouping and decimal separators of the
+// locale, e.g. 1,234.5 for en and 1.234,5 for de. An optional argument fixes the
+// number of fraction digits.
+func formatNumber(locale string, value interface{}, decimals ...int) string {
+	num, ok := templateNumber(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	var opts []number.Option
+	if len(decimals) > 0 {
+		opts = append(opts, number.MinFractionDigits(decimals[0]), number.MaxFractionDigits(decimals[0]))
+	}
+	return message.NewPrinter(localeTag(locale)).Sprint(number.Decimal(num, opts...))
+}
+
+// formatPercent formats a ratio as a percentage in the locale, e.g. 0.25 as 25%
+// for en and 25 % for de. An optional argument fixes the number of fraction
+// digits.
+func formatPercent(locale string, value interface{}, decimals ...int) string {
+	num, ok := templateNumber(value)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	var opts []number.Option
+	if len(decimals) > 0 {
+		opts = append(opts, number.MinFractionDigits(decimals[0]), number.MaxFractionDigits(decimals[0]))
+	}
+	return message.NewPrinter(localeTag(locale)).Sprint(number.Percent(num, opts...))
+}
+
+// formatDate formats a time.Time in the numeric layout of the locale. style is
+// DateStyleDate (the default), DateStyleTime, DateStyleDateTime, or a Go layout.
+func formatDate(locale string, value interface{}, style ...string) string {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		t = *v
+	default:
+		return fmt.Sprint(value)
+	}
+
+	layouts := dateLayoutsFor(locale)
+	layout := layouts.date
+	if len(style) > 0 {
+		switch style[0] {
+		case DateStyleDate:
+		case DateStyleTime:
+			layout = layouts.time
+		case DateStyleDateTime:
+			layout = layouts.date + " " + layouts.time
+		default:
+			layout = style[0]
+		}
+	}
+	return t.Format(layout)
+}
+
+// dateLayoutsFor returns the layouts of the locale's base language. English
+// without a region other than the US uses day-first dates and a 24-hour clock;
+// US English and bare "en", Telegram's most common code, use month-first dates.
+func dateLayoutsFor(locale string) dateLayouts {
+	tag := localeTag(locale)
+	base, _ := tag.Base()
+	if base.String() == "en" {
+		region, confidence := tag.Region()
+		if confidence == language.Exact && region.String() != "US" {
+			return localeDateLayouts["en"]
+		}
+		return dateLayouts{date: "01/02/2006", time: "3:04 PM"}
+	}
+	if layouts, ok := localeDateLayouts[base.String()]; ok {
+		return layouts
+	}
+	return defaultDateLayouts
+}
+
+// templateNumber converts the numeric values templates usually receive,
+// including numeric strings from user input, to float64.
+func templateNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
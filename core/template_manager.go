@@ -3,10 +3,11 @@ package teleflow
 import (
 	"encoding/json"
 	"fmt"
-	"html"
 	"log"
 	"strings"
+	"sync"
 	"text/template"
+	"text/template/parse"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -47,16 +48,92 @@ type templateManager struct {
 	templates *template.Template
 
 	registry map[string]*TemplateInfo
+	layouts  map[string]*template.Template
+
+	compiled map[string]*template.Template // Executable sets with partials and layout, built on first render
+	variants map[string]*templateVariants  // Templates rendered from one of several texts
+	mu       sync.RWMutex                  // Guards registry, layouts, compiled and variants
 }
 
+// layoutContent is the name under which a layout includes the template it wraps.
+const layoutContent = "content"
+
 func newTemplateManager() *templateManager {
 	return &templateManager{
 		templates: template.New("templateManager").Funcs(getAllTemplateFuncs()),
 		registry:  make(map[string]*TemplateInfo),
+		layouts:   make(map[string]*template.Template),
+		compiled:  make(map[string]*template.Template),
+		variants:  make(map[string]*templateVariants),
 	}
 }
 
 func (tm *templateManager) AddTemplate(name, templateText string, parseMode ParseMode) error {
+	return tm.addTemplate(name, "", templateText, parseMode)
+}
+
+// AddLayout registers a layout that wraps templates added with AddTemplateWithLayout.
+// The layout includes the wrapped template with {{template "content" .}} and sees the
+// same data, so it can hold shared headers, footers and disclaimers.
+func (tm *templateManager) AddLayout(name, layoutText string) error {
+	if name == "" {
+		return fmt.Errorf("layout name cannot be empty")
+	}
+
+	tmpl, err := template.New(name).Funcs(getAllTemplateFuncs()).Parse(layoutText)
+	if err != nil {
+		return fmt.Errorf("failed to parse layout '%s': %w", name, err)
+	}
+	if tmpl.Tree == nil || !includesTemplate(tmpl.Tree.Root, layoutContent) {
+		return fmt.Errorf("layout '%s' must include {{template \"%s\" .}}", name, layoutContent)
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.layouts[name] = tmpl
+	clear(tm.compiled)
+	return nil
+}
+
+// includesTemplate reports whether the parse tree below node invokes the named template.
+func includesTemplate(node parse.Node, name string) bool {
+	switch n := node.(type) {
+	case *parse.TemplateNode:
+		return n.Name == name
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if includesTemplate(child, name) {
+				return true
+			}
+		}
+	case *parse.IfNode:
+		return includesTemplate(n.List, name) || includesTemplate(n.ElseList, name)
+	case *parse.RangeNode:
+		return includesTemplate(n.List, name) || includesTemplate(n.ElseList, name)
+	case *parse.WithNode:
+		return includesTemplate(n.List, name) || includesTemplate(n.ElseList, name)
+	}
+	return false
+}
+
+// AddTemplateWithLayout registers a template that is rendered inside the named layout.
+func (tm *templateManager) AddTemplateWithLayout(name, layout, templateText string, parseMode ParseMode) error {
+	tm.mu.RLock()
+	layoutTmpl, ok := tm.layouts[layout]
+	tm.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("layout '%s' not found", layout)
+	}
+	if err := validateTemplateIntegrity(layoutTmpl.Tree.Root.String(), parseMode); err != nil {
+		return fmt.Errorf("layout '%s' does not suit parse mode %s: %w", layout, parseMode, err)
+	}
+	return tm.addTemplate(name, layout, templateText, parseMode)
+}
+
+func (tm *templateManager) addTemplate(name, layout, templateText string, parseMode ParseMode) error {
 	if name == "" {
 		return fmt.Errorf("template name cannot be empty")
 	}
@@ -69,6 +146,10 @@ func (tm *templateManager) AddTemplate(name, templateText string, parseMode Pars
 		return fmt.Errorf("invalid parse mode: %w", err)
 	}
 
+	if parseMode == ParseModeSimpleMarkdown {
+		templateText, parseMode = markdownToHTML(templateText), ParseModeHTML
+	}
+
 	if err := validateTemplateIntegrity(templateText, parseMode); err != nil {
 		return fmt.Errorf("template integrity validation failed for '%s': %w", name, err)
 	}
@@ -78,29 +159,129 @@ func (tm *templateManager) AddTemplate(name, templateText string, parseMode Pars
 		return fmt.Errorf("failed to parse template '%s': %w", name, err)
 	}
 
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if err := tm.checkDefines_nolock(name, tmpl); err != nil {
+		return err
+	}
+
 	_, err = tm.templates.AddParseTree(name, tmpl.Tree)
 	if err != nil {
 		return fmt.Errorf("failed to add template '%s': %w", name, err)
 	}
 
+	delete(tm.variants, name)
 	tm.registry[name] = &TemplateInfo{
 		Name:      name,
 		ParseMode: parseMode,
+		Layout:    layout,
 		Template:  tmpl,
 	}
+	// Any template may include the new one as a partial
+	clear(tm.compiled)
+
+	return nil
+}
 
+// checkDefines_nolock rejects a template named name whose {{define}} blocks, or
+// whose own name, clash with a template or {{define}} block of another
+// registered template. Every registered template is a partial of every other,
+// so a name must resolve to one definition. The caller must hold tm.mu.
+func (tm *templateManager) checkDefines_nolock(name string, tmpl *template.Template) error {
+	owners := make(map[string]string)
+	for otherName, other := range tm.registry {
+		if otherName == name {
+			continue // Re-registering a template replaces its blocks
+		}
+		for _, defined := range other.Template.Templates() {
+			if defined.Tree != nil {
+				owners[defined.Name()] = otherName
+			}
+		}
+	}
+	for _, defined := range tmpl.Templates() {
+		if defined.Tree == nil {
+			continue
+		}
+		if owner, ok := owners[defined.Name()]; ok {
+			return fmt.Errorf("template '%s' defines '%s', which template '%s' already defines", name, defined.Name(), owner)
+		}
+	}
 	return nil
 }
 
+// executable returns the template to execute for info: its layout, if any, with the
+// template as "content", and every registered template available as a partial
+// through {{template "name" .}}. Escaping follows the parse mode of info.
+func (tm *templateManager) executable(info *TemplateInfo) (*template.Template, error) {
+	tm.mu.RLock()
+	compiled, ok := tm.compiled[info.Name]
+	tm.mu.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	root := info.Template
+	if info.Layout != "" {
+		layout, ok := tm.layouts[info.Layout]
+		if !ok {
+			return nil, fmt.Errorf("layout '%s' not found for template '%s'", info.Layout, info.Name)
+		}
+		root = layout
+	}
+
+	exec := template.New(info.Name).Funcs(getTemplateFuncs(info.ParseMode))
+	add := func(name string, tmpl *template.Template) error {
+		if tmpl.Tree == nil {
+			return nil
+		}
+		_, err := exec.AddParseTree(name, tmpl.Tree)
+		return err
+	}
+	for _, other := range tm.registry {
+		for _, defined := range other.Template.Templates() {
+			if err := add(defined.Name(), defined); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, defined := range root.Templates() {
+		if err := add(defined.Name(), defined); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(info.Name, root); err != nil {
+		return nil, err
+	}
+	if info.Layout != "" {
+		if err := add(layoutContent, info.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	tm.compiled[info.Name] = exec
+	return exec, nil
+}
+
 func (tm *templateManager) HasTemplate(name string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.templates.Lookup(name) != nil
 }
 
 func (tm *templateManager) GetTemplateInfo(name string) *TemplateInfo {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.registry[name]
 }
 
 func (tm *templateManager) ListTemplates() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	var names []string
 	for _, tmpl := range tm.templates.Templates() {
 		if tmpl.Name() != "templateManager" {
@@ -111,8 +292,9 @@ func (tm *templateManager) ListTemplates() []string {
 }
 
 func (tm *templateManager) RenderTemplate(name string, data map[string]interface{}) (string, ParseMode, error) {
+	name = tm.variantFor(name)
 
-	info := tm.registry[name]
+	info := tm.GetTemplateInfo(name)
 	if info == nil {
 		return "", ParseModeNone, fmt.Errorf("template info not found for '%s'", name)
 	}
@@ -120,7 +302,11 @@ func (tm *templateManager) RenderTemplate(name string, data map[string]interface
 	if info.Template == nil {
 		return "", ParseModeNone, fmt.Errorf("parsed template not found in registry for '%s'", name)
 	}
-	tmplToExecute := info.Template // Use the template from the registry, not from tm.templates.Lookup(name)
+	// Execute a set built from the registry, so partials and the layout resolve
+	tmplToExecute, err := tm.executable(info)
+	if err != nil {
+		return "", ParseModeNone, fmt.Errorf("failed to prepare template '%s': %w", name, err)
+	}
 
 	mergedData := tm.mergeTemplateData(data, nil)
 
@@ -132,7 +318,7 @@ func (tm *templateManager) RenderTemplate(name string, data map[string]interface
 	}
 	log.Printf("DEBUG: Rendering template '%s' with ParseMode '%s' and data: %s", name, info.ParseMode, string(jsonData))
 
-	err := tmplToExecute.Execute(&buf, mergedData) // Execute the specific template instance from the registry
+	err = tmplToExecute.ExecuteTemplate(&buf, name, mergedData)
 	if err != nil {
 		log.Printf("ERROR: Failed to execute template '%s'. Data: %s. Error: %v", name, string(jsonData), err)
 		return "", ParseModeNone, fmt.Errorf("failed to render template '%s': %w", name, err)
@@ -160,14 +346,15 @@ func (tm *templateManager) mergeTemplateData(templateData map[string]interface{}
 
 func getAllTemplateFuncs() template.FuncMap {
 	titleCaser := cases.Title(language.Und)
-	return template.FuncMap{
+	funcs := template.FuncMap{
 		"escape": func(s string) string {
 
-			return html.EscapeString(s)
+			return EscapeHTML(s)
 		},
 		"safe": func(s string) string {
 			return s
 		},
+		"sanitize": SanitizeHTML,
 		"title": func(s string) string {
 			return titleCaser.String(s)
 		},
@@ -178,6 +365,13 @@ func getAllTemplateFuncs() template.FuncMap {
 			return strings.ToLower(s)
 		},
 	}
+	for name, fn := range localeTemplateFuncs() {
+		funcs[name] = fn
+	}
+	for name, fn := range formattingTemplateFuncs(ParseModeHTML) {
+		funcs[name] = fn
+	}
+	return funcs
 }
 
 func getTemplateFuncs(parseMode ParseMode) template.FuncMap {
@@ -188,13 +382,13 @@ func getTemplateFuncs(parseMode ParseMode) template.FuncMap {
 			var escapedS string
 			switch parseMode {
 			case ParseModeHTML:
-				escapedS = html.EscapeString(s)
+				escapedS = EscapeHTML(s)
 			case ParseModeMarkdown:
 				escapedS = escapeMarkdown(s)
 			case ParseModeMarkdownV2:
-				escapedS = escapeMarkdownV2(s)
+				escapedS = EscapeMarkdownV2(s)
 				// Log specifically for MarkdownV2
-				log.Printf("DEBUG: escapeMarkdownV2 called with ParseMode '%s'. Input: '%s', Output: '%s'", parseMode, originalS, escapedS)
+				log.Printf("DEBUG: EscapeMarkdownV2 called with ParseMode '%s'. Input: '%s', Output: '%s'", parseMode, originalS, escapedS)
 			default:
 				escapedS = s
 			}
@@ -204,6 +398,7 @@ func getTemplateFuncs(parseMode ParseMode) template.FuncMap {
 
 			return s
 		},
+		"sanitize": SanitizeHTML,
 		"title": func(s string) string {
 			return titleCaser.String(s)
 		},
@@ -214,6 +409,12 @@ func getTemplateFuncs(parseMode ParseMode) template.FuncMap {
 			return strings.ToLower(s)
 		},
 	}
+	for name, fn := range localeTemplateFuncs() {
+		baseFuncs[name] = fn
+	}
+	for name, fn := range formattingTemplateFuncs(parseMode) {
+		baseFuncs[name] = fn
+	}
 
 	return baseFuncs
 }
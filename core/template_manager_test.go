@@ -0,0 +1,196 @@
+package teleflow
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateManager_Partials(t *testing.T) {
+	tm := newTemplateManager()
+	if err := tm.AddTemplate("receipt", `Paid {{template "amount" .}}`, ParseModeNone); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+
+	// The partial is missing until it is registered
+	if _, _, err := tm.RenderTemplate("receipt", map[string]interface{}{"sum": 5}); err == nil {
+		t.Error("Expected an error for a missing partial")
+	}
+
+	if err := tm.AddTemplate("amount", "${{.sum}}", ParseModeNone); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+	text, _, err := tm.RenderTemplate("receipt", map[string]interface{}{"sum": 5})
+	if err != nil || text != "Paid $5" {
+		t.Errorf("Expected 'Paid $5', got %q (err %v)", text, err)
+	}
+}
+
+func TestTemplateManager_RejectsDuplicateDefines(t *testing.T) {
+	tm := newTemplateManager()
+	if err := tm.AddTemplate("receipt", `{{define "footer"}}Thanks{{end}}Paid{{template "footer"}}`, ParseModeNone); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+
+	if err := tm.AddTemplate("invoice", `{{define "footer"}}Due{{end}}Owed`, ParseModeNone); err == nil || !strings.Contains(err.Error(), "footer") {
+		t.Errorf("Expected a duplicate define to be rejected, got %v", err)
+	}
+	if err := tm.AddTemplate("footer", "Bye", ParseModeNone); err == nil {
+		t.Error("Expected a template named like another template's define to be rejected")
+	}
+	if err := tm.AddTemplate("receipt", `{{define "footer"}}Thank you{{end}}Paid{{template "footer"}}`, ParseModeNone); err != nil {
+		t.Errorf("Expected re-registering a template to replace its defines, got %v", err)
+	}
+
+	text, _, err := tm.RenderTemplate("receipt", nil)
+	if err != nil || text != "PaidThank you" {
+		t.Errorf("Expected 'PaidThank you', got %q (err %v)", text, err)
+	}
+}
+
+func TestTemplateManager_Layouts(t *testing.T) {
+	tm := newTemplateManager()
+	_ = tm.AddTemplate("disclaimer", "<i>Not financial advice.</i>", ParseModeHTML)
+	if err := tm.AddLayout("branded", "<b>MyBank</b>\n{{template \"content\" .}}\n{{template \"disclaimer\" .}}"); err != nil {
+		t.Fatalf("AddLayout failed: %v", err)
+	}
+	if err := tm.AddTemplateWithLayout("balance", "branded", "Hi {{.name | escape}}", ParseModeHTML); err != nil {
+		t.Fatalf("AddTemplateWithLayout failed: %v", err)
+	}
+
+	text, mode, err := tm.RenderTemplate("balance", map[string]interface{}{"name": "<Ann>"})
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	want := "<b>MyBank</b>\nHi &lt;Ann&gt;\n<i>Not financial advice.</i>"
+	if text != want || mode != ParseModeHTML {
+		t.Errorf("Expected %q in HTML, got %q in %q", want, text, mode)
+	}
+	if info := tm.GetTemplateInfo("balance"); info.Layout != "branded" {
+		t.Errorf("Expected layout to be recorded, got %q", info.Layout)
+	}
+}
+
+func TestTemplateManager_LayoutErrors(t *testing.T) {
+	tm := newTemplateManager()
+	if err := tm.AddLayout("plain", "no content here"); err == nil || !strings.Contains(err.Error(), "content") {
+		t.Errorf("Expected an error for a layout without content, got %v", err)
+	}
+	if err := tm.AddLayout("nested", `{{if .x}}{{template "content" .}}{{end}}`); err != nil {
+		t.Errorf("Expected content inside a condition to be accepted, got %v", err)
+	}
+	if err := tm.AddTemplateWithLayout("page", "missing", "text", ParseModeNone); err == nil {
+		t.Error("Expected an error for an unknown layout")
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	tm := newTemplateManager()
+	bot.templateManager = tm
+
+	_ = tm.AddTemplate("greeting", "Hello <b>{{.name}}</b>", ParseModeHTML)
+	_ = tm.AddTemplate("receipt", `Paid {{template "amount" .}}`, ParseModeNone)
+	_ = tm.AddTemplate("promo", "*Sale* ends {{.date}}", ParseModeMarkdownV2)
+
+	report := bot.ValidateTemplates(map[string]map[string]interface{}{
+		"greeting": {"name": "<i>Ann"},
+		"promo":    {"when": "soon"},
+	})
+
+	if len(report.Checked) != 3 {
+		t.Errorf("Expected 3 checked templates, got %v", report.Checked)
+	}
+	kinds := make(map[string]TemplateIssueKind)
+	for _, issue := range report.Issues {
+		kinds[issue.Template] = issue.Kind
+	}
+	if kinds["greeting"] != TemplateIssueMarkup {
+		t.Errorf("Expected unbalanced HTML in greeting, got %v", report.Issues)
+	}
+	if kinds["receipt"] != TemplateIssueMissingTemplate {
+		t.Errorf("Expected missing partial in receipt, got %v", report.Issues)
+	}
+	if kinds["promo"] != TemplateIssueMissingField || !strings.Contains(report.Err().Error(), `"date"`) {
+		t.Errorf("Expected missing field date in promo, got %v", report.Issues)
+	}
+
+	_ = tm.AddTemplate("amount", "5", ParseModeNone)
+	report = bot.ValidateTemplates(map[string]map[string]interface{}{
+		"greeting": {"name": "Ann"},
+		"promo":    {"date": "Friday"},
+	})
+	if !report.OK() || report.Err() != nil {
+		t.Errorf("Expected no issues, got %v", report.Issues)
+	}
+}
+
+func TestCheckMarkdownV2(t *testing.T) {
+	valid := []string{
+		"*bold* _italic_ __underline__ ~strike~ ||spoiler||",
+		"Price: 10\\.50\\!",
+		"[docs](https://example.com/a_(b\\))",
+		"`a.b-c` and ```\ncode.block!\n```",
+		"> quoted line",
+	}
+	for _, text := range valid {
+		if err := checkMarkdownV2(text); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", text, err)
+		}
+	}
+
+	invalid := []string{"Price: 10.50", "*bold", "a > b", "[link", "x|y", "`code", "end\\"}
+	for _, text := range invalid {
+		if err := checkMarkdownV2(text); err == nil {
+			t.Errorf("Expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestTemplateLocaleFuncs(t *testing.T) {
+	when := time.Date(2024, time.December, 31, 15, 4, 0, 0, time.UTC)
+	tests := []struct {
+		locale string
+		text   string
+		want   string
+	}{
+		{"en", `{{formatNumber .Locale 1234567.5}}`, "1,234,567.5"},
+		{"de", `{{formatNumber .Locale 1234567.5}}`, "1.234.567,5"},
+		{"de", `{{.amount | formatNumber .Locale}}`, "42"},
+		{"en", `{{formatNumber .Locale 2.5 2}}`, "2.50"},
+		{"en", `{{formatPercent .Locale 0.25}}`, "25%"},
+		{"en", `{{formatDate .Locale .when}}`, "12/31/2024"},
+		{"en-GB", `{{formatDate .Locale .when}}`, "31/12/2024"},
+		{"de", `{{formatDate .Locale .when "datetime"}}`, "31.12.2024 15:04"},
+		{"en", `{{formatDate .Locale .when "time"}}`, "3:04 PM"},
+		{"xx", `{{formatDate .Locale .when "2006"}}`, "2024"},
+		{"sw", `{{formatDate .Locale .when}}`, "2024-12-31"},
+	}
+
+	tm := newTemplateManager()
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.text, func(t *testing.T) {
+			if err := tm.AddTemplate("localized", tt.text, ParseModeNone); err != nil {
+				t.Fatalf("AddTemplate failed: %v", err)
+			}
+			got, _, err := tm.RenderTemplate("localized", map[string]interface{}{"Locale": tt.locale, "when": when, "amount": "42"})
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %q, got %q (err %v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestTemplateLocaleFromUser(t *testing.T) {
+	ctx := createFlowTestContext(7, "", nil)
+	ctx.update.Message.From.LanguageCode = "de"
+
+	data := withBuiltinTemplateData(nil, ctx)
+	if data["Locale"] != "de" {
+		t.Errorf("Expected locale from the user's language code, got %v", data["Locale"])
+	}
+	data = withBuiltinTemplateData(map[string]interface{}{"Locale": "fr"}, ctx)
+	if data["Locale"] != "fr" {
+		t.Errorf("Expected explicit locale to win, got %v", data["Locale"])
+	}
+}
@@ -0,0 +1,92 @@
+package teleflow
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// templateVariants is a template rendered from one of several texts.
+type templateVariants struct {
+	names      []string      // Templates holding the variant texts
+	roundRobin bool          // Rotate through the variants instead of picking at random
+	next       atomic.Uint64 // Next variant in rotation
+}
+
+// pick returns the name of the template to render.
+func (v *templateVariants) pick() string {
+	if v.roundRobin {
+		return v.names[(v.next.Add(1)-1)%uint64(len(v.names))]
+	}
+	return v.names[rand.IntN(len(v.names))]
+}
+
+// AddTemplateVariants registers a template with the default template manager
+// that renders one of several texts picked at random, so recurring messages
+// such as "Done!" or "Anything else?" do not feel robotic. All variants share
+// the parse mode and receive the same data.
+//
+// Example:
+//
+//	err := teleflow.AddTemplateVariants("done", []string{
+//		"✅ Done!",
+//		"All set, {{.name}}!",
+//		"👍 That's taken care of.",
+//	}, teleflow.ParseModeNone)
+func AddTemplateVariants(name string, variants []string, parseMode ParseMode) error {
+	return defaultTemplateManager.AddTemplateVariants(name, variants, parseMode)
+}
+
+// AddTemplateRotation registers a template with the default template manager
+// that renders its texts in turn, round-robin across all users.
+func AddTemplateRotation(name string, variants []string, parseMode ParseMode) error {
+	return defaultTemplateManager.AddTemplateRotation(name, variants, parseMode)
+}
+
+// AddTemplateVariants registers a template that renders one of several texts
+// picked at random.
+func (tm *templateManager) AddTemplateVariants(name string, variants []string, parseMode ParseMode) error {
+	return tm.addTemplateVariants(name, variants, parseMode, false)
+}
+
+// AddTemplateRotation registers a template that renders its texts in turn.
+func (tm *templateManager) AddTemplateRotation(name string, variants []string, parseMode ParseMode) error {
+	return tm.addTemplateVariants(name, variants, parseMode, true)
+}
+
+// addTemplateVariants registers each text as the template "name#n" and the
+// first one under name, so the template can be looked up and linted like any
+// other; rendering name then picks a variant.
+func (tm *templateManager) addTemplateVariants(name string, variants []string, parseMode ParseMode, roundRobin bool) error {
+	if len(variants) == 0 {
+		return fmt.Errorf("template '%s' needs at least one variant", name)
+	}
+	set := &templateVariants{roundRobin: roundRobin}
+	for i, text := range variants {
+		variantName := fmt.Sprintf("%s#%d", name, i+1)
+		if err := tm.addTemplate(variantName, "", text, parseMode); err != nil {
+			return err
+		}
+		set.names = append(set.names, variantName)
+	}
+	if err := tm.addTemplate(name, "", variants[0], parseMode); err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.variants[name] = set
+	return nil
+}
+
+// variantFor returns the template to render for name: one of its variants, or
+// name itself.
+func (tm *templateManager) variantFor(name string) string {
+	tm.mu.RLock()
+	set := tm.variants[name]
+	tm.mu.RUnlock()
+	if set == nil {
+		return name
+	}
+	return set.pick()
+}
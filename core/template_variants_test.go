@@ -0,0 +1,50 @@
+package teleflow
+
+import "testing"
+
+func TestTemplateVariants_Random(t *testing.T) {
+	tm := newTemplateManager()
+	if err := tm.AddTemplateVariants("done", []string{"✅ Done, {{.name}}!", "All set, {{.name}}!"}, ParseModeNone); err != nil {
+		t.Fatalf("AddTemplateVariants failed: %v", err)
+	}
+	if !tm.HasTemplate("done") {
+		t.Fatal("Expected the template to be registered")
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		text, _, err := tm.RenderTemplate("done", map[string]interface{}{"name": "Ann"})
+		if err != nil {
+			t.Fatalf("RenderTemplate failed: %v", err)
+		}
+		seen[text] = true
+	}
+	if len(seen) != 2 || !seen["✅ Done, Ann!"] || !seen["All set, Ann!"] {
+		t.Errorf("Expected both variants to be rendered, got %v", seen)
+	}
+
+	// Replacing the template drops its variants
+	if err := tm.AddTemplate("done", "Done.", ParseModeNone); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+	if text, _, _ := tm.RenderTemplate("done", nil); text != "Done." {
+		t.Errorf("Expected the replacement text, got %q", text)
+	}
+
+	if err := tm.AddTemplateVariants("empty", nil, ParseModeNone); err == nil {
+		t.Error("Expected an error without variants")
+	}
+}
+
+func TestTemplateVariants_Rotation(t *testing.T) {
+	tm := newTemplateManager()
+	if err := tm.AddTemplateRotation("more", []string{"Anything else?", "What next?", "Need more?"}, ParseModeNone); err != nil {
+		t.Fatalf("AddTemplateRotation failed: %v", err)
+	}
+	want := []string{"Anything else?", "What next?", "Need more?", "Anything else?"}
+	for i, expected := range want {
+		if text, _, _ := tm.RenderTemplate("more", nil); text != expected {
+			t.Errorf("Render %d: expected %q, got %q", i+1, expected, text)
+		}
+	}
+}
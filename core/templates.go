@@ -27,6 +27,13 @@ const (
 	// ParseModeHTML enables HTML formatting with standard HTML tags.
 	// Supports <b>bold</b>, <i>italic</i>, <u>underline</u>, <code>code</code>, etc.
 	ParseModeHTML ParseMode = "HTML"
+
+	// ParseModeSimpleMarkdown lets templates be written in simple Markdown:
+	// **bold**, *italic*, __underline__, ~~strikethrough~~, ||spoiler||, `code`,
+	// ```pre``` and [links](url), with a backslash for literal characters. Templates are
+	// converted to HTML when they are added and are sent with ParseModeHTML, so
+	// text needs no MarkdownV2-style escaping.
+	ParseModeSimpleMarkdown ParseMode = "SimpleMarkdown"
 )
 
 // TemplateInfo contains metadata about a registered message template.
@@ -36,12 +43,16 @@ type TemplateInfo struct {
 
 	ParseMode ParseMode // Telegram formatting mode for the template
 
+	Layout string // Layout the template is rendered in, empty for none
+
 	Template *template.Template // Compiled Go template
 }
 
 // AddTemplate registers a new message template with the default template manager.
 // Templates use Go template syntax and support the specified Telegram parse mode.
-// This is a convenience function for the global template manager.
+// This is a convenience function for the global template manager. Names of
+// templates and their {{define}} blocks are shared by all templates, so
+// defining a block another template already defines is an error.
 //
 // Example:
 //
@@ -50,6 +61,30 @@ func AddTemplate(name, templateText string, parseMode ParseMode) error {
 	return defaultTemplateManager.AddTemplate(name, templateText, parseMode)
 }
 
+// AddLayout registers a layout with the default template manager. A layout wraps
+// templates added with AddTemplateWithLayout and includes them with
+// {{template "content" .}}. Any registered template can also be included in
+// another as a partial with {{template "name" .}}.
+//
+// Example:
+//
+//	teleflow.AddTemplate("disclaimer", "<i>Not financial advice.</i>", teleflow.ParseModeHTML)
+//	teleflow.AddLayout("branded", "🏦 <b>MyBank</b>\n\n{{template \"content\" .}}\n\n{{template \"disclaimer\" .}}")
+func AddLayout(name, layoutText string) error {
+	return defaultTemplateManager.AddLayout(name, layoutText)
+}
+
+// AddTemplateWithLayout registers a template with the default template manager
+// that is rendered inside the named layout.
+//
+// Example:
+//
+//	err := teleflow.AddTemplateWithLayout("balance", "branded",
+//		"Your balance is <b>{{.balance}}</b>", teleflow.ParseModeHTML)
+func AddTemplateWithLayout(name, layout, templateText string, parseMode ParseMode) error {
+	return defaultTemplateManager.AddTemplateWithLayout(name, layout, templateText, parseMode)
+}
+
 // GetTemplateInfo retrieves information about a registered template.
 // Returns nil if the template doesn't exist. This is a convenience function
 // for the global template manager.
@@ -73,7 +108,7 @@ func HasTemplate(name string) bool {
 // Returns an error if the parse mode is not recognized.
 func validateParseMode(mode ParseMode) error {
 	switch mode {
-	case ParseModeNone, ParseModeMarkdown, ParseModeMarkdownV2, ParseModeHTML:
+	case ParseModeNone, ParseModeMarkdown, ParseModeMarkdownV2, ParseModeHTML, ParseModeSimpleMarkdown:
 		return nil
 	default:
 		return fmt.Errorf("unsupported parse mode: %s", mode)
@@ -88,7 +123,7 @@ func validateTemplateIntegrity(templateText string, parseMode ParseMode) error {
 		return validateMarkdown(templateText)
 	case ParseModeMarkdownV2:
 		return validateMarkdownV2(templateText)
-	case ParseModeHTML:
+	case ParseModeHTML, ParseModeSimpleMarkdown:
 		return validateHTML(templateText)
 	case ParseModeNone:
 		return nil
@@ -197,27 +232,3 @@ func escapeMarkdown(s string) string {
 	)
 	return replacer.Replace(s)
 }
-
-func escapeMarkdownV2(s string) string {
-	replacer := strings.NewReplacer(
-		"_", "\\_",
-		"*", "\\*",
-		"[", "\\[",
-		"]", "\\]",
-		"(", "\\(",
-		")", "\\)",
-		"~", "\\~",
-		"`", "\\`",
-		">", "\\>",
-		"#", "\\#",
-		"+", "\\+",
-		"-", "\\-",
-		"=", "\\=",
-		"|", "\\|",
-		"{", "\\{",
-		"}", "\\}",
-		".", "\\.",
-		"!", "\\!",
-	)
-	return replacer.Replace(s)
-}
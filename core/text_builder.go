@@ -0,0 +1,233 @@
+package teleflow
+
+import (
+	"html"
+	"strings"
+)
+
+// EscapeMarkdownV2 escapes every character Telegram reserves in MarkdownV2 so s
+// is shown literally in a ParseModeMarkdownV2 message.
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Escaper.Replace(s)
+}
+
+// EscapeHTML escapes <, >, & and quotes so s is shown literally in a
+// ParseModeHTML message.
+func EscapeHTML(s string) string {
+	return html.EscapeString(s)
+}
+
+// Escape escapes s for the parse mode. Text for ParseModeNone is returned as is.
+func Escape(s string, mode ParseMode) string {
+	switch mode {
+	case ParseModeMarkdownV2:
+		return EscapeMarkdownV2(s)
+	case ParseModeHTML:
+		return EscapeHTML(s)
+	case ParseModeMarkdown:
+		return escapeMarkdown(s)
+	default:
+		return s
+	}
+}
+
+var markdownV2Escaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"_", "\\_",
+	"*", "\\*",
+	"[", "\\[",
+	"]", "\\]",
+	"(", "\\(",
+	")", "\\)",
+	"~", "\\~",
+	"`", "\\`",
+	">", "\\>",
+	"#", "\\#",
+	"+", "\\+",
+	"-", "\\-",
+	"=", "\\=",
+	"|", "\\|",
+	"{", "\\{",
+	"}", "\\}",
+	".", "\\.",
+	"!", "\\!",
+)
+
+// markdownV2CodeEscaper escapes the characters reserved inside code entities.
+var markdownV2CodeEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// markdownV2URLEscaper escapes the characters reserved inside link URLs.
+var markdownV2URLEscaper = strings.NewReplacer("\\", "\\\\", ")", "\\)")
+
+// TextBuilder composes formatted messages outside templates. Every piece of text
+// is escaped for the builder's parse mode, so user input can't break the markup.
+// A *TextBuilder can be used directly as a prompt's Message.
+//
+// ParseModeMarkdownV2 and ParseModeHTML are supported; with any other mode the
+// builder produces plain text and drops the formatting.
+//
+// Example:
+//
+//	msg := teleflow.NewTextBuilder(teleflow.ParseModeMarkdownV2).
+//		Bold("Order #").Bold(orderID).Text(" confirmed.").Line().
+//		Text("Track it ").Link("here", trackingURL)
+//	ctx.SendPrompt(&teleflow.PromptConfig{Message: msg})
+type TextBuilder struct {
+	mode ParseMode
+	text strings.Builder
+}
+
+// NewTextBuilder creates a TextBuilder for the parse mode.
+func NewTextBuilder(mode ParseMode) *TextBuilder {
+	if mode != ParseModeMarkdownV2 && mode != ParseModeHTML {
+		mode = ParseModeNone
+	}
+	return &TextBuilder{mode: mode}
+}
+
+// Text appends plain text.
+func (b *TextBuilder) Text(s string) *TextBuilder {
+	b.text.WriteString(Escape(s, b.mode))
+	return b
+}
+
+// Line appends a line break.
+func (b *TextBuilder) Line() *TextBuilder {
+	b.text.WriteString("\n")
+	return b
+}
+
+// Bold appends bold text.
+func (b *TextBuilder) Bold(s string) *TextBuilder {
+	return b.wrap(s, "*", "<b>", "</b>")
+}
+
+// Italic appends italic text.
+func (b *TextBuilder) Italic(s string) *TextBuilder {
+	return b.wrap(s, "_", "<i>", "</i>")
+}
+
+// Code appends inline monospace text.
+func (b *TextBuilder) Code(s string) *TextBuilder {
+	switch b.mode {
+	case ParseModeMarkdownV2:
+		b.text.WriteString("`" + markdownV2CodeEscaper.Replace(s) + "`")
+	case ParseModeHTML:
+		b.text.WriteString("<code>" + EscapeHTML(s) + "</code>")
+	default:
+		b.text.WriteString(s)
+	}
+	return b
+}
+
+// Link appends text linking to url. Plain text builders append the text
+// followed by the URL in parentheses.
+func (b *TextBuilder) Link(s, url string) *TextBuilder {
+	switch b.mode {
+	case ParseModeMarkdownV2:
+		b.text.WriteString("[" + EscapeMarkdownV2(s) + "](" + markdownV2URLEscaper.Replace(url) + ")")
+	case ParseModeHTML:
+		b.text.WriteString(`<a href="` + EscapeHTML(url) + `">` + EscapeHTML(s) + "</a>")
+	default:
+		b.text.WriteString(s + " (" + url + ")")
+	}
+	return b
+}
+
+// Spoiler appends text hidden until the user taps it. Plain text builders
+// append the text as is.
+func (b *TextBuilder) Spoiler(s string) *TextBuilder {
+	b.text.WriteString(formatSpoiler(s, b.mode))
+	return b
+}
+
+// Blockquote appends a quotation on its own lines.
+func (b *TextBuilder) Blockquote(s string) *TextBuilder {
+	b.text.WriteString(formatBlockquote(s, b.mode, false))
+	return b
+}
+
+// ExpandableBlockquote appends a quotation that Telegram shows collapsed until
+// the user expands it.
+func (b *TextBuilder) ExpandableBlockquote(s string) *TextBuilder {
+	b.text.WriteString(formatBlockquote(s, b.mode, true))
+	return b
+}
+
+// String returns the composed message.
+func (b *TextBuilder) String() string {
+	return b.text.String()
+}
+
+// ParseMode returns the parse mode the message must be sent with.
+func (b *TextBuilder) ParseMode() ParseMode {
+	return b.mode
+}
+
+func (b *TextBuilder) wrap(s, markdown, openTag, closeTag string) *TextBuilder {
+	switch b.mode {
+	case ParseModeMarkdownV2:
+		b.text.WriteString(markdown + EscapeMarkdownV2(s) + markdown)
+	case ParseModeHTML:
+		b.text.WriteString(openTag + EscapeHTML(s) + closeTag)
+	default:
+		b.text.WriteString(s)
+	}
+	return b
+}
+
+// formattingTemplateFuncs returns the spoiler, blockquote and
+// expandableBlockquote template functions, which escape their argument for the
+// template's parse mode, e.g. {{spoiler .answer}}.
+func formattingTemplateFuncs(mode ParseMode) map[string]interface{} {
+	return map[string]interface{}{
+		"spoiler": func(s string) string {
+			return formatSpoiler(s, mode)
+		},
+		"blockquote": func(s string) string {
+			return formatBlockquote(s, mode, false)
+		},
+		"expandableBlockquote": func(s string) string {
+			return formatBlockquote(s, mode, true)
+		},
+	}
+}
+
+// formatSpoiler escapes s for the parse mode and marks it as a spoiler. Legacy
+// Markdown has no spoilers, so the text is only escaped.
+func formatSpoiler(s string, mode ParseMode) string {
+	switch mode {
+	case ParseModeMarkdownV2:
+		return "||" + EscapeMarkdownV2(s) + "||"
+	case ParseModeHTML:
+		return "<tg-spoiler>" + EscapeHTML(s) + "</tg-spoiler>"
+	default:
+		return Escape(s, mode)
+	}
+}
+
+// formatBlockquote escapes s for the parse mode and quotes it. In MarkdownV2
+// every line is prefixed with ">", and an expandable quotation starts with
+// "**>" and ends with "||". Legacy Markdown has no quotations, so the text is
+// only escaped.
+func formatBlockquote(s string, mode ParseMode, expandable bool) string {
+	switch mode {
+	case ParseModeMarkdownV2:
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			lines[i] = ">" + EscapeMarkdownV2(line)
+		}
+		if expandable {
+			lines[0] = "**" + lines[0]
+			lines[len(lines)-1] += "||"
+		}
+		return "\n" + strings.Join(lines, "\n") + "\n"
+	case ParseModeHTML:
+		if expandable {
+			return "<blockquote expandable>" + EscapeHTML(s) + "</blockquote>"
+		}
+		return "<blockquote>" + EscapeHTML(s) + "</blockquote>"
+	default:
+		return Escape(s, mode)
+	}
+}
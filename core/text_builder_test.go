@@ -0,0 +1,70 @@
+package teleflow
+
+import "testing"
+
+func TestEscape(t *testing.T) {
+	if got := EscapeMarkdownV2(`1.5*2 = [3] \o/`); got != `1\.5\*2 \= \[3\] \\o/` {
+		t.Errorf("EscapeMarkdownV2 = %q", got)
+	}
+	if got := EscapeHTML(`<b>"Tom & Jerry"</b>`); got != "&lt;b&gt;&#34;Tom &amp; Jerry&#34;&lt;/b&gt;" {
+		t.Errorf("EscapeHTML = %q", got)
+	}
+	if got := Escape("a_b", ParseModeNone); got != "a_b" {
+		t.Errorf("Escape with ParseModeNone = %q", got)
+	}
+}
+
+func TestTextBuilder(t *testing.T) {
+	build := func(mode ParseMode) *TextBuilder {
+		return NewTextBuilder(mode).
+			Bold("Total:").Text(" 5.00 (incl. tax)").Line().
+			Italic("a_b").Text(" ").Code("x`y").Text(" ").
+			Link("docs", "https://example.com/a_(b)?q=1&r=2")
+	}
+
+	tests := []struct {
+		mode ParseMode
+		want string
+	}{
+		{ParseModeMarkdownV2, "*Total:* 5\\.00 \\(incl\\. tax\\)\n_a\\_b_ `x\\`y` [docs](https://example.com/a_(b\\)?q=1&r=2)"},
+		{ParseModeHTML, "<b>Total:</b> 5.00 (incl. tax)\n<i>a_b</i> <code>x`y</code> <a href=\"https://example.com/a_(b)?q=1&amp;r=2\">docs</a>"},
+		{ParseModeMarkdown, "Total: 5.00 (incl. tax)\na_b x`y docs (https://example.com/a_(b)?q=1&r=2)"},
+	}
+	for _, tt := range tests {
+		b := build(tt.mode)
+		if got := b.String(); got != tt.want {
+			t.Errorf("%q builder:\n got %q\nwant %q", tt.mode, got, tt.want)
+		}
+	}
+	if mode := build(ParseModeMarkdown).ParseMode(); mode != ParseModeNone {
+		t.Errorf("Expected unsupported mode to fall back to plain text, got %q", mode)
+	}
+
+	handler := newMessageHandler(newTemplateManager())
+	text, mode, err := handler.renderMessage(&PromptConfig{Message: NewTextBuilder(ParseModeHTML).Bold("hi")}, nil)
+	if err != nil || text != "<b>hi</b>" || mode != ParseModeHTML {
+		t.Errorf("Expected builder message with its parse mode, got %q %q (err %v)", text, mode, err)
+	}
+}
+
+func TestSpoilerAndBlockquote(t *testing.T) {
+	md := NewTextBuilder(ParseModeMarkdownV2).Text("Answer:").Spoiler("42!").
+		Blockquote("a.b\nc").ExpandableBlockquote("long|\nquote").String()
+	if want := "Answer:||42\\!||\n>a\\.b\n>c\n\n**>long\\|\n>quote||\n"; md != want {
+		t.Errorf("MarkdownV2:\n got %q\nwant %q", md, want)
+	}
+
+	html := NewTextBuilder(ParseModeHTML).Spoiler("<x>").Blockquote("q").ExpandableBlockquote("e&f").String()
+	if want := "<tg-spoiler>&lt;x&gt;</tg-spoiler><blockquote>q</blockquote><blockquote expandable>e&amp;f</blockquote>"; html != want {
+		t.Errorf("HTML:\n got %q\nwant %q", html, want)
+	}
+
+	tm := newTemplateManager()
+	if err := tm.AddTemplate("quiz", `Answer: {{spoiler .answer}}{{expandableBlockquote .details}}`, ParseModeMarkdownV2); err != nil {
+		t.Fatalf("AddTemplate failed: %v", err)
+	}
+	got, _, err := tm.RenderTemplate("quiz", map[string]interface{}{"answer": "Paris.", "details": "It's big"})
+	if want := "Answer: ||Paris\\.||\n**>It's big||\n"; err != nil || got != want {
+		t.Errorf("Expected %q, got %q (err %v)", want, got, err)
+	}
+}
@@ -0,0 +1,92 @@
+package teleflow
+
+import (
+	"log"
+	"sync"
+)
+
+// defaultDedupeSize is the number of update IDs remembered by a
+// MemoryDedupeStore created with a non-positive size.
+const defaultDedupeSize = 10000
+
+// DedupeStore remembers the IDs of updates the bot has handled. Implement it
+// on shared storage, e.g. Redis SET NX with an expiry, when several instances
+// consume the same at-least-once delivery.
+type DedupeStore interface {
+	// MarkSeen records an update ID and reports whether it had been recorded
+	// before.
+	MarkSeen(updateID int) (duplicate bool, err error)
+}
+
+// WithDeduplication drops updates whose update_id store has already seen, so a
+// webhook gateway or queue redelivering an update never runs its handlers
+// twice. It applies to Start, StartWithSource, webhooks and
+// ProcessExternalUpdate; replayed dead letters are not checked. Updates without
+// an ID, such as ones built by tests, are always processed, and so are updates
+// the store fails to check.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithDeduplication(teleflow.NewMemoryDedupeStore(50000)))
+func WithDeduplication(store DedupeStore) BotOption {
+	return func(b *Bot) {
+		b.updatesConfig.dedupe = store
+	}
+}
+
+// isDuplicate reports whether an update was handled before and should be
+// dropped.
+func (b *Bot) isDuplicate(updateID int) bool {
+	store := b.updatesConfig.dedupe
+	if store == nil || updateID == 0 {
+		return false
+	}
+	duplicate, err := store.MarkSeen(updateID)
+	if err != nil {
+		log.Printf("[DEDUPE_FAILED] Update %d: %v", updateID, err)
+		return false
+	}
+	if duplicate {
+		b.duplicateUpdates.Add(1)
+		log.Printf("[DUPLICATE_UPDATE] Dropped update %d", updateID)
+	}
+	return duplicate
+}
+
+// MemoryDedupeStore remembers the most recent update IDs in a ring buffer.
+// IDs are lost when the process exits.
+type MemoryDedupeStore struct {
+	mu   sync.Mutex
+	ids  []int            // Ring buffer of remembered IDs, oldest at next once full
+	next int              // Position the next ID is written to
+	seen map[int]struct{} // IDs currently in the ring
+}
+
+// NewMemoryDedupeStore creates a store that remembers the last size update
+// IDs, 10000 if size is not positive.
+func NewMemoryDedupeStore(size int) *MemoryDedupeStore {
+	if size <= 0 {
+		size = defaultDedupeSize
+	}
+	return &MemoryDedupeStore{ids: make([]int, 0, size), seen: make(map[int]struct{}, size)}
+}
+
+// MarkSeen records an update ID, forgetting the oldest one when the store is
+// full, and reports whether it was already remembered.
+func (s *MemoryDedupeStore) MarkSeen(updateID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.seen[updateID]; ok {
+		return true, nil
+	}
+
+	if len(s.ids) < cap(s.ids) {
+		s.ids = append(s.ids, updateID)
+	} else {
+		delete(s.seen, s.ids[s.next])
+		s.ids[s.next] = updateID
+		s.next = (s.next + 1) % len(s.ids)
+	}
+	s.seen[updateID] = struct{}{}
+	return false, nil
+}
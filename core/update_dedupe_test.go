@@ -0,0 +1,49 @@
+package teleflow
+
+import "testing"
+
+func TestWithDeduplication_DropsRedeliveredUpdates(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithDeduplication(NewMemoryDedupeStore(0)))
+	handled := 0
+	bot.DefaultHandler(func(ctx *Context, text string) error {
+		handled++
+		return nil
+	})
+
+	update := createPoolTestUpdate(7, "pay 100")
+	update.UpdateID = 900
+	bot.ProcessExternalUpdate(update)
+	bot.ProcessExternalUpdate(update)
+	if err := bot.ProcessExternalUpdateJSON([]byte(`{"update_id":900,"message":{"message_id":1,"from":{"id":7},"chat":{"id":7,"type":"private"},"date":0,"text":"pay 100"}}`)); err != nil {
+		t.Fatalf("ProcessExternalUpdateJSON failed: %v", err)
+	}
+	if handled != 1 {
+		t.Errorf("Expected the update to be handled once, got %d", handled)
+	}
+
+	// Updates without an ID are never treated as duplicates
+	bot.ProcessExternalUpdate(createPoolTestUpdate(7, "hi"))
+	bot.ProcessExternalUpdate(createPoolTestUpdate(7, "hi"))
+	if handled != 3 {
+		t.Errorf("Expected updates without an ID to be handled, got %d calls", handled)
+	}
+
+	if status := bot.Health(); status.Duplicates != 2 {
+		t.Errorf("Expected 2 duplicates in Health, got %d", status.Duplicates)
+	}
+}
+
+func TestMemoryDedupeStore_ForgetsOldest(t *testing.T) {
+	store := NewMemoryDedupeStore(2)
+	for _, id := range []int{1, 2, 3} {
+		if duplicate, _ := store.MarkSeen(id); duplicate {
+			t.Fatalf("Expected update %d to be new", id)
+		}
+	}
+	if duplicate, _ := store.MarkSeen(3); !duplicate {
+		t.Error("Expected update 3 to be remembered")
+	}
+	if duplicate, _ := store.MarkSeen(1); duplicate {
+		t.Error("Expected update 1 to have been forgotten")
+	}
+}
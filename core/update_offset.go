@@ -0,0 +1,134 @@
+package teleflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OffsetStore persists the long-polling position of a bot, so that after a
+// restart Start resumes with the first update that was not processed instead
+// of receiving again the updates Telegram has not been told about yet.
+type OffsetStore interface {
+	// LoadOffset returns the update_id to resume from, or 0 if none is stored.
+	LoadOffset() (int, error)
+
+	// SaveOffset stores the update_id of the first update not processed yet.
+	SaveOffset(offset int) error
+}
+
+// WithOffsetStore makes Start load its polling offset from store and save it as
+// updates are processed. Updates are handled concurrently, so the saved offset
+// is that of the oldest update still in progress: after a crash no update is
+// skipped, at the cost of handling again some that had finished after it.
+// StartWithSource and webhooks do not use the store.
+//
+// Example:
+//
+//	offsets, err := teleflow.NewFileOffsetStore("/var/lib/mybot/offset")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	bot, err := teleflow.NewBot(token, teleflow.WithOffsetStore(offsets))
+func WithOffsetStore(store OffsetStore) BotOption {
+	return func(b *Bot) {
+		b.updatesConfig.offsets = store
+	}
+}
+
+// offsetTracker computes the offset to save from the updates in progress.
+type offsetTracker struct {
+	store OffsetStore
+
+	mu      sync.Mutex
+	pending map[int]struct{} // Updates received but not processed yet
+	next    int              // One past the newest update received
+	saved   int              // Offset last saved
+}
+
+// newOffsetTracker creates a tracker that resumes at offset.
+func newOffsetTracker(store OffsetStore, offset int) *offsetTracker {
+	return &offsetTracker{store: store, pending: make(map[int]struct{}), next: offset, saved: offset}
+}
+
+// received marks an update as in progress.
+func (t *offsetTracker) received(updateID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[updateID] = struct{}{}
+	if updateID >= t.next {
+		t.next = updateID + 1
+	}
+}
+
+// done marks an update as processed and saves the new offset if it advanced.
+func (t *offsetTracker) done(updateID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, updateID)
+
+	offset := t.next
+	for id := range t.pending {
+		if id < offset {
+			offset = id
+		}
+	}
+	if offset <= t.saved {
+		return
+	}
+	if err := t.store.SaveOffset(offset); err != nil {
+		log.Printf("[OFFSET_SAVE_FAILED] Offset %d: %v", offset, err)
+		return
+	}
+	t.saved = offset
+}
+
+// FileOffsetStore keeps the polling offset in a small text file. Each save
+// replaces the file through a temporary file, so a crash never leaves it
+// truncated.
+type FileOffsetStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileOffsetStore creates a store that keeps the offset in the file at path.
+// The file is created on the first save.
+func NewFileOffsetStore(path string) (*FileOffsetStore, error) {
+	if path == "" {
+		return nil, errors.New("offset file path is empty")
+	}
+	return &FileOffsetStore{path: path}, nil
+}
+
+// LoadOffset reads the stored offset, 0 if the file does not exist yet.
+func (s *FileOffsetStore) LoadOffset() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset file %s: %w", s.path, err)
+	}
+	return offset, nil
+}
+
+// SaveOffset writes the offset to the file.
+func (s *FileOffsetStore) SaveOffset(offset int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(offset)+"\n"), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,71 @@
+package teleflow
+
+import (
+	"path/filepath"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestFileOffsetStore(t *testing.T) {
+	store, err := NewFileOffsetStore(filepath.Join(t.TempDir(), "offset"))
+	if err != nil {
+		t.Fatalf("NewFileOffsetStore failed: %v", err)
+	}
+	if offset, err := store.LoadOffset(); err != nil || offset != 0 {
+		t.Fatalf("Expected offset 0 before the first save, got %d (%v)", offset, err)
+	}
+	if err := store.SaveOffset(1042); err != nil {
+		t.Fatalf("SaveOffset failed: %v", err)
+	}
+	if offset, err := store.LoadOffset(); err != nil || offset != 1042 {
+		t.Errorf("Expected offset 1042, got %d (%v)", offset, err)
+	}
+}
+
+func TestOffsetTracker_SavesOldestInProgress(t *testing.T) {
+	store, _ := NewFileOffsetStore(filepath.Join(t.TempDir(), "offset"))
+	tracker := newOffsetTracker(store, 10)
+	tracker.received(10)
+	tracker.received(11)
+	tracker.received(12)
+
+	tracker.done(11)
+	if offset, _ := store.LoadOffset(); offset != 0 {
+		t.Errorf("Expected no save while update 10 is in progress, got %d", offset)
+	}
+	tracker.done(10)
+	if offset, _ := store.LoadOffset(); offset != 12 {
+		t.Errorf("Expected offset 12 while update 12 is in progress, got %d", offset)
+	}
+	tracker.done(12)
+	if offset, _ := store.LoadOffset(); offset != 13 {
+		t.Errorf("Expected offset 13 once all updates are processed, got %d", offset)
+	}
+}
+
+func TestStart_ResumesFromOffsetStore(t *testing.T) {
+	store, _ := NewFileOffsetStore(filepath.Join(t.TempDir(), "offset"))
+	store.SaveOffset(500)
+	bot, mockClient, _, _ := createTestBot(WithOffsetStore(store))
+
+	mockClient.GetUpdatesChanFunc = func(config tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+		updates := make(chan tgbotapi.Update, 1)
+		update := createPoolTestUpdate(7, "hello")
+		update.UpdateID = 500
+		updates <- update
+		close(updates)
+		return updates
+	}
+	if err := bot.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if offset := mockClient.GetUpdatesChanCalls[0].Offset; offset != 500 {
+		t.Errorf("Expected polling to resume at 500, got %d", offset)
+	}
+
+	waitForActiveHandlers(bot)
+	if offset, _ := store.LoadOffset(); offset != 501 {
+		t.Errorf("Expected offset 501 to be saved, got %d", offset)
+	}
+}
@@ -0,0 +1,144 @@
+package teleflow
+
+import (
+	"log"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Defaults of the update queue enabled with WithUpdateQueue.
+const (
+	defaultUpdateQueueSize    = 1000
+	defaultUpdateQueueWorkers = 32
+)
+
+// QueueOverflow selects what happens to an update that arrives while the
+// update queue is full.
+type QueueOverflow int
+
+const (
+	OverflowBlock      QueueOverflow = iota // Wait for room, which slows down receiving (default)
+	OverflowDropOldest                      // Drop the oldest queued update to make room
+	OverflowShed                            // Drop non-essential updates; essential ones wait for room
+)
+
+// UpdateQueueConfig configures the bounded queue between received updates and
+// the workers that process them.
+type UpdateQueueConfig struct {
+	Size     int           // Updates the queue holds, 1000 by default
+	Workers  int           // Updates processed at the same time, 32 by default
+	Overflow QueueOverflow // Behaviour when the queue is full
+
+	// Essential reports whether OverflowShed must keep an update. By default
+	// messages, callback queries, shipping and pre-checkout queries are kept,
+	// while edits, channel posts, inline queries, polls and membership
+	// changes are shed.
+	Essential func(update tgbotapi.Update) bool
+}
+
+// WithUpdateQueue processes updates received by Start and StartWithSource with
+// a fixed number of workers fed by a bounded queue, instead of one goroutine
+// per update, protecting the bot and its backends during sudden spikes.
+// Updates dropped on overflow are logged and counted in
+// HealthStatus.DroppedUpdates. Webhooks are not queued.
+//
+// Example:
+//
+//	bot, err := teleflow.NewBot(token, teleflow.WithUpdateQueue(teleflow.UpdateQueueConfig{
+//		Size:     500,
+//		Workers:  16,
+//		Overflow: teleflow.OverflowShed,
+//	}))
+func WithUpdateQueue(config UpdateQueueConfig) BotOption {
+	return func(b *Bot) {
+		if config.Size <= 0 {
+			config.Size = defaultUpdateQueueSize
+		}
+		if config.Workers <= 0 {
+			config.Workers = defaultUpdateQueueWorkers
+		}
+		if config.Essential == nil {
+			config.Essential = isEssentialUpdate
+		}
+		b.updatesConfig.queue = &config
+	}
+}
+
+// isEssentialUpdate reports whether an update is kept by OverflowShed by
+// default.
+func isEssentialUpdate(update tgbotapi.Update) bool {
+	return update.Message != nil || update.CallbackQuery != nil ||
+		update.ShippingQuery != nil || update.PreCheckoutQuery != nil
+}
+
+// updateQueue hands received updates to a fixed pool of workers.
+type updateQueue struct {
+	bot    *Bot
+	config UpdateQueueConfig
+	items  chan tgbotapi.Update
+	handle func(update tgbotapi.Update) // Processes an update
+	drop   func(update tgbotapi.Update) // Called for updates dropped on overflow
+	wg     sync.WaitGroup
+}
+
+// startUpdateQueue creates the queue and starts its workers.
+func (b *Bot) startUpdateQueue(config UpdateQueueConfig, handle, drop func(update tgbotapi.Update)) *updateQueue {
+	q := &updateQueue{
+		bot:    b,
+		config: config,
+		items:  make(chan tgbotapi.Update, config.Size),
+		handle: handle,
+		drop:   drop,
+	}
+	q.wg.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go func() {
+			defer q.wg.Done()
+			for update := range q.items {
+				b.activeHandlers.Add(1)
+				q.handle(update)
+				b.activeHandlers.Add(-1)
+			}
+		}()
+	}
+	return q
+}
+
+// push queues an update, applying the overflow behaviour when the queue is
+// full. It must only be called from one goroutine.
+func (q *updateQueue) push(update tgbotapi.Update) {
+	select {
+	case q.items <- update:
+		return
+	default:
+	}
+
+	switch q.config.Overflow {
+	case OverflowDropOldest:
+		select {
+		case oldest := <-q.items:
+			q.dropped(oldest)
+		default:
+		}
+	case OverflowShed:
+		if !q.config.Essential(update) {
+			q.dropped(update)
+			return
+		}
+	}
+	q.items <- update
+}
+
+// dropped records an update dropped on overflow.
+func (q *updateQueue) dropped(update tgbotapi.Update) {
+	q.bot.droppedUpdates.Add(1)
+	log.Printf("[UPDATE_DROPPED] Update %d: queue full", update.UpdateID)
+	q.drop(update)
+}
+
+// close stops accepting updates and waits until the queued ones are processed.
+func (q *updateQueue) close() {
+	close(q.items)
+	q.wg.Wait()
+}
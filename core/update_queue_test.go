@@ -0,0 +1,119 @@
+package teleflow
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// queueRecorder processes updates for a test queue, holding the first one until
+// released.
+type queueRecorder struct {
+	mu      sync.Mutex
+	handled []int
+	dropped []int
+	started chan struct{}
+	release chan struct{}
+}
+
+func newQueueRecorder() *queueRecorder {
+	return &queueRecorder{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (r *queueRecorder) handle(update tgbotapi.Update) {
+	r.mu.Lock()
+	r.handled = append(r.handled, update.UpdateID)
+	first := len(r.handled) == 1
+	r.mu.Unlock()
+	if first {
+		close(r.started)
+		<-r.release
+	}
+}
+
+func (r *queueRecorder) drop(update tgbotapi.Update) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropped = append(r.dropped, update.UpdateID)
+}
+
+func queueTestUpdate(id int, edited bool) tgbotapi.Update {
+	message := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 7}, Text: "hi"}
+	if edited {
+		return tgbotapi.Update{UpdateID: id, EditedMessage: message}
+	}
+	return tgbotapi.Update{UpdateID: id, Message: message}
+}
+
+func TestUpdateQueue_DropOldest(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithUpdateQueue(UpdateQueueConfig{Size: 2, Workers: 1, Overflow: OverflowDropOldest}))
+	recorder := newQueueRecorder()
+	queue := bot.startUpdateQueue(*bot.updatesConfig.queue, recorder.handle, recorder.drop)
+
+	queue.push(queueTestUpdate(1, false))
+	<-recorder.started
+	for id := 2; id <= 4; id++ {
+		queue.push(queueTestUpdate(id, false))
+	}
+	close(recorder.release)
+	queue.close()
+
+	if !reflect.DeepEqual(recorder.handled, []int{1, 3, 4}) || !reflect.DeepEqual(recorder.dropped, []int{2}) {
+		t.Errorf("Expected update 2 to be dropped, handled %v, dropped %v", recorder.handled, recorder.dropped)
+	}
+	if status := bot.Health(); status.DroppedUpdates != 1 {
+		t.Errorf("Expected 1 dropped update in Health, got %d", status.DroppedUpdates)
+	}
+}
+
+func TestUpdateQueue_ShedsNonEssential(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithUpdateQueue(UpdateQueueConfig{Size: 1, Workers: 1, Overflow: OverflowShed}))
+	recorder := newQueueRecorder()
+	queue := bot.startUpdateQueue(*bot.updatesConfig.queue, recorder.handle, recorder.drop)
+
+	queue.push(queueTestUpdate(1, false))
+	<-recorder.started
+	queue.push(queueTestUpdate(2, false))
+	queue.push(queueTestUpdate(3, true))
+
+	pushed := make(chan struct{})
+	go func() {
+		queue.push(queueTestUpdate(4, false))
+		close(pushed)
+	}()
+	close(recorder.release)
+	<-pushed
+	queue.close()
+
+	if !reflect.DeepEqual(recorder.handled, []int{1, 2, 4}) || !reflect.DeepEqual(recorder.dropped, []int{3}) {
+		t.Errorf("Expected only the edit to be shed, handled %v, dropped %v", recorder.handled, recorder.dropped)
+	}
+}
+
+func TestWithUpdateQueue_ProcessesSourceUpdates(t *testing.T) {
+	bot, _, _, _ := createTestBot(WithUpdateQueue(UpdateQueueConfig{Size: 2, Workers: 2}))
+	var count atomic.Int32
+	bot.HandleText("ping", func(ctx *Context, text string) error {
+		count.Add(1)
+		return nil
+	})
+
+	source := UpdateSourceFunc(func(ctx context.Context) (tgbotapi.UpdatesChannel, error) {
+		updates := make(chan tgbotapi.Update, 5)
+		for i := 0; i < 5; i++ {
+			updates <- createCaptchaAnswerUpdate(int64(i+1), int64(i+1), "ping")
+		}
+		close(updates)
+		return updates, nil
+	})
+	if err := bot.StartWithSource(context.Background(), source); err != nil {
+		t.Fatalf("StartWithSource failed: %v", err)
+	}
+	if count.Load() != 5 {
+		t.Errorf("Expected all 5 updates to be processed before returning, got %d", count.Load())
+	}
+}
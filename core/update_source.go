@@ -0,0 +1,86 @@
+package teleflow
+
+import (
+	"context"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// UpdateSource delivers Telegram updates from somewhere other than the bot's own
+// long polling, e.g. a NATS subject, Kafka topic or SQS queue fed by a webhook
+// gateway. Implementations close the returned channel when the source is
+// exhausted or ctx is cancelled.
+type UpdateSource interface {
+	Updates(ctx context.Context) (tgbotapi.UpdatesChannel, error)
+}
+
+// UpdateSourceFunc adapts a function to the UpdateSource interface.
+type UpdateSourceFunc func(ctx context.Context) (tgbotapi.UpdatesChannel, error)
+
+// Updates calls f(ctx).
+func (f UpdateSourceFunc) Updates(ctx context.Context) (tgbotapi.UpdatesChannel, error) {
+	return f(ctx)
+}
+
+// ProcessExternalUpdate handles a single update exactly like one received by
+// Start: middleware, flows and handlers all apply. It returns once the update has
+// been processed, so queue consumers can acknowledge the message afterwards.
+// It is safe to call concurrently.
+//
+// Example:
+//
+//	sub, _ := nc.Subscribe("telegram.updates", func(m *nats.Msg) {
+//		var update tgbotapi.Update
+//		if err := json.Unmarshal(m.Data, &update); err == nil {
+//			bot.ProcessExternalUpdate(update)
+//		}
+//	})
+func (b *Bot) ProcessExternalUpdate(update tgbotapi.Update) {
+	if b.isDuplicate(update.UpdateID) {
+		return
+	}
+	b.lastUpdate.Store(b.clock.Now().UnixNano())
+	b.processUpdate(update)
+}
+
+// ProcessExternalUpdateJSON decodes an update in the JSON format Telegram sends
+// to webhooks and processes it with ProcessExternalUpdate.
+func (b *Bot) ProcessExternalUpdateJSON(data []byte) error {
+	envelope, err := decodeUpdate(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode update: %w", err)
+	}
+	b.processEnvelope(envelope)
+	return nil
+}
+
+// StartWithSource runs the bot on updates from source instead of long polling.
+// Updates are processed concurrently, as in Start. It blocks until the source's
+// channel is closed or ctx is cancelled.
+//
+// Example:
+//
+//	source := teleflow.UpdateSourceFunc(func(ctx context.Context) (tgbotapi.UpdatesChannel, error) {
+//		return kafkaConsumer.TelegramUpdates(ctx)
+//	})
+//	err := bot.StartWithSource(ctx, source)
+func (b *Bot) StartWithSource(ctx context.Context, source UpdateSource) error {
+	updates, err := source.Updates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open update source: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		b.runUpdateLoop(updates, nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
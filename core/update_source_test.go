@@ -0,0 +1,76 @@
+package teleflow
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestProcessExternalUpdate(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	var handled string
+	bot.HandleText("ping", func(ctx *Context, text string) error {
+		handled = text
+		return nil
+	})
+
+	bot.ProcessExternalUpdate(createCaptchaAnswerUpdate(42, 42, "ping"))
+	if handled != "ping" {
+		t.Errorf("Expected update to be handled synchronously, got %q", handled)
+	}
+	if bot.Health().LastUpdate.IsZero() {
+		t.Error("Expected external update to refresh the last update timestamp")
+	}
+
+	handled = ""
+	err := bot.ProcessExternalUpdateJSON([]byte(`{"update_id":7,"message":{"message_id":1,"from":{"id":42},"chat":{"id":42,"type":"private"},"text":"ping"}}`))
+	if err != nil || handled != "ping" {
+		t.Errorf("Expected JSON update to be handled, got %q (err %v)", handled, err)
+	}
+	if err := bot.ProcessExternalUpdateJSON([]byte("not json")); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestStartWithSource(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	var count atomic.Int32
+	bot.HandleText("ping", func(ctx *Context, text string) error {
+		count.Add(1)
+		return nil
+	})
+
+	source := UpdateSourceFunc(func(ctx context.Context) (tgbotapi.UpdatesChannel, error) {
+		updates := make(chan tgbotapi.Update, 3)
+		for i := 0; i < 3; i++ {
+			updates <- createCaptchaAnswerUpdate(int64(i+1), int64(i+1), "ping")
+		}
+		close(updates)
+		return updates, nil
+	})
+
+	if err := bot.StartWithSource(context.Background(), source); err != nil {
+		t.Fatalf("StartWithSource failed: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for count.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if count.Load() != 3 {
+		t.Errorf("Expected 3 updates to be processed, got %d", count.Load())
+	}
+
+	failing := UpdateSourceFunc(func(ctx context.Context) (tgbotapi.UpdatesChannel, error) {
+		return nil, errors.New("broker unavailable")
+	})
+	if err := bot.StartWithSource(context.Background(), failing); err == nil {
+		t.Error("Expected error when the source cannot be opened")
+	}
+}
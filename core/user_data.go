@@ -0,0 +1,327 @@
+package teleflow
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DataSubject is a store that holds personal data of users, such as sessions,
+// audit logs or profiles. Register stores with Bot.RegisterDataSubject so that
+// ExportUserData and PurgeUserData cover them.
+type DataSubject interface {
+	// ExportUserData returns the data stored about a user in a form that can be
+	// encoded as JSON, or nil if there is none.
+	ExportUserData(userID int64) (interface{}, error)
+
+	// PurgeUserData deletes all data stored about a user.
+	PurgeUserData(userID int64) error
+}
+
+// UserDataExport is the JSON document produced by Bot.ExportUserData.
+type UserDataExport struct {
+	UserID int64                  `json:"user_id"`
+	Stores map[string]interface{} `json:"stores"` // Data of each store that holds any, by store name
+}
+
+// namedDataSubject is a DataSubject registered under a name.
+type namedDataSubject struct {
+	name    string
+	subject DataSubject
+}
+
+// dataSubjectRegistry holds the DataSubjects registered with a bot.
+type dataSubjectRegistry struct {
+	mu       sync.RWMutex
+	subjects []namedDataSubject
+}
+
+// RegisterDataSubject adds a store to the ones ExportUserData and PurgeUserData
+// walk, under the name used in exports. Registering a name again replaces the
+// store.
+//
+// The bot's own stores are always included: active flows ("flows"), inline
+// keyboard callback data ("callbacks"), the roles of an RBACAccessManager
+// ("roles") and the memory or file dead-letter queue ("dead_letters").
+//
+// Example:
+//
+//	bot.RegisterDataSubject("orders", orderStore)
+func (b *Bot) RegisterDataSubject(name string, subject DataSubject) {
+	b.dataSubjects.mu.Lock()
+	defer b.dataSubjects.mu.Unlock()
+	for i, named := range b.dataSubjects.subjects {
+		if named.name == name {
+			b.dataSubjects.subjects[i].subject = subject
+			return
+		}
+	}
+	b.dataSubjects.subjects = append(b.dataSubjects.subjects, namedDataSubject{name: name, subject: subject})
+}
+
+// ExportUserData collects everything the bot and its registered stores hold
+// about a user into a JSON UserDataExport, e.g. to answer a GDPR access
+// request.
+//
+// Example:
+//
+//	bot.HandleCommand("mydata", func(ctx *teleflow.Context, command, args string) error {
+//		export, err := bot.ExportUserData(ctx.UserID())
+//		if err != nil {
+//			return err
+//		}
+//		return sendDocument(ctx, "my-data.json", export)
+//	})
+func (b *Bot) ExportUserData(userID int64) ([]byte, error) {
+	export := UserDataExport{UserID: userID, Stores: make(map[string]interface{})}
+	for _, named := range b.allDataSubjects() {
+		data, err := named.subject.ExportUserData(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", named.name, err)
+		}
+		if data != nil {
+			export.Stores[named.name] = data
+		}
+	}
+	encoded, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user data: %w", err)
+	}
+	return encoded, nil
+}
+
+// PurgeUserData deletes everything the bot and its registered stores hold
+// about a user, e.g. for a right-to-be-forgotten request. Active flows of the
+// user end without running their OnCancel handlers. Every store is purged even
+// if another one fails; the errors are returned together.
+func (b *Bot) PurgeUserData(userID int64) error {
+	var errs []error
+	for _, named := range b.allDataSubjects() {
+		if err := named.subject.PurgeUserData(userID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge %s: %w", named.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// allDataSubjects returns the bot's own stores followed by the registered ones.
+func (b *Bot) allDataSubjects() []namedDataSubject {
+	subjects := []namedDataSubject{{name: "flows", subject: b.flowManager}}
+	if callbacks, ok := b.promptKeyboardHandler.(DataSubject); ok {
+		subjects = append(subjects, namedDataSubject{name: "callbacks", subject: callbacks})
+	}
+	if roles, ok := b.accessManager.(DataSubject); ok {
+		subjects = append(subjects, namedDataSubject{name: "roles", subject: roles})
+	}
+	if b.deadLetters != nil {
+		if letters, ok := b.deadLetters.queue.(DataSubject); ok {
+			subjects = append(subjects, namedDataSubject{name: "dead_letters", subject: letters})
+		}
+	}
+
+	b.dataSubjects.mu.RLock()
+	defer b.dataSubjects.mu.RUnlock()
+	return append(subjects, b.dataSubjects.subjects...)
+}
+
+// ExportUserData returns snapshots of the user's active flows. Flows bound to a
+// whole chat are not included.
+func (fm *flowManager) ExportUserData(userID int64) (interface{}, error) {
+	var flows []FlowStateSnapshot
+	for i := range fm.shards {
+		shard := &fm.shards[i]
+		shard.mu.RLock()
+		for key, state := range shard.states {
+			if key.UserID != userID {
+				continue
+			}
+			flows = append(flows, state.snapshot())
+		}
+		shard.mu.RUnlock()
+	}
+	if len(flows) == 0 {
+		return nil, nil
+	}
+	return flows, nil
+}
+
+// PurgeUserData ends the user's active flows without running their handlers
+// and forgets their flow start history.
+func (fm *flowManager) PurgeUserData(userID int64) error {
+	fm.startLimits.forget(userID)
+	for i := range fm.shards {
+		shard := &fm.shards[i]
+		shard.mu.Lock()
+		for key := range shard.states {
+			if key.UserID == userID {
+				fm.deleteState_nolock(key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return nil
+}
+
+// ExportUserData returns the data behind the user's inline keyboard buttons in
+// all chats.
+func (pkh *PromptKeyboardHandler) ExportUserData(userID int64) (interface{}, error) {
+	pkh.mu.RLock()
+	defer pkh.mu.RUnlock()
+	var data []interface{}
+	for owner, mappings := range pkh.uuidMappings {
+		if owner.UserID != userID {
+			continue
+		}
+		for _, value := range mappings {
+			data = append(data, value)
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// PurgeUserData removes the user's callback mappings in all chats.
+func (pkh *PromptKeyboardHandler) PurgeUserData(userID int64) error {
+	pkh.mu.Lock()
+	defer pkh.mu.Unlock()
+	for owner := range pkh.uuidMappings {
+		if owner.UserID == userID {
+			delete(pkh.uuidMappings, owner)
+		}
+	}
+	return nil
+}
+
+// ExportUserData returns the roles assigned to the user.
+func (m *RBACAccessManager) ExportUserData(userID int64) (interface{}, error) {
+	roles, err := m.store.GetRoles(userID)
+	if err != nil || len(roles) == 0 {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// PurgeUserData revokes all roles of the user.
+func (m *RBACAccessManager) PurgeUserData(userID int64) error {
+	roles, err := m.store.GetRoles(userID)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if err := m.store.RevokeRole(userID, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportUserData returns the user's failed updates.
+func (q *MemoryDeadLetterQueue) ExportUserData(userID int64) (interface{}, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var letters []DeadLetter
+	for _, letter := range q.letters {
+		if letterFrom(letter, userID) {
+			letters = append(letters, letter)
+		}
+	}
+	if len(letters) == 0 {
+		return nil, nil
+	}
+	return letters, nil
+}
+
+// PurgeUserData removes the user's failed updates.
+func (q *MemoryDeadLetterQueue) PurgeUserData(userID int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.letters[:0]
+	for _, letter := range q.letters {
+		if !letterFrom(letter, userID) {
+			kept = append(kept, letter)
+		}
+	}
+	q.letters = kept
+	return nil
+}
+
+// ExportUserData returns the user's failed updates.
+func (q *FileDeadLetterQueue) ExportUserData(userID int64) (interface{}, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters, err := q.readAll()
+	if err != nil {
+		return nil, err
+	}
+	var own []DeadLetter
+	for _, letter := range letters {
+		if letterFrom(letter, userID) {
+			own = append(own, letter)
+		}
+	}
+	if len(own) == 0 {
+		return nil, nil
+	}
+	return own, nil
+}
+
+// PurgeUserData rewrites the file without the user's failed updates.
+func (q *FileDeadLetterQueue) PurgeUserData(userID int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters, err := q.readAll()
+	if err != nil {
+		return err
+	}
+	var data []byte
+	for _, letter := range letters {
+		if letterFrom(letter, userID) {
+			continue
+		}
+		line, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
+
+// readAll reads every letter in the file. The caller must hold q.mu.
+func (q *FileDeadLetterQueue) readAll() ([]DeadLetter, error) {
+	file, err := os.Open(q.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			return nil, fmt.Errorf("corrupt dead letter: %w", err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
+
+// letterFrom reports whether a dead letter is an update sent by the user.
+func letterFrom(letter DeadLetter, userID int64) bool {
+	from := letter.Update.SentFrom()
+	return from != nil && from.ID == userID
+}
@@ -0,0 +1,129 @@
+package teleflow
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// orderStore is a DataSubject holding orders by user.
+type orderStore map[int64][]string
+
+func (s orderStore) ExportUserData(userID int64) (interface{}, error) {
+	if len(s[userID]) == 0 {
+		return nil, nil
+	}
+	return s[userID], nil
+}
+
+func (s orderStore) PurgeUserData(userID int64) error {
+	delete(s, userID)
+	return nil
+}
+
+func TestExportAndPurgeUserData(t *testing.T) {
+	queue := NewMemoryDeadLetterQueue()
+	bot, _, _, _ := createTestBot(WithDeadLetterQueue(queue, 0))
+	rbac := NewRBACAccessManager(NewMemoryRoleStore()).DefineRole("admin", "*")
+	bot.accessManager = rbac
+
+	userID, otherID := int64(42), int64(7)
+	flow, err := NewFlow("signup").
+		Step("name").
+		Prompt("Your name?").
+		Process(func(ctx *Context, input string, click *ButtonClick) ProcessResult { return Retry() }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	bot.RegisterFlow(flow)
+	for _, id := range []int64{userID, otherID} {
+		if err := bot.StartFlowFor(id, id, "signup", map[string]interface{}{"email": "user@example.com"}); err != nil {
+			t.Fatalf("StartFlowFor failed: %v", err)
+		}
+	}
+	if err := rbac.AssignRole(userID, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	_ = queue.Push(DeadLetter{Update: createPoolTestUpdate(userID, "hello"), Error: "boom"})
+	_ = queue.Push(DeadLetter{Update: createPoolTestUpdate(otherID, "hello"), Error: "boom"})
+	orders := orderStore{userID: {"order_1"}, otherID: {"order_2"}}
+	bot.RegisterDataSubject("orders", orders)
+
+	data, err := bot.ExportUserData(userID)
+	if err != nil {
+		t.Fatalf("ExportUserData failed: %v", err)
+	}
+	var export struct {
+		UserID int64                      `json:"user_id"`
+		Stores map[string]json.RawMessage `json:"stores"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+	for _, store := range []string{"flows", "roles", "dead_letters", "orders"} {
+		if _, ok := export.Stores[store]; !ok {
+			t.Errorf("Expected %s in the export, got %s", store, data)
+		}
+	}
+	if export.UserID != userID || string(export.Stores["orders"]) != `[
+      "order_1"
+    ]` {
+		t.Errorf("Unexpected export %s", data)
+	}
+
+	if err := bot.PurgeUserData(userID); err != nil {
+		t.Fatalf("PurgeUserData failed: %v", err)
+	}
+	if _, inFlow := bot.GetUserFlow(userID, userID); inFlow {
+		t.Error("Expected the user's flow to be removed")
+	}
+	if _, inFlow := bot.GetUserFlow(otherID, otherID); !inFlow {
+		t.Error("Other users' flows must be kept")
+	}
+	if roles, _ := rbac.store.GetRoles(userID); len(roles) != 0 {
+		t.Errorf("Expected the roles to be revoked, got %v", roles)
+	}
+	if queue.Len() != 1 || len(orders) != 1 {
+		t.Errorf("Expected only the other user's data to remain, got %d letters and %v", queue.Len(), orders)
+	}
+	if data, _ := bot.ExportUserData(userID); string(data) != "{\n  \"user_id\": 42,\n  \"stores\": {}\n}" {
+		t.Errorf("Expected an empty export after purging, got %s", data)
+	}
+}
+
+func TestFileDeadLetterQueue_PurgeUserData(t *testing.T) {
+	queue, err := NewFileDeadLetterQueue(filepath.Join(t.TempDir(), "dead.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = queue.Push(DeadLetter{Update: createPoolTestUpdate(42, "a"), Error: "boom"})
+	_ = queue.Push(DeadLetter{Update: createPoolTestUpdate(7, "b"), Error: "boom"})
+
+	if letters, err := queue.ExportUserData(42); err != nil || len(letters.([]DeadLetter)) != 1 {
+		t.Fatalf("Expected one letter of the user, got %v, %v", letters, err)
+	}
+	if err := queue.PurgeUserData(42); err != nil {
+		t.Fatalf("PurgeUserData failed: %v", err)
+	}
+	letters, err := queue.Pop(10)
+	if err != nil || len(letters) != 1 || letters[0].Update.Message.Text != "b" {
+		t.Errorf("Expected only the other user's letter, got %v, %v", letters, err)
+	}
+}
+
+func TestPurgeUserData_JoinsErrors(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+	failure := errors.New("database down")
+	bot.RegisterDataSubject("orders", failingSubject{failure})
+	if err := bot.PurgeUserData(42); !errors.Is(err, failure) {
+		t.Errorf("Expected the store's error, got %v", err)
+	}
+}
+
+// failingSubject is a DataSubject whose store is unavailable.
+type failingSubject struct{ err error }
+
+func (s failingSubject) ExportUserData(int64) (interface{}, error) { return nil, s.err }
+func (s failingSubject) PurgeUserData(int64) error                 { return s.err }
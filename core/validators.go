@@ -0,0 +1,335 @@
+package teleflow
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator checks the user's input for a flow step before its ProcessFunc runs.
+// It returns nil when the input is acceptable. Any returned error causes the step
+// to be retried, using the error text (typically a *ValidationError) as the prompt;
+// the retry counts toward the step's StepBuilder.MaxAttempts limit.
+// The message may reference a template with the "template:" prefix.
+//
+// Validators only inspect messages; inline keyboard clicks bypass validation.
+type Validator func(ctx *Context, input string) error
+
+// ValidationError is returned by validators to describe why input was rejected.
+// Its Message is shown to the user before the step is retried. When Message
+// references a template, TemplateData is used to render it.
+type ValidationError struct {
+	Message      string                 // User-facing explanation of the problem
+	TemplateData map[string]interface{} // Data for templated messages (optional)
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// validationFailed creates a ValidationError, preferring a caller-supplied
+// message over the validator's default one.
+func validationFailed(defaultMessage string, message []string) error {
+	if len(message) > 0 && message[0] != "" {
+		return &ValidationError{Message: message[0]}
+	}
+	return &ValidationError{Message: defaultMessage}
+}
+
+// validationRetry converts a validation failure into the Retry result applied to
+// the step. It sets no limit of its own, so the step's MaxAttempts applies.
+func validationRetry(err error) ProcessResult {
+	result := Retry().WithPrompt(err.Error())
+	if validationErr, ok := err.(*ValidationError); ok && validationErr.TemplateData != nil {
+		result = result.WithTemplateData(validationErr.TemplateData)
+	}
+	return result
+}
+
+// Chain combines several validators into one. Validators run in order and
+// the first failure is reported.
+//
+// Example:
+//
+//	step.Validate(teleflow.Chain(
+//		teleflow.NotEmptyValidator(),
+//		teleflow.LengthValidator(3, 32, "Name must be 3-32 characters long"),
+//	))
+func Chain(validators ...Validator) Validator {
+	return func(ctx *Context, input string) error {
+		for _, validate := range validators {
+			if validate == nil {
+				continue
+			}
+			if err := validate(ctx, input); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// NotEmptyValidator rejects input that is empty or consists only of whitespace.
+func NotEmptyValidator(message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		if strings.TrimSpace(input) == "" {
+			return validationFailed("❗ Please enter a value.", message)
+		}
+		return nil
+	}
+}
+
+// LengthValidator accepts input whose length in characters is between min and max (inclusive).
+// A max of 0 means there is no upper limit.
+func LengthValidator(min, max int, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		length := utf8.RuneCountInString(strings.TrimSpace(input))
+		if length < min || (max > 0 && length > max) {
+			if max > 0 {
+				return validationFailed(fmt.Sprintf("❗ Please enter between %d and %d characters.", min, max), message)
+			}
+			return validationFailed(fmt.Sprintf("❗ Please enter at least %d characters.", min), message)
+		}
+		return nil
+	}
+}
+
+// NumberValidator accepts any decimal number, such as "42" or "-3.5".
+func NumberValidator(message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(input), 64); err != nil {
+			return validationFailed("❗ Please enter a valid number.", message)
+		}
+		return nil
+	}
+}
+
+// MoneyValidator accepts amounts of the currency as understood by ParseMoney,
+// such as "1,234.56" or "1 234,56 €". Negative amounts are rejected.
+func MoneyValidator(currencyCode string, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		money, err := ParseMoney(input, currencyCode)
+		if err != nil || money.Minor < 0 {
+			return validationFailed("❗ Please enter a valid amount, e.g. 100.50.", message)
+		}
+		return nil
+	}
+}
+
+// IntRangeValidator accepts whole numbers between min and max (inclusive).
+func IntRangeValidator(min, max int, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil || n < min || n > max {
+			return validationFailed(fmt.Sprintf("❗ Please enter a whole number between %d and %d.", min, max), message)
+		}
+		return nil
+	}
+}
+
+// ChoiceValidator accepts only one of the given choices. Matching is case-insensitive.
+func ChoiceValidator(choices []string, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		trimmed := strings.TrimSpace(input)
+		for _, choice := range choices {
+			if strings.EqualFold(trimmed, choice) {
+				return nil
+			}
+		}
+		return validationFailed(fmt.Sprintf("❗ Please choose one of: %s.", strings.Join(choices, ", ")), message)
+	}
+}
+
+// RegexValidator accepts input matching the given regular expression.
+// It panics if the pattern does not compile, like regexp.MustCompile.
+func RegexValidator(pattern string, message ...string) Validator {
+	re := regexp.MustCompile(pattern)
+	return func(ctx *Context, input string) error {
+		if !re.MatchString(strings.TrimSpace(input)) {
+			return validationFailed("❗ The value has an invalid format.", message)
+		}
+		return nil
+	}
+}
+
+// EmailValidator accepts a single bare email address such as "john@example.com".
+func EmailValidator(message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		trimmed := strings.TrimSpace(input)
+		addr, err := mail.ParseAddress(trimmed)
+		if err != nil || addr.Address != trimmed || !strings.Contains(trimmed[strings.LastIndex(trimmed, "@"):], ".") {
+			return validationFailed("❗ Please enter a valid email address.", message)
+		}
+		return nil
+	}
+}
+
+// e164Pattern matches phone numbers in E.164 format: a plus sign followed by up to 15 digits.
+var e164Pattern = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+
+// PhoneValidator accepts phone numbers in international E.164 format, e.g. "+14155552671".
+// Spaces, dashes and parentheses are ignored.
+func PhoneValidator(message ...string) Validator {
+	cleaner := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "")
+	return func(ctx *Context, input string) error {
+		if !e164Pattern.MatchString(cleaner.Replace(strings.TrimSpace(input))) {
+			return validationFailed("❗ Please enter a phone number in international format, e.g. +14155552671.", message)
+		}
+		return nil
+	}
+}
+
+// URLValidator accepts absolute http and https URLs.
+func URLValidator(message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		u, err := url.ParseRequestURI(strings.TrimSpace(input))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return validationFailed("❗ Please enter a valid link starting with http:// or https://.", message)
+		}
+		return nil
+	}
+}
+
+// DateValidator accepts dates in the given time layout, e.g. "2006-01-02".
+func DateValidator(layout string, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		if _, err := time.Parse(layout, strings.TrimSpace(input)); err != nil {
+			return validationFailed(fmt.Sprintf("❗ Please enter a date in the format %s.", layout), message)
+		}
+		return nil
+	}
+}
+
+// fileValidationFailed is like validationFailed but also exposes details about the
+// rejected file to templated messages as .file_name, .mime_type, .file_size and
+// any validator-specific limits.
+func fileValidationFailed(defaultMessage string, message []string, file *FileInput, limits map[string]interface{}) error {
+	err := validationFailed(defaultMessage, message).(*ValidationError)
+	err.TemplateData = map[string]interface{}{}
+	if file != nil {
+		err.TemplateData["file_name"] = file.FileName
+		err.TemplateData["mime_type"] = file.MimeType
+		err.TemplateData["file_size"] = file.FileSize
+	}
+	for k, v := range limits {
+		err.TemplateData[k] = v
+	}
+	return err
+}
+
+// FileRequiredValidator rejects messages that do not carry a file (photo, document,
+// video, animation, audio or voice). The other file validators include this check.
+func FileRequiredValidator(message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		if ctx.File() == nil {
+			return fileValidationFailed("❗ Please send a file.", message, nil, nil)
+		}
+		return nil
+	}
+}
+
+// MaxFileSizeValidator rejects files larger than maxBytes.
+// Templated messages can use .max_size in addition to the file details.
+//
+// Example:
+//
+//	step.Validate(teleflow.MaxFileSizeValidator(5<<20, "template:file_too_large"))
+func MaxFileSizeValidator(maxBytes int, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		file := ctx.File()
+		if file == nil {
+			return fileValidationFailed("❗ Please send a file.", message, nil, nil)
+		}
+		if file.FileSize > maxBytes {
+			return fileValidationFailed(fmt.Sprintf("❗ The file is too large. Maximum size is %s.", formatByteSize(maxBytes)),
+				message, file, map[string]interface{}{"max_size": maxBytes})
+		}
+		return nil
+	}
+}
+
+// MimeTypeValidator accepts files whose MIME type is in the allowed list.
+// Entries may end in "/*" to allow a whole family, e.g. "image/*".
+// Templated messages can use .allowed in addition to the file details.
+func MimeTypeValidator(allowed []string, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		file := ctx.File()
+		if file == nil {
+			return fileValidationFailed("❗ Please send a file.", message, nil, nil)
+		}
+		mimeType := strings.ToLower(file.MimeType)
+		for _, pattern := range allowed {
+			pattern = strings.ToLower(pattern)
+			if pattern == mimeType || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*"))) {
+				return nil
+			}
+		}
+		return fileValidationFailed(fmt.Sprintf("❗ Unsupported file type. Allowed: %s.", strings.Join(allowed, ", ")),
+			message, file, map[string]interface{}{"allowed": allowed})
+	}
+}
+
+// FileExtensionValidator accepts documents whose file name ends in one of the given
+// extensions (with or without the leading dot, case-insensitive).
+// Templated messages can use .allowed in addition to the file details.
+func FileExtensionValidator(extensions []string, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		file := ctx.File()
+		if file == nil {
+			return fileValidationFailed("❗ Please send a file.", message, nil, nil)
+		}
+		name := strings.ToLower(file.FileName)
+		for _, ext := range extensions {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			if strings.HasSuffix(name, ext) {
+				return nil
+			}
+		}
+		return fileValidationFailed(fmt.Sprintf("❗ Unsupported file extension. Allowed: %s.", strings.Join(extensions, ", ")),
+			message, file, map[string]interface{}{"allowed": extensions})
+	}
+}
+
+// ImageDimensionsValidator accepts photos, videos and animations whose size lies
+// within the given bounds in pixels. A max of 0 means there is no upper limit.
+// Templated messages can use .width, .height, .min_width, .min_height,
+// .max_width and .max_height in addition to the file details.
+func ImageDimensionsValidator(minWidth, minHeight, maxWidth, maxHeight int, message ...string) Validator {
+	return func(ctx *Context, input string) error {
+		file := ctx.File()
+		if file == nil || file.Width == 0 || file.Height == 0 {
+			return fileValidationFailed("❗ Please send an image.", message, file, nil)
+		}
+		if file.Width < minWidth || file.Height < minHeight ||
+			(maxWidth > 0 && file.Width > maxWidth) || (maxHeight > 0 && file.Height > maxHeight) {
+			return fileValidationFailed(fmt.Sprintf("❗ The image is %dx%d pixels, which is outside the allowed size.", file.Width, file.Height),
+				message, file, map[string]interface{}{
+					"width": file.Width, "height": file.Height,
+					"min_width": minWidth, "min_height": minHeight,
+					"max_width": maxWidth, "max_height": maxHeight,
+				})
+		}
+		return nil
+	}
+}
+
+// formatByteSize renders a byte count in a human-friendly unit.
+func formatByteSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
@@ -0,0 +1,280 @@
+package teleflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator Validator
+		input     string
+		wantErr   bool
+	}{
+		{"not empty accepts text", NotEmptyValidator(), "hello", false},
+		{"not empty rejects whitespace", NotEmptyValidator(), "   ", true},
+		{"length within bounds", LengthValidator(2, 5), "abc", false},
+		{"length counts runes", LengthValidator(2, 2), "ёж", false},
+		{"length too long", LengthValidator(2, 5), "abcdef", true},
+		{"length without upper bound", LengthValidator(2, 0), "abcdefghij", false},
+		{"number accepts decimals", NumberValidator(), "-3.5", false},
+		{"number rejects text", NumberValidator(), "abc", true},
+		{"int range accepts bound", IntRangeValidator(1, 10), "10", false},
+		{"int range rejects outside", IntRangeValidator(1, 10), "11", true},
+		{"int range rejects decimals", IntRangeValidator(1, 10), "2.5", true},
+		{"choice is case-insensitive", ChoiceValidator([]string{"Yes", "No"}), "yes", false},
+		{"choice rejects unknown", ChoiceValidator([]string{"Yes", "No"}), "maybe", true},
+		{"regex match", RegexValidator(`^[A-Z]{3}$`), "USD", false},
+		{"regex mismatch", RegexValidator(`^[A-Z]{3}$`), "usd", true},
+		{"email valid", EmailValidator(), "john@example.com", false},
+		{"email with display name", EmailValidator(), "John <john@example.com>", true},
+		{"email without domain dot", EmailValidator(), "john@localhost", true},
+		{"phone e164", PhoneValidator(), "+1 (415) 555-2671", false},
+		{"phone without plus", PhoneValidator(), "4155552671", true},
+		{"url https", URLValidator(), "https://example.com/path", false},
+		{"url other scheme", URLValidator(), "ftp://example.com", true},
+		{"money accepts grouping", MoneyValidator("EUR"), "1.234,50 €", false},
+		{"money rejects negative", MoneyValidator("EUR"), "-5", true},
+		{"date layout", DateValidator("2006-01-02"), "2024-02-29", false},
+		{"date invalid", DateValidator("2006-01-02"), "2023-02-29", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validator(nil, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validator(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidators_CustomMessage(t *testing.T) {
+	err := NotEmptyValidator("Name is required")(nil, "")
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *ValidationError, got %T", err)
+	}
+	if validationErr.Message != "Name is required" {
+		t.Errorf("Expected custom message, got %q", validationErr.Message)
+	}
+}
+
+func TestChain(t *testing.T) {
+	validator := Chain(
+		NotEmptyValidator("empty"),
+		LengthValidator(3, 0, "short"),
+	)
+
+	if err := validator(nil, ""); err == nil || err.Error() != "empty" {
+		t.Errorf("Expected first validator to fail with 'empty', got %v", err)
+	}
+	if err := validator(nil, "ab"); err == nil || err.Error() != "short" {
+		t.Errorf("Expected second validator to fail with 'short', got %v", err)
+	}
+	if err := validator(nil, "abc"); err != nil {
+		t.Errorf("Expected chain to pass, got %v", err)
+	}
+}
+
+func TestFlowManager_StepValidators(t *testing.T) {
+	fm, mockSender, _, _ := createTestFlowManager()
+
+	processed := false
+	flow := createTestFlow()
+	flow.Steps["step1"].Validators = []Validator{IntRangeValidator(1, 120, "Invalid age")}
+	flow.Steps["step1"].ProcessFunc = func(ctx *Context, input string, buttonClick *ButtonClick) ProcessResult {
+		processed = true
+		return NextStep()
+	}
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	mockSender.reset()
+
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "abc", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	if processed {
+		t.Error("ProcessFunc should not run when validation fails")
+	}
+	calls := mockSender.getComposeAndSendCalls()
+	if len(calls) != 1 || calls[0].Message != "Invalid age" {
+		t.Errorf("Expected validation prompt, got %+v", calls)
+	}
+
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "42", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	if !processed {
+		t.Error("ProcessFunc should run when validation passes")
+	}
+}
+
+func TestFlowManager_ValidatorsHonorStepMaxAttempts(t *testing.T) {
+	fm, _, _, _ := createTestFlowManager()
+
+	var maxedStep string
+	flow := createTestFlow()
+	flow.Steps["step1"].Validators = []Validator{IntRangeValidator(1, 120, "Invalid age")}
+	flow.Steps["step1"].MaxAttempts = 2
+	flow.OnMaxRetries = func(ctx *Context, stepName string) ProcessResult {
+		maxedStep = stepName
+		return CancelFlow()
+	}
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := fm.HandleUpdate(createFlowTestContext(userID, "abc", fm)); err != nil {
+			t.Fatalf("HandleUpdate failed: %v", err)
+		}
+	}
+	if maxedStep != "step1" {
+		t.Errorf("Expected OnMaxRetries for step1 after two failed validations, got %q", maxedStep)
+	}
+	if fm.isUserInFlow(userID, userID) {
+		t.Error("Expected the flow to be cancelled by OnMaxRetries")
+	}
+}
+
+func TestFlowManager_AsyncValidators(t *testing.T) {
+	fm, mockSender, _, _ := createTestFlowManager()
+
+	release := make(chan error)
+	flow := createTestFlow()
+	flow.Steps["step1"].AsyncValidators = []Validator{func(ctx *Context, input string) error {
+		return <-release
+	}}
+	fm.registerFlow(flow)
+
+	userID := int64(12345)
+	if err := fm.startFlow(userID, userID, "test-flow", createFlowTestContext(userID, "", fm)); err != nil {
+		t.Fatalf("Failed to start flow: %v", err)
+	}
+	mockSender.reset()
+
+	stepOf := func() string {
+		locks := fm.stateLocks(userID, userID)
+		locks.RLock()
+		defer locks.RUnlock()
+		state, _ := fm.getState_nolock(flowKey{UserID: userID})
+		return state.CurrentStep
+	}
+	waitForStep := func(want string) {
+		deadline := time.Now().Add(time.Second)
+		for stepOf() != want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := stepOf(); got != want {
+			t.Fatalf("Expected step %s, got %s", want, got)
+		}
+	}
+
+	// First attempt is rejected by the async validator
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "BADCODE", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	if calls := mockSender.getComposeAndSendCalls(); len(calls) != 1 || calls[0].Message != defaultPendingValidationMessage {
+		t.Fatalf("Expected pending prompt, got %+v", calls)
+	}
+
+	// Input received while the check is running is not processed
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "another", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+
+	release <- &ValidationError{Message: "Unknown code"}
+	deadline := time.Now().Add(time.Second)
+	for len(mockSender.getComposeAndSendCalls()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	calls := mockSender.getComposeAndSendCalls()
+	if len(calls) != 3 || calls[2].Message != "Unknown code" {
+		t.Fatalf("Expected retry prompt with validation error, got %+v", calls)
+	}
+	waitForStep("step1")
+
+	// Second attempt passes and advances the flow
+	if _, err := fm.HandleUpdate(createFlowTestContext(userID, "GOODCODE", fm)); err != nil {
+		t.Fatalf("HandleUpdate failed: %v", err)
+	}
+	release <- nil
+	waitForStep("step2")
+
+	if value, ok := fm.getUserFlowData(userID, userID, "name"); !ok || value != "GOODCODE" {
+		t.Errorf("Expected ProcessFunc to store validated input, got %v", value)
+	}
+}
+
+func TestFileValidators(t *testing.T) {
+	newFileContext := func(msg *tgbotapi.Message) *Context {
+		msg.From = &tgbotapi.User{ID: 1}
+		msg.Chat = &tgbotapi.Chat{ID: 1}
+		return &Context{update: tgbotapi.Update{Message: msg}}
+	}
+	pdf := newFileContext(&tgbotapi.Message{Document: &tgbotapi.Document{FileName: "Invoice.PDF", MimeType: "application/pdf", FileSize: 2048}})
+	photo := newFileContext(&tgbotapi.Message{Photo: []tgbotapi.PhotoSize{
+		{Width: 90, Height: 60, FileSize: 100},
+		{Width: 1280, Height: 720, FileSize: 90000},
+	}})
+	text := newFileContext(&tgbotapi.Message{Text: "hello"})
+
+	tests := []struct {
+		name      string
+		validator Validator
+		ctx       *Context
+		wantErr   bool
+	}{
+		{"file required with document", FileRequiredValidator(), pdf, false},
+		{"file required with text", FileRequiredValidator(), text, true},
+		{"size within limit", MaxFileSizeValidator(4096), pdf, false},
+		{"size over limit", MaxFileSizeValidator(1024), pdf, true},
+		{"mime exact", MimeTypeValidator([]string{"application/pdf"}), pdf, false},
+		{"mime wildcard", MimeTypeValidator([]string{"image/*"}), photo, false},
+		{"mime rejected", MimeTypeValidator([]string{"image/*"}), pdf, true},
+		{"extension without dot", FileExtensionValidator([]string{"pdf"}), pdf, false},
+		{"extension rejected", FileExtensionValidator([]string{".docx"}), pdf, true},
+		{"dimensions use largest photo", ImageDimensionsValidator(800, 600, 0, 0), photo, false},
+		{"dimensions too large", ImageDimensionsValidator(0, 0, 1024, 1024), photo, true},
+		{"dimensions on document", ImageDimensionsValidator(1, 1, 0, 0), pdf, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validator(tt.ctx, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileValidators_TemplateData(t *testing.T) {
+	ctx := &Context{update: tgbotapi.Update{Message: &tgbotapi.Message{
+		Document: &tgbotapi.Document{FileName: "big.zip", MimeType: "application/zip", FileSize: 5000},
+	}}}
+
+	err := MaxFileSizeValidator(1000, "template:file_too_large")(ctx, "")
+	result := validationRetry(err)
+
+	if result.Prompt == nil || result.Prompt.Message != "template:file_too_large" {
+		t.Fatalf("Expected templated retry prompt, got %+v", result.Prompt)
+	}
+	data := result.Prompt.TemplateData
+	if data["max_size"] != 1000 || data["file_name"] != "big.zip" || data["file_size"] != 5000 {
+		t.Errorf("Unexpected template data: %v", data)
+	}
+}
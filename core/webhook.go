@@ -0,0 +1,181 @@
+package teleflow
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramNetworks are the IP ranges Telegram sends webhook requests from.
+var telegramNetworks = []string{"149.154.160.0/20", "91.108.4.0/22"}
+
+// webhookSecretHeader carries the secret token configured with setWebhook.
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxWebhookBodySize bounds the size of an accepted webhook request body.
+const maxWebhookBodySize = 1 << 20
+
+// WebhookConfig configures the HTTP handler returned by Bot.WebhookHandler.
+type WebhookConfig struct {
+	SecretToken           string   // Expected X-Telegram-Bot-Api-Secret-Token value (empty disables the check)
+	RestrictToTelegramIPs bool     // Reject requests from outside Telegram's published IP ranges
+	AllowedNetworks       []string // CIDRs accepted instead of Telegram's ranges (e.g. a gateway)
+	TrustForwardedFor     bool     // Take the client IP from X-Forwarded-For (behind a reverse proxy)
+	TrustedProxies        int      // Reverse proxies appending to X-Forwarded-For, 1 by default
+	Synchronous           bool     // Process the update before responding instead of in the background
+}
+
+// WebhookHandler returns an HTTP handler that receives updates pushed by
+// Telegram. Requests with a wrong secret token or from outside the allowed
+// networks are rejected with 401/403 before they reach any bot logic.
+// By default updates are processed in the background so Telegram gets a quick
+// response; set Synchronous to process them before responding.
+//
+// Example:
+//
+//	secret := os.Getenv("WEBHOOK_SECRET")
+//	if err := bot.SetWebhook("https://bot.example.com/telegram", secret); err != nil {
+//		log.Fatal(err)
+//	}
+//	handler, err := bot.WebhookHandler(teleflow.WebhookConfig{
+//		SecretToken:           secret,
+//		RestrictToTelegramIPs: true,
+//	})
+//	http.Handle("/telegram", handler)
+func (b *Bot) WebhookHandler(config WebhookConfig) (http.Handler, error) {
+	b.startFlowSweeper()
+	b.webhook.Store(true)
+	b.startedAt.CompareAndSwap(0, b.clock.Now().UnixNano())
+
+	var networks []*net.IPNet
+	if config.RestrictToTelegramIPs || len(config.AllowedNetworks) > 0 {
+		cidrs := config.AllowedNetworks
+		if len(cidrs) == 0 {
+			cidrs = telegramNetworks
+		}
+		for _, cidr := range cidrs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", cidr, err)
+			}
+			networks = append(networks, network)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if config.SecretToken != "" {
+			token := r.Header.Get(webhookSecretHeader)
+			if subtle.ConstantTimeCompare([]byte(token), []byte(config.SecretToken)) != 1 {
+				log.Printf("[WEBHOOK] Rejected request from %s: invalid secret token", r.RemoteAddr)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if networks != nil {
+			proxies := 0
+			if config.TrustForwardedFor {
+				proxies = max(config.TrustedProxies, 1)
+			}
+			ip := webhookClientIP(r, proxies)
+			if !ipInNetworks(ip, networks) {
+				log.Printf("[WEBHOOK] Rejected request from %s: source not allowed", r.RemoteAddr)
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		var update updateEnvelope
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+		if err == nil {
+			update, err = decodeUpdate(body)
+		}
+		if err != nil {
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+
+		if config.Synchronous {
+			b.processEnvelope(update)
+		} else {
+			b.activeHandlers.Add(1)
+			go func() {
+				defer b.activeHandlers.Add(-1)
+				b.processEnvelope(update)
+			}()
+		}
+		w.WriteHeader(http.StatusOK)
+	}), nil
+}
+
+// SetWebhook tells Telegram to push updates to url and to send secretToken in
+// the X-Telegram-Bot-Api-Secret-Token header of every request. Update types
+// configured with WithAllowedUpdates are requested as well, and pending updates
+// are dropped with WithDropPendingUpdates.
+func (b *Bot) SetWebhook(url, secretToken string) error {
+	params := tgbotapi.Params{}
+	params["url"] = url
+	params.AddNonEmpty("secret_token", secretToken)
+	if b.updatesConfig.dropPending {
+		params.AddBool("drop_pending_updates", true)
+	}
+	if b.updatesConfig.allowedUpdates != nil {
+		if err := params.AddInterface("allowed_updates", b.updatesConfig.allowedUpdates); err != nil {
+			return fmt.Errorf("failed to set webhook: %w", err)
+		}
+	}
+
+	if _, err := makeRawRequest(b.sender, "setWebhook", params); err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
+
+// webhookClientIP returns the IP address the request originated from. Behind
+// trusted proxies it is the X-Forwarded-For entry added by the outermost one:
+// entries to its left come from the client and can be forged. It returns nil
+// when the header has fewer entries than there are proxies.
+func webhookClientIP(r *http.Request, trustedProxies int) net.IP {
+	if trustedProxies > 0 {
+		var entries []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			entries = append(entries, strings.Split(header, ",")...)
+		}
+		if len(entries) > 0 {
+			if len(entries) < trustedProxies {
+				return nil
+			}
+			return net.ParseIP(strings.TrimSpace(entries[len(entries)-trustedProxies]))
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// ipInNetworks reports whether ip belongs to one of the networks.
+func ipInNetworks(ip net.IP, networks []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
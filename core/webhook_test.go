@@ -0,0 +1,126 @@
+package teleflow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testWebhookBody = `{"update_id":1,"message":{"message_id":1,"from":{"id":42},"chat":{"id":42,"type":"private"},"text":"ping"}}`
+
+func TestWebhookHandler_Validation(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	var handled int
+	bot.HandleText("ping", func(ctx *Context, text string) error {
+		handled++
+		return nil
+	})
+
+	handler, err := bot.WebhookHandler(WebhookConfig{
+		SecretToken:           "s3cret",
+		RestrictToTelegramIPs: true,
+		Synchronous:           true,
+	})
+	if err != nil {
+		t.Fatalf("WebhookHandler failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		remoteAddr string
+		secret     string
+		body       string
+		expected   int
+	}{
+		{"valid request", http.MethodPost, "149.154.167.220:443", "s3cret", testWebhookBody, http.StatusOK},
+		{"wrong secret", http.MethodPost, "149.154.167.220:443", "guess", testWebhookBody, http.StatusUnauthorized},
+		{"foreign IP", http.MethodPost, "203.0.113.7:443", "s3cret", testWebhookBody, http.StatusForbidden},
+		{"invalid body", http.MethodPost, "91.108.4.10:443", "s3cret", "{", http.StatusBadRequest},
+		{"wrong method", http.MethodGet, "149.154.167.220:443", "s3cret", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/telegram", strings.NewReader(tt.body))
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(webhookSecretHeader, tt.secret)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			if recorder.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, recorder.Code)
+			}
+		})
+	}
+
+	if handled != 1 {
+		t.Errorf("Expected only the valid request to be processed, got %d", handled)
+	}
+}
+
+func TestWebhookHandler_ForwardedFor(t *testing.T) {
+	bot, _, _, _ := createTestBot()
+
+	handler, err := bot.WebhookHandler(WebhookConfig{
+		AllowedNetworks:   []string{"10.0.0.0/8"},
+		TrustForwardedFor: true,
+		Synchronous:       true,
+	})
+	if err != nil {
+		t.Fatalf("WebhookHandler failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(testWebhookBody))
+	req.RemoteAddr = "127.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "10.1.2.3")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected forwarded IP to be accepted, got %d", recorder.Code)
+	}
+
+	// A client-supplied left-most entry must not pass the allowlist
+	req = httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(testWebhookBody))
+	req.RemoteAddr = "127.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "10.1.2.3, 203.0.113.9")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected forged forwarded IP to be rejected, got %d", recorder.Code)
+	}
+
+	// With two proxies the entry added by the outer one is used
+	twoProxies, _ := bot.WebhookHandler(WebhookConfig{
+		AllowedNetworks:   []string{"10.0.0.0/8"},
+		TrustForwardedFor: true,
+		TrustedProxies:    2,
+		Synchronous:       true,
+	})
+	req = httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(testWebhookBody))
+	req.RemoteAddr = "127.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "149.154.167.1, 10.1.2.3, 172.16.0.2")
+	recorder = httptest.NewRecorder()
+	twoProxies.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected the outer proxy's entry to be accepted, got %d", recorder.Code)
+	}
+
+	if _, err := bot.WebhookHandler(WebhookConfig{AllowedNetworks: []string{"bogus"}}); err == nil {
+		t.Error("Expected error for an invalid network")
+	}
+}
+
+func TestSetWebhook(t *testing.T) {
+	bot, mockClient, _, _ := createTestBot()
+
+	if err := bot.SetWebhook("https://bot.example.com/telegram", "s3cret"); err != nil {
+		t.Fatalf("SetWebhook failed: %v", err)
+	}
+	call := mockClient.MakeRequestCalls[0]
+	if call.Endpoint != "setWebhook" || call.Params["url"] != "https://bot.example.com/telegram" || call.Params["secret_token"] != "s3cret" {
+		t.Errorf("Unexpected request: %+v", call)
+	}
+}
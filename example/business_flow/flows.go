@@ -101,10 +101,11 @@ func createAccountInfoFlow(businessService *BusinessService) (*teleflow.Flow, er
 			return fmt.Sprintf("💰 Enter initial balance for '%s' account:", accountName)
 		}).
 		Process(func(ctx *teleflow.Context, input string, buttonClick *teleflow.ButtonClick) teleflow.ProcessResult {
-			balance, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
-			if err != nil || balance < 0 {
-				return teleflow.Retry().WithPrompt("Please enter a valid amount (numbers only, e.g., 100.50):")
+			money, err := teleflow.ParseMoney(input, "USD")
+			if err != nil || money.Minor < 0 {
+				return teleflow.Retry().WithPrompt("Please enter a valid amount (e.g., 100.50 or 1,250.00):")
 			}
+			balance := money.Float()
 
 			accountName, _ := ctx.GetFlowData("new_account_name")
 			err = businessService.AddAccount(ctx.UserID(), accountName.(string), balance)
@@ -197,13 +198,15 @@ func createTransferFundsFlow(businessService *BusinessService) (*teleflow.Flow,
 		Prompt(func(ctx *teleflow.Context) string {
 			fromAccountID, _ := ctx.GetFlowData("from_account_id")
 			balance, _ := businessService.GetAccountBalance(ctx.UserID(), fromAccountID.(string))
-			return fmt.Sprintf("💵 Enter transfer amount (Available balance: $%.2f):", balance)
+			available, _ := teleflow.NewMoney(balance, "USD")
+			return fmt.Sprintf("💵 Enter transfer amount (Available balance: %s):", available.Format("en"))
 		}).
 		Process(func(ctx *teleflow.Context, input string, buttonClick *teleflow.ButtonClick) teleflow.ProcessResult {
-			amount, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
-			if err != nil || amount <= 0 {
-				return teleflow.Retry().WithPrompt("Please enter a valid amount (numbers only, e.g., 50.00):")
+			money, err := teleflow.ParseMoney(input, "USD")
+			if err != nil || money.Minor <= 0 {
+				return teleflow.Retry().WithPrompt("Please enter a valid amount (e.g., 50.00):")
 			}
+			amount := money.Float()
 
 			fromAccountID, _ := ctx.GetFlowData("from_account_id")
 			toAccountID, _ := ctx.GetFlowData("to_account_id")
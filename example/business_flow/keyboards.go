@@ -79,32 +79,28 @@ func ShippingSelectionKeyboard(methods []ShippingOption) *teleflow.PromptKeyboar
 
 // PaymentAccountSelectionKeyboard creates keyboard for payment account selection
 func PaymentAccountSelectionKeyboard(accounts []UserAccount) *teleflow.PromptKeyboardBuilder {
-	kb := teleflow.NewPromptKeyboard()
-
-	for _, account := range accounts {
-		buttonText := account.Name + " - $" + formatPrice(account.Balance)
-		kb.ButtonCallback(buttonText, account.AccountID)
-		kb.Row()
-	}
-
-	return kb
+	return teleflow.ButtonsFromSlice(teleflow.NewPromptKeyboard(), accounts, 1,
+		func(account UserAccount) string {
+			return account.Name + " - $" + formatPrice(account.Balance)
+		},
+		func(account UserAccount) interface{} { return account.AccountID },
+	)
 }
 
 // AccountSelectionKeyboard creates a general account selection keyboard
 func AccountSelectionKeyboard(accounts []UserAccount, action string) *teleflow.PromptKeyboardBuilder {
-	kb := teleflow.NewPromptKeyboard()
-
-	for _, account := range accounts {
-		buttonText := account.Name + " - $" + formatPrice(account.Balance)
-		kb.ButtonCallback(buttonText, map[string]interface{}{
-			"action":       action,
-			"account_id":   account.AccountID,
-			"account_name": account.Name,
-		})
-		kb.Row()
-	}
-
-	return kb
+	return teleflow.ButtonsFromSlice(teleflow.NewPromptKeyboard(), accounts, 1,
+		func(account UserAccount) string {
+			return account.Name + " - $" + formatPrice(account.Balance)
+		},
+		func(account UserAccount) interface{} {
+			return map[string]interface{}{
+				"action":       action,
+				"account_id":   account.AccountID,
+				"account_name": account.Name,
+			}
+		},
+	)
 }
 
 // ConfirmationKeyboard creates a yes/no confirmation keyboard